@@ -22,6 +22,7 @@ type Client struct {
 	client            *resty.Client // Клиент для подключения к серверам
 	metricsCollection *collection.Type
 	sendPool          Sender
+	pausedUntil       time.Time // До какого времени сервер попросил не отправлять метрики
 }
 
 // Sender интерфейс для пула конектов к серверу
@@ -48,6 +49,10 @@ func (c *Client) PeriodicSender(ctx context.Context) {
 		// Ловим закрытие контекста, чтобы завершить обработку
 		select {
 		case <-ticker.C:
+			if wait := time.Until(c.pausedUntil); wait > 0 {
+				logger.Log.Infow("Server asked to back off, skip sending", "wait", wait)
+				continue
+			}
 			c.retrySend()
 		case <-ctx.Done():
 			logger.Log.Info("Periodic sender stopped")
@@ -71,11 +76,19 @@ func (c *Client) retrySend() {
 			break
 		}
 
-		<-time.After(pause)
+		<-time.After(c.retryPause(pause))
 		pause += 2 * time.Second
 	}
 }
 
+// retryPause пауза перед повтором отправки, не меньше той, о которой попросил сервер
+func (c *Client) retryPause(pause time.Duration) time.Duration {
+	if wait := time.Until(c.pausedUntil); wait > pause {
+		return wait
+	}
+	return pause
+}
+
 // sendMetrics Функция прохода по метрикам и запуск их отправки
 func (c *Client) sendMetrics() error {
 	logger.Log.Info("Sending metrics")
@@ -124,10 +137,19 @@ func (c *Client) sendToServer(body []payload.Metrics) error {
 	// Отправляем запрос
 	res, err := c.sendPool.Send(body)
 	logger.Log.Info("Finish sending metrics")
+	// Метрики приняты, хотя ответ и не проверен, поэтому их не отправляем повторно
+	if errors.Is(err, sendpool.ErrorUnverifiedResponse) {
+		logger.Log.Warn(err)
+		err = nil
+	}
 	if err != nil {
 		//return metricerrors.NewRetriable(err)
 		return err
 	}
+	// Сервер может попросить подождать как в ответе с ошибкой, так и в успешном
+	if backoff := sendpool.Backoff(res); backoff > 0 {
+		c.pausedUntil = time.Now().Add(backoff)
+	}
 	if statusCode := res.StatusCode(); statusCode != http.StatusOK {
 		return metricerrors.NewRetriable(fmt.Errorf("http status code %d", statusCode))
 	}
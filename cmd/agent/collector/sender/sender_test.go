@@ -11,6 +11,7 @@ import (
 	"gmetrics/internal/payload"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -74,6 +75,9 @@ func TestSendToServer(t *testing.T) {
 			name: "successful_metric_send",
 			setupMock: func() *httptest.Server {
 				return httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+					// Агент с ключом принимает только подписанный ответ
+					sign, _ := sendpool.HashBody(nil, "1")
+					responseWriter.Header().Set("HashSHA256", sign)
 					responseWriter.WriteHeader(http.StatusOK)
 				}))
 			},
@@ -136,7 +140,7 @@ func TestSendToServer(t *testing.T) {
 			defer cancelFunc()
 
 			// Создаём пул отправок на сервер
-			sendPool, poolErr := sendpool.New(ctx, 1, "1", mockServer.URL, nil)
+			sendPool, poolErr := sendpool.New(ctx, 1, "1", mockServer.URL, nil, sendpool.Credentials{})
 			if poolErr != nil {
 				assert.NoError(t, poolErr)
 				return
@@ -152,6 +156,45 @@ func TestSendToServer(t *testing.T) {
 	}
 }
 
+func TestRetrySend_UnverifiedResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		sign string
+	}{
+		{name: "missing_sign", sign: ""},
+		{name: "wrong_sign", sign: "00"},
+	}
+	config.Params = config.InitializeDefaultConfig()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			mockServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+				requests.Add(1)
+				if tc.sign != "" {
+					responseWriter.Header().Set("HashSHA256", tc.sign)
+				}
+				responseWriter.WriteHeader(http.StatusOK)
+			}))
+			defer mockServer.Close()
+			ctx, cancelFunc := context.WithCancel(context.TODO())
+			defer cancelFunc()
+
+			sendPool, err := sendpool.New(ctx, 1, "1", mockServer.URL, nil, sendpool.Credentials{})
+			if !assert.NoError(t, err) {
+				return
+			}
+			cl := getMockCollection()
+			client := New(cl, sendPool)
+			client.retrySend()
+
+			// Сервер уже записал приращения каунтеров, поэтому они не отправляются повторно
+			assert.Equal(t, int32(1), requests.Load())
+			assert.Equal(t, metrics.Counter(0), cl.PollCount)
+		})
+	}
+}
+
 func createMockSender(t *testing.T) *MockSender {
 	ctrl := gomock.NewController(t)
 	s := NewMockSender(ctrl)
@@ -312,3 +355,53 @@ func TestPeriodicSender(t *testing.T) {
 		})
 	}
 }
+
+func TestSendToServerBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantErr    bool
+		wantPause  bool
+	}{
+		{
+			name:       "success_without_backoff",
+			statusCode: http.StatusOK,
+			body:       `{"status":"success"}`,
+		},
+		{
+			name:       "success_with_backoff",
+			statusCode: http.StatusOK,
+			body:       `{"status":"success","backoff":60}`,
+			wantPause:  true,
+		},
+		{
+			name:       "too_many_requests_with_backoff",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"status":"error","backoff":60}`,
+			wantErr:    true,
+			wantPause:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSender := createMockSender(t)
+			res := &resty.Response{RawResponse: &http.Response{StatusCode: tc.statusCode}}
+			mockSender.EXPECT().Send(gomock.Any()).Return(res.SetBody([]byte(tc.body)), nil)
+			client := New(getMockCollection(), mockSender)
+			err := client.sendToServer([]payload.Metrics{})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tc.wantPause {
+				assert.InDelta(t, time.Minute.Seconds(), time.Until(client.pausedUntil).Seconds(), 5)
+				assert.Greater(t, client.retryPause(time.Second), 50*time.Second)
+				return
+			}
+			assert.True(t, client.pausedUntil.IsZero())
+			assert.Equal(t, time.Second, client.retryPause(time.Second))
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package config
 
-import "crypto/rsa"
+import (
+	"crypto/rsa"
+	"crypto/tls"
+)
 
 const (
 	// DefaultPollInterval Интервал между сборкой данных по умолчанию
@@ -42,6 +45,16 @@ type CliConfig struct {
 	CryptoKey *rsa.PublicKey
 	// ConfigFilePath Путь к файлу с конфигурацией
 	ConfigFilePath string `env:"CONFIG"`
+	// TLSCAPath Путь к сертификату центра, которым подписан сертификат сервера
+	TLSCAPath string `env:"TLS_CA"`
+	// TLSCertPath Путь к сертификату агента для mTLS
+	TLSCertPath string `env:"TLS_CERT"`
+	// TLSKeyPath Путь к приватному ключу сертификата агента
+	TLSKeyPath string `env:"TLS_KEY"`
+	// TLSConfig Конфигурация TLS для подключения к серверу, если nil, то соединение не шифруется
+	TLSConfig *tls.Config
+	// Token Токен доступа к серверу, передаётся в заголовке Authorization
+	Token string `env:"TOKEN"`
 }
 
 // Params конфигурация приложения
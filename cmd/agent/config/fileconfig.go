@@ -9,4 +9,8 @@ type FileConfig struct {
 	ReportInterval incnf.Duration `json:"report_interval"`
 	PollInterval   incnf.Duration `json:"poll_interval"`
 	CryptoKey      string         `json:"crypto_key"`
+	TLSCA          string         `json:"tls_ca"`
+	TLSCert        string         `json:"tls_cert"`
+	TLSKey         string         `json:"tls_key"`
+	Token          string         `json:"token"`
 }
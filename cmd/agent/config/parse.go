@@ -36,6 +36,12 @@ func Parse() (*CliConfig, error) {
 		cnf.CryptoKey = key
 	}
 
+	tlsConfig, err := incnf.NewClientTLSConfig(cnf.TLSCAPath, cnf.TLSCertPath, cnf.TLSKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	cnf.TLSConfig = tlsConfig
+
 	return cnf, nil
 }
 
@@ -73,6 +79,18 @@ func parseFromEnv(params *CliConfig) error {
 	if cnf.ConfigFilePath != "" {
 		params.ConfigFilePath = cnf.ConfigFilePath
 	}
+	if cnf.TLSCAPath != "" {
+		params.TLSCAPath = cnf.TLSCAPath
+	}
+	if cnf.TLSCertPath != "" {
+		params.TLSCertPath = cnf.TLSCertPath
+	}
+	if cnf.TLSKeyPath != "" {
+		params.TLSKeyPath = cnf.TLSKeyPath
+	}
+	if cnf.Token != "" {
+		params.Token = cnf.Token
+	}
 
 	return nil
 }
@@ -93,6 +111,10 @@ func parseFromCli(cnf *CliConfig) error {
 	flag.StringVar(&cnf.CryptoKeyPath, "crypto-key", "", "crypto key")
 	flag.StringVar(&cnf.ConfigFilePath, "c", "", "Path to the configuration file (shorthand)")
 	flag.StringVar(&cnf.ConfigFilePath, "config", "", "Path to the configuration file")
+	flag.StringVar(&cnf.TLSCAPath, "tls-ca", "", "Path to the CA certificate for verifying the server")
+	flag.StringVar(&cnf.TLSCertPath, "tls-cert", "", "Path to the agent TLS certificate (mTLS)")
+	flag.StringVar(&cnf.TLSKeyPath, "tls-key", "", "Path to the agent TLS private key (mTLS)")
+	flag.StringVar(&cnf.Token, "token", "", "Access token for the server")
 
 	// Парсим переданные серверу аргументы в зарегистрированные переменные
 	flag.Parse() // Сейчас будет выход из приложения, поэтому код ниже не будет исполнен, но может пригодиться в будущем, если поменять флаг выхода или будет несколько сетов
@@ -146,5 +168,17 @@ func parseFromFile(cnf *CliConfig) error {
 	if fileConf.CryptoKey != "" && cnf.CryptoKeyPath == "" {
 		cnf.CryptoKeyPath = fileConf.CryptoKey
 	}
+	if fileConf.TLSCA != "" && cnf.TLSCAPath == "" {
+		cnf.TLSCAPath = fileConf.TLSCA
+	}
+	if fileConf.TLSCert != "" && cnf.TLSCertPath == "" {
+		cnf.TLSCertPath = fileConf.TLSCert
+	}
+	if fileConf.TLSKey != "" && cnf.TLSKeyPath == "" {
+		cnf.TLSKeyPath = fileConf.TLSKey
+	}
+	if fileConf.Token != "" && cnf.Token == "" {
+		cnf.Token = fileConf.Token
+	}
 	return nil
 }
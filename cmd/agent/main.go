@@ -39,6 +39,7 @@ func main() {
 		"server url", config.Params.ServerURL,
 		"report interval", config.Params.ReportInterval,
 		"hash key", config.Params.HashKey,
+		"tls", config.Params.TLSConfig != nil,
 	)
 
 	// Создаём новую коллекцию метрик и устанавливаем её глобально
@@ -59,7 +60,10 @@ func main() {
 	}() // Запускаем сборку данных использования системы
 
 	// Создаём пул отправок на сервер
-	sendPool, poolErr := sendpool.NewWithRPC(ctx, config.Params.RateLimit, config.Params.HashKey, config.Params.ServerURL, config.Params.CryptoKey)
+	sendPool, poolErr := sendpool.NewWithRPC(ctx, config.Params.RateLimit, config.Params.HashKey, config.Params.ServerURL, config.Params.CryptoKey, sendpool.Credentials{
+		TLS:   config.Params.TLSConfig,
+		Token: config.Params.Token,
+	})
 	if poolErr != nil {
 		log.Fatal(poolErr)
 	}
@@ -5,6 +5,7 @@
 package sendpool
 
 import (
+	http "net/http"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -90,6 +91,34 @@ func (m *MockMetricResponse) EXPECT() *MockMetricResponseMockRecorder {
 	return m.recorder
 }
 
+// Body mocks base method.
+func (m *MockMetricResponse) Body() []byte {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Body")
+	ret0, _ := ret[0].([]byte)
+	return ret0
+}
+
+// Body indicates an expected call of Body.
+func (mr *MockMetricResponseMockRecorder) Body() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Body", reflect.TypeOf((*MockMetricResponse)(nil).Body))
+}
+
+// Header mocks base method.
+func (m *MockMetricResponse) Header() http.Header {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Header")
+	ret0, _ := ret[0].(http.Header)
+	return ret0
+}
+
+// Header indicates an expected call of Header.
+func (mr *MockMetricResponseMockRecorder) Header() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Header", reflect.TypeOf((*MockMetricResponse)(nil).Header))
+}
+
 // StatusCode mocks base method.
 func (m *MockMetricResponse) StatusCode() int {
 	m.ctrl.T.Helper()
@@ -7,14 +7,18 @@ import (
 	"crypto/hmac"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"gmetrics/internal/encrypt"
 	"gmetrics/internal/logger"
+	"gmetrics/internal/metricerrors"
 	"gmetrics/internal/payload"
 	"io"
+	"net/http"
 	"sync"
+	"time"
 )
 
 var (
@@ -35,12 +39,27 @@ var (
 
 	// ErrorCantEcryptBody Ошибка, что мы не смогли зашифровать тело
 	ErrorCantEcryptBody = errors.New("cant encrypt body")
+
+	// ErrorWrongResponseSign Ошибка, что подпись ответа сервера не совпадает с телом ответа
+	ErrorWrongResponseSign = errors.New("response sign is not correct")
+
+	// ErrorMissingResponseSign Ошибка, что ключ задан, а ответ сервера не подписан
+	ErrorMissingResponseSign = errors.New("response is not signed")
+
+	// ErrorUnverifiedResponse Ошибка, что сервер принял метрики, но подпись его ответа не прошла проверку
+	ErrorUnverifiedResponse = errors.New("metrics are accepted, but response is not verified")
 )
 
 var (
 	URLUpdates = "/updates" // адрес обновления метрик
 )
 
+// Credentials данные для подключения к серверу
+type Credentials struct {
+	TLS   *tls.Config // Конфигурация TLS, если nil, то соединение не шифруется
+	Token string      // Токен доступа, передаётся в заголовке Authorization
+}
+
 // IClient Клиент для отправки метрик на сервер
 type IClient interface {
 	Post(url string, body []byte, headers ...Header) (MetricResponse, error)
@@ -56,6 +75,8 @@ type response struct {
 // MetricResponse интерфейс для ответов от сервера
 type MetricResponse interface {
 	StatusCode() int
+	Body() []byte
+	Header() http.Header
 }
 
 // poolPayload структура тела для запроса на сервер
@@ -90,11 +111,11 @@ func newEncoder() interface{} {
 
 // New Создание нового пула отправщиков.
 // Закрывается по завершению контекста
-func New(ctx context.Context, size int, HashKey, ServerURL string, publicKey *rsa.PublicKey) (*Pool, error) {
+func New(ctx context.Context, size int, HashKey, ServerURL string, publicKey *rsa.PublicKey, creds Credentials) (*Pool, error) {
 	if ServerURL == "" {
 		return nil, ErrorServerURLIsEmpty
 	}
-	client, err := NewRestClient(ServerURL)
+	client, err := NewRestClient(ServerURL, creds)
 	if err != nil {
 		return nil, err
 	}
@@ -103,11 +124,11 @@ func New(ctx context.Context, size int, HashKey, ServerURL string, publicKey *rs
 
 // NewWithRPC Создание нового пула отправщиков c rpc клиентом.
 // Закрывается по завершению контекста
-func NewWithRPC(ctx context.Context, size int, HashKey, ServerURL string, publicKey *rsa.PublicKey) (*Pool, error) {
+func NewWithRPC(ctx context.Context, size int, HashKey, ServerURL string, publicKey *rsa.PublicKey, creds Credentials) (*Pool, error) {
 	if ServerURL == "" {
 		return nil, ErrorServerURLIsEmpty
 	}
-	client, err := NewRPCClient(ctx, ServerURL)
+	client, err := NewRPCClient(ctx, ServerURL, creds)
 	if err != nil {
 		return nil, err
 	}
@@ -247,8 +268,55 @@ func (p *Pool) sendToServer(body []payload.Metrics) (MetricResponse, error) {
 	// Отправляем запрос
 	res, err := p.client.Post(URLUpdates, compressedBody, headers...)
 	logger.Log.Info("Finish sending metrics")
+	if err != nil {
+		return res, err
+	}
+	if err = p.checkResponseSign(res); err != nil {
+		// Успешный ответ значит, что метрики уже записаны: повтор прибавил бы приращения каунтеров ещё раз
+		if status := res.StatusCode(); status >= http.StatusOK && status < http.StatusMultipleChoices {
+			return res, errors.Join(ErrorUnverifiedResponse, err)
+		}
+		return res, metricerrors.NewRetriable(err)
+	}
+
+	return res, nil
+}
+
+// checkResponseSign проверка подписи ответа сервера
+func (p *Pool) checkResponseSign(res MetricResponse) error {
+	return CheckResponseSign(res, p.HashKey)
+}
+
+// CheckResponseSign проверка подписи ответа сервера ключом hashKey.
+// Без ключа ответ не проверяется. С ключом ответ без подписи отклоняется:
+// заголовок может удалить любой посредник, и тогда подпись ничего бы не подтверждала
+func CheckResponseSign(res MetricResponse, hashKey string) error {
+	if hashKey == "" {
+		return nil
+	}
+	hashHeader := res.Header().Get("HashSHA256")
+	if hashHeader == "" {
+		return ErrorMissingResponseSign
+	}
+	hash, err := hex.DecodeString(hashHeader)
+	if err != nil {
+		return errors.Join(ErrorWrongResponseSign, err)
+	}
+	harsher := hmac.New(sha256.New, []byte(hashKey))
+	harsher.Write(res.Body())
+	if !hmac.Equal(hash, harsher.Sum(nil)) {
+		return ErrorWrongResponseSign
+	}
+	return nil
+}
 
-	return res, err
+// Backoff время, на которое сервер попросил агента прекратить отправку метрик
+func Backoff(res MetricResponse) time.Duration {
+	var body payload.ResponseBody
+	if err := json.Unmarshal(res.Body(), &body); err != nil || body.Backoff <= 0 {
+		return 0
+	}
+	return time.Duration(body.Backoff) * time.Second
 }
 
 // marshalBody преобразует тело в строку JSON
@@ -259,10 +327,15 @@ func (p *Pool) marshalBody(body []payload.Metrics) ([]byte, error) {
 
 // hashBody создаём подпись запроса
 func (p *Pool) hashBody(body []byte) (string, error) {
-	if p.HashKey == "" {
+	return HashBody(body, p.HashKey)
+}
+
+// HashBody подпись тела запроса ключом hashKey для заголовка HashSHA256
+func HashBody(body []byte, hashKey string) (string, error) {
+	if hashKey == "" {
 		return "", ErrorEmptyHashKey
 	}
-	harsher := hmac.New(sha256.New, []byte(p.HashKey))
+	harsher := hmac.New(sha256.New, []byte(hashKey))
 	harsher.Write(body)
 	return hex.EncodeToString(harsher.Sum(nil)), nil
 }
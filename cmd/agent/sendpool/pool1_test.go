@@ -2,22 +2,27 @@ package sendpool
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"github.com/go-resty/resty/v2"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"gmetrics/internal/metricerrors"
 	"gmetrics/internal/metrics"
 	"gmetrics/internal/payload"
 	"net/http"
 	"sync"
 	"testing"
+	"time"
 )
 
 func Benchmark(b *testing.B) {
 	ctrl := gomock.NewController(b)
 	restClient := NewMockIClient(ctrl)
 	restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+		Return(signedResponse(200), nil).
 		AnyTimes()
 	restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 	p, err := NewWithClient(context.TODO(), 2, "aboba", restClient, nil)
@@ -205,7 +210,7 @@ func TestPool_sendToServer(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -222,7 +227,7 @@ func TestPool_sendToServer(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				return restClient
 			},
@@ -237,7 +242,7 @@ func TestPool_sendToServer(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -270,7 +275,7 @@ func TestPool_sendToServer(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -334,7 +339,7 @@ func TestPool_processRequest(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -351,7 +356,7 @@ func TestPool_processRequest(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -386,7 +391,7 @@ func TestPool_processRequest(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -452,7 +457,7 @@ func TestPool_worker(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -469,7 +474,7 @@ func TestPool_worker(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -504,7 +509,7 @@ func TestPool_worker(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -521,7 +526,7 @@ func TestPool_worker(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -601,7 +606,7 @@ func TestPool_Send(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -617,7 +622,7 @@ func TestPool_Send(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 200}}, nil).
+					Return(signedResponse(200), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -650,7 +655,7 @@ func TestPool_Send(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -666,7 +671,7 @@ func TestPool_Send(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -683,7 +688,7 @@ func TestPool_Send(t *testing.T) {
 			restClient: func() *MockIClient {
 				restClient := NewMockIClient(ctrl)
 				restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(&resty.Response{RawResponse: &http.Response{StatusCode: 400}}, nil).
+					Return(signedResponse(400), nil).
 					AnyTimes()
 				restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
 				return restClient
@@ -733,3 +738,152 @@ func TestPool_Send(t *testing.T) {
 		})
 	}
 }
+
+// signedResponse ответ сервера с пустым телом, подписанный ключом secret
+func signedResponse(status int) *resty.Response {
+	sign, _ := HashBody(nil, "secret")
+	return &resty.Response{RawResponse: &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Hashsha256": []string{sign}},
+	}}
+}
+
+func TestPool_checkResponseSign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	body := []byte(`{"status":"success"}`)
+	harsher := hmac.New(sha256.New, []byte("secret"))
+	harsher.Write(body)
+	validSign := hex.EncodeToString(harsher.Sum(nil))
+	tests := []struct {
+		name    string
+		hashKey string
+		sign    string
+		wantErr error
+	}{
+		{name: "valid_sign", hashKey: "secret", sign: validSign},
+		{name: "unsigned_response", hashKey: "secret", sign: "", wantErr: ErrorMissingResponseSign},
+		{name: "no_key", hashKey: "", sign: ""},
+		{name: "wrong_sign", hashKey: "secret", sign: hex.EncodeToString([]byte("wrong")), wantErr: ErrorWrongResponseSign},
+		{name: "not_hex_sign", hashKey: "secret", sign: "zz", wantErr: ErrorWrongResponseSign},
+		{name: "other_key", hashKey: "other", sign: validSign, wantErr: ErrorWrongResponseSign},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewMockMetricResponse(ctrl)
+			res.EXPECT().Header().Return(http.Header{"Hashsha256": []string{tt.sign}}).AnyTimes()
+			res.EXPECT().Body().Return(body).AnyTimes()
+			p := &Pool{HashKey: tt.hashKey}
+			err := p.checkResponseSign(res)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPool_sendToServerWrongSign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	restClient := NewMockIClient(ctrl)
+	restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&resty.Response{RawResponse: &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Hashsha256": []string{"00"}},
+		}}, nil)
+	restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
+	p := &Pool{
+		encodeWriterPool: sync.Pool{
+			New: newEncoder,
+		},
+		client:  restClient,
+		HashKey: "secret",
+	}
+	_, err := p.sendToServer([]payload.Metrics{})
+	var rErr *metricerrors.Retriable
+	assert.False(t, errors.As(err, &rErr))
+	assert.ErrorIs(t, err, ErrorUnverifiedResponse)
+	assert.ErrorIs(t, err, ErrorWrongResponseSign)
+}
+
+func TestPool_sendToServerWrongSignOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	restClient := NewMockIClient(ctrl)
+	restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&resty.Response{RawResponse: &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     http.Header{},
+		}}, nil)
+	restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
+	p := &Pool{
+		encodeWriterPool: sync.Pool{
+			New: newEncoder,
+		},
+		client:  restClient,
+		HashKey: "secret",
+	}
+	_, err := p.sendToServer([]payload.Metrics{})
+	var rErr *metricerrors.Retriable
+	assert.ErrorAs(t, err, &rErr)
+	assert.NotErrorIs(t, err, ErrorUnverifiedResponse)
+}
+
+func TestBackoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tests := []struct {
+		name string
+		body []byte
+		want time.Duration
+	}{
+		{name: "with_backoff", body: []byte(`{"status":"error","backoff":3}`), want: 3 * time.Second},
+		{name: "without_backoff", body: []byte(`{"status":"success"}`), want: 0},
+		{name: "negative_backoff", body: []byte(`{"status":"success","backoff":-1}`), want: 0},
+		{name: "not_json", body: []byte(`ok`), want: 0},
+		{name: "empty_body", body: nil, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewMockMetricResponse(ctrl)
+			res.EXPECT().Body().Return(tt.body)
+			assert.Equal(t, tt.want, Backoff(res))
+		})
+	}
+}
+
+func TestHashBodyAndCheckResponseSign(t *testing.T) {
+	body := []byte(`{"status":"success"}`)
+	_, err := HashBody(body, "")
+	assert.ErrorIs(t, err, ErrorEmptyHashKey)
+
+	sign, err := HashBody(body, "secret")
+	assert.NoError(t, err)
+	res := RPCResponse{status: http.StatusOK, body: body, header: http.Header{"Hashsha256": []string{sign}}}
+	assert.NoError(t, CheckResponseSign(res, "secret"))
+	assert.NoError(t, CheckResponseSign(res, ""))
+	assert.ErrorIs(t, CheckResponseSign(res, "other"), ErrorWrongResponseSign)
+	unsigned := RPCResponse{status: http.StatusOK, body: body, header: http.Header{}}
+	assert.ErrorIs(t, CheckResponseSign(unsigned, "secret"), ErrorMissingResponseSign)
+}
+
+func TestPool_sendToServerMissingSign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	restClient := NewMockIClient(ctrl)
+	restClient.EXPECT().Post(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&resty.Response{RawResponse: &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+		}}, nil)
+	restClient.EXPECT().EnableManualCompression().Return(true).AnyTimes()
+	p := &Pool{
+		encodeWriterPool: sync.Pool{
+			New: newEncoder,
+		},
+		client:  restClient,
+		HashKey: "secret",
+	}
+	_, err := p.sendToServer([]payload.Metrics{})
+	var rErr *metricerrors.Retriable
+	assert.False(t, errors.As(err, &rErr))
+	assert.ErrorIs(t, err, ErrorUnverifiedResponse)
+	assert.ErrorIs(t, err, ErrorMissingResponseSign)
+}
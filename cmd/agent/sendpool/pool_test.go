@@ -108,7 +108,7 @@ func TestNew(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.TODO())
 			defer cancel()
 
-			got, err := New(ctx, tt.size, tt.hashKey, tt.serverURL, nil)
+			got, err := New(ctx, tt.size, tt.hashKey, tt.serverURL, nil, Credentials{})
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr)
 				return
@@ -199,7 +199,7 @@ func TestNewWithRPC(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.TODO())
 			defer cancel()
 
-			got, err := NewWithRPC(ctx, tt.size, tt.hashKey, tt.serverURL, nil)
+			got, err := NewWithRPC(ctx, tt.size, tt.hashKey, tt.serverURL, nil, Credentials{})
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr)
 				return
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"github.com/go-resty/resty/v2"
 	"net"
+	"net/http"
+	"strings"
+	"time"
 )
 
 var ErrorNoIPAddres = errors.New("no ip address")
@@ -22,18 +25,40 @@ type RestClient struct {
 
 // Post отправляет HTTP POST запрос на заданный URL с указанным телом и опциональными заголовками.
 func (r RestClient) Post(url string, body []byte, headers ...Header) (MetricResponse, error) {
+	return r.Do(http.MethodPost, url, body, headers...)
+}
+
+// Do отправляет HTTP запрос с методом method на заданный URL. Пустое тело не передаётся
+func (r RestClient) Do(method, url string, body []byte, headers ...Header) (MetricResponse, error) {
 	client := r.client.R()
 	for _, header := range headers {
 		client.SetHeader(header.Name, header.Value)
 	}
 	client.SetHeader("X-Real-IP", r.netAddr)
-	client.SetBody(body)
-	return client.Post(url)
+	if body != nil {
+		client.SetBody(body)
+	}
+	return client.Execute(method, url)
+}
+
+// SetTimeout ограничивает время выполнения запросов
+func (r RestClient) SetTimeout(timeout time.Duration) {
+	r.client.SetTimeout(timeout)
 }
 
 // NewRestClient инициализирует новый RestClient с предоставленным базовым URL-адресом.
-func NewRestClient(baseURL string) (*RestClient, error) {
+// Если передана конфигурация TLS, то запросы будут отправляться по https
+func NewRestClient(baseURL string, creds Credentials) (*RestClient, error) {
 	c := resty.New()
+	if creds.Token != "" {
+		c.SetAuthToken(creds.Token)
+	}
+	if creds.TLS != nil {
+		c.SetTLSClientConfig(creds.TLS)
+		if strings.HasPrefix(baseURL, "http://") {
+			baseURL = "https://" + strings.TrimPrefix(baseURL, "http://")
+		}
+	}
 	c.BaseURL = baseURL
 	addr, err := getNetAddr()
 	if err != nil {
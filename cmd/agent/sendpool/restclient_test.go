@@ -1,12 +1,15 @@
 package sendpool
 
 import (
+	"crypto/tls"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,7 +34,7 @@ func TestNewRestClient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			got, err := NewRestClient(tt.baseURL)
+			got, err := NewRestClient(tt.baseURL, Credentials{})
 			assert.NoError(t, err)
 			if got.client.BaseURL != tt.want.BaseURL {
 				t.Errorf("NewRestClient() baseURL = %v, want %v", got.client.BaseURL, tt.want.BaseURL)
@@ -84,7 +87,7 @@ func TestRestClient_Post(t *testing.T) {
 			// запускаем тестовый сервер, будет выбран первый свободный порт
 			srv := httptest.NewServer(router)
 
-			client, cErr := NewRestClient(srv.URL)
+			client, cErr := NewRestClient(srv.URL, Credentials{})
 			assert.NoError(t, cErr)
 			_, err := client.Post(tt.url, tt.body, tt.headers...)
 			assert.NoError(t, err)
@@ -92,6 +95,52 @@ func TestRestClient_Post(t *testing.T) {
 	}
 }
 
+func TestRestClient_PostTLS(t *testing.T) {
+	router := chi.NewRouter()
+	router.Post("/updates", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewTLSServer(router)
+	defer srv.Close()
+	// Доверяем самоподписанному сертификату тестового сервера
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+	tests := []struct {
+		desc      string
+		baseURL   string
+		tlsConfig *tls.Config
+		wantErr   bool
+	}{
+		{
+			desc:      "https_url",
+			baseURL:   srv.URL,
+			tlsConfig: tlsConfig,
+		},
+		{
+			desc:      "http_url_upgraded_to_https",
+			baseURL:   strings.Replace(srv.URL, "https://", "http://", 1),
+			tlsConfig: tlsConfig,
+		},
+		{
+			desc:    "untrusted_server",
+			baseURL: srv.URL,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			client, cErr := NewRestClient(tt.baseURL, Credentials{TLS: tt.tlsConfig})
+			assert.NoError(t, cErr)
+			res, err := client.Post("/updates", []byte("[]"))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode())
+		})
+	}
+}
+
 // TestGetNetAddr tests the getNetAddr function.
 func TestGetNetAddr(t *testing.T) {
 	tests := []struct {
@@ -136,3 +185,68 @@ func TestRESTClient_EnableManualCompression(t *testing.T) {
 		})
 	}
 }
+
+func TestRestClient_PostToken(t *testing.T) {
+	tests := []struct {
+		desc  string
+		token string
+		want  string
+	}{
+		{
+			desc:  "with_token",
+			token: "secret",
+			want:  "Bearer secret",
+		},
+		{
+			desc: "without_token",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				got = request.Header.Get("Authorization")
+				writer.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+			client, cErr := NewRestClient(srv.URL, Credentials{Token: tt.token})
+			assert.NoError(t, cErr)
+			_, err := client.Post("/updates", []byte("[]"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRestClient_Do(t *testing.T) {
+	tests := []struct {
+		desc   string
+		method string
+		body   []byte
+	}{
+		{desc: "get_without_body", method: http.MethodGet},
+		{desc: "delete_without_body", method: http.MethodDelete},
+		{desc: "post_with_body", method: http.MethodPost, body: []byte(`[{"id":"Alloc","type":"gauge"}]`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				body, err := io.ReadAll(request.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.method, request.Method)
+				assert.Equal(t, "/path", request.URL.Path)
+				assert.Equal(t, string(tt.body), string(body))
+				writer.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			client, err := NewRestClient(srv.URL, Credentials{})
+			assert.NoError(t, err)
+			client.SetTimeout(time.Second)
+			res, err := client.Do(tt.method, "/path", tt.body)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, res.StatusCode())
+		})
+	}
+}
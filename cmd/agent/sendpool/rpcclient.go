@@ -3,9 +3,11 @@ package sendpool
 import (
 	"context"
 	"errors"
+	"gmetrics/internal/payload"
 	pb "gmetrics/internal/payload/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
@@ -42,6 +44,8 @@ var statusMap = map[codes.Code]int{
 // RPCResponse Специальный тип ответа для сопоставления с ответами по http
 type RPCResponse struct {
 	status int
+	body   []byte
+	header http.Header
 }
 
 // StatusCode возвращает статус код
@@ -49,6 +53,16 @@ func (R RPCResponse) StatusCode() int {
 	return R.status
 }
 
+// Body возвращает тело ответа в формате JSON, как по http
+func (R RPCResponse) Body() []byte {
+	return R.body
+}
+
+// Header возвращает трейлеры ответа
+func (R RPCResponse) Header() http.Header {
+	return R.header
+}
+
 // NewRPCResponse создаём новый ответ с маппленным кодом, как в http
 func NewRPCResponse(code codes.Code) RPCResponse {
 	rCode, ok := statusMap[code]
@@ -57,7 +71,27 @@ func NewRPCResponse(code codes.Code) RPCResponse {
 	}
 	return RPCResponse{
 		status: rCode,
+		header: http.Header{},
+	}
+}
+
+// newRPCResponseWithBody создаём ответ с телом и трейлерами, по которым агент проверяет подпись сервера
+func newRPCResponseWithBody(code codes.Code, response payload.RPCResponse, trailer metadata.MD) (RPCResponse, error) {
+	res := NewRPCResponse(code)
+	for name, values := range trailer {
+		for _, value := range values {
+			res.header.Add(name, value)
+		}
+	}
+	if response == nil {
+		return res, nil
 	}
+	body, err := payload.RPCResponseBody(response)
+	if err != nil {
+		return res, err
+	}
+	res.body = body
+	return res, nil
 }
 
 // RPCConnection Интерфейс подключения по rpc
@@ -72,12 +106,18 @@ type RPCClient struct {
 	conn    RPCConnection
 	service pb.MetricsServiceClient
 	netAddr string // реальный адрес кликета, будет встроен в X-Real-IP
+	token   string // токен доступа, будет встроен в Authorization
 }
 
 // NewRPCClient Создание нового rpc клиента
-func NewRPCClient(ctx context.Context, baseURL string) (*RPCClient, error) {
+// Если передана конфигурация TLS, то соединение будет зашифровано
+func NewRPCClient(ctx context.Context, baseURL string, creds Credentials) (*RPCClient, error) {
 	baseURL = clearURL(baseURL)
-	conn, err := grpc.NewClient(baseURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	transport := insecure.NewCredentials()
+	if creds.TLS != nil {
+		transport = credentials.NewTLS(creds.TLS)
+	}
+	conn, err := grpc.NewClient(baseURL, grpc.WithTransportCredentials(transport))
 	if err != nil {
 		return nil, err
 	}
@@ -90,28 +130,67 @@ func NewRPCClient(ctx context.Context, baseURL string) (*RPCClient, error) {
 		conn:    conn,
 		service: pb.NewMetricsServiceClient(conn),
 		netAddr: addr,
+		token:   creds.Token,
 	}, nil
 }
 
 // Post Отправка зпроса на сервер по rpc
 func (r RPCClient) Post(url string, body []byte, headers ...Header) (MetricResponse, error) {
 	requestCtx := r.createMeta(headers)
-	var err error
+	var (
+		err     error
+		resp    *pb.MetricsResponse
+		trailer metadata.MD
+	)
 	switch url {
 	case URLUpdates:
-		_, err = r.sendUpdates(requestCtx, body)
+		resp, err = r.sendUpdates(requestCtx, body, grpc.Trailer(&trailer))
 	default:
 		return nil, ErrorMethodNotExists
 	}
+	return r.response(resp, err, trailer)
+}
+
+// DeleteMetric удаление метрики по rpc
+func (r RPCClient) DeleteMetric(metricType, name string, headers ...Header) (MetricResponse, error) {
+	var trailer metadata.MD
+	resp, err := r.service.DeleteMetric(r.createMeta(headers), &pb.DeleteMetricRequest{
+		Type: metricType,
+		Name: name,
+	}, grpc.Trailer(&trailer))
+	return r.response(resp, err, trailer)
+}
+
+// ResetCounter обнуление counter по rpc
+func (r RPCClient) ResetCounter(name string, headers ...Header) (MetricResponse, error) {
+	var trailer metadata.MD
+	resp, err := r.service.ResetCounter(r.createMeta(headers), &pb.ResetCounterRequest{
+		Name: name,
+	}, grpc.Trailer(&trailer))
+	return r.response(resp, err, trailer)
+}
+
+// response ответ в формате http по результату вызова rpc
+func (r RPCClient) response(resp *pb.MetricsResponse, err error, trailer metadata.MD) (MetricResponse, error) {
 	if err != nil {
 		if e, ok := status.FromError(err); ok {
-			return NewRPCResponse(e.Code()), nil
+			return newRPCResponseWithBody(e.Code(), responseFromDetails(e), trailer)
 		} else {
 			return nil, err
 		}
 	}
 
-	return NewRPCResponse(codes.OK), nil
+	return newRPCResponseWithBody(codes.OK, resp, trailer)
+}
+
+// responseFromDetails ответ сервера из деталей ошибки, например, с просьбой подождать
+func responseFromDetails(st *status.Status) payload.RPCResponse {
+	for _, detail := range st.Details() {
+		if r, ok := detail.(payload.RPCResponse); ok {
+			return r
+		}
+	}
+	return nil
 }
 
 // Close Закрытие подключения
@@ -126,14 +205,17 @@ func (r RPCClient) createMeta(headers []Header) context.Context {
 		md[h.Name] = h.Value
 	}
 	md["X-Real-IP"] = r.netAddr
+	if r.token != "" {
+		md["Authorization"] = "Bearer " + r.token
+	}
 	return metadata.NewOutgoingContext(r.ctx, metadata.New(md))
 }
 
 // sendUpdates отправка запроса на обновление метрик
-func (r RPCClient) sendUpdates(ctx context.Context, body []byte) (*pb.MetricsResponse, error) {
+func (r RPCClient) sendUpdates(ctx context.Context, body []byte, opts ...grpc.CallOption) (*pb.MetricsResponse, error) {
 	return r.service.HandleMetrics(ctx, &pb.MetricsRequest{
 		Body: body,
-	}, grpc.UseCompressor(gzip.Name))
+	}, append([]grpc.CallOption{grpc.UseCompressor(gzip.Name)}, opts...)...)
 }
 
 // clearURL обработка урл сервера, отчистка от http
@@ -6,6 +6,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gmetrics/internal/payload"
 	pb "gmetrics/internal/payload/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -171,6 +172,8 @@ func TestCreateMeta(t *testing.T) {
 		name    string
 		headers []Header
 		addr    string
+		token   string
+		want    []string
 	}{
 		{
 			name: "valid_headers_with_net_addr",
@@ -190,6 +193,13 @@ func TestCreateMeta(t *testing.T) {
 			headers: []Header{},
 			addr:    "",
 		},
+		{
+			name:    "with_token",
+			headers: []Header{},
+			addr:    "10.0.0.3",
+			token:   "secret",
+			want:    []string{"Bearer secret"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -197,6 +207,7 @@ func TestCreateMeta(t *testing.T) {
 			client := RPCClient{
 				ctx:     context.Background(),
 				netAddr: tt.addr,
+				token:   tt.token,
 			}
 			got := client.createMeta(tt.headers)
 			md, ok := metadata.FromOutgoingContext(got)
@@ -205,6 +216,7 @@ func TestCreateMeta(t *testing.T) {
 				assert.Equal(t, []string{header.Value}, md.Get(header.Name))
 			}
 			assert.Equal(t, []string{tt.addr}, md.Get("X-Real-IP"))
+			assert.Equal(t, tt.want, md.Get("Authorization"))
 		})
 	}
 }
@@ -384,7 +396,7 @@ func TestNewRPCClient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client, err := NewRPCClient(context.TODO(), tt.url)
+			client, err := NewRPCClient(context.TODO(), tt.url, Credentials{})
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -394,3 +406,94 @@ func TestNewRPCClient(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRPCResponseWithBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       codes.Code
+		response   payload.RPCResponse
+		trailer    metadata.MD
+		wantStatus int
+		wantBody   string
+		wantSign   string
+	}{
+		{
+			name:       "success_with_sign",
+			code:       codes.OK,
+			response:   &pb.MetricsResponse{Status: payload.ResponseSuccessStatus},
+			trailer:    metadata.Pairs("HashSHA256", "abc"),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"success"}`,
+			wantSign:   "abc",
+		},
+		{
+			name:       "error_with_backoff",
+			code:       codes.ResourceExhausted,
+			response:   &pb.MetricsResponse{Status: payload.ResponseErrorStatus, Backoff: 2},
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   `{"status":"error","backoff":2}`,
+		},
+		{
+			name:       "error_without_response",
+			code:       codes.Internal,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRPCResponseWithBody(tt.code, tt.response, tt.trailer)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, got.StatusCode())
+			assert.Equal(t, tt.wantBody, string(got.Body()))
+			assert.Equal(t, tt.wantSign, got.Header().Get("HashSHA256"))
+		})
+	}
+}
+
+func TestRPCClient_DeleteMetricAndResetCounter(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		call     func(client *RPCClient) (MetricResponse, error)
+		wantCode int
+	}{
+		{
+			name: "delete_metric",
+			call: func(client *RPCClient) (MetricResponse, error) {
+				return client.DeleteMetric("gauge", "Alloc")
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "delete_not_found",
+			err:  status.Error(codes.NotFound, "metric not found"),
+			call: func(client *RPCClient) (MetricResponse, error) {
+				return client.DeleteMetric("gauge", "Alloc")
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "reset_counter",
+			call: func(client *RPCClient) (MetricResponse, error) {
+				return client.ResetCounter("PollCount", Header{Name: "HashSHA256", Value: "sign"})
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			conn := NewMockRPCConnection(ctrl)
+			conn.EXPECT().Invoke(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.err)
+			client := &RPCClient{
+				ctx:     context.TODO(),
+				conn:    conn,
+				service: pb.NewMetricsServiceClient(conn),
+				netAddr: "addr",
+			}
+			res, err := tt.call(client)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCode, res.StatusCode())
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gmetrics/cmd/agent/sendpool"
+	incnf "gmetrics/internal/config"
+	"gmetrics/internal/encrypt"
+	"gmetrics/internal/payload"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	urlValues = "/values"         // чтение многих метрик
+	urlList   = "/api/v1/metrics" // список метрик
+	urlPing   = "/ping"           // проверка сервера
+	urlValue  = "/value/"         // удаление метрики, за ним следуют тип и имя
+	urlReset  = "/reset/counter/" // обнуление counter, за ним следует имя
+	urlExport = "/admin/export"   // выгрузка снимка хранилища
+	urlImport = "/admin/import"   // загрузка снимка хранилища
+)
+
+// ErrorNotSupportedOverRPC ошибка, что запрос нельзя отправить по gRPC
+var ErrorNotSupportedOverRPC = errors.New("not supported over gRPC")
+
+// ResponseError ответ сервера с ошибкой
+type ResponseError struct {
+	Status  int
+	Message string
+}
+
+// Error текст ошибки со статусом ответа
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server responded %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("server responded %d: %s", e.Status, e.Message)
+}
+
+// transport способ доставки запросов на сервер. Запросы описываются методом и адресом, как по http
+type transport interface {
+	Do(method, url string, body []byte, headers ...sendpool.Header) (sendpool.MetricResponse, error)
+	EnableManualCompression() bool
+}
+
+// rpcTransport доставка запросов по gRPC. Поддерживаются только запросы, для которых у сервера есть метод rpc
+type rpcTransport struct {
+	*sendpool.RPCClient
+}
+
+// Do отправляет запрос методом rpc, соответствующим адресу
+func (t rpcTransport) Do(method, path string, body []byte, headers ...sendpool.Header) (sendpool.MetricResponse, error) {
+	switch {
+	case method == http.MethodPost && path == sendpool.URLUpdates:
+		return t.Post(path, body, headers...)
+	case method == http.MethodDelete && strings.HasPrefix(path, urlValue):
+		metricType, name, ok := strings.Cut(strings.TrimPrefix(path, urlValue), "/")
+		if ok {
+			if name, err := url.PathUnescape(name); err == nil {
+				return t.DeleteMetric(metricType, name, headers...)
+			}
+		}
+	case method == http.MethodPost && strings.HasPrefix(path, urlReset):
+		if name, err := url.PathUnescape(strings.TrimPrefix(path, urlReset)); err == nil {
+			return t.ResetCounter(name, headers...)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrorNotSupportedOverRPC, method, path)
+}
+
+// Client клиент сервера метрик. Подписывает и шифрует тела запросов так же, как агент
+type Client struct {
+	transport transport
+	hashKey   string         // Ключ подписи запросов и проверки подписи ответов
+	publicKey *rsa.PublicKey // Ключ шифрования тела запросов, если nil, то тело не шифруется
+}
+
+// newClient создаёт клиента по общим флагам. Соединение по gRPC живёт, пока не завершён контекст
+func newClient(ctx context.Context, opts options) (*Client, error) {
+	tlsConfig, err := incnf.NewClientTLSConfig(opts.TLSCAPath, opts.TLSCertPath, opts.TLSKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{hashKey: opts.HashKey}
+	if opts.CryptoKeyPath != "" {
+		if client.publicKey, err = incnf.ParsePublicKeyFromFile(opts.CryptoKeyPath); err != nil {
+			return nil, err
+		}
+	}
+	creds := sendpool.Credentials{TLS: tlsConfig, Token: opts.Token}
+	if opts.RPC {
+		rpcClient, rErr := sendpool.NewRPCClient(ctx, opts.Address, creds)
+		if rErr != nil {
+			return nil, rErr
+		}
+		client.transport = rpcTransport{rpcClient}
+		return client, nil
+	}
+	restClient, err := sendpool.NewRestClient(opts.Address, creds)
+	if err != nil {
+		return nil, err
+	}
+	restClient.SetTimeout(opts.Timeout)
+	client.transport = restClient
+	return client, nil
+}
+
+// Close закрывает соединение с сервером, если оно постоянное
+func (c *Client) Close() error {
+	if closer, ok := c.transport.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// request отправляет запрос с телом body в JSON и разбирает JSON ответа в result.
+// Если body или result равны nil, то тело не отправляется или не разбирается
+func (c *Client) request(method, path string, body, result any) error {
+	var rawBody []byte
+	if body != nil {
+		marshaled, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rawBody = marshaled
+	}
+	return c.send(method, path, rawBody, "application/json", result)
+}
+
+// send отправляет запрос с готовым телом body типа contentType и разбирает JSON ответа в result.
+// Если body или result равны nil, то тело не отправляется или не разбирается
+func (c *Client) send(method, path string, body []byte, contentType string, result any) error {
+	var headers []sendpool.Header
+	if body != nil {
+		var err error
+		if body, headers, err = c.prepareBody(body, contentType); err != nil {
+			return err
+		}
+	}
+	res, err := c.transport.Do(method, path, body, headers...)
+	if err != nil {
+		return err
+	}
+	if err = sendpool.CheckResponseSign(res, c.hashKey); err != nil {
+		return err
+	}
+	if res.StatusCode() != http.StatusOK {
+		return responseError(res)
+	}
+	if result == nil || len(res.Body()) == 0 {
+		return nil
+	}
+	// Числа сохраняются как есть, чтобы большие counter не теряли точность
+	decoder := json.NewDecoder(bytes.NewReader(res.Body()))
+	decoder.UseNumber()
+	return decoder.Decode(result)
+}
+
+// download тело ответа на GET без проверки подписи. Сервер не подписывает потоковую выгрузку снимка,
+// чтобы не копить её в памяти, поэтому целостность снимка проверяется количеством метрик в его конце
+func (c *Client) download(path string) ([]byte, error) {
+	res, err := c.transport.Do(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode() != http.StatusOK {
+		return nil, responseError(res)
+	}
+	return res.Body(), nil
+}
+
+// prepareBody шифрует, сжимает и подписывает тело запроса типа contentType в том же порядке, что и агент
+func (c *Client) prepareBody(body []byte, contentType string) ([]byte, []sendpool.Header, error) {
+	headers := []sendpool.Header{{Name: "Content-Type", Value: contentType}}
+	if c.publicKey != nil {
+		encrypted, err := encrypt.Encrypt(body, c.publicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = encrypted
+		headers = append(headers, sendpool.Header{Name: "X-Body-Encrypted", Value: "1"})
+	}
+	if c.transport.EnableManualCompression() {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(body); err != nil {
+			return nil, nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, nil, err
+		}
+		body = buf.Bytes()
+		headers = append(headers, sendpool.Header{Name: "Content-Encoding", Value: "gzip"})
+	}
+	if c.hashKey != "" {
+		sign, err := sendpool.HashBody(body, c.hashKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		headers = append(headers, sendpool.Header{Name: "HashSHA256", Value: sign})
+	}
+	return body, headers, nil
+}
+
+// responseError ошибка по ответу сервера. Сообщение берётся из JSON ответа, если он есть
+func responseError(res sendpool.MetricResponse) error {
+	err := &ResponseError{Status: res.StatusCode()}
+	var body payload.ResponseBody
+	if jErr := json.Unmarshal(res.Body(), &body); jErr == nil {
+		err.Message = body.Message
+	} else {
+		err.Message = strings.TrimSpace(string(res.Body()))
+	}
+	return err
+}
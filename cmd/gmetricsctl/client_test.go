@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"gmetrics/cmd/agent/sendpool"
+	incnf "gmetrics/internal/config"
+	"gmetrics/internal/encrypt"
+	"gmetrics/internal/payload"
+	pb "gmetrics/internal/payload/proto"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// signedResponse ответ тестового сервера с подписью ключом hashKey
+func signedResponse(t *testing.T, w http.ResponseWriter, hashKey string, status int, body any) {
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	if hashKey != "" {
+		sign, sErr := sendpool.HashBody(raw, hashKey)
+		require.NoError(t, sErr)
+		w.Header().Set("HashSHA256", sign)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(raw)
+	require.NoError(t, err)
+}
+
+// newTestClient клиент тестового сервера по http
+func newTestClient(t *testing.T, serverURL, hashKey string) *Client {
+	client, err := newClient(context.Background(), options{Address: serverURL, HashKey: hashKey, Timeout: DefaultTimeout})
+	require.NoError(t, err)
+	return client
+}
+
+func TestClient_request(t *testing.T) {
+	key, err := incnf.GenerateKey(incnf.MinKeyBits)
+	require.NoError(t, err)
+	publicKeyPath := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, incnf.WritePublicKeyToFile(publicKeyPath, &key.PublicKey))
+
+	var received []payload.Metrics
+	// Сервер проверяет подпись сжатого тела, разжимает и расшифровывает его, как настоящий
+	handler := encrypt.NewDecrypter(key).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		signedResponse(t, w, "secret", http.StatusOK, payload.ResponseBody{Status: payload.ResponseSuccessStatus})
+	}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, rErr := io.ReadAll(r.Body)
+		require.NoError(t, rErr)
+		sign, sErr := sendpool.HashBody(raw, "secret")
+		require.NoError(t, sErr)
+		assert.Equal(t, sign, r.Header.Get("HashSHA256"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		reader, gErr := gzip.NewReader(bytes.NewReader(raw))
+		require.NoError(t, gErr)
+		r.Body = reader
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client, err := newClient(context.Background(), options{
+		Address:       srv.URL,
+		HashKey:       "secret",
+		CryptoKeyPath: publicKeyPath,
+		Timeout:       DefaultTimeout,
+	})
+	require.NoError(t, err)
+	metric, err := newMetric("gauge", "Alloc", "1.5")
+	require.NoError(t, err)
+	var res payload.ResponseBody
+	require.NoError(t, client.request(http.MethodPost, sendpool.URLUpdates, []payload.Metrics{metric}, &res))
+	assert.Equal(t, payload.ResponseSuccessStatus, res.Status)
+	assert.Equal(t, []payload.Metrics{metric}, received)
+	assert.NoError(t, client.Close())
+}
+
+func TestClient_requestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverKey  string
+		status     int
+		body       any
+		wantStatus int
+		wantErr    error
+	}{
+		{
+			name:       "error_response",
+			serverKey:  "secret",
+			status:     http.StatusNotFound,
+			body:       payload.ResponseBody{Status: payload.ResponseErrorStatus, Message: "metric not found"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:      "wrong_response_sign",
+			serverKey: "other",
+			status:    http.StatusOK,
+			body:      payload.ResponseBody{Status: payload.ResponseSuccessStatus},
+			wantErr:   sendpool.ErrorWrongResponseSign,
+		},
+		{
+			name:    "missing_response_sign",
+			status:  http.StatusOK,
+			body:    payload.ResponseBody{Status: payload.ResponseSuccessStatus},
+			wantErr: sendpool.ErrorMissingResponseSign,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				signedResponse(t, w, tt.serverKey, tt.status, tt.body)
+			}))
+			defer srv.Close()
+
+			err := newTestClient(t, srv.URL, "secret").request(http.MethodGet, urlPing, nil, nil)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			var resErr *ResponseError
+			require.ErrorAs(t, err, &resErr)
+			assert.Equal(t, tt.wantStatus, resErr.Status)
+			assert.Equal(t, "server responded 404: metric not found", resErr.Error())
+		})
+	}
+}
+
+// testRPCService сервис метрик, запоминающий запросы
+type testRPCService struct {
+	pb.UnimplementedMetricsServiceServer
+	deleted []string
+	reset   []string
+}
+
+// DeleteMetric запоминает удалённую метрику, метрика missing не найдена
+func (s *testRPCService) DeleteMetric(_ context.Context, request *pb.DeleteMetricRequest) (*pb.MetricsResponse, error) {
+	if request.GetName() == "missing" {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+	s.deleted = append(s.deleted, request.GetType()+" "+request.GetName())
+	return &pb.MetricsResponse{Status: payload.ResponseSuccessStatus}, nil
+}
+
+// ResetCounter запоминает обнулённый counter
+func (s *testRPCService) ResetCounter(_ context.Context, request *pb.ResetCounterRequest) (*pb.MetricsResponse, error) {
+	s.reset = append(s.reset, request.GetName())
+	return &pb.MetricsResponse{Status: payload.ResponseSuccessStatus}, nil
+}
+
+func TestRPCTransport_Do(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	service := &testRPCService{}
+	server := grpc.NewServer()
+	pb.RegisterMetricsServiceServer(server, service)
+	go func() {
+		_ = server.Serve(listen)
+	}()
+	defer server.Stop()
+
+	client, err := newClient(context.Background(), options{Address: listen.Addr().String(), RPC: true, Timeout: DefaultTimeout})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, client.Close())
+	}()
+
+	require.NoError(t, client.request(http.MethodDelete, urlValue+"gauge/Heap%20Alloc", nil, nil))
+	require.NoError(t, client.request(http.MethodPost, urlReset+"PollCount", nil, nil))
+	assert.Equal(t, []string{"gauge Heap Alloc"}, service.deleted)
+	assert.Equal(t, []string{"PollCount"}, service.reset)
+
+	var resErr *ResponseError
+	require.ErrorAs(t, client.request(http.MethodDelete, urlValue+"gauge/missing", nil, nil), &resErr)
+	assert.Equal(t, http.StatusNotFound, resErr.Status)
+
+	assert.ErrorIs(t, client.request(http.MethodGet, urlList, nil, nil), ErrorNotSupportedOverRPC)
+	assert.ErrorIs(t, client.request(http.MethodDelete, urlValue+"gauge", nil, nil), ErrorNotSupportedOverRPC)
+}
+
+// gunzip разжатое тело запроса
+func gunzip(t *testing.T, r *http.Request) io.Reader {
+	assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+	reader, err := gzip.NewReader(r.Body)
+	require.NoError(t, err)
+	return reader
+}
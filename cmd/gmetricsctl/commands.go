@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"gmetrics/cmd/agent/sendpool"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/snapshot"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// importModeMerge метрики снимка записываются поверх текущих, значения counter заменяются
+	importModeMerge = "merge"
+	// importModeReplace хранилище заменяется снимком целиком
+	importModeReplace = "replace"
+)
+
+var (
+	// ErrorWrongArguments ошибка, что аргументы команды неверны
+	ErrorWrongArguments = errors.New("wrong arguments")
+	// ErrorWrongType ошибка, что тип метрики не поддерживается
+	ErrorWrongType = errors.New("type must be gauge or counter")
+)
+
+// command команда клиента
+type command struct {
+	usage string
+	run   func(c *Client, args []string, p printer) error
+}
+
+// commands команды по именам
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"push":   {usage: "push TYPE NAME VALUE", run: push},
+		"get":    {usage: "get TYPE NAME [TYPE NAME ...]", run: get},
+		"list":   {usage: "list [-type T] [-prefix P] [-glob G] [-regex R] [-label L ...] [-sort S] [-limit N] [-cursor C] [-all]", run: list},
+		"delete": {usage: "delete TYPE NAME", run: deleteMetric},
+		"reset":  {usage: "reset NAME", run: reset},
+		"export": {usage: "export [-format F] [-file FILE]", run: export},
+		"import": {usage: "import -mode merge|replace FILE", run: importMetrics},
+		"health": {usage: "health", run: health},
+	}
+}
+
+// commandNames имена команд по алфавиту
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usageError ошибка неверных аргументов с подсказкой по использованию команды
+func usageError(name string) error {
+	return fmt.Errorf("%w, usage: gmetricsctl %s", ErrorWrongArguments, commands[name].usage)
+}
+
+// push отправка значения метрики
+func push(c *Client, args []string, p printer) error {
+	if len(args) != 3 {
+		return usageError("push")
+	}
+	metric, err := newMetric(args[0], args[1], args[2])
+	if err != nil {
+		return err
+	}
+	var res payload.ResponseBody
+	if err = c.request(http.MethodPost, sendpool.URLUpdates, []payload.Metrics{metric}, &res); err != nil {
+		return err
+	}
+	return p.print(res, statusTable(res))
+}
+
+// get значения метрик по парам тип и имя
+func get(c *Client, args []string, p printer) error {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return usageError("get")
+	}
+	body := make([]payload.Metrics, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		if args[i] != metrics.TypeGauge && args[i] != metrics.TypeCounter {
+			return ErrorWrongType
+		}
+		body = append(body, payload.Metrics{MType: args[i], ID: args[i+1]})
+	}
+	var values []payload.MetricValue
+	if err := c.request(http.MethodPost, urlValues, body, &values); err != nil {
+		return err
+	}
+	return p.print(values, func() table {
+		t := table{header: []string{"TYPE", "NAME", "VALUE"}}
+		for _, value := range values {
+			formatted := "not found"
+			if value.Found {
+				formatted = formatValue(value.Metrics)
+			}
+			t.rows = append(t.rows, []string{value.MType, value.ID, formatted})
+		}
+		return t
+	})
+}
+
+// list страница списка метрик или все метрики с флагом -all
+func list(c *Client, args []string, p printer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(p.warn)
+	query := url.Values{}
+	for _, name := range []string{"type", "prefix", "glob", "regex", "sort", "cursor"} {
+		fs.Func(name, "the "+name+" parameter of the list", func(s string) error {
+			query.Set(name, s)
+			return nil
+		})
+	}
+	fs.Func("label", "label matcher: key=value, key!=value, key=~regex or key!~regex, can be repeated", func(s string) error {
+		query.Add("label", s)
+		return nil
+	})
+	limit := fs.Int("limit", 0, "page size, the server default if 0")
+	all := fs.Bool("all", false, "load all pages")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("list")
+	}
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+	result, err := fetchList(c, query, *all)
+	if err != nil {
+		return err
+	}
+	return p.print(result, func() table {
+		t := table{header: []string{"TYPE", "NAME", "VALUE", "UPDATED"}}
+		for _, metric := range result.Metrics {
+			updated := "-"
+			if !metric.UpdatedAt.IsZero() {
+				updated = metric.UpdatedAt.Format(time.RFC3339)
+			}
+			t.rows = append(t.rows, []string{metric.Type, metric.Name, fmt.Sprint(metric.Value), updated})
+		}
+		if result.NextCursor != "" {
+			t.footer = "next cursor: " + result.NextCursor
+		}
+		return t
+	})
+}
+
+// fetchList страница списка метрик. Если all, то страницы загружаются, пока не закончатся
+func fetchList(c *Client, query url.Values, all bool) (payload.MetricsList, error) {
+	result := payload.MetricsList{Metrics: make([]payload.ListedMetric, 0)}
+	for {
+		var page payload.MetricsList
+		if err := c.request(http.MethodGet, urlList+"?"+query.Encode(), nil, &page); err != nil {
+			return result, err
+		}
+		result.Metrics = append(result.Metrics, page.Metrics...)
+		result.NextCursor = page.NextCursor
+		if !all || page.NextCursor == "" {
+			return result, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// deleteMetric удаление метрики
+func deleteMetric(c *Client, args []string, p printer) error {
+	if len(args) != 2 {
+		return usageError("delete")
+	}
+	var res payload.ResponseBody
+	if err := c.request(http.MethodDelete, urlValue+args[0]+"/"+url.PathEscape(args[1]), nil, &res); err != nil {
+		return err
+	}
+	return p.print(res, statusTable(res))
+}
+
+// reset обнуление counter
+func reset(c *Client, args []string, p printer) error {
+	if len(args) != 1 {
+		return usageError("reset")
+	}
+	var res payload.ResponseBody
+	if err := c.request(http.MethodPost, urlReset+url.PathEscape(args[0]), nil, &res); err != nil {
+		return err
+	}
+	return p.print(res, statusTable(res))
+}
+
+// export выгрузка снимка хранилища со всеми метриками, включая гистограммы, сводки и множества,
+// временем их обновления и описаниями. Снимок сохраняется как есть и загружается обратно командой import
+func export(c *Client, args []string, p printer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(p.warn)
+	format := fs.String("format", snapshot.FormatNDJSON, "snapshot format: ndjson or json compressed with gzip")
+	file := fs.String("file", "", "file to write snapshot to, stdout if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("export")
+	}
+	raw, err := c.download(urlExport + "?" + url.Values{"format": {*format}}.Encode())
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		_, err = p.out.Write(raw)
+		return err
+	}
+	if err = os.WriteFile(*file, raw, 0600); err != nil {
+		return err
+	}
+	p.warnf("exported snapshot to %s", *file)
+	return nil
+}
+
+// importMetrics загрузка снимка, выгруженного export. Режим указывается явно: при merge метрики снимка
+// записываются поверх текущих и значения counter заменяются, а не прибавляются, при replace хранилище заменяется снимком
+func importMetrics(c *Client, args []string, p printer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(p.warn)
+	mode := fs.String("mode", "", "merge - write snapshot over current metrics, replace - replace storage with snapshot")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || (*mode != importModeMerge && *mode != importModeReplace) {
+		return usageError("import")
+	}
+	raw, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// Снимок в формате json сжат gzip, а ndjson передаётся построчно
+	contentType := "application/x-ndjson"
+	if bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		contentType = "application/gzip"
+	}
+	var res payload.ResponseBody
+	if err = c.send(http.MethodPost, urlImport+"?"+url.Values{"mode": {*mode}}.Encode(), raw, contentType, &res); err != nil {
+		return err
+	}
+	return p.print(res, statusTable(res))
+}
+
+// health проверка, что сервер отвечает и подключён к базе данных
+func health(c *Client, args []string, p printer) error {
+	if len(args) != 0 {
+		return usageError("health")
+	}
+	if err := c.request(http.MethodGet, urlPing, nil, nil); err != nil {
+		return err
+	}
+	res := payload.ResponseBody{Status: payload.ResponseSuccessStatus, Message: "ok"}
+	return p.print(res, statusTable(res))
+}
+
+// newMetric метрика для отправки по типу, имени и значению из аргументов
+func newMetric(metricType, name, rawValue string) (payload.Metrics, error) {
+	metric := payload.Metrics{ID: name, MType: metricType}
+	switch metricType {
+	case metrics.TypeGauge:
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			return metric, err
+		}
+		metric.Value = &value
+	case metrics.TypeCounter:
+		delta, err := strconv.ParseInt(rawValue, 10, 64)
+		if err != nil {
+			return metric, err
+		}
+		metric.Delta = &delta
+	default:
+		return metric, ErrorWrongType
+	}
+	return metric, nil
+}
+
+// formatValue значение метрики строкой
+func formatValue(metric payload.Metrics) string {
+	switch {
+	case metric.Value != nil:
+		return strconv.FormatFloat(*metric.Value, 'f', -1, 64)
+	case metric.Delta != nil:
+		return strconv.FormatInt(*metric.Delta, 10)
+	}
+	return ""
+}
+
+// statusTable таблица ответа сервера со статусом и сообщением
+func statusTable(res payload.ResponseBody) func() table {
+	return func() table {
+		return table{
+			header: []string{"STATUS", "MESSAGE"},
+			rows:   [][]string{{res.Status, res.Message}},
+		}
+	}
+}
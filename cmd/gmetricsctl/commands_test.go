@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"gmetrics/internal/payload"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listServer сервер, отдающий список из двух страниц по одной метрике
+func listServer(t *testing.T) *httptest.Server {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.HandleFunc(urlList, func(w http.ResponseWriter, r *http.Request) {
+		page := payload.MetricsList{
+			Metrics:    []payload.ListedMetric{{Type: "gauge", Name: "Alloc", Value: 1.5, UpdatedAt: updated}},
+			NextCursor: "next",
+		}
+		if r.URL.Query().Get("cursor") == "next" {
+			page = payload.MetricsList{Metrics: []payload.ListedMetric{
+				{Type: "counter", Name: "PollCount", Value: 9007199254740993},
+				{Type: "gauge", Name: "Broken", Value: "NaN"},
+			}}
+		}
+		signedResponse(t, w, "", http.StatusOK, page)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestList(t *testing.T) {
+	srv := listServer(t)
+	defer srv.Close()
+	client := newTestClient(t, srv.URL, "")
+
+	var out bytes.Buffer
+	require.NoError(t, list(client, []string{"-prefix", "A"}, printer{out: &out, warn: &out, format: outputTable}))
+	assert.Equal(t, "TYPE   NAME   VALUE  UPDATED\n"+
+		"gauge  Alloc  1.5    2024-05-01T10:00:00Z\n"+
+		"next cursor: next\n", out.String())
+
+	out.Reset()
+	require.NoError(t, list(client, []string{"-all"}, printer{out: &out, warn: &out, format: outputJSON}))
+	var result payload.MetricsList
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Len(t, result.Metrics, 3)
+	assert.Empty(t, result.NextCursor)
+
+	assert.ErrorIs(t, list(client, []string{"extra"}, printer{out: &out, warn: &out}), ErrorWrongArguments)
+}
+
+func TestExportAndImport(t *testing.T) {
+	// Снимок с гистограммой, которую нельзя выгрузить списком метрик
+	exported := []byte(`{"version":3,"format":"ndjson","created_at":"2024-05-01T10:00:00Z"}` + "\n" +
+		`{"type":"histogram","name":"Latency","histogram":{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}}` + "\n" +
+		`{"count":1}` + "\n")
+	var (
+		imported    []byte
+		importQuery url.Values
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc(urlExport, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ndjson", r.URL.Query().Get("format"))
+		// Выгрузка снимка не подписывается
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, err := w.Write(exported)
+		assert.NoError(t, err)
+	})
+	mux.HandleFunc(urlImport, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		importQuery = r.URL.Query()
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		imported, err = io.ReadAll(gunzip(t, r))
+		require.NoError(t, err)
+		signedResponse(t, w, "secret", http.StatusOK, payload.ResponseBody{Status: payload.ResponseSuccessStatus, Message: "1 metrics successfully imported"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := newTestClient(t, srv.URL, "secret")
+	file := filepath.Join(t.TempDir(), "metrics.ndjson")
+
+	var out, warn bytes.Buffer
+	require.NoError(t, export(client, []string{"-file", file}, printer{out: &out, warn: &warn, format: outputJSON}))
+	assert.Empty(t, out.String())
+	raw, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, exported, raw)
+
+	require.NoError(t, importMetrics(client, []string{"-mode", "replace", file}, printer{out: &out, warn: &warn, format: outputTable}))
+	assert.Equal(t, "replace", importQuery.Get("mode"))
+	assert.Equal(t, exported, imported)
+	assert.Contains(t, out.String(), "1 metrics successfully imported")
+
+	// Режим загрузки обязателен: загрузка поверх и замена по-разному обходятся с текущими метриками
+	assert.ErrorIs(t, importMetrics(client, []string{file}, printer{out: &out, warn: &warn}), ErrorWrongArguments)
+	assert.ErrorIs(t, importMetrics(client, []string{"-mode", "add", file}, printer{out: &out, warn: &warn}), ErrorWrongArguments)
+}
+
+func TestGet(t *testing.T) {
+	value := 1.5
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, urlValues, r.URL.Path)
+		var body []payload.Metrics
+		require.NoError(t, json.NewDecoder(gunzip(t, r)).Decode(&body))
+		assert.Equal(t, []payload.Metrics{{ID: "Alloc", MType: "gauge"}, {ID: "Missing", MType: "counter"}}, body)
+		signedResponse(t, w, "", http.StatusOK, []payload.MetricValue{
+			{Metrics: payload.Metrics{ID: "Alloc", MType: "gauge", Value: &value}, Found: true},
+			{Metrics: payload.Metrics{ID: "Missing", MType: "counter"}},
+		})
+	}))
+	defer srv.Close()
+	client := newTestClient(t, srv.URL, "")
+
+	var out bytes.Buffer
+	require.NoError(t, get(client, []string{"gauge", "Alloc", "counter", "Missing"}, printer{out: &out, format: outputTable}))
+	assert.Equal(t, "TYPE     NAME     VALUE\n"+
+		"gauge    Alloc    1.5\n"+
+		"counter  Missing  not found\n", out.String())
+
+	assert.ErrorIs(t, get(client, []string{"gauge"}, printer{out: &out}), ErrorWrongArguments)
+	assert.ErrorIs(t, get(client, []string{"histogram", "Alloc"}, printer{out: &out}), ErrorWrongType)
+}
+
+func TestNewMetric(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      [3]string
+		wantValue string
+		wantErr   bool
+	}{
+		{name: "gauge", args: [3]string{"gauge", "Alloc", "1.5"}, wantValue: "1.5"},
+		{name: "counter", args: [3]string{"counter", "PollCount", "5"}, wantValue: "5"},
+		{name: "fractional_counter", args: [3]string{"counter", "PollCount", "1.5"}, wantErr: true},
+		{name: "wrong_gauge", args: [3]string{"gauge", "Alloc", "abc"}, wantErr: true},
+		{name: "wrong_type", args: [3]string{"histogram", "Alloc", "1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric, err := newMetric(tt.args[0], tt.args[1], tt.args[2])
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValue, formatValue(metric))
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Клиент командной строки для сервера метрик.
+//
+// Запросы подписываются ключом -k и шифруются публичным ключом -crypto-key так же, как это делает агент.
+// По умолчанию запросы отправляются по http, с флагом -grpc - по gRPC.
+// По gRPC доступны только push, delete и reset, так как сервер не отдаёт метрики по gRPC.
+//
+// Использование:
+//
+//	gmetricsctl [флаги] команда [аргументы]
+//
+// Команды:
+//
+//	push TYPE NAME VALUE             отправка значения метрики
+//	get TYPE NAME [TYPE NAME]        значения метрик
+//	list [флаги]                     список метрик с фильтрами, флаги как у GET /api/v1/metrics
+//	delete TYPE NAME                 удаление метрики
+//	reset NAME                       обнуление counter
+//	export [-format F] [-file FILE]  выгрузка снимка хранилища со всеми метриками и описаниями
+//	import -mode M FILE              загрузка снимка поверх метрик (merge) или вместо них (replace)
+//	health                           проверка сервера
+//
+// Пример:
+//
+//	gmetricsctl -a localhost:8080 -k secret -o table list -prefix Heap
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gmetrics/internal/buildflags"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultAddress адрес сервера по умолчанию
+	DefaultAddress = "http://localhost:8080"
+	// DefaultTimeout время ожидания ответа сервера по умолчанию
+	DefaultTimeout = 10 * time.Second
+)
+
+var (
+	// ErrorNoCommand ошибка, что команда не передана
+	ErrorNoCommand = errors.New("command is required")
+	// ErrorUnknownCommand ошибка, что команда не поддерживается
+	ErrorUnknownCommand = errors.New("unknown command")
+	// ErrorWrongOutput ошибка, что формат вывода не поддерживается
+	ErrorWrongOutput = errors.New("output must be json or table")
+)
+
+// options общие флаги всех команд
+type options struct {
+	Address       string
+	RPC           bool
+	HashKey       string
+	CryptoKeyPath string
+	TLSCAPath     string
+	TLSCertPath   string
+	TLSKeyPath    string
+	Token         string
+	Output        string
+	Timeout       time.Duration
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	// Справка и версия уже выведены при разборе флагов
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run разбирает флаги и выполняет команду, результат пишется в out, предупреждения - в errOut
+func run(ctx context.Context, args []string, out, errOut io.Writer) error {
+	opts, rest, err := parseOptions(args, errOut)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return ErrorNoCommand
+	}
+	cmd, ok := commands[rest[0]]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrorUnknownCommand, rest[0])
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	client, err := newClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := client.Close(); cErr != nil {
+			fmt.Fprintln(errOut, cErr)
+		}
+	}()
+	return cmd.run(client, rest[1:], printer{out: out, warn: errOut, format: opts.Output})
+}
+
+// parseOptions разбирает общие флаги до имени команды
+func parseOptions(args []string, errOut io.Writer) (options, []string, error) {
+	opts := options{}
+	fs := flag.NewFlagSet("gmetricsctl", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() {
+		fmt.Fprintln(errOut, "Usage: gmetricsctl [flags] command [arguments]")
+		fmt.Fprintln(errOut, "Commands:")
+		for _, name := range commandNames() {
+			fmt.Fprintf(errOut, "  %s\n", commands[name].usage)
+		}
+		fmt.Fprintln(errOut, "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.Address, "a", DefaultAddress, "server address and port")
+	fs.BoolVar(&opts.RPC, "grpc", false, "send requests over gRPC")
+	fs.StringVar(&opts.HashKey, "k", "", "key for signing requests and checking responses")
+	fs.StringVar(&opts.CryptoKeyPath, "crypto-key", "", "path to the public key for encrypting request bodies")
+	fs.StringVar(&opts.TLSCAPath, "tls-ca", "", "path to the CA certificate for verifying the server")
+	fs.StringVar(&opts.TLSCertPath, "tls-cert", "", "path to the client TLS certificate (mTLS)")
+	fs.StringVar(&opts.TLSKeyPath, "tls-key", "", "path to the client TLS private key (mTLS)")
+	fs.StringVar(&opts.Token, "token", "", "access token for the server")
+	fs.StringVar(&opts.Output, "o", outputJSON, "output format: json or table")
+	fs.DurationVar(&opts.Timeout, "timeout", DefaultTimeout, "timeout of the whole command")
+	version := fs.Bool("version", false, "print build information")
+	if err := fs.Parse(args); err != nil {
+		return opts, nil, err
+	}
+	if *version {
+		buildflags.PrintBuildInformation()
+		return opts, nil, flag.ErrHelp
+	}
+	if opts.Output != outputJSON && opts.Output != outputTable {
+		return opts, nil, ErrorWrongOutput
+	}
+	if !strings.HasPrefix(opts.Address, "http://") && !strings.HasPrefix(opts.Address, "https://") {
+		opts.Address = "http://" + opts.Address
+	}
+	return opts, fs.Args(), nil
+}
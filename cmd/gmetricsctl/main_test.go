@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"gmetrics/cmd/agent/sendpool"
+	"gmetrics/internal/payload"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     options
+		wantRest []string
+		wantErr  error
+	}{
+		{
+			name:     "defaults",
+			args:     []string{"health"},
+			want:     options{Address: DefaultAddress, Output: outputJSON, Timeout: DefaultTimeout},
+			wantRest: []string{"health"},
+		},
+		{
+			name: "flags",
+			args: []string{"-a", "localhost:3200", "-grpc", "-k", "secret", "-token", "t", "-o", "table", "-timeout", "1s", "list", "-all"},
+			want: options{
+				Address: "http://localhost:3200",
+				RPC:     true,
+				HashKey: "secret",
+				Token:   "t",
+				Output:  outputTable,
+				Timeout: time.Second,
+			},
+			wantRest: []string{"list", "-all"},
+		},
+		{
+			name:    "wrong_output",
+			args:    []string{"-o", "yaml", "health"},
+			wantErr: ErrorWrongOutput,
+		},
+		{
+			name:    "help",
+			args:    []string{"-h"},
+			wantErr: flag.ErrHelp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, rest, err := parseOptions(tt.args, io.Discard)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+			assert.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /updates":
+			signedResponse(t, w, "secret", http.StatusOK, payload.ResponseBody{Status: payload.ResponseSuccessStatus})
+		case "DELETE /value/gauge/Alloc":
+			signedResponse(t, w, "secret", http.StatusOK, payload.ResponseBody{Status: payload.ResponseSuccessStatus, Message: "metric Alloc successfully deleted"})
+		case "POST /reset/counter/PollCount":
+			signedResponse(t, w, "secret", http.StatusNotFound, payload.ResponseBody{Status: payload.ResponseErrorStatus, Message: "metric not found"})
+		case "GET /ping":
+			sign, err := sendpool.HashBody(nil, "secret")
+			require.NoError(t, err)
+			w.Header().Set("HashSHA256", sign)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantOut string
+		wantErr error
+	}{
+		{name: "push", args: []string{"push", "gauge", "Alloc", "1.5"}, wantOut: "STATUS   MESSAGE\nsuccess  \n"},
+		{name: "delete", args: []string{"delete", "gauge", "Alloc"}, wantOut: "STATUS   MESSAGE\nsuccess  metric Alloc successfully deleted\n"},
+		{name: "reset_not_found", args: []string{"reset", "PollCount"}, wantErr: &ResponseError{}},
+		{name: "health", args: []string{"health"}, wantOut: "STATUS   MESSAGE\nsuccess  ok\n"},
+		{name: "wrong_arguments", args: []string{"push", "gauge"}, wantErr: ErrorWrongArguments},
+		{name: "no_command", args: []string{}, wantErr: ErrorNoCommand},
+		{name: "unknown_command", args: []string{"restart"}, wantErr: ErrorUnknownCommand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"-a", srv.URL, "-k", "secret", "-o", "table"}, tt.args...)
+			err := run(context.Background(), args, &out, io.Discard)
+			if tt.wantErr != nil {
+				if resErr, ok := tt.wantErr.(*ResponseError); ok {
+					assert.ErrorAs(t, err, &resErr)
+					return
+				}
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOut, out.String())
+		})
+	}
+}
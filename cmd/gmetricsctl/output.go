@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputJSON  = "json"  // вывод ответа сервера в JSON
+	outputTable = "table" // вывод таблицей
+)
+
+// table строки таблицы с заголовком
+type table struct {
+	header []string
+	rows   [][]string
+	footer string // строка после таблицы, например, курсор следующей страницы
+}
+
+// printer вывод результатов команд
+type printer struct {
+	out    io.Writer
+	warn   io.Writer // предупреждения, которые не должны попадать в результат
+	format string
+}
+
+// print выводит value в JSON или таблицу, построенную по value функцией toTable
+func (p printer) print(value any, toTable func() table) error {
+	if p.format == outputTable {
+		return p.printTable(toTable())
+	}
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printTable выводит таблицу с выровненными колонками
+func (p printer) printTable(t table) error {
+	writer := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, strings.Join(t.header, "\t")); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		if _, err := fmt.Fprintln(writer, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if t.footer != "" {
+		_, err := fmt.Fprintln(p.out, t.footer)
+		return err
+	}
+	return nil
+}
+
+// warnf выводит предупреждение
+func (p printer) warnf(format string, args ...any) {
+	fmt.Fprintf(p.warn, format+"\n", args...)
+}
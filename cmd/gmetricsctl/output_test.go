@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinter_print(t *testing.T) {
+	value := map[string]string{"status": "ok"}
+	toTable := func() table {
+		return table{
+			header: []string{"NAME", "VALUE"},
+			rows:   [][]string{{"Alloc", "1.5"}, {"HeapAlloc", "2"}},
+			footer: "next cursor: abc",
+		}
+	}
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{name: "json", format: outputJSON, want: "{\n  \"status\": \"ok\"\n}\n"},
+		{name: "table", format: outputTable, want: "NAME       VALUE\nAlloc      1.5\nHeapAlloc  2\nnext cursor: abc\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, printer{out: &out, format: tt.format}.print(value, toTable))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
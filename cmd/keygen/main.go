@@ -0,0 +1,67 @@
+// Генератор пары ключей RSA для шифрования тела запросов агента.
+//
+// Приватный ключ записывается в формате PKCS#8 (блок "PRIVATE KEY") и передаётся серверу через -crypto-key,
+// публичный ключ записывается в формате PKIX (блок "PUBLIC KEY") и передаётся агенту через -crypto-key.
+//
+// Пример:
+//
+//	keygen -bits 4096 -private deployments/keys/private.pem -public deployments/keys/public.pem
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gmetrics/internal/buildflags"
+	incnf "gmetrics/internal/config"
+	"log"
+	"os"
+)
+
+const (
+	// DefaultBits размер ключа по умолчанию
+	DefaultBits = 4096
+	// DefaultPrivatePath путь к приватному ключу по умолчанию
+	DefaultPrivatePath = "private.pem"
+	// DefaultPublicPath путь к публичному ключу по умолчанию
+	DefaultPublicPath = "public.pem"
+)
+
+func main() {
+	buildflags.PrintBuildInformation()
+	bits := flag.Int("bits", DefaultBits, "RSA key size in bits")
+	privatePath := flag.String("private", DefaultPrivatePath, "path to write the private key (server -crypto-key)")
+	publicPath := flag.String("public", DefaultPublicPath, "path to write the public key (agent -crypto-key)")
+	force := flag.Bool("force", false, "overwrite existing key files")
+	flag.Parse()
+
+	if err := generate(*bits, *privatePath, *publicPath, *force); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Private key written to %s, public key written to %s", *privatePath, *publicPath)
+}
+
+// generate создаёт пару ключей и записывает её в файлы.
+// Существующие файлы перезаписываются только с флагом force
+func generate(bits int, privatePath, publicPath string, force bool) error {
+	if privatePath == "" || publicPath == "" {
+		return incnf.ErrorEmptyKeyPath
+	}
+	if !force {
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, use -force to overwrite", path)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	key, err := incnf.GenerateKey(bits)
+	if err != nil {
+		return err
+	}
+	if err = incnf.WritePrivateKeyToFile(privatePath, key); err != nil {
+		return err
+	}
+	return incnf.WritePublicKeyToFile(publicPath, &key.PublicKey)
+}
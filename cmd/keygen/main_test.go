@@ -0,0 +1,82 @@
+package main
+
+import (
+	incnf "gmetrics/internal/config"
+	"gmetrics/internal/encrypt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		bits     int
+		existing bool
+		force    bool
+		emptyPub bool
+		wantErr  bool
+	}{
+		{
+			name: "new_keys",
+			bits: incnf.MinKeyBits,
+		},
+		{
+			name:     "existing_keys_without_force",
+			bits:     incnf.MinKeyBits,
+			existing: true,
+			wantErr:  true,
+		},
+		{
+			name:     "existing_keys_with_force",
+			bits:     incnf.MinKeyBits,
+			existing: true,
+			force:    true,
+		},
+		{
+			name:    "too_small_key",
+			bits:    512,
+			wantErr: true,
+		},
+		{
+			name:     "empty_path",
+			bits:     incnf.MinKeyBits,
+			emptyPub: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			privatePath := filepath.Join(dir, "private.pem")
+			publicPath := filepath.Join(dir, "public.pem")
+			if tt.existing {
+				require.NoError(t, os.WriteFile(privatePath, []byte("old"), 0600))
+			}
+			if tt.emptyPub {
+				publicPath = ""
+			}
+			err := generate(tt.bits, privatePath, publicPath, tt.force)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// Ключи должны загружаться теми же функциями, что используют сервер и агент
+			private, err := incnf.ParsePrivateKeyFromFile(privatePath)
+			require.NoError(t, err)
+			public, err := incnf.ParsePublicKeyFromFile(publicPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.bits, private.N.BitLen())
+
+			message := []byte(`[{"id":"Alloc","type":"gauge","value":1.5}]`)
+			encrypted, err := encrypt.Encrypt(message, public)
+			require.NoError(t, err)
+			assert.NotEqual(t, message, encrypted)
+		})
+	}
+}
@@ -2,6 +2,10 @@ package config
 
 import (
 	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"gmetrics/internal/auth"
+	"gmetrics/internal/validation"
 	"net"
 )
 
@@ -28,23 +32,92 @@ const (
 
 	// DefaultHashKey ключ шифрования по умолчанию
 	DefaultHashKey = ""
+
+	// DefaultRateLimit количество запросов в секунду от одного клиента по умолчанию; 0 - без ограничений
+	DefaultRateLimit float64 = 0
+	// DefaultRateBurst запас запросов клиента по умолчанию; 0 - равен DefaultRateLimit
+	DefaultRateBurst = 0
+	// DefaultMaxBodySize максимальный размер тела запроса по умолчанию
+	DefaultMaxBodySize int64 = 4 << 20
+	// DefaultMaxDecompressedSize максимальный размер разжатого тела запроса по умолчанию
+	DefaultMaxDecompressedSize int64 = 16 << 20
+	// DefaultMaxBatchSize максимальное количество метрик в одном запросе по умолчанию
+	DefaultMaxBatchSize = 10000
+	// DefaultAuditFileMaxSize размер файла аудита, после которого он ротируется, по умолчанию
+	DefaultAuditFileMaxSize int64 = 100 << 20
+	// DefaultAuditFileMaxBackups количество хранимых копий файла аудита по умолчанию
+	DefaultAuditFileMaxBackups = 5
+
+	// TTLModeHide устаревшие метрики скрываются из чтения, но остаются в хранилище до следующей записи
+	TTLModeHide = "hide"
+	// TTLModeDelete устаревшие метрики периодически удаляются из хранилища
+	TTLModeDelete = "delete"
+	// DefaultMetricTTL время устаревания метрик по умолчанию; 0 - не устаревают
+	DefaultMetricTTL int64 = 0
+	// DefaultMetricTTLMode что делать с устаревшими метриками по умолчанию
+	DefaultMetricTTLMode = TTLModeHide
+
+	// DefaultWriteBehindQueue сколько изменённых метрик может ждать записи в бд по умолчанию
+	DefaultWriteBehindQueue = 10000
+	// DefaultWriteBehindInterval период записи изменённых метрик в бд в миллисекундах по умолчанию
+	DefaultWriteBehindInterval int64 = 1000
 )
 
+// ErrorWrongTTLMode ошибка, что указан неизвестный режим устаревания метрик
+var ErrorWrongTTLMode = errors.New("metric ttl mode must be hide or delete")
+
+// ErrorWrongWriteBehind ошибка, что размер очереди или период записи в фоне не положительные
+var ErrorWrongWriteBehind = errors.New("write behind queue and interval must be positive")
+
 // CliConfig конфигурация сервера из командной строки
 type CliConfig struct {
-	Address          string          `env:"ADDRESS"`        // адрес сервера
-	RPCAddress       string          `env:"RPC_ADDRESS"`    // адрес сервера
-	LogLevel         string          `env:"LOG_LEVEL"`      // Уровень логирования
-	FileStorage      string          `env:"FILE_STORAGE"`   // Путь к хранению файлов, если не указан, то будет создано обычное хранилище в памяти
-	DatabaseDSN      string          `env:"DATABASE_DSN"`   // подключение к базе данных
-	HashKey          string          `env:"KEY"`            // Ключ для шифрования
-	StoreInterval    int64           `env:"STORE_INTERVAL"` // период сохранения метрик в файл; 0 - синхронный режим
-	Restore          bool            `env:"RESTORE"`        // Надобность загрузки старых данных из файла при включении
-	CryptoKeyPath    string          `env:"CRYPTO_KEY"`     // Путь к файлу с приватным ключом
-	CryptoKey        *rsa.PrivateKey // Приватный ключ для дешифрования тела запроса
-	ConfigFilePath   string          `env:"CONFIG"`         // Путь к файлу с конфигурацией
-	TrustedSubnetStr string          `env:"TRUSTED_SUBNET"` // CIDR адрес подсети, запросы из которого будут обрабатываться
-	TrustedSubnet    *net.IPNet      // Доверенная подсеть
+	Address             string              `env:"ADDRESS"`        // адрес сервера
+	RPCAddress          string              `env:"RPC_ADDRESS"`    // адрес сервера
+	LogLevel            string              `env:"LOG_LEVEL"`      // Уровень логирования
+	FileStorage         string              `env:"FILE_STORAGE"`   // Путь к хранению файлов, если не указан, то будет создано обычное хранилище в памяти
+	DatabaseDSN         string              `env:"DATABASE_DSN"`   // подключение к базе данных
+	HashKey             string              `env:"KEY"`            // Ключ для шифрования
+	StoreInterval       int64               `env:"STORE_INTERVAL"` // период сохранения метрик в файл; 0 - синхронный режим
+	Restore             bool                `env:"RESTORE"`        // Надобность загрузки старых данных из файла при включении
+	CryptoKeyPath       string              `env:"CRYPTO_KEY"`     // Путь к файлу с приватным ключом
+	CryptoKey           *rsa.PrivateKey     // Приватный ключ для дешифрования тела запроса
+	ConfigFilePath      string              `env:"CONFIG"`         // Путь к файлу с конфигурацией
+	TrustedSubnetStr    string              `env:"TRUSTED_SUBNET"` // CIDR адрес подсети, запросы из которого будут обрабатываться
+	TrustedSubnet       *net.IPNet          // Доверенная подсеть
+	TLSCertPath         string              `env:"TLS_CERT"`      // Путь к сертификату сервера
+	TLSKeyPath          string              `env:"TLS_KEY"`       // Путь к приватному ключу сертификата сервера
+	TLSClientCAPath     string              `env:"TLS_CLIENT_CA"` // Путь к сертификату центра, которым подписаны сертификаты агентов. Включает mTLS
+	TLSConfig           *tls.Config         // Конфигурация TLS для http и rpc серверов, если nil, то сервера работают без шифрования
+	Tokens              string              `env:"TOKENS"`      // Токены доступа в формате name:token:scope[|scope], через запятую
+	TokensFile          string              `env:"TOKENS_FILE"` // Путь к JSON файлу с токенами доступа
+	Authenticator       *auth.Authenticator // Проверка токенов доступа, если токенов нет, то проверка отключена
+	RateLimit           float64             `env:"RATE_LIMIT"`             // Количество запросов в секунду от одного клиента; 0 - без ограничений
+	RateBurst           int                 `env:"RATE_BURST"`             // Запас запросов клиента сверх RateLimit
+	MaxBodySize         int64               `env:"MAX_BODY_SIZE"`          // Максимальный размер тела запроса в байтах; 0 - без ограничений
+	MaxDecompressedSize int64               `env:"MAX_DECOMPRESSED_SIZE"`  // Максимальный размер разжатого тела запроса в байтах; 0 - без ограничений
+	MaxBatchSize        int                 `env:"MAX_BATCH_SIZE"`         // Максимальное количество метрик в одном запросе; 0 - без ограничений
+	AuditFile           string              `env:"AUDIT_FILE"`             // Путь к файлу журнала аудита в формате JSON lines
+	AuditFileMaxSize    int64               `env:"AUDIT_FILE_MAX_SIZE"`    // Размер файла аудита в байтах, после которого он ротируется; 0 - без ротации
+	AuditFileMaxBackups int                 `env:"AUDIT_FILE_MAX_BACKUPS"` // Количество хранимых копий файла аудита
+	AuditURL            string              `env:"AUDIT_URL"`              // Адрес, на который отправляются события аудита
+	MetricTTL           int64               `env:"METRIC_TTL"`             // Время в секундах после последнего обновления, через которое метрика устаревает; 0 - не устаревают
+	MetricTTLMode       string              `env:"METRIC_TTL_MODE"`        // Что делать с устаревшими метриками: hide или delete
+	WriteBehind         bool                `env:"WRITE_BEHIND"`           // Записывать изменения в бд в фоне, а не в запросе
+	WriteBehindQueue    int                 `env:"WRITE_BEHIND_QUEUE"`     // Сколько изменённых метрик может ждать записи в бд
+	WriteBehindInterval int64               `env:"WRITE_BEHIND_INTERVAL"`  // Период записи изменённых метрик в бд в миллисекундах
+	MemShards           int                 `env:"MEM_SHARDS"`             // На сколько шардов делить хранилище в памяти; 0 - одна общая блокировка
+	MetricNameRegex     string              `env:"METRIC_NAME_REGEX"`      // Регулярное выражение для базового имени метрики; пустое - любое имя
+	MaxMetricNameLength int                 `env:"MAX_METRIC_NAME_LENGTH"` // Наибольшая длина имени метрики в байтах; 0 - без ограничений
+	RejectNonFinite     bool                `env:"REJECT_NON_FINITE"`      // Отклонять NaN и бесконечности в значениях метрик
+	RejectNegativeDelta bool                `env:"REJECT_NEGATIVE_DELTA"`  // Отклонять отрицательное приращение counter
+	AllowedMetricNames  string              `env:"ALLOWED_METRIC_NAMES"`   // Разрешённые клиентам имена в формате client:name|prefix*, через запятую
+	Validation          *validation.Policy  // Политика проверки метрик, собранная из параметров выше
+	MaxSeries           int                 `env:"MAX_SERIES"`        // Наибольшее количество рядов метрик на сервере; 0 - без ограничений
+	MaxClientSeries     int                 `env:"MAX_CLIENT_SERIES"` // Наибольшее количество рядов, которые может создать один клиент; 0 - без ограничений
+	Tenants             string              `env:"TENANTS"`           // Тенанты клиентов в формате client:tenant, через запятую
+	TenantMaxSeries     string              `env:"TENANT_MAX_SERIES"` // Лимиты рядов тенантов в формате tenant:limit, через запятую; вместо MaxSeries
+	TenantClients       map[string]string   // Тенанты клиентов, собранные из Tenants
+	TenantSeriesLimits  map[string]int      // Лимиты рядов тенантов, собранные из TenantMaxSeries
 }
 
 // Params конфигурация приложения
@@ -53,13 +126,24 @@ var Params *CliConfig
 // InitializeDefaultConfig инициализация конфигурации приложения
 func InitializeDefaultConfig() *CliConfig {
 	return &CliConfig{
-		Address:       DefaultServerURL,
-		LogLevel:      DefaultLogLevel,
-		FileStorage:   DefaultFilePath,
-		Restore:       DefaultRestore,
-		StoreInterval: DefaultStoreInterval,
-		DatabaseDSN:   DefaultDatabaseDSN,
-		HashKey:       DefaultHashKey,
-		RPCAddress:    DefaultRPCServerURL,
+		Address:             DefaultServerURL,
+		LogLevel:            DefaultLogLevel,
+		FileStorage:         DefaultFilePath,
+		Restore:             DefaultRestore,
+		StoreInterval:       DefaultStoreInterval,
+		DatabaseDSN:         DefaultDatabaseDSN,
+		HashKey:             DefaultHashKey,
+		RPCAddress:          DefaultRPCServerURL,
+		RateLimit:           DefaultRateLimit,
+		RateBurst:           DefaultRateBurst,
+		MaxBodySize:         DefaultMaxBodySize,
+		MaxDecompressedSize: DefaultMaxDecompressedSize,
+		MaxBatchSize:        DefaultMaxBatchSize,
+		AuditFileMaxSize:    DefaultAuditFileMaxSize,
+		AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+		MetricTTL:           DefaultMetricTTL,
+		MetricTTLMode:       DefaultMetricTTLMode,
+		WriteBehindQueue:    DefaultWriteBehindQueue,
+		WriteBehindInterval: DefaultWriteBehindInterval,
 	}
 }
@@ -20,13 +20,23 @@ func TestInitializeDefaultConfig(t *testing.T) {
 			cliInput: []string{},
 			envInput: map[string]string{},
 			expected: &CliConfig{
-				Address:       DefaultServerURL,
-				LogLevel:      DefaultLogLevel,
-				FileStorage:   DefaultFilePath,
-				DatabaseDSN:   DefaultDatabaseDSN,
-				StoreInterval: DefaultStoreInterval,
-				Restore:       DefaultRestore,
-				HashKey:       DefaultHashKey,
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				DatabaseDSN:         DefaultDatabaseDSN,
+				StoreInterval:       DefaultStoreInterval,
+				Restore:             DefaultRestore,
+				HashKey:             DefaultHashKey,
+				RateLimit:           DefaultRateLimit,
+				RateBurst:           DefaultRateBurst,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
 			},
 			expectedCliErr: nil,
 			expectedEnvErr: nil,
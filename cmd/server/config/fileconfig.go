@@ -5,12 +5,41 @@ import (
 )
 
 type FileConfig struct {
-	Address       string         `json:"address"`
-	RPCAddress    string         `json:"rpc_address"`
-	Restore       bool           `json:"restore"`
-	StoreInterval incnf.Duration `json:"store_interval"`
-	StoreFile     string         `json:"store_file"`
-	DatabaseDsn   string         `json:"database_dsn"`
-	CryptoKey     string         `json:"crypto_key"`
-	TrustedSubnet string         `json:"trusted_subnet"`
+	Address             string         `json:"address"`
+	RPCAddress          string         `json:"rpc_address"`
+	Restore             bool           `json:"restore"`
+	StoreInterval       incnf.Duration `json:"store_interval"`
+	StoreFile           string         `json:"store_file"`
+	DatabaseDsn         string         `json:"database_dsn"`
+	CryptoKey           string         `json:"crypto_key"`
+	TrustedSubnet       string         `json:"trusted_subnet"`
+	TLSCert             string         `json:"tls_cert"`
+	TLSKey              string         `json:"tls_key"`
+	TLSClientCA         string         `json:"tls_client_ca"`
+	Tokens              string         `json:"tokens"`
+	TokensFile          string         `json:"tokens_file"`
+	RateLimit           float64        `json:"rate_limit"`
+	RateBurst           int            `json:"rate_burst"`
+	MaxBodySize         int64          `json:"max_body_size"`
+	MaxDecompressedSize int64          `json:"max_decompressed_size"`
+	MaxBatchSize        int            `json:"max_batch_size"`
+	AuditFile           string         `json:"audit_file"`
+	AuditFileMaxSize    int64          `json:"audit_file_max_size"`
+	AuditFileMaxBackups int            `json:"audit_file_max_backups"`
+	AuditURL            string         `json:"audit_url"`
+	MetricTTL           incnf.Duration `json:"metric_ttl"`
+	MetricTTLMode       string         `json:"metric_ttl_mode"`
+	WriteBehind         bool           `json:"write_behind"`
+	WriteBehindQueue    int            `json:"write_behind_queue"`
+	WriteBehindInterval incnf.Duration `json:"write_behind_interval"`
+	MemShards           int            `json:"mem_shards"`
+	MetricNameRegex     string         `json:"metric_name_regex"`
+	MaxMetricNameLength int            `json:"max_metric_name_length"`
+	RejectNonFinite     bool           `json:"reject_non_finite"`
+	RejectNegativeDelta bool           `json:"reject_negative_delta"`
+	AllowedMetricNames  string         `json:"allowed_metric_names"`
+	MaxSeries           int            `json:"max_series"`
+	MaxClientSeries     int            `json:"max_client_series"`
+	Tenants             string         `json:"tenants"`
+	TenantMaxSeries     string         `json:"tenant_max_series"`
 }
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"gmetrics/internal/auth"
 	incnf "gmetrics/internal/config"
+	"gmetrics/internal/tenant"
+	"gmetrics/internal/validation"
 	"net"
 	"os"
 
@@ -44,6 +47,35 @@ func Parse() (*CliConfig, error) {
 		cnf.TrustedSubnet = network
 	}
 
+	tlsConfig, err := incnf.NewServerTLSConfig(cnf.TLSCertPath, cnf.TLSKeyPath, cnf.TLSClientCAPath)
+	if err != nil {
+		return nil, err
+	}
+	cnf.TLSConfig = tlsConfig
+
+	if cnf.MetricTTLMode != TTLModeHide && cnf.MetricTTLMode != TTLModeDelete {
+		return nil, ErrorWrongTTLMode
+	}
+	if cnf.WriteBehind && (cnf.WriteBehindQueue <= 0 || cnf.WriteBehindInterval <= 0) {
+		return nil, ErrorWrongWriteBehind
+	}
+
+	authenticator, err := parseTokens(cnf.Tokens, cnf.TokensFile)
+	if err != nil {
+		return nil, err
+	}
+	cnf.Authenticator = authenticator
+
+	policy, err := parsePolicy(cnf)
+	if err != nil {
+		return nil, err
+	}
+	cnf.Validation = policy
+
+	if cnf.TenantClients, cnf.TenantSeriesLimits, err = parseTenants(cnf.Tenants, cnf.TenantMaxSeries); err != nil {
+		return nil, err
+	}
+
 	return cnf, nil
 }
 
@@ -88,6 +120,93 @@ func parseFromEnv(params *CliConfig) error {
 	if cnf.TrustedSubnetStr != "" {
 		params.TrustedSubnetStr = cnf.TrustedSubnetStr
 	}
+	if cnf.TLSCertPath != "" {
+		params.TLSCertPath = cnf.TLSCertPath
+	}
+	if cnf.TLSKeyPath != "" {
+		params.TLSKeyPath = cnf.TLSKeyPath
+	}
+	if cnf.TLSClientCAPath != "" {
+		params.TLSClientCAPath = cnf.TLSClientCAPath
+	}
+	if cnf.Tokens != "" {
+		params.Tokens = cnf.Tokens
+	}
+	if cnf.TokensFile != "" {
+		params.TokensFile = cnf.TokensFile
+	}
+	if _, ok := os.LookupEnv("RATE_LIMIT"); ok {
+		params.RateLimit = cnf.RateLimit
+	}
+	if _, ok := os.LookupEnv("RATE_BURST"); ok {
+		params.RateBurst = cnf.RateBurst
+	}
+	if _, ok := os.LookupEnv("MAX_BODY_SIZE"); ok {
+		params.MaxBodySize = cnf.MaxBodySize
+	}
+	if _, ok := os.LookupEnv("MAX_DECOMPRESSED_SIZE"); ok {
+		params.MaxDecompressedSize = cnf.MaxDecompressedSize
+	}
+	if _, ok := os.LookupEnv("MAX_BATCH_SIZE"); ok {
+		params.MaxBatchSize = cnf.MaxBatchSize
+	}
+	if cnf.AuditFile != "" {
+		params.AuditFile = cnf.AuditFile
+	}
+	if _, ok := os.LookupEnv("AUDIT_FILE_MAX_SIZE"); ok {
+		params.AuditFileMaxSize = cnf.AuditFileMaxSize
+	}
+	if _, ok := os.LookupEnv("AUDIT_FILE_MAX_BACKUPS"); ok {
+		params.AuditFileMaxBackups = cnf.AuditFileMaxBackups
+	}
+	if cnf.AuditURL != "" {
+		params.AuditURL = cnf.AuditURL
+	}
+	if _, ok := os.LookupEnv("METRIC_TTL"); ok {
+		params.MetricTTL = cnf.MetricTTL
+	}
+	if cnf.MetricTTLMode != "" {
+		params.MetricTTLMode = cnf.MetricTTLMode
+	}
+	if _, ok := os.LookupEnv("WRITE_BEHIND"); ok {
+		params.WriteBehind = cnf.WriteBehind
+	}
+	if _, ok := os.LookupEnv("WRITE_BEHIND_QUEUE"); ok {
+		params.WriteBehindQueue = cnf.WriteBehindQueue
+	}
+	if _, ok := os.LookupEnv("WRITE_BEHIND_INTERVAL"); ok {
+		params.WriteBehindInterval = cnf.WriteBehindInterval
+	}
+	if _, ok := os.LookupEnv("MEM_SHARDS"); ok {
+		params.MemShards = cnf.MemShards
+	}
+	if cnf.MetricNameRegex != "" {
+		params.MetricNameRegex = cnf.MetricNameRegex
+	}
+	if _, ok := os.LookupEnv("MAX_METRIC_NAME_LENGTH"); ok {
+		params.MaxMetricNameLength = cnf.MaxMetricNameLength
+	}
+	if _, ok := os.LookupEnv("REJECT_NON_FINITE"); ok {
+		params.RejectNonFinite = cnf.RejectNonFinite
+	}
+	if _, ok := os.LookupEnv("REJECT_NEGATIVE_DELTA"); ok {
+		params.RejectNegativeDelta = cnf.RejectNegativeDelta
+	}
+	if cnf.AllowedMetricNames != "" {
+		params.AllowedMetricNames = cnf.AllowedMetricNames
+	}
+	if _, ok := os.LookupEnv("MAX_SERIES"); ok {
+		params.MaxSeries = cnf.MaxSeries
+	}
+	if _, ok := os.LookupEnv("MAX_CLIENT_SERIES"); ok {
+		params.MaxClientSeries = cnf.MaxClientSeries
+	}
+	if cnf.Tenants != "" {
+		params.Tenants = cnf.Tenants
+	}
+	if cnf.TenantMaxSeries != "" {
+		params.TenantMaxSeries = cnf.TenantMaxSeries
+	}
 	return nil
 }
 
@@ -98,7 +217,7 @@ func parseFromCli(cnf *CliConfig) (parseError error) {
 	flag.StringVar(&cnf.RPCAddress, "ra", DefaultRPCServerURL, "address and port to run rpc server")
 	flag.StringVar(&cnf.LogLevel, "ll", DefaultLogLevel, "level of logging")
 	flag.StringVar(&cnf.FileStorage, "f", DefaultFilePath, "store file path")
-	flag.StringVar(&cnf.DatabaseDSN, "d", DefaultDatabaseDSN, "database connection")
+	flag.StringVar(&cnf.DatabaseDSN, "d", DefaultDatabaseDSN, "database connection: Postgres DSN or sqlite:///path/to/gmetrics.db")
 	flag.Int64Var(&cnf.StoreInterval, "i", DefaultStoreInterval, "frequency of save metrics. 0 is sync mode")
 	flag.BoolVar(&cnf.Restore, "r", DefaultRestore, "need to restore")
 	flag.StringVar(&cnf.HashKey, "k", DefaultHashKey, "encrypted key")
@@ -106,6 +225,35 @@ func parseFromCli(cnf *CliConfig) (parseError error) {
 	flag.StringVar(&cnf.ConfigFilePath, "c", "", "Path to the configuration file (shorthand)")
 	flag.StringVar(&cnf.ConfigFilePath, "config", "", "Path to the configuration file")
 	flag.StringVar(&cnf.TrustedSubnetStr, "t", "", "Trusted subnet for updated metrics")
+	flag.StringVar(&cnf.TLSCertPath, "tls-cert", "", "Path to the server TLS certificate")
+	flag.StringVar(&cnf.TLSKeyPath, "tls-key", "", "Path to the server TLS private key")
+	flag.StringVar(&cnf.TLSClientCAPath, "tls-client-ca", "", "Path to the CA certificate for verifying agent certificates (enables mTLS)")
+	flag.StringVar(&cnf.Tokens, "tokens", "", "Access tokens in format name:token:scope[|scope], comma separated")
+	flag.IntVar(&cnf.MaxSeries, "max-series", 0, "Maximum number of metric series on the server. 0 is unlimited")
+	flag.IntVar(&cnf.MaxClientSeries, "max-client-series", 0, "Maximum number of metric series one client can create. 0 is unlimited")
+	flag.StringVar(&cnf.TokensFile, "tokens-file", "", "Path to the JSON file with access tokens")
+	flag.Float64Var(&cnf.RateLimit, "rate-limit", DefaultRateLimit, "Requests per second allowed for each client. 0 is unlimited")
+	flag.IntVar(&cnf.RateBurst, "rate-burst", DefaultRateBurst, "Requests a client can make at once above the rate limit")
+	flag.Int64Var(&cnf.MaxBodySize, "max-body-size", DefaultMaxBodySize, "Maximum request body size in bytes. 0 is unlimited")
+	flag.Int64Var(&cnf.MaxDecompressedSize, "max-decompressed-size", DefaultMaxDecompressedSize, "Maximum decompressed request body size in bytes. 0 is unlimited")
+	flag.IntVar(&cnf.MaxBatchSize, "max-batch-size", DefaultMaxBatchSize, "Maximum number of metrics in one request. 0 is unlimited")
+	flag.StringVar(&cnf.AuditFile, "audit-file", "", "Path to the audit log file in JSON lines format")
+	flag.Int64Var(&cnf.AuditFileMaxSize, "audit-file-max-size", DefaultAuditFileMaxSize, "Audit log file size in bytes that triggers rotation. 0 disables rotation")
+	flag.IntVar(&cnf.AuditFileMaxBackups, "audit-file-max-backups", DefaultAuditFileMaxBackups, "Number of rotated audit log files to keep")
+	flag.StringVar(&cnf.AuditURL, "audit-url", "", "URL to POST audit events to")
+	flag.Int64Var(&cnf.MetricTTL, "metric-ttl", DefaultMetricTTL, "Seconds after the last update when a metric expires. 0 disables expiry")
+	flag.StringVar(&cnf.MetricTTLMode, "metric-ttl-mode", DefaultMetricTTLMode, "What to do with expired metrics: hide or delete")
+	flag.BoolVar(&cnf.WriteBehind, "write-behind", false, "Write changed metrics to the database in the background instead of in the request")
+	flag.IntVar(&cnf.WriteBehindQueue, "write-behind-queue", DefaultWriteBehindQueue, "Number of changed metrics that can wait for the background write")
+	flag.Int64Var(&cnf.WriteBehindInterval, "write-behind-interval", DefaultWriteBehindInterval, "Milliseconds between background writes of changed metrics")
+	flag.IntVar(&cnf.MemShards, "mem-shards", 0, "Number of lock shards of the in-memory storage. 0 uses a single lock")
+	flag.StringVar(&cnf.MetricNameRegex, "metric-name-regex", "", "Regular expression the metric name without labels must match. Empty allows any name")
+	flag.IntVar(&cnf.MaxMetricNameLength, "max-metric-name-length", 0, "Maximum metric name length in bytes. 0 is unlimited")
+	flag.BoolVar(&cnf.RejectNonFinite, "reject-non-finite", false, "Reject NaN and infinite metric values")
+	flag.BoolVar(&cnf.RejectNegativeDelta, "reject-negative-delta", false, "Reject negative counter deltas")
+	flag.StringVar(&cnf.AllowedMetricNames, "allowed-metric-names", "", "Metric names allowed for clients in format client:name|prefix*, comma separated")
+	flag.StringVar(&cnf.Tenants, "tenants", "", "Tenants of clients in format client:tenant, comma separated. Each tenant has its own metrics")
+	flag.StringVar(&cnf.TenantMaxSeries, "tenant-max-series", "", "Maximum number of metric series of tenants in format tenant:limit, comma separated")
 
 	// Парсим переданные серверу аргументы в зарегистрированные переменные
 	flag.Parse() // Сейчас будет выход из приложения, поэтому код ниже не будет исполнен, но может пригодиться в будущем, если поменять флаг выхода или будет несколько сетов
@@ -152,6 +300,93 @@ func parseFromFile(cnf *CliConfig) error {
 	if fileConf.TrustedSubnet != "" && cnf.TrustedSubnetStr == "" {
 		cnf.TrustedSubnetStr = fileConf.TrustedSubnet
 	}
+	if fileConf.TLSCert != "" && cnf.TLSCertPath == "" {
+		cnf.TLSCertPath = fileConf.TLSCert
+	}
+	if fileConf.TLSKey != "" && cnf.TLSKeyPath == "" {
+		cnf.TLSKeyPath = fileConf.TLSKey
+	}
+	if fileConf.TLSClientCA != "" && cnf.TLSClientCAPath == "" {
+		cnf.TLSClientCAPath = fileConf.TLSClientCA
+	}
+	if fileConf.Tokens != "" && cnf.Tokens == "" {
+		cnf.Tokens = fileConf.Tokens
+	}
+	if fileConf.TokensFile != "" && cnf.TokensFile == "" {
+		cnf.TokensFile = fileConf.TokensFile
+	}
+	if fileConf.RateLimit != 0 && cnf.RateLimit == DefaultRateLimit {
+		cnf.RateLimit = fileConf.RateLimit
+	}
+	if fileConf.RateBurst != 0 && cnf.RateBurst == DefaultRateBurst {
+		cnf.RateBurst = fileConf.RateBurst
+	}
+	if fileConf.MaxBodySize != 0 && cnf.MaxBodySize == DefaultMaxBodySize {
+		cnf.MaxBodySize = fileConf.MaxBodySize
+	}
+	if fileConf.MaxDecompressedSize != 0 && cnf.MaxDecompressedSize == DefaultMaxDecompressedSize {
+		cnf.MaxDecompressedSize = fileConf.MaxDecompressedSize
+	}
+	if fileConf.MaxBatchSize != 0 && cnf.MaxBatchSize == DefaultMaxBatchSize {
+		cnf.MaxBatchSize = fileConf.MaxBatchSize
+	}
+	if fileConf.AuditFile != "" && cnf.AuditFile == "" {
+		cnf.AuditFile = fileConf.AuditFile
+	}
+	if fileConf.AuditFileMaxSize != 0 && cnf.AuditFileMaxSize == DefaultAuditFileMaxSize {
+		cnf.AuditFileMaxSize = fileConf.AuditFileMaxSize
+	}
+	if fileConf.AuditFileMaxBackups != 0 && cnf.AuditFileMaxBackups == DefaultAuditFileMaxBackups {
+		cnf.AuditFileMaxBackups = fileConf.AuditFileMaxBackups
+	}
+	if fileConf.AuditURL != "" && cnf.AuditURL == "" {
+		cnf.AuditURL = fileConf.AuditURL
+	}
+	if fileConf.MetricTTL.Duration != 0 && cnf.MetricTTL == DefaultMetricTTL {
+		cnf.MetricTTL = int64(fileConf.MetricTTL.Seconds())
+	}
+	if fileConf.MetricTTLMode != "" && cnf.MetricTTLMode == DefaultMetricTTLMode {
+		cnf.MetricTTLMode = fileConf.MetricTTLMode
+	}
+	if fileConf.WriteBehind && !cnf.WriteBehind {
+		cnf.WriteBehind = fileConf.WriteBehind
+	}
+	if fileConf.WriteBehindQueue != 0 && cnf.WriteBehindQueue == DefaultWriteBehindQueue {
+		cnf.WriteBehindQueue = fileConf.WriteBehindQueue
+	}
+	if fileConf.WriteBehindInterval.Duration != 0 && cnf.WriteBehindInterval == DefaultWriteBehindInterval {
+		cnf.WriteBehindInterval = fileConf.WriteBehindInterval.Milliseconds()
+	}
+	if fileConf.MemShards != 0 && cnf.MemShards == 0 {
+		cnf.MemShards = fileConf.MemShards
+	}
+	if fileConf.MetricNameRegex != "" && cnf.MetricNameRegex == "" {
+		cnf.MetricNameRegex = fileConf.MetricNameRegex
+	}
+	if fileConf.MaxMetricNameLength != 0 && cnf.MaxMetricNameLength == 0 {
+		cnf.MaxMetricNameLength = fileConf.MaxMetricNameLength
+	}
+	if fileConf.RejectNonFinite && !cnf.RejectNonFinite {
+		cnf.RejectNonFinite = fileConf.RejectNonFinite
+	}
+	if fileConf.RejectNegativeDelta && !cnf.RejectNegativeDelta {
+		cnf.RejectNegativeDelta = fileConf.RejectNegativeDelta
+	}
+	if fileConf.AllowedMetricNames != "" && cnf.AllowedMetricNames == "" {
+		cnf.AllowedMetricNames = fileConf.AllowedMetricNames
+	}
+	if fileConf.MaxSeries != 0 && cnf.MaxSeries == 0 {
+		cnf.MaxSeries = fileConf.MaxSeries
+	}
+	if fileConf.MaxClientSeries != 0 && cnf.MaxClientSeries == 0 {
+		cnf.MaxClientSeries = fileConf.MaxClientSeries
+	}
+	if fileConf.Tenants != "" && cnf.Tenants == "" {
+		cnf.Tenants = fileConf.Tenants
+	}
+	if fileConf.TenantMaxSeries != "" && cnf.TenantMaxSeries == "" {
+		cnf.TenantMaxSeries = fileConf.TenantMaxSeries
+	}
 	return nil
 }
 
@@ -166,3 +401,48 @@ func parseSubnet(subnet string) (*net.IPNet, error) {
 	}
 	return network, nil
 }
+
+// parseTokens собираем токены доступа из строки конфигурации и файла
+func parseTokens(tokens, tokensFile string) (*auth.Authenticator, error) {
+	inline, err := auth.ParseTokens(tokens)
+	if err != nil {
+		return nil, err
+	}
+	fromFile, err := auth.ParseTokensFile(tokensFile)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewAuthenticator(append(inline, fromFile...))
+}
+
+// parsePolicy собираем политику проверки метрик из конфигурации
+func parsePolicy(cnf *CliConfig) (*validation.Policy, error) {
+	allowed, err := validation.ParseAllowedNames(cnf.AllowedMetricNames)
+	if err != nil {
+		return nil, err
+	}
+	return validation.NewPolicy(cnf.MetricNameRegex, cnf.MaxMetricNameLength, cnf.RejectNonFinite, cnf.RejectNegativeDelta, allowed)
+}
+
+// parseTenants собираем тенанты клиентов и лимиты рядов тенантов из конфигурации.
+// Лимит можно задать только тенанту, у которого есть клиенты
+func parseTenants(tenants, maxSeries string) (map[string]string, map[string]int, error) {
+	clients, err := tenant.ParseClients(tenants)
+	if err != nil {
+		return nil, nil, err
+	}
+	limits, err := tenant.ParseSeriesLimits(maxSeries)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]bool, len(clients))
+	for _, name := range clients {
+		known[name] = true
+	}
+	for name := range limits {
+		if !known[name] {
+			return nil, nil, tenant.ErrorWrongTenantSeries
+		}
+	}
+	return clients, limits, nil
+}
@@ -4,6 +4,9 @@ package config
 import (
 	"flag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gmetrics/internal/tenant"
+	"gmetrics/internal/validation"
 	"os"
 	"testing"
 )
@@ -23,26 +26,201 @@ func TestParseFromCli(t *testing.T) {
 			name:  "empty",
 			input: []string{},
 			expected: &CliConfig{
-				Address:       DefaultServerURL,
-				LogLevel:      DefaultLogLevel,
-				FileStorage:   DefaultFilePath,
-				DatabaseDSN:   DefaultDatabaseDSN,
-				StoreInterval: DefaultStoreInterval,
-				Restore:       DefaultRestore,
-				HashKey:       DefaultHashKey,
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				DatabaseDSN:         DefaultDatabaseDSN,
+				StoreInterval:       DefaultStoreInterval,
+				Restore:             DefaultRestore,
+				HashKey:             DefaultHashKey,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
 			},
 		},
 		{
 			name:  "flags_passed",
 			input: []string{"-a=someAddress", "-ll=loglevel", "-f=path", "-d=dsn", "-i=1", "-r=true", "-k=key"},
 			expected: &CliConfig{
-				Address:       "someAddress",
-				LogLevel:      "loglevel",
-				FileStorage:   "path",
-				DatabaseDSN:   "dsn",
-				StoreInterval: 1,
-				Restore:       true,
-				HashKey:       "key",
+				Address:             "someAddress",
+				LogLevel:            "loglevel",
+				FileStorage:         "path",
+				DatabaseDSN:         "dsn",
+				StoreInterval:       1,
+				Restore:             true,
+				HashKey:             "key",
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+		},
+		{
+			name:  "limit_flags_passed",
+			input: []string{"-rate-limit=2.5", "-rate-burst=10", "-max-body-size=1024", "-max-decompressed-size=0", "-max-batch-size=100"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				RateLimit:           2.5,
+				RateBurst:           10,
+				MaxBodySize:         1024,
+				MaxBatchSize:        100,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+		},
+		{
+			name:  "audit_flags_passed",
+			input: []string{"-audit-file=audit.log", "-audit-file-max-size=0", "-audit-file-max-backups=2", "-audit-url=http://audit.local/events"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFile:           "audit.log",
+				AuditFileMaxSize:    0,
+				AuditFileMaxBackups: 2,
+				AuditURL:            "http://audit.local/events",
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+		},
+		{
+			name:  "metric_ttl_flags_passed",
+			input: []string{"-metric-ttl=60", "-metric-ttl-mode=delete"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTL:           60,
+				MetricTTLMode:       TTLModeDelete,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+		},
+		{
+			name:  "write_behind_flags_passed",
+			input: []string{"-write-behind", "-write-behind-queue=100", "-write-behind-interval=250"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehind:         true,
+				WriteBehindQueue:    100,
+				WriteBehindInterval: 250,
+			},
+		},
+		{
+			name:  "mem_shards_flag_passed",
+			input: []string{"-mem-shards=16"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				MemShards:           16,
+			},
+		},
+		{
+			name:  "validation_flags_passed",
+			input: []string{"-metric-name-regex=[A-Za-z]+", "-max-metric-name-length=64", "-reject-non-finite", "-reject-negative-delta", "-allowed-metric-names=agent:Alloc|Heap*"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				MetricNameRegex:     "[A-Za-z]+",
+				MaxMetricNameLength: 64,
+				RejectNonFinite:     true,
+				RejectNegativeDelta: true,
+				AllowedMetricNames:  "agent:Alloc|Heap*",
+			},
+		},
+		{
+			name:  "series_limits_passed",
+			input: []string{"-max-series=1000", "-max-client-series=100"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				MaxSeries:           1000,
+				MaxClientSeries:     100,
+			},
+		},
+		{
+			name:  "tenants_passed",
+			input: []string{"-tenants=agent-1:team-a,agent-2:team-b", "-tenant-max-series=team-a:1000"},
+			expected: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				StoreInterval:       DefaultStoreInterval,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				Tenants:             "agent-1:team-a,agent-2:team-b",
+				TenantMaxSeries:     "team-a:1000",
 			},
 		},
 	}
@@ -67,7 +245,36 @@ func compareConfigs(expected, actual *CliConfig) bool {
 		expected.DatabaseDSN != actual.DatabaseDSN ||
 		expected.StoreInterval != actual.StoreInterval ||
 		expected.Restore != actual.Restore ||
-		expected.HashKey != actual.HashKey {
+		expected.HashKey != actual.HashKey ||
+		expected.TLSCertPath != actual.TLSCertPath ||
+		expected.TLSKeyPath != actual.TLSKeyPath ||
+		expected.TLSClientCAPath != actual.TLSClientCAPath ||
+		expected.Tokens != actual.Tokens ||
+		expected.TokensFile != actual.TokensFile ||
+		expected.RateLimit != actual.RateLimit ||
+		expected.RateBurst != actual.RateBurst ||
+		expected.MaxBodySize != actual.MaxBodySize ||
+		expected.MaxDecompressedSize != actual.MaxDecompressedSize ||
+		expected.MaxBatchSize != actual.MaxBatchSize ||
+		expected.AuditFile != actual.AuditFile ||
+		expected.AuditFileMaxSize != actual.AuditFileMaxSize ||
+		expected.AuditFileMaxBackups != actual.AuditFileMaxBackups ||
+		expected.AuditURL != actual.AuditURL ||
+		expected.MetricTTL != actual.MetricTTL ||
+		expected.MetricTTLMode != actual.MetricTTLMode ||
+		expected.WriteBehind != actual.WriteBehind ||
+		expected.WriteBehindQueue != actual.WriteBehindQueue ||
+		expected.WriteBehindInterval != actual.WriteBehindInterval ||
+		expected.MemShards != actual.MemShards ||
+		expected.MetricNameRegex != actual.MetricNameRegex ||
+		expected.MaxMetricNameLength != actual.MaxMetricNameLength ||
+		expected.RejectNonFinite != actual.RejectNonFinite ||
+		expected.RejectNegativeDelta != actual.RejectNegativeDelta ||
+		expected.AllowedMetricNames != actual.AllowedMetricNames ||
+		expected.MaxSeries != actual.MaxSeries ||
+		expected.MaxClientSeries != actual.MaxClientSeries ||
+		expected.Tenants != actual.Tenants ||
+		expected.TenantMaxSeries != actual.TenantMaxSeries {
 		return false
 	}
 	return true
@@ -113,6 +320,94 @@ func TestParseFromEnv(t *testing.T) {
 				HashKey:       "key",
 			},
 		},
+		{
+			name: "limits_set",
+			input: map[string]string{
+				"RATE_LIMIT":            "2.5",
+				"RATE_BURST":            "10",
+				"MAX_BODY_SIZE":         "1024",
+				"MAX_DECOMPRESSED_SIZE": "0",
+				"MAX_BATCH_SIZE":        "100",
+			},
+			expected: &CliConfig{
+				RateLimit:           2.5,
+				RateBurst:           10,
+				MaxBodySize:         1024,
+				MaxDecompressedSize: 0,
+				MaxBatchSize:        100,
+			},
+		},
+		{
+			name: "audit_set",
+			input: map[string]string{
+				"AUDIT_FILE":             "audit.log",
+				"AUDIT_FILE_MAX_SIZE":    "1024",
+				"AUDIT_FILE_MAX_BACKUPS": "0",
+				"AUDIT_URL":              "http://audit.local/events",
+			},
+			expected: &CliConfig{
+				AuditFile:           "audit.log",
+				AuditFileMaxSize:    1024,
+				AuditFileMaxBackups: 0,
+				AuditURL:            "http://audit.local/events",
+			},
+		},
+		{
+			name: "metric_ttl_set",
+			input: map[string]string{
+				"METRIC_TTL":      "0",
+				"METRIC_TTL_MODE": "delete",
+			},
+			expected: &CliConfig{
+				MetricTTL:     0,
+				MetricTTLMode: TTLModeDelete,
+			},
+		},
+		{
+			name: "write_behind_set",
+			input: map[string]string{
+				"WRITE_BEHIND":          "true",
+				"WRITE_BEHIND_QUEUE":    "100",
+				"WRITE_BEHIND_INTERVAL": "250",
+			},
+			expected: &CliConfig{
+				WriteBehind:         true,
+				WriteBehindQueue:    100,
+				WriteBehindInterval: 250,
+			},
+		},
+		{
+			name:     "mem_shards_set",
+			input:    map[string]string{"MEM_SHARDS": "16"},
+			expected: &CliConfig{MemShards: 16},
+		},
+		{
+			name: "validation_set",
+			input: map[string]string{
+				"METRIC_NAME_REGEX":      "[A-Za-z]+",
+				"MAX_METRIC_NAME_LENGTH": "64",
+				"REJECT_NON_FINITE":      "true",
+				"REJECT_NEGATIVE_DELTA":  "true",
+				"ALLOWED_METRIC_NAMES":   "agent:Alloc|Heap*",
+			},
+			expected: &CliConfig{
+				MetricNameRegex:     "[A-Za-z]+",
+				MaxMetricNameLength: 64,
+				RejectNonFinite:     true,
+				RejectNegativeDelta: true,
+				AllowedMetricNames:  "agent:Alloc|Heap*",
+			},
+		},
+		{
+			name:     "series_limits_set",
+			input:    map[string]string{"MAX_SERIES": "1000", "MAX_CLIENT_SERIES": "100"},
+			expected: &CliConfig{MaxSeries: 1000, MaxClientSeries: 100},
+		},
+		{
+			name:     "tenants_set",
+			input:    map[string]string{"TENANTS": "agent-1:team-a", "TENANT_MAX_SERIES": "team-a:1000"},
+			expected: &CliConfig{Tenants: "agent-1:team-a", TenantMaxSeries: "team-a:1000"},
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -148,13 +443,21 @@ func TestParse(t *testing.T) {
 			cliInput: []string{},
 			envInput: map[string]string{},
 			expected: &CliConfig{
-				Address:       DefaultServerURL,
-				LogLevel:      DefaultLogLevel,
-				FileStorage:   DefaultFilePath,
-				DatabaseDSN:   DefaultDatabaseDSN,
-				StoreInterval: 0,
-				Restore:       DefaultRestore,
-				HashKey:       DefaultHashKey,
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				FileStorage:         DefaultFilePath,
+				DatabaseDSN:         DefaultDatabaseDSN,
+				StoreInterval:       0,
+				Restore:             DefaultRestore,
+				HashKey:             DefaultHashKey,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
 			},
 			expectedCliErr: nil,
 			expectedEnvErr: nil,
@@ -164,13 +467,21 @@ func TestParse(t *testing.T) {
 			cliInput: []string{"-a=someAddress", "-ll=loglevel", "-f=path", "-d=dsn", "-i=1", "-r=true", "-k=key"},
 			envInput: map[string]string{"STORE_INTERVAL": "-1"},
 			expected: &CliConfig{
-				Address:       "someAddress",
-				LogLevel:      "loglevel",
-				FileStorage:   "path",
-				DatabaseDSN:   "dsn",
-				StoreInterval: 1,
-				Restore:       true,
-				HashKey:       "key",
+				Address:             "someAddress",
+				LogLevel:            "loglevel",
+				FileStorage:         "path",
+				DatabaseDSN:         "dsn",
+				StoreInterval:       1,
+				Restore:             true,
+				HashKey:             "key",
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
 			},
 			expectedCliErr: nil,
 			expectedEnvErr: nil,
@@ -188,13 +499,21 @@ func TestParse(t *testing.T) {
 				"KEY":            "key",
 			},
 			expected: &CliConfig{
-				Address:       "someAddress",
-				LogLevel:      "loglevel",
-				FileStorage:   "path",
-				DatabaseDSN:   "dsn",
-				StoreInterval: 1,
-				Restore:       true,
-				HashKey:       "key",
+				Address:             "someAddress",
+				LogLevel:            "loglevel",
+				FileStorage:         "path",
+				DatabaseDSN:         "dsn",
+				StoreInterval:       1,
+				Restore:             true,
+				HashKey:             "key",
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
 			},
 			expectedCliErr: nil,
 			expectedEnvErr: nil,
@@ -212,13 +531,21 @@ func TestParse(t *testing.T) {
 				"KEY":            "key",
 			},
 			expected: &CliConfig{
-				Address:       "someAddress",
-				LogLevel:      "loglevel",
-				FileStorage:   "path",
-				DatabaseDSN:   "dsn",
-				StoreInterval: 1,
-				Restore:       true,
-				HashKey:       "key",
+				Address:             "someAddress",
+				LogLevel:            "loglevel",
+				FileStorage:         "path",
+				DatabaseDSN:         "dsn",
+				StoreInterval:       1,
+				Restore:             true,
+				HashKey:             "key",
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
 			},
 			expectedCliErr: nil,
 			expectedEnvErr: nil,
@@ -245,6 +572,22 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseWrongTTLMode(t *testing.T) {
+	os.Args = []string{"cmd", "-metric-ttl-mode=drop"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.PanicOnError)
+	os.Clearenv()
+	_, err := Parse()
+	assert.ErrorIs(t, err, ErrorWrongTTLMode)
+}
+
+func TestParseWrongWriteBehind(t *testing.T) {
+	os.Args = []string{"cmd", "-write-behind", "-write-behind-interval=0"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.PanicOnError)
+	os.Clearenv()
+	_, err := Parse()
+	assert.ErrorIs(t, err, ErrorWrongWriteBehind)
+}
+
 func createFileWithContent(path string, content []byte) {
 	os.WriteFile(path, content, os.ModePerm)
 }
@@ -313,6 +656,311 @@ func TestParseFromFile(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:    "config_file_with_tls",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "tls_cert": "/path/to/server.crt",
+    "tls_key": "/path/to/server.key",
+    "tls_client_ca": "/path/to/ca.crt"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				return &CliConfig{
+					Address:        DefaultServerURL,
+					StoreInterval:  DefaultStoreInterval,
+					FileStorage:    DefaultFilePath,
+					ConfigFilePath: testFilePath,
+				}
+			},
+			want: &CliConfig{
+				Address:         DefaultServerURL,
+				StoreInterval:   DefaultStoreInterval,
+				FileStorage:     DefaultFilePath,
+				ConfigFilePath:  testFilePath,
+				TLSCertPath:     "/path/to/server.crt",
+				TLSKeyPath:      "/path/to/server.key",
+				TLSClientCAPath: "/path/to/ca.crt",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_tokens",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "tokens": "agent:secret:write",
+    "tokens_file": "/path/to/tokens.json"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				return &CliConfig{
+					Address:        DefaultServerURL,
+					StoreInterval:  DefaultStoreInterval,
+					FileStorage:    DefaultFilePath,
+					ConfigFilePath: testFilePath,
+				}
+			},
+			want: &CliConfig{
+				Address:        DefaultServerURL,
+				StoreInterval:  DefaultStoreInterval,
+				FileStorage:    DefaultFilePath,
+				ConfigFilePath: testFilePath,
+				Tokens:         "agent:secret:write",
+				TokensFile:     "/path/to/tokens.json",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_limits",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "rate_limit": 5,
+    "rate_burst": 20,
+    "max_body_size": 2048,
+    "max_batch_size": 50
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				RateLimit:           5,
+				RateBurst:           20,
+				MaxBodySize:         2048,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        50,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_audit",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "audit_file": "/var/log/gmetrics/audit.log",
+    "audit_file_max_size": 1024,
+    "audit_file_max_backups": 3,
+    "audit_url": "http://audit.local/events"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFile:           "/var/log/gmetrics/audit.log",
+				AuditFileMaxSize:    1024,
+				AuditFileMaxBackups: 3,
+				AuditURL:            "http://audit.local/events",
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_metric_ttl",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "metric_ttl": "1m",
+    "metric_ttl_mode": "delete"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTL:           60,
+				MetricTTLMode:       TTLModeDelete,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_write_behind",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "write_behind": true,
+    "write_behind_queue": 500,
+    "write_behind_interval": "250ms"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehind:         true,
+				WriteBehindQueue:    500,
+				WriteBehindInterval: 250,
+			},
+			wantErr: false,
+		},
+		{
+			name:       "config_file_with_mem_shards",
+			cfgPath:    testFilePath,
+			fileConfig: `{"mem_shards": 16}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				MemShards:           16,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_validation",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "metric_name_regex": "[A-Za-z]+",
+    "max_metric_name_length": 64,
+    "reject_non_finite": true,
+    "reject_negative_delta": true,
+    "allowed_metric_names": "agent:Alloc|Heap*"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				MetricNameRegex:     "[A-Za-z]+",
+				MaxMetricNameLength: 64,
+				RejectNonFinite:     true,
+				RejectNegativeDelta: true,
+				AllowedMetricNames:  "agent:Alloc|Heap*",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_series_limits",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "max_series": 1000,
+    "max_client_series": 100
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				MaxSeries:           1000,
+				MaxClientSeries:     100,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "config_file_with_tenants",
+			cfgPath: testFilePath,
+			fileConfig: `{
+    "tenants": "agent-1:team-a",
+    "tenant_max_series": "team-a:1000"
+}`,
+			getCnf: func(t *testing.T) *CliConfig {
+				cnf := InitializeDefaultConfig()
+				cnf.ConfigFilePath = testFilePath
+				return cnf
+			},
+			want: &CliConfig{
+				Address:             DefaultServerURL,
+				LogLevel:            DefaultLogLevel,
+				StoreInterval:       DefaultStoreInterval,
+				FileStorage:         DefaultFilePath,
+				ConfigFilePath:      testFilePath,
+				MaxBodySize:         DefaultMaxBodySize,
+				MaxDecompressedSize: DefaultMaxDecompressedSize,
+				MaxBatchSize:        DefaultMaxBatchSize,
+				AuditFileMaxSize:    DefaultAuditFileMaxSize,
+				AuditFileMaxBackups: DefaultAuditFileMaxBackups,
+				MetricTTLMode:       DefaultMetricTTLMode,
+				WriteBehindQueue:    DefaultWriteBehindQueue,
+				WriteBehindInterval: DefaultWriteBehindInterval,
+				Tenants:             "agent-1:team-a",
+				TenantMaxSeries:     "team-a:1000",
+			},
+			wantErr: false,
+		},
 		{
 			name:    "config_file_exists_with_invalid_data",
 			cfgPath: testFilePath,
@@ -379,6 +1027,42 @@ func TestParseFromFile(t *testing.T) {
 	}
 }
 
+func TestParsePolicy(t *testing.T) {
+	policy, err := parsePolicy(&CliConfig{})
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled())
+
+	policy, err = parsePolicy(&CliConfig{MetricNameRegex: "[A-Za-z]+", AllowedMetricNames: "agent:Alloc|Heap*"})
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled())
+	assert.Equal(t, map[string][]string{"agent": {"Alloc", "Heap*"}}, policy.AllowedNames)
+
+	_, err = parsePolicy(&CliConfig{MetricNameRegex: "["})
+	assert.Error(t, err)
+	_, err = parsePolicy(&CliConfig{AllowedMetricNames: "agent"})
+	assert.ErrorIs(t, err, validation.ErrorWrongAllowedNames)
+}
+
+func TestParseTenants(t *testing.T) {
+	clients, limits, err := parseTenants("", "")
+	require.NoError(t, err)
+	assert.Nil(t, clients)
+	assert.Nil(t, limits)
+
+	clients, limits, err = parseTenants("agent-1:team-a,agent-2:team-b", "team-a:1000")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"agent-1": "team-a", "agent-2": "team-b"}, clients)
+	assert.Equal(t, map[string]int{"team-a": 1000}, limits)
+
+	_, _, err = parseTenants("agent-1", "")
+	assert.ErrorIs(t, err, tenant.ErrorWrongTenants)
+	_, _, err = parseTenants("agent-1:team-a", "team-a")
+	assert.ErrorIs(t, err, tenant.ErrorWrongTenantSeries)
+	// Лимит тенанта без клиентов
+	_, _, err = parseTenants("agent-1:team-a", "team-b:10")
+	assert.ErrorIs(t, err, tenant.ErrorWrongTenantSeries)
+}
+
 // Test cases for parseSubnet function
 func TestParseSubnet(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,232 @@
+package exposition
+
+import (
+	"bytes"
+	"gmetrics/internal/cardinality"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/tenant"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType тип содержимого текстового формата Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// helpEscaper экранирование пояснения в строке # HELP
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// labelEscaper экранирование значения метки
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// Handler Возвращает все метрики в текстовом формате Prometheus
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Handler
+// @Description Возвращает все метрики тенанта клиента и собственную метрику сервера с отклонёнными рядами тенанта в текстовом формате Prometheus. Описания метрик выводятся строками # HELP и # UNIT
+// @Tags Метрики
+// @Produce plain
+// @Success 200 {string} string "Метрики в текстовом формате Prometheus"
+// @Failure 500 {object} helpers.ErrorResponse
+// @Failure 501 {object} helpers.ErrorResponse
+// @Router /metrics [get]
+func Handler(response http.ResponseWriter, request *http.Request) {
+	namespace := tenant.FromContext(request.Context())
+	store, ok := namespace.Storage.(metrics.IListingStorage)
+	if !ok {
+		helpers.SetHTTPResponse(response, http.StatusNotImplemented, helpers.GetErrorJSONBody(metrics.ErrorListNotSupported.Error()))
+		return
+	}
+	list, err := metrics.ListContext(request.Context(), store, metrics.ListQuery{Sort: metrics.ListSortName})
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	metadata, err := metrics.GetAllMetadata(namespace.Storage)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	var buff bytes.Buffer
+	Write(&buff, withSelfMetrics(list, namespace.Series), metadata, time.Now())
+	response.Header().Set("Content-Type", ContentType)
+	helpers.SetHTTPResponse(response, http.StatusOK, buff.Bytes())
+}
+
+// withSelfMetrics метрики хранилища с собственными метриками сервера об отклонённых рядах вместо сохранённых прежними версиями
+func withSelfMetrics(list []metrics.ListedMetric, series *cardinality.Limiter) []metrics.ListedMetric {
+	result := make([]metrics.ListedMetric, 0, len(list))
+	for _, metric := range list {
+		if !cardinality.IsSelfMetric(metric.Name) {
+			result = append(result, metric)
+		}
+	}
+	rejected := series.Rejected()
+	clients := make([]string, 0, len(rejected))
+	for client := range rejected {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	for _, client := range clients {
+		result = append(result, metrics.ListedMetric{
+			Type:    metrics.TypeCounter,
+			Name:    cardinality.RejectedMetricName(client),
+			Counter: metrics.Counter(rejected[client]),
+		})
+	}
+	return result
+}
+
+// family ряды метрик одного типа с одним базовым именем
+type family struct {
+	name       string
+	metricType string
+	metadata   metrics.Metadata
+	series     []metrics.ListedMetric
+}
+
+// Write записывает метрики в текстовом формате Prometheus. Ряды с метками в имени группируются по базовому имени,
+// описание семейства собирается из описаний его рядов. Имена и метки приводятся к допустимым в Prometheus.
+// Если у базового имени несколько типов, то к имени семейства добавляется тип, чтобы имена не повторялись
+func Write(buff *bytes.Buffer, list []metrics.ListedMetric, metadata map[metrics.ListKey]metrics.Metadata, now time.Time) {
+	byKey := make(map[metrics.ListKey]*family)
+	families := make([]*family, 0)
+	types := make(map[string]int)
+	for _, metric := range list {
+		base, _ := metrics.SplitLabels(metric.Name)
+		key := metrics.ListKey{Type: metric.Type, Name: sanitizeName(base)}
+		f, ok := byKey[key]
+		if !ok {
+			f = &family{name: key.Name, metricType: metric.Type}
+			byKey[key] = f
+			families = append(families, f)
+			types[key.Name]++
+		}
+		f.metadata = f.metadata.Merge(metadata[metric.Key()])
+		f.series = append(f.series, metric)
+	}
+	for _, f := range families {
+		if types[f.name] > 1 {
+			f.name += "_" + f.metricType
+		}
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+	for _, f := range families {
+		f.write(buff, now)
+	}
+}
+
+// write запись семейства: описание, тип и значения рядов
+func (f *family) write(buff *bytes.Buffer, now time.Time) {
+	if f.metadata.Help != "" {
+		buff.WriteString("# HELP " + f.name + " " + helpEscaper.Replace(f.metadata.Help) + "\n")
+	}
+	if f.metadata.Unit != "" {
+		buff.WriteString("# UNIT " + f.name + " " + f.metadata.Unit + "\n")
+	}
+	promType := f.metricType
+	if f.metricType == metrics.TypeSet {
+		// Множество выводится оценкой количества элементов
+		promType = metrics.TypeGauge
+	}
+	buff.WriteString("# TYPE " + f.name + " " + promType + "\n")
+	for _, metric := range f.series {
+		_, labels := metrics.SplitLabels(metric.Name)
+		switch metric.Type {
+		case metrics.TypeGauge:
+			writeSample(buff, f.name, labels, "", "", formatFloat(metric.Gauge.GetRaw()))
+		case metrics.TypeCounter:
+			writeSample(buff, f.name, labels, "", "", strconv.FormatInt(metric.Counter.GetRaw(), 10))
+		case metrics.TypeHistogram:
+			var cumulative uint64
+			for i, count := range metric.Histogram.Counts {
+				cumulative += count
+				bound := "+Inf"
+				if i < len(metric.Histogram.Bounds) {
+					bound = formatFloat(metric.Histogram.Bounds[i])
+				}
+				writeSample(buff, f.name+"_bucket", labels, "le", bound, strconv.FormatUint(cumulative, 10))
+			}
+			writeSample(buff, f.name+"_sum", labels, "", "", formatFloat(metric.Histogram.Sum))
+			writeSample(buff, f.name+"_count", labels, "", "", strconv.FormatUint(metric.Histogram.Count, 10))
+		case metrics.TypeSummary:
+			value := metric.Summary.Value(now, metrics.DefaultSummaryWindow)
+			for _, q := range value.Quantiles {
+				writeSample(buff, f.name, labels, "quantile", formatFloat(q.Quantile), formatFloat(q.Value))
+			}
+			writeSample(buff, f.name+"_sum", labels, "", "", formatFloat(value.Sum))
+			writeSample(buff, f.name+"_count", labels, "", "", strconv.FormatUint(value.Count, 10))
+		case metrics.TypeSet:
+			writeSample(buff, f.name, labels, "", "", strconv.FormatUint(metric.Set.Estimate(), 10))
+		}
+	}
+}
+
+// writeSample запись значения ряда. Метки выводятся по порядку имён, дополнительная метка extra - последней
+func writeSample(buff *bytes.Buffer, name string, labels map[string]string, extra, extraValue, value string) {
+	buff.WriteString(name)
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		pairs = append(pairs, sanitizeLabel(key)+`="`+labelEscaper.Replace(labels[key])+`"`)
+	}
+	if extra != "" {
+		pairs = append(pairs, extra+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		buff.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	buff.WriteString(" " + value + "\n")
+}
+
+// formatFloat запись числа, как её ожидает Prometheus: NaN, +Inf и -Inf словами
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sanitizeName имя метрики из букв, цифр, _ и :, которое не начинается с цифры
+func sanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// sanitizeLabel имя метки из букв, цифр и _, которое не начинается с цифры
+func sanitizeLabel(name string) string {
+	return sanitize(name, false)
+}
+
+// sanitize замена недопустимых символов имени на _
+func sanitize(name string, colon bool) string {
+	var result strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', colon && r == ':':
+			result.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				result.WriteByte('_')
+			}
+			result.WriteRune(r)
+		default:
+			result.WriteByte('_')
+		}
+	}
+	if result.Len() == 0 {
+		return "_"
+	}
+	return result.String()
+}
@@ -0,0 +1,130 @@
+package exposition
+
+import (
+	"bytes"
+	"context"
+	"gmetrics/internal/cardinality"
+	"gmetrics/internal/metrics"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	store := metrics.NewMemStorage()
+	_ = store.SetGauge("Alloc", 1.5)
+	_ = store.SetGauge("NaNGauge", metrics.Gauge(math.NaN()))
+	_ = store.AddCounter(`requests{method="GET"}`, 3)
+	_ = store.AddCounter(`requests{method="POST"}`, 4)
+	_ = store.SetGauge("PollCount", 1)
+	_ = store.AddCounter("PollCount", 7)
+	_ = store.SetGauge("cpu.usage-1", 0.25)
+	histogram := metrics.NewHistogram([]float64{1, 5})
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	histogram.Observe(10)
+	_ = metrics.AddHistogramContext(ctx, store, `latency{path="/"}`, histogram)
+	_ = metrics.AddSetContext(ctx, store, "Users", metrics.NewSketch("alice", "bob"))
+	_ = store.SetMetadata(metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}, metrics.Metadata{Unit: "bytes", Help: "Allocated heap\nin bytes"})
+	_ = store.SetMetadata(metrics.ListKey{Type: metrics.TypeCounter, Name: `requests{method="GET"}`}, metrics.Metadata{Help: "HTTP requests"})
+	// Сохранённая прежней версией собственная метрика заменяется отклонениями из учёта рядов
+	_ = store.AddCounter(cardinality.RejectedMetricName("agent-9"), 5)
+	metrics.MeStore = store
+	cardinality.Series = cardinality.New(1, 0)
+	defer func() { cardinality.Series = nil }()
+	require.NoError(t, cardinality.Series.Admit("agent-1", metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}))
+	_ = cardinality.Series.Admit("agent-2", metrics.ListKey{Type: metrics.TypeGauge, Name: "Frees"})
+	_ = cardinality.Series.Admit("10.0.0.1", metrics.ListKey{Type: metrics.TypeGauge, Name: "Frees"})
+	_ = cardinality.Series.Admit("agent-2", metrics.ListKey{Type: metrics.TypeGauge, Name: "cpu"})
+
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, ContentType, response.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP Alloc Allocated heap\nin bytes
+# UNIT Alloc bytes
+# TYPE Alloc gauge
+Alloc 1.5
+# TYPE NaNGauge gauge
+NaNGauge NaN
+# TYPE PollCount_counter counter
+PollCount_counter 7
+# TYPE PollCount_gauge gauge
+PollCount_gauge 1
+# TYPE Users gauge
+Users 2
+# TYPE cpu_usage_1 gauge
+cpu_usage_1 0.25
+# TYPE gmetrics_series_rejected counter
+gmetrics_series_rejected{client="10.0.0.1"} 1
+gmetrics_series_rejected{client="agent-2"} 2
+# TYPE latency histogram
+latency_bucket{path="/",le="1"} 1
+latency_bucket{path="/",le="5"} 2
+latency_bucket{path="/",le="+Inf"} 3
+latency_sum{path="/"} 13.5
+latency_count{path="/"} 3
+# HELP requests HTTP requests
+# TYPE requests counter
+requests{method="GET"} 3
+requests{method="POST"} 4
+`, response.Body.String())
+}
+
+func TestHandlerNotSupported(t *testing.T) {
+	metrics.MeStore = struct{ metrics.IStorage }{metrics.NewMemStorage()}
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotImplemented, response.Code)
+}
+
+func TestWrite(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		metric metrics.ListedMetric
+		want   string
+	}{
+		{
+			name:   "label_escape",
+			metric: metrics.ListedMetric{Type: metrics.TypeCounter, Name: `hits{1path="a\"b"}`, Counter: 2},
+			want:   "# TYPE hits counter\nhits{_1path=\"a\\\"b\"} 2\n",
+		},
+		{
+			name:   "leading_digit",
+			metric: metrics.ListedMetric{Type: metrics.TypeGauge, Name: "9lives", Gauge: -1},
+			want:   "# TYPE _9lives gauge\n_9lives -1\n",
+		},
+		{
+			name:   "infinity",
+			metric: metrics.ListedMetric{Type: metrics.TypeGauge, Name: "ratio:max", Gauge: metrics.Gauge(math.Inf(1))},
+			want:   "# TYPE ratio:max gauge\nratio:max +Inf\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			Write(&buff, []metrics.ListedMetric{test.metric}, nil, now)
+			assert.Equal(t, test.want, buff.String())
+		})
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	store := metrics.NewMemStorage()
+	require.NoError(t, metrics.AddSummaryContext(context.Background(), store, "duration", []float64{1, 2, 3}))
+	list, err := metrics.ListContext(context.Background(), store, metrics.ListQuery{})
+	require.NoError(t, err)
+
+	var buff bytes.Buffer
+	Write(&buff, list, nil, time.Now())
+	assert.Contains(t, buff.String(), "# TYPE duration summary\n")
+	assert.Contains(t, buff.String(), "duration{quantile=\"0.5\"} ")
+	assert.Contains(t, buff.String(), "duration_sum 6\nduration_count 3\n")
+}
@@ -38,3 +38,19 @@ func ExampleJSONHandler() {
 
 	_, _ = request.Send()
 }
+
+// Example for JSONManyHandler
+func ExampleJSONManyHandler() {
+	// Set Server
+	router := chi.NewRouter()
+	router.Post("/values", JSONManyHandler)
+	// запускаем тестовый сервер, будет выбран первый свободный порт
+	srv := httptest.NewServer(router)
+	// Set up an HTTP request.
+	request := resty.New().R()
+	request.Method = http.MethodPost
+	request.Body = `[{"id":"Alloc","type":"gauge"},{"id":"PollCount","type":"counter"}]`
+	request.URL = srv.URL + "/values"
+
+	_, _ = request.Send()
+}
@@ -6,6 +6,7 @@ import (
 	"gmetrics/internal/logger"
 	"gmetrics/internal/metrics"
 	"gmetrics/internal/payload"
+	"gmetrics/internal/tenant"
 	"io"
 	"net/http"
 )
@@ -33,7 +34,7 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 	rawBody, err := io.ReadAll(request.Body)
 	if err != nil {
 		logger.Log.Error(err)
-		helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody(err.Error()))
+		helpers.SetHTTPResponse(response, helpers.ReadBodyErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
 		return
 	}
 	// Парсим тело в структуру запроса
@@ -44,9 +45,15 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 		helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody("Bad request for get metric"))
 		return
 	}
+	store := tenant.FromContext(request.Context()).Storage
 	switch body.MType {
 	case metrics.TypeGauge:
-		value, ok := metrics.MeStore.GetGauge(body.ID)
+		value, ok, gErr := metrics.WithContext(store).GetGaugeContext(request.Context(), body.ID)
+		if gErr != nil {
+			logger.Log.Error(gErr)
+			helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(gErr), helpers.GetErrorJSONBody(gErr.Error()))
+			return
+		}
 		if !ok {
 			http.NotFound(response, request)
 			return
@@ -54,13 +61,42 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 		rawValue := value.GetRaw()
 		body.Value = &rawValue
 	case metrics.TypeCounter:
-		value, ok := metrics.MeStore.GetCounter(body.ID)
+		value, ok, gErr := metrics.WithContext(store).GetCounterContext(request.Context(), body.ID)
+		if gErr != nil {
+			logger.Log.Error(gErr)
+			helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(gErr), helpers.GetErrorJSONBody(gErr.Error()))
+			return
+		}
 		if !ok {
 			http.NotFound(response, request)
 			return
 		}
 		rawValue := value.GetRaw()
 		body.Delta = &rawValue
+	case metrics.TypeHistogram:
+		histogram, ok := metrics.GetHistogramByName(store, body.ID)
+		if !ok {
+			http.NotFound(response, request)
+			return
+		}
+		value := histogram.Payload()
+		body.Histogram = &value
+	case metrics.TypeSummary:
+		summary, ok := metrics.GetSummaryByName(store, body.ID)
+		if !ok {
+			http.NotFound(response, request)
+			return
+		}
+		value := summary.Payload()
+		body.Summary = &value
+	case metrics.TypeSet:
+		sketch, ok := metrics.GetSetByName(store, body.ID)
+		if !ok {
+			http.NotFound(response, request)
+			return
+		}
+		estimate := sketch.Estimate()
+		body.Cardinality = &estimate
 	default:
 		http.NotFound(response, request)
 		return
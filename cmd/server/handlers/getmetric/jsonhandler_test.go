@@ -53,6 +53,33 @@ func TestJSONHandler(t *testing.T) {
 			wantContentType: "application/json",
 			wantValue:       `{"delta":5,"id":"someName","type":"counter"}`,
 		},
+		{
+			name:            "histogram",
+			body:            `{"id":"someName","type":"histogram"}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantValue:       `{"id":"someName","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}}`,
+		},
+		{
+			name:            "summary",
+			body:            `{"id":"someName","type":"summary"}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantValue:       `{"id":"someName","type":"summary","summary":{"count":1,"sum":2,"quantiles":{"0.5":2,"0.9":2,"0.99":2}}}`,
+		},
+		{
+			name:            "set",
+			body:            `{"id":"someName","type":"set"}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantValue:       `{"id":"someName","type":"set","cardinality":2}`,
+		},
+		{
+			name:            "empty_summary",
+			body:            `{"id":"someName1","type":"summary"}`,
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+		},
 		{
 			name:            "empty_gauge",
 			body:            `{"id":"someName1","type":"gauge"}`,
@@ -73,6 +100,9 @@ func TestJSONHandler(t *testing.T) {
 		metrics.MeStore = metrics.NewMemStorage()
 		_ = metrics.MeStore.SetGauge("someName", 56.67)
 		_ = metrics.MeStore.AddCounter("someName", 5)
+		_ = metrics.MeStore.(metrics.IDistributionStorage).AddHistogram("someName", metrics.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1})
+		_ = metrics.MeStore.(metrics.IDistributionStorage).AddSummary("someName", []float64{2})
+		_ = metrics.MeStore.(metrics.ISetStorage).AddSet("someName", metrics.NewSketch("a", "b"))
 		JSONHandler(writer, request)
 	})
 	// запускаем тестовый сервер, будет выбран первый свободный порт
@@ -0,0 +1,133 @@
+package getmetric
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/tenant"
+	"io"
+	"net/http"
+)
+
+// MaxBatchSize максимальное количество метрик в одном запросе; 0 - без ограничений
+var MaxBatchSize int
+
+// ErrorTooManyMetrics ошибка, что в запросе больше метрик, чем разрешено за раз
+var ErrorTooManyMetrics = errors.New("too many metrics in request")
+
+// JSONManyHandler Возвращает значения многих метрик по запросу с JSON телом
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary JSONManyHandler
+// @Description Возвращает значения метрик из списка, для ненайденных метрик found равен false
+// @Tags Метрики
+// @Accept json
+// @Produce json
+// @Param request body []payload.Metrics true "список метрик с id и type"
+// @Success 200 {object} []payload.MetricValue
+// @Failure 400 {object} helpers.ErrorResponse
+// @Failure 413 {object} helpers.ErrorResponse
+// @Failure 500 {object} helpers.ErrorResponse
+// @Router /values [post]
+func JSONManyHandler(response http.ResponseWriter, request *http.Request) {
+	// Читаем тело запроса
+	rawBody, err := io.ReadAll(request.Body)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, helpers.ReadBodyErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	// Парсим тело в структуру запроса
+	var body []payload.Metrics
+	err = json.Unmarshal(rawBody, &body)
+	if err != nil {
+		logger.Log.Infow("Bad request for get metrics", "error", err, "body", string(rawBody))
+		helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody("Bad request for get metrics"))
+		return
+	}
+	if MaxBatchSize > 0 && len(body) > MaxBatchSize {
+		helpers.SetHTTPResponse(response, http.StatusRequestEntityTooLarge, helpers.GetErrorJSONBody(ErrorTooManyMetrics.Error()))
+		return
+	}
+	values, err := readMetrics(request.Context(), tenant.FromContext(request.Context()).Storage, body)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+
+	jsonResponse, err := json.Marshal(values)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	response.WriteHeader(http.StatusOK)
+	_, err = response.Write(jsonResponse)
+	if err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// readMetrics значения запрошенных метрик в порядке запроса. Метрики каждого типа читаются из хранилища за раз,
+// метрики неизвестного типа и без имени считаются ненайденными
+func readMetrics(ctx context.Context, storage metrics.IStorage, bodies []payload.Metrics) ([]payload.MetricValue, error) {
+	var gaugeNames, counterNames []string
+	for _, body := range bodies {
+		switch body.MType {
+		case metrics.TypeGauge:
+			gaugeNames = append(gaugeNames, body.ID)
+		case metrics.TypeCounter:
+			counterNames = append(counterNames, body.ID)
+		}
+	}
+	gauges, err := metrics.GetGaugesByNamesContext(ctx, storage, gaugeNames)
+	if err != nil {
+		return nil, err
+	}
+	counters, err := metrics.GetCountersByNamesContext(ctx, storage, counterNames)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]payload.MetricValue, 0, len(bodies))
+	for _, body := range bodies {
+		value := payload.MetricValue{Metrics: payload.Metrics{ID: body.ID, MType: body.MType}}
+		switch body.MType {
+		case metrics.TypeGauge:
+			if gauge, ok := gauges[body.ID]; ok && body.ID != "" {
+				rawValue := gauge.GetRaw()
+				value.Value, value.Found = &rawValue, true
+			}
+		case metrics.TypeCounter:
+			if counter, ok := counters[body.ID]; ok && body.ID != "" {
+				rawValue := counter.GetRaw()
+				value.Delta, value.Found = &rawValue, true
+			}
+		case metrics.TypeHistogram:
+			if histogram, ok := metrics.GetHistogramByName(storage, body.ID); ok {
+				rawValue := histogram.Payload()
+				value.Histogram, value.Found = &rawValue, true
+			}
+		case metrics.TypeSummary:
+			if summary, ok := metrics.GetSummaryByName(storage, body.ID); ok {
+				rawValue := summary.Payload()
+				value.Summary, value.Found = &rawValue, true
+			}
+		case metrics.TypeSet:
+			if sketch, ok := metrics.GetSetByName(storage, body.ID); ok {
+				estimate := sketch.Estimate()
+				value.Cardinality, value.Found = &estimate, true
+			}
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,104 @@
+package getmetric
+
+import (
+	"context"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONManyHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		maxBatchSize int
+		wantStatus   int
+		wantValue    string
+	}{
+		{
+			name:       "found_and_not_found",
+			body:       `[{"id":"someName","type":"gauge"},{"id":"someName","type":"counter"},{"id":"someName1","type":"gauge"},{"id":"someName","type":"aboba"}]`,
+			wantStatus: http.StatusOK,
+			wantValue: `[{"value":56.67,"id":"someName","type":"gauge","found":true},` +
+				`{"delta":5,"id":"someName","type":"counter","found":true},` +
+				`{"id":"someName1","type":"gauge","found":false},` +
+				`{"id":"someName","type":"aboba","found":false}]`,
+		},
+		{
+			name:       "distributions",
+			body:       `[{"id":"someName","type":"histogram"},{"id":"someName","type":"summary"},{"id":"someName1","type":"summary"}]`,
+			wantStatus: http.StatusOK,
+			wantValue: `[{"id":"someName","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1},"found":true},` +
+				`{"id":"someName","type":"summary","summary":{"count":1,"sum":2,"quantiles":{"0.5":2,"0.9":2,"0.99":2}},"found":true},` +
+				`{"id":"someName1","type":"summary","found":false}]`,
+		},
+		{
+			name:       "sets",
+			body:       `[{"id":"someName","type":"set"},{"id":"someName1","type":"set"}]`,
+			wantStatus: http.StatusOK,
+			wantValue:  `[{"id":"someName","type":"set","cardinality":2,"found":true},{"id":"someName1","type":"set","found":false}]`,
+		},
+		{
+			name:       "empty",
+			body:       `[]`,
+			wantStatus: http.StatusOK,
+			wantValue:  `[]`,
+		},
+		{
+			name:       "bad_body",
+			body:       `{"id":"someName","type":"gauge"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "too_many",
+			body:         `[{"id":"someName","type":"gauge"},{"id":"someName","type":"counter"}]`,
+			maxBatchSize: 1,
+			wantStatus:   http.StatusRequestEntityTooLarge,
+		},
+	}
+	router := chi.NewRouter()
+	router.Post("/values", func(writer http.ResponseWriter, request *http.Request) {
+		metrics.MeStore = metrics.NewMemStorage()
+		_ = metrics.MeStore.SetGauge("someName", 56.67)
+		_ = metrics.MeStore.AddCounter("someName", 5)
+		_ = metrics.MeStore.(metrics.IDistributionStorage).AddHistogram("someName", metrics.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1})
+		_ = metrics.MeStore.(metrics.IDistributionStorage).AddSummary("someName", []float64{2})
+		_ = metrics.MeStore.(metrics.ISetStorage).AddSet("someName", metrics.NewSketch("a", "b"))
+		JSONManyHandler(writer, request)
+	})
+	// запускаем тестовый сервер, будет выбран первый свободный порт
+	srv := httptest.NewServer(router)
+	// останавливаем сервер после завершения теста
+	defer srv.Close()
+	defer func() { MaxBatchSize = 0 }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			MaxBatchSize = test.maxBatchSize
+			request := resty.New().R()
+			request.Method = http.MethodPost
+			request.Body = test.body
+			request.URL = srv.URL + "/values"
+
+			res, err := request.Send()
+			assert.NoError(t, err, "error making HTTP request")
+			assert.Equal(t, test.wantStatus, res.StatusCode(), "unexpected response status code")
+			if test.wantStatus == http.StatusOK {
+				assert.Equal(t, test.wantValue, string(res.Body()))
+			}
+		})
+	}
+}
+
+func TestReadMetrics_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := readMetrics(ctx, metrics.NewMemStorage(), []payload.Metrics{{ID: "Alloc", MType: metrics.TypeGauge}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,9 +1,12 @@
 package getmetric
 
 import (
+	"encoding/json"
 	"fmt"
+	"gmetrics/internal/helpers"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/metrics"
+	"gmetrics/internal/tenant"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -22,7 +25,7 @@ import (
 // @Produce  json
 // @Param type path string true "Тип метрики"
 // @Param name path string true "Имя метрики"
-// @Success 200 {string} string "значение метрики"
+// @Success 200 {string} string "значение метрики, для histogram и summary - JSON, для set - оценка количества элементов"
 // @Failure 404 {string} string "метрика не найдена"
 // @Router /value/{type}/{name} [get]
 func URLHandler(response http.ResponseWriter, request *http.Request) {
@@ -32,9 +35,15 @@ func URLHandler(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	store := tenant.FromContext(request.Context()).Storage
 	switch metricType {
 	case metrics.TypeGauge:
-		value, ok := metrics.MeStore.GetGauge(metricName)
+		value, ok, gErr := metrics.WithContext(store).GetGaugeContext(request.Context(), metricName)
+		if gErr != nil {
+			logger.Log.Error(gErr)
+			http.Error(response, gErr.Error(), helpers.StorageErrorStatus(gErr))
+			return
+		}
 		if !ok {
 			http.NotFound(response, request)
 			return
@@ -43,7 +52,12 @@ func URLHandler(response http.ResponseWriter, request *http.Request) {
 			logger.Log.Error(fErr)
 		}
 	case metrics.TypeCounter:
-		value, ok := metrics.MeStore.GetCounter(metricName)
+		value, ok, gErr := metrics.WithContext(store).GetCounterContext(request.Context(), metricName)
+		if gErr != nil {
+			logger.Log.Error(gErr)
+			http.Error(response, gErr.Error(), helpers.StorageErrorStatus(gErr))
+			return
+		}
 		if !ok {
 			http.NotFound(response, request)
 			return
@@ -51,12 +65,49 @@ func URLHandler(response http.ResponseWriter, request *http.Request) {
 		if _, fErr := fmt.Fprint(response, value.ToString()); fErr != nil {
 			logger.Log.Error(fErr)
 		}
+	case metrics.TypeHistogram:
+		histogram, ok := metrics.GetHistogramByName(store, metricName)
+		if !ok {
+			http.NotFound(response, request)
+			return
+		}
+		writeJSON(response, histogram.Payload())
+	case metrics.TypeSummary:
+		summary, ok := metrics.GetSummaryByName(store, metricName)
+		if !ok {
+			http.NotFound(response, request)
+			return
+		}
+		writeJSON(response, summary.Payload())
+	case metrics.TypeSet:
+		sketch, ok := metrics.GetSetByName(store, metricName)
+		if !ok {
+			http.NotFound(response, request)
+			return
+		}
+		if _, fErr := fmt.Fprint(response, sketch.Estimate()); fErr != nil {
+			logger.Log.Error(fErr)
+		}
 	default:
 		http.NotFound(response, request)
 		return
 	}
 }
 
+// writeJSON запись значения гистограммы или сводки в ответ в виде JSON
+func writeJSON(response http.ResponseWriter, value any) {
+	jsonResponse, err := json.Marshal(value)
+	if err != nil {
+		logger.Log.Error(err)
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	if _, err = response.Write(jsonResponse); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
 // parseURL Разбор URL на тип метрики, имя метрики
 // Parameters:
 // - request
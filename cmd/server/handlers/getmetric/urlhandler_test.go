@@ -114,6 +114,39 @@ func TestURLHandler(t *testing.T) {
 			wantContentType: "application/json",
 			wantValue:       "5",
 		},
+		{
+			name:            "histogram",
+			sendURL:         fmt.Sprintf(urlUpdateTemplate, metrics.TypeHistogram, "someName"),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantValue:       `{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}`,
+		},
+		{
+			name:            "summary",
+			sendURL:         fmt.Sprintf(urlUpdateTemplate, metrics.TypeSummary, "someName"),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantValue:       `{"count":1,"sum":2,"quantiles":{"0.5":2,"0.9":2,"0.99":2}}`,
+		},
+		{
+			name:            "set",
+			sendURL:         fmt.Sprintf(urlUpdateTemplate, metrics.TypeSet, "someName"),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantValue:       "2",
+		},
+		{
+			name:            "empty_set",
+			sendURL:         fmt.Sprintf(urlUpdateTemplate, metrics.TypeSet, "someName1"),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "empty_histogram",
+			sendURL:         fmt.Sprintf(urlUpdateTemplate, metrics.TypeHistogram, "someName1"),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+		},
 		{
 			name:            "empty_gauge",
 			sendURL:         fmt.Sprintf(urlUpdateTemplate, metrics.TypeGauge, "someName1"),
@@ -134,6 +167,9 @@ func TestURLHandler(t *testing.T) {
 		metrics.MeStore = metrics.NewMemStorage()
 		_ = metrics.MeStore.SetGauge("someName", 56.67)
 		_ = metrics.MeStore.AddCounter("someName", 5)
+		_ = metrics.MeStore.(metrics.IDistributionStorage).AddHistogram("someName", metrics.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1})
+		_ = metrics.MeStore.(metrics.IDistributionStorage).AddSummary("someName", []float64{2})
+		_ = metrics.MeStore.(metrics.ISetStorage).AddSet("someName", metrics.NewSketch("a", "b"))
 		URLHandler(writer, request)
 	})
 	// запускаем тестовый сервер, будет выбран первый свободный порт
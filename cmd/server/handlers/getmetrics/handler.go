@@ -2,17 +2,32 @@ package getmetrics
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"gmetrics/internal/helpers"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/metrics"
+	"gmetrics/internal/tenant"
 	"html/template"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// RefreshInterval как часто страница метрик обновляет значения, в секундах
+const RefreshInterval = 10
+
 // t Массив для хранения шиблонов с их именами
 var t *template.Template
 
+// detailTemplate шаблон страницы отдельной метрики
+var detailTemplate *template.Template
+
 // baseTemplate содержит встроенную файловую систему со всеми файлами шаблонов, расположенными в каталоге шаблонов.
 //
 //go:embed templates/*
@@ -24,6 +39,7 @@ func init() {
 	// Синтаксический разбор шаблона всегда в готовую переменную
 	// Загрузка шаблонов вместе с основным шаблоном
 	t = template.Must(template.New("metrics.gohtml").ParseFS(baseTemplate, "templates/base.gohtml", "templates/metrics.gohtml"))
+	detailTemplate = template.Must(template.New("metric.gohtml").ParseFS(baseTemplate, "templates/base.gohtml", "templates/metric.gohtml"))
 }
 
 // Handler Возвращает страницу с метриками
@@ -33,45 +49,336 @@ func init() {
 // - request: http.Request объект, содержащий информацию о запросе HTTP.
 //
 // @Summary	  Возвращает страницу с метриками
-// @Description  Возвращает страницу с метриками
+// @Description  Возвращает страницу с отсортированными по имени метриками, поиском по имени и фильтром по типу
 // @Tags		 Метрики
 // @Produce	  html
+// @Param q query string false "Поиск по части имени"
+// @Param type query string false "gauge или counter"
 // @Success	  200  {object}  string  "Metrics page"
 // @Failure	  500  {object}  string  "Internal Server Error"
 // @Router / [get]
 func Handler(response http.ResponseWriter, request *http.Request) {
-	gauges, errGauge := metrics.MeStore.GetGauges()
+	search := request.URL.Query().Get("q")
+	metricType := request.URL.Query().Get("type")
+	if metricType != metrics.TypeGauge && metricType != metrics.TypeCounter {
+		metricType = ""
+	}
+	store := tenant.FromContext(request.Context()).Storage
+	list, err := loadMetrics(request.Context(), store, metricType)
+	if err != nil {
+		logger.Log.Error(err)
+	}
+
+	metadata, err := metrics.GetAllMetadata(store)
+	if err != nil {
+		logger.Log.Error(err)
+	}
+
+	now := time.Now()
+	gaugeList := make([]ShowedMetrics, 0)
+	counterList := make([]ShowedMetrics, 0)
+	for _, metric := range list {
+		showed := newShowedMetrics(metric, now)
+		showed.setMetadata(metadata[metric.Key()])
+		showed.Hidden = search != "" && !strings.Contains(strings.ToLower(metric.Name), strings.ToLower(search))
+		// Остальные типы списка показываются своими таблицами ниже
+		switch metric.Type {
+		case metrics.TypeGauge:
+			gaugeList = append(gaugeList, showed)
+		case metrics.TypeCounter:
+			counterList = append(counterList, showed)
+		}
+	}
+	// Гистограммы, сводки и множества показываются только без фильтра по типу и не обновляются скриптом страницы
+	histogramList := make([]ShowedDistribution, 0)
+	summaryList := make([]ShowedDistribution, 0)
+	setList := make([]ShowedDistribution, 0)
+	if metricType == "" {
+		histogramList, summaryList, err = loadDistributions(store, search)
+		if err != nil {
+			logger.Log.Error(err)
+		}
+		setList, err = loadSets(store, search)
+		if err != nil {
+			logger.Log.Error(err)
+		}
+	}
+	data := struct {
+		GaugeList      []ShowedMetrics
+		CounterList    []ShowedMetrics
+		HistogramList  []ShowedDistribution
+		SummaryList    []ShowedDistribution
+		SetList        []ShowedDistribution
+		Search         string
+		Type           string
+		RefreshSeconds int
+	}{
+		GaugeList:      gaugeList,
+		CounterList:    counterList,
+		HistogramList:  histogramList,
+		SummaryList:    summaryList,
+		SetList:        setList,
+		Search:         search,
+		Type:           metricType,
+		RefreshSeconds: RefreshInterval,
+	}
+	render(response, t, data)
+}
+
+// MetricHandler Возвращает страницу отдельной метрики с графиком недавних значений
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP.
+// - request: http.Request объект, содержащий информацию о запросе HTTP.
+//
+// @Summary	  Возвращает страницу метрики
+// @Description  Возвращает страницу метрики со значением, временем обновления, метками и графиком недавних значений
+// @Tags		 Метрики
+// @Produce	  html
+// @Param type path string true "Тип метрики"
+// @Param name path string true "Имя метрики"
+// @Success	  200  {object}  string  "Metric page"
+// @Failure	  404  {object}  string  "Not Found"
+// @Failure	  500  {object}  string  "Internal Server Error"
+// @Router /metric/{type}/{name} [get]
+func MetricHandler(response http.ResponseWriter, request *http.Request) {
+	name := chi.URLParam(request, "name")
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	namespace := tenant.FromContext(request.Context())
+	metric, ok := findMetric(request.Context(), namespace.Storage, chi.URLParam(request, "type"), name)
+	if !ok {
+		http.NotFound(response, request)
+		return
+	}
+
+	points := namespace.History.Points(metric.Key())
+	_, labels := metrics.SplitLabels(metric.Name)
+	showed := newShowedMetrics(metric, time.Now())
+	if metadata, ok := metrics.GetMetadataByKey(namespace.Storage, metric.Key()); ok {
+		showed.setMetadata(metadata)
+	}
+	data := struct {
+		ShowedMetrics
+		Labels    map[string]string
+		Sparkline string
+		Width     int
+		Height    int
+		Min       string
+		Max       string
+		Points    int
+		Period    string
+	}{
+		ShowedMetrics: showed,
+		Labels:        labels,
+		Sparkline:     sparkline(points, sparklineWidth, sparklineHeight),
+		Width:         sparklineWidth,
+		Height:        sparklineHeight,
+		Points:        len(points),
+	}
+	if len(points) > 1 {
+		low, high := bounds(points)
+		data.Min = strconv.FormatFloat(low, 'f', -1, 64)
+		data.Max = strconv.FormatFloat(high, 'f', -1, 64)
+		data.Period = formatAge(points[len(points)-1].Time.Sub(points[0].Time))
+	}
+	render(response, detailTemplate, data)
+}
+
+// ShowedMetrics представляет собой структурированную метрику с именем и значением, которые будут отображаться в пользовательском интерфейсе.
+type ShowedMetrics struct {
+	Type        string
+	Name        string
+	Value       string
+	URL         string // Ссылка на страницу метрики
+	Age         string // Сколько прошло с последнего обновления
+	UpdatedAt   string // Время последнего обновления в RFC 3339
+	UpdatedUnix int64  // Время последнего обновления в миллисекундах для скрипта страницы, 0 - неизвестно
+	Hidden      bool   // Не подходит под поиск
+	Unit        string // Единица измерения из описания метрики
+	Help        string // Пояснение из описания метрики
+	Owner       string // Команда-владелец из описания метрики
+}
+
+// setMetadata заполнение полей описания метрики
+func (m *ShowedMetrics) setMetadata(metadata metrics.Metadata) {
+	m.Unit = metadata.Unit
+	m.Help = metadata.Help
+	m.Owner = metadata.Owner
+}
+
+// ShowedDistribution гистограмма, сводка или множество для отображения
+type ShowedDistribution struct {
+	Name    string
+	Count   uint64 // Количество наблюдений или оценка количества элементов множества
+	Sum     string
+	Details string // Корзины гистограммы или квантили сводки
+	Hidden  bool   // Не подходит под поиск
+}
+
+// loadDistributions гистограммы и сводки хранилища, отсортированные по имени
+func loadDistributions(storage metrics.IStorage, search string) ([]ShowedDistribution, []ShowedDistribution, error) {
+	histograms, summaries, err := metrics.GetDistributions(storage)
+	if err != nil {
+		return make([]ShowedDistribution, 0), make([]ShowedDistribution, 0), err
+	}
+	histogramList := make([]ShowedDistribution, 0, len(histograms))
+	for name, histogram := range histograms {
+		buckets := make([]string, 0, len(histogram.Counts))
+		for i, count := range histogram.Counts {
+			bound := "∞"
+			if i < len(histogram.Bounds) {
+				bound = strconv.FormatFloat(histogram.Bounds[i], 'f', -1, 64)
+			}
+			buckets = append(buckets, "≤"+bound+": "+strconv.FormatUint(count, 10))
+		}
+		histogramList = append(histogramList, ShowedDistribution{
+			Name:    name,
+			Count:   histogram.Count,
+			Sum:     strconv.FormatFloat(histogram.Sum, 'f', -1, 64),
+			Details: strings.Join(buckets, ", "),
+			Hidden:  hiddenBySearch(name, search),
+		})
+	}
+	summaryList := make([]ShowedDistribution, 0, len(summaries))
+	for name, summary := range summaries {
+		quantiles := make([]string, 0, len(summary.Quantiles))
+		for _, q := range summary.Quantiles {
+			quantiles = append(quantiles, "p"+strconv.FormatFloat(q.Quantile*100, 'f', -1, 64)+": "+strconv.FormatFloat(q.Value, 'f', -1, 64))
+		}
+		summaryList = append(summaryList, ShowedDistribution{
+			Name:    name,
+			Count:   summary.Count,
+			Sum:     strconv.FormatFloat(summary.Sum, 'f', -1, 64),
+			Details: strings.Join(quantiles, ", "),
+			Hidden:  hiddenBySearch(name, search),
+		})
+	}
+	for _, list := range [][]ShowedDistribution{histogramList, summaryList} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Name < list[j].Name
+		})
+	}
+	return histogramList, summaryList, nil
+}
+
+// loadSets множества хранилища с оценкой количества элементов, отсортированные по имени
+func loadSets(storage metrics.IStorage, search string) ([]ShowedDistribution, error) {
+	sets, err := metrics.GetSets(storage)
+	if err != nil {
+		return make([]ShowedDistribution, 0), err
+	}
+	list := make([]ShowedDistribution, 0, len(sets))
+	for name, sketch := range sets {
+		list = append(list, ShowedDistribution{Name: name, Count: sketch.Estimate(), Hidden: hiddenBySearch(name, search)})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// hiddenBySearch не подходит ли имя под поиск по части имени
+func hiddenBySearch(name, search string) bool {
+	return search != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(search))
+}
+
+// newShowedMetrics метрика для отображения на момент now
+func newShowedMetrics(metric metrics.ListedMetric, now time.Time) ShowedMetrics {
+	showed := ShowedMetrics{
+		Type: metric.Type,
+		Name: metric.Name,
+		URL:  "/metric/" + metric.Type + "/" + url.PathEscape(metric.Name),
+	}
+	if metric.Type == metrics.TypeGauge {
+		showed.Value = metric.Gauge.ToString()
+	} else {
+		showed.Value = metric.Counter.ToString()
+	}
+	if !metric.UpdatedAt.IsZero() {
+		showed.Age = formatAge(now.Sub(metric.UpdatedAt))
+		showed.UpdatedAt = metric.UpdatedAt.Format(time.RFC3339)
+		showed.UpdatedUnix = metric.UpdatedAt.UnixMilli()
+	}
+	return showed
+}
+
+// loadMetrics метрики хранилища, отсортированные по имени. Если хранилище не умеет отдавать список,
+// то метрики берутся целиком, а время обновления неизвестно
+func loadMetrics(ctx context.Context, storage metrics.IStorage, metricType string) ([]metrics.ListedMetric, error) {
+	query := metrics.ListQuery{Filter: metrics.ListFilter{Type: metricType}, Sort: metrics.ListSortName}
+	if st, ok := storage.(metrics.IListingStorage); ok {
+		return metrics.ListContext(ctx, st, query)
+	}
+	list := make([]metrics.ListedMetric, 0)
+	store := metrics.WithContext(storage)
+	gauges, errGauge := store.GetGaugesContext(ctx)
 	if errGauge != nil {
 		logger.Log.Error(errGauge)
 	}
-	counters, errCounter := metrics.MeStore.GetCounters()
-	if errGauge != nil {
+	counters, errCounter := store.GetCountersContext(ctx)
+	if errCounter != nil {
 		logger.Log.Error(errCounter)
 	}
+	if query.Filter.MatchType(metrics.TypeGauge) {
+		for name, value := range gauges {
+			list = append(list, metrics.ListedMetric{Type: metrics.TypeGauge, Name: name, Gauge: value})
+		}
+	}
+	if query.Filter.MatchType(metrics.TypeCounter) {
+		for name, value := range counters {
+			list = append(list, metrics.ListedMetric{Type: metrics.TypeCounter, Name: name, Counter: value})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return query.Less(list[i].Key(), list[j].Key())
+	})
+	if errGauge != nil {
+		return list, errGauge
+	}
+	return list, errCounter
+}
 
-	gaugeList := make([]ShowedMetrics, 0, len(gauges))
-	for name, value := range gauges {
-		gaugeList = append(gaugeList, ShowedMetrics{
-			Name:  name,
-			Value: value.ToString(),
-		})
+// findMetric метрика с типом и именем. Время обновления берётся из списка, если хранилище умеет его отдавать
+func findMetric(ctx context.Context, storage metrics.IStorage, metricType, name string) (metrics.ListedMetric, bool) {
+	metric := metrics.ListedMetric{Type: metricType, Name: name}
+	var (
+		ok  bool
+		err error
+	)
+	switch metricType {
+	case metrics.TypeGauge:
+		metric.Gauge, ok, err = metrics.WithContext(storage).GetGaugeContext(ctx, name)
+	case metrics.TypeCounter:
+		metric.Counter, ok, err = metrics.WithContext(storage).GetCounterContext(ctx, name)
 	}
-	counterList := make([]ShowedMetrics, 0, len(counters))
-	for name, value := range counters {
-		counterList = append(counterList, ShowedMetrics{
-			Name:  name,
-			Value: value.ToString(),
-		})
+	if err != nil {
+		logger.Log.Error(err)
 	}
-	data := struct {
-		GaugeList   []ShowedMetrics
-		CounterList []ShowedMetrics
-	}{
-		GaugeList:   gaugeList,
-		CounterList: counterList,
+	if !ok {
+		return metric, false
+	}
+	if st, isListing := storage.(metrics.IListingStorage); isListing {
+		// Имя сортируется раньше всех имён, которые начинаются с него
+		list, err := metrics.ListContext(ctx, st, metrics.ListQuery{
+			Filter: metrics.ListFilter{Type: metricType, Prefix: name},
+			Sort:   metrics.ListSortName,
+			Limit:  1,
+		})
+		if err != nil {
+			logger.Log.Error(err)
+		} else if len(list) == 1 && list[0].Name == name {
+			metric.UpdatedAt = list[0].UpdatedAt
+		}
 	}
-	var buff bytes.Buffer                         // Создание буфера для сохранения результата побработки шаблона
-	err := t.ExecuteTemplate(&buff, "base", data) // Подключение шиблона к странице
+	return metric, true
+}
+
+// render вывод страницы по шаблону
+func render(response http.ResponseWriter, page *template.Template, data any) {
+	var buff bytes.Buffer                            // Создание буфера для сохранения результата побработки шаблона
+	err := page.ExecuteTemplate(&buff, "base", data) // Подключение шиблона к странице
 	if err != nil {
 		helpers.SetHTTPResponse(response, http.StatusInternalServerError, []byte(err.Error()))
 		return
@@ -85,8 +392,16 @@ func Handler(response http.ResponseWriter, request *http.Request) {
 	}
 }
 
-// ShowedMetrics представляет собой структурированную метрику с именем и значением, которые будут отображаться в пользовательском интерфейсе.
-type ShowedMetrics struct {
-	Name  string
-	Value string
+// formatAge короткая запись промежутка времени
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return strconv.Itoa(int(max(age, 0)/time.Second)) + " с"
+	case age < time.Hour:
+		return strconv.Itoa(int(age/time.Minute)) + " мин"
+	case age < 24*time.Hour:
+		return strconv.Itoa(int(age/time.Hour)) + " ч"
+	default:
+		return strconv.Itoa(int(age/(24*time.Hour))) + " д"
+	}
 }
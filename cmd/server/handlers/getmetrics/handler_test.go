@@ -1,10 +1,15 @@
 package getmetrics
 
 import (
+	"context"
+	"gmetrics/internal/history"
 	"gmetrics/internal/metrics"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
@@ -104,3 +109,138 @@ func TestHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerSortedAndFiltered(t *testing.T) {
+	store := metrics.NewMemStorage()
+	_ = store.SetGauge("b_gauge", 2)
+	_ = store.SetGauge("a_gauge", 1)
+	_ = store.AddCounter("c_counter", 3)
+	metrics.MeStore = store
+
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/?q=A_G", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	body := response.Body.String()
+	// Метрики отсортированы по имени
+	assert.Less(t, strings.Index(body, `data-name="a_gauge"`), strings.Index(body, `data-name="b_gauge"`))
+	// Неподходящие под поиск строки скрыты
+	assert.Contains(t, body, `data-name="b_gauge" data-updated=`)
+	assert.Regexp(t, `data-name="b_gauge" data-updated="\d+" hidden`, body)
+	assert.NotRegexp(t, `data-name="a_gauge" data-updated="\d+" hidden`, body)
+	assert.Contains(t, body, `href="/metric/gauge/a_gauge"`)
+
+	response = httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/?type=counter", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), "c_counter")
+	assert.NotContains(t, response.Body.String(), "a_gauge")
+}
+
+func TestHandlerDistributions(t *testing.T) {
+	store := metrics.NewMemStorage()
+	_ = store.AddHistogram("latency", metrics.Histogram{Bounds: []float64{0.1, 1}, Counts: []uint64{2, 1, 0}, Sum: 0.7, Count: 3})
+	_ = store.AddSummary("size", []float64{10, 20})
+	_ = store.AddSet("users", metrics.NewSketch("alice", "bob"))
+	metrics.MeStore = store
+
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/?q=lat", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	body := response.Body.String()
+	assert.Contains(t, body, "≤0.1: 2, ≤1: 1, ≤∞: 0")
+	assert.Contains(t, body, "p50: 10, p90: 20, p99: 20")
+	// Гистограммы и сводки не обновляются скриптом, поэтому у строк нет data-key
+	assert.NotContains(t, body, `data-key="histogram/latency"`)
+	assert.Contains(t, body, `<tr data-name="latency">`)
+	assert.Contains(t, body, `<tr data-name="size" hidden>`)
+	assert.Contains(t, body, `<tr data-name="users" hidden>`)
+	assert.Contains(t, body, "≈2")
+
+	response = httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/?type=gauge", nil))
+	assert.NotContains(t, response.Body.String(), "latency")
+}
+
+func TestHandlerMetadata(t *testing.T) {
+	store := metrics.NewMemStorage()
+	_ = store.SetGauge("Alloc", 1)
+	_ = store.SetMetadata(metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}, metrics.Metadata{Unit: "bytes", Help: "Allocated heap"})
+	metrics.MeStore = store
+
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	body := response.Body.String()
+	assert.Contains(t, body, `<a href="/metric/gauge/Alloc" title="Allocated heap">Alloc</a>`)
+	assert.Contains(t, body, `<td class="muted">bytes</td>`)
+}
+
+func TestMetricHandler(t *testing.T) {
+	store := metrics.NewMemStorage()
+	_ = store.SetGauge(`requests{method="GET"}`, 2.5)
+	_ = store.SetMetadata(metrics.ListKey{Type: metrics.TypeCounter, Name: "PollCount"}, metrics.Metadata{Unit: "polls", Help: "Number of polls", Owner: "agent"})
+	_ = store.AddCounter("PollCount", 3)
+	metrics.MeStore = store
+	history.Recent = history.New(history.DefaultSize)
+	defer func() { history.Recent = nil }()
+	start := time.Now()
+	history.Recent.Record(metrics.ListKey{Type: metrics.TypeGauge, Name: `requests{method="GET"}`}, history.Point{Time: start, Value: 1})
+	history.Recent.Record(metrics.ListKey{Type: metrics.TypeGauge, Name: `requests{method="GET"}`}, history.Point{Time: start.Add(time.Minute), Value: 2.5})
+
+	tests := []struct {
+		name         string
+		url          string
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name:         "gauge_with_history",
+			url:          "/metric/gauge/" + url.PathEscape(`requests{method="GET"}`),
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"2.5", "<polyline", `points="2.0,58.0 298.0,2.0"`, "<dt>method</dt>", "2 точек за 1 мин"},
+		},
+		{
+			name:         "counter_without_history",
+			url:          "/metric/counter/PollCount",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"PollCount", "Недостаточно данных", "<p>Number of polls</p>", "3 polls", "<dd>agent</dd>"},
+		},
+		{name: "not_found", url: "/metric/gauge/PollCount", wantStatus: http.StatusNotFound},
+		{name: "wrong_type", url: "/metric/timer/PollCount", wantStatus: http.StatusNotFound},
+	}
+	router := chi.NewRouter()
+	router.Get("/metric/{type}/{name}", MetricHandler)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := resty.New().R().Get(srv.URL + tc.url)
+			assert.NoError(t, err, "error making HTTP request")
+			assert.Equal(t, tc.wantStatus, res.StatusCode())
+			for _, want := range tc.wantContains {
+				assert.Contains(t, string(res.Body()), want)
+			}
+		})
+	}
+}
+
+func TestLoadMetricsWithoutListing(t *testing.T) {
+	store := metrics.NewMemStorage()
+	_ = store.SetGauge("b", 2)
+	_ = store.AddCounter("a", 1)
+	list, err := loadMetrics(context.Background(), struct{ metrics.IStorage }{store}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []metrics.ListedMetric{
+		{Type: metrics.TypeCounter, Name: "a", Counter: 1},
+		{Type: metrics.TypeGauge, Name: "b", Gauge: 2},
+	}, list)
+}
+
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "0 с", formatAge(-time.Second))
+	assert.Equal(t, "45 с", formatAge(45*time.Second))
+	assert.Equal(t, "3 мин", formatAge(3*time.Minute+10*time.Second))
+	assert.Equal(t, "2 ч", formatAge(2*time.Hour))
+	assert.Equal(t, "3 д", formatAge(72*time.Hour))
+}
@@ -0,0 +1,63 @@
+package getmetrics
+
+import (
+	"gmetrics/internal/history"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// sparklineWidth ширина графика в пикселях
+	sparklineWidth = 300
+	// sparklineHeight высота графика в пикселях
+	sparklineHeight = 60
+	// sparklinePadding отступ линии от краёв графика, чтобы её не обрезало
+	sparklinePadding = 2
+)
+
+// sparkline координаты ломаной для атрибута points элемента polyline.
+// Точки равномерно распределяются по ширине, значения масштабируются по высоте между минимумом и максимумом.
+// Пустая строка, если точек меньше двух. NaN и бесконечности пропускаются
+func sparkline(points []history.Point, width, height int) string {
+	values := make([]float64, 0, len(points))
+	for _, point := range points {
+		if !math.IsNaN(point.Value) && !math.IsInf(point.Value, 0) {
+			values = append(values, point.Value)
+		}
+	}
+	if len(values) < 2 {
+		return ""
+	}
+	low, high := values[0], values[0]
+	for _, value := range values {
+		low, high = math.Min(low, value), math.Max(high, value)
+	}
+	innerWidth := float64(width - 2*sparklinePadding)
+	innerHeight := float64(height - 2*sparklinePadding)
+	var b strings.Builder
+	for i, value := range values {
+		x := sparklinePadding + innerWidth*float64(i)/float64(len(values)-1)
+		// Постоянное значение рисуется посередине
+		y := sparklinePadding + innerHeight/2
+		if high > low {
+			y = sparklinePadding + innerHeight*(high-value)/(high-low)
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.FormatFloat(x, 'f', 1, 64))
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatFloat(y, 'f', 1, 64))
+	}
+	return b.String()
+}
+
+// bounds наименьшее и наибольшее значения точек
+func bounds(points []history.Point) (float64, float64) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, point := range points {
+		low, high = math.Min(low, point.Value), math.Max(high, point.Value)
+	}
+	return low, high
+}
@@ -0,0 +1,41 @@
+package getmetrics
+
+import (
+	"gmetrics/internal/history"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	points := func(values ...float64) []history.Point {
+		result := make([]history.Point, 0, len(values))
+		for _, value := range values {
+			result = append(result, history.Point{Value: value})
+		}
+		return result
+	}
+	tests := []struct {
+		name   string
+		points []history.Point
+		want   string
+	}{
+		{name: "empty", points: nil, want: ""},
+		{name: "one_point", points: points(1), want: ""},
+		{name: "rising", points: points(0, 5, 10), want: "2.0,18.0 50.0,10.0 98.0,2.0"},
+		{name: "constant", points: points(3, 3), want: "2.0,10.0 98.0,10.0"},
+		{name: "skip_nan", points: points(0, math.NaN(), 10), want: "2.0,18.0 98.0,2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sparkline(tt.points, 100, 20))
+		})
+	}
+}
+
+func TestBounds(t *testing.T) {
+	low, high := bounds([]history.Point{{Value: 3}, {Value: -1}, {Value: 2}})
+	assert.Equal(t, float64(-1), low)
+	assert.Equal(t, float64(3), high)
+}
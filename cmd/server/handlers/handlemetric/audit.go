@@ -0,0 +1,304 @@
+package handlemetric
+
+import (
+	"context"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/middlewares"
+	"gmetrics/internal/tenant"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// httpSource источник записи по http запросу. Адрес клиента берётся из X-Real-IP, а если его нет, то из соединения
+func httpSource(request *http.Request, transport string) audit.Source {
+	ip := request.Header.Get("X-Real-IP")
+	if ip == "" {
+		ip = hostFromAddr(request.RemoteAddr)
+	}
+	return audit.Source{
+		Transport: transport,
+		ClientIP:  ip,
+		ClientID:  middlewares.GetClientID(request.Context()),
+		Tenant:    tenant.FromContext(request.Context()).Name,
+		PeerIP:    hostFromAddr(request.RemoteAddr),
+	}
+}
+
+// rpcSource источник записи по rpc запросу
+func rpcSource(ctx context.Context) audit.Source {
+	var ip, peerIP string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if h := md.Get("X-Real-IP"); len(h) > 0 {
+			ip = h[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerIP = hostFromAddr(p.Addr.String())
+	}
+	if ip == "" {
+		ip = peerIP
+	}
+	return audit.Source{
+		Transport: audit.TransportRPC,
+		ClientIP:  ip,
+		ClientID:  middlewares.GetClientID(ctx),
+		Tenant:    tenant.FromContext(ctx).Name,
+		PeerIP:    peerIP,
+	}
+}
+
+// seriesClient клиент, за которым учитываются ряды: идентификатор клиента, а у анонимного клиента - адрес соединения,
+// как при ограничении частоты запросов
+func seriesClient(src audit.Source) string {
+	if src.ClientID != "" {
+		return src.ClientID
+	}
+	return src.PeerIP
+}
+
+// hostFromAddr получение ip адреса из адреса вида host:port
+func hostFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// setGauge запись gauge. Если аудит включён, то возвращает изменение значения для журнала аудита
+func setGauge(ctx context.Context, store metrics.IStorage, name string, value metrics.Gauge) (audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return audit.Change{}, metrics.WithContext(store).SetGaugeContext(ctx, name, value)
+	}
+	changes, err := setGauges(ctx, store, map[string]metrics.Gauge{name: value})
+	if err != nil {
+		return audit.Change{}, err
+	}
+	return changes[0], nil
+}
+
+// setGauges массовая запись gauge. Если аудит включён, то хранилище возвращает прежние значения
+// под блокировкой записи, и по ним строятся изменения для журнала аудита
+func setGauges(ctx context.Context, store metrics.IStorage, gauges map[string]metrics.Gauge) ([]audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return nil, metrics.WithContext(store).SetGaugesContext(ctx, gauges)
+	}
+	old, err := metrics.SetGaugesReturningOld(ctx, store, gauges)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]audit.Change, 0, len(gauges))
+	for name, value := range gauges {
+		change := audit.Change{Name: name, Type: metrics.TypeGauge, New: value.GetRaw()}
+		if oldValue, ok := old[name]; ok {
+			change.Old = oldValue.GetRaw()
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// addCounter прибавление к counter. Если аудит включён, то возвращает изменение значения для журнала аудита
+func addCounter(ctx context.Context, store metrics.IStorage, name string, delta metrics.Counter) (audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return audit.Change{}, metrics.WithContext(store).AddCounterContext(ctx, name, delta)
+	}
+	changes, err := addCounters(ctx, store, map[string]metrics.Counter{name: delta})
+	if err != nil {
+		return audit.Change{}, err
+	}
+	return changes[0], nil
+}
+
+// addCounters массовое прибавление к counter. Если аудит включён, то хранилище возвращает значения
+// до и после прибавления под блокировкой записи, и по ним строятся изменения для журнала аудита
+func addCounters(ctx context.Context, store metrics.IStorage, counters map[string]metrics.Counter) ([]audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return nil, metrics.WithContext(store).AddCountersContext(ctx, counters)
+	}
+	old, updated, err := metrics.AddCountersReturningNew(ctx, store, counters)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]audit.Change, 0, len(counters))
+	for name := range counters {
+		change := audit.Change{Name: name, Type: metrics.TypeCounter, New: updated[name].GetRaw()}
+		if oldValue, ok := old[name]; ok {
+			change.Old = oldValue.GetRaw()
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// addHistogram прибавление гистограммы. Если аудит включён, то хранилище возвращает гистограммы
+// до и после прибавления под блокировкой записи, и по количеству наблюдений в них строится изменение для журнала аудита
+func addHistogram(ctx context.Context, store metrics.IStorage, name string, histogram metrics.Histogram) (audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return audit.Change{}, metrics.AddHistogramContext(ctx, store, name, histogram)
+	}
+	old, updated, err := metrics.AddHistogramReturningNew(ctx, store, name, histogram)
+	if err != nil {
+		return audit.Change{}, err
+	}
+	change := audit.Change{Name: name, Type: metrics.TypeHistogram, New: updated.Count}
+	if old != nil {
+		change.Old = old.Count
+	}
+	return change, nil
+}
+
+// addSummary добавление наблюдений сводки. Изменение строится по количеству наблюдений сводки за окно до и после добавления
+func addSummary(ctx context.Context, store metrics.IStorage, name string, values []float64) (audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return audit.Change{}, metrics.AddSummaryContext(ctx, store, name, values)
+	}
+	old, updated, err := metrics.AddSummaryReturningNew(ctx, store, name, values)
+	if err != nil {
+		return audit.Change{}, err
+	}
+	change := audit.Change{Name: name, Type: metrics.TypeSummary, New: updated.Count}
+	if old != nil {
+		change.Old = old.Count
+	}
+	return change, nil
+}
+
+// addSet объединение скетча с сохранённым. Изменение строится по оценке количества элементов до и после объединения
+func addSet(ctx context.Context, store metrics.IStorage, name string, sketch metrics.Sketch) (audit.Change, error) {
+	if !audit.Log.Enabled() {
+		return audit.Change{}, metrics.AddSetContext(ctx, store, name, sketch)
+	}
+	old, updated, err := metrics.AddSetReturningNew(ctx, store, name, sketch)
+	if err != nil {
+		return audit.Change{}, err
+	}
+	change := audit.Change{Name: name, Type: metrics.TypeSet, New: updated.Estimate()}
+	if old != nil {
+		change.Old = old.Estimate()
+	}
+	return change, nil
+}
+
+// deleteChange изменение метрики при удалении по удалённому значению old, которое вернуло хранилище
+func deleteChange(metricType, name string, old any) audit.Change {
+	change := audit.Change{Name: name, Type: metricType}
+	switch value := old.(type) {
+	case metrics.Gauge:
+		change.Old = value.GetRaw()
+	case metrics.Counter:
+		change.Old = value.GetRaw()
+	case metrics.Histogram:
+		change.Old = value.Count
+	case metrics.SummaryValue:
+		change.Old = value.Count
+	case metrics.Sketch:
+		change.Old = value.Estimate()
+	}
+	return change
+}
+
+// resetChange изменение counter при обнулении по значению old до обнуления, которое вернуло хранилище
+func resetChange(name string, old metrics.Counter) audit.Change {
+	return audit.Change{Name: name, Type: metrics.TypeCounter, Old: old.GetRaw(), New: metrics.Counter(0).GetRaw()}
+}
+
+// sortChanges сортировка изменений по типу и имени метрики
+func sortChanges(changes []audit.Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Type != changes[j].Type {
+			return changes[i].Type < changes[j].Type
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// importChanges изменения метрик до загрузки снимка, отсортированные по типу и имени.
+// Если replace, то в изменения попадают и удаляемые метрики, которых нет в снимке
+func importChanges(store metrics.IStorage, list []metrics.ListedMetric, replace bool) []audit.Change {
+	changes := make([]audit.Change, 0, len(list))
+	imported := make(map[metrics.ListKey]struct{}, len(list))
+	for _, metric := range list {
+		imported[metric.Key()] = struct{}{}
+		change := audit.Change{Name: metric.Name, Type: metric.Type}
+		switch metric.Type {
+		case metrics.TypeGauge:
+			change.New = metric.Gauge.GetRaw()
+			if old, ok := store.GetGauge(metric.Name); ok {
+				change.Old = old.GetRaw()
+			}
+		case metrics.TypeCounter:
+			change.New = metric.Counter.GetRaw()
+			if old, ok := store.GetCounter(metric.Name); ok {
+				change.Old = old.GetRaw()
+			}
+		case metrics.TypeHistogram:
+			change.New = metric.Histogram.Count
+			if old, ok := metrics.GetHistogramByName(store, metric.Name); ok {
+				change.Old = old.Count
+			}
+		case metrics.TypeSummary:
+			change.New = metric.Summary.Value(time.Now(), metrics.DefaultSummaryWindow).Count
+			if old, ok := metrics.GetSummaryByName(store, metric.Name); ok {
+				change.Old = old.Count
+			}
+		case metrics.TypeSet:
+			change.New = metric.Set.Estimate()
+			if old, ok := metrics.GetSetByName(store, metric.Name); ok {
+				change.Old = old.Estimate()
+			}
+		}
+		changes = append(changes, change)
+	}
+	if replace {
+		gauges, err := store.GetGauges()
+		if err != nil {
+			logger.Log.Error(err)
+		}
+		for name, value := range gauges {
+			if _, ok := imported[metrics.ListKey{Type: metrics.TypeGauge, Name: name}]; !ok {
+				changes = append(changes, deleteChange(metrics.TypeGauge, name, value))
+			}
+		}
+		counters, err := store.GetCounters()
+		if err != nil {
+			logger.Log.Error(err)
+		}
+		for name, value := range counters {
+			if _, ok := imported[metrics.ListKey{Type: metrics.TypeCounter, Name: name}]; !ok {
+				changes = append(changes, deleteChange(metrics.TypeCounter, name, value))
+			}
+		}
+		histograms, summaries, err := metrics.GetDistributions(store)
+		if err != nil {
+			logger.Log.Error(err)
+		}
+		for name, value := range histograms {
+			if _, ok := imported[metrics.ListKey{Type: metrics.TypeHistogram, Name: name}]; !ok {
+				changes = append(changes, deleteChange(metrics.TypeHistogram, name, value))
+			}
+		}
+		for name, value := range summaries {
+			if _, ok := imported[metrics.ListKey{Type: metrics.TypeSummary, Name: name}]; !ok {
+				changes = append(changes, deleteChange(metrics.TypeSummary, name, value))
+			}
+		}
+		sets, err := metrics.GetSets(store)
+		if err != nil {
+			logger.Log.Error(err)
+		}
+		for name, value := range sets {
+			if _, ok := imported[metrics.ListKey{Type: metrics.TypeSet, Name: name}]; !ok {
+				changes = append(changes, deleteChange(metrics.TypeSet, name, value))
+			}
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
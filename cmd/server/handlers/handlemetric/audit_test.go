@@ -0,0 +1,189 @@
+package handlemetric
+
+import (
+	"context"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/middlewares"
+	"gmetrics/internal/payload"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// auditSink приёмник, запоминающий события аудита
+type auditSink struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *auditSink) Write(events []audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *auditSink) Close() error {
+	return nil
+}
+
+// withAudit включает глобальный аудит на время теста
+func withAudit(t *testing.T) (*auditSink, func()) {
+	sink := &auditSink{}
+	auditor, err := audit.New(0, sink)
+	require.NoError(t, err)
+	audit.Log = auditor
+	return sink, func() {
+		require.NoError(t, auditor.Close())
+		audit.Log = nil
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		realIP   string
+		clientID string
+		wantIP   string
+	}{
+		{name: "remote_addr", wantIP: "192.0.2.1"},
+		{name: "real_ip", realIP: "10.0.0.5", clientID: "agent-1", wantIP: "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/update", nil)
+			if tt.realIP != "" {
+				request.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.clientID != "" {
+				request = request.WithContext(middlewares.WithClientID(request.Context(), tt.clientID))
+			}
+			src := httpSource(request, audit.TransportJSON)
+			assert.Equal(t, audit.Source{Transport: audit.TransportJSON, ClientIP: tt.wantIP, ClientID: tt.clientID, PeerIP: "192.0.2.1"}, src)
+		})
+	}
+}
+
+func TestRPCSource(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5000}})
+	assert.Equal(t, audit.Source{Transport: audit.TransportRPC, ClientIP: "192.0.2.7", PeerIP: "192.0.2.7"}, rpcSource(ctx))
+
+	ctx = metadata.NewIncomingContext(middlewares.WithClientID(ctx, "agent-2"), metadata.Pairs("X-Real-IP", "10.0.0.9"))
+	assert.Equal(t, audit.Source{Transport: audit.TransportRPC, ClientIP: "10.0.0.9", ClientID: "agent-2", PeerIP: "192.0.2.7"}, rpcSource(ctx))
+}
+
+func TestSeriesClient(t *testing.T) {
+	assert.Equal(t, "agent-1", seriesClient(audit.Source{ClientID: "agent-1", PeerIP: "192.0.2.1"}))
+	assert.Equal(t, "192.0.2.1", seriesClient(audit.Source{ClientIP: "10.0.0.5", PeerIP: "192.0.2.1"}))
+}
+
+func TestUpdateMetricsAudit(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	require.NoError(t, metrics.MeStore.SetGauge("Alloc", 1.5))
+	require.NoError(t, metrics.MeStore.AddCounter("PollCount", 2))
+	sink, closeAudit := withAudit(t)
+
+	src := audit.Source{Transport: audit.TransportBatch, ClientIP: "10.0.0.1", ClientID: "agent-1"}
+	value, delta := 2.5, int64(3)
+	_, err := updateMetricsByRequestBody(context.Background(), src, []payload.Metrics{
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta},
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: &value},
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta},
+	})
+	require.NoError(t, err)
+	require.NoError(t, updateMetricByStringValue(context.Background(), audit.Source{Transport: audit.TransportURL}, metrics.TypeGauge, "Frees", "7"))
+	// Отклонённые записи не попадают в журнал
+	assert.Error(t, updateMetricByRequestBody(context.Background(), audit.Source{Transport: audit.TransportJSON}, payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge}))
+	closeAudit()
+
+	require.Len(t, sink.events, 2)
+	batch := sink.events[0]
+	assert.Equal(t, audit.TransportBatch, batch.Transport)
+	assert.Equal(t, "10.0.0.1", batch.ClientIP)
+	assert.Equal(t, "agent-1", batch.ClientID)
+	assert.False(t, batch.Time.IsZero())
+	assert.Equal(t, []audit.Change{
+		{Name: "PollCount", Type: metrics.TypeCounter, Old: int64(2), New: int64(8)},
+		{Name: "Alloc", Type: metrics.TypeGauge, Old: 1.5, New: 2.5},
+	}, batch.Metrics)
+	assert.Equal(t, []audit.Change{{Name: "Frees", Type: metrics.TypeGauge, New: float64(7)}}, sink.events[1].Metrics)
+}
+
+func TestUpdateDistributionsAudit(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	store := metrics.MeStore.(metrics.IDistributionStorage)
+	require.NoError(t, store.AddSummary("Size", []float64{1}))
+	sink, closeAudit := withAudit(t)
+
+	value := 0.3
+	_, err := updateMetricsByRequestBody(context.Background(), audit.Source{Transport: audit.TransportBatch}, []payload.Metrics{
+		{ID: "Size", MType: metrics.TypeSummary, Observations: []float64{2, 3}},
+		{ID: "Latency", MType: metrics.TypeHistogram, Value: &value},
+	})
+	require.NoError(t, err)
+	require.NoError(t, updateMetricByStringValue(context.Background(), audit.Source{Transport: audit.TransportURL}, metrics.TypeHistogram, "Latency", "1"))
+	require.NoError(t, deleteMetric(context.Background(), audit.Source{Transport: audit.TransportURL}, metrics.TypeSummary, "Size"))
+	closeAudit()
+
+	require.Len(t, sink.events, 3)
+	assert.Equal(t, []audit.Change{
+		{Name: "Latency", Type: metrics.TypeHistogram, New: uint64(1)},
+		{Name: "Size", Type: metrics.TypeSummary, Old: uint64(1), New: uint64(3)},
+	}, sink.events[0].Metrics)
+	assert.Equal(t, []audit.Change{{Name: "Latency", Type: metrics.TypeHistogram, Old: uint64(1), New: uint64(2)}}, sink.events[1].Metrics)
+	assert.Equal(t, []audit.Change{{Name: "Size", Type: metrics.TypeSummary, Old: uint64(3)}}, sink.events[2].Metrics)
+}
+
+func TestUpdateSetsAudit(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	require.NoError(t, metrics.MeStore.(metrics.ISetStorage).AddSet("Users", metrics.NewSketch("alice")))
+	sink, closeAudit := withAudit(t)
+
+	_, err := updateMetricsByRequestBody(context.Background(), audit.Source{Transport: audit.TransportBatch}, []payload.Metrics{
+		{ID: "Users", MType: metrics.TypeSet, Members: []string{"alice", "bob"}},
+		{ID: "Users", MType: metrics.TypeSet, Members: []string{"carol"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, deleteMetric(context.Background(), audit.Source{Transport: audit.TransportURL}, metrics.TypeSet, "Users"))
+	closeAudit()
+
+	require.Len(t, sink.events, 2)
+	assert.Equal(t, []audit.Change{{Name: "Users", Type: metrics.TypeSet, Old: uint64(1), New: uint64(3)}}, sink.events[0].Metrics)
+	assert.Equal(t, []audit.Change{{Name: "Users", Type: metrics.TypeSet, Old: uint64(3)}}, sink.events[1].Metrics)
+}
+
+func TestUpdateCounterAudit_Concurrent(t *testing.T) {
+	metrics.MeStore = metrics.NewShardedMemStorage(4)
+	sink, closeAudit := withAudit(t)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, updateMetricByStringValue(context.Background(), audit.Source{Transport: audit.TransportURL}, metrics.TypeCounter, "PollCount", "1"))
+		}()
+	}
+	wg.Wait()
+	closeAudit()
+
+	// Каждое событие содержит значения своей записи: новое на единицу больше старого и не повторяется
+	require.Len(t, sink.events, writers)
+	seen := make(map[int64]struct{}, writers)
+	for _, event := range sink.events {
+		require.Len(t, event.Metrics, 1)
+		updated := event.Metrics[0].New.(int64)
+		old, _ := event.Metrics[0].Old.(int64)
+		assert.Equal(t, old+1, updated)
+		seen[updated] = struct{}{}
+	}
+	assert.Len(t, seen, writers)
+}
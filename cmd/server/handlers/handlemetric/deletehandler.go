@@ -0,0 +1,78 @@
+package handlemetric
+
+import (
+	"errors"
+	"fmt"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DeleteHandler Обработка запроса удаления метрики по типу и имени из урл
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Удаление метрики
+// @Description Удаление метрики по типу и имени. Требует токен с областью действия admin
+// @Tags Метрики
+// @Produce json
+// @Param type path string true "Metric type"
+// @Param name path string true "Metric name"
+// @Success 200 {object} payload.ResponseBody "Метрика удалена"
+// @Failure 400 {object} payload.ErrorResponse "Неверный тип метрики"
+// @Failure 404 {object} payload.ErrorResponse "Метрика не найдена"
+// @Failure 500 {object} payload.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /value/{type}/{name} [delete]
+func DeleteHandler(response http.ResponseWriter, request *http.Request) {
+	metricType := chi.URLParam(request, "type")
+	metricName := chi.URLParam(request, "name")
+	err := deleteMetric(request.Context(), httpSource(request, audit.TransportURL), metricType, metricName)
+	writeAdminResponse(response, err, fmt.Sprintf("metric %s successfully deleted", metricName))
+}
+
+// ResetHandler Обработка запроса обнуления counter по имени из урл
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Обнуление counter
+// @Description Обнуление counter по имени. Требует токен с областью действия admin
+// @Tags Метрики
+// @Produce json
+// @Param name path string true "Metric name"
+// @Success 200 {object} payload.ResponseBody "Counter обнулён"
+// @Failure 404 {object} payload.ErrorResponse "Метрика не найдена"
+// @Failure 500 {object} payload.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /reset/counter/{name} [post]
+func ResetHandler(response http.ResponseWriter, request *http.Request) {
+	metricName := chi.URLParam(request, "name")
+	err := resetCounter(request.Context(), httpSource(request, audit.TransportURL), metricName)
+	writeAdminResponse(response, err, fmt.Sprintf("counter %s successfully reset", metricName))
+}
+
+// writeAdminResponse ответ на запрос изменения хранилища: ошибка или сообщение об успехе
+func writeAdminResponse(response http.ResponseWriter, err error, message string) {
+	if err == nil {
+		var rBody []byte
+		rBody, err = createEmptyResponse(message)
+		if err == nil {
+			response.WriteHeader(http.StatusOK)
+			if _, wErr := response.Write(rBody); wErr != nil {
+				logger.Log.Error(wErr)
+			}
+			return
+		}
+	}
+	var metricErr *UpdateMetricError
+	if errors.As(err, &metricErr) {
+		helpers.SetHTTPResponse(response, metricErr.HTTPStatus, errorJSONBody(err))
+	} else {
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+	}
+}
@@ -0,0 +1,92 @@
+package handlemetric
+
+import (
+	"context"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "delete_gauge", url: "/value/gauge/Alloc", wantStatus: http.StatusOK},
+		{name: "delete_counter", url: "/value/counter/PollCount", wantStatus: http.StatusOK},
+		{name: "not_found", url: "/value/gauge/PollCount", wantStatus: http.StatusNotFound},
+		{name: "delete_histogram", url: "/value/histogram/Latency", wantStatus: http.StatusOK},
+		{name: "wrong_type", url: "/value/timer/Alloc", wantStatus: http.StatusBadRequest},
+	}
+	router := chi.NewRouter()
+	router.Delete("/value/{type}/{name}", DeleteHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.MeStore = metrics.NewMemStorage()
+			require.NoError(t, metrics.MeStore.SetGauge("Alloc", 1.5))
+			require.NoError(t, metrics.MeStore.AddCounter("PollCount", 3))
+			require.NoError(t, metrics.MeStore.(metrics.IDistributionStorage).AddSummary("Latency", []float64{1}))
+			require.NoError(t, metrics.MeStore.(metrics.IDistributionStorage).AddHistogram("Latency", metrics.NewHistogram(nil)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.url, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), "successfully deleted")
+			}
+		})
+	}
+}
+
+func TestResetHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "reset", url: "/reset/counter/PollCount", wantStatus: http.StatusOK},
+		{name: "not_found", url: "/reset/counter/Alloc", wantStatus: http.StatusNotFound},
+	}
+	router := chi.NewRouter()
+	router.Post("/reset/counter/{name}", ResetHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.MeStore = metrics.NewMemStorage()
+			require.NoError(t, metrics.MeStore.AddCounter("PollCount", 3))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			value, _ := metrics.MeStore.GetCounter("PollCount")
+			assert.Equal(t, tt.wantStatus != http.StatusOK, value == 3)
+		})
+	}
+}
+
+func TestDeleteAndResetAudit(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	require.NoError(t, metrics.MeStore.SetGauge("Alloc", 1.5))
+	require.NoError(t, metrics.MeStore.AddCounter("PollCount", 3))
+	sink, closeAudit := withAudit(t)
+
+	src := audit.Source{Transport: audit.TransportURL, ClientID: "admin"}
+	require.NoError(t, deleteMetric(context.Background(), src, metrics.TypeGauge, "Alloc"))
+	require.NoError(t, resetCounter(context.Background(), src, "PollCount"))
+	assert.Error(t, deleteMetric(context.Background(), src, metrics.TypeGauge, "Alloc"))
+	closeAudit()
+
+	require.Len(t, sink.events, 2)
+	assert.Equal(t, audit.ActionDelete, sink.events[0].Action)
+	assert.Equal(t, []audit.Change{{Name: "Alloc", Type: metrics.TypeGauge, Old: 1.5}}, sink.events[0].Metrics)
+	assert.Equal(t, audit.ActionReset, sink.events[1].Action)
+	assert.Equal(t, []audit.Change{{Name: "PollCount", Type: metrics.TypeCounter, Old: int64(3), New: int64(0)}}, sink.events[1].Metrics)
+}
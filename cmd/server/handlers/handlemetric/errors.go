@@ -1,7 +1,14 @@
 package handlemetric
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/validation"
 	"net/http"
 )
 
@@ -46,3 +53,94 @@ var BadRequestError = &UpdateMetricError{
 	error:      errors.New("invalid body"),
 	HTTPStatus: http.StatusBadRequest,
 }
+
+// BodyTooLargeError представляет ошибку, когда тело запроса больше допустимого размера.
+var BodyTooLargeError = &UpdateMetricError{
+	error:      errors.New("request body too large"),
+	HTTPStatus: http.StatusRequestEntityTooLarge,
+}
+
+// TooManyMetricsError представляет ошибку, когда в запросе больше метрик, чем разрешено за раз.
+var TooManyMetricsError = &UpdateMetricError{
+	error:      errors.New("too many metrics in request"),
+	HTTPStatus: http.StatusRequestEntityTooLarge,
+}
+
+// InvalidMetricsError представляет ошибку, когда политика сервера отклонила метрики пакета.
+var InvalidMetricsError = &UpdateMetricError{
+	error:      errors.New("invalid metrics"),
+	HTTPStatus: http.StatusBadRequest,
+}
+
+// BatchError ошибка пакета вместе с ошибками каждой отклонённой метрики
+type BatchError struct {
+	*UpdateMetricError
+	Items []payload.ItemError
+}
+
+// Unwrap возвращает ошибку пакета, чтобы её можно было получить через errors.As как UpdateMetricError
+func (e *BatchError) Unwrap() error {
+	return e.UpdateMetricError
+}
+
+// policyError ошибка для ответа по ошибке проверки метрики политикой сервера
+func policyError(err error) *UpdateMetricError {
+	if errors.Is(err, validation.ErrorNameNotAllowed) {
+		return &UpdateMetricError{err, http.StatusForbidden}
+	}
+	return &UpdateMetricError{err, http.StatusBadRequest}
+}
+
+// errorJSONBody тело ответа с ошибкой. Если в пакете отклонены отдельные метрики, то перечисляются их ошибки
+func errorJSONBody(err error) []byte {
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		return helpers.GetErrorJSONBody(err.Error())
+	}
+	body, mErr := json.Marshal(payload.ResponseBody{
+		Status:  payload.ResponseErrorStatus,
+		Message: batchErr.Error(),
+		Errors:  batchErr.Items,
+	})
+	if mErr != nil {
+		logger.Log.Error(mErr)
+		return helpers.GetErrorJSONBody(batchErr.Error())
+	}
+	return body
+}
+
+// readBodyError ошибка для ответа, если не удалось прочитать тело запроса
+func readBodyError(err error) *UpdateMetricError {
+	if helpers.ReadBodyErrorStatus(err) == http.StatusRequestEntityTooLarge {
+		return BodyTooLargeError
+	}
+	return BadRequestError
+}
+
+// MetricNotFoundError представляет ошибку, когда метрики нет в хранилище.
+var MetricNotFoundError = &UpdateMetricError{
+	error:      metrics.ErrorMetricNotFound,
+	HTTPStatus: http.StatusNotFound,
+}
+
+// storageError ошибка для ответа по ошибке хранилища
+func storageError(err error) *UpdateMetricError {
+	switch {
+	case errors.Is(err, metrics.ErrorMetricNotFound):
+		return MetricNotFoundError
+	case errors.Is(err, metrics.ErrorUnknownMetricType):
+		return InvalidMetricTypeError
+	case errors.Is(err, metrics.ErrorWrongHistogram), errors.Is(err, metrics.ErrorHistogramBounds), errors.Is(err, metrics.ErrorWrongObservation):
+		return &UpdateMetricError{err, http.StatusBadRequest}
+	case errors.Is(err, metrics.ErrorWrongSketch), errors.Is(err, metrics.ErrorWrongMetadata):
+		return &UpdateMetricError{err, http.StatusBadRequest}
+	case errors.Is(err, metrics.ErrorDistributionNotSupported), errors.Is(err, metrics.ErrorSetNotSupported),
+		errors.Is(err, metrics.ErrorMetadataNotSupported):
+		// Хранилище сервера не умеет хранить гистограммы, сводки, множества или описания метрик
+		return &UpdateMetricError{err, http.StatusNotImplemented}
+	case errors.Is(err, context.DeadlineExceeded):
+		// Хранилище не успело ответить за время запроса
+		return &UpdateMetricError{err, http.StatusGatewayTimeout}
+	}
+	return &UpdateMetricError{err, http.StatusInternalServerError}
+}
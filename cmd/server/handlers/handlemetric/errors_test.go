@@ -2,8 +2,13 @@ package handlemetric
 
 import (
 	"errors"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/validation"
 	"net/http"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestUnwrap(t *testing.T) {
@@ -32,3 +37,46 @@ func TestUnwrap(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "not_found", err: metrics.ErrorMetricNotFound, wantStatus: http.StatusNotFound},
+		{name: "unknown_type", err: metrics.ErrorUnknownMetricType, wantStatus: http.StatusBadRequest},
+		{name: "histogram_bounds", err: metrics.ErrorHistogramBounds, wantStatus: http.StatusBadRequest},
+		{name: "wrong_observation", err: metrics.ErrorWrongObservation, wantStatus: http.StatusBadRequest},
+		{name: "wrong_sketch", err: metrics.ErrorWrongSketch, wantStatus: http.StatusBadRequest},
+		{name: "wrong_metadata", err: metrics.ErrorWrongMetadata, wantStatus: http.StatusBadRequest},
+		{name: "metadata_not_supported", err: metrics.ErrorMetadataNotSupported, wantStatus: http.StatusNotImplemented},
+		{name: "set_not_supported", err: metrics.ErrorSetNotSupported, wantStatus: http.StatusNotImplemented},
+		{name: "distribution_not_supported", err: metrics.ErrorDistributionNotSupported, wantStatus: http.StatusNotImplemented},
+		{name: "other", err: errors.New("db is down"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := storageError(tt.err); got.HTTPStatus != tt.wantStatus {
+				t.Errorf("storageError() status = %v, want %v", got.HTTPStatus, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPolicyError(t *testing.T) {
+	assert.Equal(t, http.StatusForbidden, policyError(validation.ErrorNameNotAllowed).HTTPStatus)
+	assert.Equal(t, http.StatusBadRequest, policyError(validation.ErrorWrongName).HTTPStatus)
+}
+
+func TestErrorJSONBody(t *testing.T) {
+	assert.JSONEq(t, `{"status":"error","message":"invalid body"}`, string(errorJSONBody(BadRequestError)))
+	batchErr := &BatchError{
+		UpdateMetricError: InvalidMetricsError,
+		Items:             []payload.ItemError{{Index: 0, ID: "Heap Alloc", Message: "wrong name"}},
+	}
+	assert.JSONEq(t, `{"status":"error","message":"invalid metrics","errors":[{"index":0,"id":"Heap Alloc","message":"wrong name"}]}`, string(errorJSONBody(batchErr)))
+	var metricErr *UpdateMetricError
+	assert.ErrorAs(t, batchErr, &metricErr)
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"gmetrics/internal/audit"
 	"gmetrics/internal/helpers"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/metrics"
 	"gmetrics/internal/payload"
+	"gmetrics/internal/tenant"
 	"io"
 	"net/http"
 )
@@ -32,7 +34,8 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 	rawBody, err := io.ReadAll(request.Body)
 	if err != nil {
 		logger.Log.Error(err)
-		helpers.SetHTTPResponse(response, BadRequestError.HTTPStatus, helpers.GetErrorJSONBody(BadRequestError.Error()))
+		readErr := readBodyError(err)
+		helpers.SetHTTPResponse(response, readErr.HTTPStatus, helpers.GetErrorJSONBody(readErr.Error()))
 		return
 	}
 	// Парсим тело в структуру запроса
@@ -44,7 +47,7 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 	var metricErr *UpdateMetricError
-	uError := updateMetricByRequestBody(body)
+	uError := updateMetricByRequestBody(request.Context(), httpSource(request, audit.TransportJSON), body)
 	if uError != nil {
 		if errors.As(uError, &metricErr) {
 			helpers.SetHTTPResponse(response, metricErr.HTTPStatus, helpers.GetErrorJSONBody(metricErr.Error()))
@@ -53,7 +56,7 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 		}
 		return
 	}
-	rBody, rError := createResponse(body, fmt.Sprintf("metric %s successfully add", body.ID))
+	rBody, rError := createResponse(tenant.FromContext(request.Context()).Storage, body, fmt.Sprintf("metric %s successfully add", body.ID))
 	if rError != nil {
 		if errors.As(rError, &metricErr) {
 			helpers.SetHTTPResponse(response, metricErr.HTTPStatus, helpers.GetErrorJSONBody(metricErr.Error()))
@@ -69,8 +72,8 @@ func JSONHandler(response http.ResponseWriter, request *http.Request) {
 	}
 }
 
-// createResponse создаём тело для ответа
-func createResponse(body payload.Metrics, responseMessage string) ([]byte, error) {
+// createResponse создаём тело для ответа со значением метрики из хранилища store
+func createResponse(store metrics.IStorage, body payload.Metrics, responseMessage string) ([]byte, error) {
 	rBody := payload.ResponseBody{
 		Status:  payload.ResponseSuccessStatus,
 		ID:      body.ID,
@@ -78,12 +81,12 @@ func createResponse(body payload.Metrics, responseMessage string) ([]byte, error
 	}
 	switch body.MType {
 	case metrics.TypeGauge:
-		val, ok := metrics.MeStore.GetGauge(body.ID)
+		val, ok := store.GetGauge(body.ID)
 		if ok {
 			rBody.Value = val.GetRaw()
 		}
 	case metrics.TypeCounter:
-		val, ok := metrics.MeStore.GetCounter(body.ID)
+		val, ok := store.GetCounter(body.ID)
 		if ok {
 			rBody.Delta = val.GetRaw()
 		}
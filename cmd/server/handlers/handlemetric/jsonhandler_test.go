@@ -168,7 +168,7 @@ func TestCreateResponse(t *testing.T) {
 					}
 				}
 			}
-			got, err := createResponse(test.body(), test.message)
+			got, err := createResponse(metrics.MeStore, test.body(), test.message)
 			assert.Equal(t, test.want, got, "unexpected response")
 			if (err != nil) != test.wantError {
 				t.Errorf("createResponse() error = %v, wantError %v", err, test.wantError)
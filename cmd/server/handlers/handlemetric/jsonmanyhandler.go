@@ -3,6 +3,7 @@ package handlemetric
 import (
 	"encoding/json"
 	"errors"
+	"gmetrics/internal/audit"
 	"gmetrics/internal/helpers"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/payload"
@@ -22,8 +23,9 @@ import (
 // @Accept json
 // @Produce json
 // @Param request body []payload.Metrics true "список метрик"
-// @Success 200 {object} payload.ResponseBody "успешный ответ"
-// @Failure 400 {object} payload.ResponseBody "ошибка запроса"
+// @Success 200 {object} payload.ResponseBody "успешный ответ, в errors метрики новых рядов, отброшенные из-за лимитов"
+// @Failure 400 {object} payload.ResponseBody "ошибка запроса, в errors ошибки отклонённых метрик"
+// @Failure 403 {object} payload.ResponseBody "клиенту не разрешены имена метрик"
 // @Failure 500 {object} payload.ResponseBody "внутренняя ошибка"
 // @Router /updates [post]
 func JSONManyHandler(response http.ResponseWriter, request *http.Request) {
@@ -31,7 +33,8 @@ func JSONManyHandler(response http.ResponseWriter, request *http.Request) {
 	rawBody, err := io.ReadAll(request.Body)
 	if err != nil {
 		logger.Log.Error(err)
-		helpers.SetHTTPResponse(response, BadRequestError.HTTPStatus, helpers.GetErrorJSONBody(BadRequestError.Error()))
+		readErr := readBodyError(err)
+		helpers.SetHTTPResponse(response, readErr.HTTPStatus, helpers.GetErrorJSONBody(readErr.Error()))
 		return
 	}
 	// Парсим тело в структуру запроса
@@ -43,16 +46,20 @@ func JSONManyHandler(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 	var metricErr *UpdateMetricError
-	uError := updateMetricsByRequestBody(body)
+	rejected, uError := updateMetricsByRequestBody(request.Context(), httpSource(request, audit.TransportBatch), body)
 	if uError != nil {
 		if errors.As(uError, &metricErr) {
-			helpers.SetHTTPResponse(response, metricErr.HTTPStatus, helpers.GetErrorJSONBody(metricErr.Error()))
+			helpers.SetHTTPResponse(response, metricErr.HTTPStatus, errorJSONBody(uError))
 		} else {
 			helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(uError.Error()))
 		}
 		return
 	}
-	rBody, rError := createEmptyResponse("Metrics successfully updated.")
+	message := "Metrics successfully updated."
+	if len(rejected) > 0 {
+		message = "Metrics partially updated, series limit reached."
+	}
+	rBody, rError := createItemsResponse(message, rejected)
 	if rError != nil {
 		if errors.As(rError, &metricErr) {
 			helpers.SetHTTPResponse(response, metricErr.HTTPStatus, helpers.GetErrorJSONBody(metricErr.Error()))
@@ -70,9 +77,15 @@ func JSONManyHandler(response http.ResponseWriter, request *http.Request) {
 
 // createEmptyResponse создаём тело для ответа
 func createEmptyResponse(responseMessage string) ([]byte, error) {
+	return createItemsResponse(responseMessage, nil)
+}
+
+// createItemsResponse создаём тело для ответа с ошибками отдельных метрик
+func createItemsResponse(responseMessage string, items []payload.ItemError) ([]byte, error) {
 	rBody := payload.ResponseBody{
 		Status:  payload.ResponseSuccessStatus,
 		Message: responseMessage,
+		Errors:  items,
 	}
 
 	jsonResponse, err := json.Marshal(rBody)
@@ -1,14 +1,20 @@
 package handlemetric
 
 import (
+	"encoding/json"
+	"gmetrics/internal/cardinality"
 	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/validation"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJSONManyHandler(t *testing.T) {
@@ -66,14 +72,28 @@ func TestJSONManyHandler(t *testing.T) {
 			wantStatus:      http.StatusOK,
 			wantContentType: "application/json",
 		},
+		{
+			name:            "too_many_metrics",
+			body:            `[{"id":"someName","type":"counter","delta":5},{"id":"someName","type":"counter","delta":5},{"id":"someName","type":"counter","delta":5}]`,
+			wantStatus:      http.StatusRequestEntityTooLarge,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "body_too_large",
+			body:            `[{"id":"` + strings.Repeat("a", 1024) + `","type":"counter","delta":5}]`,
+			wantStatus:      http.StatusRequestEntityTooLarge,
+			wantContentType: "application/json",
+		},
 	}
 	router := chi.NewRouter()
+	MaxBatchSize = 2
+	defer func() { MaxBatchSize = 0 }()
 
 	// Устанавливаем глобальное хранилище метрик
 	storage := metrics.NewMemStorage()
 	metrics.MeStore = storage
 
-	router.Post("/updates", JSONManyHandler)
+	router.Post("/updates", http.MaxBytesHandler(http.HandlerFunc(JSONManyHandler), 1024).ServeHTTP)
 	// запускаем тестовый сервер, будет выбран первый свободный порт
 	srv := httptest.NewServer(router)
 	// останавливаем сервер после завершения теста
@@ -93,6 +113,27 @@ func TestJSONManyHandler(t *testing.T) {
 	}
 }
 
+func TestJSONManyHandlerItemErrors(t *testing.T) {
+	policy, err := validation.NewPolicy("[A-Za-z]+", 0, false, false, nil)
+	require.NoError(t, err)
+	Policy = policy
+	defer func() { Policy = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+
+	response := httptest.NewRecorder()
+	body := `[{"id":"Alloc","type":"gauge","value":1},{"id":"Heap Alloc","type":"gauge","value":2},{"id":"Poll2","type":"counter","delta":1}]`
+	JSONManyHandler(response, httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	var result payload.ResponseBody
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, payload.ResponseErrorStatus, result.Status)
+	assert.Equal(t, InvalidMetricsError.Error(), result.Message)
+	assert.Equal(t, []payload.ItemError{
+		{Index: 1, ID: "Heap Alloc", Message: validation.ErrorWrongName.Error()},
+		{Index: 2, ID: "Poll2", Message: validation.ErrorWrongName.Error()},
+	}, result.Errors)
+}
+
 func TestCreateEmptyResponse(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -122,3 +163,22 @@ func TestCreateEmptyResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONManyHandlerSeriesLimit(t *testing.T) {
+	cardinality.Series = cardinality.New(1, 0)
+	defer func() { cardinality.Series = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+
+	response := httptest.NewRecorder()
+	body := `[{"id":"Alloc","type":"gauge","value":1},{"id":"PollCount","type":"counter","delta":1}]`
+	JSONManyHandler(response, httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, response.Code)
+	var result payload.ResponseBody
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, payload.ResponseSuccessStatus, result.Status)
+	assert.Equal(t, []payload.ItemError{
+		{Index: 1, ID: "PollCount", Message: cardinality.ErrorSeriesLimit.Error()},
+	}, result.Errors)
+	_, ok := metrics.MeStore.GetGauge("Alloc")
+	assert.True(t, ok)
+}
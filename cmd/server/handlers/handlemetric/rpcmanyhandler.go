@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/payload"
 	pb "gmetrics/internal/payload/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
 )
 
 // RPCManyHandler Сервис для обновления метрик по rpc
@@ -27,9 +31,19 @@ func (r *RPCManyHandler) HandleMetrics(ctx context.Context, request *pb.MetricsR
 		return nil, status.Error(codes.InvalidArgument, BadRequestError.Error())
 	}
 	var metricErr *UpdateMetricError
-	uError := updateMetricsByRequestBody(body)
+	rejected, uError := updateMetricsByRequestBody(ctx, rpcSource(ctx), body)
 	if uError != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		var batchErr *BatchError
+		if errors.As(uError, &batchErr) {
+			return nil, rpcBatchError(batchErr)
+		}
 		if errors.As(uError, &metricErr) {
+			if metricErr.HTTPStatus == http.StatusForbidden {
+				return nil, status.Error(codes.PermissionDenied, metricErr.Error())
+			}
 			return nil, status.Error(codes.InvalidArgument, metricErr.Error())
 		} else {
 			return nil, status.Error(codes.Internal, uError.Error())
@@ -38,10 +52,79 @@ func (r *RPCManyHandler) HandleMetrics(ctx context.Context, request *pb.MetricsR
 
 	return &pb.MetricsResponse{
 		Status:  payload.ResponseSuccessStatus,
-		Message: "",
+		Message: rejectedMessage(rejected),
+	}, nil
+}
+
+// rejectedMessage сообщение об отброшенных метриках новых рядов, пустое, если записаны все метрики
+func rejectedMessage(rejected []payload.ItemError) string {
+	if len(rejected) == 0 {
+		return ""
+	}
+	ids := make([]string, 0, len(rejected))
+	for _, item := range rejected {
+		ids = append(ids, fmt.Sprintf("[%d] %s: %s", item.Index, item.ID, item.Message))
+	}
+	return "metrics partially updated, rejected: " + strings.Join(ids, "; ")
+}
+
+// DeleteMetric удаление метрики
+func (r *RPCManyHandler) DeleteMetric(ctx context.Context, request *pb.DeleteMetricRequest) (*pb.MetricsResponse, error) {
+	if err := deleteMetric(ctx, rpcSource(ctx), request.GetType(), request.GetName()); err != nil {
+		return nil, rpcStorageError(ctx, err)
+	}
+	return &pb.MetricsResponse{
+		Status:  payload.ResponseSuccessStatus,
+		Message: fmt.Sprintf("metric %s successfully deleted", request.GetName()),
+	}, nil
+}
+
+// ResetCounter обнуление counter
+func (r *RPCManyHandler) ResetCounter(ctx context.Context, request *pb.ResetCounterRequest) (*pb.MetricsResponse, error) {
+	if err := resetCounter(ctx, rpcSource(ctx), request.GetName()); err != nil {
+		return nil, rpcStorageError(ctx, err)
+	}
+	return &pb.MetricsResponse{
+		Status:  payload.ResponseSuccessStatus,
+		Message: fmt.Sprintf("counter %s successfully reset", request.GetName()),
 	}, nil
 }
 
+// rpcStorageError ошибка rpc по ошибке изменения хранилища. Если запрос отменён или истёк его срок, то ошибка контекста
+func rpcStorageError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	switch err {
+	case MetricNotFoundError:
+		return status.Error(codes.NotFound, err.Error())
+	case InvalidMetricTypeError:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// rpcBatchError ошибка rpc по отклонённым метрикам пакета. Ошибки метрик передаются в деталях статуса
+func rpcBatchError(batchErr *BatchError) error {
+	code := codes.InvalidArgument
+	if batchErr.HTTPStatus == http.StatusForbidden {
+		code = codes.PermissionDenied
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(batchErr.Items))
+	for _, item := range batchErr.Items {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       fmt.Sprintf("[%d].id", item.Index),
+			Description: item.ID + ": " + item.Message,
+		})
+	}
+	st, err := status.New(code, batchErr.Error()).WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		logger.Log.Error(err)
+		return status.Error(code, batchErr.Error())
+	}
+	return st.Err()
+}
+
 // NewRPCManyHandler создание нового сервиса
 func NewRPCManyHandler() *RPCManyHandler {
 	return &RPCManyHandler{}
@@ -4,11 +4,18 @@ package handlemetric
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gmetrics/internal/cardinality"
 	"gmetrics/internal/metrics"
+	"gmetrics/internal/middlewares"
+	"gmetrics/internal/payload"
 	pb "gmetrics/internal/payload/proto"
+	"gmetrics/internal/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"testing"
+	"time"
 )
 
 func TestNewRPCManyHandler(t *testing.T) {
@@ -88,3 +95,101 @@ func TestRPCManyHandler_HandleMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestRPCManyHandler_ItemErrors(t *testing.T) {
+	policy, err := validation.NewPolicy("", 0, false, false, map[string][]string{"agent-1": {"Heap*"}})
+	require.NoError(t, err)
+	Policy = policy
+	defer func() { Policy = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := middlewares.WithClientID(context.Background(), "agent-1")
+
+	body := `[{"id":"HeapIdle","type":"gauge","value":1},{"id":"PollCount","type":"counter","delta":1}]`
+	_, err = NewRPCManyHandler().HandleMetrics(ctx, &pb.MetricsRequest{Body: []byte(body)})
+	st := status.Convert(err)
+	assert.Equal(t, codes.PermissionDenied, st.Code())
+	require.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.GetFieldViolations(), 1)
+	assert.Equal(t, "[1].id", badRequest.GetFieldViolations()[0].GetField())
+	assert.Equal(t, "PollCount: "+validation.ErrorNameNotAllowed.Error(), badRequest.GetFieldViolations()[0].GetDescription())
+
+	// Клиенту без списка разрешённых имён можно писать любые имена
+	_, err = NewRPCManyHandler().HandleMetrics(context.Background(), &pb.MetricsRequest{Body: []byte(body)})
+	assert.NoError(t, err)
+}
+
+func TestRPCManyHandler_DeleteMetric(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    *pb.DeleteMetricRequest
+		wantStatus codes.Code
+	}{
+		{name: "delete", request: &pb.DeleteMetricRequest{Type: metrics.TypeGauge, Name: "Alloc"}, wantStatus: codes.OK},
+		{name: "not_found", request: &pb.DeleteMetricRequest{Type: metrics.TypeCounter, Name: "Alloc"}, wantStatus: codes.NotFound},
+		{name: "wrong_type", request: &pb.DeleteMetricRequest{Type: "aboba", Name: "Alloc"}, wantStatus: codes.InvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metrics.MeStore = metrics.NewMemStorage()
+			assert.NoError(t, metrics.MeStore.SetGauge("Alloc", 1.5))
+
+			_, err := NewRPCManyHandler().DeleteMetric(context.TODO(), test.request)
+			assert.Equal(t, test.wantStatus, status.Code(err), "unexpected error code")
+		})
+	}
+}
+
+func TestRPCManyHandler_ResetCounter(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    *pb.ResetCounterRequest
+		wantStatus codes.Code
+	}{
+		{name: "reset", request: &pb.ResetCounterRequest{Name: "PollCount"}, wantStatus: codes.OK},
+		{name: "not_found", request: &pb.ResetCounterRequest{Name: "Alloc"}, wantStatus: codes.NotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metrics.MeStore = metrics.NewMemStorage()
+			assert.NoError(t, metrics.MeStore.AddCounter("PollCount", 3))
+
+			_, err := NewRPCManyHandler().ResetCounter(context.TODO(), test.request)
+			assert.Equal(t, test.wantStatus, status.Code(err), "unexpected error code")
+		})
+	}
+}
+
+func TestRPCManyHandler_DeadlineExceeded(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	assert.NoError(t, metrics.MeStore.SetGauge("Alloc", 1.5))
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	_, err := NewRPCManyHandler().DeleteMetric(ctx, &pb.DeleteMetricRequest{Type: metrics.TypeGauge, Name: "Alloc"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	_, err = NewRPCManyHandler().HandleMetrics(ctx, &pb.MetricsRequest{Body: []byte(`[{"id":"Alloc","type":"gauge","value":2.5}]`)})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	// Хранилище не меняется после истечения срока запроса
+	value, ok := metrics.MeStore.GetGauge("Alloc")
+	assert.True(t, ok)
+	assert.Equal(t, metrics.Gauge(1.5), value)
+}
+
+func TestRPCManyHandler_SeriesLimit(t *testing.T) {
+	cardinality.Series = cardinality.New(1, 0)
+	defer func() { cardinality.Series = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+
+	body := `[{"id":"Alloc","type":"gauge","value":1},{"id":"PollCount","type":"counter","delta":1}]`
+	response, err := NewRPCManyHandler().HandleMetrics(context.Background(), &pb.MetricsRequest{Body: []byte(body)})
+	require.NoError(t, err)
+	assert.Equal(t, payload.ResponseSuccessStatus, response.GetStatus())
+	assert.Equal(t, "metrics partially updated, rejected: [1] PollCount: "+cardinality.ErrorSeriesLimit.Error(), response.GetMessage())
+
+	// Все ряды уже есть
+	response, err = NewRPCManyHandler().HandleMetrics(context.Background(), &pb.MetricsRequest{Body: []byte(`[{"id":"Alloc","type":"gauge","value":2}]`)})
+	require.NoError(t, err)
+	assert.Empty(t, response.GetMessage())
+}
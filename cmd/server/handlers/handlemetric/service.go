@@ -1,17 +1,64 @@
 package handlemetric
 
 import (
+	"context"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/logger"
 	"gmetrics/internal/metrics"
 	"gmetrics/internal/payload"
+	"gmetrics/internal/tenant"
+	"gmetrics/internal/validation"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 )
 
+// Policy политика проверки имён и значений метрик; nil - метрики не проверяются
+var Policy *validation.Policy
+
+// validateMetric проверка метрики политикой сервера. Через неё или validateMetrics проходят все способы записи метрик,
+// включая загрузку снимка
+func validateMetric(src audit.Source, body payload.Metrics) error {
+	if err := Policy.Check(src.ClientID, body); err != nil {
+		return policyError(err)
+	}
+	return nil
+}
+
+// validateMetrics проверка всех метрик пакета политикой сервера. Возвращает ошибки каждой отклонённой метрики
+func validateMetrics(src audit.Source, bodies []payload.Metrics) error {
+	var (
+		items  []payload.ItemError
+		status = http.StatusForbidden
+	)
+	for i, body := range bodies {
+		err := Policy.Check(src.ClientID, body)
+		if err == nil {
+			continue
+		}
+		items = append(items, payload.ItemError{Index: i, ID: body.ID, Message: err.Error()})
+		// Пакет запрещён, только если все метрики отклонены из-за запрещённых имён
+		if policyError(err).HTTPStatus != http.StatusForbidden {
+			status = http.StatusBadRequest
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return &BatchError{
+		UpdateMetricError: &UpdateMetricError{InvalidMetricsError.error, status},
+		Items:             items,
+	}
+}
+
 // updateMetricByStringValue updates the specified metric with the given value.
 // It supports gauge and counter metric types.
 //
 // Parameters:
-// - metricType: the type of the metric (either "gauge" or "counter")
+// - ctx: the request context, storage calls are cancelled with it
+// - src: the source of the write for the audit log
+// - metricType: the type of the metric ("gauge", "counter", "histogram", "summary" or "set")
 // - metricName: the name of the metric
 // - metricValue: the value of the metric
 //
@@ -29,9 +76,13 @@ import (
 // UpdateMetricError with the message "metric value is not a valid int" and
 // an HTTP status code of http.StatusBadRequest will be returned.
 //
-// If the metricType is neither "gauge" nor "counter", an UpdateMetricError
+// For histogram and summary metrics, metricValue is a single float64 observation.
+// For set metrics, metricValue is a member added to the set.
+//
+// If the metricType is not one of the known types, an UpdateMetricError
 // with the message "invalid metric type" and an HTTP status code of http.StatusBadRequest will be returned.
-func updateMetricByStringValue(metricType, metricName, metricValue string) error {
+func updateMetricByStringValue(ctx context.Context, src audit.Source, metricType, metricName, metricValue string) error {
+	namespace := tenant.ForClient(src.ClientID)
 	switch metricType {
 	case metrics.TypeGauge:
 		convertedValue, err := strconv.ParseFloat(metricValue, 64)
@@ -39,11 +90,18 @@ func updateMetricByStringValue(metricType, metricName, metricValue string) error
 			//log.Println(err)
 			return NotValidGaugeError
 		}
-		err = metrics.MeStore.SetGauge(metricName, metrics.Gauge(convertedValue))
+		if err = validateMetric(src, payload.Metrics{ID: metricName, MType: metricType, Value: &convertedValue}); err != nil {
+			return err
+		}
+		if err = admitSeries(namespace, src, metrics.ListKey{Type: metricType, Name: metricName}); err != nil {
+			return err
+		}
+		change, err := setGauge(ctx, namespace.Storage, metricName, metrics.Gauge(convertedValue))
 		if err != nil {
 			//log.Println(err)
-			return &UpdateMetricError{err, http.StatusInternalServerError}
+			return storageError(err)
 		}
+		audit.Log.Record(src.Event(audit.ActionUpdate, []audit.Change{change}))
 		return nil
 	case metrics.TypeCounter:
 		convertedValue, err := strconv.ParseInt(metricValue, 10, 64)
@@ -51,12 +109,27 @@ func updateMetricByStringValue(metricType, metricName, metricValue string) error
 			//log.Println(err)
 			return NotValidCounterError
 		}
-		err = metrics.MeStore.AddCounter(metricName, metrics.Counter(convertedValue))
+		if err = validateMetric(src, payload.Metrics{ID: metricName, MType: metricType, Delta: &convertedValue}); err != nil {
+			return err
+		}
+		if err = admitSeries(namespace, src, metrics.ListKey{Type: metricType, Name: metricName}); err != nil {
+			return err
+		}
+		change, err := addCounter(ctx, namespace.Storage, metricName, metrics.Counter(convertedValue))
 		if err != nil {
 			//log.Println(err)
-			return &UpdateMetricError{err, http.StatusInternalServerError}
+			return storageError(err)
 		}
+		audit.Log.Record(src.Event(audit.ActionUpdate, []audit.Change{change}))
 		return nil
+	case metrics.TypeHistogram, metrics.TypeSummary:
+		convertedValue, err := strconv.ParseFloat(metricValue, 64)
+		if err != nil {
+			return NotValidGaugeError
+		}
+		return updateMetricByRequestBody(ctx, src, payload.Metrics{ID: metricName, MType: metricType, Value: &convertedValue})
+	case metrics.TypeSet:
+		return updateMetricByRequestBody(ctx, src, payload.Metrics{ID: metricName, MType: metricType, Members: []string{metricValue}})
 	default:
 		return InvalidMetricTypeError
 	}
@@ -66,64 +139,150 @@ func updateMetricByStringValue(metricType, metricName, metricValue string) error
 // It supports gauge and counter metric types.
 //
 // Parameters:
+// - ctx: the request context, storage calls are cancelled with it
+// - src: the source of the write for the audit log
 // - body: the request body containing the metric information
 //
 // Returns:
 // - empty string and UpdateMetricError if there is an error updating the metric
 //
 // UpdateMetricError is a custom error type that contains an error message and an HTTP status code.
-func updateMetricByRequestBody(body payload.Metrics) error {
+func updateMetricByRequestBody(ctx context.Context, src audit.Source, body payload.Metrics) error {
 	if body.ID == "" {
 		return BadRequestError
 	}
+	if err := validateMetric(src, body); err != nil {
+		return err
+	}
+	namespace := tenant.ForClient(src.ClientID)
+	metadata, err := metadataFromBody(body)
+	if err != nil {
+		return err
+	}
+	key := metrics.ListKey{Type: body.MType, Name: body.ID}
 
+	var change audit.Change
 	switch body.MType {
 	case metrics.TypeGauge:
 		if body.Value == nil {
 			return BadRequestError
 		}
-		err := metrics.MeStore.SetGauge(body.ID, metrics.Gauge(*body.Value))
-		if err != nil {
+		if err = admitSeries(namespace, src, key); err != nil {
+			return err
+		}
+		if change, err = setGauge(ctx, namespace.Storage, body.ID, metrics.Gauge(*body.Value)); err != nil {
 			//log.Println(err)
-			return &UpdateMetricError{err, http.StatusInternalServerError}
+			return storageError(err)
 		}
 	case metrics.TypeCounter:
 		if body.Delta == nil {
 			return BadRequestError
 		}
-		err := metrics.MeStore.AddCounter(body.ID, metrics.Counter(*body.Delta))
-		if err != nil {
+		if err = admitSeries(namespace, src, key); err != nil {
+			return err
+		}
+		if change, err = addCounter(ctx, namespace.Storage, body.ID, metrics.Counter(*body.Delta)); err != nil {
 			//log.Println(err)
-			return &UpdateMetricError{err, http.StatusInternalServerError}
+			return storageError(err)
+		}
+	case metrics.TypeHistogram:
+		histogram, err := histogramFromBody(namespace.Storage, body)
+		if err != nil {
+			return err
+		}
+		if err = admitSeries(namespace, src, key); err != nil {
+			return err
+		}
+		if change, err = addHistogram(ctx, namespace.Storage, body.ID, histogram); err != nil {
+			return storageError(err)
+		}
+	case metrics.TypeSummary:
+		values, err := summaryFromBody(body)
+		if err != nil {
+			return err
+		}
+		if err = admitSeries(namespace, src, key); err != nil {
+			return err
+		}
+		if change, err = addSummary(ctx, namespace.Storage, body.ID, values); err != nil {
+			return storageError(err)
+		}
+	case metrics.TypeSet:
+		sketch, err := setFromBody(body)
+		if err != nil {
+			return err
+		}
+		if err = admitSeries(namespace, src, key); err != nil {
+			return err
+		}
+		if change, err = addSet(ctx, namespace.Storage, body.ID, sketch); err != nil {
+			return storageError(err)
 		}
 	default:
 		return InvalidMetricTypeError
 	}
+	if metadata != nil {
+		if err = metrics.SetMetadataContext(ctx, namespace.Storage, key, *metadata); err != nil {
+			return storageError(err)
+		}
+	}
+	audit.Log.Record(src.Event(audit.ActionUpdate, []audit.Change{change}))
 
 	return nil
 }
 
-// updateMetricsByRequestBody обновляет Gauge и Counter из предоставленного тела запроса.
-func updateMetricsByRequestBody(bodies []payload.Metrics) error {
+// MaxBatchSize максимальное количество метрик в одном запросе; 0 - без ограничений
+var MaxBatchSize int
+
+// updateMetricsByRequestBody обновляет метрики из предоставленного тела запроса.
+// Если политика сервера отклонила метрики, то не записывается ни одна, а ошибка перечисляет отклонённые.
+// Метрики новых рядов сверх лимитов отбрасываются, остальные записываются, а отброшенные возвращаются списком
+func updateMetricsByRequestBody(ctx context.Context, src audit.Source, bodies []payload.Metrics) ([]payload.ItemError, error) {
+	if MaxBatchSize > 0 && len(bodies) > MaxBatchSize {
+		return nil, TooManyMetricsError
+	}
+	if err := validateMetrics(src, bodies); err != nil {
+		return nil, err
+	}
+	namespace := tenant.ForClient(src.ClientID)
 	var (
-		gauges   = make(map[string]metrics.Gauge)
-		counters = make(map[string]metrics.Counter)
+		gauges     = make(map[string]metrics.Gauge)
+		counters   = make(map[string]metrics.Counter)
+		histograms = make(map[string]metrics.Histogram)
+		summaries  = make(map[string][]float64)
+		sets       = make(map[string]metrics.Sketch)
+		metadata   = make(map[metrics.ListKey]metrics.Metadata)
+		// Номера метрик пакета по рядам в порядке первого появления ряда
+		series = make(map[metrics.ListKey][]int)
+		order  []metrics.ListKey
 	)
 
-	for _, body := range bodies {
+	for i, body := range bodies {
 		if body.ID == "" {
-			return BadRequestError
+			return nil, BadRequestError
+		}
+		key := metrics.ListKey{Type: body.MType, Name: body.ID}
+		if _, ok := series[key]; !ok {
+			order = append(order, key)
+		}
+		series[key] = append(series[key], i)
+		bodyMetadata, err := metadataFromBody(body)
+		if err != nil {
+			return nil, err
+		}
+		if bodyMetadata != nil {
+			metadata[key] = metadata[key].Merge(*bodyMetadata)
 		}
 
 		switch body.MType {
 		case metrics.TypeGauge:
 			if body.Value == nil {
-				return BadRequestError
+				return nil, BadRequestError
 			}
 			gauges[body.ID] = metrics.Gauge(*body.Value)
 		case metrics.TypeCounter:
 			if body.Delta == nil {
-				return BadRequestError
+				return nil, BadRequestError
 			}
 			var newValue metrics.Counter
 			val, ok := counters[body.ID]
@@ -133,19 +292,282 @@ func updateMetricsByRequestBody(bodies []payload.Metrics) error {
 				newValue = metrics.Counter(*body.Delta)
 			}
 			counters[body.ID] = newValue
+		case metrics.TypeHistogram:
+			histogram, err := histogramFromBody(namespace.Storage, body)
+			if err != nil {
+				return nil, err
+			}
+			if old, ok := histograms[body.ID]; ok {
+				if histogram, err = old.Merge(histogram); err != nil {
+					return nil, storageError(err)
+				}
+			}
+			histograms[body.ID] = histogram
+		case metrics.TypeSummary:
+			values, err := summaryFromBody(body)
+			if err != nil {
+				return nil, err
+			}
+			summaries[body.ID] = append(summaries[body.ID], values...)
+		case metrics.TypeSet:
+			sketch, err := setFromBody(body)
+			if err != nil {
+				return nil, err
+			}
+			sets[body.ID] = sets[body.ID].Merge(sketch)
 		default:
-			return InvalidMetricTypeError
+			return nil, InvalidMetricTypeError
+		}
+	}
+
+	// Пакет проверяется целиком до записи первой метрики: иначе при ошибке часть пакета осталась бы записанной,
+	// а агент повторил бы его и прибавил counter ещё раз
+	if err := checkBatch(namespace.Storage, histograms, summaries, sets); err != nil {
+		return nil, err
+	}
+
+	// Новые ряды сверх лимитов отбрасываются вместе со всеми метриками пакета в них
+	var rejected []payload.ItemError
+	client := seriesClient(src)
+	for _, key := range order {
+		err := namespace.Series.Admit(client, key)
+		if err == nil {
+			continue
+		}
+		for _, i := range series[key] {
+			rejected = append(rejected, payload.ItemError{Index: i, ID: key.Name, Message: err.Error()})
+		}
+		switch key.Type {
+		case metrics.TypeGauge:
+			delete(gauges, key.Name)
+		case metrics.TypeCounter:
+			delete(counters, key.Name)
+		case metrics.TypeHistogram:
+			delete(histograms, key.Name)
+		case metrics.TypeSummary:
+			delete(summaries, key.Name)
+		case metrics.TypeSet:
+			delete(sets, key.Name)
+		}
+		delete(metadata, key)
+	}
+	sort.Slice(rejected, func(i, j int) bool {
+		return rejected[i].Index < rejected[j].Index
+	})
+
+	gaugeChanges, err := setGauges(ctx, namespace.Storage, gauges)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	counterChanges, err := addCounters(ctx, namespace.Storage, counters)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	changes := append(gaugeChanges, counterChanges...)
+	for name, histogram := range histograms {
+		change, err := addHistogram(ctx, namespace.Storage, name, histogram)
+		if err != nil {
+			return nil, storageError(err)
+		}
+		changes = append(changes, change)
+	}
+	for name, values := range summaries {
+		change, err := addSummary(ctx, namespace.Storage, name, values)
+		if err != nil {
+			return nil, storageError(err)
+		}
+		changes = append(changes, change)
+	}
+	for name, sketch := range sets {
+		change, err := addSet(ctx, namespace.Storage, name, sketch)
+		if err != nil {
+			return nil, storageError(err)
 		}
+		changes = append(changes, change)
 	}
+	sortChanges(changes)
+	for key, m := range metadata {
+		if err = metrics.SetMetadataContext(ctx, namespace.Storage, key, m); err != nil {
+			return nil, storageError(err)
+		}
+	}
+	audit.Log.Record(src.Event(audit.ActionUpdate, changes))
 
-	err := metrics.MeStore.SetGauges(gauges)
+	return rejected, nil
+}
+
+// checkBatch проверка, что хранилище примет гистограммы, сводки и множества пакета, в том числе что границы корзин
+// гистограмм совпадают с границами сохранённых гистограмм
+func checkBatch(store metrics.IStorage, histograms map[string]metrics.Histogram, summaries map[string][]float64, sets map[string]metrics.Sketch) error {
+	for name, histogram := range histograms {
+		if err := metrics.CheckHistogram(store, name, histogram); err != nil {
+			return storageError(err)
+		}
+	}
+	if len(summaries) > 0 {
+		if err := metrics.CheckSupported(store, metrics.TypeSummary); err != nil {
+			return storageError(err)
+		}
+	}
+	if len(sets) > 0 {
+		if err := metrics.CheckSupported(store, metrics.TypeSet); err != nil {
+			return storageError(err)
+		}
+	}
+	return nil
+}
+
+// admitSeries проверка лимитов рядов пространства имён для отдельной метрики. Ряды анонимного клиента учитываются по адресу соединения
+func admitSeries(namespace *tenant.Namespace, src audit.Source, key metrics.ListKey) error {
+	if err := namespace.Series.Admit(seriesClient(src), key); err != nil {
+		return &UpdateMetricError{err, http.StatusTooManyRequests}
+	}
+	return nil
+}
+
+// histogramFromBody гистограмма из тела запроса: переданные корзины или одно наблюдение value
+// с границами корзин гистограммы, сохранённой в store
+func histogramFromBody(store metrics.IStorage, body payload.Metrics) (metrics.Histogram, error) {
+	switch {
+	case body.Histogram != nil:
+		histogram := metrics.HistogramFromPayload(*body.Histogram)
+		if err := histogram.Validate(); err != nil {
+			return histogram, storageError(err)
+		}
+		return histogram, nil
+	case body.Value != nil:
+		histogram, err := metrics.HistogramObservation(store, body.ID, *body.Value)
+		if err != nil {
+			return histogram, storageError(err)
+		}
+		return histogram, nil
+	}
+	return metrics.Histogram{}, BadRequestError
+}
+
+// summaryFromBody наблюдения сводки из тела запроса: observations и value, если оно передано
+func summaryFromBody(body payload.Metrics) ([]float64, error) {
+	values := body.Observations
+	if body.Value != nil {
+		values = append(values, *body.Value)
+	}
+	if len(values) == 0 {
+		return nil, BadRequestError
+	}
+	// Наблюдения проверяются до записи так же, как их проверит хранилище
+	if _, err := metrics.NewObservations(values, time.Now()); err != nil {
+		return nil, storageError(err)
+	}
+	return values, nil
+}
+
+// setFromBody скетч множества из тела запроса: элементы members и скетч, собранный агентом
+func setFromBody(body payload.Metrics) (metrics.Sketch, error) {
+	if len(body.Members) == 0 && len(body.Sketch) == 0 {
+		return metrics.Sketch{}, BadRequestError
+	}
+	sketch := metrics.NewSketch(body.Members...)
+	if len(body.Sketch) > 0 {
+		var received metrics.Sketch
+		if err := received.UnmarshalBinary(body.Sketch); err != nil {
+			return sketch, storageError(err)
+		}
+		sketch = sketch.Merge(received)
+	}
+	return sketch, nil
+}
+
+// metadataFromBody проверенное описание метрики из тела запроса; nil, если описание не передано
+func metadataFromBody(body payload.Metrics) (*metrics.Metadata, error) {
+	if body.Metadata == nil {
+		return nil, nil
+	}
+	metadata := metrics.Metadata{Unit: body.Metadata.Unit, Help: body.Metadata.Help, Owner: body.Metadata.Owner}
+	if err := metadata.Validate(); err != nil {
+		return nil, storageError(err)
+	}
+	return &metadata, nil
+}
+
+// deleteMetric удаляет метрику указанного типа
+func deleteMetric(ctx context.Context, src audit.Source, metricType, metricName string) error {
+	namespace := tenant.ForClient(src.ClientID)
+	if !audit.Log.Enabled() {
+		if err := metrics.WithContext(namespace.Storage).DeleteContext(ctx, metricType, metricName); err != nil {
+			return storageError(err)
+		}
+		namespace.Series.Forget(metrics.ListKey{Type: metricType, Name: metricName})
+		return nil
+	}
+	// В журнал попадает значение, которое хранилище удалило, а не прочитанное до удаления
+	old, err := metrics.DeleteReturningOld(ctx, namespace.Storage, metricType, metricName)
 	if err != nil {
-		return &UpdateMetricError{err, http.StatusInternalServerError}
+		return storageError(err)
+	}
+	namespace.Series.Forget(metrics.ListKey{Type: metricType, Name: metricName})
+	audit.Log.Record(src.Event(audit.ActionDelete, []audit.Change{deleteChange(metricType, metricName, old)}))
+	return nil
+}
+
+// resetCounter обнуляет counter
+func resetCounter(ctx context.Context, src audit.Source, metricName string) error {
+	store := tenant.ForClient(src.ClientID).Storage
+	if !audit.Log.Enabled() {
+		if err := metrics.WithContext(store).ResetCounterContext(ctx, metricName); err != nil {
+			return storageError(err)
+		}
+		return nil
 	}
-	err = metrics.MeStore.AddCounters(counters)
+	old, err := metrics.ResetCounterReturningOld(ctx, store, metricName)
 	if err != nil {
-		return &UpdateMetricError{err, http.StatusInternalServerError}
+		return storageError(err)
 	}
+	audit.Log.Record(src.Event(audit.ActionReset, []audit.Change{resetChange(metricName, old)}))
+	return nil
+}
 
+// importMetrics загружает метрики снимка и их описания. Если replace, то остальные метрики удаляются, а описания сохраняются.
+// Снимок проверяется политикой сервера целиком: если отклонена хоть одна метрика, то не загружается ничего
+func importMetrics(ctx context.Context, src audit.Source, list []metrics.ListedMetric, metadata []metrics.MetadataEntry, replace bool) error {
+	bodies := make([]payload.Metrics, 0, len(list))
+	for _, metric := range list {
+		bodies = append(bodies, importedBody(metric))
+	}
+	if err := validateMetrics(src, bodies); err != nil {
+		return err
+	}
+	namespace := tenant.ForClient(src.ClientID)
+	var changes []audit.Change
+	if audit.Log.Enabled() {
+		changes = importChanges(namespace.Storage, list, replace)
+	}
+	if err := metrics.ImportContext(ctx, namespace.Storage, list, metadata, replace); err != nil {
+		return storageError(err)
+	}
+	// Загруженные ряды учитываются без владельца
+	if err := namespace.Series.Sync(namespace.Storage); err != nil {
+		logger.Log.Error(err)
+	}
+	audit.Log.Record(src.Event(audit.ActionImport, changes))
 	return nil
 }
+
+// importedBody метрика снимка в виде тела запроса на запись, чтобы проверить её политикой сервера.
+// Значение counter в снимке - не приращение, поэтому запрет отрицательных приращений к нему не применяется
+func importedBody(metric metrics.ListedMetric) payload.Metrics {
+	body := payload.Metrics{ID: metric.Name, MType: metric.Type}
+	switch metric.Type {
+	case metrics.TypeGauge:
+		value := metric.Gauge.GetRaw()
+		body.Value = &value
+	case metrics.TypeHistogram:
+		histogram := metric.Histogram.Payload()
+		body.Histogram = &histogram
+	case metrics.TypeSummary:
+		body.Observations = make([]float64, 0, len(metric.Summary.Observations))
+		for _, observation := range metric.Summary.Observations {
+			body.Observations = append(body.Observations, observation.Value)
+		}
+	}
+	return body
+}
@@ -1,11 +1,19 @@
 package handlemetric
 
 import (
+	"context"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/cardinality"
 	"gmetrics/internal/metrics"
 	"gmetrics/internal/payload"
+	"gmetrics/internal/tenant"
+	"gmetrics/internal/validation"
+	"math"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUpdateMetricByStringValue(t *testing.T) {
@@ -51,13 +59,41 @@ func TestUpdateMetricByStringValue(t *testing.T) {
 			metricValue: "1",
 			expectError: true,
 		},
+		{
+			name:        "histogram_valid",
+			metricType:  metrics.TypeHistogram,
+			metricName:  "Latency",
+			metricValue: "0.3",
+			expectError: false,
+		},
+		{
+			name:        "histogram_not_finite",
+			metricType:  metrics.TypeHistogram,
+			metricName:  "Latency",
+			metricValue: "NaN",
+			expectError: true,
+		},
+		{
+			name:        "summary_valid",
+			metricType:  metrics.TypeSummary,
+			metricName:  "Size",
+			metricValue: "12",
+			expectError: false,
+		},
+		{
+			name:        "summary_not_valid",
+			metricType:  metrics.TypeSummary,
+			metricName:  "Size",
+			metricValue: "abc",
+			expectError: true,
+		},
 	}
 
 	metrics.MeStore = metrics.NewMemStorage()
 	// Run the tests
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := updateMetricByStringValue(tc.metricType, tc.metricName, tc.metricValue)
+			err := updateMetricByStringValue(context.Background(), audit.Source{}, tc.metricType, tc.metricName, tc.metricValue)
 			if tc.expectError {
 				assert.Error(t, err, "expect error")
 			} else {
@@ -149,7 +185,7 @@ func TestUpdateMetricByRequestBody(t *testing.T) {
 	// Run the tests
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := updateMetricByRequestBody(tc.payload())
+			err := updateMetricByRequestBody(context.Background(), audit.Source{}, tc.payload())
 			if tc.expectError {
 				assert.Error(t, err, "expect error")
 			} else {
@@ -248,7 +284,7 @@ func TestUpdateMetricsByRequestBody(t *testing.T) {
 	// Run the tests
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := updateMetricsByRequestBody(tc.payload())
+			_, err := updateMetricsByRequestBody(context.Background(), audit.Source{}, tc.payload())
 			if tc.expectError {
 				assert.Error(t, err, "expect error")
 			} else {
@@ -257,3 +293,346 @@ func TestUpdateMetricsByRequestBody(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateDistributions(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := context.Background()
+	value := 0.3
+	delta := int64(1)
+	buckets := &payload.Histogram{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 0, 1}, Sum: 5.05, Count: 2}
+
+	// Одно наблюдение попадает в гистограмму с границами по умолчанию
+	require.NoError(t, updateMetricByRequestBody(ctx, audit.Source{}, payload.Metrics{ID: "Default", MType: metrics.TypeHistogram, Value: &value}))
+	store := metrics.MeStore.(metrics.IDistributionStorage)
+	histogram, ok := store.GetHistogram("Default")
+	require.True(t, ok)
+	assert.Equal(t, metrics.DefaultHistogramBounds, histogram.Bounds)
+
+	// Корзины прибавляются к сохранённым, а наблюдения используют границы сохранённой гистограммы
+	_, err := updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{
+		{ID: "Latency", MType: metrics.TypeHistogram, Histogram: buckets},
+		{ID: "Latency", MType: metrics.TypeHistogram, Histogram: buckets},
+		{ID: "Size", MType: metrics.TypeSummary, Observations: []float64{1, 2}},
+		{ID: "Size", MType: metrics.TypeSummary, Value: &value},
+	})
+	require.NoError(t, err)
+	require.NoError(t, updateMetricByStringValue(ctx, audit.Source{}, metrics.TypeHistogram, "Latency", "0.5"))
+	histogram, ok = store.GetHistogram("Latency")
+	require.True(t, ok)
+	assert.Equal(t, metrics.Histogram{Bounds: []float64{0.1, 1}, Counts: []uint64{2, 1, 2}, Sum: 10.6, Count: 5}, histogram)
+	summary, ok := store.GetSummary("Size")
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), summary.Count)
+
+	tests := []struct {
+		name       string
+		body       payload.Metrics
+		wantStatus int
+	}{
+		{name: "histogram_empty", body: payload.Metrics{ID: "Latency", MType: metrics.TypeHistogram}, wantStatus: http.StatusBadRequest},
+		{name: "histogram_wrong", body: payload.Metrics{ID: "Latency", MType: metrics.TypeHistogram, Histogram: &payload.Histogram{Bounds: []float64{1}}}, wantStatus: http.StatusBadRequest},
+		{name: "histogram_bounds", body: payload.Metrics{ID: "Latency", MType: metrics.TypeHistogram, Histogram: &payload.Histogram{Bounds: []float64{2}, Counts: []uint64{0, 0}}}, wantStatus: http.StatusBadRequest},
+		{name: "summary_empty", body: payload.Metrics{ID: "Size", MType: metrics.TypeSummary}, wantStatus: http.StatusBadRequest},
+		{name: "summary_not_finite", body: payload.Metrics{ID: "Size", MType: metrics.TypeSummary, Observations: []float64{math.Inf(1)}}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metricErr *UpdateMetricError
+			require.ErrorAs(t, updateMetricByRequestBody(ctx, audit.Source{}, tt.body), &metricErr)
+			assert.Equal(t, tt.wantStatus, metricErr.HTTPStatus)
+			_, err := updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{
+				{ID: "Requests", MType: metrics.TypeCounter, Delta: &delta},
+				{ID: "Alloc", MType: metrics.TypeGauge, Value: &value},
+				tt.body,
+			})
+			require.ErrorAs(t, err, &metricErr)
+			assert.Equal(t, tt.wantStatus, metricErr.HTTPStatus)
+			// Пакет с ошибкой не записывается даже частично
+			_, ok := metrics.MeStore.GetCounter("Requests")
+			assert.False(t, ok)
+			_, ok = metrics.MeStore.GetGauge("Alloc")
+			assert.False(t, ok)
+		})
+	}
+
+	// Хранилище без гистограмм и сводок
+	memory := metrics.NewMemStorage()
+	metrics.MeStore = &metrics.DBStorage{IStorage: &plainStorage{IStorage: memory}}
+	var metricErr *UpdateMetricError
+	require.ErrorAs(t, updateMetricByRequestBody(ctx, audit.Source{}, payload.Metrics{ID: "Size", MType: metrics.TypeSummary, Value: &value}), &metricErr)
+	assert.Equal(t, http.StatusNotImplemented, metricErr.HTTPStatus)
+	_, err = updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{
+		{ID: "Requests", MType: metrics.TypeCounter, Delta: &delta},
+		{ID: "Latency", MType: metrics.TypeHistogram, Histogram: buckets},
+	})
+	require.ErrorAs(t, err, &metricErr)
+	assert.Equal(t, http.StatusNotImplemented, metricErr.HTTPStatus)
+	_, ok = memory.GetCounter("Requests")
+	assert.False(t, ok)
+}
+
+// plainStorage хранилище только с методами IStorage
+type plainStorage struct {
+	metrics.IStorage
+}
+
+func TestUpdateSets(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := context.Background()
+	agentSketch, err := metrics.NewSketch("host-1", "host-2").MarshalBinary()
+	require.NoError(t, err)
+
+	require.NoError(t, updateMetricByStringValue(ctx, audit.Source{}, metrics.TypeSet, "Users", "alice"))
+	require.NoError(t, updateMetricByRequestBody(ctx, audit.Source{}, payload.Metrics{ID: "Users", MType: metrics.TypeSet, Members: []string{"alice", "bob"}}))
+	// Скетчи с разных агентов объединяются в пакете и с сохранённым
+	_, err = updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{
+		{ID: "Hosts", MType: metrics.TypeSet, Sketch: agentSketch},
+		{ID: "Hosts", MType: metrics.TypeSet, Members: []string{"host-2", "host-3"}, Sketch: agentSketch},
+	})
+	require.NoError(t, err)
+	users, ok := metrics.GetSetByName(metrics.MeStore, "Users")
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), users.Estimate())
+	hosts, ok := metrics.GetSetByName(metrics.MeStore, "Hosts")
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), hosts.Estimate())
+
+	tests := []struct {
+		name       string
+		body       payload.Metrics
+		wantStatus int
+	}{
+		{name: "empty", body: payload.Metrics{ID: "Users", MType: metrics.TypeSet}, wantStatus: http.StatusBadRequest},
+		{name: "wrong_sketch", body: payload.Metrics{ID: "Users", MType: metrics.TypeSet, Sketch: []byte{1, 2}}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metricErr *UpdateMetricError
+			require.ErrorAs(t, updateMetricByRequestBody(ctx, audit.Source{}, tt.body), &metricErr)
+			assert.Equal(t, tt.wantStatus, metricErr.HTTPStatus)
+			_, err := updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{tt.body})
+			require.ErrorAs(t, err, &metricErr)
+			assert.Equal(t, tt.wantStatus, metricErr.HTTPStatus)
+		})
+	}
+
+	// Хранилище без множеств
+	metrics.MeStore = &metrics.DBStorage{IStorage: &plainStorage{IStorage: metrics.NewMemStorage()}}
+	var metricErr *UpdateMetricError
+	require.ErrorAs(t, updateMetricByStringValue(ctx, audit.Source{}, metrics.TypeSet, "Users", "alice"), &metricErr)
+	assert.Equal(t, http.StatusNotImplemented, metricErr.HTTPStatus)
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := context.Background()
+	value := 1.5
+	alloc := metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}
+
+	require.NoError(t, updateMetricByRequestBody(ctx, audit.Source{}, payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &value, Metadata: &payload.Metadata{Unit: "bytes"}}))
+	// В пакете описания одной метрики объединяются
+	_, err := updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: &value, Metadata: &payload.Metadata{Help: "Allocated heap"}},
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: &value, Metadata: &payload.Metadata{Owner: "runtime"}},
+	})
+	require.NoError(t, err)
+	metadata, ok := metrics.GetMetadataByKey(metrics.MeStore, alloc)
+	require.True(t, ok)
+	assert.Equal(t, metrics.Metadata{Unit: "bytes", Help: "Allocated heap", Owner: "runtime"}, metadata)
+
+	// Неверное описание отклоняет весь пакет до записи значений
+	wrong := []payload.Metrics{
+		{ID: "Frees", MType: metrics.TypeGauge, Value: &value},
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: &value, Metadata: &payload.Metadata{Unit: "bytes\n"}},
+	}
+	var metricErr *UpdateMetricError
+	_, err = updateMetricsByRequestBody(ctx, audit.Source{}, wrong)
+	require.ErrorAs(t, err, &metricErr)
+	assert.Equal(t, http.StatusBadRequest, metricErr.HTTPStatus)
+	_, ok = metrics.MeStore.GetGauge("Frees")
+	assert.False(t, ok)
+	require.ErrorAs(t, updateMetricByRequestBody(ctx, audit.Source{}, wrong[1]), &metricErr)
+	assert.Equal(t, http.StatusBadRequest, metricErr.HTTPStatus)
+
+	// Хранилище без описаний
+	metrics.MeStore = &plainStorage{IStorage: metrics.NewMemStorage()}
+	require.ErrorAs(t, updateMetricByRequestBody(ctx, audit.Source{}, payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &value, Metadata: &payload.Metadata{Unit: "bytes"}}), &metricErr)
+	assert.Equal(t, http.StatusNotImplemented, metricErr.HTTPStatus)
+}
+
+func TestUpdateWithPolicy(t *testing.T) {
+	policy, err := validation.NewPolicy("[A-Za-z_][A-Za-z0-9_]*", 32, true, true, map[string][]string{"agent-1": {"Heap*"}})
+	require.NoError(t, err)
+	Policy = policy
+	defer func() { Policy = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := context.Background()
+	agent := audit.Source{ClientID: "agent-1"}
+	value := 1.5
+	nan := math.NaN()
+	negative := int64(-1)
+
+	tests := []struct {
+		name       string
+		src        audit.Source
+		metricType string
+		metricName string
+		value      string
+		wantStatus int
+	}{
+		{name: "valid", metricType: metrics.TypeGauge, metricName: "Alloc", value: "1.5"},
+		{name: "space", metricType: metrics.TypeGauge, metricName: "heap alloc", value: "1", wantStatus: http.StatusBadRequest},
+		{name: "nan", metricType: metrics.TypeGauge, metricName: "Alloc", value: "NaN", wantStatus: http.StatusBadRequest},
+		{name: "inf_observation", metricType: metrics.TypeSummary, metricName: "latency", value: "+Inf", wantStatus: http.StatusBadRequest},
+		{name: "negative_delta", metricType: metrics.TypeCounter, metricName: "PollCount", value: "-1", wantStatus: http.StatusBadRequest},
+		{name: "not_allowed", src: agent, metricType: metrics.TypeCounter, metricName: "PollCount", value: "1", wantStatus: http.StatusForbidden},
+		{name: "allowed_prefix", src: agent, metricType: metrics.TypeGauge, metricName: "HeapIdle", value: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := updateMetricByStringValue(ctx, tt.src, tt.metricType, tt.metricName, tt.value)
+			if tt.wantStatus == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var metricErr *UpdateMetricError
+			require.ErrorAs(t, err, &metricErr)
+			assert.Equal(t, tt.wantStatus, metricErr.HTTPStatus)
+		})
+	}
+
+	var metricErr *UpdateMetricError
+	require.ErrorAs(t, updateMetricByRequestBody(ctx, audit.Source{}, payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &nan}), &metricErr)
+	assert.Equal(t, http.StatusBadRequest, metricErr.HTTPStatus)
+
+	// В пакете проверяются все метрики, и ни одна не записывается, если какие-то отклонены
+	_, err = updateMetricsByRequestBody(ctx, audit.Source{}, []payload.Metrics{
+		{ID: "Frees", MType: metrics.TypeGauge, Value: &value},
+		{ID: "heap alloc", MType: metrics.TypeGauge, Value: &value},
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: &negative},
+	})
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, http.StatusBadRequest, batchErr.HTTPStatus)
+	assert.Equal(t, []payload.ItemError{
+		{Index: 1, ID: "heap alloc", Message: validation.ErrorWrongName.Error()},
+		{Index: 2, ID: "PollCount", Message: validation.ErrorNegativeDelta.Error()},
+	}, batchErr.Items)
+	require.ErrorAs(t, err, &metricErr)
+	_, ok := metrics.MeStore.GetGauge("Frees")
+	assert.False(t, ok)
+
+	// Пакет только с запрещёнными клиенту именами
+	_, err = updateMetricsByRequestBody(ctx, agent, []payload.Metrics{{ID: "PollCount", MType: metrics.TypeCounter, Delta: new(int64)}})
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, http.StatusForbidden, batchErr.HTTPStatus)
+}
+
+func TestUpdateWithSeriesLimits(t *testing.T) {
+	cardinality.Series = cardinality.New(0, 2)
+	defer func() { cardinality.Series = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := context.Background()
+	agent := audit.Source{ClientID: "agent-1"}
+	value := 1.5
+	delta := int64(2)
+
+	require.NoError(t, updateMetricByStringValue(ctx, agent, metrics.TypeGauge, "Alloc", "1"))
+	require.NoError(t, updateMetricByRequestBody(ctx, agent, payload.Metrics{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta}))
+	// Новый ряд сверх лимита клиента отклоняется, а существующие обновляются
+	var metricErr *UpdateMetricError
+	require.ErrorAs(t, updateMetricByStringValue(ctx, agent, metrics.TypeGauge, "Frees", "1"), &metricErr)
+	assert.Equal(t, http.StatusTooManyRequests, metricErr.HTTPStatus)
+	require.ErrorAs(t, updateMetricByRequestBody(ctx, agent, payload.Metrics{ID: "Latency", MType: metrics.TypeSummary, Value: &value}), &metricErr)
+	assert.Equal(t, http.StatusTooManyRequests, metricErr.HTTPStatus)
+	require.NoError(t, updateMetricByRequestBody(ctx, agent, payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &value}))
+
+	// В пакете отбрасываются только метрики новых рядов
+	rejected, err := updateMetricsByRequestBody(ctx, agent, []payload.Metrics{
+		{ID: "Frees", MType: metrics.TypeGauge, Value: &value},
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta},
+		{ID: "Frees", MType: metrics.TypeGauge, Value: &value},
+		{ID: "HeapIdle", MType: metrics.TypeGauge, Value: &value, Metadata: &payload.Metadata{Unit: "bytes"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []payload.ItemError{
+		{Index: 0, ID: "Frees", Message: cardinality.ErrorClientSeriesLimit.Error()},
+		{Index: 2, ID: "Frees", Message: cardinality.ErrorClientSeriesLimit.Error()},
+		{Index: 3, ID: "HeapIdle", Message: cardinality.ErrorClientSeriesLimit.Error()},
+	}, rejected)
+	pollCount, ok := metrics.MeStore.GetCounter("PollCount")
+	require.True(t, ok)
+	assert.Equal(t, metrics.Counter(4), pollCount)
+	_, ok = metrics.MeStore.GetGauge("Frees")
+	assert.False(t, ok)
+	_, ok = metrics.GetMetadataByKey(metrics.MeStore, metrics.ListKey{Type: metrics.TypeGauge, Name: "HeapIdle"})
+	assert.False(t, ok)
+
+	// Отклонённые ряды учитываются в собственной метрике сервера, а не в хранилище
+	assert.Equal(t, map[string]uint64{"agent-1": 4}, cardinality.Series.Rejected())
+	_, ok = metrics.MeStore.GetCounter(cardinality.RejectedMetricName("agent-1"))
+	assert.False(t, ok)
+
+	// Удалённый ряд освобождает место
+	require.NoError(t, deleteMetric(ctx, agent, metrics.TypeGauge, "Alloc"))
+	require.NoError(t, updateMetricByStringValue(ctx, agent, metrics.TypeGauge, "Frees", "1"))
+}
+
+func TestUpdateWithSeriesLimits_Anonymous(t *testing.T) {
+	cardinality.Series = cardinality.New(0, 1)
+	defer func() { cardinality.Series = nil }()
+	metrics.MeStore = metrics.NewMemStorage()
+	ctx := context.Background()
+	first := audit.Source{PeerIP: "10.0.0.1"}
+	second := audit.Source{PeerIP: "10.0.0.2"}
+
+	// Анонимные клиенты учитываются по адресу соединения, а не вместе
+	require.NoError(t, updateMetricByStringValue(ctx, first, metrics.TypeGauge, "Alloc", "1"))
+	require.NoError(t, updateMetricByStringValue(ctx, second, metrics.TypeGauge, "Frees", "1"))
+	var metricErr *UpdateMetricError
+	require.ErrorAs(t, updateMetricByStringValue(ctx, first, metrics.TypeGauge, "HeapIdle", "1"), &metricErr)
+	assert.Equal(t, http.StatusTooManyRequests, metricErr.HTTPStatus)
+	assert.Equal(t, map[string]uint64{"10.0.0.1": 1}, cardinality.Series.Rejected())
+}
+
+func TestUpdateWithTenants(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	teamA := &tenant.Namespace{Name: "team-a", Storage: metrics.NewMemStorage(), Series: cardinality.New(1, 0)}
+	tenant.Tenants = tenant.NewRegistry(map[string]string{"agent-1": "team-a"})
+	tenant.Tenants.Add(teamA)
+	defer func() { tenant.Tenants = nil }()
+	ctx := context.Background()
+	agent := audit.Source{ClientID: "agent-1"}
+	shared := audit.Source{ClientID: "agent-2"}
+	value := 1.5
+	delta := int64(2)
+
+	// Метрики тенанта пишутся в его хранилище, а метрики клиента без тенанта - в общее
+	require.NoError(t, updateMetricByStringValue(ctx, agent, metrics.TypeGauge, "Alloc", "1"))
+	require.NoError(t, updateMetricByRequestBody(ctx, shared, payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &value}))
+	alloc, ok := teamA.Storage.GetGauge("Alloc")
+	require.True(t, ok)
+	assert.Equal(t, metrics.Gauge(1), alloc)
+	alloc, ok = metrics.MeStore.GetGauge("Alloc")
+	require.True(t, ok)
+	assert.Equal(t, metrics.Gauge(1.5), alloc)
+
+	// Лимит рядов тенанта не зависит от рядов общего пространства имён
+	rejected, err := updateMetricsByRequestBody(ctx, agent, []payload.Metrics{
+		{ID: "Alloc", MType: metrics.TypeGauge, Value: &value},
+		{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []payload.ItemError{{Index: 1, ID: "PollCount", Message: cardinality.ErrorSeriesLimit.Error()}}, rejected)
+	assert.Equal(t, map[string]uint64{"agent-1": 1}, teamA.Series.Rejected())
+	require.NoError(t, updateMetricByRequestBody(ctx, shared, payload.Metrics{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta}))
+	_, ok = teamA.Storage.GetCounter("PollCount")
+	assert.False(t, ok)
+
+	// Удаление у тенанта не трогает общую метрику с тем же именем
+	require.NoError(t, deleteMetric(ctx, agent, metrics.TypeGauge, "Alloc"))
+	_, ok = teamA.Storage.GetGauge("Alloc")
+	assert.False(t, ok)
+	_, ok = metrics.MeStore.GetGauge("Alloc")
+	assert.True(t, ok)
+}
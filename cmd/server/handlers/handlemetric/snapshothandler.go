@@ -0,0 +1,161 @@
+package handlemetric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/snapshot"
+	"gmetrics/internal/tenant"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// ImportModeMerge метрики снимка записываются поверх существующих, остальные метрики сохраняются
+	ImportModeMerge = "merge"
+	// ImportModeReplace хранилище заменяется снимком целиком
+	ImportModeReplace = "replace"
+)
+
+// MaxImportSize максимальный размер разжатого снимка при загрузке; 0 - без ограничений
+var MaxImportSize int64
+
+// ExportPageSize количество метрик, которое выгрузка читает из хранилища за раз
+var ExportPageSize = 1000
+
+// ErrorWrongImportMode ошибка, что режим загрузки не поддерживается
+var ErrorWrongImportMode = errors.New("mode must be merge or replace")
+
+// exportContentTypes тип содержимого снимка по формату
+var exportContentTypes = map[string]string{
+	snapshot.FormatNDJSON: "application/x-ndjson",
+	snapshot.FormatJSON:   "application/gzip",
+}
+
+// exportExtensions расширение файла снимка по формату
+var exportExtensions = map[string]string{
+	snapshot.FormatNDJSON: ".ndjson",
+	snapshot.FormatJSON:   ".json.gz",
+}
+
+// ExportHandler Выгрузка снимка хранилища
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Выгрузка снимка хранилища
+// @Description Выгружает все метрики со значениями, временем обновления и метками, а также описания метрик в переносимом формате. Метрики читаются из хранилища страницами и отдаются потоком; если выгрузка прервалась, в снимке не будет количества метрик и он не загрузится. Требует токен с областью действия admin
+// @Tags Администрирование
+// @Produce application/x-ndjson,application/gzip
+// @Param format query string false "ndjson (по умолчанию) или json, сжатый gzip"
+// @Success 200 {string} string "Снимок"
+// @Failure 400 {object} payload.ErrorResponse "Неверный формат"
+// @Failure 500 {object} payload.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 501 {object} payload.ErrorResponse "Хранилище не умеет отдавать список метрик"
+// @Router /admin/export [get]
+func ExportHandler(response http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format == "" {
+		format = snapshot.FormatNDJSON
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody(snapshot.ErrorWrongFormat.Error()))
+		return
+	}
+	namespace := tenant.FromContext(request.Context())
+	store, ok := namespace.Storage.(metrics.IListingStorage)
+	if !ok {
+		helpers.SetHTTPResponse(response, http.StatusNotImplemented, helpers.GetErrorJSONBody(metrics.ErrorListNotSupported.Error()))
+		return
+	}
+	query := metrics.ListQuery{Sort: metrics.ListSortName, Limit: ExportPageSize}
+	list, err := metrics.ListContext(request.Context(), store, query)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	metadata, err := metrics.GetAllMetadata(namespace.Storage)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	now := time.Now()
+	response.Header().Set("Content-Type", contentType)
+	response.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="gmetrics-%s%s"`, now.UTC().Format("20060102T150405Z"), exportExtensions[format]))
+	response.WriteHeader(http.StatusOK)
+	// Заголовки уже отправлены, поэтому ошибку записи можно только залогировать.
+	// Незакрытый снимок остаётся без количества метрик, и загрузка его отклонит
+	if err = exportPages(request.Context(), store, query, list, response, format, metrics.MetadataEntries(metadata), now); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// exportPages запись снимка: первая страница уже прочитана, следующие читаются по курсору,
+// пока страница не окажется короче query.Limit
+func exportPages(ctx context.Context, store metrics.IListingStorage, query metrics.ListQuery, list []metrics.ListedMetric,
+	w io.Writer, format string, metadata []metrics.MetadataEntry, createdAt time.Time) error {
+	writer, err := snapshot.NewWriter(w, format, metadata, createdAt)
+	if err != nil {
+		return err
+	}
+	for {
+		if err = writer.Add(list); err != nil {
+			return err
+		}
+		if len(list) < query.Limit || query.Limit <= 0 {
+			return writer.Close()
+		}
+		after := list[len(list)-1].Key()
+		query.After = &after
+		if list, err = metrics.ListContext(ctx, store, query); err != nil {
+			return err
+		}
+	}
+}
+
+// ImportHandler Загрузка снимка хранилища
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Загрузка снимка хранилища
+// @Description Загружает снимок любого формата, выгруженный /admin/export, вместе со временем обновления и описаниями метрик. Описания объединяются с сохранёнными и не удаляются при replace. Метрики проверяются политикой сервера так же, как при записи; если отклонена хоть одна, снимок не загружается. Требует токен с областью действия admin
+// @Tags Администрирование
+// @Accept application/x-ndjson,application/gzip
+// @Produce json
+// @Param mode query string false "merge (по умолчанию) - записать поверх, replace - заменить хранилище снимком"
+// @Success 200 {object} payload.ResponseBody "Снимок загружен"
+// @Failure 400 {object} payload.ResponseBody "Неверный снимок или режим, либо метрики, отклонённые политикой, с ошибкой каждой"
+// @Failure 403 {object} payload.ResponseBody "Все отклонённые метрики запрещены клиенту"
+// @Failure 413 {object} payload.ErrorResponse "Снимок слишком большой"
+// @Failure 500 {object} payload.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/import [post]
+func ImportHandler(response http.ResponseWriter, request *http.Request) {
+	mode := request.URL.Query().Get("mode")
+	if mode == "" {
+		mode = ImportModeMerge
+	}
+	if mode != ImportModeMerge && mode != ImportModeReplace {
+		helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody(ErrorWrongImportMode.Error()))
+		return
+	}
+	snap, err := snapshot.Read(request.Body, MaxImportSize)
+	if err != nil {
+		logger.Log.Infow("Bad snapshot for import", "error", err)
+		helpers.SetHTTPResponse(response, helpers.ReadBodyErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	err = importMetrics(request.Context(), httpSource(request, audit.TransportJSON), snap.Metrics, snap.Metadata, mode == ImportModeReplace)
+	writeAdminResponse(response, err, fmt.Sprintf("%d metrics successfully imported", len(snap.Metrics)))
+}
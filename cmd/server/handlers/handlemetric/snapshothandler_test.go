@@ -0,0 +1,244 @@
+package handlemetric
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/snapshot"
+	"gmetrics/internal/validation"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotStore хранилище с тремя метриками для выгрузки
+func snapshotStore(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	require.NoError(t, metrics.MeStore.SetGauge("Alloc", 1.5))
+	require.NoError(t, metrics.MeStore.AddCounter("PollCount", 3))
+	latency := metrics.NewHistogram([]float64{1})
+	latency.Observe(0.5)
+	require.NoError(t, metrics.AddHistogramContext(context.Background(), metrics.MeStore, "Latency", latency))
+	require.NoError(t, metrics.SetMetadataContext(context.Background(), metrics.MeStore,
+		metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}, metrics.Metadata{Help: "Allocated heap"}))
+}
+
+func TestExportHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantContentType string
+	}{
+		{name: "ndjson_by_default", url: "/admin/export", wantStatus: http.StatusOK, wantContentType: "application/x-ndjson"},
+		{name: "json", url: "/admin/export?format=json", wantStatus: http.StatusOK, wantContentType: "application/gzip"},
+		{name: "wrong_format", url: "/admin/export?format=csv", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshotStore(t)
+			w := httptest.NewRecorder()
+			ExportHandler(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+			snap, err := snapshot.Read(w.Body, 0)
+			require.NoError(t, err)
+			require.Len(t, snap.Metrics, 3)
+			assert.Equal(t, "Alloc", snap.Metrics[0].Name)
+			assert.Equal(t, metrics.Gauge(1.5), snap.Metrics[0].Gauge)
+			assert.Equal(t, uint64(1), snap.Metrics[1].Histogram.Count)
+			assert.Equal(t, metrics.Counter(3), snap.Metrics[2].Counter)
+			assert.Equal(t, []metrics.MetadataEntry{
+				{Type: metrics.TypeGauge, Name: "Alloc", Metadata: metrics.Metadata{Help: "Allocated heap"}},
+			}, snap.Metadata)
+		})
+	}
+}
+
+// pagedStore хранилище, которое запоминает запрошенные страницы списка
+type pagedStore struct {
+	*metrics.MemStorage
+	pages int
+}
+
+func (s *pagedStore) List(query metrics.ListQuery) ([]metrics.ListedMetric, error) {
+	s.pages++
+	return s.MemStorage.List(query)
+}
+
+func TestExportHandler_Pages(t *testing.T) {
+	defer func(size int) { ExportPageSize = size }(ExportPageSize)
+	tests := []struct {
+		pageSize  int
+		wantPages int
+	}{
+		{pageSize: 1, wantPages: 4},
+		{pageSize: 2, wantPages: 2},
+		{pageSize: 3, wantPages: 2},
+		{pageSize: 1000, wantPages: 1},
+	}
+	for _, format := range []string{snapshot.FormatNDJSON, snapshot.FormatJSON} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s_%d", format, tt.pageSize), func(t *testing.T) {
+				snapshotStore(t)
+				store := &pagedStore{MemStorage: metrics.MeStore.(*metrics.MemStorage)}
+				metrics.MeStore = store
+				ExportPageSize = tt.pageSize
+				w := httptest.NewRecorder()
+				ExportHandler(w, httptest.NewRequest(http.MethodGet, "/admin/export?format="+format, nil))
+				require.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, tt.wantPages, store.pages)
+				snap, err := snapshot.Read(w.Body, 0)
+				require.NoError(t, err)
+				names := make([]string, 0, len(snap.Metrics))
+				for _, metric := range snap.Metrics {
+					names = append(names, metric.Name)
+				}
+				assert.Equal(t, []string{"Alloc", "Latency", "PollCount"}, names)
+			})
+		}
+	}
+}
+
+func TestImportHandler(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var body bytes.Buffer
+	require.NoError(t, snapshot.Write(&body, snapshot.FormatNDJSON, []metrics.ListedMetric{
+		{Type: metrics.TypeGauge, Name: "Alloc", Gauge: 2.5, UpdatedAt: updated},
+		{Type: metrics.TypeCounter, Name: "Requests", Counter: 7, UpdatedAt: updated},
+	}, []metrics.MetadataEntry{
+		{Type: metrics.TypeGauge, Name: "Alloc", Metadata: metrics.Metadata{Unit: "bytes"}},
+	}, updated))
+
+	tests := []struct {
+		name         string
+		url          string
+		body         string
+		wantStatus   int
+		wantCounters map[string]metrics.Counter
+	}{
+		{
+			name:         "merge",
+			url:          "/admin/import",
+			body:         body.String(),
+			wantStatus:   http.StatusOK,
+			wantCounters: map[string]metrics.Counter{"PollCount": 3, "Requests": 7},
+		},
+		{
+			name:         "replace",
+			url:          "/admin/import?mode=replace",
+			body:         body.String(),
+			wantStatus:   http.StatusOK,
+			wantCounters: map[string]metrics.Counter{"Requests": 7},
+		},
+		{
+			name:         "wrong_mode",
+			url:          "/admin/import?mode=append",
+			body:         body.String(),
+			wantStatus:   http.StatusBadRequest,
+			wantCounters: map[string]metrics.Counter{"PollCount": 3},
+		},
+		{
+			name:         "wrong_snapshot",
+			url:          "/admin/import",
+			body:         `{"version":99}`,
+			wantStatus:   http.StatusBadRequest,
+			wantCounters: map[string]metrics.Counter{"PollCount": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshotStore(t)
+			w := httptest.NewRecorder()
+			ImportHandler(w, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			counters, err := metrics.MeStore.GetCounters()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCounters, counters)
+			if tt.wantStatus == http.StatusOK {
+				assert.Contains(t, w.Body.String(), "2 metrics successfully imported")
+				value, _ := metrics.MeStore.GetGauge("Alloc")
+				assert.Equal(t, metrics.Gauge(2.5), value)
+				// Описание из снимка объединяется с сохранённым и при замене
+				metadata, _ := metrics.GetMetadataByKey(metrics.MeStore, metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"})
+				assert.Equal(t, metrics.Metadata{Unit: "bytes", Help: "Allocated heap"}, metadata)
+			}
+		})
+	}
+}
+
+func TestImportHandler_Policy(t *testing.T) {
+	policy, err := validation.NewPolicy("[A-Za-z]+", 0, true, true, nil)
+	require.NoError(t, err)
+	Policy = policy
+	defer func() { Policy = nil }()
+	write := func(list []metrics.ListedMetric) string {
+		var body bytes.Buffer
+		require.NoError(t, snapshot.Write(&body, snapshot.FormatNDJSON, list, nil, time.Now()))
+		return body.String()
+	}
+
+	snapshotStore(t)
+	response := httptest.NewRecorder()
+	ImportHandler(response, httptest.NewRequest(http.MethodPost, "/admin/import?mode=replace", strings.NewReader(write([]metrics.ListedMetric{
+		{Type: metrics.TypeGauge, Name: "Heap Alloc", Gauge: 1},
+		{Type: metrics.TypeCounter, Name: "Requests", Counter: 7},
+		{Type: metrics.TypeGauge, Name: "Ratio", Gauge: metrics.Gauge(math.Inf(1))},
+	}))))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	var result payload.ResponseBody
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, []payload.ItemError{
+		{Index: 0, ID: "Heap Alloc", Message: validation.ErrorWrongName.Error()},
+		{Index: 2, ID: "Ratio", Message: validation.ErrorNonFinite.Error()},
+	}, result.Errors)
+	// Снимок с отклонёнными метриками не загружается совсем
+	counters, err := metrics.MeStore.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]metrics.Counter{"PollCount": 3}, counters)
+
+	// Значение counter в снимке - не приращение, поэтому отрицательное значение загружается
+	response = httptest.NewRecorder()
+	ImportHandler(response, httptest.NewRequest(http.MethodPost, "/admin/import", strings.NewReader(write([]metrics.ListedMetric{
+		{Type: metrics.TypeCounter, Name: "Balance", Counter: -5},
+	}))))
+	assert.Equal(t, http.StatusOK, response.Code)
+	value, ok := metrics.MeStore.GetCounter("Balance")
+	require.True(t, ok)
+	assert.Equal(t, metrics.Counter(-5), value)
+}
+
+func TestImportAudit(t *testing.T) {
+	snapshotStore(t)
+	sink, closeAudit := withAudit(t)
+
+	src := audit.Source{Transport: audit.TransportJSON, ClientID: "admin"}
+	require.NoError(t, importMetrics(context.Background(), src, []metrics.ListedMetric{
+		{Type: metrics.TypeGauge, Name: "Alloc", Gauge: 2.5},
+		{Type: metrics.TypeCounter, Name: "Requests", Counter: 7},
+	}, nil, true))
+	closeAudit()
+
+	require.Len(t, sink.events, 1)
+	assert.Equal(t, audit.ActionImport, sink.events[0].Action)
+	assert.Equal(t, []audit.Change{
+		{Name: "PollCount", Type: metrics.TypeCounter, Old: int64(3)},
+		{Name: "Requests", Type: metrics.TypeCounter, New: int64(7)},
+		{Name: "Alloc", Type: metrics.TypeGauge, Old: 1.5, New: 2.5},
+		{Name: "Latency", Type: metrics.TypeHistogram, Old: uint64(1)},
+	}, sink.events[0].Metrics)
+}
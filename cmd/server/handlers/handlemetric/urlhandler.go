@@ -3,6 +3,7 @@ package handlemetric
 import (
 	"errors"
 	"fmt"
+	"gmetrics/internal/audit"
 	"gmetrics/internal/helpers"
 	"gmetrics/internal/logger"
 	"net/http"
@@ -36,7 +37,7 @@ func URLHandler(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	updatedErr := updateMetricByStringValue(metricType, metricName, metricValue)
+	updatedErr := updateMetricByStringValue(request.Context(), httpSource(request, audit.TransportURL), metricType, metricName, metricValue)
 	if updatedErr != nil {
 		var metricErr *UpdateMetricError
 		if errors.As(updatedErr, &metricErr) {
@@ -0,0 +1,26 @@
+package listmetrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"gmetrics/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Example for Handler
+func ExampleHandler() {
+	metrics.MeStore = metrics.NewMemStorage()
+	// Set Server
+	router := chi.NewRouter()
+	router.Get("/api/v1/metrics", Handler)
+	// запускаем тестовый сервер, будет выбран первый свободный порт
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	// Set up an HTTP request.
+	request := resty.New().R()
+	request.Method = http.MethodGet
+	request.URL = srv.URL + "/api/v1/metrics?type=gauge&prefix=Heap&sort=-name&limit=10"
+
+	_, _ = request.Send()
+}
@@ -0,0 +1,227 @@
+package listmetrics
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"gmetrics/internal/tenant"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit размер страницы, если он не указан
+	DefaultLimit = 100
+	// MaxLimit наибольший размер страницы
+	MaxLimit = 1000
+)
+
+var (
+	// ErrorWrongType ошибка, что тип метрики не поддерживается
+	ErrorWrongType = errors.New("type must be gauge, counter, histogram, summary or set")
+	// ErrorWrongSort ошибка, что сортировка не поддерживается
+	ErrorWrongSort = errors.New("sort must be name, -name, type or -type")
+	// ErrorWrongLimit ошибка, что размер страницы не число от 1 до MaxLimit
+	ErrorWrongLimit = errors.New("limit must be a number from 1 to 1000")
+	// ErrorWrongRegex ошибка, что регулярное выражение некорректно
+	ErrorWrongRegex = errors.New("wrong name regex")
+	// ErrorWrongCursor ошибка, что курсор некорректен
+	ErrorWrongCursor = errors.New("wrong cursor")
+)
+
+// Handler Возвращает страницу списка метрик в JSON
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Handler
+// @Description Возвращает отфильтрованный отсортированный список метрик постранично
+// @Tags Метрики
+// @Produce json
+// @Param type query string false "gauge, counter, histogram, summary или set"
+// @Param prefix query string false "Префикс имени"
+// @Param glob query string false "Шаблон базового имени, например, Heap*"
+// @Param regex query string false "Регулярное выражение для всего базового имени"
+// @Param label query []string false "Условия на метки: key=value, key!=value, key=~regex, key!~regex"
+// @Param sort query string false "name, -name, type или -type"
+// @Param limit query int false "Размер страницы, от 1 до 1000"
+// @Param cursor query string false "Курсор следующей страницы из next_cursor"
+// @Success 200 {object} payload.MetricsList
+// @Failure 400 {object} helpers.ErrorResponse
+// @Failure 500 {object} helpers.ErrorResponse
+// @Failure 501 {object} helpers.ErrorResponse
+// @Router /api/v1/metrics [get]
+func Handler(response http.ResponseWriter, request *http.Request) {
+	namespace := tenant.FromContext(request.Context())
+	store, ok := namespace.Storage.(metrics.IListingStorage)
+	if !ok {
+		helpers.SetHTTPResponse(response, http.StatusNotImplemented, helpers.GetErrorJSONBody(metrics.ErrorListNotSupported.Error()))
+		return
+	}
+	query, err := parseQuery(request.URL.Query())
+	if err != nil {
+		helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	// Берём на одну метрику больше, чтобы узнать, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+	list, err := metrics.ListContext(request.Context(), store, query)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+
+	metadata, err := metrics.GetAllMetadata(namespace.Storage)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, helpers.StorageErrorStatus(err), helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+
+	result := payload.MetricsList{Metrics: make([]payload.ListedMetric, 0, min(len(list), limit))}
+	if len(list) > limit {
+		list = list[:limit]
+		result.NextCursor = EncodeCursor(list[limit-1].Key())
+	}
+	for _, metric := range list {
+		listed := listedMetric(metric)
+		if m, ok := metadata[metric.Key()]; ok {
+			listed.Metadata = &payload.Metadata{Unit: m.Unit, Help: m.Help, Owner: m.Owner}
+		}
+		result.Metrics = append(result.Metrics, listed)
+	}
+	jsonResponse, err := json.Marshal(result)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	response.WriteHeader(http.StatusOK)
+	if _, err = response.Write(jsonResponse); err != nil {
+		logger.Log.Error(err)
+	}
+}
+
+// parseQuery разбор параметров запроса списка
+func parseQuery(values url.Values) (metrics.ListQuery, error) {
+	query := metrics.ListQuery{
+		Filter: metrics.ListFilter{
+			Type:   values.Get("type"),
+			Prefix: values.Get("prefix"),
+			Glob:   values.Get("glob"),
+		},
+		Sort:  metrics.ListSortName,
+		Limit: DefaultLimit,
+	}
+	switch query.Filter.Type {
+	case "", metrics.TypeGauge, metrics.TypeCounter, metrics.TypeHistogram, metrics.TypeSummary, metrics.TypeSet:
+	default:
+		return query, ErrorWrongType
+	}
+	if query.Filter.Glob != "" {
+		if err := metrics.ValidateGlob(query.Filter.Glob); err != nil {
+			return query, err
+		}
+	}
+	if raw := values.Get("regex"); raw != "" {
+		re, err := regexp.Compile("^(?:" + raw + ")$")
+		if err != nil {
+			return query, ErrorWrongRegex
+		}
+		query.Filter.Regexp = re
+	}
+	for _, raw := range values["label"] {
+		matcher, err := metrics.ParseLabelMatcher(raw)
+		if err != nil {
+			return query, err
+		}
+		query.Filter.Labels = append(query.Filter.Labels, matcher)
+	}
+	if raw := values.Get("sort"); raw != "" {
+		query.Desc = strings.HasPrefix(raw, "-")
+		query.Sort = strings.TrimPrefix(raw, "-")
+		if query.Sort != metrics.ListSortName && query.Sort != metrics.ListSortType {
+			return query, ErrorWrongSort
+		}
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return query, ErrorWrongLimit
+		}
+		query.Limit = limit
+	}
+	if raw := values.Get("cursor"); raw != "" {
+		after, err := decodeCursor(raw)
+		if err != nil {
+			return query, err
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// EncodeCursor курсор страницы, следующей за метрикой с ключом key
+func EncodeCursor(key metrics.ListKey) string {
+	raw, err := json.Marshal(key)
+	if err != nil {
+		logger.Log.Error(err)
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor ключ метрики из курсора
+func decodeCursor(cursor string) (*metrics.ListKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrorWrongCursor
+	}
+	var key metrics.ListKey
+	if err = json.Unmarshal(raw, &key); err != nil || key.Type == "" || key.Name == "" {
+		return nil, ErrorWrongCursor
+	}
+	return &key, nil
+}
+
+// listedMetric метрика для ответа. NaN и бесконечности не представимы в JSON числом, поэтому передаются строкой.
+// Гистограмма передаётся корзинами, сводка - количеством, суммой и квантилями, а множество - оценкой количества элементов, как в /value
+func listedMetric(metric metrics.ListedMetric) payload.ListedMetric {
+	_, labels := metrics.SplitLabels(metric.Name)
+	result := payload.ListedMetric{
+		Type:      metric.Type,
+		Name:      metric.Name,
+		Labels:    labels,
+		UpdatedAt: metric.UpdatedAt,
+	}
+	switch metric.Type {
+	case metrics.TypeCounter:
+		result.Value = metric.Counter.GetRaw()
+	case metrics.TypeHistogram:
+		result.Value = metric.Histogram.Payload()
+	case metrics.TypeSummary:
+		result.Value = metric.Summary.Value(time.Now(), metrics.DefaultSummaryWindow).Payload()
+	case metrics.TypeSet:
+		result.Value = metric.Set.Estimate()
+	default:
+		value := metric.Gauge.GetRaw()
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			result.Value = strconv.FormatFloat(value, 'g', -1, 64)
+		} else {
+			result.Value = value
+		}
+	}
+	return result
+}
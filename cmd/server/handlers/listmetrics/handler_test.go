@@ -0,0 +1,167 @@
+package listmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNames  []string
+		wantValues []any
+		wantCursor bool
+	}{
+		{
+			name:       "all",
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"Alloc", "HeapAlloc", "NaNGauge", "PollCount", "Users", `requests{method="GET"}`, `requests{method="POST"}`},
+			wantValues: []any{1.5, float64(2), "NaN", float64(7), float64(2), float64(3), float64(4)},
+		},
+		{
+			name:       "counters_by_label",
+			query:      "?type=counter&label=method!%3DPOST",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"PollCount", `requests{method="GET"}`},
+			wantValues: []any{float64(7), float64(3)},
+		},
+		{
+			name:       "glob_desc_page",
+			query:      "?glob=*Alloc&sort=-name&limit=1",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"HeapAlloc"},
+			wantValues: []any{float64(2)},
+			wantCursor: true,
+		},
+		{
+			name:       "regex",
+			query:      "?regex=req.*",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{`requests{method="GET"}`, `requests{method="POST"}`},
+			wantValues: []any{float64(3), float64(4)},
+		},
+		{
+			name:       "next_page",
+			query:      "?limit=2&cursor=" + EncodeCursor(metrics.ListKey{Type: metrics.TypeGauge, Name: "HeapAlloc"}),
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"NaNGauge", "PollCount"},
+			wantValues: []any{"NaN", float64(7)},
+			wantCursor: true,
+		},
+		{
+			name:       "sets",
+			query:      "?type=set",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"Users"},
+			wantValues: []any{float64(2)},
+		},
+		{name: "wrong_type", query: "?type=unknown", wantStatus: http.StatusBadRequest},
+		{name: "wrong_sort", query: "?sort=value", wantStatus: http.StatusBadRequest},
+		{name: "wrong_limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "wrong_glob", query: "?glob=%5B", wantStatus: http.StatusBadRequest},
+		{name: "wrong_regex", query: "?regex=%28", wantStatus: http.StatusBadRequest},
+		{name: "wrong_label", query: "?label=method", wantStatus: http.StatusBadRequest},
+		{name: "wrong_cursor", query: "?cursor=abc", wantStatus: http.StatusBadRequest},
+	}
+
+	metrics.MeStore = metrics.NewMemStorage()
+	_ = metrics.MeStore.SetGauge("Alloc", 1.5)
+	_ = metrics.MeStore.SetGauge("HeapAlloc", 2)
+	_ = metrics.MeStore.SetGauge("NaNGauge", metrics.Gauge(math.NaN()))
+	_ = metrics.MeStore.AddCounter("PollCount", 7)
+	_ = metrics.MeStore.AddCounter(`requests{method="GET"}`, 3)
+	_ = metrics.MeStore.AddCounter(`requests{method="POST"}`, 4)
+	_ = metrics.AddSetContext(context.Background(), metrics.MeStore, "Users", metrics.NewSketch("alice", "bob"))
+
+	router := chi.NewRouter()
+	router.Get("/api/v1/metrics", Handler)
+	// запускаем тестовый сервер, будет выбран первый свободный порт
+	srv := httptest.NewServer(router)
+	// останавливаем сервер после завершения теста
+	defer srv.Close()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := resty.New().R().Get(srv.URL + "/api/v1/metrics" + test.query)
+			require.NoError(t, err, "error making HTTP request")
+			assert.Equal(t, test.wantStatus, res.StatusCode(), "unexpected response status code")
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+			var list payload.MetricsList
+			require.NoError(t, json.Unmarshal(res.Body(), &list))
+			names := make([]string, 0, len(list.Metrics))
+			values := make([]any, 0, len(list.Metrics))
+			for _, metric := range list.Metrics {
+				names = append(names, metric.Name)
+				values = append(values, metric.Value)
+				assert.False(t, metric.UpdatedAt.IsZero())
+			}
+			assert.Equal(t, test.wantNames, names)
+			assert.Equal(t, test.wantValues, values)
+			assert.Equal(t, test.wantCursor, list.NextCursor != "")
+		})
+	}
+}
+
+func TestHandlerLabels(t *testing.T) {
+	metrics.MeStore = metrics.NewMemStorage()
+	_ = metrics.MeStore.AddCounter(`requests{method="GET",code="200"}`, 1)
+
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil))
+	require.Equal(t, http.StatusOK, response.Code)
+	var list payload.MetricsList
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &list))
+	require.Len(t, list.Metrics, 1)
+	assert.Equal(t, map[string]string{"method": "GET", "code": "200"}, list.Metrics[0].Labels)
+	assert.Equal(t, metrics.TypeCounter, list.Metrics[0].Type)
+}
+
+func TestHandlerMetadata(t *testing.T) {
+	store := metrics.NewMemStorage()
+	_ = store.SetGauge("Alloc", 1)
+	_ = store.SetGauge("Frees", 2)
+	_ = store.SetMetadata(metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}, metrics.Metadata{Unit: "bytes", Owner: "runtime"})
+	metrics.MeStore = store
+
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil))
+	require.Equal(t, http.StatusOK, response.Code)
+	var list payload.MetricsList
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &list))
+	require.Len(t, list.Metrics, 2)
+	assert.Equal(t, &payload.Metadata{Unit: "bytes", Owner: "runtime"}, list.Metrics[0].Metadata)
+	assert.Nil(t, list.Metrics[1].Metadata)
+}
+
+func TestHandlerNotSupported(t *testing.T) {
+	metrics.MeStore = struct{ metrics.IStorage }{metrics.NewMemStorage()}
+	response := httptest.NewRecorder()
+	Handler(response, httptest.NewRequest(http.MethodGet, "/api/v1/metrics", nil))
+	assert.Equal(t, http.StatusNotImplemented, response.Code)
+}
+
+func TestCursor(t *testing.T) {
+	key := metrics.ListKey{Type: metrics.TypeGauge, Name: `requests{method="GET"}`}
+	decoded, err := decodeCursor(EncodeCursor(key))
+	require.NoError(t, err)
+	assert.Equal(t, key, *decoded)
+
+	_, err = decodeCursor(EncodeCursor(metrics.ListKey{}))
+	assert.ErrorIs(t, err, ErrorWrongCursor)
+}
@@ -0,0 +1,26 @@
+package seriesstats
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"gmetrics/internal/cardinality"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Example for Handler
+func ExampleHandler() {
+	cardinality.Series = cardinality.New(1000, 100)
+	// Set Server
+	router := chi.NewRouter()
+	router.Get("/admin/series/top", Handler)
+	// запускаем тестовый сервер, будет выбран первый свободный порт
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	// Set up an HTTP request.
+	request := resty.New().R()
+	request.Method = http.MethodGet
+	request.URL = srv.URL + "/admin/series/top?limit=5"
+
+	_, _ = request.Send()
+}
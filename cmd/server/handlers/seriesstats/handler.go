@@ -0,0 +1,56 @@
+// Package seriesstats Пакет отдаёт состояние лимитов рядов метрик и клиентов, создавших больше всего рядов
+package seriesstats
+
+import (
+	"encoding/json"
+	"errors"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/tenant"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultLimit сколько клиентов показывать, если не указано
+	DefaultLimit = 10
+	// MaxLimit наибольшее количество клиентов в ответе
+	MaxLimit = 1000
+)
+
+// ErrorWrongLimit ошибка, что количество клиентов не число от 1 до MaxLimit
+var ErrorWrongLimit = errors.New("limit must be a number from 1 to 1000")
+
+// Handler Возвращает состояние лимитов рядов и клиентов с наибольшим количеством рядов
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Handler
+// @Description Возвращает количество рядов, лимиты и клиентов тенанта клиента, создавших больше всего рядов, с количеством отклонённых новых рядов. Требует токен с областью действия admin
+// @Tags Хранилище
+// @Produce json
+// @Param limit query int false "Сколько клиентов вернуть, от 1 до 1000"
+// @Success 200 {object} cardinality.Stats
+// @Failure 400 {object} helpers.ErrorResponse
+// @Failure 500 {object} helpers.ErrorResponse
+// @Router /admin/series/top [get]
+func Handler(response http.ResponseWriter, request *http.Request) {
+	limit := DefaultLimit
+	if raw := request.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			helpers.SetHTTPResponse(response, http.StatusBadRequest, helpers.GetErrorJSONBody(ErrorWrongLimit.Error()))
+			return
+		}
+	}
+	body, err := json.Marshal(tenant.FromContext(request.Context()).Series.Stats(limit))
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	helpers.SetHTTPResponse(response, http.StatusOK, body)
+}
@@ -0,0 +1,70 @@
+package seriesstats
+
+import (
+	"encoding/json"
+	"gmetrics/internal/cardinality"
+	"gmetrics/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	limiter := cardinality.New(0, 2)
+	for _, name := range []string{"Alloc", "Frees", "HeapIdle"} {
+		_ = limiter.Admit("agent-1", metrics.ListKey{Type: metrics.TypeGauge, Name: name})
+	}
+	require.NoError(t, limiter.Admit("agent-2", metrics.ListKey{Type: metrics.TypeGauge, Name: "cpu"}))
+
+	tests := []struct {
+		name       string
+		limiter    *cardinality.Limiter
+		query      string
+		wantStatus int
+		want       cardinality.Stats
+	}{
+		{
+			name:       "disabled",
+			wantStatus: http.StatusOK,
+			want:       cardinality.Stats{Clients: []cardinality.ClientStats{}},
+		},
+		{
+			name:       "top",
+			limiter:    limiter,
+			wantStatus: http.StatusOK,
+			want: cardinality.Stats{Series: 3, MaxClientSeries: 2, Rejected: 1, Clients: []cardinality.ClientStats{
+				{Client: "agent-1", Series: 2, Rejected: 1},
+				{Client: "agent-2", Series: 1},
+			}},
+		},
+		{
+			name:       "limit",
+			limiter:    limiter,
+			query:      "?limit=1",
+			wantStatus: http.StatusOK,
+			want: cardinality.Stats{Series: 3, MaxClientSeries: 2, Rejected: 1, Clients: []cardinality.ClientStats{
+				{Client: "agent-1", Series: 2, Rejected: 1},
+			}},
+		},
+		{name: "wrong_limit", limiter: limiter, query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "not_number", limiter: limiter, query: "?limit=ten", wantStatus: http.StatusBadRequest},
+	}
+	defer func() { cardinality.Series = nil }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cardinality.Series = tt.limiter
+			w := httptest.NewRecorder()
+			Handler(w, httptest.NewRequest(http.MethodGet, "/admin/series/top"+tt.query, nil))
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got cardinality.Stats
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package storagestats
+
+import (
+	"encoding/json"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/tenant"
+	"net/http"
+)
+
+// Handler Возвращает состояние записи хранилища в бд в фоне
+//
+// Parameters:
+// - response: http.ResponseWriter объект, содержащий информацию о ответе HTTP
+// - request: http.Request объект, содержащий информацию о запросе HTTP
+//
+// @Summary Handler
+// @Description Возвращает глубину очереди изменённых метрик и длительность записи их в бд для хранилища тенанта клиента. Требует токен с областью действия admin
+// @Tags Хранилище
+// @Produce json
+// @Success 200 {object} metrics.WriteBehindStats
+// @Failure 500 {object} helpers.ErrorResponse
+// @Router /admin/storage/stats [get]
+func Handler(response http.ResponseWriter, request *http.Request) {
+	var stats metrics.WriteBehindStats
+	if st, ok := tenant.FromContext(request.Context()).Storage.(metrics.IWriteBehindStorage); ok {
+		stats = st.WriteBehindStats()
+	}
+	body, err := json.Marshal(stats)
+	if err != nil {
+		logger.Log.Error(err)
+		helpers.SetHTTPResponse(response, http.StatusInternalServerError, helpers.GetErrorJSONBody(err.Error()))
+		return
+	}
+	helpers.SetHTTPResponse(response, http.StatusOK, body)
+}
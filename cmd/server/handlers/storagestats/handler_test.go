@@ -0,0 +1,59 @@
+package storagestats
+
+import (
+	"context"
+	"encoding/json"
+	"gmetrics/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBehindStore хранилище, которое пишет изменения в бд в фоне
+type writeBehindStore struct {
+	metrics.IStorage
+	stats metrics.WriteBehindStats
+}
+
+// RunWriteBehind запись изменений в фоне
+func (s *writeBehindStore) RunWriteBehind(context.Context, time.Duration) error { return nil }
+
+// WriteBehindStats состояние очереди записи
+func (s *writeBehindStore) WriteBehindStats() metrics.WriteBehindStats { return s.stats }
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		store metrics.IStorage
+		want  metrics.WriteBehindStats
+	}{
+		{
+			name:  "memory",
+			store: metrics.NewMemStorage(),
+			want:  metrics.WriteBehindStats{},
+		},
+		{
+			name: "write_behind",
+			store: &writeBehindStore{
+				IStorage: metrics.NewMemStorage(),
+				stats:    metrics.WriteBehindStats{Enabled: true, QueueDepth: 1, QueueCapacity: 10, Flushes: 2},
+			},
+			want: metrics.WriteBehindStats{Enabled: true, QueueDepth: 1, QueueCapacity: 10, Flushes: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics.MeStore = tt.store
+			w := httptest.NewRecorder()
+			Handler(w, httptest.NewRequest(http.MethodGet, "/admin/storage/stats", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			var got metrics.WriteBehindStats
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -4,19 +4,30 @@ import (
 	"context"
 	"errors"
 	"gmetrics/cmd/server/config"
+	"gmetrics/cmd/server/handlers/exposition"
 	"gmetrics/cmd/server/handlers/getmetric"
 	"gmetrics/cmd/server/handlers/getmetrics"
 	"gmetrics/cmd/server/handlers/handlemetric"
+	"gmetrics/cmd/server/handlers/listmetrics"
 	"gmetrics/cmd/server/handlers/ping"
+	"gmetrics/cmd/server/handlers/seriesstats"
+	"gmetrics/cmd/server/handlers/storagestats"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/auth"
 	"gmetrics/internal/buildflags"
+	"gmetrics/internal/cardinality"
 	"gmetrics/internal/contextkeys"
 	"gmetrics/internal/database"
 	"gmetrics/internal/encrypt"
+	"gmetrics/internal/history"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/metrics"
 	"gmetrics/internal/middlewares"
 	pb "gmetrics/internal/payload/proto"
+	"gmetrics/internal/ratelimit"
+	"gmetrics/internal/tenant"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/encoding/gzip"
 	"log"
@@ -25,12 +36,16 @@ import (
 	_ "net/http/pprof" // подключаем пакет pprof
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	cMiddleware "github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/sync/errgroup"
 )
 
+// expiryInterval наибольший интервал между удалениями устаревших метрик
+const expiryInterval = time.Minute
+
 func main() {
 	buildflags.PrintBuildInformation()
 	go func() {
@@ -72,8 +87,33 @@ func runApplication() error {
 		"fileStorage", config.Params.FileStorage,
 		"restore", config.Params.Restore,
 		"storeInterval", config.Params.StoreInterval,
+		"writeBehind", config.Params.WriteBehind,
+		"memShards", config.Params.MemShards,
 		"databaseDSN", config.Params.DatabaseDSN,
+		"tls", config.Params.TLSConfig != nil,
+		"mTLS", config.Params.TLSClientCAPath != "",
+		"auth", config.Params.Authenticator.Enabled(),
+		"validation", config.Params.Validation.Enabled(),
+		"maxSeries", config.Params.MaxSeries,
+		"maxClientSeries", config.Params.MaxClientSeries,
+		"tenants", len(config.Params.TenantClients),
+		"rateLimit", config.Params.RateLimit,
+		"maxBodySize", config.Params.MaxBodySize,
+		"auditFile", config.Params.AuditFile,
+		"auditURL", config.Params.AuditURL,
+		"metricTTL", config.Params.MetricTTL,
+		"metricTTLMode", config.Params.MetricTTLMode,
 	)
+	handlemetric.MaxBatchSize = config.Params.MaxBatchSize
+	handlemetric.Policy = config.Params.Validation
+	getmetric.MaxBatchSize = config.Params.MaxBatchSize
+	handlemetric.MaxImportSize = config.Params.MaxDecompressedSize
+
+	// Включаем журнал аудита
+	if err = initAudit(); err != nil {
+		return err
+	}
+	defer closeAudit()
 
 	// Вызываем функцию закрытия базы данных
 	defer func() {
@@ -90,21 +130,21 @@ func runApplication() error {
 
 	// Вызываем функцию закрытия
 	defer closeStorage()
+	defer closeTenants()
 	//wg := new(errgroup.Group)
 	wg, ctx2 := errgroup.WithContext(ctx)
 	//wg := sync.WaitGroup{} // Группа для синхронизации
 	// Инициализируем хранилище
 	InitStore(ctx2)
-	// Запускаем синхронизацию хранилища, если оно это подразумевает
-	if st, ok := metrics.MeStore.(metrics.ISynchronizationStorage); ok {
-		ctx3 := context.WithValue(ctx2, contextkeys.SyncInterval, config.Params.StoreInterval)
-		// Запускаем синхронизацию в файл
-		if !st.IsSyncMode() {
-			wg.Go(func() error {
-				return st.Sync(ctx3)
-			})
-		}
-	}
+	// Запускаем запись изменений в бд в фоне или синхронизацию хранилища и устаревание метрик
+	startStorage(ctx2, wg, metrics.MeStore)
+	// Запоминаем недавние значения метрик для графиков на странице метрик
+	history.Recent = history.New(history.DefaultSize)
+	// Учитываем ряды метрик для лимитов и сверяем их с хранилищем
+	cardinality.Series = cardinality.New(config.Params.MaxSeries, config.Params.MaxClientSeries)
+	startNamespace(ctx2, wg, tenant.Shared())
+	// Создаём хранилища тенантов, метрики которых отделены от общих
+	initTenants(ctx2, wg)
 
 	// определяем листенер для сервера rpc
 	listen, err := net.Listen("tcp", config.Params.RPCAddress)
@@ -119,7 +159,7 @@ func runApplication() error {
 	server := initServer()
 	// Запускаем сервер
 	wg.Go(func() error {
-		sErr := server.ListenAndServe()
+		sErr := listenAndServe(server)
 		if sErr != nil && !errors.Is(sErr, http.ErrServerClosed) {
 			return sErr
 		}
@@ -143,9 +183,102 @@ func runApplication() error {
 	return nil
 }
 
+// startStorage запускает запись изменений хранилища в бд в фоне или синхронизацию хранилища,
+// если оно это подразумевает, и устаревание метрик
+func startStorage(ctx context.Context, wg *errgroup.Group, store metrics.IStorage) {
+	if st, ok := store.(metrics.IWriteBehindStorage); ok && config.Params.WriteBehind {
+		interval := time.Duration(config.Params.WriteBehindInterval) * time.Millisecond
+		wg.Go(func() error {
+			return st.RunWriteBehind(ctx, interval)
+		})
+	} else if st, ok := store.(metrics.ISynchronizationStorage); ok {
+		syncCtx := context.WithValue(ctx, contextkeys.SyncInterval, config.Params.StoreInterval)
+		// Запускаем синхронизацию в файл
+		if !st.IsSyncMode() {
+			wg.Go(func() error {
+				return st.Sync(syncCtx)
+			})
+		}
+	}
+	// Включаем устаревание метрик
+	startExpiry(ctx, wg, store)
+}
+
+// startNamespace запускает снятие истории значений и сверку рядов с хранилищем пространства имён
+func startNamespace(ctx context.Context, wg *errgroup.Group, namespace *tenant.Namespace) {
+	wg.Go(func() error {
+		return namespace.History.Run(ctx, namespace.Storage, history.DefaultInterval)
+	})
+	wg.Go(func() error {
+		return namespace.Series.Run(ctx, namespace.Storage, cardinality.DefaultSyncInterval)
+	})
+}
+
+// initTenants создаёт пространства имён тенантов клиентов из конфигурации: хранилище, лимиты рядов и историю.
+// Лимит рядов тенанта заменяет общий лимит сервера, а лимит рядов клиента общий для всех
+func initTenants(ctx context.Context, wg *errgroup.Group) {
+	if len(config.Params.TenantClients) == 0 {
+		tenant.Tenants = nil
+		return
+	}
+	registry := tenant.NewRegistry(config.Params.TenantClients)
+	for _, name := range registry.Names() {
+		maxSeries, ok := config.Params.TenantSeriesLimits[name]
+		if !ok {
+			maxSeries = config.Params.MaxSeries
+		}
+		namespace := &tenant.Namespace{
+			Name:    name,
+			Storage: newStore(ctx, name),
+			Series:  cardinality.New(maxSeries, config.Params.MaxClientSeries),
+			History: history.New(history.DefaultSize),
+		}
+		registry.Add(namespace)
+		startStorage(ctx, wg, namespace.Storage)
+		startNamespace(ctx, wg, namespace)
+		logger.Log.Infow("Tenant namespace created", "tenant", name, "maxSeries", maxSeries)
+	}
+	tenant.Tenants = registry
+}
+
+// closeTenants закрытие хранилищ тенантов
+func closeTenants() {
+	if tenant.Tenants == nil {
+		return
+	}
+	for _, namespace := range tenant.Tenants.Namespaces() {
+		closeStore(namespace.Storage)
+	}
+}
+
+// startExpiry устанавливает хранилищу время жизни метрик. В режиме удаления
+// запускает периодическое удаление устаревших метрик
+func startExpiry(ctx context.Context, wg *errgroup.Group, store metrics.IStorage) {
+	if config.Params.MetricTTL <= 0 {
+		return
+	}
+	st, ok := store.(metrics.IExpiringStorage)
+	if !ok {
+		return
+	}
+	ttl := time.Duration(config.Params.MetricTTL) * time.Second
+	st.SetTTL(ttl)
+	if config.Params.MetricTTLMode != config.TTLModeDelete {
+		return
+	}
+	wg.Go(func() error {
+		return metrics.DeleteExpiredPeriodically(ctx, st, min(ttl, expiryInterval))
+	})
+}
+
 // closeStorage функция закрытия хранилища
 func closeStorage() {
-	st, ok := metrics.MeStore.(metrics.ISynchronizationStorage)
+	closeStore(metrics.MeStore)
+}
+
+// closeStore запись несохранённых метрик хранилища и его закрытие
+func closeStore(store metrics.IStorage) {
+	st, ok := store.(metrics.ISynchronizationStorage)
 	if !ok {
 		return
 	}
@@ -156,17 +289,65 @@ func closeStorage() {
 	logger.Log.Info("Storage closed")
 }
 
+// initAudit создание журнала аудита, если указан хотя бы один приёмник событий
+func initAudit() error {
+	var sinks []audit.Sink
+	if config.Params.AuditFile != "" {
+		sink, err := audit.NewFileSink(config.Params.AuditFile, config.Params.AuditFileMaxSize, config.Params.AuditFileMaxBackups)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if config.Params.AuditURL != "" {
+		sinks = append(sinks, audit.NewHTTPSink(config.Params.AuditURL))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	auditor, err := audit.New(audit.DefaultBufferSize, sinks...)
+	if err != nil {
+		return err
+	}
+	audit.Log = auditor
+	return nil
+}
+
+// closeAudit доставка оставшихся событий и закрытие журнала аудита
+func closeAudit() {
+	if !audit.Log.Enabled() {
+		return
+	}
+	logger.Log.Info("Close audit log")
+	if err := audit.Log.Close(); err != nil {
+		logger.Log.Error(err)
+	}
+	if dropped := audit.Log.Dropped(); dropped > 0 {
+		logger.Log.Infow("Audit events were dropped", "count", dropped)
+	}
+}
+
 // run запуск сервера
 func initServer() *http.Server {
 	logger.Log.Infof("Running server on %s", config.Params.Address)
 	server := http.Server{
-		Addr:    config.Params.Address,
-		Handler: getRouter(),
+		Addr:      config.Params.Address,
+		Handler:   getRouter(),
+		TLSConfig: config.Params.TLSConfig,
 	}
 
 	return &server
 }
 
+// listenAndServe запуск сервера, с шифрованием, если оно настроено
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// Сертификаты уже загружены в конфигурацию TLS
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
 // stopServer корректно завершает работу предоставленного HTTP-сервера, используя заданный контекст. Регистрирует ошибки в случае сбоя завершения работы.
 func stopServer(server *http.Server, ctx context.Context) error {
 	// Заставляем завершиться сервер и ждём его завершения
@@ -184,65 +365,137 @@ func getRouter() chi.Router {
 	router := chi.NewRouter()
 	decrypter := encrypt.NewDecrypter(config.Params.CryptoKey)
 	netFilter := middlewares.NewNetworkMiddleware(config.Params.TrustedSubnet)
+	authorization := middlewares.NewAuthMiddleware(config.Params.Authenticator)
+	rateLimit := middlewares.NewRateLimitMiddleware(ratelimit.New(config.Params.RateLimit, config.Params.RateBurst))
 	// Устанавилваем мидлваре
 	router.Use(
 		cMiddleware.StripSlashes,         // Убираем лишние слеши
 		logger.LogRequests,               // Логируем данные запроса
+		middlewares.ClientIdentity,       // Определяем агента по сертификату
 		middlewares.GZIPCompressResponse, // Сжимаем ответ TODO исключить для роутов, которые будут возвращать не application/json или text/html. Проверять в мидлваре или компрессоре может быть не эффективно,так как заголовок с контентом может быть поставлен позже записи контента
+		middlewares.SignResponse,         // Подписываем несжатое тело ответа
+	)
+	// Тело запроса читается, разжимается и расшифровывается только после проверки токена и ограничения частоты запросов,
+	// поэтому мидлваре тела подключаются в каждой группе после них
+	body := []func(http.Handler) http.Handler{
+		middlewares.LimitBody(config.Params.MaxBodySize), // Ограничиваем размер тела запроса
 		middlewares.CheckSign,
-		middlewares.GZIPDecompressRequest, // Разжимаем тело ответа
+		middlewares.GZIPDecompressRequest, // Разжимаем тело запроса
 		decrypter.Middleware,
-	)
+	}
+	// Запись метрик
 	router.Group(func(r chi.Router) {
-		r.Use(netFilter.FilterNetwork)
+		r.Use(authorization.Require(auth.ScopeWrite), netFilter.FilterNetwork, rateLimit.Limit)
+		r.Use(body...)
 		// Сохранение метрики по URL
 		r.Post("/update/{type}/{name}/{value}", handlemetric.URLHandler)
 	})
 
-	// Получение всех метрик
-	router.Get("/", getmetrics.Handler)
-	// Получение отдельной метрики
-	router.Get("/value/{type}/{name}", getmetric.URLHandler)
-
-	// проверка состояния соединения с базой данных
-	router.Get("/ping", ping.NewController(database.DB).Handler)
+	// Чтение метрик
+	router.Group(func(r chi.Router) {
+		r.Use(authorization.Require(auth.ScopeRead))
+		r.Use(body...)
+		// Получение всех метрик
+		r.With(middlewares.Unsigned).Get("/", getmetrics.Handler)
+		// Страница отдельной метрики
+		r.With(middlewares.Unsigned).Get("/metric/{type}/{name}", getmetrics.MetricHandler)
+		// Получение отдельной метрики
+		r.Get("/value/{type}/{name}", getmetric.URLHandler)
+		// Все метрики в текстовом формате Prometheus
+		r.Get("/metrics", exposition.Handler)
+		// проверка состояния соединения с базой данных
+		r.Get("/ping", ping.NewController(database.DB).Handler)
+	})
 
 	router.Group(func(r chi.Router) {
 		// Устанавилваем мидлваре
 		r.Use(middlewares.JSONHeaders)
 		r.Group(func(r chi.Router) {
-			r.Use(netFilter.FilterNetwork)
+			r.Use(authorization.Require(auth.ScopeWrite), netFilter.FilterNetwork, rateLimit.Limit)
+			r.Use(body...)
 			// Сохранение метрики с помощью JSON тела
 			r.Post("/update", handlemetric.JSONHandler)
 			// Сохранение метрик с помощью JSON тела
 			r.Post("/updates", handlemetric.JSONManyHandler)
 		})
-		// Получение отдельной метрики
-		r.Post("/value", getmetric.JSONHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(authorization.Require(auth.ScopeRead))
+			r.Use(body...)
+			// Получение отдельной метрики
+			r.Post("/value", getmetric.JSONHandler)
+			// Получение многих метрик за раз
+			r.Post("/values", getmetric.JSONManyHandler)
+			// Список метрик с фильтрами и постраничной выборкой
+			r.Get("/api/v1/metrics", listmetrics.Handler)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(authorization.Require(auth.ScopeAdmin), netFilter.FilterNetwork, rateLimit.Limit)
+			r.Use(body...)
+			// Удаление метрики
+			r.Delete("/value/{type}/{name}", handlemetric.DeleteHandler)
+			// Обнуление counter
+			r.Post("/reset/counter/{name}", handlemetric.ResetHandler)
+			// Выгрузка снимка хранилища
+			r.With(middlewares.Unsigned).Get("/admin/export", handlemetric.ExportHandler)
+			// Загрузка снимка хранилища
+			r.Post("/admin/import", handlemetric.ImportHandler)
+			// Состояние записи хранилища в бд в фоне
+			r.Get("/admin/storage/stats", storagestats.Handler)
+			// Клиенты с наибольшим количеством рядов метрик
+			r.Get("/admin/series/top", seriesstats.Handler)
+		})
 	})
 	return router
 }
 
 // InitStore устанавливаем глобальное хранилище метрик.
 func InitStore(ctx context.Context) {
+	metrics.MeStore = newStore(ctx, "")
+}
+
+// newStore создаёт хранилище метрик тенанта name; пустое имя - общее хранилище.
+// Тенанты хранят метрики в тех же таблицах бд под своим именем, а в файле рядом с общим
+func newStore(ctx context.Context, name string) metrics.IStorage {
 	// Если указан путь к файлу, то будет создано хранилище с сохранением в файл, иначе будет создано хранилище в памяти
-	if config.Params.DatabaseDSN != "" {
-		logger.Log.Info("Set database store")
-		store, err := metrics.NewDBStorage(ctx, metrics.NewDBAdapter(database.DB), config.Params.Restore, config.Params.StoreInterval == 0)
+	driver, _ := database.ParseDSN(config.Params.DatabaseDSN)
+	switch {
+	case config.Params.DatabaseDSN != "" && driver == database.DriverSQLite:
+		logger.Log.Infow("Set SQLite store", "tenant", name)
+		store, err := metrics.NewTenantSQLiteStorage(ctx, metrics.NewDBAdapter(database.DB), name, config.Params.Restore, config.Params.StoreInterval == 0)
+		if err != nil {
+			logger.Log.Fatal(err)
+		}
+		if config.Params.WriteBehind {
+			store.EnableWriteBehind(config.Params.WriteBehindQueue)
+		}
+		return store
+	case config.Params.DatabaseDSN != "":
+		logger.Log.Infow("Set database store", "tenant", name)
+		store, err := metrics.NewTenantDBStorage(ctx, metrics.NewDBAdapter(database.DB), name, config.Params.Restore, config.Params.StoreInterval == 0)
 		if err != nil {
 			logger.Log.Fatal(err)
 		}
-		metrics.MeStore = store
-	} else if config.Params.FileStorage != "" {
-		logger.Log.Info("Set file store")
-		store, err := metrics.NewFileStorage(config.Params.FileStorage, config.Params.Restore, config.Params.StoreInterval == 0)
+		if config.Params.WriteBehind {
+			store.EnableWriteBehind(config.Params.WriteBehindQueue)
+		}
+		return store
+	case config.Params.FileStorage != "":
+		path := config.Params.FileStorage
+		if name != "" {
+			path = tenant.StoragePath(path, name)
+		}
+		logger.Log.Infow("Set file store", "tenant", name, "path", path)
+		store, err := metrics.NewFileStorage(path, config.Params.Restore, config.Params.StoreInterval == 0)
 		if err != nil {
 			logger.Log.Fatal(err)
 		}
-		metrics.MeStore = store
-	} else {
-		logger.Log.Info("Set in-memory store")
-		metrics.MeStore = metrics.NewMemStorage()
+		return store
+	case config.Params.MemShards > 0:
+		logger.Log.Infow("Set sharded in-memory store", "tenant", name, "shards", config.Params.MemShards)
+		return metrics.NewShardedMemStorage(config.Params.MemShards)
+	default:
+		logger.Log.Infow("Set in-memory store", "tenant", name)
+		return metrics.NewMemStorage()
 	}
 }
 
@@ -251,7 +504,8 @@ func InitStore(ctx context.Context) {
 func initDB() error {
 	// Создание пула подключений к базе данных для приложения
 	var err error
-	database.DB, err = database.NewDB("pgx", config.Params.DatabaseDSN)
+	driver, dsn := database.ParseDSN(config.Params.DatabaseDSN)
+	database.DB, err = database.NewDB(driver, dsn)
 	if err != nil {
 		return err
 	}
@@ -259,7 +513,11 @@ func initDB() error {
 	if config.Params.DatabaseDSN != "" {
 		logger.Log.Info("Migrate migrations")
 		// Применим миграции
-		migrator, err := migrations()
+		newMigrator := migrations
+		if driver == database.DriverSQLite {
+			newMigrator = sqliteMigrations
+		}
+		migrator, err := newMigrator()
 		if err != nil {
 			return err
 		}
@@ -287,14 +545,33 @@ func closeDB() error {
 func startRPC(listen net.Listener) error {
 	decrypter := encrypt.NewDecrypter(config.Params.CryptoKey)
 	netFilter := middlewares.NewNetworkMiddleware(config.Params.TrustedSubnet)
+	authorization := middlewares.NewAuthMiddleware(config.Params.Authenticator)
+	rateLimit := middlewares.NewRateLimitMiddleware(ratelimit.New(config.Params.RateLimit, config.Params.RateBurst))
 	// создаём gRPC-сервер без зарегистрированной службы
 	encoding.RegisterCompressor(encoding.GetCompressor(gzip.Name))
-	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
-		logger.LogInterceptor,
-		middlewares.CheckSignInterceptor,
-		decrypter.Interceptor,
-		netFilter.Interceptor,
-	))
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			logger.LogInterceptor,
+			middlewares.SignResponseInterceptor,
+			middlewares.ClientIdentityInterceptor,
+			authorization.Interceptor(map[string]auth.Scope{
+				pb.MetricsService_HandleMetrics_FullMethodName: auth.ScopeWrite,
+				pb.MetricsService_DeleteMetric_FullMethodName:  auth.ScopeAdmin,
+				pb.MetricsService_ResetCounter_FullMethodName:  auth.ScopeAdmin,
+			}),
+			rateLimit.Interceptor,
+			middlewares.CheckSignInterceptor,
+			decrypter.Interceptor,
+			netFilter.Interceptor,
+		),
+	}
+	if config.Params.MaxBodySize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(config.Params.MaxBodySize)))
+	}
+	if config.Params.TLSConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config.Params.TLSConfig)))
+	}
+	s := grpc.NewServer(opts...)
 
 	pb.RegisterMetricsServiceServer(s, handlemetric.NewRPCManyHandler())
 
@@ -5,14 +5,23 @@ import (
 	"database/sql"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gmetrics/cmd/server/config"
+	"gmetrics/internal/audit"
+	"gmetrics/internal/auth"
 	"gmetrics/internal/database"
 	"gmetrics/internal/metrics"
+	"gmetrics/internal/tenant"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 func TestGetRouter(t *testing.T) {
@@ -21,6 +30,47 @@ func TestGetRouter(t *testing.T) {
 	assert.NotNil(t, router, "Router should not be nil")
 }
 
+// readTracker тело запроса, которое запоминает, что его читали
+type readTracker struct {
+	read bool
+}
+
+func (r *readTracker) Read([]byte) (int, error) {
+	r.read = true
+	return 0, io.EOF
+}
+
+func TestGetRouter_LimitsBeforeBody(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]auth.Token{{Name: "agent", Value: "secret", Scopes: []auth.Scope{auth.ScopeWrite}}})
+	require.NoError(t, err)
+	config.Params = &config.CliConfig{Authenticator: authenticator, RateLimit: 1, RateBurst: 1}
+	router := getRouter()
+	send := func(token string) (int, bool) {
+		body := &readTracker{}
+		request := httptest.NewRequest(http.MethodPost, "/updates", body)
+		request.Header.Set("Content-Encoding", "gzip")
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response.Code, body.read
+	}
+
+	// Тело клиента без токена не читается и не разжимается
+	code, read := send("")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.False(t, read)
+
+	code, read = send("secret")
+	assert.NotEqual(t, http.StatusTooManyRequests, code)
+	assert.True(t, read)
+	// Тело клиента, превысившего ограничение частоты запросов, тоже не читается
+	code, read = send("secret")
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.False(t, read)
+}
+
 func TestInitServer(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -181,6 +231,97 @@ func TestCloseDB(t *testing.T) {
 	}
 }
 
+func TestInitAudit(t *testing.T) {
+	tests := []struct {
+		name        string
+		cnf         func(t *testing.T) *config.CliConfig
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{
+			name:        "disabled",
+			cnf:         func(t *testing.T) *config.CliConfig { return &config.CliConfig{} },
+			wantEnabled: false,
+		},
+		{
+			name: "file_and_url",
+			cnf: func(t *testing.T) *config.CliConfig {
+				return &config.CliConfig{
+					AuditFile: filepath.Join(t.TempDir(), "audit.log"),
+					AuditURL:  "http://localhost:1/audit",
+				}
+			},
+			wantEnabled: true,
+		},
+		{
+			name: "wrong_file",
+			cnf: func(t *testing.T) *config.CliConfig {
+				return &config.CliConfig{AuditFile: filepath.Join(t.TempDir(), "missing", "audit.log")}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit.Log = nil
+			config.Params = tt.cnf(t)
+			err := initAudit()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEnabled, audit.Log.Enabled())
+			closeAudit()
+			audit.Log = nil
+		})
+	}
+}
+
+func TestStartExpiry(t *testing.T) {
+	tests := []struct {
+		name        string
+		cnf         *config.CliConfig
+		wantExpired bool
+	}{
+		{
+			name:        "disabled",
+			cnf:         &config.CliConfig{MetricTTL: 0, MetricTTLMode: config.TTLModeHide},
+			wantExpired: false,
+		},
+		{
+			name:        "hide",
+			cnf:         &config.CliConfig{MetricTTL: 1, MetricTTLMode: config.TTLModeHide},
+			wantExpired: true,
+		},
+		{
+			name:        "delete",
+			cnf:         &config.CliConfig{MetricTTL: 1, MetricTTLMode: config.TTLModeDelete},
+			wantExpired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Params = tt.cnf
+			store := metrics.NewMemStorage()
+			metrics.MeStore = store
+			assert.NoError(t, store.SetGauge("gauge", 1))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			wg, ctx2 := errgroup.WithContext(ctx)
+			startExpiry(ctx2, wg, store)
+			time.Sleep(1100 * time.Millisecond)
+			_, ok := store.GetGauge("gauge")
+			assert.Equal(t, !tt.wantExpired, ok)
+
+			cancel()
+			assert.NoError(t, wg.Wait())
+		})
+	}
+}
+
 func TestInitStore(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -209,6 +350,13 @@ func TestInitStore(t *testing.T) {
 			},
 			wantStore: "mem",
 		},
+		{
+			name: "init_sharded_memory_store",
+			preFunc: func() {
+				config.Params = &config.CliConfig{MemShards: 8}
+			},
+			wantStore: "sharded",
+		},
 	}
 
 	for _, tt := range tests {
@@ -216,6 +364,7 @@ func TestInitStore(t *testing.T) {
 			tt.preFunc()
 			if config.Params.FileStorage != "" {
 				defer os.Remove(config.Params.FileStorage)
+				defer os.Remove(config.Params.FileStorage + metrics.WALSuffix)
 			}
 			InitStore(context.TODO())
 			switch tt.wantStore {
@@ -228,7 +377,49 @@ func TestInitStore(t *testing.T) {
 			case "mem":
 				_, ok := metrics.MeStore.(*metrics.MemStorage)
 				assert.True(t, ok)
+			case "sharded":
+				_, ok := metrics.MeStore.(*metrics.ShardedMemStorage)
+				assert.True(t, ok)
 			}
 		})
 	}
 }
+
+func TestInitTenants(t *testing.T) {
+	defer func() { tenant.Tenants = nil }()
+	config.Params = &config.CliConfig{
+		FileStorage:        "test_storage_file.json",
+		MaxSeries:          10,
+		TenantClients:      map[string]string{"agent-1": "team-a", "agent-2": "team-b"},
+		TenantSeriesLimits: map[string]int{"team-a": 5},
+	}
+	for _, path := range []string{"test_storage_file.team-a.json", "test_storage_file.team-b.json"} {
+		defer os.Remove(path)
+		defer os.Remove(path + metrics.WALSuffix)
+	}
+	metrics.MeStore = metrics.NewMemStorage()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg, ctx2 := errgroup.WithContext(ctx)
+	initTenants(ctx2, wg)
+	require.NotNil(t, tenant.Tenants)
+	assert.Equal(t, []string{"team-a", "team-b"}, tenant.Tenants.Names())
+
+	teamA := tenant.ForClient("agent-1")
+	assert.Equal(t, "team-a", teamA.Name)
+	assert.NotSame(t, metrics.MeStore, teamA.Storage)
+	_, ok := teamA.Storage.(*metrics.DurationFileStorage)
+	assert.True(t, ok)
+	assert.Equal(t, 5, teamA.Series.Stats(1).MaxSeries)
+	assert.Equal(t, 10, tenant.ForClient("agent-2").Series.Stats(1).MaxSeries)
+	assert.Same(t, metrics.MeStore, tenant.ForClient("agent-3").Storage)
+
+	cancel()
+	assert.NoError(t, wg.Wait())
+	closeTenants()
+
+	// Без тенантов реестр не создаётся
+	config.Params = &config.CliConfig{}
+	initTenants(context.Background(), wg)
+	assert.Nil(t, tenant.Tenants)
+}
@@ -47,6 +47,224 @@ func migrations() (*migrator.Migrator, error) {
 					return nil
 				},
 			},
+			&migrator.Migration{
+				Name: "Add indexes for metric listing",
+				Func: func(tx *sql.Tx) error {
+					// Побайтовый порядок имён для постраничной выборки и поиска по префиксу
+					if _, err := tx.Exec(`create index if not exists t_gauge_name_c_idx on public.t_gauge (name collate "C");`); err != nil {
+						return err
+					}
+					if _, err := tx.Exec(`create index if not exists t_counter_name_c_idx on public.t_counter (name collate "C");`); err != nil {
+						return err
+					}
+					// Поиск устаревших метрик
+					if _, err := tx.Exec("create index if not exists t_gauge_updated_at_idx on public.t_gauge (updated_at);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("create index if not exists t_counter_updated_at_idx on public.t_counter (updated_at);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Create histogram and summary tables",
+				Func: func(tx *sql.Tx) error {
+					// Гистограммы и наблюдения сводок хранятся целиком в JSON
+					if _, err := tx.Exec("create table if not exists public.t_histogram (name varchar primary key, data text not null, created_at timestamp without time zone default now(), updated_at timestamp without time zone default now());"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("create table if not exists public.t_summary (name varchar primary key, data text not null, created_at timestamp without time zone default now(), updated_at timestamp without time zone default now());"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("create index if not exists t_histogram_updated_at_idx on public.t_histogram (updated_at);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("create index if not exists t_summary_updated_at_idx on public.t_summary (updated_at);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Create set table",
+				Func: func(tx *sql.Tx) error {
+					// Скетч HyperLogLog хранится в двоичном виде
+					if _, err := tx.Exec("create table if not exists public.t_set (name varchar primary key, data bytea not null, created_at timestamp without time zone default now(), updated_at timestamp without time zone default now());"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("create index if not exists t_set_updated_at_idx on public.t_set (updated_at);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Create metadata table",
+				Func: func(tx *sql.Tx) error {
+					// Описания метрик: единица измерения, пояснение и команда-владелец
+					if _, err := tx.Exec("create table if not exists public.t_metadata (type varchar not null, name varchar not null, unit varchar not null default '', help text not null default '', owner varchar not null default '', created_at timestamp without time zone default now(), updated_at timestamp without time zone default now(), primary key (type, name));"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Add tenant to metric keys",
+				Func: func(tx *sql.Tx) error {
+					// Метрики тенантов хранятся в общих таблицах, имя уникально внутри тенанта.
+					// Метрики без тенанта попадают в общее пространство имён с пустым тенантом
+					for table, key := range map[string]string{
+						"t_gauge":     "tenant, name",
+						"t_counter":   "tenant, name",
+						"t_histogram": "tenant, name",
+						"t_summary":   "tenant, name",
+						"t_set":       "tenant, name",
+						"t_metadata":  "tenant, type, name",
+					} {
+						if _, err := tx.Exec("alter table public." + table + " add column if not exists tenant varchar not null default '';"); err != nil {
+							return err
+						}
+						if _, err := tx.Exec("alter table public." + table + " drop constraint if exists " + table + "_pkey;"); err != nil {
+							return err
+						}
+						if _, err := tx.Exec("alter table public." + table + " add primary key (" + key + ");"); err != nil {
+							return err
+						}
+					}
+					// Постраничная выборка идёт по метрикам одного тенанта
+					if _, err := tx.Exec("drop index if exists public.t_gauge_name_c_idx;"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("drop index if exists public.t_counter_name_c_idx;"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec(`create index if not exists t_gauge_tenant_name_c_idx on public.t_gauge (tenant, name collate "C");`); err != nil {
+						return err
+					}
+					if _, err := tx.Exec(`create index if not exists t_counter_tenant_name_c_idx on public.t_counter (tenant, name collate "C");`); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+		),
+	)
+}
+
+// sqliteMigrations устанавливает и возвращает экземпляр Migrator с миграциями встроенной бд SQLite.
+// Строки в SQLite по умолчанию сравниваются побайтово, поэтому индексом по имени служит первичный ключ
+func sqliteMigrations() (*migrator.Migrator, error) {
+	return migrator.New(
+		migrator.Migrations(
+			&migrator.Migration{
+				Name: "Create metric tables",
+				Func: func(tx *sql.Tx) error {
+					if _, err := tx.Exec("CREATE TABLE t_gauge (name TEXT PRIMARY KEY, value DOUBLE PRECISION, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("CREATE TABLE t_counter (name TEXT PRIMARY KEY, value BIGINT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Add indexes for expired metrics",
+				Func: func(tx *sql.Tx) error {
+					if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS t_gauge_updated_at_idx ON t_gauge (updated_at);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS t_counter_updated_at_idx ON t_counter (updated_at);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Create histogram and summary tables",
+				Func: func(tx *sql.Tx) error {
+					if _, err := tx.Exec("CREATE TABLE IF NOT EXISTS t_histogram (name TEXT PRIMARY KEY, data TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("CREATE TABLE IF NOT EXISTS t_summary (name TEXT PRIMARY KEY, data TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS t_histogram_updated_at_idx ON t_histogram (updated_at);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS t_summary_updated_at_idx ON t_summary (updated_at);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Create set table",
+				Func: func(tx *sql.Tx) error {
+					if _, err := tx.Exec("CREATE TABLE IF NOT EXISTS t_set (name TEXT PRIMARY KEY, data BLOB NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);"); err != nil {
+						return err
+					}
+					if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS t_set_updated_at_idx ON t_set (updated_at);"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Create metadata table",
+				Func: func(tx *sql.Tx) error {
+					if _, err := tx.Exec("CREATE TABLE IF NOT EXISTS t_metadata (type TEXT NOT NULL, name TEXT NOT NULL, unit TEXT NOT NULL DEFAULT '', help TEXT NOT NULL DEFAULT '', owner TEXT NOT NULL DEFAULT '', created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (type, name));"); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			&migrator.Migration{
+				Name: "Add tenant to metric keys",
+				Func: func(tx *sql.Tx) error {
+					// SQLite не умеет менять первичный ключ, поэтому таблицы пересоздаются с тенантом в ключе
+					tables := []struct {
+						name    string
+						columns string
+						schema  string
+					}{
+						{name: "t_gauge", columns: "name, value", schema: "value DOUBLE PRECISION"},
+						{name: "t_counter", columns: "name, value", schema: "value BIGINT"},
+						{name: "t_histogram", columns: "name, data", schema: "data TEXT NOT NULL"},
+						{name: "t_summary", columns: "name, data", schema: "data TEXT NOT NULL"},
+						{name: "t_set", columns: "name, data", schema: "data BLOB NOT NULL"},
+					}
+					for _, table := range tables {
+						if err := recreateSQLiteTable(tx, table.name, table.columns,
+							"tenant TEXT NOT NULL DEFAULT '', name TEXT NOT NULL, "+table.schema+", created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, name)"); err != nil {
+							return err
+						}
+						if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS " + table.name + "_updated_at_idx ON " + table.name + " (updated_at);"); err != nil {
+							return err
+						}
+					}
+					return recreateSQLiteTable(tx, "t_metadata", "type, name, unit, help, owner",
+						"tenant TEXT NOT NULL DEFAULT '', type TEXT NOT NULL, name TEXT NOT NULL, unit TEXT NOT NULL DEFAULT '', help TEXT NOT NULL DEFAULT '', owner TEXT NOT NULL DEFAULT '', created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, type, name)")
+				},
+			},
 		),
 	)
 }
+
+// recreateSQLiteTable пересоздаёт таблицу SQLite со схемой schema и переносит в неё колонки columns и время создания и обновления.
+// Индексы старой таблицы удаляются вместе с ней
+func recreateSQLiteTable(tx *sql.Tx, table, columns, schema string) error {
+	if _, err := tx.Exec("CREATE TABLE " + table + "_new (" + schema + ");"); err != nil {
+		return err
+	}
+	copyColumns := columns + ", created_at, updated_at"
+	if _, err := tx.Exec("INSERT INTO " + table + "_new (" + copyColumns + ") SELECT " + copyColumns + " FROM " + table + ";"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DROP TABLE " + table + ";"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("ALTER TABLE " + table + "_new RENAME TO " + table + ";")
+	return err
+}
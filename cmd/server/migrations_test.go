@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/stretchr/testify/require"
+	"gmetrics/internal/database"
 	"testing"
 )
 
@@ -31,3 +32,29 @@ func TestMigrations(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLiteMigrations(t *testing.T) {
+	m, err := sqliteMigrations()
+	require.NoError(t, err)
+	db, err := database.NewDB(database.DriverSQLite, ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, m.Migrate(db))
+	// Повторный запуск не применяет миграции заново
+	require.NoError(t, m.Migrate(db))
+	_, err = db.Exec("INSERT INTO t_gauge (name, value) VALUES ('Alloc', 1.5)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t_histogram (name, data) VALUES ('Latency', '{}')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t_set (name, data) VALUES ('Users', x'0102')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t_metadata (type, name, unit) VALUES ('gauge', 'Alloc', 'bytes')")
+	require.NoError(t, err)
+	// Одно имя метрики у разных тенантов
+	_, err = db.Exec("INSERT INTO t_gauge (tenant, name, value) VALUES ('team-a', 'Alloc', 2.5)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO t_gauge (tenant, name, value) VALUES ('team-a', 'Alloc', 3.5)")
+	require.Error(t, err)
+	_, err = db.Exec("INSERT INTO t_metadata (tenant, type, name, unit) VALUES ('team-a', 'gauge', 'Alloc', 'bytes')")
+	require.NoError(t, err)
+}
@@ -0,0 +1,199 @@
+// Package audit Пакет содержит журнал аудита операций записи метрик.
+// События доставляются в приёмники асинхронно, поэтому запись в журнал никогда не блокирует обработку запроса
+package audit
+
+import (
+	"errors"
+	"gmetrics/internal/logger"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// TransportURL запись метрики через параметры урл
+	TransportURL = "url"
+	// TransportJSON запись метрики через JSON тело
+	TransportJSON = "json"
+	// TransportBatch запись набора метрик через JSON тело
+	TransportBatch = "batch"
+	// TransportRPC запись набора метрик через rpc
+	TransportRPC = "grpc"
+
+	// ActionUpdate запись значения метрики
+	ActionUpdate = "update"
+	// ActionDelete удаление метрики
+	ActionDelete = "delete"
+	// ActionReset обнуление counter
+	ActionReset = "reset"
+	// ActionImport загрузка снимка хранилища
+	ActionImport = "import"
+
+	// DefaultBufferSize количество событий, которые могут ожидать доставки
+	DefaultBufferSize = 1024
+	// maxBatch максимальное количество событий, передаваемых приёмнику за раз
+	maxBatch = 100
+)
+
+// ErrorNoSinks ошибка, что журнал создаётся без приёмников
+var ErrorNoSinks = errors.New("audit sinks are not set")
+
+// Change изменение одной метрики. Old равен nil, если метрики до записи не было, New равен nil, если метрика удалена
+type Change struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+// Event событие аудита одной принятой записи
+type Event struct {
+	Time      time.Time `json:"ts"`
+	Transport string    `json:"transport"`
+	Action    string    `json:"action"`
+	ClientIP  string    `json:"ip,omitempty"`
+	ClientID  string    `json:"agent,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Metrics   []Change  `json:"metrics"`
+}
+
+// Source источник записи: способ передачи и клиент
+type Source struct {
+	Transport string
+	ClientIP  string
+	ClientID  string
+	Tenant    string // Тенант клиента, пустой у общего пространства имён
+	PeerIP    string // Адрес соединения без порта, по нему учитываются ряды анонимных клиентов
+}
+
+// Event создаёт событие действия action от источника с текущим временем
+func (s Source) Event(action string, changes []Change) Event {
+	return Event{
+		Time:      time.Now().UTC(),
+		Transport: s.Transport,
+		Action:    action,
+		ClientIP:  s.ClientIP,
+		ClientID:  s.ClientID,
+		Tenant:    s.Tenant,
+		Metrics:   changes,
+	}
+}
+
+// Sink приёмник событий аудита
+type Sink interface {
+	io.Closer
+	// Write сохраняет пачку событий
+	Write(events []Event) error
+}
+
+// Auditor журнал аудита с асинхронной доставкой событий во все приёмники
+type Auditor struct {
+	sinks   []Sink
+	events  chan Event
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Int64
+}
+
+// Log глобальный журнал аудита, если nil, то аудит отключен
+var Log *Auditor
+
+// New создаёт журнал и запускает доставку событий. В очереди ожидают не больше bufferSize событий,
+// если bufferSize не больше нуля, то используется DefaultBufferSize
+func New(bufferSize int, sinks ...Sink) (*Auditor, error) {
+	if len(sinks) == 0 {
+		return nil, ErrorNoSinks
+	}
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	a := &Auditor{
+		sinks:  sinks,
+		events: make(chan Event, bufferSize),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+// Enabled включен ли аудит
+func (a *Auditor) Enabled() bool {
+	return a != nil
+}
+
+// Record ставит событие в очередь доставки. Не блокируется: если очередь заполнена, то событие отбрасывается
+func (a *Auditor) Record(event Event) {
+	if a == nil {
+		return
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return
+	}
+	select {
+	case a.events <- event:
+	default:
+		if a.dropped.Add(1) == 1 {
+			logger.Log.Warn("audit queue is full, events are dropped")
+		}
+	}
+}
+
+// Dropped количество отброшенных из-за переполнения очереди событий
+func (a *Auditor) Dropped() int64 {
+	if a == nil {
+		return 0
+	}
+	return a.dropped.Load()
+}
+
+// Close доставляет оставшиеся в очереди события и закрывает приёмники
+func (a *Auditor) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.events)
+	a.mu.Unlock()
+
+	<-a.done
+	var errs []error
+	for _, sink := range a.sinks {
+		errs = append(errs, sink.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// run доставка событий пачками, пока очередь не закрыта
+func (a *Auditor) run() {
+	defer close(a.done)
+	batch := make([]Event, 0, maxBatch)
+	for event := range a.events {
+		batch = append(batch[:0], event)
+	collect:
+		for len(batch) < maxBatch {
+			select {
+			case next, ok := <-a.events:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+		for _, sink := range a.sinks {
+			if err := sink.Write(batch); err != nil {
+				logger.Log.Errorf("audit sink: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink приёмник, запоминающий события
+type memorySink struct {
+	mu      sync.Mutex
+	events  []Event
+	closed  bool
+	started chan struct{} // сигнал о начале записи
+	release chan struct{} // запись ждёт, пока канал не закроют
+}
+
+func (s *memorySink) Write(events []Event) error {
+	if s.started != nil {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(0)
+	assert.ErrorIs(t, err, ErrorNoSinks)
+
+	a, err := New(0, &memorySink{})
+	require.NoError(t, err)
+	assert.True(t, a.Enabled())
+	assert.Equal(t, DefaultBufferSize, cap(a.events))
+	assert.NoError(t, a.Close())
+}
+
+func TestAuditor_Record(t *testing.T) {
+	sink := &memorySink{}
+	a, err := New(10, sink)
+	require.NoError(t, err)
+
+	src := Source{Transport: TransportJSON, ClientIP: "127.0.0.1", ClientID: "agent-1", Tenant: "team-a"}
+	for i := 0; i < 3; i++ {
+		a.Record(src.Event(ActionUpdate, []Change{{Name: "Alloc", Type: "gauge", New: float64(i)}}))
+	}
+	require.NoError(t, a.Close())
+	// После закрытия события не принимаются
+	a.Record(src.Event(ActionUpdate, nil))
+
+	assert.True(t, sink.closed)
+	require.Len(t, sink.events, 3)
+	assert.Equal(t, "agent-1", sink.events[0].ClientID)
+	assert.Equal(t, "team-a", sink.events[0].Tenant)
+	assert.Equal(t, TransportJSON, sink.events[0].Transport)
+	assert.Equal(t, ActionUpdate, sink.events[0].Action)
+	assert.Equal(t, float64(2), sink.events[2].Metrics[0].New)
+	assert.NoError(t, a.Close())
+}
+
+func TestAuditor_RecordDropsWhenFull(t *testing.T) {
+	sink := &memorySink{started: make(chan struct{}), release: make(chan struct{})}
+	a, err := New(1, sink)
+	require.NoError(t, err)
+
+	a.Record(Event{Transport: TransportURL})
+	// Первое событие уже у приёмника, который не отвечает
+	<-sink.started
+	a.Record(Event{Transport: TransportURL})
+	a.Record(Event{Transport: TransportURL})
+	assert.Equal(t, int64(1), a.Dropped())
+
+	close(sink.release)
+	go func() {
+		for range sink.started {
+		}
+	}()
+	require.NoError(t, a.Close())
+	assert.Len(t, sink.events, 2)
+}
+
+func TestAuditor_Nil(t *testing.T) {
+	var a *Auditor
+	assert.False(t, a.Enabled())
+	a.Record(Event{})
+	assert.Zero(t, a.Dropped())
+	assert.NoError(t, a.Close())
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents читает события из файла журнала
+func readEvents(t *testing.T, path string) []Event {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestFileSink_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write([]Event{
+		{Transport: TransportURL, Metrics: []Change{{Name: "PollCount", Type: "counter", Old: int64(1), New: int64(3)}}},
+		{Transport: TransportRPC, ClientID: "agent-1"},
+	}))
+	require.NoError(t, sink.Close())
+
+	// Файл дописывается после повторного открытия
+	sink, err = NewFileSink(path, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write([]Event{{Transport: TransportJSON}}))
+	require.NoError(t, sink.Close())
+
+	events := readEvents(t, path)
+	require.Len(t, events, 3)
+	assert.Equal(t, "PollCount", events[0].Metrics[0].Name)
+	assert.Equal(t, float64(1), events[0].Metrics[0].Old)
+	assert.Equal(t, "agent-1", events[1].ClientID)
+	assert.Equal(t, TransportJSON, events[2].Transport)
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBackups  int
+		wantBackups []string
+	}{
+		{name: "with_backups", maxBackups: 2, wantBackups: []string{"audit.log.1", "audit.log.2"}},
+		{name: "without_backups", maxBackups: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "audit.log")
+			line, err := json.Marshal(Event{Transport: TransportURL})
+			require.NoError(t, err)
+			// В файл помещается одно событие
+			sink, err := NewFileSink(path, int64(len(line)+1), tt.maxBackups)
+			require.NoError(t, err)
+			for i := 0; i < 4; i++ {
+				require.NoError(t, sink.Write([]Event{{Transport: TransportURL}}))
+			}
+			require.NoError(t, sink.Close())
+
+			assert.Len(t, readEvents(t, path), 1)
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			var backups []string
+			for _, entry := range entries {
+				if entry.Name() != "audit.log" {
+					backups = append(backups, entry.Name())
+				}
+			}
+			assert.Equal(t, tt.wantBackups, backups)
+		})
+	}
+}
+
+func TestNewFileSink_Error(t *testing.T) {
+	_, err := NewFileSink(filepath.Join(t.TempDir(), "missing", "audit.log"), 0, 0)
+	assert.Error(t, err)
+}
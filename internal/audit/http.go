@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// httpSinkTimeout время ожидания ответа от приёмника
+const httpSinkTimeout = 5 * time.Second
+
+// ErrorSinkStatus ошибка, что приёмник ответил неуспешным статусом
+var ErrorSinkStatus = errors.New("audit sink responded with error status")
+
+// HTTPSink приёмник, отправляющий пачку событий JSON массивом в POST запросе
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink создаёт приёмник, отправляющий события на url
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: httpSinkTimeout},
+	}
+}
+
+// Write отправляет события
+func (s *HTTPSink) Write(events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", ErrorSinkStatus, res.StatusCode)
+	}
+	return nil
+}
+
+// Close у приёмника нет ресурсов для закрытия
+func (s *HTTPSink) Close() error {
+	return nil
+}
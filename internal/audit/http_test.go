@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSink_Write(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "server_error", status: http.StatusInternalServerError, wantErr: ErrorSinkStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			sink := NewHTTPSink(server.URL)
+			err := sink.Write([]Event{{Transport: TransportBatch}, {Transport: TransportRPC}})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			require.Len(t, got, 2)
+			assert.Equal(t, TransportRPC, got[1].Transport)
+			assert.NoError(t, sink.Close())
+		})
+	}
+}
+
+func TestHTTPSink_WriteUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	assert.Error(t, NewHTTPSink(url).Write([]Event{{}}))
+}
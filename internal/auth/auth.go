@@ -0,0 +1,160 @@
+// Package auth Пакет содержит проверку токенов доступа к серверу и их области действия
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scope область действия токена
+type Scope string
+
+const (
+	// ScopeRead чтение метрик
+	ScopeRead Scope = "read"
+	// ScopeWrite запись метрик
+	ScopeWrite Scope = "write"
+	// ScopeAdmin административные операции, включает в себя все остальные области
+	ScopeAdmin Scope = "admin"
+)
+
+var (
+	// ErrorNoToken ошибка, что в запросе не передан токен
+	ErrorNoToken = errors.New("authentication token is required")
+	// ErrorInvalidToken ошибка, что переданный токен не найден
+	ErrorInvalidToken = errors.New("invalid authentication token")
+	// ErrorForbidden ошибка, что у токена нет нужной области действия
+	ErrorForbidden = errors.New("token scope is not sufficient")
+	// ErrorUnknownScope ошибка, что в конфигурации указана неизвестная область действия
+	ErrorUnknownScope = errors.New("unknown token scope")
+	// ErrorEmptyToken ошибка, что в конфигурации указан пустой токен
+	ErrorEmptyToken = errors.New("token value is empty")
+	// ErrorWrongTokenFormat ошибка, что токен в строке конфигурации записан неверно
+	ErrorWrongTokenFormat = errors.New("token must be in format name:token:scope[|scope]")
+)
+
+// Token токен доступа с его областями действия
+type Token struct {
+	Name   string  `json:"name"`   // Имя владельца токена, используется как идентификатор клиента
+	Value  string  `json:"token"`  // Значение токена
+	Scopes []Scope `json:"scopes"` // Области действия токена
+}
+
+// HasScope проверяет, разрешена ли токену область действия. Токену с ScopeAdmin разрешено всё
+func (t *Token) HasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// storedToken токен с хэшем значения для сравнения за постоянное время
+type storedToken struct {
+	hash  [sha256.Size]byte
+	token Token
+}
+
+// Authenticator проверка токенов доступа
+type Authenticator struct {
+	tokens []storedToken
+}
+
+// NewAuthenticator создаёт проверку для переданных токенов.
+// Если токенов нет, то проверка отключена и любой запрос считается разрешённым
+func NewAuthenticator(tokens []Token) (*Authenticator, error) {
+	stored := make([]storedToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Value == "" {
+			return nil, fmt.Errorf("%w for %q", ErrorEmptyToken, token.Name)
+		}
+		for _, scope := range token.Scopes {
+			if scope != ScopeRead && scope != ScopeWrite && scope != ScopeAdmin {
+				return nil, fmt.Errorf("%w %q for %q", ErrorUnknownScope, scope, token.Name)
+			}
+		}
+		stored = append(stored, storedToken{hash: sha256.Sum256([]byte(token.Value)), token: token})
+	}
+	return &Authenticator{tokens: stored}, nil
+}
+
+// Enabled включена ли проверка токенов
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.tokens) > 0
+}
+
+// Authenticate ищет токен по его значению.
+// Сравниваются хэши значений за постоянное время, перебор не прерывается на совпадении,
+// чтобы время ответа не зависело от того, какой токен и насколько совпал
+func (a *Authenticator) Authenticate(value string) (*Token, error) {
+	if value == "" {
+		return nil, ErrorNoToken
+	}
+	hash := sha256.Sum256([]byte(value))
+	var found *Token
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], a.tokens[i].hash[:]) == 1 {
+			found = &a.tokens[i].token
+		}
+	}
+	if found == nil {
+		return nil, ErrorInvalidToken
+	}
+	return found, nil
+}
+
+// Authorize проверяет токен и его область действия
+func (a *Authenticator) Authorize(value string, scope Scope) (*Token, error) {
+	token, err := a.Authenticate(value)
+	if err != nil {
+		return nil, err
+	}
+	if !token.HasScope(scope) {
+		return token, ErrorForbidden
+	}
+	return token, nil
+}
+
+// ParseTokens разбирает токены из строки конфигурации.
+// Формат: name:token:scope[|scope], токены разделяются запятой. Например: agent:secret:write,grafana:secret2:read
+func ParseTokens(s string) ([]Token, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	tokens := make([]Token, 0, len(parts))
+	for _, part := range parts {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 3 || fields[0] == "" || fields[1] == "" || fields[2] == "" {
+			return nil, ErrorWrongTokenFormat
+		}
+		token := Token{Name: fields[0], Value: fields[1]}
+		for _, scope := range strings.Split(fields[2], "|") {
+			token.Scopes = append(token.Scopes, Scope(scope))
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// ParseTokensFile читает токены из JSON файла вида [{"name":"agent","token":"secret","scopes":["write"]}]
+func ParseTokensFile(path string) ([]Token, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	if err = json.Unmarshal(file, &tokens); err != nil {
+		return nil, fmt.Errorf("parse tokens file %s: %w", path, err)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken_HasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []Scope
+		scope  Scope
+		want   bool
+	}{
+		{name: "same_scope", scopes: []Scope{ScopeRead}, scope: ScopeRead, want: true},
+		{name: "other_scope", scopes: []Scope{ScopeRead}, scope: ScopeWrite, want: false},
+		{name: "admin_has_all", scopes: []Scope{ScopeAdmin}, scope: ScopeWrite, want: true},
+		{name: "no_scopes", scope: ScopeRead, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := Token{Scopes: tt.scopes}
+			assert.Equal(t, tt.want, token.HasScope(tt.scope))
+		})
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name        string
+		tokens      []Token
+		wantErr     error
+		wantEnabled bool
+	}{
+		{
+			name:        "disabled",
+			wantEnabled: false,
+		},
+		{
+			name:        "valid",
+			tokens:      []Token{{Name: "agent", Value: "secret", Scopes: []Scope{ScopeWrite}}},
+			wantEnabled: true,
+		},
+		{
+			name:    "empty_value",
+			tokens:  []Token{{Name: "agent", Scopes: []Scope{ScopeWrite}}},
+			wantErr: ErrorEmptyToken,
+		},
+		{
+			name:    "unknown_scope",
+			tokens:  []Token{{Name: "agent", Value: "secret", Scopes: []Scope{"delete"}}},
+			wantErr: ErrorUnknownScope,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAuthenticator(tt.tokens)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEnabled, got.Enabled())
+		})
+	}
+}
+
+func TestAuthenticator_Authorize(t *testing.T) {
+	authenticator, err := NewAuthenticator([]Token{
+		{Name: "agent", Value: "agent-secret", Scopes: []Scope{ScopeWrite}},
+		{Name: "grafana", Value: "grafana-secret", Scopes: []Scope{ScopeRead}},
+		{Name: "root", Value: "root-secret", Scopes: []Scope{ScopeAdmin}},
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		value    string
+		scope    Scope
+		wantName string
+		wantErr  error
+	}{
+		{name: "write_token", value: "agent-secret", scope: ScopeWrite, wantName: "agent"},
+		{name: "read_token", value: "grafana-secret", scope: ScopeRead, wantName: "grafana"},
+		{name: "admin_token", value: "root-secret", scope: ScopeRead, wantName: "root"},
+		{name: "wrong_scope", value: "grafana-secret", scope: ScopeWrite, wantErr: ErrorForbidden},
+		{name: "unknown_token", value: "agent-secret2", scope: ScopeWrite, wantErr: ErrorInvalidToken},
+		{name: "no_token", scope: ScopeRead, wantErr: ErrorNoToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, aErr := authenticator.Authorize(tt.value, tt.scope)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, aErr, tt.wantErr)
+				return
+			}
+			assert.NoError(t, aErr)
+			assert.Equal(t, tt.wantName, got.Name)
+		})
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Token
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: nil,
+		},
+		{
+			name: "several_tokens",
+			in:   "agent:secret:write, admin:secret2:read|admin",
+			want: []Token{
+				{Name: "agent", Value: "secret", Scopes: []Scope{ScopeWrite}},
+				{Name: "admin", Value: "secret2", Scopes: []Scope{ScopeRead, ScopeAdmin}},
+			},
+		},
+		{
+			name:    "no_scope",
+			in:      "agent:secret",
+			wantErr: true,
+		},
+		{
+			name:    "empty_value",
+			in:      "agent::write",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTokens(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrorWrongTokenFormat)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseTokensFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "tokens.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`[{"name":"agent","token":"secret","scopes":["write"]}]`), 0600))
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"name":`), 0600))
+	tests := []struct {
+		name    string
+		path    string
+		want    []Token
+		wantErr bool
+	}{
+		{
+			name: "no_file",
+			path: "",
+			want: nil,
+		},
+		{
+			name: "valid_file",
+			path: valid,
+			want: []Token{{Name: "agent", Value: "secret", Scopes: []Scope{ScopeWrite}}},
+		},
+		{
+			name:    "invalid_json",
+			path:    invalid,
+			wantErr: true,
+		},
+		{
+			name:    "not_exists",
+			path:    filepath.Join(dir, "not_exists.json"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTokensFile(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
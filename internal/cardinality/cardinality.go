@@ -0,0 +1,291 @@
+// Package cardinality Пакет ограничивает количество рядов метрик на сервере в целом и для каждого клиента.
+// Новые ряды сверх лимита отклоняются, а существующие продолжают обновляться
+package cardinality
+
+import (
+	"context"
+	"errors"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RejectedMetric имя собственной метрики сервера: сколько новых рядов отклонено у клиента.
+	// Метрика не пишется в хранилище и выводится вместе с метриками пространства имён
+	RejectedMetric = "gmetrics_series_rejected"
+	// DefaultSyncInterval как часто ряды сверяются с хранилищем
+	DefaultSyncInterval = time.Minute
+	// MaxRejectedClients сколько клиентов учитывается в отклонениях по отдельности
+	MaxRejectedClients = 1000
+	// OtherClients клиент, под которым учитываются отклонения клиентов сверх MaxRejectedClients
+	OtherClients = "*"
+)
+
+var (
+	// ErrorSeriesLimit ошибка, что на сервере уже наибольшее разрешённое количество рядов
+	ErrorSeriesLimit = errors.New("series limit reached")
+	// ErrorClientSeriesLimit ошибка, что клиент уже создал наибольшее разрешённое количество рядов
+	ErrorClientSeriesLimit = errors.New("client series limit reached")
+)
+
+// owner клиент, создавший ряд, и время создания
+type owner struct {
+	client string
+	added  time.Time
+}
+
+// ClientStats ряды и отклонения клиента
+type ClientStats struct {
+	Client   string `json:"client"`   // Идентификатор клиента, пустой для анонимных клиентов и рядов без владельца
+	Series   int    `json:"series"`   // Сколько рядов создал клиент
+	Rejected uint64 `json:"rejected"` // Сколько новых рядов клиента отклонено
+}
+
+// Stats состояние лимитов рядов
+type Stats struct {
+	Series          int           `json:"series"`
+	MaxSeries       int           `json:"max_series"`        // 0 - без ограничений
+	MaxClientSeries int           `json:"max_client_series"` // 0 - без ограничений
+	Rejected        uint64        `json:"rejected"`
+	Clients         []ClientStats `json:"clients"` // Клиенты с наибольшим количеством рядов
+}
+
+// Limiter ряды сервера с их владельцами и лимиты на их количество
+type Limiter struct {
+	maxSeries       int
+	maxClientSeries int
+	mu              sync.Mutex
+	series          map[metrics.ListKey]owner
+	perClient       map[string]int
+	rejected        map[string]uint64
+}
+
+// Series глобальные ряды сервера, если nil, то ряды не учитываются и не ограничиваются
+var Series *Limiter
+
+// New создаёт учёт рядов с лимитами на сервер и на клиента; 0 - без ограничений
+func New(maxSeries, maxClientSeries int) *Limiter {
+	return &Limiter{
+		maxSeries:       maxSeries,
+		maxClientSeries: maxClientSeries,
+		series:          make(map[metrics.ListKey]owner),
+		perClient:       make(map[string]int),
+		rejected:        make(map[string]uint64),
+	}
+}
+
+// Admit проверка ряда, в который пишет клиент clientID. Существующий ряд пропускается всегда,
+// а новый запоминается за клиентом, если не превышен ни один лимит
+func (l *Limiter) Admit(clientID string, key metrics.ListKey) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.series[key]; ok {
+		return nil
+	}
+	if l.maxSeries > 0 && len(l.series) >= l.maxSeries {
+		l.unsafeReject(clientID)
+		return ErrorSeriesLimit
+	}
+	if l.maxClientSeries > 0 && l.perClient[clientID] >= l.maxClientSeries {
+		l.unsafeReject(clientID)
+		return ErrorClientSeriesLimit
+	}
+	l.series[key] = owner{client: clientID, added: time.Now()}
+	l.perClient[clientID]++
+	return nil
+}
+
+// unsafeReject учёт отклонённого ряда клиента. Новые клиенты сверх MaxRejectedClients учитываются вместе под OtherClients
+func (l *Limiter) unsafeReject(clientID string) {
+	if _, ok := l.rejected[clientID]; !ok && len(l.rejected) >= MaxRejectedClients {
+		clientID = OtherClients
+	}
+	l.rejected[clientID]++
+}
+
+// Rejected сколько новых рядов отклонено у каждого клиента
+func (l *Limiter) Rejected() map[string]uint64 {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rejected := make(map[string]uint64, len(l.rejected))
+	for client, count := range l.rejected {
+		rejected[client] = count
+	}
+	return rejected
+}
+
+// Forget удалённый ряд больше не учитывается
+func (l *Limiter) Forget(key metrics.ListKey) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if o, ok := l.series[key]; ok {
+		delete(l.series, key)
+		l.unsafeDecrement(o.client)
+	}
+}
+
+// unsafeDecrement уменьшение количества рядов клиента
+func (l *Limiter) unsafeDecrement(client string) {
+	if l.perClient[client] <= 1 {
+		delete(l.perClient, client)
+		return
+	}
+	l.perClient[client]--
+}
+
+// Sync сверка рядов с хранилищем. Ряды, которых больше нет, забываются, а ряды, записанные в обход лимитов,
+// например при восстановлении или загрузке снимка, учитываются без владельца.
+// Ряды, пропущенные во время сверки, сохраняются, даже если их ещё нет в прочитанном состоянии хранилища.
+// Собственные метрики сервера, сохранённые прежними версиями, не учитываются
+func (l *Limiter) Sync(storage metrics.IStorage) error {
+	if l == nil {
+		return nil
+	}
+	start := time.Now()
+	keys, err := seriesKeys(storage)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	series := make(map[metrics.ListKey]owner, len(keys))
+	for _, key := range keys {
+		if IsSelfMetric(key.Name) {
+			continue
+		}
+		series[key] = l.series[key]
+	}
+	for key, o := range l.series {
+		if !o.added.Before(start) {
+			series[key] = o
+		}
+	}
+	perClient := make(map[string]int)
+	for _, o := range series {
+		perClient[o.client]++
+	}
+	l.series = series
+	l.perClient = perClient
+	return nil
+}
+
+// Run сверяет ряды с хранилищем каждые interval, пока контекст не завершён
+func (l *Limiter) Run(ctx context.Context, storage metrics.IStorage, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := l.Sync(storage); err != nil {
+			logger.Log.Error(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stats состояние лимитов и top клиентов с наибольшим количеством рядов
+func (l *Limiter) Stats(top int) Stats {
+	if l == nil {
+		return Stats{Clients: make([]ClientStats, 0)}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := Stats{
+		Series:          len(l.series),
+		MaxSeries:       l.maxSeries,
+		MaxClientSeries: l.maxClientSeries,
+		Clients:         make([]ClientStats, 0, len(l.perClient)),
+	}
+	clients := make(map[string]*ClientStats, len(l.perClient))
+	for client, count := range l.perClient {
+		clients[client] = &ClientStats{Client: client, Series: count}
+	}
+	for client, count := range l.rejected {
+		stats.Rejected += count
+		if _, ok := clients[client]; !ok {
+			clients[client] = &ClientStats{Client: client}
+		}
+		clients[client].Rejected = count
+	}
+	for _, client := range clients {
+		stats.Clients = append(stats.Clients, *client)
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool {
+		a, b := stats.Clients[i], stats.Clients[j]
+		if a.Series != b.Series {
+			return a.Series > b.Series
+		}
+		if a.Rejected != b.Rejected {
+			return a.Rejected > b.Rejected
+		}
+		return a.Client < b.Client
+	})
+	if top > 0 && len(stats.Clients) > top {
+		stats.Clients = stats.Clients[:top]
+	}
+	return stats
+}
+
+// RejectedMetricName имя собственной метрики с отклонёнными рядами клиента в нотации с метками
+func RejectedMetricName(clientID string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(clientID)
+	return RejectedMetric + `{client="` + escaped + `"}`
+}
+
+// IsSelfMetric метрика name - собственная метрика сервера с отклонёнными рядами
+func IsSelfMetric(name string) bool {
+	base, _ := metrics.SplitLabels(name)
+	return base == RejectedMetric
+}
+
+// seriesKeys ряды всех типов в хранилище
+func seriesKeys(storage metrics.IStorage) ([]metrics.ListKey, error) {
+	gauges, err := storage.GetGauges()
+	if err != nil {
+		return nil, err
+	}
+	counters, err := storage.GetCounters()
+	if err != nil {
+		return nil, err
+	}
+	histograms, summaries, err := metrics.GetDistributions(storage)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := metrics.GetSets(storage)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]metrics.ListKey, 0, len(gauges)+len(counters)+len(histograms)+len(summaries)+len(sets))
+	for name := range gauges {
+		keys = append(keys, metrics.ListKey{Type: metrics.TypeGauge, Name: name})
+	}
+	for name := range counters {
+		keys = append(keys, metrics.ListKey{Type: metrics.TypeCounter, Name: name})
+	}
+	for name := range histograms {
+		keys = append(keys, metrics.ListKey{Type: metrics.TypeHistogram, Name: name})
+	}
+	for name := range summaries {
+		keys = append(keys, metrics.ListKey{Type: metrics.TypeSummary, Name: name})
+	}
+	for name := range sets {
+		keys = append(keys, metrics.ListKey{Type: metrics.TypeSet, Name: name})
+	}
+	return keys, nil
+}
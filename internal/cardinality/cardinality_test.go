@@ -0,0 +1,160 @@
+package cardinality
+
+import (
+	"gmetrics/internal/metrics"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gauge(name string) metrics.ListKey {
+	return metrics.ListKey{Type: metrics.TypeGauge, Name: name}
+}
+
+func TestLimiter_Admit(t *testing.T) {
+	var nilLimiter *Limiter
+	assert.NoError(t, nilLimiter.Admit("agent-1", gauge("Alloc")))
+	nilLimiter.Forget(gauge("Alloc"))
+	assert.NoError(t, nilLimiter.Sync(metrics.NewMemStorage()))
+
+	l := New(3, 2)
+	tests := []struct {
+		name     string
+		clientID string
+		key      metrics.ListKey
+		wantErr  error
+	}{
+		{name: "first", clientID: "agent-1", key: gauge("Alloc")},
+		{name: "second", clientID: "agent-1", key: gauge("Frees")},
+		{name: "client_limit", clientID: "agent-1", key: gauge("HeapIdle"), wantErr: ErrorClientSeriesLimit},
+		{name: "existing_series", clientID: "agent-1", key: gauge("Alloc")},
+		{name: "existing_series_of_other_client", clientID: "agent-2", key: gauge("Frees")},
+		{name: "other_type_is_new_series", clientID: "agent-2", key: metrics.ListKey{Type: metrics.TypeCounter, Name: "Alloc"}},
+		{name: "global_limit", clientID: "agent-3", key: gauge("cpu"), wantErr: ErrorSeriesLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, l.Admit(tt.clientID, tt.key), tt.wantErr)
+		})
+	}
+
+	// Удалённый ряд освобождает место
+	l.Forget(gauge("Alloc"))
+	assert.NoError(t, l.Admit("agent-1", gauge("HeapIdle")))
+	stats := l.Stats(0)
+	assert.Equal(t, 3, stats.Series)
+	assert.Equal(t, uint64(2), stats.Rejected)
+}
+
+// admittingStorage хранилище, при чтении gauge которого пропускается новый ряд
+type admittingStorage struct {
+	metrics.IStorage
+	admit func()
+}
+
+// GetGauges пропускает ряд и читает gauge
+func (s *admittingStorage) GetGauges() (map[string]metrics.Gauge, error) {
+	s.admit()
+	return s.IStorage.GetGauges()
+}
+
+func TestLimiter_Sync(t *testing.T) {
+	storage := metrics.NewMemStorage()
+	require.NoError(t, storage.SetGauge("Alloc", 1))
+	require.NoError(t, storage.AddCounter("PollCount", 1))
+	// Собственная метрика сервера, сохранённая прежней версией, не занимает место
+	require.NoError(t, storage.AddCounter(RejectedMetricName("agent-1"), 3))
+
+	l := New(0, 0)
+	require.NoError(t, l.Admit("agent-1", gauge("Alloc")))
+	require.NoError(t, l.Admit("agent-1", gauge("Frees")))
+	// Ряд Frees пропущен раньше сверки, но так и не записан
+	l.series[gauge("Frees")] = owner{client: "agent-1", added: time.Now().Add(-time.Minute)}
+	// Ряд cpu пропускается, пока сверка читает хранилище, и ещё не записан
+	require.NoError(t, l.Sync(&admittingStorage{IStorage: storage, admit: func() {
+		require.NoError(t, l.Admit("agent-2", gauge("cpu")))
+	}}))
+
+	assert.Equal(t, map[metrics.ListKey]string{
+		gauge("Alloc"): "agent-1",
+		gauge("cpu"):   "agent-2",
+		{Type: metrics.TypeCounter, Name: "PollCount"}: "",
+	}, owners(l))
+	assert.Equal(t, map[string]int{"agent-1": 1, "agent-2": 1, "": 1}, l.perClient)
+}
+
+// owners владельцы учтённых рядов
+func owners(l *Limiter) map[metrics.ListKey]string {
+	result := make(map[metrics.ListKey]string, len(l.series))
+	for key, o := range l.series {
+		result[key] = o.client
+	}
+	return result
+}
+
+func TestLimiter_Stats(t *testing.T) {
+	var nilLimiter *Limiter
+	assert.Equal(t, Stats{Clients: []ClientStats{}}, nilLimiter.Stats(10))
+
+	l := New(10, 2)
+	for _, name := range []string{"a", "b", "c"} {
+		_ = l.Admit("agent-1", gauge(name))
+	}
+	require.NoError(t, l.Admit("agent-2", gauge("d")))
+	require.NoError(t, l.Admit("agent-3", gauge("e")))
+	require.NoError(t, l.Admit("agent-3", gauge("f")))
+	l.maxClientSeries = 0
+	require.NoError(t, l.Admit("agent-4", gauge("g")))
+
+	assert.Equal(t, Stats{
+		Series:          6,
+		MaxSeries:       10,
+		MaxClientSeries: 0,
+		Rejected:        1,
+		Clients: []ClientStats{
+			{Client: "agent-1", Series: 2, Rejected: 1},
+			{Client: "agent-3", Series: 2},
+			{Client: "agent-2", Series: 1},
+		},
+	}, l.Stats(3))
+	assert.Len(t, l.Stats(0).Clients, 4)
+}
+
+func TestLimiter_Rejected(t *testing.T) {
+	var nilLimiter *Limiter
+	assert.Empty(t, nilLimiter.Rejected())
+
+	l := New(1, 0)
+	require.NoError(t, l.Admit("agent-1", gauge("Alloc")))
+	for i := 0; i < MaxRejectedClients+2; i++ {
+		assert.ErrorIs(t, l.Admit("agent-"+strconv.Itoa(i), gauge("Frees")), ErrorSeriesLimit)
+	}
+	assert.ErrorIs(t, l.Admit("agent-1", gauge("Frees")), ErrorSeriesLimit)
+
+	// Клиенты сверх MaxRejectedClients учитываются вместе
+	rejected := l.Rejected()
+	assert.Len(t, rejected, MaxRejectedClients+1)
+	assert.Equal(t, uint64(2), rejected["agent-1"])
+	assert.Equal(t, uint64(2), rejected[OtherClients])
+	rejected["agent-1"] = 0
+	assert.Equal(t, uint64(2), l.Rejected()["agent-1"])
+}
+
+func TestIsSelfMetric(t *testing.T) {
+	assert.True(t, IsSelfMetric(RejectedMetricName("agent-1")))
+	assert.True(t, IsSelfMetric(RejectedMetric))
+	assert.False(t, IsSelfMetric("Alloc"))
+	assert.False(t, IsSelfMetric(RejectedMetric+"_total"))
+}
+
+func TestRejectedMetricName(t *testing.T) {
+	assert.Equal(t, `gmetrics_series_rejected{client="agent-1"}`, RejectedMetricName("agent-1"))
+	assert.Equal(t, `gmetrics_series_rejected{client="a\"b\\c"}`, RejectedMetricName(`a"b\c`))
+	assert.Equal(t, `gmetrics_series_rejected{client=""}`, RejectedMetricName(""))
+	base, labels := metrics.SplitLabels(RejectedMetricName(`a"b`))
+	assert.Equal(t, RejectedMetric, base)
+	assert.Equal(t, map[string]string{"client": `a"b`}, labels)
+}
@@ -1,59 +1,128 @@
 package config
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"os"
 )
 
-var ErrorEmptyKeyPath = errors.New("no key path specified")
+// MinKeyBits минимальный размер ключа RSA, который можно сгенерировать
+const MinKeyBits = 2048
 
-// ParsePublicKeyFromFile получаем ключ из указанного файла
+var (
+	ErrorEmptyKeyPath = errors.New("no key path specified")
+	// ErrorNoPEMBlock ошибка, что в файле не найден блок PEM
+	ErrorNoPEMBlock = errors.New("no PEM block found")
+	// ErrorUnsupportedKeyBlock ошибка, что тип блока PEM не подходит для ключа
+	ErrorUnsupportedKeyBlock = errors.New("unsupported PEM block type")
+	// ErrorNotRSAKey ошибка, что в файле ключ не RSA
+	ErrorNotRSAKey = errors.New("key is not an RSA key")
+	// ErrorKeyTooSmall ошибка, что запрошен слишком короткий ключ
+	ErrorKeyTooSmall = fmt.Errorf("key size must be at least %d bits", MinKeyBits)
+)
+
+// ParsePublicKeyFromFile получаем ключ из указанного файла.
+// Поддерживаются блоки "PUBLIC KEY" (PKIX) и "RSA PUBLIC KEY" (PKCS#1)
 func ParsePublicKeyFromFile(publicKeyPath string) (*rsa.PublicKey, error) {
-	block, err := getKeyFromFile(publicKeyPath, "PUBLIC KEY")
+	block, err := getKeyFromFile(publicKeyPath, "PUBLIC KEY", "RSA PUBLIC KEY")
 	if err != nil {
 		return nil, err
 	}
 
+	if block.Type == "RSA PUBLIC KEY" {
+		pub, pErr := x509.ParsePKCS1PublicKey(block.Bytes)
+		if pErr != nil {
+			return nil, fmt.Errorf("parse PKCS#1 public key from %s: %w", publicKeyPath, pErr)
+		}
+		return pub, nil
+	}
 	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parse PKIX public key from %s: %w", publicKeyPath, err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s contains %T", ErrorNotRSAKey, publicKeyPath, pub)
 	}
 
-	return pub.(*rsa.PublicKey), nil
+	return rsaPub, nil
 }
 
-// ParsePrivateKeyFromFile получаем ключ из указанного файла
-func ParsePrivateKeyFromFile(publicKeyPath string) (*rsa.PrivateKey, error) {
-	block, err := getKeyFromFile(publicKeyPath, "RSA PRIVATE KEY")
+// ParsePrivateKeyFromFile получаем ключ из указанного файла.
+// Поддерживаются блоки "PRIVATE KEY" (PKCS#8) и "RSA PRIVATE KEY" (PKCS#1, а также PKCS#8 для ключей, созданных ранее)
+func ParsePrivateKeyFromFile(privateKeyPath string) (*rsa.PrivateKey, error) {
+	block, err := getKeyFromFile(privateKeyPath, "PRIVATE KEY", "RSA PRIVATE KEY")
 	if err != nil {
 		return nil, err
 	}
-	pub, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if block.Type == "RSA PRIVATE KEY" {
+		if key, pErr := x509.ParsePKCS1PrivateKey(block.Bytes); pErr == nil {
+			return key, nil
+		}
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parse private key from %s: %w", privateKeyPath, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s contains %T", ErrorNotRSAKey, privateKeyPath, key)
 	}
 
-	return pub.(*rsa.PrivateKey), nil
+	return rsaKey, nil
 }
 
 // getKeyFromFile читает и декодирует ключ, закодированный в формате PEM, по заданному пути к файлу.
-// Функция ожидает в качестве аргументов указанный путь к файлу, содержащему блок PEM, и желаемый тип блока.
+// Функция ожидает в качестве аргументов указанный путь к файлу, содержащему блок PEM, и допустимые типы блока.
 // Он возвращает декодированный *pem.Block и ошибку, если она возникает во время чтения или декодирования содержимого файла.
-func getKeyFromFile(publicKeyPath string, blockType string) (*pem.Block, error) {
-	if publicKeyPath == "" {
+func getKeyFromFile(keyPath string, blockTypes ...string) (*pem.Block, error) {
+	if keyPath == "" {
 		return nil, ErrorEmptyKeyPath
 	}
 
-	rawKey, err := os.ReadFile(publicKeyPath)
+	rawKey, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, err
 	}
 	block, _ := pem.Decode(rawKey)
-	if block == nil || block.Type != blockType {
-		return nil, errors.New("failed to decode PEM block containing public key")
+	if block == nil {
+		return nil, fmt.Errorf("%w in %s", ErrorNoPEMBlock, keyPath)
+	}
+	for _, blockType := range blockTypes {
+		if block.Type == blockType {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q in %s, expected one of %q", ErrorUnsupportedKeyBlock, block.Type, keyPath, blockTypes)
+}
+
+// GenerateKey создаёт новый ключ RSA заданного размера
+func GenerateKey(bits int) (*rsa.PrivateKey, error) {
+	if bits < MinKeyBits {
+		return nil, ErrorKeyTooSmall
+	}
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// WritePrivateKeyToFile записывает приватный ключ в файл в формате PKCS#8 с блоком "PRIVATE KEY".
+// Файл доступен только владельцу
+func WritePrivateKeyToFile(privateKeyPath string, key *rsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(privateKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
+
+// WritePublicKeyToFile записывает публичный ключ в файл в формате PKIX с блоком "PUBLIC KEY"
+func WritePublicKeyToFile(publicKeyPath string, key *rsa.PublicKey) error {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return err
 	}
-	return block, nil
+	return os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
 }
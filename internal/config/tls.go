@@ -0,0 +1,89 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrorIncompleteKeyPair ошибка, что указан только сертификат или только ключ
+	ErrorIncompleteKeyPair = errors.New("both certificate and key must be specified")
+	// ErrorEmptyCertPool ошибка, что в файле не найдено ни одного сертификата
+	ErrorEmptyCertPool = errors.New("no certificates found")
+)
+
+// NewServerTLSConfig создаёт конфигурацию TLS для сервера.
+// Если не указаны сертификат и ключ, то возвращается nil, сервер будет работать без шифрования.
+// Если указан путь к сертификату удостоверяющего центра клиентов, то сервер будет требовать и проверять сертификат клиента (mTLS).
+func NewServerTLSConfig(certPath, keyPath, clientCAPath string) (*tls.Config, error) {
+	if certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+	if certPath == "" || keyPath == "" {
+		return nil, ErrorIncompleteKeyPair
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCAPath != "" {
+		pool, err := loadCertPool(clientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// NewClientTLSConfig создаёт конфигурацию TLS для клиента.
+// Если не указаны ни сертификат удостоверяющего центра, ни сертификат клиента, то возвращается nil.
+// caPath - сертификаты, которым доверяет клиент при проверке сервера, если не указан, используются системные.
+// certPath и keyPath - сертификат клиента для mTLS.
+func NewClientTLSConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
+	if caPath == "" && certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caPath != "" {
+		pool, err := loadCertPool(caPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certPath != "" || keyPath != "" {
+		if certPath == "" || keyPath == "" {
+			return nil, ErrorIncompleteKeyPair
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// loadCertPool читает сертификаты в формате PEM из файла и собирает из них пул
+func loadCertPool(path string) (*x509.CertPool, error) {
+	rawCerts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rawCerts) {
+		return nil, fmt.Errorf("%w in %s", ErrorEmptyCertPool, path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificates пути к сгенерированным для тестов сертификатам
+type testCertificates struct {
+	caCert     string
+	serverCert string
+	serverKey  string
+	clientCert string
+	clientKey  string
+}
+
+// generateTestCertificates создаёт удостоверяющий центр, сертификат сервера для localhost и сертификат агента
+func generateTestCertificates(t *testing.T) testCertificates {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gmetrics test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (string, string) {
+		key, kErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, kErr)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, cErr := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, cErr)
+		keyDER, mErr := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, mErr)
+		certPath := filepath.Join(dir, name+".crt")
+		keyPath := filepath.Join(dir, name+".key")
+		writePEM(t, certPath, "CERTIFICATE", der)
+		writePEM(t, keyPath, "PRIVATE KEY", keyDER)
+		return certPath, keyPath
+	}
+
+	certs := testCertificates{caCert: filepath.Join(dir, "ca.crt")}
+	writePEM(t, certs.caCert, "CERTIFICATE", caDER)
+	certs.serverCert, certs.serverKey = issue(2, "server", x509.ExtKeyUsageServerAuth)
+	certs.clientCert, certs.clientKey = issue(3, "agent-1", x509.ExtKeyUsageClientAuth)
+	return certs
+}
+
+// writePEM записывает блок PEM в файл
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	require.NoError(t, err)
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+	certs := generateTestCertificates(t)
+	tests := []struct {
+		name           string
+		certPath       string
+		keyPath        string
+		clientCAPath   string
+		wantNil        bool
+		wantErr        error
+		anyErr         bool
+		wantClientAuth tls.ClientAuthType
+	}{
+		{
+			name:    "tls_disabled",
+			wantNil: true,
+		},
+		{
+			name:     "only_cert",
+			certPath: certs.serverCert,
+			wantErr:  ErrorIncompleteKeyPair,
+		},
+		{
+			name:    "only_key",
+			keyPath: certs.serverKey,
+			wantErr: ErrorIncompleteKeyPair,
+		},
+		{
+			name:     "wrong_key",
+			certPath: certs.serverCert,
+			keyPath:  certs.clientKey,
+			anyErr:   true,
+		},
+		{
+			name:           "tls",
+			certPath:       certs.serverCert,
+			keyPath:        certs.serverKey,
+			wantClientAuth: tls.NoClientCert,
+		},
+		{
+			name:           "mtls",
+			certPath:       certs.serverCert,
+			keyPath:        certs.serverKey,
+			clientCAPath:   certs.caCert,
+			wantClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:         "client_ca_without_certificates",
+			certPath:     certs.serverCert,
+			keyPath:      certs.serverKey,
+			clientCAPath: certs.serverKey,
+			wantErr:      ErrorEmptyCertPool,
+		},
+		{
+			name:         "client_ca_not_exists",
+			certPath:     certs.serverCert,
+			keyPath:      certs.serverKey,
+			clientCAPath: "not_exists.crt",
+			wantErr:      os.ErrNotExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewServerTLSConfig(tt.certPath, tt.keyPath, tt.clientCAPath)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			if tt.anyErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Len(t, got.Certificates, 1)
+			assert.Equal(t, tt.wantClientAuth, got.ClientAuth)
+		})
+	}
+}
+
+func TestNewClientTLSConfig(t *testing.T) {
+	certs := generateTestCertificates(t)
+	tests := []struct {
+		name      string
+		caPath    string
+		certPath  string
+		keyPath   string
+		wantNil   bool
+		wantErr   error
+		wantCerts int
+	}{
+		{
+			name:    "tls_disabled",
+			wantNil: true,
+		},
+		{
+			name:   "only_ca",
+			caPath: certs.caCert,
+		},
+		{
+			name:      "mtls",
+			caPath:    certs.caCert,
+			certPath:  certs.clientCert,
+			keyPath:   certs.clientKey,
+			wantCerts: 1,
+		},
+		{
+			name:     "only_client_cert",
+			certPath: certs.clientCert,
+			wantErr:  ErrorIncompleteKeyPair,
+		},
+		{
+			name:    "wrong_ca",
+			caPath:  certs.clientKey,
+			wantErr: ErrorEmptyCertPool,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewClientTLSConfig(tt.caPath, tt.certPath, tt.keyPath)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Len(t, got.Certificates, tt.wantCerts)
+			if tt.caPath != "" {
+				assert.NotNil(t, got.RootCAs)
+			}
+		})
+	}
+}
+
+// TestMutualTLSHandshake проверяем, что сервер с mTLS принимает только агентов с сертификатом
+func TestMutualTLSHandshake(t *testing.T) {
+	certs := generateTestCertificates(t)
+	serverConfig, err := NewServerTLSConfig(certs.serverCert, certs.serverKey, certs.caCert)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = serverConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		certPath string
+		keyPath  string
+		wantErr  bool
+	}{
+		{
+			name:     "with_client_certificate",
+			certPath: certs.clientCert,
+			keyPath:  certs.clientKey,
+		},
+		{
+			name:    "without_client_certificate",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConfig, cErr := NewClientTLSConfig(certs.caCert, tt.certPath, tt.keyPath)
+			require.NoError(t, cErr)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+			res, rErr := client.Get(srv.URL)
+			if tt.wantErr {
+				assert.Error(t, rErr)
+				return
+			}
+			require.NoError(t, rErr)
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, "agent-1", string(body))
+		})
+	}
+}
@@ -4,3 +4,6 @@ package contextkeys
 type ContextKey string
 
 var SyncInterval ContextKey = "sync-interval"
+
+// ClientID идентификатор клиента (агента), полученный из его сертификата
+var ClientID ContextKey = "client-id"
@@ -3,8 +3,21 @@ package database
 import (
 	"database/sql"
 	"errors"
+	"strings"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// DriverPostgres драйвер бд Postgres
+	DriverPostgres = "pgx"
+	// DriverSQLite драйвер встроенной бд SQLite без cgo
+	DriverSQLite = "sqlite"
+	// SQLiteScheme схема DSN, которая указывает на файл бд SQLite, например, sqlite:///var/lib/gmetrics.db
+	SQLiteScheme = "sqlite://"
+	// sqlitePragmas настройки соединения с SQLite: ждать освобождения файла вместо ошибки и писать журнал WAL
+	sqlitePragmas = "_pragma=busy_timeout(5000)&_pragma=journal_mode(wal)"
 )
 
 // DB глобальный пул подключений к базе данных для приложения
@@ -15,6 +28,19 @@ var (
 	ErrorCantOpen = errors.New("can't open db")
 )
 
+// ParseDSN драйвер бд и DSN для него. DSN со схемой sqlite:// указывает на файл SQLite, остальные - на Postgres
+func ParseDSN(dsn string) (string, string) {
+	path, ok := strings.CutPrefix(dsn, SQLiteScheme)
+	if !ok {
+		return DriverPostgres, dsn
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return DriverSQLite, path + separator + sqlitePragmas
+}
+
 // NewDB создаёт новое подключение к базе данных
 func NewDB(driver string, dsn string) (*sql.DB, error) {
 	db, err := sql.Open(driver, dsn)
@@ -25,6 +51,13 @@ func NewDB(driver string, dsn string) (*sql.DB, error) {
 	if dsn == "" {
 		return db, nil
 	}
+	// SQLite пишет в файл только из одного соединения, поэтому пул из одного соединения избавляет от ошибок блокировки.
+	// Бд в памяти живёт, пока открыто соединение, поэтому оно не закрывается
+	if driver == DriverSQLite {
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxIdleTime(0)
+		db.SetConnMaxLifetime(0)
+	}
 	// Сразу проверим работоспособность соединения
 	if err = db.Ping(); err != nil {
 		return nil, errors.Join(ErrorCantPing, err)
@@ -61,7 +61,7 @@ func (d Decrypter) Middleware(next http.Handler) http.Handler {
 		// Читаем тело запроса
 		rawBody, err := io.ReadAll(r.Body)
 		if err != nil {
-			helpers.SetHTTPResponse(w, http.StatusBadRequest, []byte(err.Error()))
+			helpers.SetHTTPResponse(w, helpers.ReadBodyErrorStatus(err), []byte(err.Error()))
 			return
 		}
 		decryptBody, err := d.decrypt(rawBody)
@@ -13,11 +13,29 @@ import (
 type Reader struct {
 	originalReader io.ReadCloser
 	cReader        io.ReadCloser
+	limit          int64 // Максимальный размер разжатых данных, 0 - без ограничений
+	read           int64 // Сколько разжатых данных уже прочитано
 }
 
-// Read чтение разжатых данных
+// Read чтение разжатых данных.
+// Если разжатых данных больше лимита, то возвращает *http.MaxBytesError
 func (r *Reader) Read(p []byte) (n int, err error) {
-	return r.cReader.Read(p)
+	if r.limit <= 0 {
+		return r.cReader.Read(p)
+	}
+	if r.read > r.limit {
+		return 0, &http.MaxBytesError{Limit: r.limit}
+	}
+	// Читаем на байт больше лимита, чтобы отличить данные ровно по лимиту от превышения
+	if rest := r.limit - r.read + 1; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err = r.cReader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n - int(r.read-r.limit), &http.MaxBytesError{Limit: r.limit}
+	}
+	return n, err
 }
 
 // Close закрытие оригинального и разжимающего читателя
@@ -49,6 +67,12 @@ func (hw *HTTPWriter) Close() error {
 // Original представляет интерфейс io.ReadCloser, из которого будут считываться данные.
 // Возвращает экземпляр CompressReader и ошибку, если нет возможности создать gzip.Reader из originalReader.
 func NewGZIPReader(original io.ReadCloser) (*Reader, error) {
+	return NewLimitedGZIPReader(original, 0)
+}
+
+// NewLimitedGZIPReader возвращает CompressReader, который разжимает не больше limit байт, защищая от gzip бомб.
+// Если limit не больше нуля, то размер разжатых данных не ограничивается
+func NewLimitedGZIPReader(original io.ReadCloser, limit int64) (*Reader, error) {
 	reader, err := gzip.NewReader(original)
 	if err != nil {
 		return nil, err
@@ -56,6 +80,7 @@ func NewGZIPReader(original io.ReadCloser) (*Reader, error) {
 	return &Reader{
 		originalReader: original,
 		cReader:        reader,
+		limit:          limit,
 	}, nil
 }
 
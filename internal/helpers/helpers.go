@@ -1,7 +1,9 @@
 package helpers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"gmetrics/internal/logger"
 	"gmetrics/internal/payload"
 	"net/http"
@@ -33,3 +35,37 @@ func GetErrorJSONBody(message string) []byte {
 
 	return jsonResponse
 }
+
+// GetBackoffJSONBody Создание тела ответа с json ошибкой и просьбой к агенту подождать backoff секунд
+func GetBackoffJSONBody(message string, backoff int64) []byte {
+	responseBody := payload.ResponseBody{
+		Status:  payload.ResponseErrorStatus,
+		Message: message,
+		Backoff: backoff,
+	}
+	jsonResponse, err := json.Marshal(responseBody)
+	if err != nil {
+		logger.Log.Fatal(err)
+	}
+
+	return jsonResponse
+}
+
+// ReadBodyErrorStatus статус ответа для ошибки чтения тела запроса.
+// Если тело превысило допустимый размер, то 413, иначе 400
+func ReadBodyErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// StorageErrorStatus статус ответа для ошибки чтения хранилища.
+// Если хранилище не успело ответить за время запроса, то 504, иначе 500
+func StorageErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -1,6 +1,8 @@
 package helpers
 
 import (
+	"context"
+	"fmt"
 	"gmetrics/internal/logger"
 	"io"
 	"net/http"
@@ -93,3 +95,86 @@ func TestGetErrorJSONBody(t *testing.T) {
 		})
 	}
 }
+
+func TestReadBodyErrorStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{
+			name:     "body_too_large",
+			err:      &http.MaxBytesError{Limit: 10},
+			expected: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "wrapped_body_too_large",
+			err:      fmt.Errorf("read body: %w", &http.MaxBytesError{Limit: 10}),
+			expected: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "other_error",
+			err:      io.ErrUnexpectedEOF,
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, ReadBodyErrorStatus(c.err))
+		})
+	}
+}
+
+func TestStorageErrorStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{
+			name:     "deadline_exceeded",
+			err:      fmt.Errorf("query: %w", context.DeadlineExceeded),
+			expected: http.StatusGatewayTimeout,
+		},
+		{
+			name:     "other_error",
+			err:      io.ErrUnexpectedEOF,
+			expected: http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, StorageErrorStatus(c.err))
+		})
+	}
+}
+
+func TestGetBackoffJSONBody(t *testing.T) {
+	cases := []struct {
+		name         string
+		message      string
+		backoff      int64
+		expectedData string
+	}{
+		{
+			name:         "with_backoff",
+			message:      "too many requests",
+			backoff:      3,
+			expectedData: "{\"status\":\"error\",\"message\":\"too many requests\",\"backoff\":3}",
+		},
+		{
+			name:         "without_backoff",
+			message:      "too many requests",
+			expectedData: "{\"status\":\"error\",\"message\":\"too many requests\"}",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := GetBackoffJSONBody(c.message, c.backoff)
+			assert.Equal(t, c.expectedData, string(result))
+		})
+	}
+}
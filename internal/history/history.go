@@ -0,0 +1,120 @@
+// Package history Пакет хранит недавние значения метрик для графиков на странице метрик.
+// Значения снимаются с хранилища через равные промежутки времени, для каждой метрики хранится не больше size последних точек
+package history
+
+import (
+	"context"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/metrics"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSize количество точек, хранимых для каждой метрики
+	DefaultSize = 60
+	// DefaultInterval интервал снятия значений
+	DefaultInterval = 10 * time.Second
+)
+
+// Point значение метрики в момент времени
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Recorder недавние значения всех метрик
+type Recorder struct {
+	size   int
+	mu     sync.RWMutex
+	series map[metrics.ListKey][]Point
+}
+
+// Recent глобальная история значений, если nil, то история не ведётся
+var Recent *Recorder
+
+// New создаёт историю, в которой хранится не больше size точек для метрики.
+// Если size не больше нуля, то используется DefaultSize
+func New(size int) *Recorder {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Recorder{
+		size:   size,
+		series: make(map[metrics.ListKey][]Point),
+	}
+}
+
+// Record добавляет точку метрики, вытесняя самую старую, если точек больше size
+func (r *Recorder) Record(key metrics.ListKey, point Point) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unsafeRecord(key, point)
+}
+
+// unsafeRecord добавляет точку без блокировки
+func (r *Recorder) unsafeRecord(key metrics.ListKey, point Point) {
+	points := append(r.series[key], point)
+	if len(points) > r.size {
+		points = append(points[:0], points[len(points)-r.size:]...)
+	}
+	r.series[key] = points
+}
+
+// Points копия точек метрики от старых к новым. Безопасен для nil
+func (r *Recorder) Points(key metrics.ListKey) []Point {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Point(nil), r.series[key]...)
+}
+
+// Sample снимает значения всех метрик хранилища в момент at.
+// История метрик, которых больше нет в хранилище, удаляется
+func (r *Recorder) Sample(storage metrics.IStorage, at time.Time) error {
+	gauges, err := storage.GetGauges()
+	if err != nil {
+		return err
+	}
+	counters, err := storage.GetCounters()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[metrics.ListKey]struct{}, len(gauges)+len(counters))
+	for name, value := range gauges {
+		key := metrics.ListKey{Type: metrics.TypeGauge, Name: name}
+		seen[key] = struct{}{}
+		r.unsafeRecord(key, Point{Time: at, Value: value.GetRaw()})
+	}
+	for name, value := range counters {
+		key := metrics.ListKey{Type: metrics.TypeCounter, Name: name}
+		seen[key] = struct{}{}
+		r.unsafeRecord(key, Point{Time: at, Value: float64(value.GetRaw())})
+	}
+	for key := range r.series {
+		if _, ok := seen[key]; !ok {
+			delete(r.series, key)
+		}
+	}
+	return nil
+}
+
+// Run снимает значения хранилища каждые interval, пока контекст не завершён
+func (r *Recorder) Run(ctx context.Context, storage metrics.IStorage, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sample(storage, time.Now()); err != nil {
+			logger.Log.Error(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
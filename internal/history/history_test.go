@@ -0,0 +1,69 @@
+package history
+
+import (
+	"context"
+	"gmetrics/internal/metrics"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Record(t *testing.T) {
+	key := metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}
+	start := time.Now()
+	recorder := New(3)
+	for i := 0; i < 5; i++ {
+		recorder.Record(key, Point{Time: start.Add(time.Duration(i) * time.Second), Value: float64(i)})
+	}
+	// Хранятся только последние точки от старых к новым
+	points := recorder.Points(key)
+	require.Len(t, points, 3)
+	assert.Equal(t, []float64{2, 3, 4}, []float64{points[0].Value, points[1].Value, points[2].Value})
+
+	// Изменение копии не меняет историю
+	points[0].Value = 100
+	assert.Equal(t, float64(2), recorder.Points(key)[0].Value)
+
+	assert.Empty(t, recorder.Points(metrics.ListKey{Type: metrics.TypeCounter, Name: "Alloc"}))
+	var disabled *Recorder
+	assert.Nil(t, disabled.Points(key))
+	assert.Equal(t, DefaultSize, New(0).size)
+}
+
+func TestRecorder_Sample(t *testing.T) {
+	store := metrics.NewMemStorage()
+	require.NoError(t, store.SetGauge("Alloc", 1.5))
+	require.NoError(t, store.AddCounter("PollCount", 2))
+	recorder := New(DefaultSize)
+	at := time.Now()
+	require.NoError(t, recorder.Sample(store, at))
+
+	require.NoError(t, store.Delete(metrics.TypeGauge, "Alloc"))
+	require.NoError(t, store.AddCounter("PollCount", 3))
+	require.NoError(t, recorder.Sample(store, at.Add(time.Second)))
+
+	// Удалённая метрика пропадает из истории
+	assert.Empty(t, recorder.Points(metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"}))
+	assert.Equal(t, []Point{
+		{Time: at, Value: 2},
+		{Time: at.Add(time.Second), Value: 5},
+	}, recorder.Points(metrics.ListKey{Type: metrics.TypeCounter, Name: "PollCount"}))
+}
+
+func TestRecorder_Run(t *testing.T) {
+	store := metrics.NewMemStorage()
+	require.NoError(t, store.SetGauge("Alloc", 1))
+	recorder := New(DefaultSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- recorder.Run(ctx, store, 10*time.Millisecond)
+	}()
+	assert.Eventually(t, func() bool {
+		return len(recorder.Points(metrics.ListKey{Type: metrics.TypeGauge, Name: "Alloc"})) >= 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
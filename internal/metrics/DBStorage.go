@@ -6,6 +6,9 @@ import (
 	"errors"
 	"gmetrics/internal/contextkeys"
 	"gmetrics/internal/logger"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgerrcode"
@@ -15,6 +18,12 @@ import (
 // ErrorStorageDatabaseClosed ошибка, указывающая, что хранилище базы данных уже закрыто.
 var ErrorStorageDatabaseClosed = errors.New("DB storage is already closed")
 
+// listChunkSize сколько строк читать из бд за раз, если часть условий списка проверяется после выборки
+const listChunkSize = 500
+
+// likeEscaper экранирование спецсимволов шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // SQLExecutor интерфейс с нужными функциями из sql.DB
 type SQLExecutor interface {
 	ExecContext(ctx context.Context, query string, args ...any) (IResult, error)
@@ -35,10 +44,29 @@ type DBStorage struct {
 	syncMode bool
 	// close закрыто ли хранилище
 	close bool
+	// ttl время, после которого не обновлявшаяся метрика устаревает; 0 - не устаревают
+	ttl time.Duration
+	// dialect особенности SQL бд; nil - Postgres
+	dialect *sqlDialect
+	// queue очередь изменённых метрик для записи в бд в фоне; nil - запись в фоне выключена
+	queue *writeBehindQueue
+	// tenant тенант, метрики которого хранятся; пустой - общее пространство имён
+	tenant string
 }
 
 // NewDBStorage создание нового хранилища в базе данных
 func NewDBStorage(ctx context.Context, db SQLExecutor, restore bool, syncMode bool) (*DBStorage, error) {
+	return newDBStorage(ctx, db, postgresDialect, "", restore, syncMode)
+}
+
+// NewTenantDBStorage создание хранилища метрик тенанта в базе данных. Метрики других тенантов
+// в тех же таблицах хранилище не видит, не восстанавливает и не удаляет
+func NewTenantDBStorage(ctx context.Context, db SQLExecutor, tenant string, restore bool, syncMode bool) (*DBStorage, error) {
+	return newDBStorage(ctx, db, postgresDialect, tenant, restore, syncMode)
+}
+
+// newDBStorage создание нового хранилища метрик тенанта в бд с диалектом dialect
+func newDBStorage(ctx context.Context, db SQLExecutor, dialect *sqlDialect, tenant string, restore bool, syncMode bool) (*DBStorage, error) {
 	storage := NewMemStorage()
 	dbStorage := &DBStorage{
 		IStorage: storage,
@@ -46,15 +74,17 @@ func NewDBStorage(ctx context.Context, db SQLExecutor, restore bool, syncMode bo
 		db:       db,
 		syncMode: syncMode,
 		close:    false,
+		dialect:  dialect,
+		tenant:   tenant,
 	}
 	if restore {
 		// Восстанавливаем хранилище из файла, возвращаем ошибку, если чтение вернуло ошибку не с типом несуществующего файла или пустого файла
-		if err := dbStorage.restore(); err != nil {
+		if err := dbStorage.restore(ctx); err != nil {
 			logger.Log.Infow("Restore store failed", "error", err)
 			return dbStorage, err
 		}
 	} else {
-		if err := dbStorage.clean(); err != nil {
+		if err := dbStorage.clean(ctx); err != nil {
 			logger.Log.Infow("Clean store failed", "error", err)
 			return dbStorage, err
 		}
@@ -62,29 +92,41 @@ func NewDBStorage(ctx context.Context, db SQLExecutor, restore bool, syncMode bo
 	return dbStorage, nil
 }
 
+// inner хранилище в памяти, в котором хранятся метрики
+func (storage *DBStorage) inner() IStorage {
+	return storage.IStorage
+}
+
 // SetGauge устанавливаем gauge
 func (storage *DBStorage) SetGauge(name string, value Gauge) error {
-	err := storage.IStorage.SetGauge(name, value)
-	if err != nil {
-		return err
+	return storage.SetGaugeContext(storage.storeCtx, name, value)
+}
+
+// SetGaugeContext устанавливаем gauge, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) SetGaugeContext(ctx context.Context, name string, value Gauge) error {
+	if storage.queue != nil {
+		return storage.enqueue(ctx, []string{name}, nil, func() error {
+			return storage.IStorage.SetGauge(name, value)
+		})
 	}
+	// В синхронном режиме память обновляется только после записи в бд, чтобы при ошибке бд не расходиться с ней
 	if storage.syncMode {
-		if err = storage.syncGauge(name, value); err != nil {
+		if err := storage.syncGauge(ctx, name, value); err != nil {
 			return err
 		}
 	}
-	return nil
+	return storage.IStorage.SetGauge(name, value)
 }
 
 // syncGauge синхронизируем gauge в базу с повторными попытками сохранения
-func (storage *DBStorage) syncGauge(name string, value Gauge) (err error) {
+func (storage *DBStorage) syncGauge(ctx context.Context, name string, value Gauge) (err error) {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
 	pause := time.Second
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		err = storage.setGauge(name, value)
+		err = storage.setGauge(ctx, name, value)
 		if err == nil {
 			break
 		}
@@ -93,41 +135,52 @@ func (storage *DBStorage) syncGauge(name string, value Gauge) (err error) {
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
 	return err
 }
 
 // setGauge записываем Gauge в бд
-func (storage *DBStorage) setGauge(name string, value Gauge) error {
-	_, err := storage.db.ExecContext(storage.storeCtx, "INSERT INTO t_gauge (name, value) VALUES ($1, $2) on conflict (name) do update set value = $2, updated_at = $3", name, value, time.Now())
+func (storage *DBStorage) setGauge(ctx context.Context, name string, value Gauge) error {
+	_, err := storage.db.ExecContext(ctx, storage.upsertSQL("t_gauge", "value", "$2"), name, value, storage.timeArg(time.Now()), storage.tenant)
 	return err
 }
 
 // AddCounter добавляем каунтер
 func (storage *DBStorage) AddCounter(name string, value Counter) error {
-	err := storage.IStorage.AddCounter(name, value)
-	if err != nil {
-		return err
+	return storage.AddCounterContext(storage.storeCtx, name, value)
+}
+
+// AddCounterContext добавляем каунтер, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) AddCounterContext(ctx context.Context, name string, value Counter) error {
+	if storage.queue != nil {
+		return storage.enqueue(ctx, nil, []string{name}, func() error {
+			return storage.IStorage.AddCounter(name, value)
+		})
 	}
+	// В синхронном режиме память обновляется только после записи в бд: иначе при ошибке бд приращение
+	// осталось бы в памяти, а повтор запроса агентом прибавил бы его ещё раз
 	if storage.syncMode {
-		if err = storage.syncCounter(name, value); err != nil {
+		if err := storage.syncCounter(ctx, name, value); err != nil {
 			return err
 		}
 	}
-	return nil
+	return storage.IStorage.AddCounter(name, value)
 }
 
 // syncCounter синхронизируем Counter в базу с повторными попытками сохранения
-func (storage *DBStorage) syncCounter(name string, value Counter) (err error) {
+func (storage *DBStorage) syncCounter(ctx context.Context, name string, value Counter) (err error) {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
 	pause := time.Second
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		err = storage.addCounter(name, value)
+		err = storage.addCounter(ctx, name, value)
 		if err == nil {
 			break
 		}
@@ -136,38 +189,45 @@ func (storage *DBStorage) syncCounter(name string, value Counter) (err error) {
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
 	return err
 }
 
 // addCounter сохраняем Counter в бд
-func (storage *DBStorage) addCounter(name string, value Counter) error {
-	_, err := storage.db.ExecContext(storage.storeCtx, "INSERT INTO t_counter (name, value) VALUES ($1, $2) on conflict (name) do update set value = t_counter.value + $2, updated_at = $3", name, value, time.Now())
+func (storage *DBStorage) addCounter(ctx context.Context, name string, value Counter) error {
+	_, err := storage.db.ExecContext(ctx, storage.upsertSQL("t_counter", "value", "t_counter.value + $2"), name, value, storage.timeArg(time.Now()), storage.tenant)
 	return err
 }
 
 // GetGauge получение отдельного gauge
-func (storage *DBStorage) GetGauge(name string) (g Gauge, ok bool) {
+func (storage *DBStorage) GetGauge(name string) (Gauge, bool) {
+	g, ok, _ := storage.GetGaugeContext(storage.storeCtx, name)
+	return g, ok
+}
+
+// GetGaugeContext получение отдельного gauge. Ошибка, если значение не удалось прочитать из бд
+func (storage *DBStorage) GetGaugeContext(ctx context.Context, name string) (g Gauge, ok bool, err error) {
 	// Ищем в памяти значение
 	g, ok = storage.IStorage.GetGauge(name)
 	if ok {
-		return g, ok
+		return g, ok, nil
 	}
 	// Если нет в памяти данных, то идём в базу данных
 	pause := time.Second
-	var err error
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		g, err = storage.getGauge(name)
+		g, err = storage.getGauge(ctx, name)
 		if err == nil {
 			ok = true
 			break
 		}
 		if errors.Is(err, sql.ErrNoRows) {
-			ok = false
-			break
+			return 0, false, nil
 		}
 		logger.Log.Error(err)
 		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
@@ -175,19 +235,27 @@ func (storage *DBStorage) GetGauge(name string) (g Gauge, ok bool) {
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
-	return g, ok
+	return g, ok, err
 }
 
 // getGauge Получение значения Gauge из бд
-func (storage *DBStorage) getGauge(name string) (Gauge, error) {
+func (storage *DBStorage) getGauge(ctx context.Context, name string) (Gauge, error) {
 	var value Gauge
 	if storage.close {
 		return value, ErrorStorageDatabaseClosed
 	}
-	row := storage.db.QueryRowContext(storage.storeCtx, "SELECT value FROM t_gauge WHERE name = $1", name)
+	var row IRow
+	if storage.ttl > 0 {
+		row = storage.db.QueryRowContext(ctx, "SELECT value FROM t_gauge WHERE "+storage.tenantWhere(3)+" AND name = $1 AND updated_at > $2", name, storage.timeArg(storage.expiredBefore()), storage.tenant)
+	} else {
+		row = storage.db.QueryRowContext(ctx, "SELECT value FROM t_gauge WHERE "+storage.tenantWhere(2)+" AND name = $1", name, storage.tenant)
+	}
 	if err := row.Scan(&value); err != nil {
 		return value, err
 	}
@@ -196,25 +264,29 @@ func (storage *DBStorage) getGauge(name string) (Gauge, error) {
 }
 
 // GetCounter получение отдельного counter
-func (storage *DBStorage) GetCounter(name string) (c Counter, ok bool) {
+func (storage *DBStorage) GetCounter(name string) (Counter, bool) {
+	c, ok, _ := storage.GetCounterContext(storage.storeCtx, name)
+	return c, ok
+}
+
+// GetCounterContext получение отдельного counter. Ошибка, если значение не удалось прочитать из бд
+func (storage *DBStorage) GetCounterContext(ctx context.Context, name string) (c Counter, ok bool, err error) {
 	// Ищем в памяти значение
 	c, ok = storage.IStorage.GetCounter(name)
 	if ok {
-		return c, ok
+		return c, ok, nil
 	}
 	// Если нет в памяти данных, то идём в базу данных
 	pause := time.Second
-	var err error
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		c, err = storage.getCounter(name)
+		c, err = storage.getCounter(ctx, name)
 		if err == nil {
 			ok = true
 			break
 		}
 		if errors.Is(err, sql.ErrNoRows) {
-			ok = false
-			break
+			return 0, false, nil
 		}
 		logger.Log.Error(err)
 		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
@@ -222,19 +294,27 @@ func (storage *DBStorage) GetCounter(name string) (c Counter, ok bool) {
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
-	return c, ok
+	return c, ok, err
 }
 
 // getCounter получаем Counter из бд
-func (storage *DBStorage) getCounter(name string) (Counter, error) {
+func (storage *DBStorage) getCounter(ctx context.Context, name string) (Counter, error) {
 	var value Counter
 	if storage.close {
 		return value, ErrorStorageDatabaseClosed
 	}
-	row := storage.db.QueryRowContext(storage.storeCtx, "SELECT value FROM t_counter WHERE name = $1", name)
+	var row IRow
+	if storage.ttl > 0 {
+		row = storage.db.QueryRowContext(ctx, "SELECT value FROM t_counter WHERE "+storage.tenantWhere(3)+" AND name = $1 AND updated_at > $2", name, storage.timeArg(storage.expiredBefore()), storage.tenant)
+	} else {
+		row = storage.db.QueryRowContext(ctx, "SELECT value FROM t_counter WHERE "+storage.tenantWhere(2)+" AND name = $1", name, storage.tenant)
+	}
 	if err := row.Scan(&value); err != nil {
 		return value, err
 	}
@@ -243,7 +323,12 @@ func (storage *DBStorage) getCounter(name string) (Counter, error) {
 }
 
 // GetGauges получение всех gauge
-func (storage *DBStorage) GetGauges() (gauges map[string]Gauge, err error) {
+func (storage *DBStorage) GetGauges() (map[string]Gauge, error) {
+	return storage.GetGaugesContext(storage.storeCtx)
+}
+
+// GetGaugesContext получение всех gauge, чтение из бд прерывается при отмене контекста
+func (storage *DBStorage) GetGaugesContext(ctx context.Context) (gauges map[string]Gauge, err error) {
 	// Ищем в памяти значение
 	gauges, err = storage.IStorage.GetGauges()
 	if err != nil {
@@ -253,7 +338,7 @@ func (storage *DBStorage) GetGauges() (gauges map[string]Gauge, err error) {
 	pause := time.Second
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		gauges, err = storage.getGauges()
+		gauges, err = storage.getGauges(ctx)
 		if err == nil {
 			break
 		}
@@ -262,7 +347,10 @@ func (storage *DBStorage) GetGauges() (gauges map[string]Gauge, err error) {
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
 	if err != nil {
@@ -272,12 +360,20 @@ func (storage *DBStorage) GetGauges() (gauges map[string]Gauge, err error) {
 }
 
 // getGauges получение всех Gauge из БД
-func (storage *DBStorage) getGauges() (map[string]Gauge, error) {
+func (storage *DBStorage) getGauges(ctx context.Context) (map[string]Gauge, error) {
 	gauges := make(map[string]Gauge)
 	if storage.close {
 		return gauges, ErrorStorageDatabaseClosed
 	}
-	rows, err := storage.db.QueryContext(storage.storeCtx, "SELECT name, value FROM t_gauge")
+	var (
+		rows IRows
+		err  error
+	)
+	if storage.ttl > 0 {
+		rows, err = storage.db.QueryContext(ctx, "SELECT name, value FROM t_gauge WHERE "+storage.tenantWhere(2)+" AND updated_at > $1", storage.timeArg(storage.expiredBefore()), storage.tenant)
+	} else {
+		rows, err = storage.db.QueryContext(ctx, "SELECT name, value FROM t_gauge WHERE "+storage.tenantWhere(1), storage.tenant)
+	}
 	if err != nil {
 		return gauges, err
 	}
@@ -307,7 +403,12 @@ func (storage *DBStorage) getGauges() (map[string]Gauge, error) {
 }
 
 // GetCounters получение всех counter
-func (storage *DBStorage) GetCounters() (counters map[string]Counter, err error) {
+func (storage *DBStorage) GetCounters() (map[string]Counter, error) {
+	return storage.GetCountersContext(storage.storeCtx)
+}
+
+// GetCountersContext получение всех counter, чтение из бд прерывается при отмене контекста
+func (storage *DBStorage) GetCountersContext(ctx context.Context) (counters map[string]Counter, err error) {
 	// Ищем в памяти значение
 	counters, err = storage.IStorage.GetCounters()
 	if err != nil {
@@ -317,7 +418,7 @@ func (storage *DBStorage) GetCounters() (counters map[string]Counter, err error)
 	pause := time.Second
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		counters, err = storage.getCounters()
+		counters, err = storage.getCounters(ctx)
 		if err == nil {
 			break
 		}
@@ -326,19 +427,30 @@ func (storage *DBStorage) GetCounters() (counters map[string]Counter, err error)
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
 	return counters, err
 }
 
 // getCounters получение всех Counter из бд
-func (storage *DBStorage) getCounters() (map[string]Counter, error) {
+func (storage *DBStorage) getCounters(ctx context.Context) (map[string]Counter, error) {
 	counters := make(map[string]Counter)
 	if storage.close {
 		return counters, ErrorStorageDatabaseClosed
 	}
-	rows, err := storage.db.QueryContext(storage.storeCtx, "SELECT name, value FROM t_counter")
+	var (
+		rows IRows
+		err  error
+	)
+	if storage.ttl > 0 {
+		rows, err = storage.db.QueryContext(ctx, "SELECT name, value FROM t_counter WHERE "+storage.tenantWhere(2)+" AND updated_at > $1", storage.timeArg(storage.expiredBefore()), storage.tenant)
+	} else {
+		rows, err = storage.db.QueryContext(ctx, "SELECT name, value FROM t_counter WHERE "+storage.tenantWhere(1), storage.tenant)
+	}
 	if err != nil {
 		return counters, err
 	}
@@ -368,24 +480,53 @@ func (storage *DBStorage) getCounters() (map[string]Counter, error) {
 }
 
 // SetGauges массовое обновление метрик Гауге
-func (storage *DBStorage) SetGauges(gauges map[string]Gauge) (err error) {
-	err = storage.IStorage.SetGauges(gauges)
-	if err != nil || !storage.syncMode {
-		return err
+func (storage *DBStorage) SetGauges(gauges map[string]Gauge) error {
+	return storage.SetGaugesContext(storage.storeCtx, gauges)
+}
+
+// SetGaugesContext массовое обновление метрик Гауге, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) SetGaugesContext(ctx context.Context, gauges map[string]Gauge) (err error) {
+	if storage.queue != nil {
+		return storage.enqueue(ctx, mapNames(gauges), nil, func() error {
+			return storage.IStorage.SetGauges(gauges)
+		})
 	}
-	// Записываем в базу данных
-	return storage.syncGauges(gauges)
+	// Записываем в базу данных, а затем в память
+	if storage.syncMode {
+		if err = storage.syncGauges(ctx, gauges); err != nil {
+			return err
+		}
+	}
+	return storage.IStorage.SetGauges(gauges)
+}
+
+// SetGaugesReturningOld массовое обновление метрик Гауге с прежними значениями существовавших gauge.
+// Прежние значения берутся из памяти хранилища под блокировкой записи, без запросов к бд
+func (storage *DBStorage) SetGaugesReturningOld(ctx context.Context, gauges map[string]Gauge) (old map[string]Gauge, err error) {
+	if storage.queue != nil {
+		err = storage.enqueue(ctx, mapNames(gauges), nil, func() (aErr error) {
+			old, aErr = SetGaugesReturningOld(ctx, storage.IStorage, gauges)
+			return aErr
+		})
+		return old, err
+	}
+	if storage.syncMode {
+		if err = storage.syncGauges(ctx, gauges); err != nil {
+			return nil, err
+		}
+	}
+	return SetGaugesReturningOld(ctx, storage.IStorage, gauges)
 }
 
 // syncGauges запись в бд нескольких Gauge с ретраями
-func (storage *DBStorage) syncGauges(gauges map[string]Gauge) (err error) {
+func (storage *DBStorage) syncGauges(ctx context.Context, gauges map[string]Gauge) (err error) {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
 	pause := time.Second
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		err = storage.setGauges(gauges)
+		err = storage.setGauges(ctx, gauges)
 		if err == nil {
 			break
 		}
@@ -394,16 +535,29 @@ func (storage *DBStorage) syncGauges(gauges map[string]Gauge) (err error) {
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
 	return err
 }
 
-// setGauges массовое обновление гауге в базе
-func (storage *DBStorage) setGauges(gauges map[string]Gauge) error {
+// setGauges массовое обновление гауге в базе. Большие пачки загружаются командой COPY, если бд это умеет
+func (storage *DBStorage) setGauges(ctx context.Context, gauges map[string]Gauge) error {
 	nowTime := time.Now()
-	tx, err := storage.db.BeginTx(storage.storeCtx, nil)
+	if len(gauges) >= copyMinBatch {
+		if err := copyUpsertValues(ctx, storage, copyGauges, gauges, nowTime); !isCopyUnsupported(err) {
+			return err
+		}
+	}
+	return storage.setGaugesPrepared(ctx, gauges, nowTime)
+}
+
+// setGaugesPrepared обновление гауге в базе подготовленным запросом по одной метрике
+func (storage *DBStorage) setGaugesPrepared(ctx context.Context, gauges map[string]Gauge, nowTime time.Time) error {
+	tx, err := storage.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -412,7 +566,7 @@ func (storage *DBStorage) setGauges(gauges map[string]Gauge) error {
 			logger.Log.Error(tErr)
 		}
 	}()
-	prepared, err := tx.PrepareContext(storage.storeCtx, "INSERT INTO t_gauge (name, value) VALUES ($1, $2) on conflict (name) do update set value = $2, updated_at = $3")
+	prepared, err := tx.PrepareContext(ctx, storage.upsertSQL("t_gauge", "value", "$2"))
 	if err != nil {
 		return err
 	}
@@ -423,7 +577,7 @@ func (storage *DBStorage) setGauges(gauges map[string]Gauge) error {
 	}()
 
 	for name, gauge := range gauges {
-		if _, err = prepared.Exec(name, gauge, nowTime); err != nil {
+		if _, err = prepared.Exec(name, gauge, storage.timeArg(nowTime), storage.tenant); err != nil {
 			return err
 		}
 	}
@@ -431,24 +585,53 @@ func (storage *DBStorage) setGauges(gauges map[string]Gauge) error {
 }
 
 // AddCounters массовое обновление метрик Каунтер
-func (storage *DBStorage) AddCounters(counters map[string]Counter) (err error) {
-	err = storage.IStorage.AddCounters(counters)
-	if err != nil || !storage.syncMode {
-		return err
+func (storage *DBStorage) AddCounters(counters map[string]Counter) error {
+	return storage.AddCountersContext(storage.storeCtx, counters)
+}
+
+// AddCountersContext массовое обновление метрик Каунтер, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) AddCountersContext(ctx context.Context, counters map[string]Counter) (err error) {
+	if storage.queue != nil {
+		return storage.enqueue(ctx, nil, mapNames(counters), func() error {
+			return storage.IStorage.AddCounters(counters)
+		})
+	}
+	// Записываем в базу данных, а затем в память
+	if storage.syncMode {
+		if err = storage.syncCounters(ctx, counters, false); err != nil {
+			return err
+		}
 	}
-	// Записываем в базу данных
-	return storage.syncCounters(counters, false)
+	return storage.IStorage.AddCounters(counters)
+}
+
+// AddCountersReturningNew массовое обновление метрик Каунтер со значениями до и после прибавления.
+// Значения берутся из памяти хранилища под блокировкой записи, без запросов к бд
+func (storage *DBStorage) AddCountersReturningNew(ctx context.Context, counters map[string]Counter) (old, updated map[string]Counter, err error) {
+	if storage.queue != nil {
+		err = storage.enqueue(ctx, nil, mapNames(counters), func() (aErr error) {
+			old, updated, aErr = AddCountersReturningNew(ctx, storage.IStorage, counters)
+			return aErr
+		})
+		return old, updated, err
+	}
+	if storage.syncMode {
+		if err = storage.syncCounters(ctx, counters, false); err != nil {
+			return nil, nil, err
+		}
+	}
+	return AddCountersReturningNew(ctx, storage.IStorage, counters)
 }
 
 // syncCounters запись в бд нескольких Counter с ретраями
-func (storage *DBStorage) syncCounters(counters map[string]Counter, clearAndSet bool) (err error) {
+func (storage *DBStorage) syncCounters(ctx context.Context, counters map[string]Counter, clearAndSet bool) (err error) {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
 	pause := time.Second
 	var pgErr *pgconn.PgError
 	for i := 0; i < 3; i++ {
-		err = storage.addCounters(counters, clearAndSet)
+		err = storage.addCounters(ctx, counters, clearAndSet)
 		if err == nil {
 			break
 		}
@@ -457,16 +640,33 @@ func (storage *DBStorage) syncCounters(counters map[string]Counter, clearAndSet
 			break
 		}
 
-		<-time.After(pause)
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
 		pause += 2 * time.Second
 	}
 	return err
 }
 
-// addCounters массовое обновление каунтер в базе
-func (storage *DBStorage) addCounters(counters map[string]Counter, clearAndSet bool) error {
+// addCounters массовое обновление каунтер в базе. Большие пачки загружаются командой COPY, если бд это умеет
+func (storage *DBStorage) addCounters(ctx context.Context, counters map[string]Counter, clearAndSet bool) error {
 	nowTime := time.Now()
-	tx, err := storage.db.BeginTx(storage.storeCtx, nil)
+	if len(counters) >= copyMinBatch {
+		upsert := copyCounters
+		if clearAndSet {
+			upsert = copySetCounters
+		}
+		if err := copyUpsertValues(ctx, storage, upsert, counters, nowTime); !isCopyUnsupported(err) {
+			return err
+		}
+	}
+	return storage.addCountersPrepared(ctx, counters, clearAndSet, nowTime)
+}
+
+// addCountersPrepared обновление каунтер в базе подготовленным запросом по одной метрике
+func (storage *DBStorage) addCountersPrepared(ctx context.Context, counters map[string]Counter, clearAndSet bool, nowTime time.Time) error {
+	tx, err := storage.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -475,11 +675,11 @@ func (storage *DBStorage) addCounters(counters map[string]Counter, clearAndSet b
 			logger.Log.Error(tErr)
 		}
 	}()
-	queryString := "INSERT INTO t_counter (name, value) VALUES ($1, $2) on conflict (name) do update set value = t_counter.value + $2, updated_at = $3"
+	queryString := storage.upsertSQL("t_counter", "value", "t_counter.value + $2")
 	if clearAndSet {
-		queryString = "INSERT INTO t_counter (name, value) VALUES ($1, $2) on conflict (name) do update set value = $2, updated_at = $3"
+		queryString = storage.upsertSQL("t_counter", "value", "$2")
 	}
-	prepared, err := tx.PrepareContext(storage.storeCtx, queryString)
+	prepared, err := tx.PrepareContext(ctx, queryString)
 	if err != nil {
 		return err
 	}
@@ -490,19 +690,650 @@ func (storage *DBStorage) addCounters(counters map[string]Counter, clearAndSet b
 	}()
 
 	for name, counter := range counters {
-		if _, err = prepared.Exec(name, counter, nowTime); err != nil {
+		if _, err = prepared.Exec(name, counter, storage.timeArg(nowTime), storage.tenant); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// metricTypes типы метрик в порядке записи в бд
+var metricTypes = []string{TypeGauge, TypeCounter, TypeHistogram, TypeSummary, TypeSet}
+
+// metricTables таблицы метрик в бд по типу метрики
+var metricTables = map[string]string{
+	TypeGauge:     "t_gauge",
+	TypeCounter:   "t_counter",
+	TypeHistogram: "t_histogram",
+	TypeSummary:   "t_summary",
+	TypeSet:       "t_set",
+}
+
+// Delete удаление метрики. Из бд метрика удаляется сразу, независимо от режима,
+// так как при синхронизации в бд записываются только метрики из памяти
+func (storage *DBStorage) Delete(metricType, name string) error {
+	return storage.DeleteContext(storage.storeCtx, metricType, name)
+}
+
+// DeleteContext удаление метрики, запрос к бд прерывается при отмене контекста
+func (storage *DBStorage) DeleteContext(ctx context.Context, metricType, name string) error {
+	_, err := storage.deleteMetric(ctx, metricType, name, func() (any, error) {
+		return nil, storage.IStorage.Delete(metricType, name)
+	})
+	return err
+}
+
+// DeleteReturningOld удаление метрики, возвращает удалённое из памяти значение.
+// Если метрика была только в бд, то значение не возвращается
+func (storage *DBStorage) DeleteReturningOld(ctx context.Context, metricType, name string) (any, error) {
+	return storage.deleteMetric(ctx, metricType, name, func() (any, error) {
+		return DeleteReturningOld(ctx, storage.IStorage, metricType, name)
+	})
+}
+
+// deleteMetric удаление метрики из памяти функцией deleteMem и из бд
+func (storage *DBStorage) deleteMetric(ctx context.Context, metricType, name string, deleteMem func() (any, error)) (any, error) {
+	table, ok := metricTables[metricType]
+	if !ok {
+		return nil, ErrorUnknownMetricType
+	}
+	defer storage.lockQueue()()
+	old, memErr := deleteMem()
+	if memErr != nil && !errors.Is(memErr, ErrorMetricNotFound) {
+		return nil, memErr
+	}
+	deleted, err := storage.syncExec(ctx, "DELETE FROM "+table+" WHERE "+storage.tenantWhere(2)+" AND name = $1", name, storage.tenant)
+	if err != nil {
+		return nil, err
+	}
+	if memErr != nil && deleted == 0 {
+		return nil, memErr
+	}
+	return old, nil
+}
+
+// ResetCounter обнуление counter. В бд значение обнуляется сразу, независимо от режима
+func (storage *DBStorage) ResetCounter(name string) error {
+	return storage.ResetCounterContext(storage.storeCtx, name)
+}
+
+// ResetCounterContext обнуление counter, запрос к бд прерывается при отмене контекста
+func (storage *DBStorage) ResetCounterContext(ctx context.Context, name string) error {
+	_, err := storage.resetCounter(ctx, name, func() (Counter, error) {
+		return 0, storage.IStorage.ResetCounter(name)
+	})
+	return err
+}
+
+// ResetCounterReturningOld обнуление counter, возвращает значение до обнуления из памяти.
+// Если counter был только в бд, то возвращается 0
+func (storage *DBStorage) ResetCounterReturningOld(ctx context.Context, name string) (Counter, error) {
+	return storage.resetCounter(ctx, name, func() (Counter, error) {
+		return ResetCounterReturningOld(ctx, storage.IStorage, name)
+	})
+}
+
+// resetCounter обнуление counter в памяти функцией resetMem и в бд
+func (storage *DBStorage) resetCounter(ctx context.Context, name string, resetMem func() (Counter, error)) (Counter, error) {
+	defer storage.lockQueue()()
+	old, memErr := resetMem()
+	if memErr != nil && !errors.Is(memErr, ErrorMetricNotFound) {
+		return 0, memErr
+	}
+	updated, err := storage.syncExec(ctx, "UPDATE t_counter SET value = 0, updated_at = $2 WHERE "+storage.tenantWhere(3)+" AND name = $1", name, storage.timeArg(time.Now()), storage.tenant)
+	if err != nil {
+		return 0, err
+	}
+	if memErr != nil && updated == 0 {
+		return 0, memErr
+	}
+	return old, nil
+}
+
+// SetTTL устанавливает время устаревания метрик.
+// Метрики, восстановленные из бд при создании хранилища, считаются обновлёнными в момент восстановления
+func (storage *DBStorage) SetTTL(ttl time.Duration) {
+	storage.ttl = ttl
+	if st, ok := storage.IStorage.(IExpiringStorage); ok {
+		st.SetTTL(ttl)
+	}
+}
+
+// DeleteExpired удаляет устаревшие метрики из памяти и из бд, возвращает количество удалённых из бд
+func (storage *DBStorage) DeleteExpired() (int, error) {
+	return storage.DeleteExpiredContext(storage.storeCtx)
+}
+
+// DeleteExpiredContext удаляет устаревшие метрики, запросы к бд прерываются при отмене контекста
+func (storage *DBStorage) DeleteExpiredContext(ctx context.Context) (int, error) {
+	defer storage.lockQueue()()
+	if st, ok := storage.IStorage.(IExpiringStorage); ok {
+		if _, err := st.DeleteExpired(); err != nil {
+			return 0, err
+		}
+	}
+	if storage.ttl <= 0 {
+		return 0, nil
+	}
+	before := storage.timeArg(storage.expiredBefore())
+	gauges, err := storage.syncExec(ctx, "DELETE FROM t_gauge WHERE "+storage.tenantWhere(2)+" AND updated_at <= $1", before, storage.tenant)
+	if err != nil {
+		return 0, err
+	}
+	counters, err := storage.syncExec(ctx, "DELETE FROM t_counter WHERE "+storage.tenantWhere(2)+" AND updated_at <= $1", before, storage.tenant)
+	if err != nil {
+		return int(gauges), err
+	}
+	deleted := gauges + counters
+	for _, table := range []string{"t_histogram", "t_summary", "t_set"} {
+		distributions, dErr := storage.syncExec(ctx, "DELETE FROM "+table+" WHERE "+storage.tenantWhere(2)+" AND updated_at <= $1", before, storage.tenant)
+		if dErr != nil {
+			return int(deleted), dErr
+		}
+		deleted += distributions
+	}
+	return int(deleted), nil
+}
+
+// GetGaugesByNames значения gauge с указанными именами. Метрики, которых нет в памяти,
+// выбираются из бд одним запросом
+func (storage *DBStorage) GetGaugesByNames(names []string) (map[string]Gauge, error) {
+	return storage.GetGaugesByNamesContext(storage.storeCtx, names)
+}
+
+// GetGaugesByNamesContext значения gauge с указанными именами, чтение из бд прерывается при отмене контекста
+func (storage *DBStorage) GetGaugesByNamesContext(ctx context.Context, names []string) (map[string]Gauge, error) {
+	gauges, err := GetGaugesByNames(storage.IStorage, names)
+	if err != nil {
+		return gauges, err
+	}
+	misses := missingNames(names, gauges)
+	if len(misses) == 0 {
+		return gauges, nil
+	}
+	found, err := retryQueryValues[Gauge](ctx, storage, "t_gauge", misses)
+	for name, value := range found {
+		gauges[name] = value
+	}
+	return gauges, err
+}
+
+// GetCountersByNames значения counter с указанными именами. Метрики, которых нет в памяти,
+// выбираются из бд одним запросом
+func (storage *DBStorage) GetCountersByNames(names []string) (map[string]Counter, error) {
+	return storage.GetCountersByNamesContext(storage.storeCtx, names)
+}
+
+// GetCountersByNamesContext значения counter с указанными именами, чтение из бд прерывается при отмене контекста
+func (storage *DBStorage) GetCountersByNamesContext(ctx context.Context, names []string) (map[string]Counter, error) {
+	counters, err := GetCountersByNames(storage.IStorage, names)
+	if err != nil {
+		return counters, err
+	}
+	misses := missingNames(names, counters)
+	if len(misses) == 0 {
+		return counters, nil
+	}
+	found, err := retryQueryValues[Counter](ctx, storage, "t_counter", misses)
+	for name, value := range found {
+		counters[name] = value
+	}
+	return counters, err
+}
+
+// retryQueryValues выбор значений метрик с указанными именами из таблицы с повторными попытками
+func retryQueryValues[V Gauge | Counter](ctx context.Context, storage *DBStorage, table string, names []string) (values map[string]V, err error) {
+	pause := time.Second
+	var pgErr *pgconn.PgError
+	for i := 0; i < 3; i++ {
+		values, err = queryValues[V](ctx, storage, table, names)
+		if err == nil {
+			break
+		}
+		logger.Log.Error(err)
+		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
+			break
+		}
+
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
+		pause += 2 * time.Second
+	}
+	return values, err
+}
+
+// queryValues выбор значений метрик с указанными именами из таблицы одним запросом
+func queryValues[V Gauge | Counter](ctx context.Context, storage *DBStorage, table string, names []string) (map[string]V, error) {
+	values := make(map[string]V, len(names))
+	if storage.close {
+		return values, ErrorStorageDatabaseClosed
+	}
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	query := "SELECT name, value FROM " + table + " WHERE tenant = " + arg(storage.tenant) + " AND " + storage.sqlDialect().inNames(arg, names)
+	if storage.ttl > 0 {
+		query += " AND updated_at > " + arg(storage.timeArg(storage.expiredBefore()))
+	}
+	rows, err := storage.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return values, err
+	}
+	// Закроем строки, чтобы освободить соединение
+	defer func() {
+		if rErr := rows.Close(); rErr != nil {
+			logger.Log.Error(rErr)
+		}
+	}()
+	var (
+		name  string
+		value V
+	)
+	for rows.Next() {
+		if err = rows.Scan(&name, &value); err != nil {
+			return values, err
+		}
+		values[name] = value
+	}
+	return values, rows.Err()
+}
+
+// mapNames имена метрик из набора значений
+func mapNames[V any](values map[string]V) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	return names
+}
+
+// missingNames имена без повторов, которых нет среди найденных
+func missingNames[V any](names []string, found map[string]V) []string {
+	misses := make([]string, 0)
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := found[name]; ok {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		misses = append(misses, name)
+	}
+	return misses
+}
+
+// List список метрик. В синхронном режиме метрики выбираются из бд запросами по индексу имени,
+// иначе из памяти, так как в бд ещё может не быть последних значений
+func (storage *DBStorage) List(query ListQuery) ([]ListedMetric, error) {
+	return storage.ListContext(storage.storeCtx, query)
+}
+
+// ListContext список метрик, чтение из бд прерывается при отмене контекста
+func (storage *DBStorage) ListContext(ctx context.Context, query ListQuery) (list []ListedMetric, err error) {
+	if !storage.syncMode {
+		st, ok := storage.IStorage.(IListingStorage)
+		if !ok {
+			return nil, ErrorListNotSupported
+		}
+		return st.List(query)
+	}
+	pause := time.Second
+	var pgErr *pgconn.PgError
+	for i := 0; i < 3; i++ {
+		list, err = storage.list(ctx, query)
+		if err == nil {
+			break
+		}
+		logger.Log.Error(err)
+		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
+			break
+		}
+
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
+		pause += 2 * time.Second
+	}
+	return list, err
+}
+
+// list выборка страницы метрик из бд. Шаблон, регулярное выражение и метки проверяются после выборки,
+// поэтому в этом случае строки читаются порциями, пока страница не заполнится
+func (storage *DBStorage) list(ctx context.Context, query ListQuery) ([]ListedMetric, error) {
+	list := make([]ListedMetric, 0)
+	if storage.close {
+		return list, ErrorStorageDatabaseClosed
+	}
+	chunk := query.Limit
+	if query.Filter.hasNameConditions() {
+		chunk = max(chunk, listChunkSize)
+	}
+	after := query.After
+	for {
+		sqlQuery, args := storage.listSQL(query, after, chunk)
+		if sqlQuery == "" {
+			return list, nil
+		}
+		fetched, err := storage.queryList(ctx, sqlQuery, args...)
+		if err != nil {
+			return list, err
+		}
+		for _, metric := range fetched {
+			if !query.Filter.MatchName(metric.Name) {
+				continue
+			}
+			list = append(list, metric)
+			if query.Limit > 0 && len(list) == query.Limit {
+				return list, nil
+			}
+		}
+		if chunk <= 0 || len(fetched) < chunk {
+			return list, nil
+		}
+		last := fetched[len(fetched)-1].Key()
+		after = &last
+	}
+}
+
+// listSQL запрос страницы метрик размером limit после ключа after. Имена сравниваются побайтово,
+// чтобы порядок совпадал с порядком в памяти и использовался индекс по имени. Пустой запрос, если тип не подходит ни одной таблице
+func (storage *DBStorage) listSQL(query ListQuery, after *ListKey, limit int) (string, []any) {
+	dialect := storage.sqlDialect()
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	// Один параметр тенанта на все объединяемые таблицы
+	tenant := "tenant = " + arg(storage.tenant)
+	var where []string
+	if storage.ttl > 0 {
+		where = append(where, "updated_at > "+arg(storage.timeArg(storage.expiredBefore())))
+	}
+	if prefix := query.Filter.namePrefix(); prefix != "" {
+		where = append(where, dialect.hasPrefix(arg, prefix))
+	}
+	if after != nil {
+		cmp := ">"
+		if query.Desc {
+			cmp = "<"
+		}
+		name, metricType := arg(after.Name), arg(after.Type)
+		if query.Sort == ListSortType {
+			where = append(where, "(type "+cmp+" "+metricType+" OR (type = "+metricType+" AND name"+dialect.collate+" "+cmp+" "+name+"))")
+		} else {
+			where = append(where, "name"+dialect.collate+" "+cmp+"= "+name+" AND (name"+dialect.collate+" "+cmp+" "+name+" OR type "+cmp+" "+metricType+")")
+		}
+	}
+	// Скетчи множеств двоичные, поэтому читаются отдельной колонкой sketch
+	var tables []string
+	if query.Filter.MatchType(TypeGauge) {
+		tables = append(tables, "SELECT 'gauge' AS type, name, value AS gauge, "+dialect.nullCounter+" AS counter, "+
+			dialect.nullData+" AS data, "+dialect.nullSketch+" AS sketch, updated_at FROM t_gauge WHERE "+tenant)
+	}
+	if query.Filter.MatchType(TypeCounter) {
+		tables = append(tables, "SELECT 'counter' AS type, name, "+dialect.nullGauge+" AS gauge, value AS counter, "+
+			dialect.nullData+" AS data, "+dialect.nullSketch+" AS sketch, updated_at FROM t_counter WHERE "+tenant)
+	}
+	for _, metricType := range []string{TypeHistogram, TypeSummary} {
+		if query.Filter.MatchType(metricType) {
+			tables = append(tables, "SELECT '"+metricType+"' AS type, name, "+dialect.nullGauge+" AS gauge, "+dialect.nullCounter+" AS counter, "+
+				"data, "+dialect.nullSketch+" AS sketch, updated_at FROM "+metricTables[metricType]+" WHERE "+tenant)
+		}
+	}
+	if query.Filter.MatchType(TypeSet) {
+		tables = append(tables, "SELECT 'set' AS type, name, "+dialect.nullGauge+" AS gauge, "+dialect.nullCounter+" AS counter, "+
+			dialect.nullData+" AS data, data AS sketch, updated_at FROM t_set WHERE "+tenant)
+	}
+	if len(tables) == 0 {
+		return "", nil
+	}
+	sqlQuery := "SELECT type, name, gauge, counter, data, sketch, updated_at FROM (" + strings.Join(tables, " UNION ALL ") + ") m"
+	if len(where) > 0 {
+		sqlQuery += " WHERE " + strings.Join(where, " AND ")
+	}
+	direction := " ASC"
+	if query.Desc {
+		direction = " DESC"
+	}
+	if query.Sort == ListSortType {
+		sqlQuery += " ORDER BY type" + direction + ", name" + dialect.collate + direction
+	} else {
+		sqlQuery += " ORDER BY name" + dialect.collate + direction + ", type" + direction
+	}
+	if limit > 0 {
+		sqlQuery += " LIMIT " + arg(limit)
+	}
+	return sqlQuery, args
+}
+
+// queryList выполнение запроса списка метрик
+func (storage *DBStorage) queryList(ctx context.Context, query string, args ...any) ([]ListedMetric, error) {
+	list := make([]ListedMetric, 0)
+	rows, err := storage.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return list, err
+	}
+	// Закроем строки, чтобы освободить соединение
+	defer func() {
+		if rErr := rows.Close(); rErr != nil {
+			logger.Log.Error(rErr)
+		}
+	}()
+	for rows.Next() {
+		var (
+			metric  ListedMetric
+			data    sql.NullString
+			sketch  []byte
+			updated sql.NullTime
+		)
+		if err = rows.Scan(&metric.Type, &metric.Name, &metric.Gauge, &metric.Counter, &data, &sketch, &updated); err != nil {
+			return list, err
+		}
+		metric.UpdatedAt = updated.Time
+		raw := []byte(data.String)
+		if metric.Type == TypeSet {
+			raw = sketch
+		}
+		if err = decodeListedData(&metric, raw); err != nil {
+			logger.Log.Infow("Skip broken distribution", "type", metric.Type, "name", metric.Name, "error", err)
+			continue
+		}
+		list = append(list, metric)
+	}
+	return list, rows.Err()
+}
+
+// Import загрузка метрик в память и в бд. В бд метрики записываются сразу, независимо от режима,
+// вместе со временем обновления. Если replace, то остальные метрики удаляются из памяти и из бд
+func (storage *DBStorage) Import(list []ListedMetric, replace bool) error {
+	return storage.ImportContext(storage.storeCtx, list, replace)
+}
+
+// ImportContext загрузка метрик, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) ImportContext(ctx context.Context, list []ListedMetric, replace bool) error {
+	if err := checkImported(list); err != nil {
+		return err
+	}
+	defer storage.lockQueue()()
+	if err := Import(storage.IStorage, list, replace); err != nil {
+		return err
+	}
+	if storage.close {
+		return ErrorStorageDatabaseClosed
+	}
+	pause := time.Second
+	var pgErr *pgconn.PgError
+	var err error
+	for i := 0; i < 3; i++ {
+		err = storage.importMetrics(ctx, list, replace)
+		if err == nil {
+			break
+		}
+		logger.Log.Error(err)
+		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
+			break
+		}
+
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
+		pause += 2 * time.Second
+	}
+	return err
+}
+
+// importMetrics запись загружаемых метрик в бд одной транзакцией
+func (storage *DBStorage) importMetrics(ctx context.Context, list []ListedMetric, replace bool) error {
+	nowTime := time.Now()
+	tx, err := storage.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tErr := tx.Rollback(); tErr != nil && tErr.Error() != "sql: transaction has already been committed or rolled back" {
+			logger.Log.Error(tErr)
+		}
+	}()
+	if replace {
+		for _, metricType := range metricTypes {
+			if _, err = tx.ExecContext(ctx, storage.clearSQL(metricTables[metricType]), storage.tenant); err != nil {
+				return err
+			}
+		}
+	}
+	for _, metricType := range metricTypes {
+		if err = storage.importType(ctx, tx, metricType, list, nowTime); err != nil {
 			return err
 		}
 	}
 	return tx.Commit()
 }
 
+// importType запись загружаемых метрик одного типа в транзакции. Гистограммы, сводки и множества записываются в колонку data
+func (storage *DBStorage) importType(ctx context.Context, tx ITX, metricType string, list []ListedMetric, now time.Time) error {
+	if !slices.ContainsFunc(list, func(metric ListedMetric) bool { return metric.Type == metricType }) {
+		return nil
+	}
+	column := "value"
+	if _, ok := distributionTables[metricType]; ok {
+		column = "data"
+	}
+	prepared, err := tx.PrepareContext(ctx, storage.upsertSQL(metricTables[metricType], column, "$2"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if pErr := prepared.Close(); pErr != nil {
+			logger.Log.Error(pErr)
+		}
+	}()
+	for _, metric := range list {
+		if metric.Type != metricType {
+			continue
+		}
+		value, vErr := importedValue(metric)
+		if vErr != nil {
+			return vErr
+		}
+		if _, err = prepared.Exec(metric.Name, value, storage.timeArg(importedAt(metric, now)), storage.tenant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlDialect диалект SQL бд хранилища
+func (storage *DBStorage) sqlDialect() *sqlDialect {
+	if storage.dialect == nil {
+		return postgresDialect
+	}
+	return storage.dialect
+}
+
+// tenantWhere условие на тенанта хранилища с параметром номер n. Тенант всегда передаётся параметром
+// со значением storage.tenant, а не подставляется в текст запроса
+func (storage *DBStorage) tenantWhere(n int) string {
+	return "tenant = $" + strconv.Itoa(n)
+}
+
+// upsertSQL запрос записи метрики тенанта с параметрами имени, значения, времени обновления и тенанта.
+// При конфликте колонке column присваивается выражение set
+func (storage *DBStorage) upsertSQL(table, column, set string) string {
+	return "INSERT INTO " + table + " (tenant, name, " + column + ", updated_at) VALUES ($4, $1, $2, $3)" +
+		" on conflict (tenant, name) do update set " + column + " = " + set + ", updated_at = $3"
+}
+
+// clearSQL запрос удаления всех метрик тенанта из таблицы с параметром тенанта.
+// Таблица не очищается целиком, так как в ней метрики других тенантов
+func (storage *DBStorage) clearSQL(table string) string {
+	return "DELETE FROM " + table + " WHERE " + storage.tenantWhere(1)
+}
+
+// timeArg значение параметра запроса для времени в диалекте бд хранилища
+func (storage *DBStorage) timeArg(t time.Time) any {
+	return storage.sqlDialect().time(t)
+}
+
+// expiredBefore время, обновлённые раньше которого метрики устарели
+func (storage *DBStorage) expiredBefore() time.Time {
+	return time.Now().Add(-storage.ttl)
+}
+
+// syncExec выполнение запроса в бд с повторными попытками, возвращает количество затронутых строк
+func (storage *DBStorage) syncExec(ctx context.Context, query string, args ...any) (affected int64, err error) {
+	if storage.close {
+		return 0, ErrorStorageDatabaseClosed
+	}
+	pause := time.Second
+	var pgErr *pgconn.PgError
+	for i := 0; i < 3; i++ {
+		var res IResult
+		res, err = storage.db.ExecContext(ctx, query, args...)
+		if err == nil {
+			return res.RowsAffected()
+		}
+		logger.Log.Error(err)
+		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
+			break
+		}
+
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
+		pause += 2 * time.Second
+	}
+	return 0, err
+}
+
+// waitRetry пауза перед повторной попыткой запроса. Возвращает ошибку контекста, если он отменён раньше,
+// чтобы не повторять запрос, результат которого уже никому не нужен
+func waitRetry(ctx context.Context, pause time.Duration) error {
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // restore восстанавливаем данные из базы данных
-func (storage *DBStorage) restore() error {
+func (storage *DBStorage) restore(ctx context.Context) error {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
-	gauges, err := storage.GetGauges()
+	gauges, err := storage.GetGaugesContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -510,7 +1341,7 @@ func (storage *DBStorage) restore() error {
 	if err != nil {
 		return err
 	}
-	counters, err := storage.GetCounters()
+	counters, err := storage.GetCountersContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -518,15 +1349,21 @@ func (storage *DBStorage) restore() error {
 	if err != nil {
 		return err
 	}
-	return nil
+	if err = storage.restoreDistributions(ctx); err != nil {
+		return err
+	}
+	if err = storage.restoreSets(ctx); err != nil {
+		return err
+	}
+	return storage.restoreMetadata(ctx)
 }
 
 // clean удаляем данные из базы данных перед стартом без восстановления данных
-func (storage *DBStorage) clean() error {
+func (storage *DBStorage) clean(ctx context.Context) error {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
-	tx, err := storage.db.BeginTx(storage.storeCtx, nil)
+	tx, err := storage.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -535,39 +1372,54 @@ func (storage *DBStorage) clean() error {
 			logger.Log.Error(tErr)
 		}
 	}()
-	_, err = tx.ExecContext(storage.storeCtx, "TRUNCATE TABLE t_gauge")
+	_, err = tx.ExecContext(ctx, storage.clearSQL("t_gauge"), storage.tenant)
 	if err != nil {
 		return err
 	}
-	_, err = tx.ExecContext(storage.storeCtx, "TRUNCATE TABLE t_counter")
+	_, err = tx.ExecContext(ctx, storage.clearSQL("t_counter"), storage.tenant)
 	if err != nil {
 		return err
 	}
+	for _, table := range []string{"t_histogram", "t_summary", "t_set", "t_metadata"} {
+		if _, err = tx.ExecContext(ctx, storage.clearSQL(table), storage.tenant); err != nil {
+			return err
+		}
+	}
 
 	return tx.Commit()
 }
 
-// Flush записываем данные в базу данных перед закрытием
+// Flush записываем данные в базу данных перед закрытием. При записи в фоне записываются только изменённые метрики
 func (storage *DBStorage) Flush() error {
 	if storage.close {
 		return ErrorStorageDatabaseClosed
 	}
+	if storage.queue != nil {
+		// Хранилище закрывается после отмены контекста сервера, а несохранённые изменения нужно записать
+		return storage.flushQueue(context.WithoutCancel(storage.storeCtx))
+	}
 	gauges, err := storage.IStorage.GetGauges()
 	if err != nil {
 		return err
 	}
-	if err = storage.syncGauges(gauges); err != nil {
+	if err = storage.syncGauges(storage.storeCtx, gauges); err != nil {
 		return err
 	}
 	counters, err := storage.IStorage.GetCounters()
 	if err != nil {
 		return err
 	}
-	if err = storage.syncCounters(counters, true); err != nil {
+	if err = storage.syncCounters(storage.storeCtx, counters, true); err != nil {
 		return err
 	}
 
-	return nil
+	if err = storage.flushDistributions(storage.storeCtx); err != nil {
+		return err
+	}
+	if err = storage.flushSets(storage.storeCtx); err != nil {
+		return err
+	}
+	return storage.flushMetadata(storage.storeCtx)
 }
 
 // Sync синхронизация данных хранилища в базу данных по таймеру
@@ -164,8 +164,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -180,8 +184,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, errorBeginTX).AnyTimes()
 				return executor
@@ -196,8 +204,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -212,8 +224,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), errorGauge).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), errorGauge).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -228,8 +244,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), errorCounter).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), errorCounter).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -244,8 +264,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -260,8 +284,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -276,8 +304,12 @@ func TestDBStorage_clean(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -293,7 +325,7 @@ func TestDBStorage_clean(t *testing.T) {
 				close:    tc.close,
 			}
 
-			err := dbStorage.clean()
+			err := dbStorage.clean(context.Background())
 			if tc.expectErr != nil {
 				assert.ErrorIs(t, err, tc.expectErr)
 			} else {
@@ -325,7 +357,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -343,7 +375,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -361,7 +393,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -379,7 +411,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -397,7 +429,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -415,7 +447,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -433,7 +465,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -451,7 +483,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -469,7 +501,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -487,7 +519,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -505,7 +537,7 @@ func TestDBStorage_setGauges(t *testing.T) {
 				storeCtx: context.Background(),
 				db:       tc.getExecutor(t),
 			}
-			err := dbStorage.setGauges(tc.gauges)
+			err := dbStorage.setGauges(context.Background(), tc.gauges)
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -538,7 +570,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -556,7 +588,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -574,7 +606,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -593,7 +625,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -611,7 +643,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -629,7 +661,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -647,7 +679,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -665,7 +697,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -683,7 +715,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -701,7 +733,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -719,7 +751,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -737,7 +769,7 @@ func TestDBStorage_addCounters(t *testing.T) {
 				storeCtx: context.Background(),
 				db:       tc.getExecutor(t),
 			}
-			err := dbStorage.addCounters(tc.counters, tc.clearAndSet)
+			err := dbStorage.addCounters(context.Background(), tc.counters, tc.clearAndSet)
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -771,7 +803,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -789,7 +821,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -807,7 +839,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -826,7 +858,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -844,7 +876,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -862,7 +894,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -880,7 +912,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -898,7 +930,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -916,7 +948,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -934,7 +966,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -952,7 +984,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -970,7 +1002,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -988,7 +1020,7 @@ func TestDBStorage_syncCounters(t *testing.T) {
 				storeCtx: context.Background(),
 				db:       tc.getExecutor(t),
 			}
-			err := dbStorage.syncCounters(tc.counters, tc.clearAndSet)
+			err := dbStorage.syncCounters(context.Background(), tc.counters, tc.clearAndSet)
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -33,7 +33,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -51,7 +51,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -69,7 +69,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -87,7 +87,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -105,7 +105,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -123,7 +123,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -141,7 +141,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -159,7 +159,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -177,7 +177,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -195,7 +195,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -213,7 +213,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -231,7 +231,7 @@ func TestDBStorage_syncGauges(t *testing.T) {
 				storeCtx: context.Background(),
 				db:       tc.getExecutor(t),
 			}
-			err := dbStorage.syncGauges(tc.gauges)
+			err := dbStorage.syncGauges(context.Background(), tc.gauges)
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -265,7 +265,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -283,7 +283,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -301,7 +301,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -320,7 +320,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -339,7 +339,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -358,7 +358,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -377,7 +377,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -396,7 +396,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -415,7 +415,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -434,7 +434,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -453,7 +453,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -472,7 +472,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -491,7 +491,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -510,7 +510,7 @@ func TestDBStorage_SetGauges(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -564,7 +564,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -583,7 +583,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -602,7 +602,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -621,7 +621,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -640,7 +640,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -659,7 +659,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -678,7 +678,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -697,7 +697,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -716,7 +716,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -735,7 +735,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -754,7 +754,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -773,7 +773,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -792,7 +792,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -811,7 +811,7 @@ func TestDBStorage_AddCounters(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -865,7 +865,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -890,7 +890,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -914,7 +914,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -938,7 +938,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -962,7 +962,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -986,7 +986,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1010,7 +1010,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1034,7 +1034,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1058,7 +1058,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1082,7 +1082,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -1106,7 +1106,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -1130,7 +1130,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1154,7 +1154,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1178,7 +1178,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -1202,7 +1202,7 @@ func TestDBStorage_Flush(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
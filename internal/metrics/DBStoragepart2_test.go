@@ -39,7 +39,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -65,7 +65,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -90,7 +90,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -115,7 +115,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -140,7 +140,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -164,7 +164,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(errorCommit).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -188,7 +188,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -212,7 +212,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(errorPrepareClose).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -237,7 +237,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPrepareExec).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPrepareExec).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -261,7 +261,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollback).AnyTimes()
@@ -286,7 +286,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(errorRollbackOK).AnyTimes()
@@ -311,7 +311,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -335,7 +335,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -360,7 +360,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -384,7 +384,7 @@ func TestDBStorage_FlushAndClose(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -439,7 +439,7 @@ func TestDBStorage_Sync(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -464,7 +464,7 @@ func TestDBStorage_Sync(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -491,7 +491,7 @@ func TestDBStorage_Sync(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -516,7 +516,7 @@ func TestDBStorage_Sync(t *testing.T) {
 				ctrl := gomock.NewController(t)
 				prepared := NewMockIStmt(ctrl)
 				prepared.EXPECT().Close().Return(nil).AnyTimes()
-				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
@@ -587,7 +587,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: map[string]Gauge{"test": Gauge(123)},
@@ -626,7 +626,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 					}
 				}).After(first)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: map[string]Gauge{"test": Gauge(123), "test1": Gauge(124)},
@@ -637,7 +637,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorQueryContext).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorQueryContext).AnyTimes()
 				return executor
 			},
 			expectedGauges: make(map[string]Gauge),
@@ -651,7 +651,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 				rows.EXPECT().Close().Return(nil).AnyTimes()
 				rows.EXPECT().Err().Return(errorRowsErr).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: make(map[string]Gauge),
@@ -666,7 +666,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 				rows.EXPECT().Err().Return(nil).AnyTimes()
 				rows.EXPECT().Next().Return(false).Times(1)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: make(map[string]Gauge),
@@ -693,7 +693,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 					}
 				}).Times(1)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: make(map[string]Gauge),
@@ -731,7 +731,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 					}
 				}).After(first)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: map[string]Gauge{"test": Gauge(123), "test1": Gauge(124)},
@@ -745,7 +745,7 @@ func TestDBStorage_getGauges(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			res, err := dbStorage.getGauges()
+			res, err := dbStorage.getGauges(context.Background())
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -774,7 +774,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: map[string]Counter{"test": Counter(123)},
@@ -813,7 +813,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 					}
 				}).After(first)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: map[string]Counter{"test": Counter(123), "test1": Counter(124)},
@@ -824,7 +824,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorQueryContext).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorQueryContext).AnyTimes()
 				return executor
 			},
 			expectedCounters: make(map[string]Counter),
@@ -838,7 +838,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 				rows.EXPECT().Close().Return(nil).AnyTimes()
 				rows.EXPECT().Err().Return(errorRowsErr).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: make(map[string]Counter),
@@ -853,7 +853,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 				rows.EXPECT().Err().Return(nil).AnyTimes()
 				rows.EXPECT().Next().Return(false).Times(1)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: make(map[string]Counter),
@@ -880,7 +880,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 					}
 				}).Times(1)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: make(map[string]Counter),
@@ -918,7 +918,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 					}
 				}).After(first)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: map[string]Counter{"test": Counter(123), "test1": Counter(124)},
@@ -932,7 +932,7 @@ func TestDBStorage_getCounters(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			res, err := dbStorage.getCounters()
+			res, err := dbStorage.getCounters(context.Background())
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -961,7 +961,7 @@ func TestDBStorage_GetGauges(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: map[string]Gauge{"test": Gauge(123)},
@@ -1006,7 +1006,7 @@ func TestDBStorage_GetGauges(t *testing.T) {
 					}
 				}).After(first)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedGauges: map[string]Gauge{"test": Gauge(123), "test1": Gauge(124)},
@@ -1023,8 +1023,8 @@ func TestDBStorage_GetGauges(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				first := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorQueryContext).After(first)
+				first := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorQueryContext).After(first)
 				return executor
 			},
 			expectedGauges: make(map[string]Gauge),
@@ -1041,7 +1041,7 @@ func TestDBStorage_GetGauges(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			expectedGauges: make(map[string]Gauge),
@@ -1058,7 +1058,7 @@ func TestDBStorage_GetGauges(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			expectedGauges: map[string]Gauge{"test1": 9},
@@ -1107,7 +1107,7 @@ func TestDBStorage_GetCounters(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: map[string]Counter{"test": Counter(123)},
@@ -1152,7 +1152,7 @@ func TestDBStorage_GetCounters(t *testing.T) {
 					}
 				}).After(first)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).AnyTimes()
 				return executor
 			},
 			expectedCounters: map[string]Counter{"test": Counter(123), "test1": Counter(124)},
@@ -1169,8 +1169,8 @@ func TestDBStorage_GetCounters(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				first := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorQueryContext).After(first)
+				first := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorQueryContext).After(first)
 				return executor
 			},
 			expectedCounters: make(map[string]Counter),
@@ -1187,7 +1187,7 @@ func TestDBStorage_GetCounters(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			expectedCounters: make(map[string]Counter),
@@ -1204,7 +1204,7 @@ func TestDBStorage_GetCounters(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			expectedCounters: map[string]Counter{"test1": 9},
@@ -1252,7 +1252,7 @@ func TestDBStorage_restore(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			close: true,
@@ -1296,7 +1296,7 @@ func TestDBStorage_restore(t *testing.T) {
 						}
 					}
 				}).After(firstG)
-				fe := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rowsG, nil).Times(1)
+				fe := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rowsG, nil).Times(1)
 
 				rows := NewMockIRows(ctrl)
 				rows.EXPECT().Close().Return(nil).AnyTimes()
@@ -1325,7 +1325,7 @@ func TestDBStorage_restore(t *testing.T) {
 						}
 					}
 				}).After(first)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).After(fe)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).After(fe)
 				return executor
 			},
 			getStorage: func(t *testing.T) IStorage {
@@ -1344,7 +1344,7 @@ func TestDBStorage_restore(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			getStorage: func(t *testing.T) IStorage {
@@ -1387,7 +1387,7 @@ func TestDBStorage_restore(t *testing.T) {
 						}
 					}
 				}).After(firstG)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rowsG, nil).Times(1)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rowsG, nil).Times(1)
 				return executor
 			},
 			getStorage: func(t *testing.T) IStorage {
@@ -1433,7 +1433,7 @@ func TestDBStorage_restore(t *testing.T) {
 						}
 					}
 				}).After(firstG)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rowsG, nil).Times(1)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rowsG, nil).Times(1)
 				return executor
 			},
 			getStorage: func(t *testing.T) IStorage {
@@ -1479,7 +1479,7 @@ func TestDBStorage_restore(t *testing.T) {
 						}
 					}
 				}).After(firstG)
-				fe := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rowsG, nil).Times(1)
+				fe := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rowsG, nil).Times(1)
 
 				rows := NewMockIRows(ctrl)
 				rows.EXPECT().Close().Return(nil).AnyTimes()
@@ -1508,7 +1508,7 @@ func TestDBStorage_restore(t *testing.T) {
 						}
 					}
 				}).After(first)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).After(fe)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).After(fe)
 				return executor
 			},
 			getStorage: func(t *testing.T) IStorage {
@@ -1530,7 +1530,7 @@ func TestDBStorage_restore(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			err := dbStorage.restore()
+			err := dbStorage.restore(context.Background())
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -1556,7 +1556,7 @@ func TestDBStorage_getCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedCounter: Counter(0),
@@ -1614,7 +1614,7 @@ func TestDBStorage_getCounter(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			res, err := dbStorage.getCounter(tc.counterName)
+			res, err := dbStorage.getCounter(context.Background(), tc.counterName)
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
@@ -1641,7 +1641,7 @@ func TestDBStorage_getGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedGauge: Gauge(0),
@@ -1699,7 +1699,7 @@ func TestDBStorage_getGauge(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			res, err := dbStorage.getGauge(tc.gaugeName)
+			res, err := dbStorage.getGauge(context.Background(), tc.gaugeName)
 			if tc.err != nil {
 				assert.ErrorIs(t, err, tc.err)
 			} else {
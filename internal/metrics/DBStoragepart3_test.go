@@ -29,7 +29,7 @@ func TestDBStorage_GetGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedGauge: Gauge(0),
@@ -258,7 +258,7 @@ func TestDBStorage_GetCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			expectedCounter: Counter(0),
@@ -486,7 +486,7 @@ func TestDBStorage_addCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -504,7 +504,7 @@ func TestDBStorage_addCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -523,7 +523,7 @@ func TestDBStorage_addCounter(t *testing.T) {
 				storeCtx: context.Background(),
 				db:       tc.getExecutor(t),
 			}
-			err := dbStorage.addCounter(tc.counterName, tc.counterValue)
+			err := dbStorage.addCounter(context.Background(), tc.counterName, tc.counterValue)
 			if tc.wantError != nil {
 				assert.ErrorIs(t, tc.wantError, err)
 			} else {
@@ -568,7 +568,7 @@ func TestDBStorage_syncCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -585,7 +585,7 @@ func TestDBStorage_syncCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -602,7 +602,7 @@ func TestDBStorage_syncCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -619,8 +619,8 @@ func TestDBStorage_syncCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).After(first)
 				return executor
 			},
 			counterValue: Counter(12),
@@ -640,7 +640,7 @@ func TestDBStorage_syncCounter(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			err := dbStorage.syncCounter(tc.counterName, tc.counterValue)
+			err := dbStorage.syncCounter(context.Background(), tc.counterName, tc.counterValue)
 			if tc.wantError != nil {
 				assert.ErrorIs(t, tc.wantError, err)
 			} else {
@@ -678,7 +678,7 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
@@ -689,7 +689,7 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -708,7 +708,7 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -716,7 +716,7 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
@@ -727,7 +727,7 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			counterValue: Counter(12),
@@ -735,7 +735,7 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
@@ -746,8 +746,8 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).After(first)
 				return executor
 			},
 			counterValue: Counter(12),
@@ -755,19 +755,18 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().AddCounter(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
 		},
 		{
-			name:      "pg_and_then_exec_error_error",
+			name:      "store_error_after_db",
 			wantError: execStore,
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				//first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				//.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).Times(1)
 				return executor
 			},
 			counterValue: Counter(12),
@@ -786,8 +785,8 @@ func TestDBStorage_AddCounter(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				//first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				//.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				//first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				//.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).After(first)
 				return executor
 			},
 			counterValue: Counter(12),
@@ -836,7 +835,7 @@ func TestDBStorage_setGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -853,7 +852,7 @@ func TestDBStorage_setGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -872,7 +871,7 @@ func TestDBStorage_setGauge(t *testing.T) {
 				storeCtx: context.Background(),
 				db:       tc.getExecutor(t),
 			}
-			err := dbStorage.setGauge(tc.gaugeName, tc.gaugeValue)
+			err := dbStorage.setGauge(context.Background(), tc.gaugeName, tc.gaugeValue)
 			if tc.wantError != nil {
 				assert.ErrorIs(t, tc.wantError, err)
 			} else {
@@ -917,7 +916,7 @@ func TestDBStorage_syncGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -934,7 +933,7 @@ func TestDBStorage_syncGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -951,7 +950,7 @@ func TestDBStorage_syncGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -968,8 +967,8 @@ func TestDBStorage_syncGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).After(first)
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -989,7 +988,7 @@ func TestDBStorage_syncGauge(t *testing.T) {
 				db:       tc.getExecutor(t),
 				close:    tc.close,
 			}
-			err := dbStorage.syncGauge(tc.gaugeName, tc.gaugeValue)
+			err := dbStorage.syncGauge(context.Background(), tc.gaugeName, tc.gaugeValue)
 			if tc.wantError != nil {
 				assert.ErrorIs(t, tc.wantError, err)
 			} else {
@@ -1027,7 +1026,7 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
@@ -1038,7 +1037,7 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -1057,7 +1056,7 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -1065,7 +1064,7 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
@@ -1076,7 +1075,7 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).AnyTimes()
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).AnyTimes()
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -1084,7 +1083,7 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
@@ -1095,8 +1094,8 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).After(first)
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -1104,19 +1103,18 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getStorage: func(t *testing.T) IStorage {
 				ctrl := gomock.NewController(t)
 				store := NewMockIStorage(ctrl)
-				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+				store.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Times(0)
 				return store
 			},
 			syncMode: true,
 		},
 		{
-			name:      "pg_and_then_exec_error_error",
+			name:      "store_error_after_db",
 			wantError: execStore,
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				//first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				//executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, nil).Times(1)
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -1135,8 +1133,8 @@ func TestDBStorage_SetGauge(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				//first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errorPGConnection).Times(1)
-				//executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execError).After(first)
+				//first := executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, errorPGConnection).Times(1)
+				//executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").Return(nil, execError).After(first)
 				return executor
 			},
 			gaugeValue: Gauge(12),
@@ -1213,7 +1211,7 @@ func TestDBStorage_NewDBStorage(t *testing.T) {
 						}
 					}
 				}).After(firstG)
-				fe := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rowsG, nil).Times(1)
+				fe := executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rowsG, nil).Times(1)
 
 				rows := NewMockIRows(ctrl)
 				rows.EXPECT().Close().Return(nil).AnyTimes()
@@ -1242,7 +1240,8 @@ func TestDBStorage_NewDBStorage(t *testing.T) {
 						}
 					}
 				}).After(first)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(rows, nil).After(fe)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(rows, nil).After(fe)
+				expectNoDistributions(ctrl, executor)
 				return executor
 			},
 		},
@@ -1253,7 +1252,7 @@ func TestDBStorage_NewDBStorage(t *testing.T) {
 			getExecutor: func(t *testing.T) SQLExecutor {
 				ctrl := gomock.NewController(t)
 				executor := NewMockSQLExecutor(ctrl)
-				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any()).Return(nil, restoreError).AnyTimes()
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, restoreError).AnyTimes()
 				return executor
 			},
 		},
@@ -1266,8 +1265,12 @@ func TestDBStorage_NewDBStorage(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
 				return executor
@@ -1282,8 +1285,12 @@ func TestDBStorage_NewDBStorage(t *testing.T) {
 				tx := NewMockITX(ctrl)
 				tx.EXPECT().Commit().Return(nil).AnyTimes()
 				tx.EXPECT().Rollback().Return(nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_gauge").Return(NewMockIResult(ctrl), nil).AnyTimes()
-				tx.EXPECT().ExecContext(gomock.Any(), "TRUNCATE TABLE t_counter").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_gauge WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_counter WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_histogram WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_summary WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_set WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
+				tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM t_metadata WHERE tenant = $1", "").Return(NewMockIResult(ctrl), nil).AnyTimes()
 				executor := NewMockSQLExecutor(ctrl)
 				executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, errorBeginTX).AnyTimes()
 				return executor
@@ -0,0 +1,554 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// affectedExecutor исполнитель запросов, который затрагивает rows строк или возвращает ошибку
+func affectedExecutor(t *testing.T, rows int64, execErr error) SQLExecutor {
+	ctrl := gomock.NewController(t)
+	executor := NewMockSQLExecutor(ctrl)
+	if execErr != nil {
+		executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execErr).AnyTimes()
+		executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, execErr).AnyTimes()
+		return executor
+	}
+	result := NewMockIResult(ctrl)
+	result.EXPECT().RowsAffected().Return(rows, nil).AnyTimes()
+	executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(result, nil).AnyTimes()
+	executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(result, nil).AnyTimes()
+	return executor
+}
+
+func TestDBStorage_Delete(t *testing.T) {
+	execError := errors.New("execError")
+	testCases := []struct {
+		name       string
+		metricType string
+		metricName string
+		rows       int64
+		execErr    error
+		closed     bool
+		wantErr    error
+	}{
+		{name: "memory_and_db", metricType: TypeGauge, metricName: "gauge1", rows: 1},
+		{name: "only_memory", metricType: TypeCounter, metricName: "counter1", rows: 0},
+		{name: "only_db", metricType: TypeGauge, metricName: "stored", rows: 1},
+		{name: "not_found", metricType: TypeGauge, metricName: "missing", rows: 0, wantErr: ErrorMetricNotFound},
+		{name: "unknown_type", metricType: "timer", metricName: "gauge1", wantErr: ErrorUnknownMetricType},
+		{name: "exec_error", metricType: TypeGauge, metricName: "gauge1", execErr: execError, wantErr: execError},
+		{name: "closed", metricType: TypeGauge, metricName: "gauge1", closed: true, wantErr: ErrorStorageDatabaseClosed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := NewMemStorage()
+			_ = mem.SetGauge("gauge1", 1.5)
+			_ = mem.AddCounter("counter1", 3)
+			dbStorage := DBStorage{
+				IStorage: mem,
+				storeCtx: context.Background(),
+				db:       affectedExecutor(t, tc.rows, tc.execErr),
+				close:    tc.closed,
+			}
+			err := dbStorage.Delete(tc.metricType, tc.metricName)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			_, ok := mem.GetGauge(tc.metricName)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestDBStorage_ResetCounter(t *testing.T) {
+	execError := errors.New("execError")
+	testCases := []struct {
+		name       string
+		metricName string
+		rows       int64
+		execErr    error
+		wantErr    error
+	}{
+		{name: "memory_and_db", metricName: "counter1", rows: 1},
+		{name: "only_db", metricName: "stored", rows: 1},
+		{name: "not_found", metricName: "missing", rows: 0, wantErr: ErrorMetricNotFound},
+		{name: "exec_error", metricName: "counter1", execErr: execError, wantErr: execError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := NewMemStorage()
+			_ = mem.AddCounter("counter1", 3)
+			dbStorage := DBStorage{
+				IStorage: mem,
+				storeCtx: context.Background(),
+				db:       affectedExecutor(t, tc.rows, tc.execErr),
+			}
+			err := dbStorage.ResetCounter(tc.metricName)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			if value, ok := mem.GetCounter(tc.metricName); ok {
+				assert.Equal(t, Counter(0), value)
+			}
+		})
+	}
+}
+
+func TestDBStorage_DeleteExpired(t *testing.T) {
+	execError := errors.New("execError")
+	testCases := []struct {
+		name        string
+		ttl         time.Duration
+		rows        int64
+		execErr     error
+		wantDeleted int
+		wantErr     error
+	}{
+		{name: "disabled", ttl: 0, rows: 5, wantDeleted: 0},
+		{name: "deleted", ttl: time.Minute, rows: 2, wantDeleted: 10},
+		{name: "exec_error", ttl: time.Minute, execErr: execError, wantErr: execError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := NewMemStorage()
+			dbStorage := DBStorage{
+				IStorage: mem,
+				storeCtx: context.Background(),
+				db:       affectedExecutor(t, tc.rows, tc.execErr),
+			}
+			dbStorage.SetTTL(tc.ttl)
+			assert.Equal(t, tc.ttl, mem.ttl)
+			deleted, err := dbStorage.DeleteExpired()
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantDeleted, deleted)
+		})
+	}
+}
+
+func TestDBStorage_getGaugesTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	executor := NewMockSQLExecutor(ctrl)
+	rows := NewMockIRows(ctrl)
+	rows.EXPECT().Close().Return(nil)
+	rows.EXPECT().Err().Return(nil)
+	rows.EXPECT().Next().Return(false)
+	// С устареванием в запрос передаётся граница времени обновления
+	executor.EXPECT().QueryContext(gomock.Any(), "SELECT name, value FROM t_gauge WHERE tenant = $2 AND updated_at > $1", gomock.Any(), "").Return(rows, nil)
+	row := NewMockIRow(ctrl)
+	row.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+	executor.EXPECT().QueryRowContext(gomock.Any(), "SELECT value FROM t_counter WHERE tenant = $3 AND name = $1 AND updated_at > $2", "counter", gomock.Any(), "").Return(row)
+
+	dbStorage := DBStorage{
+		IStorage: NewMemStorage(),
+		storeCtx: context.Background(),
+		db:       executor,
+	}
+	dbStorage.SetTTL(time.Minute)
+	gauges, err := dbStorage.getGauges(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, gauges)
+	_, err = dbStorage.getCounter(context.Background(), "counter")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// listRows строки ответа бд на запрос списка метрик
+func listRows(ctrl *gomock.Controller, list []ListedMetric) IRows {
+	rows := NewMockIRows(ctrl)
+	i := -1
+	rows.EXPECT().Next().DoAndReturn(func() bool {
+		i++
+		return i < len(list)
+	}).AnyTimes()
+	rows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+		metric := list[i]
+		*dest[0].(*string) = metric.Type
+		*dest[1].(*string) = metric.Name
+		*dest[2].(*Gauge) = metric.Gauge
+		*dest[3].(*Counter) = metric.Counter
+		if metric.Type != TypeGauge && metric.Type != TypeCounter {
+			data, err := importedValue(metric)
+			if err != nil {
+				return err
+			}
+			if metric.Type == TypeSet {
+				*dest[5].(*[]byte) = data.([]byte)
+			} else {
+				*dest[4].(*sql.NullString) = sql.NullString{String: data.(string), Valid: true}
+			}
+		}
+		*dest[6].(*sql.NullTime) = sql.NullTime{Time: metric.UpdatedAt, Valid: true}
+		return nil
+	}).AnyTimes()
+	rows.EXPECT().Err().Return(nil).AnyTimes()
+	rows.EXPECT().Close().Return(nil).AnyTimes()
+	return rows
+}
+
+func TestDBStorage_listSQL(t *testing.T) {
+	const union = "SELECT type, name, gauge, counter, data, sketch, updated_at FROM (" +
+		"SELECT 'gauge' AS type, name, value AS gauge, NULL::bigint AS counter, NULL::text AS data, NULL::bytea AS sketch, updated_at FROM t_gauge WHERE tenant = $1 UNION ALL " +
+		"SELECT 'counter' AS type, name, NULL::double precision AS gauge, value AS counter, NULL::text AS data, NULL::bytea AS sketch, updated_at FROM t_counter WHERE tenant = $1 UNION ALL " +
+		"SELECT 'histogram' AS type, name, NULL::double precision AS gauge, NULL::bigint AS counter, data, NULL::bytea AS sketch, updated_at FROM t_histogram WHERE tenant = $1 UNION ALL " +
+		"SELECT 'summary' AS type, name, NULL::double precision AS gauge, NULL::bigint AS counter, data, NULL::bytea AS sketch, updated_at FROM t_summary WHERE tenant = $1 UNION ALL " +
+		"SELECT 'set' AS type, name, NULL::double precision AS gauge, NULL::bigint AS counter, NULL::text AS data, data AS sketch, updated_at FROM t_set WHERE tenant = $1) m"
+	tests := []struct {
+		name     string
+		query    ListQuery
+		after    *ListKey
+		limit    int
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "all",
+			query:    ListQuery{Sort: ListSortName},
+			wantSQL:  union + ` ORDER BY name COLLATE "C" ASC, type ASC`,
+			wantArgs: []any{"team-a"},
+		},
+		{
+			name:     "gauge_prefix_limit",
+			query:    ListQuery{Filter: ListFilter{Type: TypeGauge, Prefix: "Heap_%"}, Sort: ListSortName},
+			limit:    10,
+			wantSQL:  "SELECT type, name, gauge, counter, data, sketch, updated_at FROM (SELECT 'gauge' AS type, name, value AS gauge, NULL::bigint AS counter, NULL::text AS data, NULL::bytea AS sketch, updated_at FROM t_gauge WHERE tenant = $1) m" + ` WHERE name COLLATE "C" LIKE $2 ORDER BY name COLLATE "C" ASC, type ASC LIMIT $3`,
+			wantArgs: []any{"team-a", `Heap\_\%%`, 10},
+		},
+		{
+			name:     "after_by_name_desc",
+			query:    ListQuery{Sort: ListSortName, Desc: true},
+			after:    &ListKey{Type: TypeGauge, Name: "Alloc"},
+			wantSQL:  union + ` WHERE name COLLATE "C" <= $2 AND (name COLLATE "C" < $2 OR type < $3) ORDER BY name COLLATE "C" DESC, type DESC`,
+			wantArgs: []any{"team-a", "Alloc", TypeGauge},
+		},
+		{
+			name:     "after_by_type",
+			query:    ListQuery{Sort: ListSortType},
+			after:    &ListKey{Type: TypeCounter, Name: "PollCount"},
+			wantSQL:  union + ` WHERE (type > $3 OR (type = $3 AND name COLLATE "C" > $2)) ORDER BY type ASC, name COLLATE "C" ASC`,
+			wantArgs: []any{"team-a", "PollCount", TypeCounter},
+		},
+		{
+			name:  "histogram",
+			query: ListQuery{Filter: ListFilter{Type: TypeHistogram}, Sort: ListSortName},
+			wantSQL: "SELECT type, name, gauge, counter, data, sketch, updated_at FROM (SELECT 'histogram' AS type, name, NULL::double precision AS gauge, NULL::bigint AS counter, data, NULL::bytea AS sketch, updated_at FROM t_histogram WHERE tenant = $1) m" +
+				` ORDER BY name COLLATE "C" ASC, type ASC`,
+			wantArgs: []any{"team-a"},
+		},
+		{
+			name:  "set",
+			query: ListQuery{Filter: ListFilter{Type: TypeSet}, Sort: ListSortName},
+			wantSQL: "SELECT type, name, gauge, counter, data, sketch, updated_at FROM (SELECT 'set' AS type, name, NULL::double precision AS gauge, NULL::bigint AS counter, NULL::text AS data, data AS sketch, updated_at FROM t_set WHERE tenant = $1) m" +
+				` ORDER BY name COLLATE "C" ASC, type ASC`,
+			wantArgs: []any{"team-a"},
+		},
+		{
+			name:    "unknown_type",
+			query:   ListQuery{Filter: ListFilter{Type: "unknown"}},
+			wantSQL: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := DBStorage{tenant: "team-a"}
+			sqlQuery, args := storage.listSQL(tt.query, tt.after, tt.limit)
+			assert.Equal(t, tt.wantSQL, sqlQuery)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestDBStorage_List(t *testing.T) {
+	queryError := errors.New("queryError")
+	updated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	latency := NewHistogram([]float64{0.1, 1})
+	latency.Observe(0.5)
+	size := Summary{Observations: []Observation{{Value: 2, Time: updated}}}
+	users := NewSketch("alice", "bob")
+	notMatched := make([]ListedMetric, 0, listChunkSize)
+	for i := 0; i < listChunkSize; i++ {
+		notMatched = append(notMatched, ListedMetric{Type: TypeGauge, Name: fmt.Sprintf("a%03d", i)})
+	}
+	tests := []struct {
+		name        string
+		syncMode    bool
+		query       ListQuery
+		getExecutor func(t *testing.T) SQLExecutor
+		want        []ListedMetric
+		wantErr     error
+	}{
+		{
+			name:     "one_query",
+			syncMode: true,
+			query:    ListQuery{Sort: ListSortName, Limit: 5},
+			getExecutor: func(t *testing.T) SQLExecutor {
+				ctrl := gomock.NewController(t)
+				executor := NewMockSQLExecutor(ctrl)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "", 5).Return(listRows(ctrl, []ListedMetric{
+					{Type: TypeGauge, Name: "Alloc", Gauge: 1.5, UpdatedAt: updated},
+					{Type: TypeHistogram, Name: "Latency", Histogram: latency, UpdatedAt: updated},
+					{Type: TypeCounter, Name: "PollCount", Counter: 3, UpdatedAt: updated},
+					{Type: TypeSummary, Name: "Size", Summary: size, UpdatedAt: updated},
+					{Type: TypeSet, Name: "Users", Set: users, UpdatedAt: updated},
+				}), nil)
+				return executor
+			},
+			want: []ListedMetric{
+				{Type: TypeGauge, Name: "Alloc", Gauge: 1.5, UpdatedAt: updated},
+				{Type: TypeHistogram, Name: "Latency", Histogram: latency, UpdatedAt: updated},
+				{Type: TypeCounter, Name: "PollCount", Counter: 3, UpdatedAt: updated},
+				{Type: TypeSummary, Name: "Size", Summary: size, UpdatedAt: updated},
+				{Type: TypeSet, Name: "Users", Set: users, UpdatedAt: updated},
+			},
+		},
+		{
+			name:     "broken_histogram",
+			syncMode: true,
+			query:    ListQuery{Sort: ListSortName},
+			getExecutor: func(t *testing.T) SQLExecutor {
+				ctrl := gomock.NewController(t)
+				executor := NewMockSQLExecutor(ctrl)
+				// Гистограмма с несовпадающими корзинами пропускается
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(listRows(ctrl, []ListedMetric{
+					{Type: TypeHistogram, Name: "Broken", Histogram: Histogram{Bounds: []float64{1}, Counts: []uint64{1}}, UpdatedAt: updated},
+					{Type: TypeGauge, Name: "Heap", Gauge: 2, UpdatedAt: updated},
+				}), nil)
+				return executor
+			},
+			want: []ListedMetric{{Type: TypeGauge, Name: "Heap", Gauge: 2, UpdatedAt: updated}},
+		},
+		{
+			name:     "filtered_in_chunks",
+			syncMode: true,
+			query:    ListQuery{Filter: ListFilter{Regexp: regexp.MustCompile("^b$")}, Sort: ListSortName, Limit: 1},
+			getExecutor: func(t *testing.T) SQLExecutor {
+				ctrl := gomock.NewController(t)
+				executor := NewMockSQLExecutor(ctrl)
+				gomock.InOrder(
+					executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "", listChunkSize).Return(listRows(ctrl, notMatched), nil),
+					// Следующая порция начинается после последней прочитанной строки
+					executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "", "a499", TypeGauge, listChunkSize).Return(listRows(ctrl, []ListedMetric{
+						{Type: TypeGauge, Name: "b"},
+						{Type: TypeGauge, Name: "c"},
+					}), nil),
+				)
+				return executor
+			},
+			want: []ListedMetric{{Type: TypeGauge, Name: "b"}},
+		},
+		{
+			name:     "query_error",
+			syncMode: true,
+			query:    ListQuery{Sort: ListSortName},
+			getExecutor: func(t *testing.T) SQLExecutor {
+				ctrl := gomock.NewController(t)
+				executor := NewMockSQLExecutor(ctrl)
+				executor.EXPECT().QueryContext(gomock.Any(), gomock.Any(), "").Return(nil, queryError)
+				return executor
+			},
+			wantErr: queryError,
+		},
+		{
+			name:     "memory_in_interval_mode",
+			syncMode: false,
+			query:    ListQuery{Sort: ListSortName},
+			getExecutor: func(t *testing.T) SQLExecutor {
+				return NewMockSQLExecutor(gomock.NewController(t))
+			},
+			want: []ListedMetric{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbStorage := DBStorage{
+				IStorage: NewMemStorage(),
+				storeCtx: context.Background(),
+				db:       tt.getExecutor(t),
+				syncMode: tt.syncMode,
+			}
+			list, err := dbStorage.List(tt.query)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, list)
+		})
+	}
+}
+
+// valueRows строки ответа бд с именами и значениями метрик
+func valueRows[V Gauge | Counter](ctrl *gomock.Controller, names []string, values []V) IRows {
+	rows := NewMockIRows(ctrl)
+	i := -1
+	rows.EXPECT().Next().DoAndReturn(func() bool {
+		i++
+		return i < len(names)
+	}).AnyTimes()
+	rows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+		*dest[0].(*string), *dest[1].(*V) = names[i], values[i]
+		return nil
+	}).AnyTimes()
+	rows.EXPECT().Err().Return(nil).AnyTimes()
+	rows.EXPECT().Close().Return(nil).AnyTimes()
+	return rows
+}
+
+func TestDBStorage_GetByNames(t *testing.T) {
+	queryError := errors.New("queryError")
+	ctrl := gomock.NewController(t)
+	executor := NewMockSQLExecutor(ctrl)
+	// Из бд запрашиваются одним запросом только метрики, которых нет в памяти, без повторов
+	executor.EXPECT().QueryContext(gomock.Any(), "SELECT name, value FROM t_gauge WHERE tenant = $1 AND name = ANY($2)", "", []string{"db", "missing"}).
+		Return(valueRows(ctrl, []string{"db"}, []Gauge{2}), nil)
+	executor.EXPECT().QueryContext(gomock.Any(), "SELECT name, value FROM t_counter WHERE tenant = $1 AND name = ANY($2) AND updated_at > $3", "", []string{"db"}, gomock.Any()).
+		Return(nil, queryError)
+
+	mem := NewMemStorage()
+	require.NoError(t, mem.SetGauge("memory", 1))
+	require.NoError(t, mem.AddCounter("memory", 3))
+	dbStorage := DBStorage{
+		IStorage: mem,
+		storeCtx: context.Background(),
+		db:       executor,
+	}
+
+	gauges, err := dbStorage.GetGaugesByNames([]string{"memory", "db", "missing", "db"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"memory": 1, "db": 2}, gauges)
+
+	// Все метрики в памяти, запроса в бд нет
+	gauges, err = dbStorage.GetGaugesByNames([]string{"memory"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"memory": 1}, gauges)
+
+	dbStorage.SetTTL(time.Hour)
+	counters, err := dbStorage.GetCountersByNames([]string{"memory", "db"})
+	assert.ErrorIs(t, err, queryError)
+	assert.Equal(t, map[string]Counter{"memory": 3}, counters)
+}
+
+// importExecutor исполнитель транзакции загрузки, запоминающий запросы и записанные значения
+func importExecutor(t *testing.T, queries *[]string, values *[][]any, execErr error) SQLExecutor {
+	ctrl := gomock.NewController(t)
+	prepared := NewMockIStmt(ctrl)
+	prepared.EXPECT().Close().Return(nil).AnyTimes()
+	prepared.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(args ...any) (IResult, error) {
+		*values = append(*values, args)
+		return nil, execErr
+	}).AnyTimes()
+	tx := NewMockITX(ctrl)
+	tx.EXPECT().ExecContext(gomock.Any(), gomock.Any(), "team-a").DoAndReturn(func(_ context.Context, query string, _ ...any) (IResult, error) {
+		*queries = append(*queries, query)
+		return nil, nil
+	}).AnyTimes()
+	tx.EXPECT().PrepareContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, query string) (IStmt, error) {
+		*queries = append(*queries, query)
+		return prepared, nil
+	}).AnyTimes()
+	tx.EXPECT().Commit().Return(nil).AnyTimes()
+	tx.EXPECT().Rollback().Return(nil).AnyTimes()
+	executor := NewMockSQLExecutor(ctrl)
+	executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(tx, nil).AnyTimes()
+	return executor
+}
+
+func TestDBStorage_Import(t *testing.T) {
+	execError := errors.New("execError")
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	importGauge := "INSERT INTO t_gauge (tenant, name, value, updated_at) VALUES ($4, $1, $2, $3) on conflict (tenant, name) do update set value = $2, updated_at = $3"
+	importCounter := "INSERT INTO t_counter (tenant, name, value, updated_at) VALUES ($4, $1, $2, $3) on conflict (tenant, name) do update set value = $2, updated_at = $3"
+	importHistogram := "INSERT INTO t_histogram (tenant, name, data, updated_at) VALUES ($4, $1, $2, $3) on conflict (tenant, name) do update set data = $2, updated_at = $3"
+	latency := NewHistogram([]float64{1})
+	latency.Observe(0.5)
+	list := []ListedMetric{
+		{Type: TypeCounter, Name: "counter2", Counter: 7, UpdatedAt: updated},
+		{Type: TypeHistogram, Name: "latency", Histogram: latency, UpdatedAt: updated},
+		{Type: TypeGauge, Name: "gauge1", Gauge: 2.5, UpdatedAt: updated},
+	}
+	testCases := []struct {
+		name        string
+		list        []ListedMetric
+		replace     bool
+		execErr     error
+		closed      bool
+		wantQueries []string
+		wantErr     error
+	}{
+		{
+			name:        "merge",
+			list:        list,
+			wantQueries: []string{importGauge, importCounter, importHistogram},
+		},
+		{
+			name:    "replace",
+			list:    list,
+			replace: true,
+			// Очищаются таблицы всех типов, а не только тех, что есть в снимке
+			wantQueries: []string{
+				"DELETE FROM t_gauge WHERE tenant = $1", "DELETE FROM t_counter WHERE tenant = $1", "DELETE FROM t_histogram WHERE tenant = $1",
+				"DELETE FROM t_summary WHERE tenant = $1", "DELETE FROM t_set WHERE tenant = $1",
+				importGauge, importCounter, importHistogram,
+			},
+		},
+		{
+			name:    "wrong_histogram",
+			list:    []ListedMetric{{Type: TypeHistogram, Name: "h", Histogram: Histogram{Bounds: []float64{1}}}},
+			wantErr: ErrorWrongHistogram,
+		},
+		{name: "unknown_type", list: []ListedMetric{{Type: "unknown", Name: "u"}}, wantErr: ErrorUnknownMetricType},
+		{name: "exec_error", list: list, execErr: execError, wantErr: execError},
+		{name: "closed", list: list, closed: true, wantErr: ErrorStorageDatabaseClosed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := NewMemStorage()
+			_ = mem.SetGauge("gauge2", 1.5)
+			_ = mem.AddSet("users", NewSketch("alice"))
+			var queries []string
+			var values [][]any
+			dbStorage := DBStorage{
+				IStorage: mem,
+				storeCtx: context.Background(),
+				db:       importExecutor(t, &queries, &values, tc.execErr),
+				close:    tc.closed,
+				tenant:   "team-a",
+			}
+			err := dbStorage.Import(tc.list, tc.replace)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantQueries, queries)
+			assert.Equal(t, [][]any{
+				{"gauge1", Gauge(2.5), updated, "team-a"},
+				{"counter2", Counter(7), updated, "team-a"},
+				{"latency", `{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}`, updated, "team-a"},
+			}, values)
+			_, ok := mem.GetGauge("gauge2")
+			assert.Equal(t, !tc.replace, ok)
+			_, ok = mem.GetSet("users")
+			assert.Equal(t, !tc.replace, ok)
+			histogram, ok := mem.GetHistogram("latency")
+			require.True(t, ok)
+			assert.Equal(t, latency, histogram)
+		})
+	}
+}
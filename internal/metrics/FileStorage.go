@@ -9,6 +9,7 @@ import (
 	"gmetrics/internal/metrics/fileworker"
 	"io"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -18,11 +19,41 @@ type Writer interface {
 	io.Closer
 }
 
-// DurationFileStorage хранилище с циклической записью в файл данных
+// WALSuffix суффикс файла журнала предзаписи, который лежит рядом с файлом хранилища
+const WALSuffix = ".wal"
+
+// WALCompactSize размер журнала предзаписи в байтах, после которого он сворачивается в снимок хранилища
+var WALCompactSize int64 = 4 << 20
+
+// DurationFileStorage хранилище с циклической записью в файл данных.
+// Каждое изменение дописывается в журнал предзаписи, а журнал периодически сворачивается в снимок хранилища
 type DurationFileStorage struct {
 	IStorage
 	writer   Writer
-	syncMode bool // Флаг синхронного режима, в нём после записи в хранилище, сразу оно будет записано в файл
+	wal      *fileworker.WAL // Журнал изменений после последнего снимка; nil - изменения сохраняются только снимками
+	mutex    sync.Mutex      // Изменения памяти и записи в журнал идут в одном порядке
+	syncMode bool            // Флаг синхронного режима, в нём каждая запись журнала сразу сбрасывается на диск
+}
+
+// walMetric изменение метрики в журнале. Записывается итоговое значение, а не приращение,
+// поэтому повторное применение записи поверх снимка, в который она уже попала, ничего не меняет
+type walMetric struct {
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Gauge     Gauge      `json:"gauge,omitempty"`
+	Counter   Counter    `json:"counter,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	Histogram *Histogram `json:"histogram,omitempty"` // Гистограмма после прибавления
+	Summary   *Summary   `json:"summary,omitempty"`   // Наблюдения сводки после добавления
+	Set       *Sketch    `json:"set,omitempty"`       // Скетч множества после объединения
+	Metadata  *Metadata  `json:"metadata,omitempty"`  // Описание метрики после объединения
+}
+
+// walRecord запись журнала - одно изменение хранилища
+type walRecord struct {
+	Clear   bool        `json:"clear,omitempty"` // Перед изменениями удалить все метрики
+	Metrics []walMetric `json:"metrics"`
 }
 
 // IsSyncMode открыто ли хранилище в синхронном режиме
@@ -30,9 +61,45 @@ func (storage *DurationFileStorage) IsSyncMode() bool {
 	return storage.syncMode
 }
 
-// Flush запись данных в файл
+// inner хранилище в памяти, в котором хранятся метрики
+func (storage *DurationFileStorage) inner() IStorage {
+	return storage.IStorage
+}
+
+// Flush запись снимка хранилища в файл и очистка журнала
 func (storage *DurationFileStorage) Flush() error {
-	return storage.writer.Write(storage.IStorage)
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return storage.flush()
+}
+
+// flush запись снимка без блокировки. Журнал очищается только после того, как снимок записан
+func (storage *DurationFileStorage) flush() error {
+	if err := storage.writer.Write(storage.IStorage); err != nil {
+		return err
+	}
+	if storage.wal != nil {
+		return storage.wal.Truncate()
+	}
+	return nil
+}
+
+// logRecord записывает изменение в журнал и сворачивает журнал, если он стал больше WALCompactSize.
+// Если журнала нет, то в синхронном режиме сразу записывается снимок. Вызывается под storage.mutex
+func (storage *DurationFileStorage) logRecord(record walRecord) error {
+	if storage.wal == nil {
+		if storage.syncMode {
+			return storage.flush()
+		}
+		return nil
+	}
+	if err := storage.wal.Append(record); err != nil {
+		return err
+	}
+	if storage.wal.Size() > WALCompactSize {
+		return storage.flush()
+	}
+	return nil
 }
 
 // Sync синхронизация данных хранилища в файл по таймеру
@@ -63,9 +130,13 @@ func (storage *DurationFileStorage) Sync(ctx context.Context) error {
 	}
 }
 
-// Close Закрытие писателя (файла)
+// Close Закрытие писателя (файла) и журнала
 func (storage *DurationFileStorage) Close() error {
-	return storage.writer.Close()
+	err := storage.writer.Close()
+	if storage.wal != nil {
+		err = errors.Join(err, storage.wal.Close())
+	}
+	return err
 }
 
 // FlushAndClose синхронизация данных и закрытие писателя (файла)
@@ -73,68 +144,330 @@ func (storage *DurationFileStorage) FlushAndClose() error {
 	if err := storage.Flush(); err != nil {
 		return err
 	}
-	return storage.writer.Close()
+	return storage.Close()
 }
 
-// SetGauge переопределённый метод с записью в файл в случае синхронного режима
+// SetGauge переопределённый метод с записью в журнал
 func (storage *DurationFileStorage) SetGauge(name string, value Gauge) error {
-	err := storage.IStorage.SetGauge(name, value)
+	return storage.SetGauges(map[string]Gauge{name: value})
+}
+
+// AddCounter переопределённый метод с записью в журнал
+func (storage *DurationFileStorage) AddCounter(name string, value Counter) error {
+	return storage.AddCounters(map[string]Counter{name: value})
+}
+
+// SetGauges массовое обновление гауге
+func (storage *DurationFileStorage) SetGauges(gauges map[string]Gauge) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if err := storage.IStorage.SetGauges(gauges); err != nil {
+		return err
+	}
+	return storage.logGauges(gauges)
+}
+
+// SetGaugesReturningOld массовое обновление гауге с прежними значениями существовавших gauge
+func (storage *DurationFileStorage) SetGaugesReturningOld(ctx context.Context, gauges map[string]Gauge) (map[string]Gauge, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	old, err := SetGaugesReturningOld(ctx, storage.IStorage, gauges)
 	if err != nil {
+		return nil, err
+	}
+	return old, storage.logGauges(gauges)
+}
+
+// logGauges запись установленных gauge в журнал
+func (storage *DurationFileStorage) logGauges(gauges map[string]Gauge) error {
+	now := time.Now()
+	record := walRecord{Metrics: make([]walMetric, 0, len(gauges))}
+	for name, value := range gauges {
+		record.Metrics = append(record.Metrics, walMetric{Type: TypeGauge, Name: name, Gauge: value, UpdatedAt: now})
+	}
+	return storage.logRecord(record)
+}
+
+// AddCounters массовое обновление каунтер. В журнал записываются значения после прибавления
+func (storage *DurationFileStorage) AddCounters(counters map[string]Counter) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if err := storage.IStorage.AddCounters(counters); err != nil {
 		return err
 	}
-	if storage.syncMode {
-		if err = storage.Flush(); err != nil {
-			return err
-		}
+	updated := make(map[string]Counter, len(counters))
+	for name := range counters {
+		updated[name], _ = storage.IStorage.GetCounter(name)
 	}
-	return nil
+	return storage.logCounters(updated)
 }
 
-// AddCounter переопределённый метод с записью в файл в случае синхронного режима
-func (storage *DurationFileStorage) AddCounter(name string, value Counter) error {
-	err := storage.IStorage.AddCounter(name, value)
-	if err != nil {
+// AddCountersReturningNew массовое обновление каунтер со значениями до и после прибавления
+func (storage *DurationFileStorage) AddCountersReturningNew(ctx context.Context, counters map[string]Counter) (old, updated map[string]Counter, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if old, updated, err = AddCountersReturningNew(ctx, storage.IStorage, counters); err != nil {
+		return nil, nil, err
+	}
+	return old, updated, storage.logCounters(updated)
+}
+
+// logCounters запись значений counter после прибавления в журнал
+func (storage *DurationFileStorage) logCounters(counters map[string]Counter) error {
+	now := time.Now()
+	record := walRecord{Metrics: make([]walMetric, 0, len(counters))}
+	for name, value := range counters {
+		record.Metrics = append(record.Metrics, walMetric{Type: TypeCounter, Name: name, Counter: value, UpdatedAt: now})
+	}
+	return storage.logRecord(record)
+}
+
+// Delete удаление метрики с записью в журнал
+func (storage *DurationFileStorage) Delete(metricType, name string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if err := storage.IStorage.Delete(metricType, name); err != nil {
 		return err
 	}
-	if storage.syncMode {
-		if err = storage.Flush(); err != nil {
-			return err
-		}
+	return storage.logRecord(walRecord{Metrics: []walMetric{{Type: metricType, Name: name, Deleted: true}}})
+}
+
+// DeleteReturningOld удаление метрики с записью в журнал, возвращает удалённое значение
+func (storage *DurationFileStorage) DeleteReturningOld(ctx context.Context, metricType, name string) (any, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	old, err := DeleteReturningOld(ctx, storage.IStorage, metricType, name)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return old, storage.logRecord(walRecord{Metrics: []walMetric{{Type: metricType, Name: name, Deleted: true}}})
 }
 
-// SetGauges массовое обновление гауге
-func (storage *DurationFileStorage) SetGauges(gauges map[string]Gauge) error {
-	err := storage.IStorage.SetGauges(gauges)
+// ResetCounter обнуление counter с записью в журнал
+func (storage *DurationFileStorage) ResetCounter(name string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if err := storage.IStorage.ResetCounter(name); err != nil {
+		return err
+	}
+	return storage.logRecord(walRecord{Metrics: []walMetric{{Type: TypeCounter, Name: name, UpdatedAt: time.Now()}}})
+}
+
+// ResetCounterReturningOld обнуление counter с записью в журнал, возвращает значение до обнуления
+func (storage *DurationFileStorage) ResetCounterReturningOld(ctx context.Context, name string) (Counter, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	old, err := ResetCounterReturningOld(ctx, storage.IStorage, name)
 	if err != nil {
+		return 0, err
+	}
+	return old, storage.logRecord(walRecord{Metrics: []walMetric{{Type: TypeCounter, Name: name, UpdatedAt: time.Now()}}})
+}
+
+// SetTTL устанавливает время устаревания метрик
+func (storage *DurationFileStorage) SetTTL(ttl time.Duration) {
+	if st, ok := storage.IStorage.(IExpiringStorage); ok {
+		st.SetTTL(ttl)
+	}
+}
+
+// DeleteExpired удаляет устаревшие метрики с записью снимка в случае синхронного режима.
+// Удалённые метрики в журнал не пишутся: при восстановлении из журнала они всё равно окажутся устаревшими
+func (storage *DurationFileStorage) DeleteExpired() (int, error) {
+	st, ok := storage.IStorage.(IExpiringStorage)
+	if !ok {
+		return 0, nil
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	deleted, err := st.DeleteExpired()
+	if err != nil || deleted == 0 || !storage.syncMode {
+		return deleted, err
+	}
+	return deleted, storage.flush()
+}
+
+// List список метрик из памяти
+func (storage *DurationFileStorage) List(query ListQuery) ([]ListedMetric, error) {
+	st, ok := storage.IStorage.(IListingStorage)
+	if !ok {
+		return nil, ErrorListNotSupported
+	}
+	return st.List(query)
+}
+
+// GetGaugesByNames значения gauge из памяти
+func (storage *DurationFileStorage) GetGaugesByNames(names []string) (map[string]Gauge, error) {
+	return GetGaugesByNames(storage.IStorage, names)
+}
+
+// GetCountersByNames значения counter из памяти
+func (storage *DurationFileStorage) GetCountersByNames(names []string) (map[string]Counter, error) {
+	return GetCountersByNames(storage.IStorage, names)
+}
+
+// Import загрузка метрик в память с записью в журнал
+func (storage *DurationFileStorage) Import(list []ListedMetric, replace bool) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if err := Import(storage.IStorage, list, replace); err != nil {
 		return err
 	}
-	if storage.syncMode {
-		if err = storage.Flush(); err != nil {
-			return err
+	now := time.Now()
+	record := walRecord{Clear: replace, Metrics: make([]walMetric, 0, len(list))}
+	for _, metric := range list {
+		logged := walMetric{
+			Type:      metric.Type,
+			Name:      metric.Name,
+			Gauge:     metric.Gauge,
+			Counter:   metric.Counter,
+			UpdatedAt: importedAt(metric, now),
 		}
+		switch metric.Type {
+		case TypeHistogram:
+			logged.Histogram = &metric.Histogram
+		case TypeSummary:
+			logged.Summary = &metric.Summary
+		case TypeSet:
+			logged.Set = &metric.Set
+		}
+		record.Metrics = append(record.Metrics, logged)
 	}
-	return nil
+	return storage.logRecord(record)
 }
 
-// AddCounters массовое обновление каунтер
-func (storage *DurationFileStorage) AddCounters(counters map[string]Counter) error {
-	err := storage.IStorage.AddCounters(counters)
+// AddHistogram прибавление гистограммы с записью в журнал гистограммы после прибавления
+func (storage *DurationFileStorage) AddHistogram(name string, histogram Histogram) error {
+	_, _, err := storage.AddHistogramReturningNew(context.Background(), name, histogram)
+	return err
+}
+
+// AddHistogramReturningNew прибавление гистограммы с записью в журнал, возвращает гистограммы до и после прибавления
+func (storage *DurationFileStorage) AddHistogramReturningNew(ctx context.Context, name string, histogram Histogram) (old *Histogram, updated Histogram, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if old, updated, err = AddHistogramReturningNew(ctx, storage.IStorage, name, histogram); err != nil {
+		return nil, Histogram{}, err
+	}
+	state := updated.Clone()
+	return old, updated, storage.logRecord(walRecord{Metrics: []walMetric{{Type: TypeHistogram, Name: name, UpdatedAt: time.Now(), Histogram: &state}}})
+}
+
+// AddSummary добавление наблюдений сводки с записью в журнал всех наблюдений сводки за окно
+func (storage *DurationFileStorage) AddSummary(name string, values []float64) error {
+	_, _, err := storage.AddSummaryReturningNew(context.Background(), name, values)
+	return err
+}
+
+// AddSummaryReturningNew добавление наблюдений сводки с записью в журнал, возвращает сводки за окно до и после добавления
+func (storage *DurationFileStorage) AddSummaryReturningNew(ctx context.Context, name string, values []float64) (old *SummaryValue, updated SummaryValue, err error) {
+	loader, ok := storage.IStorage.(distributionLoader)
+	if !ok {
+		return nil, SummaryValue{}, ErrorDistributionNotSupported
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if old, updated, err = AddSummaryReturningNew(ctx, storage.IStorage, name, values); err != nil {
+		return nil, SummaryValue{}, err
+	}
+	state, _ := loader.summaryObservations(name)
+	return old, updated, storage.logRecord(walRecord{Metrics: []walMetric{{Type: TypeSummary, Name: name, Summary: &state}}})
+}
+
+// GetHistogram гистограмма из памяти
+func (storage *DurationFileStorage) GetHistogram(name string) (Histogram, bool) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return Histogram{}, false
+	}
+	return st.GetHistogram(name)
+}
+
+// GetSummary сводка из памяти
+func (storage *DurationFileStorage) GetSummary(name string) (SummaryValue, bool) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return SummaryValue{}, false
+	}
+	return st.GetSummary(name)
+}
+
+// GetHistograms все гистограммы из памяти
+func (storage *DurationFileStorage) GetHistograms() (map[string]Histogram, error) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetHistograms()
+}
+
+// GetSummaries все сводки из памяти
+func (storage *DurationFileStorage) GetSummaries() (map[string]SummaryValue, error) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetSummaries()
+}
+
+// AddSet объединение скетча с сохранённым с записью в журнал скетча после объединения
+func (storage *DurationFileStorage) AddSet(name string, sketch Sketch) error {
+	_, _, err := storage.AddSetReturningNew(context.Background(), name, sketch)
+	return err
+}
+
+// AddSetReturningNew объединение скетча с сохранённым с записью в журнал, возвращает скетчи до и после объединения
+func (storage *DurationFileStorage) AddSetReturningNew(ctx context.Context, name string, sketch Sketch) (old *Sketch, updated Sketch, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if old, updated, err = AddSetReturningNew(ctx, storage.IStorage, name, sketch); err != nil {
+		return nil, Sketch{}, err
+	}
+	state := updated.Clone()
+	return old, updated, storage.logRecord(walRecord{Metrics: []walMetric{{Type: TypeSet, Name: name, UpdatedAt: time.Now(), Set: &state}}})
+}
+
+// GetSet скетч из памяти
+func (storage *DurationFileStorage) GetSet(name string) (Sketch, bool) {
+	return GetSetByName(storage.IStorage, name)
+}
+
+// GetSets все скетчи из памяти
+func (storage *DurationFileStorage) GetSets() (map[string]Sketch, error) {
+	return GetSets(storage.IStorage)
+}
+
+// SetMetadata объединение описания метрики с сохранённым с записью в журнал описания после объединения
+func (storage *DurationFileStorage) SetMetadata(key ListKey, metadata Metadata) error {
+	st, err := metadataStorage(storage.IStorage)
 	if err != nil {
 		return err
 	}
-	if storage.syncMode {
-		if err = storage.Flush(); err != nil {
-			return err
-		}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if err = st.SetMetadata(key, metadata); err != nil {
+		return err
 	}
-	return nil
+	state, ok := st.GetMetadata(key)
+	if !ok {
+		return nil
+	}
+	return storage.logRecord(walRecord{Metrics: []walMetric{{Type: key.Type, Name: key.Name, Metadata: &state}}})
+}
+
+// GetMetadata описание метрики из памяти
+func (storage *DurationFileStorage) GetMetadata(key ListKey) (Metadata, bool) {
+	return GetMetadataByKey(storage.IStorage, key)
+}
+
+// GetAllMetadata описания всех метрик из памяти
+func (storage *DurationFileStorage) GetAllMetadata() (map[ListKey]Metadata, error) {
+	return GetAllMetadata(storage.IStorage)
 }
 
 // NewFileStorage создание нового хранилища
-// filename - имя файла
-// restore - нужно ли загрузить инициализирующие данные из файла
+// filename - имя файла, журнал предзаписи хранится рядом в файле с суффиксом WALSuffix
+// restore - нужно ли загрузить инициализирующие данные из файла и журнала
 func NewFileStorage(filename string, restore bool, syncMode bool) (*DurationFileStorage, error) {
 	storage := NewMemStorage()
 	if restore {
@@ -150,11 +483,112 @@ func NewFileStorage(filename string, restore bool, syncMode bool) (*DurationFile
 	if err != nil {
 		return nil, err
 	}
-	return &DurationFileStorage{
+	wal, err := fileworker.OpenWAL(filename+WALSuffix, syncMode)
+	if err != nil {
+		return nil, err
+	}
+	fileStorage := &DurationFileStorage{
 		IStorage: storage,
 		writer:   writer,
+		wal:      wal,
 		syncMode: syncMode,
-	}, nil
+	}
+	if restore {
+		if err = restoreFromWAL(wal, storage); err != nil {
+			return nil, errors.Join(err, fileStorage.Close())
+		}
+	}
+	// Сворачиваем журнал в снимок, чтобы начать с пустого журнала. Без восстановления старые данные отбрасываются
+	if err = fileStorage.Flush(); err != nil {
+		return nil, errors.Join(err, fileStorage.Close())
+	}
+	return fileStorage, nil
+}
+
+// restoreFromWAL применение к хранилищу изменений из журнала. Обрезанная последняя запись отбрасывается
+func restoreFromWAL(wal *fileworker.WAL, storage IStorage) error {
+	count, truncated, err := wal.Replay(func(data []byte) error {
+		var record walRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		return applyWALRecord(storage, record)
+	})
+	if err != nil {
+		return err
+	}
+	if truncated {
+		logger.Log.Warn("Truncated write-ahead log record is discarded")
+	}
+	logger.Log.Infow("Store restored from write-ahead log", "records", count)
+	return nil
+}
+
+// applyWALRecord применение записи журнала к хранилищу
+func applyWALRecord(storage IStorage, record walRecord) error {
+	if record.Clear {
+		if err := Import(storage, nil, true); err != nil {
+			return err
+		}
+	}
+	list := make([]ListedMetric, 0, len(record.Metrics))
+	for _, metric := range record.Metrics {
+		if metric.Deleted {
+			if err := storage.Delete(metric.Type, metric.Name); err != nil && !errors.Is(err, ErrorMetricNotFound) {
+				return err
+			}
+			continue
+		}
+		if metric.Metadata != nil {
+			st, err := metadataStorage(storage)
+			if err != nil {
+				return err
+			}
+			if err = st.SetMetadata(ListKey{Type: metric.Type, Name: metric.Name}, *metric.Metadata); err != nil {
+				return err
+			}
+			continue
+		}
+		if metric.Set != nil {
+			loader, ok := storage.(setLoader)
+			if !ok {
+				return ErrorSetNotSupported
+			}
+			loader.loadSet(metric.Name, *metric.Set, metric.UpdatedAt)
+			continue
+		}
+		if metric.Histogram != nil || metric.Summary != nil {
+			if err := loadWALDistribution(storage, metric); err != nil {
+				return err
+			}
+			continue
+		}
+		list = append(list, ListedMetric{
+			Type:      metric.Type,
+			Name:      metric.Name,
+			Gauge:     metric.Gauge,
+			Counter:   metric.Counter,
+			UpdatedAt: metric.UpdatedAt,
+		})
+	}
+	return Import(storage, list, false)
+}
+
+// loadWALDistribution замена гистограммы или сводки значением из журнала
+func loadWALDistribution(storage IStorage, metric walMetric) error {
+	loader, ok := storage.(distributionLoader)
+	if !ok {
+		return ErrorDistributionNotSupported
+	}
+	if metric.Histogram != nil {
+		if err := metric.Histogram.Validate(); err != nil {
+			return err
+		}
+		loader.loadHistogram(metric.Name, *metric.Histogram, metric.UpdatedAt)
+		return nil
+	}
+	loader.loadSummary(metric.Name, *metric.Summary)
+	return nil
 }
 
 // restoreFromFile чтение данных из файла при инициализации хранилища
@@ -6,6 +6,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"gmetrics/internal/contextkeys"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -58,23 +59,24 @@ func BenchmarkFileStorage_SetMetrics(b *testing.B) {
 	}
 }
 
-func TestNewFileStorage(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
+// removeStorageFiles удаляет файл хранилища вместе с журналом предзаписи
+func removeStorageFiles(t *testing.T, filename string) {
+	for _, name := range []string{filename, filename + WALSuffix} {
+		if err := os.Remove(name); err != nil {
+			t.Errorf("Cant remove file %s", name)
 		}
-	}()
+	}
+}
+
+func TestNewFileStorage(t *testing.T) {
+	defer removeStorageFiles(t, "test.json")
 	memStore, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	assert.NotNil(t, memStore)
 }
 
 func TestFileStorage_SetGauge(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
-		}
-	}()
+	defer removeStorageFiles(t, "test.json")
 	memStore, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	if err != nil {
@@ -107,11 +109,7 @@ func TestFileStorage_SetGauge(t *testing.T) {
 }
 
 func TestFileStorage_AddCounter(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
-		}
-	}()
+	defer removeStorageFiles(t, "test.json")
 	memStore, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	if err != nil {
@@ -147,11 +145,7 @@ func TestFileStorage_AddCounter(t *testing.T) {
 }
 
 func TestFileStorage_GetGauge(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
-		}
-	}()
+	defer removeStorageFiles(t, "test.json")
 	memStore, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	if err != nil {
@@ -192,11 +186,7 @@ func TestFileStorage_GetGauge(t *testing.T) {
 }
 
 func TestFileStorage_GetCounter(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
-		}
-	}()
+	defer removeStorageFiles(t, "test.json")
 	memStore, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	if err != nil {
@@ -289,11 +279,7 @@ func TestFileStorage_RestoreFromFile(t *testing.T) {
 }
 
 func TestFileStorage_AddCounters(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
-		}
-	}()
+	defer removeStorageFiles(t, "test.json")
 	store, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	if err != nil {
@@ -348,11 +334,7 @@ func TestFileStorage_AddCounters(t *testing.T) {
 }
 
 func TestFileStorage_SetGauges(t *testing.T) {
-	defer func() {
-		if err := os.Remove("test.json"); err != nil {
-			t.Errorf("Cant remove file test.json")
-		}
-	}()
+	defer removeStorageFiles(t, "test.json")
 	store, err := NewFileStorage("test.json", true, true)
 	assert.NoError(t, err, "Cant create file storage")
 	if err != nil {
@@ -659,3 +641,299 @@ func TestDurationFileStorage_Sync(t *testing.T) {
 		})
 	}
 }
+
+func TestFileStorage_DeleteAndResetCounter(t *testing.T) {
+	testCases := []struct {
+		name       string
+		syncMode   bool
+		withoutWAL bool
+		wantSaved  bool
+	}{
+		{name: "sync_mode", syncMode: true, wantSaved: true},
+		// Изменения попадают в журнал и без синхронного режима
+		{name: "periodic_mode", syncMode: false, wantSaved: true},
+		{name: "without_wal", syncMode: false, withoutWAL: true, wantSaved: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.json")
+			store, err := NewFileStorage(path, false, tc.syncMode)
+			require.NoError(t, err)
+			require.NoError(t, store.SetGauge("gauge1", 1.5))
+			require.NoError(t, store.AddCounter("counter1", 3))
+			if tc.withoutWAL {
+				require.NoError(t, store.Flush())
+				require.NoError(t, store.wal.Close())
+				store.wal = nil
+			}
+
+			require.NoError(t, store.Delete(TypeGauge, "gauge1"))
+			require.NoError(t, store.ResetCounter("counter1"))
+			assert.ErrorIs(t, store.Delete(TypeGauge, "gauge1"), ErrorMetricNotFound)
+			assert.ErrorIs(t, store.ResetCounter("missing"), ErrorMetricNotFound)
+			require.NoError(t, store.Close())
+
+			restored, err := NewFileStorage(path, true, true)
+			require.NoError(t, err)
+			defer restored.Close()
+			_, gaugeOk := restored.GetGauge("gauge1")
+			counter, _ := restored.GetCounter("counter1")
+			if tc.wantSaved {
+				assert.False(t, gaugeOk)
+				assert.Equal(t, Counter(0), counter)
+			} else {
+				assert.True(t, gaugeOk)
+				assert.Equal(t, Counter(3), counter)
+			}
+		})
+	}
+}
+
+func TestFileStorage_DeleteExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := NewFileStorage(path, false, true)
+	require.NoError(t, err)
+	mem := store.IStorage.(*MemStorage)
+	now := time.Now()
+	mem.now = func() time.Time { return now }
+	store.SetTTL(time.Minute)
+	require.NoError(t, store.SetGauge("old", 1))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.SetGauge("fresh", 2))
+
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	require.NoError(t, store.Close())
+
+	restored, err := NewFileStorage(path, true, true)
+	require.NoError(t, err)
+	defer restored.Close()
+	gauges, err := restored.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"fresh": 2}, gauges)
+}
+
+func TestFileStorage_List(t *testing.T) {
+	store, err := NewFileStorage(filepath.Join(t.TempDir(), "storage.json"), false, true)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.SetGauge("Alloc", 1))
+	require.NoError(t, store.AddCounter("PollCount", 2))
+
+	list, err := store.List(ListQuery{Filter: ListFilter{Type: TypeCounter}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, ListKey{Type: TypeCounter, Name: "PollCount"}, list[0].Key())
+	assert.Equal(t, Counter(2), list[0].Counter)
+}
+
+func TestFileStorage_GetByNames(t *testing.T) {
+	store, err := NewFileStorage(filepath.Join(t.TempDir(), "storage.json"), false, true)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.SetGauge("Alloc", 1))
+	require.NoError(t, store.AddCounter("PollCount", 2))
+
+	gauges, err := store.GetGaugesByNames([]string{"Alloc", "PollCount"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 1}, gauges)
+	counters, err := store.GetCountersByNames([]string{"PollCount"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 2}, counters)
+}
+
+func TestDurationFileStorage_Import(t *testing.T) {
+	testCases := []struct {
+		name     string
+		syncMode bool
+	}{
+		{name: "sync_mode", syncMode: true},
+		{name: "periodic_mode", syncMode: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.json")
+			store, err := NewFileStorage(path, false, tc.syncMode)
+			require.NoError(t, err)
+			require.NoError(t, store.SetGauge("gauge1", 1.5))
+
+			require.NoError(t, store.Import([]ListedMetric{{Type: TypeCounter, Name: "counter1", Counter: 7}}, true))
+			_, gaugeOk := store.GetGauge("gauge1")
+			assert.False(t, gaugeOk)
+			require.NoError(t, store.Close())
+
+			restored, err := NewFileStorage(path, true, true)
+			require.NoError(t, err)
+			defer restored.Close()
+			counter, counterOk := restored.GetCounter("counter1")
+			assert.True(t, counterOk)
+			assert.Equal(t, Counter(7), counter)
+			_, gaugeOk = restored.GetGauge("gauge1")
+			assert.False(t, gaugeOk)
+		})
+	}
+}
+
+func TestNewFileStorage_RestoreFromWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := NewFileStorage(path, false, true)
+	require.NoError(t, err)
+	require.NoError(t, store.SetGauge("gauge1", 1.5))
+	require.NoError(t, store.Flush())
+	require.NoError(t, store.AddCounters(map[string]Counter{"counter1": 3, "counter2": 4}))
+	require.NoError(t, store.AddCounter("counter1", 2))
+	require.NoError(t, store.Delete(TypeCounter, "counter2"))
+	require.NoError(t, store.SetGauge("gauge2", 2.5))
+	// Сбой без записи снимка: последняя запись журнала дописана не до конца
+	require.NoError(t, store.Close())
+	info, err := os.Stat(path + WALSuffix)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path+WALSuffix, info.Size()-3))
+
+	restored, err := NewFileStorage(path, true, true)
+	require.NoError(t, err)
+	gauges, err := restored.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"gauge1": 1.5}, gauges)
+	counters, err := restored.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"counter1": 5}, counters)
+	// Журнал свёрнут в снимок при открытии
+	info, err = os.Stat(path + WALSuffix)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	require.NoError(t, restored.Close())
+
+	// Повторное восстановление из снимка даёт то же самое
+	again, err := NewFileStorage(path, true, true)
+	require.NoError(t, err)
+	defer again.Close()
+	counters, err = again.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"counter1": 5}, counters)
+}
+
+func TestFileStorage_Distributions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := NewFileStorage(path, false, true)
+	require.NoError(t, err)
+	histogram := NewHistogram([]float64{1, 5})
+	histogram.Observe(3)
+	require.NoError(t, store.AddHistogram("latency", histogram))
+	require.NoError(t, store.Flush())
+	require.NoError(t, store.AddHistogram("latency", histogram))
+	require.NoError(t, store.AddSummary("size", []float64{1, 2}))
+	require.NoError(t, store.AddSummary("size", []float64{3}))
+	assert.ErrorIs(t, store.AddHistogram("latency", NewHistogram([]float64{2})), ErrorHistogramBounds)
+	// Сбой без записи снимка: изменения восстанавливаются из журнала
+	require.NoError(t, store.Close())
+
+	restored, err := NewFileStorage(path, true, true)
+	require.NoError(t, err)
+	defer restored.Close()
+	got, ok := restored.GetHistogram("latency")
+	assert.True(t, ok)
+	assert.Equal(t, Histogram{Bounds: []float64{1, 5}, Counts: []uint64{0, 2, 0}, Sum: 6, Count: 2}, got)
+	summary, ok := restored.GetSummary("size")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), summary.Count)
+	histograms, err := restored.GetHistograms()
+	require.NoError(t, err)
+	assert.Len(t, histograms, 1)
+	summaries, err := restored.GetSummaries()
+	require.NoError(t, err)
+	assert.Len(t, summaries, 1)
+	require.NoError(t, restored.Delete(TypeHistogram, "latency"))
+	_, ok = restored.GetHistogram("latency")
+	assert.False(t, ok)
+}
+
+func TestFileStorage_Sets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := NewFileStorage(path, false, true)
+	require.NoError(t, err)
+	require.NoError(t, store.AddSet("users", NewSketch("a", "b")))
+	require.NoError(t, store.Flush())
+	require.NoError(t, store.AddSet("users", NewSketch("c")))
+	// Сбой без записи снимка: скетч восстанавливается из снимка и журнала
+	require.NoError(t, store.Close())
+
+	restored, err := NewFileStorage(path, true, true)
+	require.NoError(t, err)
+	defer restored.Close()
+	got, ok := restored.GetSet("users")
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), got.Estimate())
+	sets, err := restored.GetSets()
+	require.NoError(t, err)
+	assert.Len(t, sets, 1)
+	require.NoError(t, restored.Delete(TypeSet, "users"))
+	_, ok = restored.GetSet("users")
+	assert.False(t, ok)
+}
+
+func TestFileStorage_Metadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	key := ListKey{Type: TypeGauge, Name: "Alloc"}
+	store, err := NewFileStorage(path, false, true)
+	require.NoError(t, err)
+	require.NoError(t, store.SetMetadata(key, Metadata{Unit: "bytes"}))
+	require.NoError(t, store.Flush())
+	require.NoError(t, store.SetMetadata(key, Metadata{Help: "Allocated heap"}))
+	// Сбой без записи снимка: описание восстанавливается из снимка и журнала
+	require.NoError(t, store.Close())
+
+	restored, err := NewFileStorage(path, true, true)
+	require.NoError(t, err)
+	defer restored.Close()
+	metadata, ok := restored.GetMetadata(key)
+	require.True(t, ok)
+	assert.Equal(t, Metadata{Unit: "bytes", Help: "Allocated heap"}, metadata)
+	all, err := restored.GetAllMetadata()
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestApplyWALRecord(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	store := NewMemStorage()
+	require.NoError(t, store.SetGauge("gauge1", 1.5))
+	require.NoError(t, store.AddCounter("counter1", 3))
+
+	record := walRecord{Metrics: []walMetric{
+		{Type: TypeCounter, Name: "counter1", Counter: 5, UpdatedAt: updated},
+		{Type: TypeGauge, Name: "gauge1", Deleted: true},
+		{Type: TypeGauge, Name: "missing", Deleted: true},
+	}}
+	// Записи содержат итоговые значения, поэтому повторное применение ничего не меняет
+	require.NoError(t, applyWALRecord(store, record))
+	require.NoError(t, applyWALRecord(store, record))
+	assert.Equal(t, map[string]Counter{"counter1": 5}, store.Counter)
+	assert.Empty(t, store.Gauge)
+	assert.Equal(t, updated, store.CounterUpdated["counter1"])
+
+	require.NoError(t, applyWALRecord(store, walRecord{Clear: true, Metrics: []walMetric{{Type: TypeGauge, Name: "gauge2", Gauge: 2}}}))
+	assert.Equal(t, map[string]Gauge{"gauge2": 2}, store.Gauge)
+	assert.Empty(t, store.Counter)
+}
+
+func TestDurationFileStorage_CompactWAL(t *testing.T) {
+	defer func(size int64) {
+		WALCompactSize = size
+	}(WALCompactSize)
+	WALCompactSize = 100
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := NewFileStorage(path, false, false)
+	require.NoError(t, err)
+	defer store.Close()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.AddCounter("counter1", 1))
+		assert.LessOrEqual(t, store.wal.Size(), WALCompactSize)
+	}
+	restored, err := NewFileStorage(path, true, false)
+	require.NoError(t, err)
+	defer restored.Close()
+	counter, _ := restored.GetCounter("counter1")
+	assert.Equal(t, Counter(10), counter)
+}
@@ -1,12 +1,29 @@
 package metrics
 
-import "sync"
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
 
 // MemStorage Хранилище метрик в памяти
 type MemStorage struct {
-	Gauge   map[string]Gauge   `json:"gauge"`
-	Counter map[string]Counter `json:"counter"`
-	mutex   *sync.RWMutex
+	Gauge            map[string]Gauge     `json:"gauge"`
+	Counter          map[string]Counter   `json:"counter"`
+	GaugeUpdated     map[string]time.Time `json:"gauge_updated,omitempty"`   // Время последнего обновления gauge
+	CounterUpdated   map[string]time.Time `json:"counter_updated,omitempty"` // Время последнего обновления counter
+	Histogram        map[string]Histogram `json:"histogram,omitempty"`
+	HistogramUpdated map[string]time.Time `json:"histogram_updated,omitempty"` // Время последнего обновления гистограммы
+	Summary          map[string]Summary   `json:"summary,omitempty"`
+	Set              map[string]Sketch    `json:"set,omitempty"`
+	SetUpdated       map[string]time.Time `json:"set_updated,omitempty"` // Время последнего обновления множества
+	Metadata         map[ListKey]Metadata `json:"-"`                     // Описания метрик, в снимке записываются списком
+	mutex            *sync.RWMutex
+	ttl              time.Duration // Время, после которого не обновлявшаяся метрика устаревает; 0 - не устаревает
+	now              func() time.Time
 }
 
 // SetGauge устанавливаем gauge
@@ -20,6 +37,7 @@ func (storage *MemStorage) SetGauge(name string, value Gauge) error {
 // Предполагается, что вызывающая функция обрабатывает все необходимое управление параллелизмом.
 func (storage *MemStorage) unsafeSetGauge(name string, value Gauge) error {
 	storage.Gauge[name] = value
+	storage.GaugeUpdated[name] = storage.now()
 	return nil
 }
 
@@ -32,12 +50,14 @@ func (storage *MemStorage) AddCounter(name string, value Counter) error {
 
 // unsafeAddCounter устанавливает значение Counter для данного имени без какой-либо блокировки.
 // Предполагается, что вызывающая функция обрабатывает все необходимое управление параллелизмом.
+// Устаревший counter начинается заново, как будто его не было.
 func (storage *MemStorage) unsafeAddCounter(name string, value Counter) error {
 	oldValue, ok := storage.Counter[name]
-	if ok {
+	if ok && !storage.expired(storage.CounterUpdated[name]) {
 		value = oldValue.Add(value)
 	}
 	storage.Counter[name] = value
+	storage.CounterUpdated[name] = storage.now()
 	return nil
 }
 
@@ -46,6 +66,9 @@ func (storage *MemStorage) GetGauge(name string) (Gauge, bool) {
 	storage.mutex.RLock()
 	defer storage.mutex.RUnlock()
 	value, ok := storage.Gauge[name]
+	if ok && storage.expired(storage.GaugeUpdated[name]) {
+		return 0, false
+	}
 	return value, ok
 }
 
@@ -54,6 +77,9 @@ func (storage *MemStorage) GetCounter(name string) (Counter, bool) {
 	storage.mutex.RLock()
 	defer storage.mutex.RUnlock()
 	cValue, ok := storage.Counter[name]
+	if ok && storage.expired(storage.CounterUpdated[name]) {
+		return 0, false
+	}
 	return cValue, ok
 }
 
@@ -61,9 +87,18 @@ func (storage *MemStorage) GetCounter(name string) (Counter, bool) {
 func NewMemStorage() *MemStorage {
 	return &MemStorage{
 		//metrics: make(map[string]any),
-		Gauge:   make(map[string]Gauge),
-		Counter: make(map[string]Counter),
-		mutex:   new(sync.RWMutex),
+		Gauge:            make(map[string]Gauge),
+		Counter:          make(map[string]Counter),
+		GaugeUpdated:     make(map[string]time.Time),
+		CounterUpdated:   make(map[string]time.Time),
+		Histogram:        make(map[string]Histogram),
+		HistogramUpdated: make(map[string]time.Time),
+		Summary:          make(map[string]Summary),
+		Set:              make(map[string]Sketch),
+		SetUpdated:       make(map[string]time.Time),
+		Metadata:         make(map[ListKey]Metadata),
+		mutex:            new(sync.RWMutex),
+		now:              time.Now,
 	}
 }
 
@@ -73,7 +108,9 @@ func (storage *MemStorage) GetGauges() (map[string]Gauge, error) {
 	defer storage.mutex.RUnlock()
 	newMap := make(map[string]Gauge, len(storage.Gauge))
 	for k, v := range storage.Gauge {
-		newMap[k] = v
+		if !storage.expired(storage.GaugeUpdated[k]) {
+			newMap[k] = v
+		}
 	}
 	return newMap, nil
 }
@@ -84,11 +121,92 @@ func (storage *MemStorage) GetCounters() (map[string]Counter, error) {
 	defer storage.mutex.RUnlock()
 	newMap := make(map[string]Counter, len(storage.Counter))
 	for k, v := range storage.Counter {
-		newMap[k] = v
+		if !storage.expired(storage.CounterUpdated[k]) {
+			newMap[k] = v
+		}
 	}
 	return newMap, nil
 }
 
+// List список неустаревших метрик, подходящих под фильтр, в порядке сортировки запроса
+func (storage *MemStorage) List(query ListQuery) ([]ListedMetric, error) {
+	storage.mutex.RLock()
+	list := make([]ListedMetric, 0)
+	if query.Filter.MatchType(TypeGauge) {
+		for name, value := range storage.Gauge {
+			updated := storage.GaugeUpdated[name]
+			if !storage.expired(updated) && query.Filter.Match(TypeGauge, name) {
+				list = append(list, ListedMetric{Type: TypeGauge, Name: name, Gauge: value, UpdatedAt: updated})
+			}
+		}
+	}
+	if query.Filter.MatchType(TypeCounter) {
+		for name, value := range storage.Counter {
+			updated := storage.CounterUpdated[name]
+			if !storage.expired(updated) && query.Filter.Match(TypeCounter, name) {
+				list = append(list, ListedMetric{Type: TypeCounter, Name: name, Counter: value, UpdatedAt: updated})
+			}
+		}
+	}
+	if query.Filter.MatchType(TypeHistogram) {
+		for name, histogram := range storage.Histogram {
+			updated := storage.HistogramUpdated[name]
+			if !storage.expired(updated) && query.Filter.Match(TypeHistogram, name) {
+				list = append(list, ListedMetric{Type: TypeHistogram, Name: name, Histogram: histogram.Clone(), UpdatedAt: updated})
+			}
+		}
+	}
+	if query.Filter.MatchType(TypeSummary) {
+		for name, summary := range storage.Summary {
+			updated := summary.Updated()
+			if !storage.expired(updated) && query.Filter.Match(TypeSummary, name) {
+				list = append(list, ListedMetric{
+					Type:      TypeSummary,
+					Name:      name,
+					Summary:   Summary{Observations: slices.Clone(summary.Observations)},
+					UpdatedAt: updated,
+				})
+			}
+		}
+	}
+	if query.Filter.MatchType(TypeSet) {
+		for name, sketch := range storage.Set {
+			updated := storage.SetUpdated[name]
+			if !storage.expired(updated) && query.Filter.Match(TypeSet, name) {
+				list = append(list, ListedMetric{Type: TypeSet, Name: name, Set: sketch.Clone(), UpdatedAt: updated})
+			}
+		}
+	}
+	storage.mutex.RUnlock()
+	return query.page(list), nil
+}
+
+// GetGaugesByNames значения неустаревших gauge с указанными именами
+func (storage *MemStorage) GetGaugesByNames(names []string) (map[string]Gauge, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	gauges := make(map[string]Gauge, len(names))
+	for _, name := range names {
+		if value, ok := storage.Gauge[name]; ok && !storage.expired(storage.GaugeUpdated[name]) {
+			gauges[name] = value
+		}
+	}
+	return gauges, nil
+}
+
+// GetCountersByNames значения неустаревших counter с указанными именами
+func (storage *MemStorage) GetCountersByNames(names []string) (map[string]Counter, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	counters := make(map[string]Counter, len(names))
+	for _, name := range names {
+		if value, ok := storage.Counter[name]; ok && !storage.expired(storage.CounterUpdated[name]) {
+			counters[name] = value
+		}
+	}
+	return counters, nil
+}
+
 // SetGauges массовое обновление гауге в памяти
 func (storage *MemStorage) SetGauges(gauges map[string]Gauge) error {
 	storage.mutex.Lock()
@@ -112,3 +230,557 @@ func (storage *MemStorage) AddCounters(counters map[string]Counter) error {
 	}
 	return nil
 }
+
+// SetGaugesReturningOld массовое обновление гауге в памяти с прежними значениями неустаревших gauge
+func (storage *MemStorage) SetGaugesReturningOld(ctx context.Context, gauges map[string]Gauge) (map[string]Gauge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	old := make(map[string]Gauge, len(gauges))
+	for name, gauge := range gauges {
+		if value, ok := storage.Gauge[name]; ok && !storage.expired(storage.GaugeUpdated[name]) {
+			old[name] = value
+		}
+		if err := storage.unsafeSetGauge(name, gauge); err != nil {
+			return nil, err
+		}
+	}
+	return old, nil
+}
+
+// AddCountersReturningNew массовое обновление каунтер в памяти со значениями до и после прибавления
+func (storage *MemStorage) AddCountersReturningNew(ctx context.Context, counters map[string]Counter) (old, updated map[string]Counter, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	old = make(map[string]Counter, len(counters))
+	updated = make(map[string]Counter, len(counters))
+	for name, counter := range counters {
+		if value, ok := storage.Counter[name]; ok && !storage.expired(storage.CounterUpdated[name]) {
+			old[name] = value
+		}
+		if err = storage.unsafeAddCounter(name, counter); err != nil {
+			return nil, nil, err
+		}
+		updated[name] = storage.Counter[name]
+	}
+	return old, updated, nil
+}
+
+// Delete удаление метрики из памяти
+func (storage *MemStorage) Delete(metricType, name string) error {
+	_, err := storage.DeleteReturningOld(context.Background(), metricType, name)
+	return err
+}
+
+// DeleteReturningOld удаление метрики из памяти, возвращает удалённое значение
+func (storage *MemStorage) DeleteReturningOld(ctx context.Context, metricType, name string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	switch metricType {
+	case TypeGauge:
+		value, ok := storage.Gauge[name]
+		if !ok {
+			return nil, ErrorMetricNotFound
+		}
+		delete(storage.Gauge, name)
+		delete(storage.GaugeUpdated, name)
+		return value, nil
+	case TypeCounter:
+		value, ok := storage.Counter[name]
+		if !ok {
+			return nil, ErrorMetricNotFound
+		}
+		delete(storage.Counter, name)
+		delete(storage.CounterUpdated, name)
+		return value, nil
+	case TypeHistogram:
+		histogram, ok := storage.Histogram[name]
+		if !ok {
+			return nil, ErrorMetricNotFound
+		}
+		delete(storage.Histogram, name)
+		delete(storage.HistogramUpdated, name)
+		return histogram, nil
+	case TypeSummary:
+		summary, ok := storage.Summary[name]
+		if !ok {
+			return nil, ErrorMetricNotFound
+		}
+		delete(storage.Summary, name)
+		return summary.Value(storage.now(), DefaultSummaryWindow), nil
+	case TypeSet:
+		sketch, ok := storage.Set[name]
+		if !ok {
+			return nil, ErrorMetricNotFound
+		}
+		delete(storage.Set, name)
+		delete(storage.SetUpdated, name)
+		return sketch, nil
+	}
+	return nil, ErrorUnknownMetricType
+}
+
+// ResetCounter обнуление counter в памяти
+func (storage *MemStorage) ResetCounter(name string) error {
+	_, err := storage.ResetCounterReturningOld(context.Background(), name)
+	return err
+}
+
+// ResetCounterReturningOld обнуление counter в памяти, возвращает значение до обнуления
+func (storage *MemStorage) ResetCounterReturningOld(ctx context.Context, name string) (Counter, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	value, ok := storage.Counter[name]
+	if !ok || storage.expired(storage.CounterUpdated[name]) {
+		return 0, ErrorMetricNotFound
+	}
+	storage.Counter[name] = value.Clear()
+	storage.CounterUpdated[name] = storage.now()
+	return value, nil
+}
+
+// SetTTL устанавливает время, после которого не обновлявшаяся метрика скрывается из чтения
+func (storage *MemStorage) SetTTL(ttl time.Duration) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	storage.ttl = ttl
+}
+
+// DeleteExpired удаляет устаревшие метрики из памяти
+func (storage *MemStorage) DeleteExpired() (int, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if storage.ttl <= 0 {
+		return 0, nil
+	}
+	deleted := 0
+	for name := range storage.Gauge {
+		if storage.expired(storage.GaugeUpdated[name]) {
+			delete(storage.Gauge, name)
+			delete(storage.GaugeUpdated, name)
+			deleted++
+		}
+	}
+	for name := range storage.Counter {
+		if storage.expired(storage.CounterUpdated[name]) {
+			delete(storage.Counter, name)
+			delete(storage.CounterUpdated, name)
+			deleted++
+		}
+	}
+	for name := range storage.Histogram {
+		if storage.expired(storage.HistogramUpdated[name]) {
+			delete(storage.Histogram, name)
+			delete(storage.HistogramUpdated, name)
+			deleted++
+		}
+	}
+	for name, summary := range storage.Summary {
+		if storage.expired(summary.Updated()) {
+			delete(storage.Summary, name)
+			deleted++
+		}
+	}
+	for name := range storage.Set {
+		if storage.expired(storage.SetUpdated[name]) {
+			delete(storage.Set, name)
+			delete(storage.SetUpdated, name)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Import записывает метрики в память вместе со временем обновления. Если replace, то остальные метрики удаляются.
+// У сводки время обновления - время последнего наблюдения, поэтому наблюдения загружаются со своим временем
+func (storage *MemStorage) Import(list []ListedMetric, replace bool) error {
+	// Проверяем метрики до изменения, чтобы не загрузить список частично
+	if err := checkImported(list); err != nil {
+		return err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if replace {
+		clear(storage.Gauge)
+		clear(storage.GaugeUpdated)
+		clear(storage.Counter)
+		clear(storage.CounterUpdated)
+		clear(storage.Histogram)
+		clear(storage.HistogramUpdated)
+		clear(storage.Summary)
+		clear(storage.Set)
+		clear(storage.SetUpdated)
+	}
+	now := storage.now()
+	for _, metric := range list {
+		switch metric.Type {
+		case TypeGauge:
+			storage.Gauge[metric.Name] = metric.Gauge
+			storage.GaugeUpdated[metric.Name] = importedAt(metric, now)
+		case TypeCounter:
+			storage.Counter[metric.Name] = metric.Counter
+			storage.CounterUpdated[metric.Name] = importedAt(metric, now)
+		case TypeHistogram:
+			storage.Histogram[metric.Name] = metric.Histogram.Clone()
+			storage.HistogramUpdated[metric.Name] = importedAt(metric, now)
+		case TypeSummary:
+			storage.Summary[metric.Name] = Summary{}.Observe(metric.Summary.Observations, now, DefaultSummaryWindow)
+		case TypeSet:
+			storage.Set[metric.Name] = metric.Set.Clone()
+			storage.SetUpdated[metric.Name] = importedAt(metric, now)
+		}
+	}
+	return nil
+}
+
+// expired устарела ли метрика, обновлённая в updated
+func (storage *MemStorage) expired(updated time.Time) bool {
+	return storage.ttl > 0 && storage.now().Sub(updated) > storage.ttl
+}
+
+// memSnapshot снимок хранилища для записи в файл
+type memSnapshot struct {
+	Gauge            map[string]Gauge     `json:"gauge"`
+	Counter          map[string]Counter   `json:"counter"`
+	GaugeUpdated     map[string]time.Time `json:"gauge_updated,omitempty"`
+	CounterUpdated   map[string]time.Time `json:"counter_updated,omitempty"`
+	Histogram        map[string]Histogram `json:"histogram,omitempty"`
+	HistogramUpdated map[string]time.Time `json:"histogram_updated,omitempty"`
+	Summary          map[string]Summary   `json:"summary,omitempty"`
+	Set              map[string]Sketch    `json:"set,omitempty"`
+	SetUpdated       map[string]time.Time `json:"set_updated,omitempty"`
+	Metadata         []MetadataEntry      `json:"metadata,omitempty"`
+}
+
+// MarshalJSON снимок хранилища без устаревших метрик вместе со временем обновления
+func (storage *MemStorage) MarshalJSON() ([]byte, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	snapshot := memSnapshot{
+		Gauge:            make(map[string]Gauge, len(storage.Gauge)),
+		Counter:          make(map[string]Counter, len(storage.Counter)),
+		GaugeUpdated:     make(map[string]time.Time, len(storage.Gauge)),
+		CounterUpdated:   make(map[string]time.Time, len(storage.Counter)),
+		Histogram:        make(map[string]Histogram, len(storage.Histogram)),
+		HistogramUpdated: make(map[string]time.Time, len(storage.Histogram)),
+		Summary:          make(map[string]Summary, len(storage.Summary)),
+		Set:              make(map[string]Sketch, len(storage.Set)),
+		SetUpdated:       make(map[string]time.Time, len(storage.Set)),
+		Metadata:         MetadataEntries(storage.Metadata),
+	}
+	for name, value := range storage.Gauge {
+		if updated := storage.GaugeUpdated[name]; !storage.expired(updated) {
+			snapshot.Gauge[name] = value
+			snapshot.GaugeUpdated[name] = updated
+		}
+	}
+	for name, value := range storage.Counter {
+		if updated := storage.CounterUpdated[name]; !storage.expired(updated) {
+			snapshot.Counter[name] = value
+			snapshot.CounterUpdated[name] = updated
+		}
+	}
+	for name, histogram := range storage.Histogram {
+		if updated := storage.HistogramUpdated[name]; !storage.expired(updated) {
+			snapshot.Histogram[name] = histogram
+			snapshot.HistogramUpdated[name] = updated
+		}
+	}
+	for name, summary := range storage.Summary {
+		if !storage.expired(summary.Updated()) {
+			snapshot.Summary[name] = summary
+		}
+	}
+	for name, sketch := range storage.Set {
+		if updated := storage.SetUpdated[name]; !storage.expired(updated) {
+			snapshot.Set[name] = sketch
+			snapshot.SetUpdated[name] = updated
+		}
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalJSON восстановление хранилища из снимка.
+// Если в снимке нет времени обновления метрики, то она считается обновлённой в момент восстановления
+func (storage *MemStorage) UnmarshalJSON(data []byte) error {
+	var snapshot memSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	now := storage.now()
+	for name, value := range snapshot.Gauge {
+		storage.Gauge[name] = value
+		storage.GaugeUpdated[name] = now
+		if updated, ok := snapshot.GaugeUpdated[name]; ok {
+			storage.GaugeUpdated[name] = updated
+		}
+	}
+	for name, value := range snapshot.Counter {
+		storage.Counter[name] = value
+		storage.CounterUpdated[name] = now
+		if updated, ok := snapshot.CounterUpdated[name]; ok {
+			storage.CounterUpdated[name] = updated
+		}
+	}
+	for name, histogram := range snapshot.Histogram {
+		if err := histogram.Validate(); err != nil {
+			return err
+		}
+		storage.Histogram[name] = histogram
+		storage.HistogramUpdated[name] = now
+		if updated, ok := snapshot.HistogramUpdated[name]; ok {
+			storage.HistogramUpdated[name] = updated
+		}
+	}
+	for name, summary := range snapshot.Summary {
+		storage.Summary[name] = storage.Summary[name].Observe(summary.Observations, now, DefaultSummaryWindow)
+	}
+	for name, sketch := range snapshot.Set {
+		storage.Set[name] = sketch
+		storage.SetUpdated[name] = now
+		if updated, ok := snapshot.SetUpdated[name]; ok {
+			storage.SetUpdated[name] = updated
+		}
+	}
+	for _, entry := range snapshot.Metadata {
+		if err := entry.Metadata.Validate(); err != nil {
+			return err
+		}
+		storage.Metadata[ListKey{Type: entry.Type, Name: entry.Name}] = entry.Metadata
+	}
+	return nil
+}
+
+// AddHistogram прибавляет гистограмму к сохранённой. Устаревшая гистограмма начинается заново
+func (storage *MemStorage) AddHistogram(name string, histogram Histogram) error {
+	_, _, err := storage.AddHistogramReturningNew(context.Background(), name, histogram)
+	return err
+}
+
+// AddHistogramReturningNew прибавляет гистограмму к сохранённой, возвращает гистограммы до и после прибавления
+func (storage *MemStorage) AddHistogramReturningNew(ctx context.Context, name string, histogram Histogram) (old *Histogram, updated Histogram, err error) {
+	if err = histogram.Validate(); err != nil {
+		return nil, Histogram{}, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, Histogram{}, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if stored, ok := storage.Histogram[name]; ok && !storage.expired(storage.HistogramUpdated[name]) {
+		if updated, err = stored.Merge(histogram); err != nil {
+			return nil, Histogram{}, err
+		}
+		stored = stored.Clone()
+		old = &stored
+	} else {
+		updated = histogram.Clone()
+	}
+	storage.Histogram[name] = updated
+	storage.HistogramUpdated[name] = storage.now()
+	return old, updated.Clone(), nil
+}
+
+// AddSummary добавляет наблюдения в сводку. Устаревшая сводка начинается заново
+func (storage *MemStorage) AddSummary(name string, values []float64) error {
+	_, _, err := storage.AddSummaryReturningNew(context.Background(), name, values)
+	return err
+}
+
+// AddSummaryReturningNew добавляет наблюдения в сводку, возвращает сводки за окно до и после добавления
+func (storage *MemStorage) AddSummaryReturningNew(ctx context.Context, name string, values []float64) (old *SummaryValue, updated SummaryValue, err error) {
+	now := storage.now()
+	observations, err := NewObservations(values, now)
+	if err != nil {
+		return nil, SummaryValue{}, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, SummaryValue{}, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	summary, ok := storage.Summary[name]
+	if ok && !storage.expired(summary.Updated()) {
+		value := summary.Value(now, DefaultSummaryWindow)
+		old = &value
+	} else {
+		summary = Summary{}
+	}
+	summary = summary.Observe(observations, now, DefaultSummaryWindow)
+	storage.Summary[name] = summary
+	return old, summary.Value(now, DefaultSummaryWindow), nil
+}
+
+// GetHistogram получение отдельной гистограммы
+func (storage *MemStorage) GetHistogram(name string) (Histogram, bool) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	histogram, ok := storage.Histogram[name]
+	if !ok || storage.expired(storage.HistogramUpdated[name]) {
+		return Histogram{}, false
+	}
+	return histogram.Clone(), true
+}
+
+// GetSummary количество, сумма и квантили отдельной сводки
+func (storage *MemStorage) GetSummary(name string) (SummaryValue, bool) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	summary, ok := storage.Summary[name]
+	if !ok || storage.expired(summary.Updated()) {
+		return SummaryValue{}, false
+	}
+	return summary.Value(storage.now(), DefaultSummaryWindow), true
+}
+
+// GetHistograms получение всех гистограмм
+func (storage *MemStorage) GetHistograms() (map[string]Histogram, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	histograms := make(map[string]Histogram, len(storage.Histogram))
+	for name, histogram := range storage.Histogram {
+		if !storage.expired(storage.HistogramUpdated[name]) {
+			histograms[name] = histogram.Clone()
+		}
+	}
+	return histograms, nil
+}
+
+// GetSummaries количество, сумма и квантили всех сводок
+func (storage *MemStorage) GetSummaries() (map[string]SummaryValue, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	now := storage.now()
+	summaries := make(map[string]SummaryValue, len(storage.Summary))
+	for name, summary := range storage.Summary {
+		if !storage.expired(summary.Updated()) {
+			summaries[name] = summary.Value(now, DefaultSummaryWindow)
+		}
+	}
+	return summaries, nil
+}
+
+// loadHistogram замена гистограммы вместе со временем обновления
+func (storage *MemStorage) loadHistogram(name string, histogram Histogram, updated time.Time) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	storage.Histogram[name] = histogram.Clone()
+	storage.HistogramUpdated[name] = updated
+}
+
+// loadSummary замена наблюдений сводки, наблюдения старше окна отбрасываются
+func (storage *MemStorage) loadSummary(name string, summary Summary) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	storage.Summary[name] = Summary{}.Observe(summary.Observations, storage.now(), DefaultSummaryWindow)
+}
+
+// summaryObservations наблюдения неустаревшей сводки
+func (storage *MemStorage) summaryObservations(name string) (Summary, bool) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	summary, ok := storage.Summary[name]
+	if !ok || storage.expired(summary.Updated()) {
+		return Summary{}, false
+	}
+	return Summary{Observations: slices.Clone(summary.Observations)}, true
+}
+
+// AddSet объединяет скетч с сохранённым. Устаревшее множество начинается заново
+func (storage *MemStorage) AddSet(name string, sketch Sketch) error {
+	_, _, err := storage.AddSetReturningNew(context.Background(), name, sketch)
+	return err
+}
+
+// AddSetReturningNew объединяет скетч с сохранённым, возвращает скетчи до и после объединения
+func (storage *MemStorage) AddSetReturningNew(ctx context.Context, name string, sketch Sketch) (old *Sketch, updated Sketch, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, Sketch{}, err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	if stored, ok := storage.Set[name]; ok && !storage.expired(storage.SetUpdated[name]) {
+		updated = stored.Merge(sketch)
+		stored = stored.Clone()
+		old = &stored
+	} else {
+		updated = sketch.Clone()
+	}
+	storage.Set[name] = updated
+	storage.SetUpdated[name] = storage.now()
+	return old, updated.Clone(), nil
+}
+
+// GetSet получение отдельного скетча
+func (storage *MemStorage) GetSet(name string) (Sketch, bool) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	sketch, ok := storage.Set[name]
+	if !ok || storage.expired(storage.SetUpdated[name]) {
+		return Sketch{}, false
+	}
+	return sketch.Clone(), true
+}
+
+// GetSets получение всех скетчей
+func (storage *MemStorage) GetSets() (map[string]Sketch, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	sets := make(map[string]Sketch, len(storage.Set))
+	for name, sketch := range storage.Set {
+		if !storage.expired(storage.SetUpdated[name]) {
+			sets[name] = sketch.Clone()
+		}
+	}
+	return sets, nil
+}
+
+// loadSet замена скетча вместе со временем обновления
+func (storage *MemStorage) loadSet(name string, sketch Sketch, updated time.Time) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	storage.Set[name] = sketch.Clone()
+	storage.SetUpdated[name] = updated
+}
+
+// SetMetadata объединяет описание метрики с сохранённым. Пустое описание не сохраняется
+func (storage *MemStorage) SetMetadata(key ListKey, metadata Metadata) error {
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	merged := storage.Metadata[key].Merge(metadata)
+	if !merged.Empty() {
+		storage.Metadata[key] = merged
+	}
+	return nil
+}
+
+// GetMetadata описание отдельной метрики
+func (storage *MemStorage) GetMetadata(key ListKey) (Metadata, bool) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	metadata, ok := storage.Metadata[key]
+	return metadata, ok
+}
+
+// GetAllMetadata описания всех метрик
+func (storage *MemStorage) GetAllMetadata() (map[ListKey]Metadata, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	return maps.Clone(storage.Metadata), nil
+}
@@ -1,9 +1,12 @@
 package metrics
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -394,3 +397,371 @@ func TestMemStorage_GetCounters(t *testing.T) {
 	assert.True(t, ok, "expected counter2 to be set")
 	assert.Equal(t, c2, Counter(84), "expected counter2 = 84, got %v", c1)
 }
+
+func TestMemStorage_Delete(t *testing.T) {
+	testCases := []struct {
+		name       string
+		metricType string
+		metricName string
+		wantErr    error
+	}{
+		{name: "gauge", metricType: TypeGauge, metricName: "gauge1"},
+		{name: "counter", metricType: TypeCounter, metricName: "counter1"},
+		{name: "gauge_not_found", metricType: TypeGauge, metricName: "counter1", wantErr: ErrorMetricNotFound},
+		{name: "counter_not_found", metricType: TypeCounter, metricName: "gauge1", wantErr: ErrorMetricNotFound},
+		{name: "unknown_type", metricType: "timer", metricName: "gauge1", wantErr: ErrorUnknownMetricType},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewMemStorage()
+			require.NoError(t, store.SetGauge("gauge1", 1.5))
+			require.NoError(t, store.AddCounter("counter1", 3))
+
+			err := store.Delete(tc.metricType, tc.metricName)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Len(t, store.Gauge, 1)
+				assert.Len(t, store.Counter, 1)
+				return
+			}
+			assert.NoError(t, err)
+			_, gaugeOk := store.GetGauge(tc.metricName)
+			_, counterOk := store.GetCounter(tc.metricName)
+			assert.False(t, gaugeOk || counterOk)
+		})
+	}
+}
+
+func TestMemStorage_ResetCounter(t *testing.T) {
+	store := NewMemStorage()
+	require.NoError(t, store.AddCounter("counter1", 3))
+
+	assert.NoError(t, store.ResetCounter("counter1"))
+	value, ok := store.GetCounter("counter1")
+	assert.True(t, ok)
+	assert.Equal(t, Counter(0), value)
+
+	assert.ErrorIs(t, store.ResetCounter("missing"), ErrorMetricNotFound)
+}
+
+// newTTLStorage хранилище с устареванием и управляемым временем
+func newTTLStorage(ttl time.Duration, now *time.Time) *MemStorage {
+	store := NewMemStorage()
+	store.now = func() time.Time { return *now }
+	store.SetTTL(ttl)
+	return store
+}
+
+func TestMemStorage_TTL(t *testing.T) {
+	now := time.Now()
+	store := newTTLStorage(time.Minute, &now)
+	require.NoError(t, store.SetGauge("old_gauge", 1))
+	require.NoError(t, store.AddCounter("old_counter", 5))
+	now = now.Add(45 * time.Second)
+	require.NoError(t, store.SetGauge("fresh_gauge", 2))
+	require.NoError(t, store.AddCounter("fresh_counter", 7))
+
+	// Через полторы минуты первые метрики устарели
+	now = now.Add(45 * time.Second)
+	_, ok := store.GetGauge("old_gauge")
+	assert.False(t, ok)
+	_, ok = store.GetCounter("old_counter")
+	assert.False(t, ok)
+	value, ok := store.GetGauge("fresh_gauge")
+	assert.True(t, ok)
+	assert.Equal(t, Gauge(2), value)
+
+	gauges, err := store.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"fresh_gauge": 2}, gauges)
+	counters, err := store.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"fresh_counter": 7}, counters)
+	assert.ErrorIs(t, store.ResetCounter("old_counter"), ErrorMetricNotFound)
+
+	// Устаревший counter начинается заново
+	require.NoError(t, store.AddCounter("old_counter", 1))
+	counter, ok := store.GetCounter("old_counter")
+	assert.True(t, ok)
+	assert.Equal(t, Counter(1), counter)
+
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.NotContains(t, store.Gauge, "old_gauge")
+	assert.NotContains(t, store.GaugeUpdated, "old_gauge")
+}
+
+func TestMemStorage_TTLDisabled(t *testing.T) {
+	now := time.Now()
+	store := newTTLStorage(0, &now)
+	require.NoError(t, store.SetGauge("gauge", 1))
+	now = now.Add(24 * time.Hour)
+	_, ok := store.GetGauge("gauge")
+	assert.True(t, ok)
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestMemStorage_JSON(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTTLStorage(time.Minute, &now)
+	require.NoError(t, store.SetGauge("old_gauge", 1))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.SetGauge("gauge", 2))
+	require.NoError(t, store.AddCounter("counter", 3))
+
+	// Устаревшие метрики не попадают в снимок
+	body, err := json.Marshal(store)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"gauge": {"gauge": 2},
+		"counter": {"counter": 3},
+		"gauge_updated": {"gauge": "2024-01-01T12:02:00Z"},
+		"counter_updated": {"counter": "2024-01-01T12:02:00Z"}
+	}`, string(body))
+
+	restoreTime := now.Add(time.Hour)
+	restored := newTTLStorage(0, &restoreTime)
+	require.NoError(t, json.Unmarshal(body, restored))
+	assert.Equal(t, now, restored.GaugeUpdated["gauge"])
+
+	// В снимке старого формата нет времени обновления
+	legacy := newTTLStorage(0, &restoreTime)
+	require.NoError(t, json.Unmarshal([]byte(`{"gauge":{"gauge":2},"counter":{"counter":3}}`), legacy))
+	assert.Equal(t, restoreTime, legacy.GaugeUpdated["gauge"])
+	assert.Equal(t, restoreTime, legacy.CounterUpdated["counter"])
+	assert.Error(t, json.Unmarshal([]byte(`{"gauge":[]}`), legacy))
+}
+
+func TestMemStorage_List(t *testing.T) {
+	now := time.Now()
+	store := newTTLStorage(time.Minute, &now)
+	require.NoError(t, store.SetGauge("old", 1))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.SetGauge("Alloc", 2))
+	require.NoError(t, store.SetGauge(`requests{method="GET"}`, 3))
+	require.NoError(t, store.AddCounter(`requests{method="GET"}`, 4))
+	require.NoError(t, store.AddCounter(`requests{method="POST"}`, 5))
+
+	// Устаревшие метрики не попадают в список, одинаковые имена упорядочены по типу
+	list, err := store.List(ListQuery{Sort: ListSortName})
+	require.NoError(t, err)
+	assert.Equal(t, []ListedMetric{
+		{Type: TypeGauge, Name: "Alloc", Gauge: 2, UpdatedAt: now},
+		{Type: TypeCounter, Name: `requests{method="GET"}`, Counter: 4, UpdatedAt: now},
+		{Type: TypeGauge, Name: `requests{method="GET"}`, Gauge: 3, UpdatedAt: now},
+		{Type: TypeCounter, Name: `requests{method="POST"}`, Counter: 5, UpdatedAt: now},
+	}, list)
+
+	method, err := ParseLabelMatcher("method=GET")
+	require.NoError(t, err)
+	list, err = store.List(ListQuery{
+		Filter: ListFilter{Type: TypeCounter, Labels: []LabelMatcher{method}},
+		Sort:   ListSortName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []ListedMetric{{Type: TypeCounter, Name: `requests{method="GET"}`, Counter: 4, UpdatedAt: now}}, list)
+
+	list, err = store.List(ListQuery{Sort: ListSortName, After: &ListKey{Type: TypeGauge, Name: "Alloc"}, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []ListedMetric{{Type: TypeCounter, Name: `requests{method="GET"}`, Counter: 4, UpdatedAt: now}}, list)
+}
+
+func TestMemStorage_GetByNames(t *testing.T) {
+	now := time.Now()
+	store := newTTLStorage(time.Minute, &now)
+	require.NoError(t, store.SetGauge("old", 1))
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.SetGauge("Alloc", 2))
+	require.NoError(t, store.AddCounter("PollCount", 3))
+
+	gauges, err := store.GetGaugesByNames([]string{"Alloc", "old", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 2}, gauges)
+	counters, err := store.GetCountersByNames([]string{"PollCount", "Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 3}, counters)
+}
+
+func TestMemStorage_Import(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updated := now.Add(-time.Hour)
+	list := []ListedMetric{
+		{Type: TypeGauge, Name: "gauge1", Gauge: 2.5, UpdatedAt: updated},
+		{Type: TypeCounter, Name: "counter2", Counter: 7},
+		{Type: TypeSet, Name: "visitors", Set: NewSketch("alice", "bob")},
+	}
+	testCases := []struct {
+		name    string
+		list    []ListedMetric
+		replace bool
+		wantLen int
+		wantErr error
+	}{
+		{name: "merge", list: list, wantLen: 5},
+		{name: "replace", list: list, replace: true, wantLen: 3},
+		{name: "unknown_type", list: append([]ListedMetric{{Type: "unknown", Name: "u"}}, list...), replace: true, wantLen: 3, wantErr: ErrorUnknownMetricType},
+		{
+			name:    "wrong_summary",
+			list:    []ListedMetric{{Type: TypeSummary, Name: "size", Summary: Summary{Observations: []Observation{{Value: math.NaN()}}}}},
+			wantLen: 3,
+			wantErr: ErrorWrongObservation,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTTLStorage(0, &now)
+			require.NoError(t, store.SetGauge("gauge1", 1.5))
+			require.NoError(t, store.AddCounter("counter1", 3))
+			require.NoError(t, store.AddSet("users", NewSketch("alice")))
+
+			err := store.Import(tc.list, tc.replace)
+			got, lErr := store.List(ListQuery{Sort: ListSortName})
+			require.NoError(t, lErr)
+			assert.Len(t, got, tc.wantLen)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			gauge, _ := store.GetGauge("gauge1")
+			assert.Equal(t, Gauge(2.5), gauge)
+			assert.Equal(t, updated, store.GaugeUpdated["gauge1"])
+			assert.Equal(t, now, store.CounterUpdated["counter2"])
+			visitors, _ := store.GetSet("visitors")
+			assert.Equal(t, uint64(2), visitors.Estimate())
+			// Замена удаляет метрики всех типов
+			_, ok := store.GetSet("users")
+			assert.Equal(t, !tc.replace, ok)
+		})
+	}
+}
+
+func TestMemStorage_Distributions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTTLStorage(time.Hour, &now)
+	histogram := NewHistogram([]float64{1, 10})
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	require.NoError(t, store.AddHistogram("latency", histogram))
+	require.NoError(t, store.AddHistogram("latency", histogram))
+	assert.ErrorIs(t, store.AddHistogram("latency", NewHistogram([]float64{2})), ErrorHistogramBounds)
+	assert.ErrorIs(t, store.AddHistogram("latency", Histogram{}), ErrorWrongHistogram)
+	got, ok := store.GetHistogram("latency")
+	require.True(t, ok)
+	assert.Equal(t, Histogram{Bounds: []float64{1, 10}, Counts: []uint64{2, 2, 0}, Sum: 11, Count: 4}, got)
+	// Полученная гистограмма не разделяет память с хранилищем
+	got.Counts[0] = 100
+	histograms, err := store.GetHistograms()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), histograms["latency"].Counts[0])
+
+	require.NoError(t, store.AddSummary("size", []float64{1, 2, 3}))
+	assert.ErrorIs(t, store.AddSummary("size", []float64{math.Inf(1)}), ErrorWrongObservation)
+	summary, ok := store.GetSummary("size")
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), summary.Count)
+	assert.Equal(t, float64(6), summary.Sum)
+	summaries, err := store.GetSummaries()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]SummaryValue{"size": summary}, summaries)
+
+	// Снимок сохраняет гистограммы и наблюдения сводок
+	body, err := json.Marshal(store)
+	require.NoError(t, err)
+	restored := newTTLStorage(0, &now)
+	require.NoError(t, json.Unmarshal(body, restored))
+	restoredHistogram, ok := restored.GetHistogram("latency")
+	assert.True(t, ok)
+	assert.Equal(t, histograms["latency"], restoredHistogram)
+	restoredSummary, ok := restored.GetSummary("size")
+	assert.True(t, ok)
+	assert.Equal(t, summary, restoredSummary)
+
+	// Устаревшие гистограммы и сводки удаляются вместе с остальными метриками
+	now = now.Add(2 * time.Hour)
+	_, ok = store.GetHistogram("latency")
+	assert.False(t, ok)
+	_, ok = store.GetSummary("size")
+	assert.False(t, ok)
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	require.NoError(t, restored.Delete(TypeHistogram, "latency"))
+	require.NoError(t, restored.Delete(TypeSummary, "size"))
+	assert.ErrorIs(t, restored.Delete(TypeSummary, "size"), ErrorMetricNotFound)
+	assert.ErrorIs(t, restored.Delete(TypeHistogram, "latency"), ErrorMetricNotFound)
+}
+
+func TestMemStorage_Sets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTTLStorage(time.Hour, &now)
+	sketch := NewSketch("a", "b")
+	require.NoError(t, store.AddSet("users", sketch))
+	require.NoError(t, store.AddSet("users", NewSketch("b", "c")))
+	got, ok := store.GetSet("users")
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), got.Estimate())
+	// Переданный скетч не разделяет память с хранилищем
+	sketch.Add("d")
+	got, _ = store.GetSet("users")
+	assert.Equal(t, uint64(3), got.Estimate())
+
+	// Снимок сохраняет скетчи вместе со временем обновления
+	body, err := json.Marshal(store)
+	require.NoError(t, err)
+	restored := newTTLStorage(time.Hour, &now)
+	require.NoError(t, json.Unmarshal(body, restored))
+	sets, err := restored.GetSets()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Sketch{"users": got}, sets)
+	assert.Equal(t, now, restored.SetUpdated["users"])
+
+	// Устаревшее множество начинается заново
+	now = now.Add(2 * time.Hour)
+	_, ok = store.GetSet("users")
+	assert.False(t, ok)
+	require.NoError(t, restored.AddSet("users", NewSketch("z")))
+	got, _ = restored.GetSet("users")
+	assert.Equal(t, uint64(1), got.Estimate())
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	require.NoError(t, restored.Delete(TypeSet, "users"))
+	assert.ErrorIs(t, restored.Delete(TypeSet, "users"), ErrorMetricNotFound)
+}
+
+func TestMemStorage_Metadata(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTTLStorage(time.Hour, &now)
+	alloc := ListKey{Type: TypeGauge, Name: "Alloc"}
+	polls := ListKey{Type: TypeCounter, Name: "PollCount"}
+	require.NoError(t, store.SetGauge("Alloc", 1))
+	require.NoError(t, store.SetMetadata(alloc, Metadata{Unit: "bytes", Help: "Allocated heap"}))
+	require.NoError(t, store.SetMetadata(polls, Metadata{Owner: "agent"}))
+	assert.ErrorIs(t, store.SetMetadata(alloc, Metadata{Unit: "\n"}), ErrorWrongMetadata)
+
+	// Снимок сохраняет описания списком, отсортированным по имени
+	body, err := json.Marshal(store)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"metadata":[{"type":"gauge","name":"Alloc","unit":"bytes","help":"Allocated heap"},{"type":"counter","name":"PollCount","owner":"agent"}]`)
+	restored := newTTLStorage(0, &now)
+	require.NoError(t, json.Unmarshal(body, restored))
+	all, err := restored.GetAllMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, map[ListKey]Metadata{alloc: {Unit: "bytes", Help: "Allocated heap"}, polls: {Owner: "agent"}}, all)
+
+	// Описания не устаревают и не удаляются вместе с метрикой
+	now = now.Add(2 * time.Hour)
+	_, err = store.DeleteExpired()
+	require.NoError(t, err)
+	metadata, ok := store.GetMetadata(alloc)
+	assert.True(t, ok)
+	assert.Equal(t, "bytes", metadata.Unit)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"metadata":[{"type":"gauge","name":"Alloc","unit":"a\nb"}]}`), NewMemStorage()))
+}
@@ -7,8 +7,11 @@ import (
 )
 
 const (
-	TypeGauge   = "gauge"
-	TypeCounter = "counter"
+	TypeGauge     = "gauge"
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
+	TypeSummary   = "summary"
+	TypeSet       = "set"
 )
 
 var (
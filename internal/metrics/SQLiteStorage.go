@@ -0,0 +1,20 @@
+package metrics
+
+import "context"
+
+// SQLiteStorage хранилище метрик во встроенной бд SQLite. Работает так же, как DBStorage,
+// и отличается только диалектом SQL, поэтому подходит для небольших установок без сервера Postgres
+type SQLiteStorage struct {
+	*DBStorage
+}
+
+// NewSQLiteStorage создание нового хранилища в бд SQLite
+func NewSQLiteStorage(ctx context.Context, db SQLExecutor, restore bool, syncMode bool) (*SQLiteStorage, error) {
+	return NewTenantSQLiteStorage(ctx, db, "", restore, syncMode)
+}
+
+// NewTenantSQLiteStorage создание хранилища метрик тенанта в бд SQLite
+func NewTenantSQLiteStorage(ctx context.Context, db SQLExecutor, tenant string, restore bool, syncMode bool) (*SQLiteStorage, error) {
+	storage, err := newDBStorage(ctx, db, sqliteDialect, tenant, restore, syncMode)
+	return &SQLiteStorage{DBStorage: storage}, err
+}
@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteDB бд SQLite в памяти со схемой хранилища
+func newSQLiteDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	// Бд в памяти своя у каждого соединения
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	for _, query := range []string{
+		"CREATE TABLE t_gauge (tenant TEXT NOT NULL DEFAULT '', name TEXT NOT NULL, value DOUBLE PRECISION, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, name))",
+		"CREATE TABLE t_counter (tenant TEXT NOT NULL DEFAULT '', name TEXT NOT NULL, value BIGINT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, name))",
+		"CREATE TABLE t_histogram (tenant TEXT NOT NULL DEFAULT '', name TEXT NOT NULL, data TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, name))",
+		"CREATE TABLE t_summary (tenant TEXT NOT NULL DEFAULT '', name TEXT NOT NULL, data TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, name))",
+		"CREATE TABLE t_set (tenant TEXT NOT NULL DEFAULT '', name TEXT NOT NULL, data BLOB NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, name))",
+		"CREATE TABLE t_metadata (tenant TEXT NOT NULL DEFAULT '', type TEXT NOT NULL, name TEXT NOT NULL, unit TEXT NOT NULL DEFAULT '', help TEXT NOT NULL DEFAULT '', owner TEXT NOT NULL DEFAULT '', created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (tenant, type, name))",
+	} {
+		_, err = db.Exec(query)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	db := NewDBAdapter(newSQLiteDB(t))
+	store, err := NewSQLiteStorage(context.Background(), db, false, true)
+	require.NoError(t, err)
+	require.NoError(t, store.SetGauge("Alloc", 1.5))
+	require.NoError(t, store.SetGauges(map[string]Gauge{"alloc": 2.5, "Heap": 3}))
+	require.NoError(t, store.AddCounter("PollCount", 3))
+	require.NoError(t, store.AddCounters(map[string]Counter{"PollCount": 2, "Requests": 7}))
+	require.NoError(t, store.ResetCounter("Requests"))
+	require.NoError(t, store.Delete(TypeGauge, "Heap"))
+	assert.ErrorIs(t, store.Delete(TypeGauge, "Heap"), ErrorMetricNotFound)
+
+	// Новое хранилище восстанавливается из бд
+	restored, err := NewSQLiteStorage(context.Background(), db, true, true)
+	require.NoError(t, err)
+	gauges, err := restored.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 1.5, "alloc": 2.5}, gauges)
+	counters, err := restored.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 5, "Requests": 0}, counters)
+
+	// Префикс различает регистр, а порядок побайтовый, как в памяти
+	list, err := restored.List(ListQuery{Filter: ListFilter{Prefix: "A"}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, ListKey{Type: TypeGauge, Name: "Alloc"}, list[0].Key())
+	assert.False(t, list[0].UpdatedAt.IsZero())
+
+	page, err := restored.List(ListQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, []string{"Alloc", "PollCount"}, []string{page[0].Name, page[1].Name})
+	after := page[1].Key()
+	page, err = restored.List(ListQuery{Limit: 2, After: &after})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, []string{"Requests", "alloc"}, []string{page[0].Name, page[1].Name})
+
+	// Метрики, которых нет в памяти, выбираются из бд
+	empty, err := NewSQLiteStorage(context.Background(), db, false, true)
+	require.NoError(t, err)
+	require.NoError(t, empty.Import([]ListedMetric{{Type: TypeGauge, Name: "Alloc", Gauge: 4.5}}, false))
+	empty.IStorage = NewMemStorage()
+	found, err := empty.GetGaugesByNames([]string{"Alloc", "Missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 4.5}, found)
+}
+
+func TestSQLiteStorage_Tenants(t *testing.T) {
+	db := NewDBAdapter(newSQLiteDB(t))
+	ctx := context.Background()
+	shared, err := NewSQLiteStorage(ctx, db, false, true)
+	require.NoError(t, err)
+	teamA, err := NewTenantSQLiteStorage(ctx, db, "team-a", false, true)
+	require.NoError(t, err)
+	teamB, err := NewTenantSQLiteStorage(ctx, db, "team-b", false, true)
+	require.NoError(t, err)
+
+	// Одно имя метрики у разных тенантов хранится отдельно
+	require.NoError(t, shared.SetGauge("Alloc", 1))
+	require.NoError(t, teamA.SetGauge("Alloc", 2))
+	require.NoError(t, teamA.AddCounter("PollCount", 3))
+	require.NoError(t, teamB.AddCounter("PollCount", 5))
+	require.NoError(t, teamA.SetMetadata(ListKey{Type: TypeGauge, Name: "Alloc"}, Metadata{Unit: "bytes"}))
+
+	restored, err := NewTenantSQLiteStorage(ctx, db, "team-a", true, true)
+	require.NoError(t, err)
+	gauges, err := restored.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 2}, gauges)
+	counters, err := restored.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 3}, counters)
+	metadata, err := restored.GetAllMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, map[ListKey]Metadata{{Type: TypeGauge, Name: "Alloc"}: {Unit: "bytes"}}, metadata)
+
+	list, err := teamB.list(ctx, ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, ListKey{Type: TypeCounter, Name: "PollCount"}, list[0].Key())
+	assert.Equal(t, Counter(5), list[0].Counter)
+
+	// Замена метрик тенанта не трогает метрики других тенантов
+	require.NoError(t, teamA.Import([]ListedMetric{{Type: TypeGauge, Name: "Heap", Gauge: 4}}, true))
+	list, err = teamA.list(ctx, ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "Heap", list[0].Name)
+	gauges, err = queryValues[Gauge](ctx, teamA.DBStorage, "t_gauge", []string{"Heap", "Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Heap": 4}, gauges)
+	gauges, err = queryValues[Gauge](ctx, shared.DBStorage, "t_gauge", []string{"Heap", "Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 1}, gauges)
+
+	// Тенант передаётся параметром, поэтому кавычки в имени не меняют запрос
+	quoted, err := NewTenantSQLiteStorage(ctx, db, "o'hara' OR ''='", false, true)
+	require.NoError(t, err)
+	require.NoError(t, quoted.SetGauge("Alloc", 7))
+	require.NoError(t, quoted.Import(nil, true))
+	gauges, err = queryValues[Gauge](ctx, shared.DBStorage, "t_gauge", []string{"Heap", "Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 1}, gauges)
+	list, err = quoted.list(ctx, ListQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestSQLiteStorage_ImportAndExpire(t *testing.T) {
+	db := NewDBAdapter(newSQLiteDB(t))
+	store, err := NewSQLiteStorage(context.Background(), db, false, true)
+	require.NoError(t, err)
+	require.NoError(t, store.SetGauge("Alloc", 1.5))
+
+	updated := time.Now().Add(-time.Hour).Truncate(time.Microsecond).UTC()
+	require.NoError(t, store.Import([]ListedMetric{
+		{Type: TypeGauge, Name: "Old", Gauge: 1, UpdatedAt: updated},
+		{Type: TypeCounter, Name: "Fresh", Counter: 2},
+	}, true))
+	list, err := store.list(context.Background(), ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, ListKey{Type: TypeCounter, Name: "Fresh"}, list[0].Key())
+	assert.Equal(t, updated, list[1].UpdatedAt)
+
+	store.SetTTL(time.Minute)
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	list, err = store.list(context.Background(), ListQuery{})
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestSQLiteStorage_ImportDistributions(t *testing.T) {
+	db := NewDBAdapter(newSQLiteDB(t))
+	ctx := context.Background()
+	store, err := NewSQLiteStorage(ctx, db, false, true)
+	require.NoError(t, err)
+	require.NoError(t, store.AddSet("Users", NewSketch("alice")))
+
+	latency := NewHistogram([]float64{1})
+	latency.Observe(0.5)
+	size := Summary{Observations: []Observation{{Value: 3, Time: time.Now().Add(-time.Minute).Truncate(time.Microsecond).UTC()}}}
+	require.NoError(t, store.Import([]ListedMetric{
+		{Type: TypeHistogram, Name: "Latency", Histogram: latency},
+		{Type: TypeSummary, Name: "Size", Summary: size},
+		{Type: TypeSet, Name: "Visitors", Set: NewSketch("alice", "bob")},
+	}, true))
+
+	// Гистограммы, сводки и множества выбираются из бд вместе с остальными метриками, прежнее множество удалено заменой
+	list, err := store.list(ctx, ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, ListKey{Type: TypeHistogram, Name: "Latency"}, list[0].Key())
+	assert.Equal(t, latency, list[0].Histogram)
+	assert.Equal(t, ListKey{Type: TypeSummary, Name: "Size"}, list[1].Key())
+	assert.Equal(t, size, list[1].Summary)
+	assert.Equal(t, ListKey{Type: TypeSet, Name: "Visitors"}, list[2].Key())
+	assert.Equal(t, uint64(2), list[2].Set.Estimate())
+
+	restored, err := NewSQLiteStorage(ctx, db, true, true)
+	require.NoError(t, err)
+	histogram, ok := restored.GetHistogram("Latency")
+	require.True(t, ok)
+	assert.Equal(t, latency, histogram)
+	summary, ok := restored.GetSummary("Size")
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), summary.Count)
+	_, ok = restored.GetSet("Users")
+	assert.False(t, ok)
+	visitors, ok := restored.GetSet("Visitors")
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), visitors.Estimate())
+}
@@ -0,0 +1,562 @@
+package metrics
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShards количество шардов хранилища в памяти по умолчанию
+const DefaultShards = 32
+
+// ShardedMemStorage хранилище метрик в памяти, разделённое на шарды по хешу имени.
+// У каждого шарда своя блокировка, а значения уже существующих метрик меняются атомарно
+// под блокировкой на чтение, поэтому параллельные записи разных и одних и тех же метрик не ждут друг друга.
+// Пакет метрик записывается не целиком, читатель может увидеть его часть.
+// Гистограммы, сводки, множества и описания метрик пишутся редко и хранятся в обычном хранилище в памяти
+type ShardedMemStorage struct {
+	shards        []*memShard
+	seed          maphash.Seed
+	ttl           atomic.Int64 // Время, после которого не обновлявшаяся метрика устаревает, в наносекундах; 0 - не устаревает
+	now           func() time.Time
+	distributions *MemStorage // Гистограммы, сводки, множества и описания метрик
+}
+
+// memShard шард хранилища в памяти. Блокировка на запись нужна только для добавления и удаления метрик
+type memShard struct {
+	mutex    sync.RWMutex
+	gauges   map[string]*gaugeEntry
+	counters map[string]*counterEntry
+}
+
+// gaugeEntry значение gauge и время его обновления в наносекундах
+type gaugeEntry struct {
+	bits    atomic.Uint64
+	updated atomic.Int64
+}
+
+// set установка значения
+func (e *gaugeEntry) set(value Gauge, now time.Time) {
+	e.bits.Store(math.Float64bits(float64(value)))
+	e.updated.Store(now.UnixNano())
+}
+
+// value значение gauge
+func (e *gaugeEntry) value() Gauge {
+	return Gauge(math.Float64frombits(e.bits.Load()))
+}
+
+// counterEntry значение counter и время его обновления в наносекундах
+type counterEntry struct {
+	value   atomic.Int64
+	updated atomic.Int64
+}
+
+// NewShardedMemStorage создание хранилища в памяти из shards шардов, если shards не положительное, то из DefaultShards
+func NewShardedMemStorage(shards int) *ShardedMemStorage {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	storage := &ShardedMemStorage{
+		shards:        make([]*memShard, shards),
+		seed:          maphash.MakeSeed(),
+		now:           time.Now,
+		distributions: NewMemStorage(),
+	}
+	storage.distributions.now = func() time.Time { return storage.now() }
+	for i := range storage.shards {
+		storage.shards[i] = &memShard{
+			gauges:   make(map[string]*gaugeEntry),
+			counters: make(map[string]*counterEntry),
+		}
+	}
+	return storage
+}
+
+// shard шард, в котором хранится метрика с именем name
+func (storage *ShardedMemStorage) shard(name string) *memShard {
+	return storage.shards[maphash.String(storage.seed, name)%uint64(len(storage.shards))]
+}
+
+// SetGauge устанавливаем gauge
+func (storage *ShardedMemStorage) SetGauge(name string, value Gauge) error {
+	shard := storage.shard(name)
+	now := storage.now()
+	shard.mutex.RLock()
+	entry, ok := shard.gauges[name]
+	if ok {
+		entry.set(value, now)
+	}
+	shard.mutex.RUnlock()
+	if ok {
+		return nil
+	}
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if entry, ok = shard.gauges[name]; !ok {
+		entry = new(gaugeEntry)
+		shard.gauges[name] = entry
+	}
+	entry.set(value, now)
+	return nil
+}
+
+// AddCounter добавляем каунтер. Устаревший counter начинается заново, как будто его не было
+func (storage *ShardedMemStorage) AddCounter(name string, value Counter) error {
+	shard := storage.shard(name)
+	now := storage.now()
+	shard.mutex.RLock()
+	entry, ok := shard.counters[name]
+	// Сбросить устаревший counter можно только под блокировкой на запись, иначе параллельное прибавление потеряется
+	added := ok && !storage.expired(entry.updated.Load())
+	if added {
+		entry.value.Add(int64(value))
+		entry.updated.Store(now.UnixNano())
+	}
+	shard.mutex.RUnlock()
+	if added {
+		return nil
+	}
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	shard.addCounterLocked(storage, name, value, now)
+	return nil
+}
+
+// addCounterLocked добавление counter под блокировкой шарда на запись
+func (shard *memShard) addCounterLocked(storage *ShardedMemStorage, name string, value Counter, now time.Time) {
+	entry, ok := shard.counters[name]
+	switch {
+	case !ok:
+		entry = new(counterEntry)
+		shard.counters[name] = entry
+		entry.value.Store(int64(value))
+	case storage.expired(entry.updated.Load()):
+		entry.value.Store(int64(value))
+	default:
+		entry.value.Add(int64(value))
+	}
+	entry.updated.Store(now.UnixNano())
+}
+
+// GetGauge получение отдельного gauge
+func (storage *ShardedMemStorage) GetGauge(name string) (Gauge, bool) {
+	shard := storage.shard(name)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	entry, ok := shard.gauges[name]
+	if !ok || storage.expired(entry.updated.Load()) {
+		return 0, false
+	}
+	return entry.value(), true
+}
+
+// GetCounter получение отдельного counter
+func (storage *ShardedMemStorage) GetCounter(name string) (Counter, bool) {
+	shard := storage.shard(name)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	entry, ok := shard.counters[name]
+	if !ok || storage.expired(entry.updated.Load()) {
+		return 0, false
+	}
+	return Counter(entry.value.Load()), true
+}
+
+// GetGauges получение всех gauge
+func (storage *ShardedMemStorage) GetGauges() (map[string]Gauge, error) {
+	gauges := make(map[string]Gauge)
+	for _, shard := range storage.shards {
+		shard.mutex.RLock()
+		for name, entry := range shard.gauges {
+			if !storage.expired(entry.updated.Load()) {
+				gauges[name] = entry.value()
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	return gauges, nil
+}
+
+// GetCounters получение всех counter
+func (storage *ShardedMemStorage) GetCounters() (map[string]Counter, error) {
+	counters := make(map[string]Counter)
+	for _, shard := range storage.shards {
+		shard.mutex.RLock()
+		for name, entry := range shard.counters {
+			if !storage.expired(entry.updated.Load()) {
+				counters[name] = Counter(entry.value.Load())
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	return counters, nil
+}
+
+// SetGauges массовое обновление гауге в памяти, каждая метрика блокирует только свой шард
+func (storage *ShardedMemStorage) SetGauges(gauges map[string]Gauge) error {
+	for name, gauge := range gauges {
+		if err := storage.SetGauge(name, gauge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddCounters массовое обновление каунтер в памяти, каждая метрика блокирует только свой шард
+func (storage *ShardedMemStorage) AddCounters(counters map[string]Counter) error {
+	for name, counter := range counters {
+		if err := storage.AddCounter(name, counter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetGaugesReturningOld массовое обновление гауге в памяти с прежними значениями неустаревших gauge.
+// Метрика записывается под блокировкой шарда на запись, чтобы параллельная запись не вклинилась между чтением и записью
+func (storage *ShardedMemStorage) SetGaugesReturningOld(ctx context.Context, gauges map[string]Gauge) (map[string]Gauge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	now := storage.now()
+	old := make(map[string]Gauge, len(gauges))
+	for name, gauge := range gauges {
+		shard := storage.shard(name)
+		shard.mutex.Lock()
+		entry, ok := shard.gauges[name]
+		switch {
+		case !ok:
+			entry = new(gaugeEntry)
+			shard.gauges[name] = entry
+		case !storage.expired(entry.updated.Load()):
+			old[name] = entry.value()
+		}
+		entry.set(gauge, now)
+		shard.mutex.Unlock()
+	}
+	return old, nil
+}
+
+// AddCountersReturningNew массовое обновление каунтер в памяти со значениями до и после прибавления.
+// Метрика записывается под блокировкой шарда на запись
+func (storage *ShardedMemStorage) AddCountersReturningNew(ctx context.Context, counters map[string]Counter) (old, updated map[string]Counter, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	now := storage.now()
+	old = make(map[string]Counter, len(counters))
+	updated = make(map[string]Counter, len(counters))
+	for name, counter := range counters {
+		shard := storage.shard(name)
+		shard.mutex.Lock()
+		if entry, ok := shard.counters[name]; ok && !storage.expired(entry.updated.Load()) {
+			old[name] = Counter(entry.value.Load())
+		}
+		shard.addCounterLocked(storage, name, counter, now)
+		updated[name] = Counter(shard.counters[name].value.Load())
+		shard.mutex.Unlock()
+	}
+	return old, updated, nil
+}
+
+// GetGaugesByNames значения неустаревших gauge с указанными именами
+func (storage *ShardedMemStorage) GetGaugesByNames(names []string) (map[string]Gauge, error) {
+	gauges := make(map[string]Gauge, len(names))
+	for _, name := range names {
+		if value, ok := storage.GetGauge(name); ok {
+			gauges[name] = value
+		}
+	}
+	return gauges, nil
+}
+
+// GetCountersByNames значения неустаревших counter с указанными именами
+func (storage *ShardedMemStorage) GetCountersByNames(names []string) (map[string]Counter, error) {
+	counters := make(map[string]Counter, len(names))
+	for _, name := range names {
+		if value, ok := storage.GetCounter(name); ok {
+			counters[name] = value
+		}
+	}
+	return counters, nil
+}
+
+// List список неустаревших метрик, подходящих под фильтр, в порядке сортировки запроса
+func (storage *ShardedMemStorage) List(query ListQuery) ([]ListedMetric, error) {
+	list := make([]ListedMetric, 0)
+	for _, shard := range storage.shards {
+		shard.mutex.RLock()
+		if query.Filter.MatchType(TypeGauge) {
+			for name, entry := range shard.gauges {
+				updated := entry.updated.Load()
+				if !storage.expired(updated) && query.Filter.Match(TypeGauge, name) {
+					list = append(list, ListedMetric{Type: TypeGauge, Name: name, Gauge: entry.value(), UpdatedAt: unixTime(updated)})
+				}
+			}
+		}
+		if query.Filter.MatchType(TypeCounter) {
+			for name, entry := range shard.counters {
+				updated := entry.updated.Load()
+				if !storage.expired(updated) && query.Filter.Match(TypeCounter, name) {
+					list = append(list, ListedMetric{Type: TypeCounter, Name: name, Counter: Counter(entry.value.Load()), UpdatedAt: unixTime(updated)})
+				}
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	// Гистограммы и сводки отбираются тем же фильтром, а страница собирается вместе с gauge и counter
+	distributions, err := storage.distributions.List(ListQuery{Filter: query.Filter})
+	if err != nil {
+		return nil, err
+	}
+	return query.page(append(list, distributions...)), nil
+}
+
+// Delete удаление метрики из памяти
+func (storage *ShardedMemStorage) Delete(metricType, name string) error {
+	_, err := storage.DeleteReturningOld(context.Background(), metricType, name)
+	return err
+}
+
+// DeleteReturningOld удаление метрики из памяти, возвращает удалённое значение
+func (storage *ShardedMemStorage) DeleteReturningOld(ctx context.Context, metricType, name string) (any, error) {
+	if metricType == TypeHistogram || metricType == TypeSummary || metricType == TypeSet {
+		return storage.distributions.DeleteReturningOld(ctx, metricType, name)
+	}
+	if metricType != TypeGauge && metricType != TypeCounter {
+		return nil, ErrorUnknownMetricType
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	shard := storage.shard(name)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if metricType == TypeGauge {
+		entry, ok := shard.gauges[name]
+		if !ok {
+			return nil, ErrorMetricNotFound
+		}
+		delete(shard.gauges, name)
+		return entry.value(), nil
+	}
+	entry, ok := shard.counters[name]
+	if !ok {
+		return nil, ErrorMetricNotFound
+	}
+	delete(shard.counters, name)
+	return Counter(entry.value.Load()), nil
+}
+
+// ResetCounter обнуление counter в памяти
+func (storage *ShardedMemStorage) ResetCounter(name string) error {
+	_, err := storage.ResetCounterReturningOld(context.Background(), name)
+	return err
+}
+
+// ResetCounterReturningOld обнуление counter в памяти, возвращает значение до обнуления
+func (storage *ShardedMemStorage) ResetCounterReturningOld(ctx context.Context, name string) (Counter, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	shard := storage.shard(name)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	entry, ok := shard.counters[name]
+	if !ok || storage.expired(entry.updated.Load()) {
+		return 0, ErrorMetricNotFound
+	}
+	old := entry.value.Swap(0)
+	entry.updated.Store(storage.now().UnixNano())
+	return Counter(old), nil
+}
+
+// SetTTL устанавливает время, после которого не обновлявшаяся метрика скрывается из чтения
+func (storage *ShardedMemStorage) SetTTL(ttl time.Duration) {
+	storage.ttl.Store(int64(ttl))
+	storage.distributions.SetTTL(ttl)
+}
+
+// DeleteExpired удаляет устаревшие метрики из памяти
+func (storage *ShardedMemStorage) DeleteExpired() (int, error) {
+	if storage.ttl.Load() <= 0 {
+		return 0, nil
+	}
+	deleted, err := storage.distributions.DeleteExpired()
+	if err != nil {
+		return deleted, err
+	}
+	for _, shard := range storage.shards {
+		shard.mutex.Lock()
+		for name, entry := range shard.gauges {
+			if storage.expired(entry.updated.Load()) {
+				delete(shard.gauges, name)
+				deleted++
+			}
+		}
+		for name, entry := range shard.counters {
+			if storage.expired(entry.updated.Load()) {
+				delete(shard.counters, name)
+				deleted++
+			}
+		}
+		shard.mutex.Unlock()
+	}
+	return deleted, nil
+}
+
+// Import записывает метрики в память вместе со временем обновления. Если replace, то остальные метрики удаляются.
+// На время загрузки блокируются все шарды, чтобы читатели не увидели хранилище наполовину заменённым.
+// Гистограммы и сводки загружаются в своё хранилище в памяти
+func (storage *ShardedMemStorage) Import(list []ListedMetric, replace bool) error {
+	// Проверяем метрики до изменения, чтобы не загрузить список частично
+	if err := checkImported(list); err != nil {
+		return err
+	}
+	distributions := make([]ListedMetric, 0)
+	for _, metric := range list {
+		if metric.Type != TypeGauge && metric.Type != TypeCounter {
+			distributions = append(distributions, metric)
+		}
+	}
+	for _, shard := range storage.shards {
+		shard.mutex.Lock()
+		defer shard.mutex.Unlock()
+		if replace {
+			clear(shard.gauges)
+			clear(shard.counters)
+		}
+	}
+	if err := storage.distributions.Import(distributions, replace); err != nil {
+		return err
+	}
+	now := storage.now()
+	for _, metric := range list {
+		if metric.Type != TypeGauge && metric.Type != TypeCounter {
+			continue
+		}
+		shard := storage.shard(metric.Name)
+		updated := importedAt(metric, now)
+		if metric.Type == TypeGauge {
+			entry := new(gaugeEntry)
+			entry.set(metric.Gauge, updated)
+			shard.gauges[metric.Name] = entry
+			continue
+		}
+		entry := new(counterEntry)
+		entry.value.Store(int64(metric.Counter))
+		entry.updated.Store(updated.UnixNano())
+		shard.counters[metric.Name] = entry
+	}
+	return nil
+}
+
+// AddHistogram прибавляет гистограмму к сохранённой
+func (storage *ShardedMemStorage) AddHistogram(name string, histogram Histogram) error {
+	return storage.distributions.AddHistogram(name, histogram)
+}
+
+// AddSummary добавляет наблюдения в сводку
+func (storage *ShardedMemStorage) AddSummary(name string, values []float64) error {
+	return storage.distributions.AddSummary(name, values)
+}
+
+// AddHistogramReturningNew прибавляет гистограмму к сохранённой, возвращает гистограммы до и после прибавления
+func (storage *ShardedMemStorage) AddHistogramReturningNew(ctx context.Context, name string, histogram Histogram) (*Histogram, Histogram, error) {
+	return storage.distributions.AddHistogramReturningNew(ctx, name, histogram)
+}
+
+// AddSummaryReturningNew добавляет наблюдения в сводку, возвращает сводки за окно до и после добавления
+func (storage *ShardedMemStorage) AddSummaryReturningNew(ctx context.Context, name string, values []float64) (*SummaryValue, SummaryValue, error) {
+	return storage.distributions.AddSummaryReturningNew(ctx, name, values)
+}
+
+// GetHistogram получение отдельной гистограммы
+func (storage *ShardedMemStorage) GetHistogram(name string) (Histogram, bool) {
+	return storage.distributions.GetHistogram(name)
+}
+
+// GetSummary количество, сумма и квантили отдельной сводки
+func (storage *ShardedMemStorage) GetSummary(name string) (SummaryValue, bool) {
+	return storage.distributions.GetSummary(name)
+}
+
+// GetHistograms получение всех гистограмм
+func (storage *ShardedMemStorage) GetHistograms() (map[string]Histogram, error) {
+	return storage.distributions.GetHistograms()
+}
+
+// GetSummaries количество, сумма и квантили всех сводок
+func (storage *ShardedMemStorage) GetSummaries() (map[string]SummaryValue, error) {
+	return storage.distributions.GetSummaries()
+}
+
+// loadHistogram замена гистограммы вместе со временем обновления
+func (storage *ShardedMemStorage) loadHistogram(name string, histogram Histogram, updated time.Time) {
+	storage.distributions.loadHistogram(name, histogram, updated)
+}
+
+// loadSummary замена наблюдений сводки
+func (storage *ShardedMemStorage) loadSummary(name string, summary Summary) {
+	storage.distributions.loadSummary(name, summary)
+}
+
+// summaryObservations наблюдения неустаревшей сводки
+func (storage *ShardedMemStorage) summaryObservations(name string) (Summary, bool) {
+	return storage.distributions.summaryObservations(name)
+}
+
+// expired устарела ли метрика, обновлённая в updated наносекунд
+func (storage *ShardedMemStorage) expired(updated int64) bool {
+	ttl := storage.ttl.Load()
+	return ttl > 0 && storage.now().UnixNano()-updated > ttl
+}
+
+// unixTime время обновления метрики из наносекунд
+func unixTime(nanoseconds int64) time.Time {
+	return time.Unix(0, nanoseconds).UTC()
+}
+
+// AddSet объединяет скетч с сохранённым
+func (storage *ShardedMemStorage) AddSet(name string, sketch Sketch) error {
+	return storage.distributions.AddSet(name, sketch)
+}
+
+// AddSetReturningNew объединяет скетч с сохранённым, возвращает скетчи до и после объединения
+func (storage *ShardedMemStorage) AddSetReturningNew(ctx context.Context, name string, sketch Sketch) (*Sketch, Sketch, error) {
+	return storage.distributions.AddSetReturningNew(ctx, name, sketch)
+}
+
+// GetSet получение отдельного скетча
+func (storage *ShardedMemStorage) GetSet(name string) (Sketch, bool) {
+	return storage.distributions.GetSet(name)
+}
+
+// GetSets получение всех скетчей
+func (storage *ShardedMemStorage) GetSets() (map[string]Sketch, error) {
+	return storage.distributions.GetSets()
+}
+
+// loadSet замена скетча вместе со временем обновления
+func (storage *ShardedMemStorage) loadSet(name string, sketch Sketch, updated time.Time) {
+	storage.distributions.loadSet(name, sketch, updated)
+}
+
+// SetMetadata объединяет описание метрики с сохранённым
+func (storage *ShardedMemStorage) SetMetadata(key ListKey, metadata Metadata) error {
+	return storage.distributions.SetMetadata(key, metadata)
+}
+
+// GetMetadata описание отдельной метрики
+func (storage *ShardedMemStorage) GetMetadata(key ListKey) (Metadata, bool) {
+	return storage.distributions.GetMetadata(key)
+}
+
+// GetAllMetadata описания всех метрик
+func (storage *ShardedMemStorage) GetAllMetadata() (map[ListKey]Metadata, error) {
+	return storage.distributions.GetAllMetadata()
+}
@@ -0,0 +1,298 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTTLShardedStorage шардированное хранилище с устареванием и управляемым временем
+func newTTLShardedStorage(ttl time.Duration, now *time.Time) *ShardedMemStorage {
+	store := NewShardedMemStorage(4)
+	store.now = func() time.Time { return *now }
+	store.SetTTL(ttl)
+	return store
+}
+
+func TestNewShardedMemStorage(t *testing.T) {
+	assert.Len(t, NewShardedMemStorage(0).shards, DefaultShards)
+	assert.Len(t, NewShardedMemStorage(8).shards, 8)
+}
+
+// TestShardedMemStorage_SameAsMemStorage одни и те же операции дают одинаковый результат в обоих хранилищах
+func TestShardedMemStorage_SameAsMemStorage(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mem := newTTLStorage(0, &now)
+	sharded := newTTLShardedStorage(0, &now)
+	generated := generateGaugesMap(20)
+	for _, store := range []IStorage{mem, sharded} {
+		require.NoError(t, store.SetGauge("Alloc", 1.5))
+		require.NoError(t, store.SetGauge("Alloc", 2.5))
+		require.NoError(t, store.AddCounter("PollCount", 3))
+		require.NoError(t, store.AddCounters(map[string]Counter{"PollCount": 2, "Requests": 7}))
+		require.NoError(t, store.SetGauges(generated))
+		require.NoError(t, store.Delete(TypeGauge, "metric3"))
+		assert.ErrorIs(t, store.Delete(TypeCounter, "missing"), ErrorMetricNotFound)
+		assert.ErrorIs(t, store.Delete("timer", "Alloc"), ErrorUnknownMetricType)
+		require.NoError(t, store.ResetCounter("Requests"))
+		assert.ErrorIs(t, store.ResetCounter("missing"), ErrorMetricNotFound)
+	}
+
+	memGauges, err := mem.GetGauges()
+	require.NoError(t, err)
+	gauges, err := sharded.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, memGauges, gauges)
+	counters, err := sharded.GetCounters()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 5, "Requests": 0}, counters)
+
+	for _, query := range []ListQuery{
+		{Sort: ListSortName},
+		{Sort: ListSortType, Desc: true, Limit: 5},
+		{Filter: ListFilter{Type: TypeCounter}, After: &ListKey{Type: TypeCounter, Name: "PollCount"}},
+	} {
+		memList, lErr := mem.List(query)
+		require.NoError(t, lErr)
+		list, lErr := sharded.List(query)
+		require.NoError(t, lErr)
+		assert.Equal(t, memList, list)
+	}
+
+	byNames, err := sharded.GetGaugesByNames([]string{"Alloc", "metric3", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 2.5}, byNames)
+	countersByNames, err := sharded.GetCountersByNames([]string{"PollCount", "Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 5}, countersByNames)
+}
+
+func TestShardedMemStorage_TTL(t *testing.T) {
+	now := time.Now()
+	store := newTTLShardedStorage(time.Minute, &now)
+	require.NoError(t, store.SetGauge("old_gauge", 1))
+	require.NoError(t, store.AddCounter("old_counter", 5))
+	now = now.Add(45 * time.Second)
+	require.NoError(t, store.SetGauge("fresh_gauge", 2))
+	require.NoError(t, store.AddCounter("fresh_counter", 7))
+
+	// Через полторы минуты первые метрики устарели
+	now = now.Add(45 * time.Second)
+	_, ok := store.GetGauge("old_gauge")
+	assert.False(t, ok)
+	_, ok = store.GetCounter("old_counter")
+	assert.False(t, ok)
+	gauges, err := store.GetGauges()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"fresh_gauge": 2}, gauges)
+	assert.ErrorIs(t, store.ResetCounter("old_counter"), ErrorMetricNotFound)
+
+	// Устаревший counter начинается заново
+	require.NoError(t, store.AddCounter("old_counter", 1))
+	counter, ok := store.GetCounter("old_counter")
+	assert.True(t, ok)
+	assert.Equal(t, Counter(1), counter)
+
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	store.SetTTL(0)
+	_, ok = store.GetGauge("old_gauge")
+	assert.False(t, ok)
+}
+
+func TestShardedMemStorage_Import(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updated := now.Add(-time.Hour)
+	latency := NewHistogram([]float64{1})
+	latency.Observe(0.5)
+	size := Summary{Observations: []Observation{{Value: 3, Time: now.Add(-time.Minute)}}}
+	list := []ListedMetric{
+		{Type: TypeGauge, Name: "gauge1", Gauge: 2.5, UpdatedAt: updated},
+		{Type: TypeCounter, Name: "counter2", Counter: 7},
+		{Type: TypeHistogram, Name: "latency", Histogram: latency, UpdatedAt: updated},
+		{Type: TypeSummary, Name: "size", Summary: size},
+		{Type: TypeSet, Name: "users", Set: NewSketch("alice")},
+	}
+	imported := []ListedMetric{
+		{Type: TypeCounter, Name: "counter2", Counter: 7, UpdatedAt: now},
+		{Type: TypeGauge, Name: "gauge1", Gauge: 2.5, UpdatedAt: updated},
+		{Type: TypeHistogram, Name: "latency", Histogram: latency, UpdatedAt: updated},
+		// Время обновления сводки - время последнего наблюдения
+		{Type: TypeSummary, Name: "size", Summary: size, UpdatedAt: now.Add(-time.Minute)},
+		{Type: TypeSet, Name: "users", Set: NewSketch("alice"), UpdatedAt: now},
+	}
+	testCases := []struct {
+		name    string
+		list    []ListedMetric
+		replace bool
+		want    []ListedMetric
+		wantErr error
+	}{
+		{
+			name: "merge",
+			list: list,
+			want: []ListedMetric{
+				{Type: TypeCounter, Name: "counter1", Counter: 3, UpdatedAt: now},
+				imported[0], imported[1], imported[2],
+				{Type: TypeHistogram, Name: "old", Histogram: latency, UpdatedAt: now},
+				imported[3], imported[4],
+			},
+		},
+		{
+			name:    "replace",
+			list:    list,
+			replace: true,
+			want:    imported,
+		},
+		{
+			name:    "unknown_type",
+			list:    append([]ListedMetric{{Type: "unknown", Name: "u"}}, list...),
+			replace: true,
+			want: []ListedMetric{
+				{Type: TypeCounter, Name: "counter1", Counter: 3, UpdatedAt: now},
+				{Type: TypeGauge, Name: "gauge1", Gauge: 1.5, UpdatedAt: now},
+				{Type: TypeHistogram, Name: "old", Histogram: latency, UpdatedAt: now},
+			},
+			wantErr: ErrorUnknownMetricType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTTLShardedStorage(0, &now)
+			require.NoError(t, store.SetGauge("gauge1", 1.5))
+			require.NoError(t, store.AddCounter("counter1", 3))
+			require.NoError(t, store.AddHistogram("old", latency))
+
+			assert.ErrorIs(t, store.Import(tc.list, tc.replace), tc.wantErr)
+			got, err := store.List(ListQuery{Sort: ListSortName})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestShardedMemStorage_Distributions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTTLShardedStorage(time.Minute, &now)
+	histogram := NewHistogram([]float64{1})
+	histogram.Observe(2)
+	require.NoError(t, store.AddHistogram("latency", histogram))
+	require.NoError(t, store.AddSummary("size", []float64{4}))
+	got, ok := store.GetHistogram("latency")
+	assert.True(t, ok)
+	assert.Equal(t, histogram, got)
+	histograms, err := store.GetHistograms()
+	require.NoError(t, err)
+	assert.Len(t, histograms, 1)
+	summary, ok := store.GetSummary("size")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), summary.Count)
+	summaries, err := store.GetSummaries()
+	require.NoError(t, err)
+	assert.Len(t, summaries, 1)
+	require.NoError(t, store.Delete(TypeSummary, "size"))
+
+	// Время устаревания общее для всех типов
+	now = now.Add(2 * time.Minute)
+	_, ok = store.GetHistogram("latency")
+	assert.False(t, ok)
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+}
+
+func TestShardedMemStorage_Concurrent(t *testing.T) {
+	store := NewShardedMemStorage(4)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				assert.NoError(t, store.AddCounter("PollCount", 1))
+				assert.NoError(t, store.AddCounters(map[string]Counter{fmt.Sprintf("counter%d", j%10): 1}))
+				assert.NoError(t, store.SetGauge(fmt.Sprintf("gauge%d", j%10), Gauge(j)))
+			}
+		}()
+	}
+	wg.Wait()
+	counter, ok := store.GetCounter("PollCount")
+	assert.True(t, ok)
+	assert.Equal(t, Counter(8000), counter)
+	counters, err := store.GetCounters()
+	require.NoError(t, err)
+	assert.Len(t, counters, 11)
+	assert.Equal(t, Counter(800), counters["counter5"])
+}
+
+// BenchmarkMemStorage_Parallel сравнение хранилищ в памяти при параллельной записи пачек метрик
+func BenchmarkMemStorage_Parallel(b *testing.B) {
+	stores := []struct {
+		name  string
+		store func() IStorage
+	}{
+		{name: "mem", store: func() IStorage { return NewMemStorage() }},
+		{name: "sharded", store: func() IStorage { return NewShardedMemStorage(DefaultShards) }},
+	}
+	for _, size := range []int{1, 100, 1000} {
+		gauges := generateGaugesMap(size)
+		counters := generateCounterMap(size)
+		for _, st := range stores {
+			b.Run(fmt.Sprintf("%s_%d_value", st.name, size), func(b *testing.B) {
+				store := st.store()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if err := store.SetGauges(gauges); err != nil {
+							b.Error(err, "error setting gauges")
+						}
+						if err := store.AddCounters(counters); err != nil {
+							b.Error(err, "error add counters")
+						}
+						if _, ok := store.GetCounter("metric1"); !ok {
+							b.Error("counter not found")
+						}
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestShardedMemStorage_Sets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTTLShardedStorage(time.Minute, &now)
+	require.NoError(t, store.AddSet("users", NewSketch("a", "b")))
+	require.NoError(t, store.AddSet("hosts", NewSketch("h")))
+	got, ok := store.GetSet("users")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), got.Estimate())
+	sets, err := store.GetSets()
+	require.NoError(t, err)
+	assert.Len(t, sets, 2)
+	require.NoError(t, store.Delete(TypeSet, "hosts"))
+
+	now = now.Add(2 * time.Minute)
+	_, ok = store.GetSet("users")
+	assert.False(t, ok)
+	deleted, err := store.DeleteExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+}
+
+func TestShardedMemStorage_Metadata(t *testing.T) {
+	store := NewShardedMemStorage(4)
+	key := ListKey{Type: TypeGauge, Name: "Alloc"}
+	require.NoError(t, store.SetMetadata(key, Metadata{Unit: "bytes"}))
+	metadata, ok := store.GetMetadata(key)
+	assert.True(t, ok)
+	assert.Equal(t, Metadata{Unit: "bytes"}, metadata)
+	all, err := store.GetAllMetadata()
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
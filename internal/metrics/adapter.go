@@ -3,8 +3,15 @@ package metrics
 import (
 	"context"
 	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
+// ErrorCopyNotSupported ошибка, что подключение к бд не умеет загружать строки командой COPY
+var ErrorCopyNotSupported = errors.New("copy is not supported by db connection")
+
 // ITX Интерфейс для скрытия реализации sql.TX
 type ITX interface {
 	Rollback() error
@@ -37,6 +44,18 @@ type IStmt interface {
 	Exec(args ...any) (sql.Result, error)
 }
 
+// ICopyTX Интерфейс транзакции, в которой строки можно загружать командой COPY
+type ICopyTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) error
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
+}
+
+// ICopyExecutor Интерфейс подключения к бд, которое умеет загружать строки командой COPY
+type ICopyExecutor interface {
+	// CopyTx выполняет fn в транзакции на одном соединении, транзакция фиксируется, если fn не вернула ошибку
+	CopyTx(ctx context.Context, fn func(tx ICopyTX) error) error
+}
+
 // DBAdapter Структура которая скрывает реализацию подключения к бд за функциями, которые можно замокировать
 type DBAdapter struct {
 	real *sql.DB
@@ -97,3 +116,38 @@ func (t TXAdapter) Commit() error {
 func (t TXAdapter) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	return t.real.ExecContext(ctx, query, args...)
 }
+
+// CopyTx Транзакция на соединении pgx, в которой доступна команда COPY.
+// Для других драйверов возвращает ErrorCopyNotSupported
+func (dba DBAdapter) CopyTx(ctx context.Context, fn func(tx ICopyTX) error) error {
+	conn, err := dba.real.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return ErrorCopyNotSupported
+		}
+		return pgx.BeginFunc(ctx, stdConn.Conn(), func(tx pgx.Tx) error {
+			return fn(pgxTXAdapter{real: tx})
+		})
+	})
+}
+
+// pgxTXAdapter адаптер к транзакции pgx
+type pgxTXAdapter struct {
+	real pgx.Tx
+}
+
+// ExecContext Адаптер к pgx.Tx.Exec
+func (t pgxTXAdapter) ExecContext(ctx context.Context, query string, args ...any) error {
+	_, err := t.real.Exec(ctx, query, args...)
+	return err
+}
+
+// CopyFrom Адаптер к pgx.Tx.CopyFrom
+func (t pgxTXAdapter) CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+	return t.real.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+}
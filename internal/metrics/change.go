@@ -0,0 +1,163 @@
+package metrics
+
+import "context"
+
+// IChangingStorage хранилище, которое возвращает значения метрик до и после записи.
+// Значения читаются под той же блокировкой, что и запись, поэтому параллельные записи
+// одних и тех же метрик не путают их между собой. Хранилища с бд берут значения из памяти без запросов к бд
+type IChangingStorage interface {
+	// SetGaugesReturningOld массовое обновление gauge, возвращает прежние значения существовавших gauge
+	SetGaugesReturningOld(ctx context.Context, gauges map[string]Gauge) (map[string]Gauge, error)
+	// AddCountersReturningNew массовое обновление counter, возвращает прежние значения
+	// существовавших counter и значения всех counter после прибавления
+	AddCountersReturningNew(ctx context.Context, counters map[string]Counter) (old, updated map[string]Counter, err error)
+	// DeleteReturningOld удаление метрики, возвращает удалённое значение: Gauge, Counter, Histogram,
+	// SummaryValue или Sketch по типу метрики. Если метрики нет, то возвращается ErrorMetricNotFound
+	DeleteReturningOld(ctx context.Context, metricType, name string) (any, error)
+	// ResetCounterReturningOld обнуление counter, возвращает значение до обнуления.
+	// Если counter нет, то возвращается ErrorMetricNotFound
+	ResetCounterReturningOld(ctx context.Context, name string) (Counter, error)
+}
+
+// IChangingDistributionStorage хранилище гистограмм, сводок и множеств, которое возвращает их состояние до и после записи.
+// Как и в IChangingStorage, состояния читаются под той же блокировкой, что и запись. Если метрики не было, то прежнее состояние nil
+type IChangingDistributionStorage interface {
+	// AddHistogramReturningNew прибавляет гистограмму, возвращает гистограммы до и после прибавления
+	AddHistogramReturningNew(ctx context.Context, name string, histogram Histogram) (old *Histogram, updated Histogram, err error)
+	// AddSummaryReturningNew добавляет наблюдения в сводку, возвращает сводки за окно до и после добавления
+	AddSummaryReturningNew(ctx context.Context, name string, values []float64) (old *SummaryValue, updated SummaryValue, err error)
+	// AddSetReturningNew объединяет скетч с сохранённым, возвращает скетчи до и после объединения
+	AddSetReturningNew(ctx context.Context, name string, sketch Sketch) (old *Sketch, updated Sketch, err error)
+}
+
+// SetGaugesReturningOld массовое обновление gauge в любом хранилище с прежними значениями существовавших gauge.
+// Если хранилище не умеет возвращать прежние значения, то они читаются перед записью и могут не совпасть
+// с перезаписанными при параллельной записи
+func SetGaugesReturningOld(ctx context.Context, storage IStorage, gauges map[string]Gauge) (map[string]Gauge, error) {
+	if st, ok := storage.(IChangingStorage); ok {
+		return st.SetGaugesReturningOld(ctx, gauges)
+	}
+	old, err := GetGaugesByNamesContext(ctx, storage, mapNames(gauges))
+	if err != nil {
+		return nil, err
+	}
+	if err = WithContext(storage).SetGaugesContext(ctx, gauges); err != nil {
+		return nil, err
+	}
+	return old, nil
+}
+
+// AddCountersReturningNew массовое обновление counter в любом хранилище со значениями до и после прибавления.
+// Если хранилище не умеет возвращать значения, то они читаются до и после записи
+func AddCountersReturningNew(ctx context.Context, storage IStorage, counters map[string]Counter) (old, updated map[string]Counter, err error) {
+	if st, ok := storage.(IChangingStorage); ok {
+		return st.AddCountersReturningNew(ctx, counters)
+	}
+	names := mapNames(counters)
+	if old, err = GetCountersByNamesContext(ctx, storage, names); err != nil {
+		return nil, nil, err
+	}
+	if err = WithContext(storage).AddCountersContext(ctx, counters); err != nil {
+		return nil, nil, err
+	}
+	if updated, err = GetCountersByNamesContext(ctx, storage, names); err != nil {
+		return nil, nil, err
+	}
+	return old, updated, nil
+}
+
+// DeleteReturningOld удаление метрики из любого хранилища с удалённым значением.
+// Если хранилище не умеет возвращать значение, то оно читается перед удалением
+func DeleteReturningOld(ctx context.Context, storage IStorage, metricType, name string) (any, error) {
+	if st, ok := storage.(IChangingStorage); ok {
+		return st.DeleteReturningOld(ctx, metricType, name)
+	}
+	var old any
+	switch metricType {
+	case TypeGauge:
+		if value, ok := storage.GetGauge(name); ok {
+			old = value
+		}
+	case TypeCounter:
+		if value, ok := storage.GetCounter(name); ok {
+			old = value
+		}
+	case TypeHistogram:
+		if histogram, ok := GetHistogramByName(storage, name); ok {
+			old = histogram
+		}
+	case TypeSummary:
+		if summary, ok := GetSummaryByName(storage, name); ok {
+			old = summary
+		}
+	case TypeSet:
+		if sketch, ok := GetSetByName(storage, name); ok {
+			old = sketch
+		}
+	}
+	if err := WithContext(storage).DeleteContext(ctx, metricType, name); err != nil {
+		return nil, err
+	}
+	return old, nil
+}
+
+// ResetCounterReturningOld обнуление counter в любом хранилище со значением до обнуления.
+// Если хранилище не умеет возвращать значение, то оно читается перед обнулением
+func ResetCounterReturningOld(ctx context.Context, storage IStorage, name string) (Counter, error) {
+	if st, ok := storage.(IChangingStorage); ok {
+		return st.ResetCounterReturningOld(ctx, name)
+	}
+	old, _ := storage.GetCounter(name)
+	if err := WithContext(storage).ResetCounterContext(ctx, name); err != nil {
+		return 0, err
+	}
+	return old, nil
+}
+
+// AddHistogramReturningNew прибавление гистограммы в любом хранилище с гистограммами до и после прибавления.
+// Если хранилище не умеет возвращать гистограммы, то они читаются до и после записи
+func AddHistogramReturningNew(ctx context.Context, storage IStorage, name string, histogram Histogram) (old *Histogram, updated Histogram, err error) {
+	if st, ok := storage.(IChangingDistributionStorage); ok {
+		return st.AddHistogramReturningNew(ctx, name, histogram)
+	}
+	if stored, ok := GetHistogramByName(storage, name); ok {
+		old = &stored
+	}
+	if err = AddHistogramContext(ctx, storage, name, histogram); err != nil {
+		return nil, Histogram{}, err
+	}
+	updated, _ = GetHistogramByName(storage, name)
+	return old, updated, nil
+}
+
+// AddSummaryReturningNew добавление наблюдений сводки в любом хранилище со сводками до и после добавления.
+// Если хранилище не умеет возвращать сводки, то они читаются до и после записи
+func AddSummaryReturningNew(ctx context.Context, storage IStorage, name string, values []float64) (old *SummaryValue, updated SummaryValue, err error) {
+	if st, ok := storage.(IChangingDistributionStorage); ok {
+		return st.AddSummaryReturningNew(ctx, name, values)
+	}
+	if stored, ok := GetSummaryByName(storage, name); ok {
+		old = &stored
+	}
+	if err = AddSummaryContext(ctx, storage, name, values); err != nil {
+		return nil, SummaryValue{}, err
+	}
+	updated, _ = GetSummaryByName(storage, name)
+	return old, updated, nil
+}
+
+// AddSetReturningNew объединение скетча с сохранённым в любом хранилище со скетчами до и после объединения.
+// Если хранилище не умеет возвращать скетчи, то они читаются до и после записи
+func AddSetReturningNew(ctx context.Context, storage IStorage, name string, sketch Sketch) (old *Sketch, updated Sketch, err error) {
+	if st, ok := storage.(IChangingDistributionStorage); ok {
+		return st.AddSetReturningNew(ctx, name, sketch)
+	}
+	if stored, ok := GetSetByName(storage, name); ok {
+		old = &stored
+	}
+	if err = AddSetContext(ctx, storage, name, sketch); err != nil {
+		return nil, Sketch{}, err
+	}
+	updated, _ = GetSetByName(storage, name)
+	return old, updated, nil
+}
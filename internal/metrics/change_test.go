@@ -0,0 +1,236 @@
+package metrics
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changingStorages хранилища, которые возвращают значения метрик при записи
+func changingStorages(t *testing.T) map[string]IStorage {
+	fileStore, err := NewFileStorage(filepath.Join(t.TempDir(), "metrics.json"), false, true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, fileStore.Close())
+	})
+	dbStore, err := NewSQLiteStorage(context.Background(), NewDBAdapter(newSQLiteDB(t)), false, true)
+	require.NoError(t, err)
+	return map[string]IStorage{
+		"mem":     NewMemStorage(),
+		"sharded": NewShardedMemStorage(4),
+		"file":    fileStore,
+		"db":      dbStore,
+		// Хранилище без IChangingStorage читает значения до и после записи
+		"adapter": struct{ IStorage }{NewMemStorage()},
+	}
+}
+
+func TestSetGaugesReturningOld(t *testing.T) {
+	for name, store := range changingStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			old, err := SetGaugesReturningOld(ctx, store, map[string]Gauge{"Alloc": 1})
+			require.NoError(t, err)
+			assert.Empty(t, old)
+			old, err = SetGaugesReturningOld(ctx, store, map[string]Gauge{"Alloc": 2, "Heap": 3})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]Gauge{"Alloc": 1}, old)
+			value, ok := store.GetGauge("Alloc")
+			require.True(t, ok)
+			assert.Equal(t, Gauge(2), value)
+
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err = SetGaugesReturningOld(canceled, store, map[string]Gauge{"Alloc": 4})
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestAddCountersReturningNew(t *testing.T) {
+	for name, store := range changingStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			old, updated, err := AddCountersReturningNew(ctx, store, map[string]Counter{"PollCount": 2})
+			require.NoError(t, err)
+			assert.Empty(t, old)
+			assert.Equal(t, map[string]Counter{"PollCount": 2}, updated)
+			old, updated, err = AddCountersReturningNew(ctx, store, map[string]Counter{"PollCount": 3, "Requests": 1})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]Counter{"PollCount": 2}, old)
+			assert.Equal(t, map[string]Counter{"PollCount": 5, "Requests": 1}, updated)
+		})
+	}
+}
+
+func TestAddCountersReturningNew_Concurrent(t *testing.T) {
+	for _, store := range []IChangingStorage{NewMemStorage(), NewShardedMemStorage(4)} {
+		const writers = 50
+		var (
+			wg    sync.WaitGroup
+			mutex sync.Mutex
+			// Значение до прибавления по значению после: у параллельных записей значения не повторяются
+			chain = make(map[Counter]Counter, writers)
+		)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				old, updated, err := store.AddCountersReturningNew(context.Background(), map[string]Counter{"PollCount": 1})
+				assert.NoError(t, err)
+				mutex.Lock()
+				chain[updated["PollCount"]] = old["PollCount"]
+				mutex.Unlock()
+			}()
+		}
+		wg.Wait()
+		require.Len(t, chain, writers)
+		for value := Counter(1); value <= writers; value++ {
+			assert.Equal(t, value-1, chain[value])
+		}
+	}
+}
+
+func TestDeleteReturningOld(t *testing.T) {
+	for name, store := range changingStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.SetGauge("Alloc", 1.5))
+			require.NoError(t, store.AddCounter("PollCount", 3))
+
+			old, err := DeleteReturningOld(ctx, store, TypeGauge, "Alloc")
+			require.NoError(t, err)
+			assert.Equal(t, Gauge(1.5), old)
+			old, err = DeleteReturningOld(ctx, store, TypeCounter, "PollCount")
+			require.NoError(t, err)
+			assert.Equal(t, Counter(3), old)
+			if _, ok := store.(ISetStorage); ok {
+				require.NoError(t, AddSetContext(ctx, store, "Users", NewSketch("alice", "bob")))
+				old, err = DeleteReturningOld(ctx, store, TypeSet, "Users")
+				require.NoError(t, err)
+				require.IsType(t, Sketch{}, old)
+				assert.Equal(t, uint64(2), old.(Sketch).Estimate())
+			}
+
+			_, err = DeleteReturningOld(ctx, store, TypeGauge, "Alloc")
+			assert.ErrorIs(t, err, ErrorMetricNotFound)
+			_, ok := store.GetGauge("Alloc")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestResetCounterReturningOld(t *testing.T) {
+	for name, store := range changingStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.AddCounter("PollCount", 3))
+			old, err := ResetCounterReturningOld(ctx, store, "PollCount")
+			require.NoError(t, err)
+			assert.Equal(t, Counter(3), old)
+			value, ok := store.GetCounter("PollCount")
+			require.True(t, ok)
+			assert.Equal(t, Counter(0), value)
+
+			_, err = ResetCounterReturningOld(ctx, store, "Requests")
+			assert.ErrorIs(t, err, ErrorMetricNotFound)
+		})
+	}
+}
+
+// distributionAdapter хранилище гистограмм, сводок и множеств без IChangingDistributionStorage
+type distributionAdapter struct {
+	IStorage
+	IDistributionStorage
+	ISetStorage
+}
+
+// changingDistributionStorages хранилища, которые возвращают состояние гистограмм, сводок и множеств при записи
+func changingDistributionStorages(t *testing.T) map[string]IStorage {
+	stores := changingStorages(t)
+	mem := NewMemStorage()
+	stores["adapter"] = distributionAdapter{IStorage: mem, IDistributionStorage: mem, ISetStorage: mem}
+	return stores
+}
+
+func TestAddDistributionsReturningNew(t *testing.T) {
+	for name, store := range changingDistributionStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			histogram := NewHistogram([]float64{1, 10})
+			histogram.Observe(2)
+			oldHistogram, updatedHistogram, err := AddHistogramReturningNew(ctx, store, "Latency", histogram)
+			require.NoError(t, err)
+			assert.Nil(t, oldHistogram)
+			assert.Equal(t, uint64(1), updatedHistogram.Count)
+			oldHistogram, updatedHistogram, err = AddHistogramReturningNew(ctx, store, "Latency", histogram)
+			require.NoError(t, err)
+			require.NotNil(t, oldHistogram)
+			assert.Equal(t, uint64(1), oldHistogram.Count)
+			assert.Equal(t, uint64(2), updatedHistogram.Count)
+
+			oldSummary, updatedSummary, err := AddSummaryReturningNew(ctx, store, "Size", []float64{1, 2})
+			require.NoError(t, err)
+			assert.Nil(t, oldSummary)
+			assert.Equal(t, uint64(2), updatedSummary.Count)
+			oldSummary, updatedSummary, err = AddSummaryReturningNew(ctx, store, "Size", []float64{3})
+			require.NoError(t, err)
+			require.NotNil(t, oldSummary)
+			assert.Equal(t, uint64(2), oldSummary.Count)
+			assert.Equal(t, uint64(3), updatedSummary.Count)
+
+			oldSet, updatedSet, err := AddSetReturningNew(ctx, store, "Users", NewSketch("alice"))
+			require.NoError(t, err)
+			assert.Nil(t, oldSet)
+			assert.Equal(t, uint64(1), updatedSet.Estimate())
+			oldSet, updatedSet, err = AddSetReturningNew(ctx, store, "Users", NewSketch("bob", "carol"))
+			require.NoError(t, err)
+			require.NotNil(t, oldSet)
+			assert.Equal(t, uint64(1), oldSet.Estimate())
+			assert.Equal(t, uint64(3), updatedSet.Estimate())
+
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, _, err = AddHistogramReturningNew(canceled, store, "Latency", histogram)
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestAddHistogramReturningNew_Concurrent(t *testing.T) {
+	for _, store := range []IChangingDistributionStorage{NewMemStorage(), NewShardedMemStorage(4)} {
+		const writers = 50
+		var (
+			wg    sync.WaitGroup
+			mutex sync.Mutex
+			// Количество наблюдений до прибавления по количеству после
+			chain = make(map[uint64]uint64, writers)
+		)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				histogram := NewHistogram([]float64{1})
+				histogram.Observe(0.5)
+				old, updated, err := store.AddHistogramReturningNew(context.Background(), "Latency", histogram)
+				assert.NoError(t, err)
+				var oldCount uint64
+				if old != nil {
+					oldCount = old.Count
+				}
+				mutex.Lock()
+				chain[updated.Count] = oldCount
+				mutex.Unlock()
+			}()
+		}
+		wg.Wait()
+		require.Len(t, chain, writers)
+		for count := uint64(1); count <= writers; count++ {
+			assert.Equal(t, count-1, chain[count])
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import "context"
+
+// IContextStorage хранилище, методы которого принимают контекст запроса.
+// Запросы к бд прерываются, когда клиент отключился или истёк срок запроса
+type IContextStorage interface {
+	// GetGaugesContext получение всех gauge
+	GetGaugesContext(ctx context.Context) (map[string]Gauge, error)
+	// GetCountersContext получение всех counter
+	GetCountersContext(ctx context.Context) (map[string]Counter, error)
+	// SetGaugeContext устанавливаем gauge
+	SetGaugeContext(ctx context.Context, name string, value Gauge) error
+	// AddCounterContext добавляем каунтер
+	AddCounterContext(ctx context.Context, name string, value Counter) error
+	// GetGaugeContext получение отдельного gauge, ошибка, если значение не удалось прочитать
+	GetGaugeContext(ctx context.Context, name string) (Gauge, bool, error)
+	// GetCounterContext получение отдельного counter, ошибка, если значение не удалось прочитать
+	GetCounterContext(ctx context.Context, name string) (Counter, bool, error)
+	// SetGaugesContext массовое обновление метрик Гауге
+	SetGaugesContext(ctx context.Context, gauges map[string]Gauge) error
+	// AddCountersContext массовое обновление метрик Каунтер
+	AddCountersContext(ctx context.Context, counters map[string]Counter) error
+	// DeleteContext удаление метрики, если её нет, то возвращается ErrorMetricNotFound
+	DeleteContext(ctx context.Context, metricType, name string) error
+	// ResetCounterContext обнуление counter, если его нет, то возвращается ErrorMetricNotFound
+	ResetCounterContext(ctx context.Context, name string) error
+}
+
+// IContextBatchReadingStorage хранилище, которое умеет читать много метрик за раз с контекстом запроса
+type IContextBatchReadingStorage interface {
+	// GetGaugesByNamesContext значения gauge с указанными именами
+	GetGaugesByNamesContext(ctx context.Context, names []string) (map[string]Gauge, error)
+	// GetCountersByNamesContext значения counter с указанными именами
+	GetCountersByNamesContext(ctx context.Context, names []string) (map[string]Counter, error)
+}
+
+// IContextListingStorage хранилище, которое умеет отдавать список метрик с контекстом запроса
+type IContextListingStorage interface {
+	// ListContext страница списка метрик
+	ListContext(ctx context.Context, query ListQuery) ([]ListedMetric, error)
+}
+
+// IContextImportingStorage хранилище, которое умеет загружать метрики с контекстом запроса
+type IContextImportingStorage interface {
+	// ImportContext записывает значения метрик из списка
+	ImportContext(ctx context.Context, list []ListedMetric, replace bool) error
+}
+
+// WithContext хранилище с методами, принимающими контекст. Если хранилище не умеет работать с контекстом,
+// то оно оборачивается адаптером, который проверяет контекст перед каждым вызовом
+func WithContext(storage IStorage) IContextStorage {
+	if st, ok := storage.(IContextStorage); ok {
+		return st
+	}
+	return &contextAdapter{IStorage: storage}
+}
+
+// contextAdapter адаптер хранилища без контекста к IContextStorage.
+// Вызовы такого хранилища не блокируются надолго, поэтому достаточно не начинать их после отмены контекста
+type contextAdapter struct {
+	IStorage
+}
+
+// GetGaugesContext получение всех gauge
+func (a *contextAdapter) GetGaugesContext(ctx context.Context) (map[string]Gauge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.GetGauges()
+}
+
+// GetCountersContext получение всех counter
+func (a *contextAdapter) GetCountersContext(ctx context.Context) (map[string]Counter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.GetCounters()
+}
+
+// SetGaugeContext устанавливаем gauge
+func (a *contextAdapter) SetGaugeContext(ctx context.Context, name string, value Gauge) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.SetGauge(name, value)
+}
+
+// AddCounterContext добавляем каунтер
+func (a *contextAdapter) AddCounterContext(ctx context.Context, name string, value Counter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.AddCounter(name, value)
+}
+
+// GetGaugeContext получение отдельного gauge
+func (a *contextAdapter) GetGaugeContext(ctx context.Context, name string) (Gauge, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	value, ok := a.GetGauge(name)
+	return value, ok, nil
+}
+
+// GetCounterContext получение отдельного counter
+func (a *contextAdapter) GetCounterContext(ctx context.Context, name string) (Counter, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	value, ok := a.GetCounter(name)
+	return value, ok, nil
+}
+
+// SetGaugesContext массовое обновление метрик Гауге
+func (a *contextAdapter) SetGaugesContext(ctx context.Context, gauges map[string]Gauge) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.SetGauges(gauges)
+}
+
+// AddCountersContext массовое обновление метрик Каунтер
+func (a *contextAdapter) AddCountersContext(ctx context.Context, counters map[string]Counter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.AddCounters(counters)
+}
+
+// DeleteContext удаление метрики
+func (a *contextAdapter) DeleteContext(ctx context.Context, metricType, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Delete(metricType, name)
+}
+
+// ResetCounterContext обнуление counter
+func (a *contextAdapter) ResetCounterContext(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.ResetCounter(name)
+}
+
+// GetGaugesByNamesContext значения gauge с указанными именами из любого хранилища с контекстом запроса
+func GetGaugesByNamesContext(ctx context.Context, storage IStorage, names []string) (map[string]Gauge, error) {
+	if st, ok := storage.(IContextBatchReadingStorage); ok {
+		return st.GetGaugesByNamesContext(ctx, names)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return GetGaugesByNames(storage, names)
+}
+
+// GetCountersByNamesContext значения counter с указанными именами из любого хранилища с контекстом запроса
+func GetCountersByNamesContext(ctx context.Context, storage IStorage, names []string) (map[string]Counter, error) {
+	if st, ok := storage.(IContextBatchReadingStorage); ok {
+		return st.GetCountersByNamesContext(ctx, names)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return GetCountersByNames(storage, names)
+}
+
+// ListContext страница списка метрик из хранилища с контекстом запроса
+func ListContext(ctx context.Context, storage IListingStorage, query ListQuery) ([]ListedMetric, error) {
+	if st, ok := storage.(IContextListingStorage); ok {
+		return st.ListContext(ctx, query)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return storage.List(query)
+}
+
+// ImportContext загрузка метрик и их описаний в любое хранилище с контекстом запроса.
+// Описания объединяются с сохранёнными и не удаляются при replace: это справочник, как и в IMetadataStorage.
+// Если описания есть, а хранилище не умеет их хранить, то возвращается ErrorMetadataNotSupported
+func ImportContext(ctx context.Context, storage IStorage, list []ListedMetric, metadata []MetadataEntry, replace bool) error {
+	// Описания проверяются до загрузки метрик, чтобы не загрузить снимок частично
+	if len(metadata) > 0 {
+		if _, err := metadataStorage(storage); err != nil {
+			return err
+		}
+		for _, entry := range metadata {
+			if err := entry.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	var err error
+	if st, ok := storage.(IContextImportingStorage); ok {
+		err = st.ImportContext(ctx, list, replace)
+	} else if err = ctx.Err(); err == nil {
+		err = Import(storage, list, replace)
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range metadata {
+		if err = SetMetadataContext(ctx, storage, entry.Key(), entry.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
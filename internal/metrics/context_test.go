@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "active_context", ctx: context.Background()},
+		{name: "cancelled_context", ctx: cancelled, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := NewMemStorage()
+			require.NoError(t, mem.AddCounter("PollCount", 1))
+			store := WithContext(mem)
+
+			assert.ErrorIs(t, store.SetGaugeContext(tt.ctx, "Alloc", 1.5), tt.wantErr)
+			assert.ErrorIs(t, store.AddCountersContext(tt.ctx, map[string]Counter{"PollCount": 2}), tt.wantErr)
+			_, _, err := store.GetCounterContext(tt.ctx, "PollCount")
+			assert.ErrorIs(t, err, tt.wantErr)
+			_, err = GetGaugesByNamesContext(tt.ctx, mem, []string{"Alloc"})
+			assert.ErrorIs(t, err, tt.wantErr)
+			_, err = ListContext(tt.ctx, mem, ListQuery{})
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			// После отмены контекста хранилище не меняется
+			counter, _ := mem.GetCounter("PollCount")
+			gauge, ok := mem.GetGauge("Alloc")
+			if tt.wantErr != nil {
+				assert.Equal(t, Counter(1), counter)
+				assert.False(t, ok)
+				return
+			}
+			assert.Equal(t, Counter(3), counter)
+			assert.Equal(t, Gauge(1.5), gauge)
+		})
+	}
+}
+
+func TestWithContext_DBStorage(t *testing.T) {
+	store := &DBStorage{IStorage: NewMemStorage(), storeCtx: context.Background()}
+	assert.Same(t, store, WithContext(store))
+}
+
+func TestDBStorage_RetryStopsOnCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	executor := NewMockSQLExecutor(ctrl)
+	// Ошибка соединения повторяется, пока не отменится контекст
+	executor.EXPECT().ExecContext(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "").
+		Return(nil, &pgconn.PgError{Code: pgerrcode.ConnectionException}).Times(1)
+	store := &DBStorage{IStorage: NewMemStorage(), storeCtx: context.Background(), db: executor, syncMode: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := store.SetGaugeContext(ctx, "Alloc", 1.5)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// copyMinBatch с какого размера пачки метрики записываются в бд командой COPY. Меньшие пачки
+// быстрее записать подготовленным запросом, чем создавать промежуточную таблицу
+const copyMinBatch = 64
+
+// copyColumns колонки промежуточной таблицы, в которую загружаются метрики
+var copyColumns = []string{"name", "value", "updated_at"}
+
+// copyUpsert запросы загрузки пачки метрик одного типа через промежуточную таблицу
+type copyUpsert struct {
+	// stage промежуточная таблица
+	stage string
+	// createStage создание промежуточной таблицы. Временная таблица не пишется в журнал WAL,
+	// живёт, пока живёт соединение, и очищается после каждой транзакции
+	createStage string
+	// merge слияние промежуточной таблицы с таблицей метрик тенанта одним запросом. Тенант - параметр $1
+	merge string
+}
+
+// copyGauges загрузка gauge
+var copyGauges = copyUpsert{
+	stage:       "s_gauge",
+	createStage: "CREATE TEMP TABLE IF NOT EXISTS s_gauge (name varchar, value double precision, updated_at timestamp) ON COMMIT DELETE ROWS",
+	merge: "INSERT INTO t_gauge (tenant, name, value, updated_at) SELECT $1::varchar, name, value, updated_at FROM s_gauge" +
+		" on conflict (tenant, name) do update set value = excluded.value, updated_at = excluded.updated_at",
+}
+
+// copyCounters загрузка counter с прибавлением к значению в бд
+var copyCounters = copyUpsert{
+	stage:       "s_counter",
+	createStage: "CREATE TEMP TABLE IF NOT EXISTS s_counter (name varchar, value bigint, updated_at timestamp) ON COMMIT DELETE ROWS",
+	merge: "INSERT INTO t_counter (tenant, name, value, updated_at) SELECT $1::varchar, name, value, updated_at FROM s_counter" +
+		" on conflict (tenant, name) do update set value = t_counter.value + excluded.value, updated_at = excluded.updated_at",
+}
+
+// copySetCounters загрузка counter с заменой значения в бд
+var copySetCounters = copyUpsert{
+	stage:       copyCounters.stage,
+	createStage: copyCounters.createStage,
+	merge: "INSERT INTO t_counter (tenant, name, value, updated_at) SELECT $1::varchar, name, value, updated_at FROM s_counter" +
+		" on conflict (tenant, name) do update set value = excluded.value, updated_at = excluded.updated_at",
+}
+
+// copyUpsertValues записывает метрики в бд командой COPY в промежуточную таблицу и одним запросом слияния.
+// Возвращает ErrorCopyNotSupported, если бд или подключение к ней не умеют COPY
+func copyUpsertValues[V Gauge | Counter](ctx context.Context, storage *DBStorage, upsert copyUpsert, values map[string]V, now time.Time) error {
+	executor, ok := storage.db.(ICopyExecutor)
+	if !ok || !storage.sqlDialect().bulkCopy {
+		return ErrorCopyNotSupported
+	}
+	rows := make([][]any, 0, len(values))
+	for name, value := range values {
+		rows = append(rows, []any{name, value, now})
+	}
+	return executor.CopyTx(ctx, func(tx ICopyTX) error {
+		if err := tx.ExecContext(ctx, upsert.createStage); err != nil {
+			return err
+		}
+		if _, err := tx.CopyFrom(ctx, upsert.stage, copyColumns, rows); err != nil {
+			return err
+		}
+		return tx.ExecContext(ctx, upsert.merge, storage.tenant)
+	})
+}
+
+// isCopyUnsupported нужно ли записать пачку по одной метрике, потому что бд не дала загрузить её командой COPY
+func isCopyUnsupported(err error) bool {
+	if errors.Is(err, ErrorCopyNotSupported) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == pgerrcode.FeatureNotSupported || pgErr.Code == pgerrcode.InsufficientPrivilege)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+)
+
+// sqliteTimeFormat формат времени в SQLite. SQLite хранит время строкой, поэтому время приводится к UTC
+// и записывается с фиксированным числом знаков, чтобы строки сравнивались так же, как время
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// sqlDialect особенности SQL бд, в которой хранятся метрики
+type sqlDialect struct {
+	// collate сравнение имён побайтово, чтобы порядок в бд совпадал с порядком в памяти
+	collate string
+	// nullGauge, nullCounter, nullData и nullSketch пустые значения метрик нужного типа для объединения таблиц
+	nullGauge   string
+	nullCounter string
+	nullData    string
+	nullSketch  string
+	// bulkCopy умеет ли бд загружать большие пачки метрик командой COPY
+	bulkCopy bool
+	// time значение параметра запроса для времени
+	time func(t time.Time) any
+	// hasPrefix условие, что имя начинается с префикса; arg добавляет параметр и возвращает его обозначение
+	hasPrefix func(arg func(value any) string, prefix string) string
+	// inNames условие, что имя есть в списке
+	inNames func(arg func(value any) string, names []string) string
+}
+
+// postgresDialect диалект Postgres
+var postgresDialect = &sqlDialect{
+	collate:     ` COLLATE "C"`,
+	nullGauge:   "NULL::double precision",
+	nullCounter: "NULL::bigint",
+	nullData:    "NULL::text",
+	nullSketch:  "NULL::bytea",
+	bulkCopy:    true,
+	time: func(t time.Time) any {
+		return t
+	},
+	hasPrefix: func(arg func(value any) string, prefix string) string {
+		return `name COLLATE "C" LIKE ` + arg(likeEscaper.Replace(prefix)+"%")
+	},
+	inNames: func(arg func(value any) string, names []string) string {
+		return "name = ANY(" + arg(names) + ")"
+	},
+}
+
+// sqliteDialect диалект SQLite. Строки в SQLite по умолчанию сравниваются побайтово,
+// а LIKE не различает регистр, поэтому префикс проверяется сравнением начала имени
+var sqliteDialect = &sqlDialect{
+	collate:     "",
+	nullGauge:   "NULL",
+	nullCounter: "NULL",
+	nullData:    "NULL",
+	nullSketch:  "NULL",
+	time: func(t time.Time) any {
+		return t.UTC().Format(sqliteTimeFormat)
+	},
+	hasPrefix: func(arg func(value any) string, prefix string) string {
+		param := arg(prefix)
+		return "substr(name, 1, length(" + param + ")) = " + param
+	},
+	inNames: func(arg func(value any) string, names []string) string {
+		params := make([]string, 0, len(names))
+		for _, name := range names {
+			params = append(params, arg(name))
+		}
+		return "name IN (" + strings.Join(params, ", ") + ")"
+	},
+}
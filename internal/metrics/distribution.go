@@ -0,0 +1,357 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"gmetrics/internal/payload"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultSummaryWindow за какой период последних наблюдений считаются квантили сводки
+	DefaultSummaryWindow = 10 * time.Minute
+	// MaxSummaryObservations сколько последних наблюдений хранит сводка, более старые отбрасываются
+	MaxSummaryObservations = 1000
+)
+
+var (
+	// ErrorWrongHistogram ошибка, что границы корзин гистограммы не возрастают или количества не сходятся
+	ErrorWrongHistogram = errors.New("wrong histogram buckets")
+	// ErrorHistogramBounds ошибка, что границы корзин гистограммы не совпадают с границами сохранённой гистограммы
+	ErrorHistogramBounds = errors.New("histogram bucket bounds mismatch")
+	// ErrorWrongObservation ошибка, что наблюдение сводки не конечное число
+	ErrorWrongObservation = errors.New("summary observation must be a finite number")
+	// ErrorDistributionNotSupported ошибка, что хранилище не умеет хранить гистограммы и сводки
+	ErrorDistributionNotSupported = errors.New("storage does not support histograms and summaries")
+)
+
+// DefaultHistogramBounds границы корзин гистограммы по умолчанию, подходят для задержек в секундах
+var DefaultHistogramBounds = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// SummaryQuantiles квантили, которые считаются по наблюдениям сводки
+var SummaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Histogram гистограмма с фиксированными корзинами. Counts[i] - количество наблюдений больше Bounds[i-1]
+// и не больше Bounds[i], последний элемент Counts - количество наблюдений больше всех границ
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// NewHistogram пустая гистограмма с границами корзин bounds
+func NewHistogram(bounds []float64) Histogram {
+	return Histogram{Bounds: slices.Clone(bounds), Counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe добавление наблюдения в гистограмму
+func (h *Histogram) Observe(value float64) {
+	i, _ := slices.BinarySearch(h.Bounds, value)
+	h.Counts[i]++
+	h.Sum += value
+	h.Count++
+}
+
+// Validate проверка, что границы корзин возрастают, а количество наблюдений совпадает с суммой корзин
+func (h Histogram) Validate() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return ErrorWrongHistogram
+	}
+	for i, bound := range h.Bounds {
+		if math.IsNaN(bound) || math.IsInf(bound, 0) || (i > 0 && bound <= h.Bounds[i-1]) {
+			return ErrorWrongHistogram
+		}
+	}
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	if count != h.Count || math.IsNaN(h.Sum) {
+		return ErrorWrongHistogram
+	}
+	return nil
+}
+
+// Merge сумма гистограмм с одинаковыми границами корзин. Исходные гистограммы не меняются
+func (h Histogram) Merge(other Histogram) (Histogram, error) {
+	if !slices.Equal(h.Bounds, other.Bounds) {
+		return h, ErrorHistogramBounds
+	}
+	merged := h.Clone()
+	for i, c := range other.Counts {
+		merged.Counts[i] += c
+	}
+	merged.Sum += other.Sum
+	merged.Count += other.Count
+	return merged, nil
+}
+
+// Clone копия гистограммы, не разделяющая с ней память
+func (h Histogram) Clone() Histogram {
+	return Histogram{Bounds: slices.Clone(h.Bounds), Counts: slices.Clone(h.Counts), Sum: h.Sum, Count: h.Count}
+}
+
+// HistogramFromPayload гистограмма из тела запроса
+func HistogramFromPayload(h payload.Histogram) Histogram {
+	return Histogram{Bounds: slices.Clone(h.Bounds), Counts: slices.Clone(h.Counts), Sum: h.Sum, Count: h.Count}
+}
+
+// Payload гистограмма для тела ответа
+func (h Histogram) Payload() payload.Histogram {
+	return payload.Histogram{Bounds: slices.Clone(h.Bounds), Counts: slices.Clone(h.Counts), Sum: h.Sum, Count: h.Count}
+}
+
+// Observation наблюдение сводки
+type Observation struct {
+	Value float64   `json:"v"`
+	Time  time.Time `json:"t"`
+}
+
+// Summary наблюдения сводки за скользящее окно в порядке времени
+type Summary struct {
+	Observations []Observation `json:"observations"`
+}
+
+// Observe сводка с добавленными наблюдениями. Наблюдения старше окна и сверх MaxSummaryObservations отбрасываются
+func (s Summary) Observe(observations []Observation, now time.Time, window time.Duration) Summary {
+	all := append(slices.Clone(s.Observations), observations...)
+	slices.SortStableFunc(all, func(a, b Observation) int {
+		return a.Time.Compare(b.Time)
+	})
+	start := max(len(all)-MaxSummaryObservations, 0)
+	for start < len(all) && now.Sub(all[start].Time) > window {
+		start++
+	}
+	return Summary{Observations: all[start:]}
+}
+
+// Validate проверка, что все наблюдения сводки конечные числа
+func (s Summary) Validate() error {
+	for _, observation := range s.Observations {
+		if math.IsNaN(observation.Value) || math.IsInf(observation.Value, 0) {
+			return ErrorWrongObservation
+		}
+	}
+	return nil
+}
+
+// Updated время последнего наблюдения
+func (s Summary) Updated() time.Time {
+	if len(s.Observations) == 0 {
+		return time.Time{}
+	}
+	return s.Observations[len(s.Observations)-1].Time
+}
+
+// Value количество, сумма и квантили наблюдений за окно до now
+func (s Summary) Value(now time.Time, window time.Duration) SummaryValue {
+	values := make([]float64, 0, len(s.Observations))
+	var value SummaryValue
+	for _, observation := range s.Observations {
+		if now.Sub(observation.Time) <= window {
+			values = append(values, observation.Value)
+			value.Sum += observation.Value
+		}
+	}
+	value.Count = uint64(len(values))
+	if len(values) == 0 {
+		return value
+	}
+	slices.Sort(values)
+	value.Quantiles = make([]Quantile, 0, len(SummaryQuantiles))
+	for _, q := range SummaryQuantiles {
+		// Ближайший ранг: наименьшее наблюдение, не меньше которого q доля наблюдений
+		rank := int(math.Ceil(q*float64(len(values)))) - 1
+		value.Quantiles = append(value.Quantiles, Quantile{Quantile: q, Value: values[max(rank, 0)]})
+	}
+	return value
+}
+
+// Quantile значение квантиля сводки
+type Quantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// SummaryValue количество, сумма и квантили наблюдений сводки за окно
+type SummaryValue struct {
+	Count     uint64     `json:"count"`
+	Sum       float64    `json:"sum"`
+	Quantiles []Quantile `json:"quantiles,omitempty"`
+}
+
+// Payload сводка для тела ответа, квантили записываются по их строковому значению
+func (v SummaryValue) Payload() payload.Summary {
+	summary := payload.Summary{Count: v.Count, Sum: v.Sum}
+	if len(v.Quantiles) > 0 {
+		summary.Quantiles = make(map[string]float64, len(v.Quantiles))
+		for _, q := range v.Quantiles {
+			summary.Quantiles[strconv.FormatFloat(q.Quantile, 'f', -1, 64)] = q.Value
+		}
+	}
+	return summary
+}
+
+// NewObservations наблюдения со значениями values в момент now
+func NewObservations(values []float64, now time.Time) ([]Observation, error) {
+	observations := make([]Observation, 0, len(values))
+	for _, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, ErrorWrongObservation
+		}
+		observations = append(observations, Observation{Value: value, Time: now})
+	}
+	return observations, nil
+}
+
+// IDistributionStorage хранилище гистограмм и сводок
+type IDistributionStorage interface {
+	// AddHistogram прибавляет гистограмму к сохранённой, границы корзин должны совпадать
+	AddHistogram(name string, histogram Histogram) error
+	// AddSummary добавляет наблюдения в сводку
+	AddSummary(name string, values []float64) error
+	// GetHistogram получение отдельной гистограммы
+	GetHistogram(name string) (Histogram, bool)
+	// GetSummary количество, сумма и квантили отдельной сводки
+	GetSummary(name string) (SummaryValue, bool)
+	// GetHistograms получение всех гистограмм
+	GetHistograms() (map[string]Histogram, error)
+	// GetSummaries количество, сумма и квантили всех сводок
+	GetSummaries() (map[string]SummaryValue, error)
+}
+
+// IContextDistributionStorage хранилище гистограмм и сводок, запись в которое прерывается при отмене контекста
+type IContextDistributionStorage interface {
+	// AddHistogramContext прибавляет гистограмму к сохранённой
+	AddHistogramContext(ctx context.Context, name string, histogram Histogram) error
+	// AddSummaryContext добавляет наблюдения в сводку
+	AddSummaryContext(ctx context.Context, name string, values []float64) error
+}
+
+// distributionLoader хранилище в памяти, в которое гистограммы и сводки загружаются из бд или журнала вместе со временем
+type distributionLoader interface {
+	// loadHistogram замена гистограммы
+	loadHistogram(name string, histogram Histogram, updated time.Time)
+	// loadSummary замена наблюдений сводки
+	loadSummary(name string, summary Summary)
+	// summaryObservations наблюдения сводки для записи
+	summaryObservations(name string) (Summary, bool)
+}
+
+// distributionStorage хранилище гистограмм и сводок внутри storage.
+// Если хранилище не умеет хранить гистограммы и сводки, то возвращается ErrorDistributionNotSupported
+func distributionStorage(storage IStorage) (IDistributionStorage, error) {
+	if st, ok := storage.(IDistributionStorage); ok {
+		return st, nil
+	}
+	return nil, ErrorDistributionNotSupported
+}
+
+// HistogramObservation гистограмма из одного наблюдения с границами корзин сохранённой гистограммы,
+// а если её нет, то с DefaultHistogramBounds
+func HistogramObservation(storage IStorage, name string, value float64) (Histogram, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Histogram{}, ErrorWrongObservation
+	}
+	st, err := distributionStorage(storage)
+	if err != nil {
+		return Histogram{}, err
+	}
+	bounds := DefaultHistogramBounds
+	if old, ok := st.GetHistogram(name); ok {
+		bounds = old.Bounds
+	}
+	histogram := NewHistogram(bounds)
+	histogram.Observe(value)
+	return histogram, nil
+}
+
+// CheckHistogram проверка до записи, что гистограмма прибавится к сохранённой: хранилище умеет хранить гистограммы,
+// гистограмма верна, а границы её корзин совпадают с границами сохранённой гистограммы
+func CheckHistogram(storage IStorage, name string, histogram Histogram) error {
+	if err := CheckSupported(storage, TypeHistogram); err != nil {
+		return err
+	}
+	if err := histogram.Validate(); err != nil {
+		return err
+	}
+	if old, ok := GetHistogramByName(storage, name); ok && !slices.Equal(old.Bounds, histogram.Bounds) {
+		return ErrorHistogramBounds
+	}
+	return nil
+}
+
+// AddHistogramContext прибавление гистограммы в любом хранилище с контекстом запроса.
+// Если хранилище не умеет хранить гистограммы, то возвращается ErrorDistributionNotSupported
+func AddHistogramContext(ctx context.Context, storage IStorage, name string, histogram Histogram) error {
+	if err := histogram.Validate(); err != nil {
+		return err
+	}
+	if st, ok := storage.(IContextDistributionStorage); ok {
+		return st.AddHistogramContext(ctx, name, histogram)
+	}
+	st, ok := storage.(IDistributionStorage)
+	if !ok {
+		return ErrorDistributionNotSupported
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return st.AddHistogram(name, histogram)
+}
+
+// AddSummaryContext добавление наблюдений сводки в любом хранилище с контекстом запроса.
+// Если хранилище не умеет хранить сводки, то возвращается ErrorDistributionNotSupported
+func AddSummaryContext(ctx context.Context, storage IStorage, name string, values []float64) error {
+	if st, ok := storage.(IContextDistributionStorage); ok {
+		return st.AddSummaryContext(ctx, name, values)
+	}
+	st, ok := storage.(IDistributionStorage)
+	if !ok {
+		return ErrorDistributionNotSupported
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return st.AddSummary(name, values)
+}
+
+// GetHistogramByName гистограмма из любого хранилища. В хранилище без гистограмм гистограмм нет
+func GetHistogramByName(storage IStorage, name string) (Histogram, bool) {
+	st, ok := storage.(IDistributionStorage)
+	if !ok {
+		return Histogram{}, false
+	}
+	return st.GetHistogram(name)
+}
+
+// GetSummaryByName сводка из любого хранилища. В хранилище без сводок сводок нет
+func GetSummaryByName(storage IStorage, name string) (SummaryValue, bool) {
+	st, ok := storage.(IDistributionStorage)
+	if !ok {
+		return SummaryValue{}, false
+	}
+	return st.GetSummary(name)
+}
+
+// GetDistributions все гистограммы и сводки из любого хранилища.
+// Для хранилища без гистограмм и сводок возвращаются пустые списки
+func GetDistributions(storage IStorage) (map[string]Histogram, map[string]SummaryValue, error) {
+	st, ok := storage.(IDistributionStorage)
+	if !ok {
+		return map[string]Histogram{}, map[string]SummaryValue{}, nil
+	}
+	histograms, err := st.GetHistograms()
+	if err != nil {
+		return nil, nil, err
+	}
+	summaries, err := st.GetSummaries()
+	if err != nil {
+		return nil, nil, err
+	}
+	return histograms, summaries, nil
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	histogram := NewHistogram([]float64{1, 5, 10})
+	for _, value := range []float64{0.5, 1, 3, 7, 20, 100} {
+		histogram.Observe(value)
+	}
+	assert.Equal(t, []uint64{2, 1, 1, 2}, histogram.Counts)
+	assert.Equal(t, uint64(6), histogram.Count)
+	assert.InDelta(t, 131.5, histogram.Sum, 1e-9)
+	assert.NoError(t, histogram.Validate())
+}
+
+func TestHistogram_Validate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		histogram Histogram
+		wantErr   error
+	}{
+		{name: "empty", histogram: NewHistogram(nil)},
+		{name: "valid", histogram: Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Sum: 10, Count: 3}},
+		{name: "counts_length", histogram: Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 2}, Count: 3}, wantErr: ErrorWrongHistogram},
+		{name: "not_increasing", histogram: Histogram{Bounds: []float64{2, 1}, Counts: []uint64{0, 0, 0}}, wantErr: ErrorWrongHistogram},
+		{name: "infinite_bound", histogram: Histogram{Bounds: []float64{math.Inf(1)}, Counts: []uint64{0, 0}}, wantErr: ErrorWrongHistogram},
+		{name: "wrong_count", histogram: Histogram{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 3}, wantErr: ErrorWrongHistogram},
+		{name: "nan_sum", histogram: Histogram{Bounds: []float64{1}, Counts: []uint64{0, 0}, Sum: math.NaN()}, wantErr: ErrorWrongHistogram},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.histogram.Validate(), tc.wantErr)
+		})
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	first := Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Sum: 10, Count: 3}
+	second := Histogram{Bounds: []float64{1, 2}, Counts: []uint64{0, 4, 1}, Sum: 11, Count: 5}
+	merged, err := first.Merge(second)
+	require.NoError(t, err)
+	assert.Equal(t, Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 4, 3}, Sum: 21, Count: 8}, merged)
+	// Исходная гистограмма не меняется
+	assert.Equal(t, []uint64{1, 0, 2}, first.Counts)
+
+	_, err = first.Merge(NewHistogram([]float64{1, 3}))
+	assert.ErrorIs(t, err, ErrorHistogramBounds)
+}
+
+func TestSummary(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	values := make([]float64, 0, 100)
+	for i := 100; i > 0; i-- {
+		values = append(values, float64(i))
+	}
+	observations, err := NewObservations(values, now.Add(-time.Minute))
+	require.NoError(t, err)
+	old, err := NewObservations([]float64{1000}, now.Add(-time.Hour))
+	require.NoError(t, err)
+	summary := Summary{}.Observe(append(old, observations...), now, DefaultSummaryWindow)
+	// Наблюдение старше окна отбрасывается
+	assert.Len(t, summary.Observations, 100)
+	assert.Equal(t, now.Add(-time.Minute), summary.Updated())
+
+	value := summary.Value(now, DefaultSummaryWindow)
+	assert.Equal(t, SummaryValue{Count: 100, Sum: 5050, Quantiles: []Quantile{
+		{Quantile: 0.5, Value: 50},
+		{Quantile: 0.9, Value: 90},
+		{Quantile: 0.99, Value: 99},
+	}}, value)
+	// Вне окна наблюдений нет
+	assert.Equal(t, SummaryValue{}, summary.Value(now.Add(time.Hour), DefaultSummaryWindow))
+	assert.True(t, Summary{}.Updated().IsZero())
+
+	_, err = NewObservations([]float64{1, math.NaN()}, now)
+	assert.ErrorIs(t, err, ErrorWrongObservation)
+}
+
+func TestSummary_MaxObservations(t *testing.T) {
+	now := time.Now()
+	values := make([]float64, MaxSummaryObservations+10)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	observations, err := NewObservations(values, now)
+	require.NoError(t, err)
+	summary := Summary{}.Observe(observations, now, DefaultSummaryWindow)
+	require.Len(t, summary.Observations, MaxSummaryObservations)
+	assert.Equal(t, float64(10), summary.Observations[0].Value)
+}
+
+func TestAddDistributionContext(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStorage()
+	histogram := NewHistogram([]float64{1})
+	histogram.Observe(0.5)
+	require.NoError(t, AddHistogramContext(ctx, store, "latency", histogram))
+	require.NoError(t, AddSummaryContext(ctx, store, "size", []float64{3}))
+	got, ok := store.GetHistogram("latency")
+	assert.True(t, ok)
+	assert.Equal(t, histogram, got)
+
+	assert.ErrorIs(t, AddHistogramContext(ctx, store, "latency", Histogram{Counts: []uint64{1}}), ErrorWrongHistogram)
+	// Хранилище без гистограмм и сводок
+	assert.ErrorIs(t, AddHistogramContext(ctx, &contextAdapter{IStorage: store}, "latency", histogram), ErrorDistributionNotSupported)
+	assert.ErrorIs(t, AddSummaryContext(ctx, &contextAdapter{IStorage: store}, "size", []float64{1}), ErrorDistributionNotSupported)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, AddHistogramContext(cancelled, store, "latency", histogram), context.Canceled)
+	assert.ErrorIs(t, AddSummaryContext(cancelled, store, "size", []float64{1}), context.Canceled)
+}
+
+func TestCheckHistogram(t *testing.T) {
+	store := NewMemStorage()
+	histogram := NewHistogram([]float64{1})
+	require.NoError(t, CheckHistogram(store, "latency", histogram))
+	require.NoError(t, store.AddHistogram("latency", histogram))
+	require.NoError(t, CheckHistogram(store, "latency", histogram))
+
+	assert.ErrorIs(t, CheckHistogram(store, "latency", NewHistogram([]float64{2})), ErrorHistogramBounds)
+	assert.ErrorIs(t, CheckHistogram(store, "latency", Histogram{Counts: []uint64{1}}), ErrorWrongHistogram)
+	// Хранилище с бд проверяет хранилище в памяти, в котором хранятся гистограммы
+	assert.ErrorIs(t, CheckHistogram(&DBStorage{IStorage: &contextAdapter{IStorage: store}}, "latency", histogram), ErrorDistributionNotSupported)
+}
+
+func TestCheckSupported(t *testing.T) {
+	store := NewMemStorage()
+	plain := &contextAdapter{IStorage: store}
+	for _, metricType := range []string{TypeGauge, TypeCounter, TypeHistogram, TypeSummary, TypeSet} {
+		assert.NoError(t, CheckSupported(store, metricType), metricType)
+		assert.NoError(t, CheckSupported(&DBStorage{IStorage: store}, metricType), metricType)
+	}
+	assert.NoError(t, CheckSupported(plain, TypeCounter))
+	assert.ErrorIs(t, CheckSupported(plain, TypeSummary), ErrorDistributionNotSupported)
+	assert.ErrorIs(t, CheckSupported(&DBStorage{IStorage: plain}, TypeSet), ErrorSetNotSupported)
+	assert.ErrorIs(t, CheckSupported(store, "unknown"), ErrorUnknownMetricType)
+}
+
+func TestGetDistributions(t *testing.T) {
+	store := NewMemStorage()
+	require.NoError(t, store.AddHistogram("latency", NewHistogram([]float64{1})))
+	require.NoError(t, store.AddSummary("size", []float64{3}))
+
+	histogram, ok := GetHistogramByName(store, "latency")
+	assert.True(t, ok)
+	assert.Equal(t, []float64{1}, histogram.Bounds)
+	summary, ok := GetSummaryByName(store, "size")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), summary.Count)
+	histograms, summaries, err := GetDistributions(store)
+	require.NoError(t, err)
+	assert.Len(t, histograms, 1)
+	assert.Len(t, summaries, 1)
+
+	// Хранилище без гистограмм и сводок
+	plain := &contextAdapter{IStorage: store}
+	_, ok = GetHistogramByName(plain, "latency")
+	assert.False(t, ok)
+	_, ok = GetSummaryByName(plain, "size")
+	assert.False(t, ok)
+	histograms, summaries, err = GetDistributions(plain)
+	require.NoError(t, err)
+	assert.Empty(t, histograms)
+	assert.Empty(t, summaries)
+}
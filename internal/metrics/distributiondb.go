@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"gmetrics/internal/logger"
+	"time"
+)
+
+// distributionTables таблицы бд гистограмм, сводок и множеств по типу метрики
+var distributionTables = map[string]string{
+	TypeHistogram: "t_histogram",
+	TypeSummary:   "t_summary",
+	TypeSet:       "t_set",
+}
+
+// AddHistogram прибавление гистограммы. Гистограммы не проходят через очередь записи в фоне:
+// в синхронном режиме и при записи в фоне гистограмма записывается в бд сразу, иначе при синхронизации
+func (storage *DBStorage) AddHistogram(name string, histogram Histogram) error {
+	return storage.AddHistogramContext(storage.storeCtx, name, histogram)
+}
+
+// AddHistogramContext прибавление гистограммы, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) AddHistogramContext(ctx context.Context, name string, histogram Histogram) error {
+	_, _, err := storage.AddHistogramReturningNew(ctx, name, histogram)
+	return err
+}
+
+// AddHistogramReturningNew прибавление гистограммы, возвращает гистограммы до и после прибавления из памяти
+func (storage *DBStorage) AddHistogramReturningNew(ctx context.Context, name string, histogram Histogram) (old *Histogram, updated Histogram, err error) {
+	if old, updated, err = AddHistogramReturningNew(ctx, storage.IStorage, name, histogram); err != nil {
+		return nil, Histogram{}, err
+	}
+	if !storage.syncMode && storage.queue == nil {
+		return old, updated, nil
+	}
+	return old, updated, storage.saveDistribution(ctx, TypeHistogram, name, updated)
+}
+
+// AddSummary добавление наблюдений сводки. В бд записываются все наблюдения сводки за окно, как и у гистограмм
+func (storage *DBStorage) AddSummary(name string, values []float64) error {
+	return storage.AddSummaryContext(storage.storeCtx, name, values)
+}
+
+// AddSummaryContext добавление наблюдений сводки, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) AddSummaryContext(ctx context.Context, name string, values []float64) error {
+	_, _, err := storage.AddSummaryReturningNew(ctx, name, values)
+	return err
+}
+
+// AddSummaryReturningNew добавление наблюдений сводки, возвращает сводки за окно до и после добавления из памяти
+func (storage *DBStorage) AddSummaryReturningNew(ctx context.Context, name string, values []float64) (old *SummaryValue, updated SummaryValue, err error) {
+	loader, ok := storage.IStorage.(distributionLoader)
+	if !ok {
+		return nil, SummaryValue{}, ErrorDistributionNotSupported
+	}
+	if old, updated, err = AddSummaryReturningNew(ctx, storage.IStorage, name, values); err != nil {
+		return nil, SummaryValue{}, err
+	}
+	if !storage.syncMode && storage.queue == nil {
+		return old, updated, nil
+	}
+	state, ok := loader.summaryObservations(name)
+	if !ok {
+		return old, updated, nil
+	}
+	return old, updated, storage.saveDistribution(ctx, TypeSummary, name, state)
+}
+
+// GetHistogram гистограмма из памяти, в которую при создании хранилища загружаются гистограммы из бд
+func (storage *DBStorage) GetHistogram(name string) (Histogram, bool) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return Histogram{}, false
+	}
+	return st.GetHistogram(name)
+}
+
+// GetSummary сводка из памяти
+func (storage *DBStorage) GetSummary(name string) (SummaryValue, bool) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return SummaryValue{}, false
+	}
+	return st.GetSummary(name)
+}
+
+// GetHistograms все гистограммы из памяти
+func (storage *DBStorage) GetHistograms() (map[string]Histogram, error) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetHistograms()
+}
+
+// GetSummaries все сводки из памяти
+func (storage *DBStorage) GetSummaries() (map[string]SummaryValue, error) {
+	st, err := distributionStorage(storage.IStorage)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetSummaries()
+}
+
+// saveDistribution запись гистограммы или наблюдений сводки в бд целиком
+func (storage *DBStorage) saveDistribution(ctx context.Context, metricType, name string, state any) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	table := distributionTables[metricType]
+	_, err = storage.syncExec(ctx, storage.upsertSQL(table, "data", "$2"), name, string(data), storage.timeArg(time.Now()), storage.tenant)
+	return err
+}
+
+// flushDistributions запись в бд всех гистограмм и сводок из памяти
+func (storage *DBStorage) flushDistributions(ctx context.Context) error {
+	st, ok := storage.IStorage.(IDistributionStorage)
+	if !ok {
+		return nil
+	}
+	loader, ok := storage.IStorage.(distributionLoader)
+	if !ok {
+		return nil
+	}
+	histograms, err := st.GetHistograms()
+	if err != nil {
+		return err
+	}
+	for name, histogram := range histograms {
+		if err = storage.saveDistribution(ctx, TypeHistogram, name, histogram); err != nil {
+			return err
+		}
+	}
+	summaries, err := st.GetSummaries()
+	if err != nil {
+		return err
+	}
+	for name := range summaries {
+		if summary, found := loader.summaryObservations(name); found {
+			if err = storage.saveDistribution(ctx, TypeSummary, name, summary); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreDistributions загрузка гистограмм и сводок из бд в память. Гистограммы считаются обновлёнными
+// в момент восстановления, как и остальные метрики, а у наблюдений сводок сохраняется их время
+func (storage *DBStorage) restoreDistributions(ctx context.Context) error {
+	loader, ok := storage.IStorage.(distributionLoader)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if err := storage.queryDistributions(ctx, TypeHistogram, func(name string, data []byte) error {
+		var histogram Histogram
+		if err := json.Unmarshal(data, &histogram); err != nil {
+			return err
+		}
+		if err := histogram.Validate(); err != nil {
+			return err
+		}
+		loader.loadHistogram(name, histogram, now)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return storage.queryDistributions(ctx, TypeSummary, func(name string, data []byte) error {
+		var summary Summary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return err
+		}
+		loader.loadSummary(name, summary)
+		return nil
+	})
+}
+
+// importedValue значение загружаемой метрики для записи в бд. Гистограмма и наблюдения сводки записываются в JSON, скетч - в двоичном виде, как при сохранении
+func importedValue(metric ListedMetric) (any, error) {
+	switch metric.Type {
+	case TypeHistogram, TypeSummary:
+		var state any = metric.Histogram
+		if metric.Type == TypeSummary {
+			state = metric.Summary
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	case TypeSet:
+		return metric.Set.MarshalBinary()
+	case TypeCounter:
+		return metric.Counter, nil
+	}
+	return metric.Gauge, nil
+}
+
+// decodeListedData значение гистограммы, сводки или множества в списке метрик из данных бд. У gauge и counter данных нет
+func decodeListedData(metric *ListedMetric, data []byte) error {
+	switch metric.Type {
+	case TypeSet:
+		return metric.Set.UnmarshalBinary(data)
+	case TypeHistogram:
+		if err := json.Unmarshal(data, &metric.Histogram); err != nil {
+			return err
+		}
+		return metric.Histogram.Validate()
+	case TypeSummary:
+		return json.Unmarshal(data, &metric.Summary)
+	}
+	return nil
+}
+
+// queryDistributions чтение неустаревших гистограмм, сводок или множеств из бд
+func (storage *DBStorage) queryDistributions(ctx context.Context, metricType string, load func(name string, data []byte) error) error {
+	query := "SELECT name, data FROM " + distributionTables[metricType] + " WHERE " + storage.tenantWhere(1)
+	args := []any{storage.tenant}
+	if storage.ttl > 0 {
+		query += " AND updated_at > $2"
+		args = append(args, storage.timeArg(storage.expiredBefore()))
+	}
+	rows, err := storage.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	// Закроем строки, чтобы освободить соединение
+	defer func() {
+		if rErr := rows.Close(); rErr != nil {
+			logger.Log.Error(rErr)
+		}
+	}()
+	var (
+		name string
+		data string
+	)
+	for rows.Next() {
+		if err = rows.Scan(&name, &data); err != nil {
+			return err
+		}
+		if err = load(name, []byte(data)); err != nil {
+			logger.Log.Infow("Skip broken distribution", "type", metricType, "name", name, "error", err)
+		}
+	}
+	return rows.Err()
+}
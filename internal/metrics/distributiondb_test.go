@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectNoDistributions ожидание чтения пустых таблиц гистограмм, сводок, множеств и описаний при восстановлении хранилища
+func expectNoDistributions(ctrl *gomock.Controller, executor *MockSQLExecutor) {
+	queries := []string{
+		"SELECT name, data FROM t_histogram WHERE tenant = $1",
+		"SELECT name, data FROM t_summary WHERE tenant = $1",
+		"SELECT name, data FROM t_set WHERE tenant = $1",
+		"SELECT type, name, unit, help, owner FROM t_metadata WHERE tenant = $1",
+	}
+	for _, query := range queries {
+		rows := NewMockIRows(ctrl)
+		rows.EXPECT().Next().Return(false)
+		rows.EXPECT().Err().Return(nil)
+		rows.EXPECT().Close().Return(nil)
+		executor.EXPECT().QueryContext(gomock.Any(), query, "").Return(rows, nil)
+	}
+}
+
+func TestSQLiteStorage_Distributions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		syncMode    bool
+		writeBehind bool
+		wantRows    int
+	}{
+		{name: "sync", syncMode: true, wantRows: 1},
+		{name: "write_behind", writeBehind: true, wantRows: 1},
+		{name: "interval", wantRows: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newSQLiteDB(t)
+			store, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), false, tc.syncMode)
+			require.NoError(t, err)
+			if tc.writeBehind {
+				store.EnableWriteBehind(10)
+			}
+			histogram := NewHistogram([]float64{1, 5})
+			histogram.Observe(3)
+			require.NoError(t, store.AddHistogram("latency", histogram))
+			require.NoError(t, AddHistogramContext(context.Background(), store, "latency", histogram))
+			require.NoError(t, store.AddSummary("size", []float64{1, 2}))
+			require.NoError(t, AddSummaryContext(context.Background(), store, "size", []float64{3}))
+			assert.Equal(t, tc.wantRows, countRows(t, db, "t_histogram"))
+			assert.Equal(t, tc.wantRows, countRows(t, db, "t_summary"))
+
+			// Без синхронного режима гистограммы и сводки записываются при синхронизации
+			require.NoError(t, store.Flush())
+			restored, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), true, true)
+			require.NoError(t, err)
+			got, ok := restored.GetHistogram("latency")
+			assert.True(t, ok)
+			assert.Equal(t, Histogram{Bounds: []float64{1, 5}, Counts: []uint64{0, 2, 0}, Sum: 6, Count: 2}, got)
+			histograms, err := restored.GetHistograms()
+			require.NoError(t, err)
+			assert.Len(t, histograms, 1)
+			summary, ok := restored.GetSummary("size")
+			assert.True(t, ok)
+			assert.Equal(t, SummaryValue{Count: 3, Sum: 6, Quantiles: []Quantile{
+				{Quantile: 0.5, Value: 2}, {Quantile: 0.9, Value: 3}, {Quantile: 0.99, Value: 3},
+			}}, summary)
+			summaries, err := restored.GetSummaries()
+			require.NoError(t, err)
+			assert.Len(t, summaries, 1)
+
+			require.NoError(t, restored.Delete(TypeHistogram, "latency"))
+			assert.Equal(t, 0, countRows(t, db, "t_histogram"))
+			assert.ErrorIs(t, restored.Delete(TypeHistogram, "latency"), ErrorMetricNotFound)
+
+			// Без восстановления таблицы очищаются
+			_, err = NewSQLiteStorage(context.Background(), NewDBAdapter(db), false, true)
+			require.NoError(t, err)
+			assert.Equal(t, 0, countRows(t, db, "t_summary"))
+		})
+	}
+}
+
+func TestDBStorage_DistributionsNotSupported(t *testing.T) {
+	dbStorage := DBStorage{IStorage: &contextAdapter{IStorage: NewMemStorage()}, storeCtx: context.Background()}
+	assert.ErrorIs(t, dbStorage.AddHistogram("latency", NewHistogram(nil)), ErrorDistributionNotSupported)
+	assert.ErrorIs(t, dbStorage.AddSummary("size", []float64{1}), ErrorDistributionNotSupported)
+	_, ok := dbStorage.GetHistogram("latency")
+	assert.False(t, ok)
+	_, ok = dbStorage.GetSummary("size")
+	assert.False(t, ok)
+	_, err := dbStorage.GetHistograms()
+	assert.ErrorIs(t, err, ErrorDistributionNotSupported)
+	_, err = dbStorage.GetSummaries()
+	assert.ErrorIs(t, err, ErrorDistributionNotSupported)
+	// Без хранилища гистограмм в памяти записывать нечего
+	assert.NoError(t, dbStorage.flushDistributions(context.Background()))
+	assert.NoError(t, dbStorage.restoreDistributions(context.Background()))
+}
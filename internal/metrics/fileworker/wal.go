@@ -0,0 +1,135 @@
+package fileworker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// walHeaderSize размер заголовка записи журнала: длина данных и их контрольная сумма
+const walHeaderSize = 8
+
+// MaxWALRecordSize максимальный размер одной записи журнала. Запись с большей длиной считается повреждённой
+const MaxWALRecordSize = 64 << 20
+
+// ErrorWALRecordTooLarge ошибка, что запись больше MaxWALRecordSize
+var ErrorWALRecordTooLarge = errors.New("wal record is too large")
+
+// WAL журнал предзаписи. Записи только добавляются в конец файла, каждая с длиной и контрольной суммой CRC32,
+// поэтому при чтении обрезанная или повреждённая последняя запись отбрасывается
+type WAL struct {
+	file       *os.File
+	mutex      sync.Mutex
+	size       int64
+	syncWrites bool // Сбрасывать ли каждую запись на диск
+}
+
+// OpenWAL открывает журнал, создавая файл, если его нет. Если syncWrites, то каждая запись сразу сбрасывается на диск
+func OpenWAL(filename string, syncWrites bool) (*WAL, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+	return &WAL{file: file, size: info.Size(), syncWrites: syncWrites}, nil
+}
+
+// Append сериализует значение в JSON и добавляет его записью в конец журнала
+func (w *WAL) Append(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(body) > MaxWALRecordSize {
+		return ErrorWALRecordTooLarge
+	}
+	record := make([]byte, walHeaderSize+len(body))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(body))
+	copy(record[walHeaderSize:], body)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if _, err = w.file.WriteAt(record, w.size); err != nil {
+		// Недописанная запись будет отброшена при чтении, а следующая запишется поверх неё
+		return err
+	}
+	w.size += int64(len(record))
+	if w.syncWrites {
+		return w.file.Sync()
+	}
+	return nil
+}
+
+// Replay читает записи журнала с начала и передаёт их данные в apply.
+// Чтение останавливается на первой обрезанной или повреждённой записи, журнал обрезается до последней целой записи.
+// Возвращает количество прочитанных записей и был ли журнал обрезан
+func (w *WAL) Replay(apply func(data []byte) error) (int, bool, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	reader := bufio.NewReader(io.NewSectionReader(w.file, 0, w.size))
+	var offset int64
+	count := 0
+	header := make([]byte, walHeaderSize)
+	for offset < w.size {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length > MaxWALRecordSize {
+			break
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+		if err := apply(body); err != nil {
+			return count, false, err
+		}
+		offset += walHeaderSize + int64(length)
+		count++
+	}
+	if offset == w.size {
+		return count, false, nil
+	}
+	return count, true, w.truncate(offset)
+}
+
+// Truncate очищает журнал, например, после записи снимка хранилища
+func (w *WAL) Truncate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.truncate(0)
+}
+
+// truncate обрезает журнал до size байт
+func (w *WAL) truncate(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return err
+	}
+	w.size = size
+	return w.file.Sync()
+}
+
+// Size размер журнала в байтах
+func (w *WAL) Size() int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.size
+}
+
+// Close закрывает файл журнала
+func (w *WAL) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,130 @@
+package fileworker
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayAll читает все записи журнала как строки
+func replayAll(t *testing.T, wal *WAL) ([]string, bool) {
+	var records []string
+	_, truncated, err := wal.Replay(func(data []byte) error {
+		var record string
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	require.NoError(t, err)
+	return records, truncated
+}
+
+func TestWAL_AppendAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json.wal")
+	wal, err := OpenWAL(path, true)
+	require.NoError(t, err)
+	require.NoError(t, wal.Append("first"))
+	require.NoError(t, wal.Append("second"))
+	require.NoError(t, wal.Close())
+
+	wal, err = OpenWAL(path, false)
+	require.NoError(t, err)
+	defer wal.Close()
+	records, truncated := replayAll(t, wal)
+	assert.Equal(t, []string{"first", "second"}, records)
+	assert.False(t, truncated)
+
+	require.NoError(t, wal.Truncate())
+	assert.Zero(t, wal.Size())
+	records, _ = replayAll(t, wal)
+	assert.Empty(t, records)
+}
+
+func TestWAL_ReplayDamaged(t *testing.T) {
+	tests := []struct {
+		name        string
+		damage      func(t *testing.T, path string, size int64)
+		wantRecords []string
+	}{
+		{
+			name: "truncated_body",
+			damage: func(t *testing.T, path string, size int64) {
+				require.NoError(t, os.Truncate(path, size-2))
+			},
+			wantRecords: []string{"first"},
+		},
+		{
+			name: "truncated_header",
+			damage: func(t *testing.T, path string, _ int64) {
+				file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+				require.NoError(t, err)
+				_, err = file.Write([]byte{0, 0, 0})
+				require.NoError(t, err)
+				require.NoError(t, file.Close())
+			},
+			wantRecords: []string{"first", "second"},
+		},
+		{
+			name: "wrong_checksum",
+			damage: func(t *testing.T, path string, size int64) {
+				file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+				require.NoError(t, err)
+				_, err = file.WriteAt([]byte("x"), size-2)
+				require.NoError(t, err)
+				require.NoError(t, file.Close())
+			},
+			wantRecords: []string{"first"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.json.wal")
+			wal, err := OpenWAL(path, false)
+			require.NoError(t, err)
+			require.NoError(t, wal.Append("first"))
+			require.NoError(t, wal.Append("second"))
+			size := wal.Size()
+			require.NoError(t, wal.Close())
+			tt.damage(t, path, size)
+
+			wal, err = OpenWAL(path, false)
+			require.NoError(t, err)
+			defer wal.Close()
+			records, truncated := replayAll(t, wal)
+			assert.Equal(t, tt.wantRecords, records)
+			assert.True(t, truncated)
+			// Журнал обрезан до последней целой записи, новые записи идут после неё
+			require.NoError(t, wal.Append("third"))
+			records, truncated = replayAll(t, wal)
+			assert.Equal(t, append(tt.wantRecords, "third"), records)
+			assert.False(t, truncated)
+		})
+	}
+}
+
+func TestWAL_ReplayError(t *testing.T) {
+	wal, err := OpenWAL(filepath.Join(t.TempDir(), "storage.json.wal"), false)
+	require.NoError(t, err)
+	defer wal.Close()
+	require.NoError(t, wal.Append("first"))
+	applyErr := errors.New("apply")
+	count, _, err := wal.Replay(func([]byte) error {
+		return applyErr
+	})
+	assert.ErrorIs(t, err, applyErr)
+	assert.Zero(t, count)
+
+	assert.Error(t, wal.Append(make(chan int)))
+}
+
+func TestOpenWAL(t *testing.T) {
+	_, err := OpenWAL(t.TempDir(), false)
+	assert.Error(t, err)
+}
@@ -2,30 +2,37 @@ package fileworker
 
 import (
 	"encoding/json"
-	"io"
+	"errors"
 	"os"
+	"path/filepath"
 	"sync"
 )
 
 // JSONWriter управляет записью данных JSON в файл с потокобезопасностью.
+// Файл перезаписывается атомарно: данные пишутся во временный файл, сбрасываются на диск и переименовываются,
+// поэтому при сбое в файле остаются либо старые, либо новые данные целиком
 type JSONWriter struct {
-	file  *os.File
-	mutex sync.Mutex
+	filename string
+	mutex    sync.Mutex
 }
 
 // NewWriter создает новый JSONWriter для указанного имени файла. Возвращает ошибку в случае неудачи.
 func NewWriter(filename string) (*JSONWriter, error) {
+	// Проверяем, что в файл можно писать, и создаём его, если его нет
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
+	if err = file.Close(); err != nil {
+		return nil, err
+	}
 	return &JSONWriter{
-		file:  file,
-		mutex: sync.Mutex{},
+		filename: filename,
+		mutex:    sync.Mutex{},
 	}, nil
 }
 
-// Write сериализует заданное значение в JSON и записывает его в базовый файл потокобезопасным способом.
+// Write сериализует заданное значение в JSON и атомарно заменяет им содержимое файла потокобезопасным способом.
 func (w *JSONWriter) Write(v any) error {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
@@ -33,27 +40,47 @@ func (w *JSONWriter) Write(v any) error {
 	if err != nil {
 		return err
 	}
-	// Отчищаем содержимое файла
-	err = w.file.Truncate(0) // empty the content
+	// Временный файл создаём рядом, так как переименование атомарно только в пределах файловой системы
+	dir := filepath.Dir(w.filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(w.filename)+".tmp*")
 	if err != nil {
 		return err
 	}
-	// Ставим указатель записи на начало файла
-	_, err = w.file.Seek(0, io.SeekStart)
-	if err != nil {
-		return err
+	if err = tmp.Chmod(0644); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmp.Name()))
 	}
-	// Записываем полученный джейсон в файл
-	_, err = w.file.Write(body)
-	if err != nil {
-		return err
+	if err = writeAndSync(tmp, body); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	if err = os.Rename(tmp.Name(), w.filename); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
 	}
-	//w.file.Sync() // Заставляем записать всё на диск из буферов
+	return syncDir(dir)
+}
 
+// Close завершает работу с файлом. Файл открывается только на время записи, поэтому закрывать нечего
+func (w *JSONWriter) Close() error {
 	return nil
 }
 
-// Close завершает работу с файлом, закрывая его. Возвращает ошибку, если операция не удалась.
-func (w *JSONWriter) Close() error {
-	return w.file.Close()
+// writeAndSync записывает данные в файл, сбрасывает их на диск и закрывает файл
+func writeAndSync(file *os.File, body []byte) error {
+	if _, err := file.Write(body); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	if err := file.Sync(); err != nil {
+		return errors.Join(err, file.Close())
+	}
+	return file.Close()
+}
+
+// syncDir сбрасывает на диск каталог, чтобы переименование файла пережило сбой
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	// Не все системы умеют сбрасывать каталоги, ошибку сброса не считаем ошибкой записи
+	_ = d.Sync()
+	return d.Close()
 }
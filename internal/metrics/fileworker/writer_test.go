@@ -3,9 +3,11 @@ package fileworker
 import (
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewWriter(t *testing.T) {
@@ -85,3 +87,22 @@ func TestJSONWriter_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONWriter_WriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "storage.json")
+	writer, err := NewWriter(path)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(map[string]int{"first": 1}))
+	require.NoError(t, writer.Write(map[string]int{"second": 2}))
+	// Ошибка сериализации не трогает записанный файл
+	assert.Error(t, writer.Write(make(chan int)))
+	require.NoError(t, writer.Close())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"second":2}`, string(raw))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files must be removed")
+}
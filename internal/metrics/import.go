@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+)
+
+// IImportingStorage хранилище, которое умеет загружать метрики вместе со временем их обновления
+type IImportingStorage interface {
+	// Import записывает значения метрик из списка. Если replace, то метрики, которых нет в списке, удаляются.
+	// Метрики без времени обновления считаются обновлёнными в момент загрузки
+	Import(list []ListedMetric, replace bool) error
+}
+
+// Import загрузка метрик в любое хранилище. Если хранилище не умеет загружать метрики,
+// то значения записываются обычными методами, а время обновления не сохраняется
+func Import(storage IStorage, list []ListedMetric, replace bool) error {
+	if st, ok := storage.(IImportingStorage); ok {
+		return st.Import(list, replace)
+	}
+	if err := checkImported(list); err != nil {
+		return err
+	}
+	gauges, counters := splitImported(list)
+	currentCounters, err := storage.GetCounters()
+	if err != nil {
+		return err
+	}
+	if replace {
+		currentGauges, gErr := storage.GetGauges()
+		if gErr != nil {
+			return gErr
+		}
+		for name := range currentGauges {
+			if _, ok := gauges[name]; !ok {
+				if err = storage.Delete(TypeGauge, name); err != nil && !errors.Is(err, ErrorMetricNotFound) {
+					return err
+				}
+			}
+		}
+		for name := range currentCounters {
+			if _, ok := counters[name]; !ok {
+				if err = storage.Delete(TypeCounter, name); err != nil && !errors.Is(err, ErrorMetricNotFound) {
+					return err
+				}
+			}
+		}
+		if err = clearDistributions(storage); err != nil {
+			return err
+		}
+	}
+	if err = storage.SetGauges(gauges); err != nil {
+		return err
+	}
+	// Counter только прибавляется, поэтому существующие сначала обнуляются
+	for name := range counters {
+		if _, ok := currentCounters[name]; ok {
+			if err = storage.ResetCounter(name); err != nil && !errors.Is(err, ErrorMetricNotFound) {
+				return err
+			}
+		}
+	}
+	if err = storage.AddCounters(counters); err != nil {
+		return err
+	}
+	return importDistributions(storage, list)
+}
+
+// checkImported проверка загружаемых метрик до записи, чтобы не загрузить список частично.
+// Если у метрики неизвестный тип, то возвращается ErrorUnknownMetricType
+func checkImported(list []ListedMetric) error {
+	for _, metric := range list {
+		switch metric.Type {
+		case TypeGauge, TypeCounter, TypeSet:
+		case TypeHistogram:
+			if err := metric.Histogram.Validate(); err != nil {
+				return err
+			}
+		case TypeSummary:
+			if err := metric.Summary.Validate(); err != nil {
+				return err
+			}
+		default:
+			return ErrorUnknownMetricType
+		}
+	}
+	return nil
+}
+
+// splitImported значения загружаемых gauge и counter
+func splitImported(list []ListedMetric) (map[string]Gauge, map[string]Counter) {
+	gauges := make(map[string]Gauge)
+	counters := make(map[string]Counter)
+	for _, metric := range list {
+		switch metric.Type {
+		case TypeGauge:
+			gauges[metric.Name] = metric.Gauge
+		case TypeCounter:
+			counters[metric.Name] = metric.Counter
+		}
+	}
+	return gauges, counters
+}
+
+// clearDistributions удаление всех гистограмм, сводок и множеств из хранилища, которое не умеет загружать метрики
+func clearDistributions(storage IStorage) error {
+	histograms, summaries, err := GetDistributions(storage)
+	if err != nil {
+		return err
+	}
+	sets, err := GetSets(storage)
+	if err != nil {
+		return err
+	}
+	deleted := make([]ListKey, 0, len(histograms)+len(summaries)+len(sets))
+	for name := range histograms {
+		deleted = append(deleted, ListKey{Type: TypeHistogram, Name: name})
+	}
+	for name := range summaries {
+		deleted = append(deleted, ListKey{Type: TypeSummary, Name: name})
+	}
+	for name := range sets {
+		deleted = append(deleted, ListKey{Type: TypeSet, Name: name})
+	}
+	for _, key := range deleted {
+		if err = storage.Delete(key.Type, key.Name); err != nil && !errors.Is(err, ErrorMetricNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// importDistributions запись загружаемых гистограмм, сводок и множеств в хранилище, которое не умеет загружать метрики.
+// Сохранённая метрика удаляется, чтобы значение из списка не прибавилось к ней. Наблюдения сводки получают время загрузки
+func importDistributions(storage IStorage, list []ListedMetric) error {
+	for _, metric := range list {
+		if metric.Type == TypeGauge || metric.Type == TypeCounter {
+			continue
+		}
+		if err := storage.Delete(metric.Type, metric.Name); err != nil && !errors.Is(err, ErrorMetricNotFound) {
+			return err
+		}
+		if err := importDistribution(storage, metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importDistribution запись гистограммы, сводки или множества обычными методами хранилища
+func importDistribution(storage IStorage, metric ListedMetric) error {
+	if metric.Type == TypeSet {
+		st, err := setStorage(storage)
+		if err != nil {
+			return err
+		}
+		return st.AddSet(metric.Name, metric.Set)
+	}
+	st, err := distributionStorage(storage)
+	if err != nil {
+		return err
+	}
+	if metric.Type == TypeHistogram {
+		return st.AddHistogram(metric.Name, metric.Histogram)
+	}
+	return st.AddSummary(metric.Name, summaryValues(metric.Summary))
+}
+
+// summaryValues значения наблюдений сводки
+func summaryValues(summary Summary) []float64 {
+	values := make([]float64, 0, len(summary.Observations))
+	for _, observation := range summary.Observations {
+		values = append(values, observation.Value)
+	}
+	return values
+}
+
+// importedAt время обновления загружаемой метрики, now если оно не указано
+func importedAt(metric ListedMetric, now time.Time) time.Time {
+	if metric.UpdatedAt.IsZero() {
+		return now
+	}
+	return metric.UpdatedAt
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainStorage хранилище, которое не умеет загружать метрики само
+type plainStorage struct {
+	IStorage
+}
+
+func TestImport(t *testing.T) {
+	list := []ListedMetric{
+		{Type: TypeGauge, Name: "gauge1", Gauge: 2.5},
+		{Type: TypeCounter, Name: "counter1", Counter: 7},
+		{Type: TypeCounter, Name: "counter3", Counter: 1},
+	}
+	testCases := []struct {
+		name         string
+		list         []ListedMetric
+		replace      bool
+		wantGauges   map[string]Gauge
+		wantCounters map[string]Counter
+		wantErr      error
+	}{
+		{
+			name:         "merge",
+			list:         list,
+			wantGauges:   map[string]Gauge{"gauge1": 2.5, "gauge2": 4},
+			wantCounters: map[string]Counter{"counter1": 7, "counter2": 5, "counter3": 1},
+		},
+		{
+			name:         "replace",
+			list:         list,
+			replace:      true,
+			wantGauges:   map[string]Gauge{"gauge1": 2.5},
+			wantCounters: map[string]Counter{"counter1": 7, "counter3": 1},
+		},
+		{
+			name:         "unknown_type",
+			list:         []ListedMetric{{Type: "unknown", Name: "u"}},
+			replace:      true,
+			wantGauges:   map[string]Gauge{"gauge1": 1.5, "gauge2": 4},
+			wantCounters: map[string]Counter{"counter1": 3, "counter2": 5},
+			wantErr:      ErrorUnknownMetricType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := NewMemStorage()
+			require.NoError(t, mem.SetGauges(map[string]Gauge{"gauge1": 1.5, "gauge2": 4}))
+			require.NoError(t, mem.AddCounters(map[string]Counter{"counter1": 3, "counter2": 5}))
+
+			err := Import(plainStorage{mem}, tc.list, tc.replace)
+			assert.ErrorIs(t, err, tc.wantErr)
+			gauges, _ := mem.GetGauges()
+			counters, _ := mem.GetCounters()
+			assert.Equal(t, tc.wantGauges, gauges)
+			assert.Equal(t, tc.wantCounters, counters)
+		})
+	}
+}
+
+func TestImport_Distributions(t *testing.T) {
+	mem := NewMemStorage()
+	require.NoError(t, mem.AddSet("users", NewSketch("alice", "bob", "carol")))
+	require.NoError(t, mem.AddHistogram("old", NewHistogram([]float64{1})))
+	// Хранилище без загрузки метрик, но с гистограммами и множествами
+	storage := struct {
+		IStorage
+		IDistributionStorage
+		ISetStorage
+	}{mem, mem, mem}
+	latency := NewHistogram([]float64{1})
+	latency.Observe(0.5)
+
+	require.NoError(t, Import(storage, []ListedMetric{
+		{Type: TypeHistogram, Name: "latency", Histogram: latency},
+		{Type: TypeSet, Name: "users", Set: NewSketch("alice")},
+	}, false))
+	histogram, ok := mem.GetHistogram("latency")
+	require.True(t, ok)
+	assert.Equal(t, latency, histogram)
+	// Скетч из списка заменяет сохранённый, а не объединяется с ним
+	users, ok := mem.GetSet("users")
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), users.Estimate())
+
+	require.NoError(t, Import(storage, []ListedMetric{{Type: TypeSet, Name: "visitors", Set: NewSketch("bob")}}, true))
+	sets, err := mem.GetSets()
+	require.NoError(t, err)
+	assert.Len(t, sets, 1)
+	_, ok = mem.GetHistogram("latency")
+	assert.False(t, ok)
+}
+
+func TestImportedAt(t *testing.T) {
+	now := time.Now()
+	updated := now.Add(-time.Hour)
+	assert.Equal(t, updated, importedAt(ListedMetric{UpdatedAt: updated}, now))
+	assert.Equal(t, now, importedAt(ListedMetric{}, now))
+}
@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ListSortName сортировка по имени, затем по типу
+	ListSortName = "name"
+	// ListSortType сортировка по типу, затем по имени
+	ListSortType = "type"
+)
+
+var (
+	// ErrorWrongLabelMatcher ошибка, что условие на метку не в формате key=value, key!=value, key=~regex или key!~regex
+	ErrorWrongLabelMatcher = errors.New("wrong label matcher")
+	// ErrorWrongGlob ошибка, что шаблон имени некорректен
+	ErrorWrongGlob = errors.New("wrong name glob")
+	// ErrorListNotSupported ошибка, что хранилище не умеет отдавать список метрик
+	ErrorListNotSupported = errors.New("storage does not support listing")
+)
+
+// IListingStorage хранилище, которое умеет отдавать отфильтрованный отсортированный список метрик постранично
+type IListingStorage interface {
+	// List возвращает не больше query.Limit метрик, идущих после query.After в порядке сортировки
+	List(query ListQuery) ([]ListedMetric, error)
+}
+
+// ListedMetric метрика в списке
+type ListedMetric struct {
+	Type      string
+	Name      string
+	Gauge     Gauge     // Значение, если Type равен TypeGauge
+	Counter   Counter   // Значение, если Type равен TypeCounter
+	Histogram Histogram // Гистограмма, если Type равен TypeHistogram
+	Summary   Summary   // Наблюдения сводки, если Type равен TypeSummary
+	Set       Sketch    // Скетч множества, если Type равен TypeSet
+	UpdatedAt time.Time
+}
+
+// Key ключ метрики для курсора
+func (m ListedMetric) Key() ListKey {
+	return ListKey{Type: m.Type, Name: m.Name}
+}
+
+// ListKey ключ метрики в списке. Пара тип и имя однозначно определяет метрику
+type ListKey struct {
+	Type string `json:"t"`
+	Name string `json:"n"`
+}
+
+// ListQuery запрос списка метрик
+type ListQuery struct {
+	Filter ListFilter
+	Sort   string   // ListSortName или ListSortType, по умолчанию ListSortName
+	Desc   bool     // Сортировка по убыванию
+	After  *ListKey // Курсор: ключ последней метрики предыдущей страницы
+	Limit  int      // Размер страницы, 0 - без ограничения
+}
+
+// Less идёт ли метрика с ключом a раньше метрики с ключом b в порядке запроса.
+// Строки сравниваются побайтно, как в бд при сортировке с COLLATE "C"
+func (q ListQuery) Less(a, b ListKey) bool {
+	if q.Desc {
+		a, b = b, a
+	}
+	if q.Sort == ListSortType {
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.Type < b.Type
+}
+
+// page сортирует метрики, отбрасывает идущие до курсора включительно и обрезает по размеру страницы
+func (q ListQuery) page(list []ListedMetric) []ListedMetric {
+	sort.Slice(list, func(i, j int) bool {
+		return q.Less(list[i].Key(), list[j].Key())
+	})
+	if q.After != nil {
+		after := *q.After
+		start := sort.Search(len(list), func(i int) bool {
+			return q.Less(after, list[i].Key())
+		})
+		list = list[start:]
+	}
+	if q.Limit > 0 && len(list) > q.Limit {
+		list = list[:q.Limit]
+	}
+	return list
+}
+
+// ListFilter фильтр списка метрик. Пустые поля не фильтруют
+type ListFilter struct {
+	Type   string         // Тип метрики
+	Prefix string         // Префикс полного имени
+	Glob   string         // Шаблон базового имени, синтаксис path.Match
+	Regexp *regexp.Regexp // Регулярное выражение для базового имени
+	Labels []LabelMatcher // Условия на метки, должны выполняться все
+}
+
+// MatchType подходит ли тип метрики
+func (f ListFilter) MatchType(metricType string) bool {
+	return f.Type == "" || f.Type == metricType
+}
+
+// Match подходит ли метрика под фильтр
+func (f ListFilter) Match(metricType, name string) bool {
+	if !f.MatchType(metricType) || !strings.HasPrefix(name, f.Prefix) {
+		return false
+	}
+	return f.MatchName(name)
+}
+
+// MatchName подходит ли имя под шаблон, регулярное выражение и условия на метки
+func (f ListFilter) MatchName(name string) bool {
+	if !f.hasNameConditions() {
+		return true
+	}
+	base, labels := SplitLabels(name)
+	if f.Glob != "" {
+		if ok, _ := path.Match(f.Glob, base); !ok {
+			return false
+		}
+	}
+	if f.Regexp != nil && !f.Regexp.MatchString(base) {
+		return false
+	}
+	for _, matcher := range f.Labels {
+		if !matcher.Match(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasNameConditions есть ли в фильтре условия, которые нельзя проверить по префиксу
+func (f ListFilter) hasNameConditions() bool {
+	return f.Glob != "" || f.Regexp != nil || len(f.Labels) > 0
+}
+
+// namePrefix префикс, с которого начинаются все подходящие имена.
+// Если префикс не задан, то берётся часть шаблона до первого спецсимвола
+func (f ListFilter) namePrefix() string {
+	if f.Prefix != "" || f.Glob == "" {
+		return f.Prefix
+	}
+	if i := strings.IndexAny(f.Glob, `*?[\`); i >= 0 {
+		return f.Glob[:i]
+	}
+	return f.Glob
+}
+
+// ValidateGlob проверяет синтаксис шаблона имени
+func ValidateGlob(glob string) error {
+	if _, err := path.Match(glob, ""); err != nil {
+		return ErrorWrongGlob
+	}
+	return nil
+}
+
+// LabelMatcher условие на значение метки
+type LabelMatcher struct {
+	Name     string
+	Value    string
+	Negative bool           // Условие выполняется, если значение не совпадает
+	Regexp   *regexp.Regexp // Если задано, то значение сравнивается с регулярным выражением, а не с Value
+}
+
+// ParseLabelMatcher разбирает условие вида key=value, key!=value, key=~regex или key!~regex.
+// Регулярное выражение должно совпадать со всем значением метки
+func ParseLabelMatcher(raw string) (LabelMatcher, error) {
+	i := strings.IndexAny(raw, "=!")
+	if i <= 0 {
+		return LabelMatcher{}, ErrorWrongLabelMatcher
+	}
+	matcher := LabelMatcher{Name: raw[:i]}
+	op, isRegexp := raw[i:], false
+	switch {
+	case strings.HasPrefix(op, "!="):
+		matcher.Negative, matcher.Value = true, op[2:]
+	case strings.HasPrefix(op, "!~"):
+		matcher.Negative, matcher.Value, isRegexp = true, op[2:], true
+	case strings.HasPrefix(op, "=~"):
+		matcher.Value, isRegexp = op[2:], true
+	case strings.HasPrefix(op, "="):
+		matcher.Value = op[1:]
+	default:
+		return LabelMatcher{}, ErrorWrongLabelMatcher
+	}
+	if isRegexp {
+		re, err := regexp.Compile("^(?:" + matcher.Value + ")$")
+		if err != nil {
+			return LabelMatcher{}, ErrorWrongLabelMatcher
+		}
+		matcher.Regexp = re
+	}
+	return matcher, nil
+}
+
+// Match выполняется ли условие для меток. Отсутствующая метка считается пустой
+func (m LabelMatcher) Match(labels map[string]string) bool {
+	value := labels[m.Name]
+	var ok bool
+	if m.Regexp != nil {
+		ok = m.Regexp.MatchString(value)
+	} else {
+		ok = value == m.Value
+	}
+	return ok != m.Negative
+}
+
+// SplitLabels разделяет имя метрики на базовое имя и метки.
+// Метки записываются в имени в нотации Prometheus: name{key="value",other="value"}.
+// Если имя не в этой нотации, то всё имя считается базовым и меток нет
+func SplitLabels(name string) (string, map[string]string) {
+	start := strings.IndexByte(name, '{')
+	if start <= 0 || !strings.HasSuffix(name, "}") {
+		return name, nil
+	}
+	labels := make(map[string]string)
+	rest := name[start+1 : len(name)-1]
+	for rest != "" {
+		eq := strings.Index(rest, `="`)
+		if eq <= 0 {
+			return name, nil
+		}
+		key := rest[:eq]
+		rest = rest[eq+2:]
+		var value strings.Builder
+		closed := false
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '\\' && i+1 < len(rest) {
+				i++
+				value.WriteByte(rest[i])
+				continue
+			}
+			if rest[i] == '"' {
+				rest = rest[i+1:]
+				closed = true
+				break
+			}
+			value.WriteByte(rest[i])
+		}
+		if !closed {
+			return name, nil
+		}
+		labels[key] = value.String()
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return name, nil
+		}
+		rest = rest[1:]
+	}
+	return name[:start], labels
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		metric     string
+		wantBase   string
+		wantLabels map[string]string
+	}{
+		{name: "no_labels", metric: "Alloc", wantBase: "Alloc"},
+		{name: "labels", metric: `requests{method="GET",code="200"}`, wantBase: "requests", wantLabels: map[string]string{"method": "GET", "code": "200"}},
+		{name: "escaped", metric: `path{value="a\"b,c"}`, wantBase: "path", wantLabels: map[string]string{"value": `a"b,c`}},
+		{name: "empty_labels", metric: "requests{}", wantBase: "requests", wantLabels: map[string]string{}},
+		{name: "not_closed", metric: `requests{method="GET}`, wantBase: `requests{method="GET}`},
+		{name: "no_quotes", metric: "requests{method=GET}", wantBase: "requests{method=GET}"},
+		{name: "no_base", metric: `{method="GET"}`, wantBase: `{method="GET"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, labels := SplitLabels(tt.metric)
+			assert.Equal(t, tt.wantBase, base)
+			assert.Equal(t, tt.wantLabels, labels)
+		})
+	}
+}
+
+func TestParseLabelMatcher(t *testing.T) {
+	labels := map[string]string{"method": "GET", "code": "200"}
+	tests := []struct {
+		name      string
+		raw       string
+		wantErr   bool
+		wantMatch bool
+	}{
+		{name: "equal", raw: "method=GET", wantMatch: true},
+		{name: "not_equal", raw: "method!=GET", wantMatch: false},
+		{name: "regex", raw: "code=~2..", wantMatch: true},
+		{name: "regex_whole_value", raw: "code=~2", wantMatch: false},
+		{name: "not_regex", raw: "code!~5..", wantMatch: true},
+		{name: "missing_is_empty", raw: "host=", wantMatch: true},
+		{name: "no_name", raw: "=GET", wantErr: true},
+		{name: "no_operator", raw: "method", wantErr: true},
+		{name: "wrong_operator", raw: "method!GET", wantErr: true},
+		{name: "wrong_regex", raw: "code=~(", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := ParseLabelMatcher(tt.raw)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrorWrongLabelMatcher)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, matcher.Match(labels))
+		})
+	}
+}
+
+func TestListFilter_Match(t *testing.T) {
+	method, _ := ParseLabelMatcher("method=GET")
+	tests := []struct {
+		name       string
+		filter     ListFilter
+		metricType string
+		metric     string
+		want       bool
+	}{
+		{name: "empty", filter: ListFilter{}, metricType: TypeGauge, metric: "Alloc", want: true},
+		{name: "type", filter: ListFilter{Type: TypeCounter}, metricType: TypeGauge, metric: "Alloc", want: false},
+		{name: "prefix", filter: ListFilter{Prefix: "Heap"}, metricType: TypeGauge, metric: "HeapAlloc", want: true},
+		{name: "wrong_prefix", filter: ListFilter{Prefix: "Heap"}, metricType: TypeGauge, metric: "Alloc", want: false},
+		{name: "glob_base_name", filter: ListFilter{Glob: "req*s"}, metricType: TypeCounter, metric: `requests{method="GET"}`, want: true},
+		{name: "wrong_glob", filter: ListFilter{Glob: "Heap?"}, metricType: TypeGauge, metric: "HeapAlloc", want: false},
+		{name: "regex", filter: ListFilter{Regexp: regexp.MustCompile("^CPU.*$")}, metricType: TypeGauge, metric: "CPUutilization1", want: true},
+		{name: "label", filter: ListFilter{Labels: []LabelMatcher{method}}, metricType: TypeCounter, metric: `requests{method="GET"}`, want: true},
+		{name: "no_label", filter: ListFilter{Labels: []LabelMatcher{method}}, metricType: TypeCounter, metric: "requests", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.metricType, tt.metric))
+		})
+	}
+}
+
+func TestListFilter_namePrefix(t *testing.T) {
+	assert.Equal(t, "Heap", ListFilter{Prefix: "Heap", Glob: "H*"}.namePrefix())
+	assert.Equal(t, "Heap", ListFilter{Glob: "Heap*"}.namePrefix())
+	assert.Equal(t, "Alloc", ListFilter{Glob: "Alloc"}.namePrefix())
+	assert.Equal(t, "", ListFilter{Glob: "[AB]lloc"}.namePrefix())
+}
+
+func TestValidateGlob(t *testing.T) {
+	assert.NoError(t, ValidateGlob("Heap*"))
+	assert.ErrorIs(t, ValidateGlob("Heap["), ErrorWrongGlob)
+}
+
+func TestListQuery_page(t *testing.T) {
+	list := func() []ListedMetric {
+		return []ListedMetric{
+			{Type: TypeGauge, Name: "b"},
+			{Type: TypeCounter, Name: "b"},
+			{Type: TypeGauge, Name: "a"},
+			{Type: TypeCounter, Name: "c"},
+		}
+	}
+	keys := func(list []ListedMetric) []ListKey {
+		result := make([]ListKey, 0, len(list))
+		for _, metric := range list {
+			result = append(result, metric.Key())
+		}
+		return result
+	}
+	tests := []struct {
+		name  string
+		query ListQuery
+		want  []ListKey
+	}{
+		{
+			name:  "by_name",
+			query: ListQuery{Sort: ListSortName},
+			want:  []ListKey{{TypeGauge, "a"}, {TypeCounter, "b"}, {TypeGauge, "b"}, {TypeCounter, "c"}},
+		},
+		{
+			name:  "by_type_desc",
+			query: ListQuery{Sort: ListSortType, Desc: true},
+			want:  []ListKey{{TypeGauge, "b"}, {TypeGauge, "a"}, {TypeCounter, "c"}, {TypeCounter, "b"}},
+		},
+		{
+			name:  "after_and_limit",
+			query: ListQuery{Sort: ListSortName, After: &ListKey{TypeCounter, "b"}, Limit: 1},
+			want:  []ListKey{{TypeGauge, "b"}},
+		},
+		{
+			name:  "after_last",
+			query: ListQuery{Sort: ListSortName, After: &ListKey{TypeCounter, "c"}},
+			want:  []ListKey{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, keys(tt.query.page(list())))
+		})
+	}
+}
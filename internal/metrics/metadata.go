@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMetadataLength наибольшая длина поля описания метрики в байтах
+const MaxMetadataLength = 1024
+
+var (
+	ErrorWrongMetadata        = errors.New("wrong metric metadata")
+	ErrorMetadataNotSupported = errors.New("storage does not support metric metadata")
+)
+
+// Metadata описание метрики: единица измерения, пояснение и команда-владелец. Все поля необязательные
+type Metadata struct {
+	Unit  string `json:"unit,omitempty"`
+	Help  string `json:"help,omitempty"`
+	Owner string `json:"owner,omitempty"`
+}
+
+// Validate проверка описания: поля в UTF-8 не длиннее MaxMetadataLength, единица и владелец в одну строку
+func (m Metadata) Validate() error {
+	for _, field := range []string{m.Unit, m.Help, m.Owner} {
+		if len(field) > MaxMetadataLength || !utf8.ValidString(field) {
+			return ErrorWrongMetadata
+		}
+	}
+	if strings.ContainsAny(m.Unit, "\r\n") || strings.ContainsAny(m.Owner, "\r\n") {
+		return ErrorWrongMetadata
+	}
+	return nil
+}
+
+// Empty не заполнено ли ни одно поле описания
+func (m Metadata) Empty() bool {
+	return m == Metadata{}
+}
+
+// Merge описание, в котором заполненные поля other заменяют поля m.
+// Поэтому агент может зарегистрировать, например, только единицу измерения, не стирая пояснение
+func (m Metadata) Merge(other Metadata) Metadata {
+	if other.Unit != "" {
+		m.Unit = other.Unit
+	}
+	if other.Help != "" {
+		m.Help = other.Help
+	}
+	if other.Owner != "" {
+		m.Owner = other.Owner
+	}
+	return m
+}
+
+// MetadataEntry описание метрики вместе с её типом и именем для снимка хранилища
+type MetadataEntry struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Metadata
+}
+
+// Key ключ метрики описания
+func (e MetadataEntry) Key() ListKey {
+	return ListKey{Type: e.Type, Name: e.Name}
+}
+
+// MetadataEntries описания метрик списком в порядке сортировки по имени
+func MetadataEntries(all map[ListKey]Metadata) []MetadataEntry {
+	entries := make([]MetadataEntry, 0, len(all))
+	for key, metadata := range all {
+		entries = append(entries, MetadataEntry{Type: key.Type, Name: key.Name, Metadata: metadata})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return ListQuery{}.Less(entries[i].Key(), entries[j].Key())
+	})
+	return entries
+}
+
+// IMetadataStorage хранилище описаний метрик. Описания не устаревают и не удаляются вместе с метрикой:
+// это справочник, а метрика с тем же именем может появиться снова
+type IMetadataStorage interface {
+	// SetMetadata объединяет описание метрики с сохранённым
+	SetMetadata(key ListKey, metadata Metadata) error
+	// GetMetadata описание отдельной метрики
+	GetMetadata(key ListKey) (Metadata, bool)
+	// GetAllMetadata описания всех метрик
+	GetAllMetadata() (map[ListKey]Metadata, error)
+}
+
+// IContextMetadataStorage хранилище описаний метрик, запись в которое прерывается при отмене контекста
+type IContextMetadataStorage interface {
+	// SetMetadataContext объединяет описание метрики с сохранённым
+	SetMetadataContext(ctx context.Context, key ListKey, metadata Metadata) error
+}
+
+// metadataStorage хранилище описаний внутри storage.
+// Если хранилище не умеет хранить описания, то возвращается ErrorMetadataNotSupported
+func metadataStorage(storage IStorage) (IMetadataStorage, error) {
+	if st, ok := storage.(IMetadataStorage); ok {
+		return st, nil
+	}
+	return nil, ErrorMetadataNotSupported
+}
+
+// SetMetadataContext объединение описания метрики с сохранённым в любом хранилище с контекстом запроса.
+// Если хранилище не умеет хранить описания, то возвращается ErrorMetadataNotSupported
+func SetMetadataContext(ctx context.Context, storage IStorage, key ListKey, metadata Metadata) error {
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+	if st, ok := storage.(IContextMetadataStorage); ok {
+		return st.SetMetadataContext(ctx, key, metadata)
+	}
+	st, err := metadataStorage(storage)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return st.SetMetadata(key, metadata)
+}
+
+// GetMetadataByKey описание метрики из любого хранилища. В хранилище без описаний описаний нет
+func GetMetadataByKey(storage IStorage, key ListKey) (Metadata, bool) {
+	st, err := metadataStorage(storage)
+	if err != nil {
+		return Metadata{}, false
+	}
+	return st.GetMetadata(key)
+}
+
+// GetAllMetadata описания всех метрик из любого хранилища. Для хранилища без описаний возвращается пустой список
+func GetAllMetadata(storage IStorage) (map[ListKey]Metadata, error) {
+	st, err := metadataStorage(storage)
+	if err != nil {
+		return map[ListKey]Metadata{}, nil
+	}
+	return st.GetAllMetadata()
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata_Validate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		metadata Metadata
+		wantErr  error
+	}{
+		{name: "empty"},
+		{name: "valid", metadata: Metadata{Unit: "bytes", Help: "Bytes of allocated heap objects.\nSee runtime.MemStats.", Owner: "runtime"}},
+		{name: "too_long", metadata: Metadata{Help: strings.Repeat("a", MaxMetadataLength+1)}, wantErr: ErrorWrongMetadata},
+		{name: "not_utf8", metadata: Metadata{Owner: "\xff"}, wantErr: ErrorWrongMetadata},
+		{name: "multiline_unit", metadata: Metadata{Unit: "bytes\n"}, wantErr: ErrorWrongMetadata},
+		{name: "multiline_owner", metadata: Metadata{Owner: "a\rb"}, wantErr: ErrorWrongMetadata},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.metadata.Validate(), tc.wantErr)
+		})
+	}
+}
+
+func TestMetadata_Merge(t *testing.T) {
+	metadata := Metadata{Unit: "bytes", Help: "old"}
+	assert.Equal(t, Metadata{Unit: "bytes", Help: "new", Owner: "core"}, metadata.Merge(Metadata{Help: "new", Owner: "core"}))
+	assert.Equal(t, metadata, metadata.Merge(Metadata{}))
+	assert.True(t, Metadata{}.Empty())
+	assert.False(t, metadata.Empty())
+}
+
+func TestSetMetadataContext(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStorage()
+	key := ListKey{Type: TypeGauge, Name: "Alloc"}
+	require.NoError(t, SetMetadataContext(ctx, store, key, Metadata{Unit: "bytes"}))
+	require.NoError(t, SetMetadataContext(ctx, store, key, Metadata{Help: "Allocated heap"}))
+	// Пустое описание не сохраняется
+	require.NoError(t, SetMetadataContext(ctx, store, ListKey{Type: TypeGauge, Name: "Frees"}, Metadata{}))
+	metadata, ok := GetMetadataByKey(store, key)
+	require.True(t, ok)
+	assert.Equal(t, Metadata{Unit: "bytes", Help: "Allocated heap"}, metadata)
+	all, err := GetAllMetadata(store)
+	require.NoError(t, err)
+	assert.Equal(t, map[ListKey]Metadata{key: metadata}, all)
+	assert.ErrorIs(t, SetMetadataContext(ctx, store, key, Metadata{Unit: "\n"}), ErrorWrongMetadata)
+
+	// Хранилище без описаний
+	plain := &contextAdapter{IStorage: store}
+	assert.ErrorIs(t, SetMetadataContext(ctx, plain, key, Metadata{Unit: "bytes"}), ErrorMetadataNotSupported)
+	_, ok = GetMetadataByKey(plain, key)
+	assert.False(t, ok)
+	all, err = GetAllMetadata(plain)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, SetMetadataContext(cancelled, store, key, Metadata{Unit: "bytes"}), context.Canceled)
+}
+
+func TestImportContext_Metadata(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStorage()
+	key := ListKey{Type: TypeGauge, Name: "Alloc"}
+	require.NoError(t, SetMetadataContext(ctx, store, key, Metadata{Help: "Allocated heap"}))
+	require.NoError(t, SetMetadataContext(ctx, store, ListKey{Type: TypeCounter, Name: "PollCount"}, Metadata{Unit: "polls"}))
+	list := []ListedMetric{{Type: TypeGauge, Name: "Alloc", Gauge: 1}}
+
+	// Описания объединяются с сохранёнными и не удаляются заменой
+	require.NoError(t, ImportContext(ctx, store, list, []MetadataEntry{{Type: TypeGauge, Name: "Alloc", Metadata: Metadata{Unit: "bytes"}}}, true))
+	all, err := GetAllMetadata(store)
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataEntry{
+		{Type: TypeGauge, Name: "Alloc", Metadata: Metadata{Unit: "bytes", Help: "Allocated heap"}},
+		{Type: TypeCounter, Name: "PollCount", Metadata: Metadata{Unit: "polls"}},
+	}, MetadataEntries(all))
+
+	// Неверное описание не загружает и метрики
+	wrong := []MetadataEntry{{Type: TypeGauge, Name: "Alloc", Metadata: Metadata{Unit: "\n"}}}
+	assert.ErrorIs(t, ImportContext(ctx, store, []ListedMetric{{Type: TypeGauge, Name: "Alloc", Gauge: 2}}, wrong, false), ErrorWrongMetadata)
+	value, _ := store.GetGauge("Alloc")
+	assert.Equal(t, Gauge(1), value)
+
+	plain := &contextAdapter{IStorage: NewMemStorage()}
+	require.NoError(t, ImportContext(ctx, plain, list, nil, false))
+	assert.ErrorIs(t, ImportContext(ctx, plain, list, []MetadataEntry{{Type: TypeGauge, Name: "Alloc"}}, false), ErrorMetadataNotSupported)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"gmetrics/internal/logger"
+	"time"
+)
+
+// SetMetadata объединение описания метрики с сохранённым. Как и гистограммы, описания не проходят через очередь
+// записи в фоне: в синхронном режиме и при записи в фоне описание записывается в бд сразу, иначе при синхронизации
+func (storage *DBStorage) SetMetadata(key ListKey, metadata Metadata) error {
+	return storage.SetMetadataContext(storage.storeCtx, key, metadata)
+}
+
+// SetMetadataContext объединение описания метрики с сохранённым, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) SetMetadataContext(ctx context.Context, key ListKey, metadata Metadata) error {
+	st, err := metadataStorage(storage.IStorage)
+	if err != nil {
+		return err
+	}
+	if err = st.SetMetadata(key, metadata); err != nil {
+		return err
+	}
+	if !storage.syncMode && storage.queue == nil {
+		return nil
+	}
+	state, ok := st.GetMetadata(key)
+	if !ok {
+		return nil
+	}
+	return storage.saveMetadata(ctx, key, state)
+}
+
+// GetMetadata описание метрики из памяти, в которую при создании хранилища загружаются описания из бд
+func (storage *DBStorage) GetMetadata(key ListKey) (Metadata, bool) {
+	return GetMetadataByKey(storage.IStorage, key)
+}
+
+// GetAllMetadata описания всех метрик из памяти
+func (storage *DBStorage) GetAllMetadata() (map[ListKey]Metadata, error) {
+	st, err := metadataStorage(storage.IStorage)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetAllMetadata()
+}
+
+// saveMetadata запись описания метрики в бд
+func (storage *DBStorage) saveMetadata(ctx context.Context, key ListKey, metadata Metadata) error {
+	_, err := storage.syncExec(ctx, "INSERT INTO t_metadata (tenant, type, name, unit, help, owner, updated_at) VALUES ($7, $1, $2, $3, $4, $5, $6)"+
+		" on conflict (tenant, type, name) do update set unit = $3, help = $4, owner = $5, updated_at = $6",
+		key.Type, key.Name, metadata.Unit, metadata.Help, metadata.Owner, storage.timeArg(time.Now()), storage.tenant)
+	return err
+}
+
+// flushMetadata запись в бд всех описаний из памяти
+func (storage *DBStorage) flushMetadata(ctx context.Context) error {
+	st, ok := storage.IStorage.(IMetadataStorage)
+	if !ok {
+		return nil
+	}
+	all, err := st.GetAllMetadata()
+	if err != nil {
+		return err
+	}
+	for key, metadata := range all {
+		if err = storage.saveMetadata(ctx, key, metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreMetadata загрузка описаний из бд в память. Описания не устаревают, поэтому загружаются все
+func (storage *DBStorage) restoreMetadata(ctx context.Context) error {
+	st, ok := storage.IStorage.(IMetadataStorage)
+	if !ok {
+		return nil
+	}
+	rows, err := storage.db.QueryContext(ctx, "SELECT type, name, unit, help, owner FROM t_metadata WHERE "+storage.tenantWhere(1), storage.tenant)
+	if err != nil {
+		return err
+	}
+	// Закроем строки, чтобы освободить соединение
+	defer func() {
+		if rErr := rows.Close(); rErr != nil {
+			logger.Log.Error(rErr)
+		}
+	}()
+	var (
+		key      ListKey
+		metadata Metadata
+	)
+	for rows.Next() {
+		if err = rows.Scan(&key.Type, &key.Name, &metadata.Unit, &metadata.Help, &metadata.Owner); err != nil {
+			return err
+		}
+		if err = st.SetMetadata(key, metadata); err != nil {
+			logger.Log.Infow("Skip broken metric metadata", "type", key.Type, "name", key.Name, "error", err)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStorage_Metadata(t *testing.T) {
+	testCases := []struct {
+		name        string
+		syncMode    bool
+		writeBehind bool
+		wantRows    int
+	}{
+		{name: "sync", syncMode: true, wantRows: 1},
+		{name: "write_behind", writeBehind: true, wantRows: 1},
+		{name: "interval", wantRows: 0},
+	}
+	key := ListKey{Type: TypeGauge, Name: "Alloc"}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newSQLiteDB(t)
+			store, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), false, tc.syncMode)
+			require.NoError(t, err)
+			if tc.writeBehind {
+				store.EnableWriteBehind(10)
+			}
+			require.NoError(t, store.SetMetadata(key, Metadata{Unit: "bytes", Owner: "runtime"}))
+			require.NoError(t, SetMetadataContext(context.Background(), store, key, Metadata{Help: "Allocated heap"}))
+			assert.Equal(t, tc.wantRows, countRows(t, db, "t_metadata"))
+
+			// Без синхронного режима описания записываются при синхронизации
+			require.NoError(t, store.Flush())
+			restored, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), true, true)
+			require.NoError(t, err)
+			metadata, ok := restored.GetMetadata(key)
+			require.True(t, ok)
+			assert.Equal(t, Metadata{Unit: "bytes", Help: "Allocated heap", Owner: "runtime"}, metadata)
+			all, err := restored.GetAllMetadata()
+			require.NoError(t, err)
+			assert.Len(t, all, 1)
+
+			// Без восстановления таблица очищается
+			_, err = NewSQLiteStorage(context.Background(), NewDBAdapter(db), false, true)
+			require.NoError(t, err)
+			assert.Equal(t, 0, countRows(t, db, "t_metadata"))
+		})
+	}
+}
+
+func TestDBStorage_MetadataNotSupported(t *testing.T) {
+	dbStorage := DBStorage{IStorage: &contextAdapter{IStorage: NewMemStorage()}, storeCtx: context.Background()}
+	key := ListKey{Type: TypeGauge, Name: "Alloc"}
+	assert.ErrorIs(t, dbStorage.SetMetadata(key, Metadata{Unit: "bytes"}), ErrorMetadataNotSupported)
+	_, ok := dbStorage.GetMetadata(key)
+	assert.False(t, ok)
+	_, err := dbStorage.GetAllMetadata()
+	assert.ErrorIs(t, err, ErrorMetadataNotSupported)
+	// Без хранилища описаний в памяти записывать нечего
+	assert.NoError(t, dbStorage.flushMetadata(context.Background()))
+	assert.NoError(t, dbStorage.restoreMetadata(context.Background()))
+}
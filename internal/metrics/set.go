@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+const (
+	// SketchPrecision количество бит хэша, по которым выбирается регистр скетча.
+	// 2^12 регистров дают стандартную ошибку оценки около 1.6%
+	SketchPrecision = 12
+	// sketchRegisters количество регистров скетча
+	sketchRegisters = 1 << SketchPrecision
+	// sketchVersion версия двоичного представления скетча
+	sketchVersion = 1
+)
+
+var (
+	ErrorWrongSketch     = errors.New("wrong set sketch")
+	ErrorSetNotSupported = errors.New("storage does not support sets")
+)
+
+// Sketch скетч HyperLogLog для оценки количества уникальных элементов множества.
+// Скетчи с разных агентов объединяются без потери точности, сами элементы не хранятся
+type Sketch struct {
+	registers []uint8 // nil - пустой скетч
+}
+
+// NewSketch скетч с элементами members
+func NewSketch(members ...string) Sketch {
+	var sketch Sketch
+	for _, member := range members {
+		sketch.Add(member)
+	}
+	return sketch
+}
+
+// Add добавление элемента в скетч
+func (s *Sketch) Add(member string) {
+	if s.registers == nil {
+		s.registers = make([]uint8, sketchRegisters)
+	}
+	hash := sketchHash(member)
+	index := hash >> (64 - SketchPrecision)
+	// Единица после значащих бит ограничивает ранг, если остаток хэша нулевой
+	rank := uint8(bits.LeadingZeros64(hash<<SketchPrecision|1<<(SketchPrecision-1))) + 1
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge объединение скетчей, исходные скетчи не меняются
+func (s Sketch) Merge(other Sketch) Sketch {
+	if other.registers == nil {
+		return s.Clone()
+	}
+	merged := other.Clone()
+	for i, rank := range s.registers {
+		merged.registers[i] = max(merged.registers[i], rank)
+	}
+	return merged
+}
+
+// Clone копия скетча
+func (s Sketch) Clone() Sketch {
+	return Sketch{registers: slices.Clone(s.registers)}
+}
+
+// Estimate оценка количества уникальных элементов. Для малых множеств используется линейный подсчёт
+func (s Sketch) Estimate() uint64 {
+	if s.registers == nil {
+		return 0
+	}
+	const m = float64(sketchRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, rank := range s.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(estimate))
+}
+
+// MarshalBinary двоичное представление скетча: версия, точность и регистры
+func (s Sketch) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 2, 2+sketchRegisters)
+	data[0], data[1] = sketchVersion, SketchPrecision
+	if s.registers == nil {
+		return append(data, make([]byte, sketchRegisters)...), nil
+	}
+	return append(data, s.registers...), nil
+}
+
+// UnmarshalBinary чтение скетча из двоичного представления. Скетч другой версии или точности не принимается
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) != 2+sketchRegisters || data[0] != sketchVersion || data[1] != SketchPrecision {
+		return ErrorWrongSketch
+	}
+	for _, rank := range data[2:] {
+		if rank > 64-SketchPrecision+1 {
+			return ErrorWrongSketch
+		}
+	}
+	s.registers = slices.Clone(data[2:])
+	return nil
+}
+
+// MarshalJSON скетч в JSON строкой base64 двоичного представления
+func (s Sketch) MarshalJSON() ([]byte, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON чтение скетча из строки base64
+func (s *Sketch) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	binary, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return errors.Join(ErrorWrongSketch, err)
+	}
+	return s.UnmarshalBinary(binary)
+}
+
+// sketchHash 64-битный хэш элемента, одинаковый на всех агентах и серверах.
+// FNV-1a перемешивается финализатором splitmix64, чтобы старшие биты были равномерными
+func sketchHash(member string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	z := h.Sum64()
+	z ^= z >> 30
+	z *= 0xbf58476d1ce4e5b9
+	z ^= z >> 27
+	z *= 0x94d049bb133111eb
+	z ^= z >> 31
+	return z
+}
+
+// ISetStorage хранилище множеств
+type ISetStorage interface {
+	// AddSet объединяет скетч с сохранённым
+	AddSet(name string, sketch Sketch) error
+	// GetSet получение отдельного скетча
+	GetSet(name string) (Sketch, bool)
+	// GetSets получение всех скетчей
+	GetSets() (map[string]Sketch, error)
+}
+
+// IContextSetStorage хранилище множеств, запись в которое прерывается при отмене контекста
+type IContextSetStorage interface {
+	// AddSetContext объединяет скетч с сохранённым
+	AddSetContext(ctx context.Context, name string, sketch Sketch) error
+}
+
+// setLoader хранилище в памяти, в которое скетчи загружаются из бд или журнала вместе со временем
+type setLoader interface {
+	// loadSet замена скетча
+	loadSet(name string, sketch Sketch, updated time.Time)
+}
+
+// setStorage хранилище множеств внутри storage.
+// Если хранилище не умеет хранить множества, то возвращается ErrorSetNotSupported
+func setStorage(storage IStorage) (ISetStorage, error) {
+	if st, ok := storage.(ISetStorage); ok {
+		return st, nil
+	}
+	return nil, ErrorSetNotSupported
+}
+
+// AddSetContext объединение скетча с сохранённым в любом хранилище с контекстом запроса.
+// Если хранилище не умеет хранить множества, то возвращается ErrorSetNotSupported
+func AddSetContext(ctx context.Context, storage IStorage, name string, sketch Sketch) error {
+	if st, ok := storage.(IContextSetStorage); ok {
+		return st.AddSetContext(ctx, name, sketch)
+	}
+	st, err := setStorage(storage)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return st.AddSet(name, sketch)
+}
+
+// GetSetByName скетч из любого хранилища. В хранилище без множеств множеств нет
+func GetSetByName(storage IStorage, name string) (Sketch, bool) {
+	st, err := setStorage(storage)
+	if err != nil {
+		return Sketch{}, false
+	}
+	return st.GetSet(name)
+}
+
+// GetSets все скетчи из любого хранилища. Для хранилища без множеств возвращается пустой список
+func GetSets(storage IStorage) (map[string]Sketch, error) {
+	st, err := setStorage(storage)
+	if err != nil {
+		return map[string]Sketch{}, nil
+	}
+	return st.GetSets()
+}
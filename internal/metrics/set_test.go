@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketch_Estimate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		members int
+	}{
+		{name: "small", members: 10},
+		{name: "linear_counting", members: 1000},
+		{name: "large", members: 100000},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sketch Sketch
+			for i := 0; i < tc.members; i++ {
+				sketch.Add("user-" + strconv.Itoa(i))
+				// Повторные элементы не меняют оценку
+				sketch.Add("user-" + strconv.Itoa(i))
+			}
+			assert.InEpsilon(t, tc.members, sketch.Estimate(), 0.05)
+		})
+	}
+	assert.Equal(t, uint64(0), Sketch{}.Estimate())
+}
+
+func TestSketch_Merge(t *testing.T) {
+	first := NewSketch()
+	second := NewSketch()
+	for i := 0; i < 3000; i++ {
+		first.Add("host-" + strconv.Itoa(i))
+		second.Add("host-" + strconv.Itoa(i+2000))
+	}
+	merged := first.Merge(second)
+	assert.InEpsilon(t, 5000, merged.Estimate(), 0.05)
+	// Исходные скетчи не меняются
+	assert.InEpsilon(t, 3000, first.Estimate(), 0.05)
+	assert.Equal(t, first, first.Merge(Sketch{}))
+	assert.Equal(t, first, Sketch{}.Merge(first))
+}
+
+func TestSketch_Marshal(t *testing.T) {
+	sketch := NewSketch("a", "b", "c")
+	data, err := sketch.MarshalBinary()
+	require.NoError(t, err)
+	var got Sketch
+	require.NoError(t, got.UnmarshalBinary(data))
+	assert.Equal(t, sketch, got)
+
+	raw, err := json.Marshal(sketch)
+	require.NoError(t, err)
+	got = Sketch{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, sketch, got)
+	// Пустой скетч записывается нулевыми регистрами
+	data, err = Sketch{}.MarshalBinary()
+	require.NoError(t, err)
+	assert.Len(t, data, 2+sketchRegisters)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "version", data: append([]byte{2, SketchPrecision}, make([]byte, sketchRegisters)...)},
+		{name: "precision", data: append([]byte{sketchVersion, 14}, make([]byte, sketchRegisters)...)},
+		{name: "rank", data: append([]byte{sketchVersion, SketchPrecision, 60}, make([]byte, sketchRegisters-1)...)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, new(Sketch).UnmarshalBinary(tc.data), ErrorWrongSketch)
+		})
+	}
+	assert.ErrorIs(t, new(Sketch).UnmarshalJSON([]byte(`"!"`)), ErrorWrongSketch)
+}
+
+func TestAddSetContext(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStorage()
+	require.NoError(t, AddSetContext(ctx, store, "users", NewSketch("a", "b")))
+	require.NoError(t, AddSetContext(ctx, store, "users", NewSketch("b", "c")))
+	sketch, ok := GetSetByName(store, "users")
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), sketch.Estimate())
+	sets, err := GetSets(store)
+	require.NoError(t, err)
+	assert.Len(t, sets, 1)
+
+	// Хранилище без множеств
+	plain := &contextAdapter{IStorage: store}
+	assert.ErrorIs(t, AddSetContext(ctx, plain, "users", NewSketch("a")), ErrorSetNotSupported)
+	_, ok = GetSetByName(plain, "users")
+	assert.False(t, ok)
+	sets, err = GetSets(plain)
+	require.NoError(t, err)
+	assert.Empty(t, sets)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, AddSetContext(cancelled, store, "users", NewSketch("a")), context.Canceled)
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// AddSet объединение скетча с сохранённым. Как и гистограммы, скетчи не проходят через очередь записи в фоне:
+// в синхронном режиме и при записи в фоне скетч записывается в бд сразу, иначе при синхронизации
+func (storage *DBStorage) AddSet(name string, sketch Sketch) error {
+	return storage.AddSetContext(storage.storeCtx, name, sketch)
+}
+
+// AddSetContext объединение скетча с сохранённым, запись в бд прерывается при отмене контекста
+func (storage *DBStorage) AddSetContext(ctx context.Context, name string, sketch Sketch) error {
+	_, _, err := storage.AddSetReturningNew(ctx, name, sketch)
+	return err
+}
+
+// AddSetReturningNew объединение скетча с сохранённым, возвращает скетчи до и после объединения из памяти
+func (storage *DBStorage) AddSetReturningNew(ctx context.Context, name string, sketch Sketch) (old *Sketch, updated Sketch, err error) {
+	if old, updated, err = AddSetReturningNew(ctx, storage.IStorage, name, sketch); err != nil {
+		return nil, Sketch{}, err
+	}
+	if !storage.syncMode && storage.queue == nil {
+		return old, updated, nil
+	}
+	return old, updated, storage.saveSet(ctx, name, updated)
+}
+
+// GetSet скетч из памяти, в которую при создании хранилища загружаются скетчи из бд
+func (storage *DBStorage) GetSet(name string) (Sketch, bool) {
+	return GetSetByName(storage.IStorage, name)
+}
+
+// GetSets все скетчи из памяти
+func (storage *DBStorage) GetSets() (map[string]Sketch, error) {
+	st, err := setStorage(storage.IStorage)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetSets()
+}
+
+// saveSet запись скетча в бд в двоичном виде
+func (storage *DBStorage) saveSet(ctx context.Context, name string, sketch Sketch) error {
+	data, err := sketch.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = storage.syncExec(ctx, storage.upsertSQL("t_set", "data", "$2"), name, data, storage.timeArg(time.Now()), storage.tenant)
+	return err
+}
+
+// flushSets запись в бд всех скетчей из памяти
+func (storage *DBStorage) flushSets(ctx context.Context) error {
+	st, ok := storage.IStorage.(ISetStorage)
+	if !ok {
+		return nil
+	}
+	sets, err := st.GetSets()
+	if err != nil {
+		return err
+	}
+	for name, sketch := range sets {
+		if err = storage.saveSet(ctx, name, sketch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreSets загрузка скетчей из бд в память. Скетчи считаются обновлёнными в момент восстановления
+func (storage *DBStorage) restoreSets(ctx context.Context) error {
+	loader, ok := storage.IStorage.(setLoader)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	return storage.queryDistributions(ctx, TypeSet, func(name string, data []byte) error {
+		var sketch Sketch
+		if err := sketch.UnmarshalBinary(data); err != nil {
+			return err
+		}
+		loader.loadSet(name, sketch, now)
+		return nil
+	})
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStorage_Sets(t *testing.T) {
+	testCases := []struct {
+		name        string
+		syncMode    bool
+		writeBehind bool
+		wantRows    int
+	}{
+		{name: "sync", syncMode: true, wantRows: 1},
+		{name: "write_behind", writeBehind: true, wantRows: 1},
+		{name: "interval", wantRows: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newSQLiteDB(t)
+			store, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), false, tc.syncMode)
+			require.NoError(t, err)
+			if tc.writeBehind {
+				store.EnableWriteBehind(10)
+			}
+			require.NoError(t, store.AddSet("users", NewSketch("a", "b")))
+			require.NoError(t, AddSetContext(context.Background(), store, "users", NewSketch("b", "c")))
+			assert.Equal(t, tc.wantRows, countRows(t, db, "t_set"))
+
+			// Без синхронного режима скетчи записываются при синхронизации
+			require.NoError(t, store.Flush())
+			restored, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), true, true)
+			require.NoError(t, err)
+			got, ok := restored.GetSet("users")
+			require.True(t, ok)
+			assert.Equal(t, uint64(3), got.Estimate())
+			sets, err := restored.GetSets()
+			require.NoError(t, err)
+			assert.Len(t, sets, 1)
+
+			require.NoError(t, restored.Delete(TypeSet, "users"))
+			assert.Equal(t, 0, countRows(t, db, "t_set"))
+			assert.ErrorIs(t, restored.Delete(TypeSet, "users"), ErrorMetricNotFound)
+		})
+	}
+}
+
+func TestSQLiteStorage_BrokenSet(t *testing.T) {
+	db := newSQLiteDB(t)
+	_, err := db.Exec("INSERT INTO t_set (name, data) VALUES ('users', x'0102')")
+	require.NoError(t, err)
+	// Испорченный скетч пропускается при восстановлении
+	restored, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), true, true)
+	require.NoError(t, err)
+	_, ok := restored.GetSet("users")
+	assert.False(t, ok)
+}
+
+func TestDBStorage_SetsNotSupported(t *testing.T) {
+	dbStorage := DBStorage{IStorage: &contextAdapter{IStorage: NewMemStorage()}, storeCtx: context.Background()}
+	assert.ErrorIs(t, dbStorage.AddSet("users", NewSketch("a")), ErrorSetNotSupported)
+	_, ok := dbStorage.GetSet("users")
+	assert.False(t, ok)
+	_, err := dbStorage.GetSets()
+	assert.ErrorIs(t, err, ErrorSetNotSupported)
+	// Без хранилища множеств в памяти записывать нечего
+	assert.NoError(t, dbStorage.flushSets(context.Background()))
+	assert.NoError(t, dbStorage.restoreSets(context.Background()))
+}
@@ -2,7 +2,17 @@ package metrics
 
 import (
 	"context"
+	"errors"
+	"gmetrics/internal/logger"
 	"io"
+	"time"
+)
+
+var (
+	// ErrorMetricNotFound ошибка, что метрики нет в хранилище
+	ErrorMetricNotFound = errors.New("metric not found")
+	// ErrorUnknownMetricType ошибка, что тип метрики не поддерживается хранилищем
+	ErrorUnknownMetricType = errors.New("unknown metric type")
 )
 
 // IStorage represents an interface for accessing and manipulating metrics storage.
@@ -23,6 +33,10 @@ type IStorage interface {
 	SetGauges(map[string]Gauge) error
 	// AddCounters массовое обновление метрик Каунтер
 	AddCounters(map[string]Counter) error
+	// Delete удаление метрики, если её нет, то возвращается ErrorMetricNotFound
+	Delete(metricType, name string) error
+	// ResetCounter обнуление counter, если его нет, то возвращается ErrorMetricNotFound
+	ResetCounter(name string) error
 }
 
 // ISynchronizationStorage Интерфейс
@@ -38,5 +52,97 @@ type ISynchronizationStorage interface {
 	IsSyncMode() bool
 }
 
+// IBatchReadingStorage хранилище, которое умеет читать много метрик за раз
+type IBatchReadingStorage interface {
+	// GetGaugesByNames значения gauge с указанными именами, отсутствующих метрик нет в результате
+	GetGaugesByNames(names []string) (map[string]Gauge, error)
+	// GetCountersByNames значения counter с указанными именами, отсутствующих метрик нет в результате
+	GetCountersByNames(names []string) (map[string]Counter, error)
+}
+
+// GetGaugesByNames значения gauge с указанными именами из любого хранилища.
+// Если хранилище не умеет читать много метрик за раз, то метрики читаются по одной
+func GetGaugesByNames(storage IStorage, names []string) (map[string]Gauge, error) {
+	if st, ok := storage.(IBatchReadingStorage); ok {
+		return st.GetGaugesByNames(names)
+	}
+	gauges := make(map[string]Gauge, len(names))
+	for _, name := range names {
+		if value, ok := storage.GetGauge(name); ok {
+			gauges[name] = value
+		}
+	}
+	return gauges, nil
+}
+
+// GetCountersByNames значения counter с указанными именами из любого хранилища.
+// Если хранилище не умеет читать много метрик за раз, то метрики читаются по одной
+func GetCountersByNames(storage IStorage, names []string) (map[string]Counter, error) {
+	if st, ok := storage.(IBatchReadingStorage); ok {
+		return st.GetCountersByNames(names)
+	}
+	counters := make(map[string]Counter, len(names))
+	for _, name := range names {
+		if value, ok := storage.GetCounter(name); ok {
+			counters[name] = value
+		}
+	}
+	return counters, nil
+}
+
+// IExpiringStorage хранилище, в котором устаревают метрики, которые давно не обновлялись.
+// Устаревшие метрики не возвращаются при чтении и не попадают в снимок хранилища
+type IExpiringStorage interface {
+	// SetTTL устанавливает время после последнего обновления, через которое метрика устаревает; 0 - не устаревают
+	SetTTL(ttl time.Duration)
+	// DeleteExpired удаляет устаревшие метрики, возвращает количество удалённых
+	DeleteExpired() (int, error)
+}
+
+// DeleteExpiredPeriodically удаляет устаревшие метрики из хранилища с периодом interval, пока не закрыт контекст
+func DeleteExpiredPeriodically(ctx context.Context, storage IExpiringStorage, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := storage.DeleteExpired()
+			if err != nil {
+				logger.Log.Error(err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Log.Infow("Expired metrics deleted", "count", deleted)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
 // MeStore Хранилище метрик в памяти.
 var MeStore IStorage
+
+// wrappingStorage хранилище поверх другого хранилища, которое хранит метрики тех же типов, что и оно
+type wrappingStorage interface {
+	// inner хранилище, в котором хранятся метрики
+	inner() IStorage
+}
+
+// CheckSupported проверка, что хранилище умеет хранить метрики типа metricType. Gauge и counter хранит любое хранилище
+func CheckSupported(storage IStorage, metricType string) error {
+	if st, ok := storage.(wrappingStorage); ok {
+		return CheckSupported(st.inner(), metricType)
+	}
+	switch metricType {
+	case TypeGauge, TypeCounter:
+		return nil
+	case TypeHistogram, TypeSummary:
+		_, err := distributionStorage(storage)
+		return err
+	case TypeSet:
+		_, err := setStorage(storage)
+		return err
+	}
+	return ErrorUnknownMetricType
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetByNames(t *testing.T) {
+	// Хранилище без пакетного чтения читается по одной метрике
+	ctrl := gomock.NewController(t)
+	store := NewMockIStorage(ctrl)
+	store.EXPECT().GetGauge("Alloc").Return(Gauge(1), true)
+	store.EXPECT().GetGauge("missing").Return(Gauge(0), false)
+	store.EXPECT().GetCounter("PollCount").Return(Counter(2), true)
+
+	gauges, err := GetGaugesByNames(store, []string{"Alloc", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 1}, gauges)
+	counters, err := GetCountersByNames(store, []string{"PollCount"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Counter{"PollCount": 2}, counters)
+
+	// Хранилище с пакетным чтением
+	mem := NewMemStorage()
+	require.NoError(t, mem.SetGauge("Alloc", 3))
+	gauges, err = GetGaugesByNames(mem, []string{"Alloc"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Gauge{"Alloc": 3}, gauges)
+}
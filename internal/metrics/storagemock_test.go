@@ -62,6 +62,20 @@ func (mr *MockIStorageMockRecorder) AddCounters(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCounters", reflect.TypeOf((*MockIStorage)(nil).AddCounters), arg0)
 }
 
+// Delete mocks base method.
+func (m *MockIStorage) Delete(metricType, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", metricType, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockIStorageMockRecorder) Delete(metricType, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockIStorage)(nil).Delete), metricType, name)
+}
+
 // GetCounter mocks base method.
 func (m *MockIStorage) GetCounter(name string) (Counter, bool) {
 	m.ctrl.T.Helper()
@@ -122,6 +136,20 @@ func (mr *MockIStorageMockRecorder) GetGauges() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGauges", reflect.TypeOf((*MockIStorage)(nil).GetGauges))
 }
 
+// ResetCounter mocks base method.
+func (m *MockIStorage) ResetCounter(name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetCounter", name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetCounter indicates an expected call of ResetCounter.
+func (mr *MockIStorageMockRecorder) ResetCounter(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetCounter", reflect.TypeOf((*MockIStorage)(nil).ResetCounter), name)
+}
+
 // SetGauge mocks base method.
 func (m *MockIStorage) SetGauge(name string, value Gauge) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,373 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"gmetrics/internal/logger"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// upsertChunkSize сколько строк записывать в бд одним запросом. Ограничено числом параметров запроса в Postgres
+const upsertChunkSize = 1000
+
+// DefaultWriteBehindQueueSize размер очереди изменённых метрик по умолчанию
+const DefaultWriteBehindQueueSize = 10000
+
+// IWriteBehindStorage хранилище, которое записывает изменения в бд в фоне
+type IWriteBehindStorage interface {
+	// RunWriteBehind запись изменённых метрик в бд с периодом interval, пока не закрыт контекст.
+	// Если очередь заполнилась, то запись начинается раньше
+	RunWriteBehind(ctx context.Context, interval time.Duration) error
+	// WriteBehindStats состояние очереди изменённых метрик
+	WriteBehindStats() WriteBehindStats
+}
+
+// WriteBehindStats состояние очереди изменённых метрик
+type WriteBehindStats struct {
+	Enabled           bool      `json:"enabled"`                 // Включена ли запись в фоне
+	QueueDepth        int       `json:"queue_depth"`             // Сколько метрик ждут записи
+	QueueCapacity     int       `json:"queue_capacity"`          // Сколько метрик может ждать записи
+	Flushes           uint64    `json:"flushes"`                 // Количество записей в бд
+	FailedFlushes     uint64    `json:"failed_flushes"`          // Количество неудачных записей в бд
+	FlushedSeries     uint64    `json:"flushed_series"`          // Сколько всего метрик записано
+	LastFlushAt       time.Time `json:"last_flush_at,omitempty"` // Время последней записи
+	LastFlushSeries   int       `json:"last_flush_series"`       // Сколько метрик записано последней записью
+	LastFlushDuration float64   `json:"last_flush_duration_ms"`  // Длительность последней записи в миллисекундах
+	LastFlushLag      float64   `json:"last_flush_lag_ms"`       // Сколько самое старое изменение ждало записи в миллисекундах
+	LastError         string    `json:"last_error,omitempty"`    // Ошибка последней неудачной записи
+}
+
+// writeBehindQueue очередь изменённых метрик. Хранятся только имена, значения при записи берутся из памяти,
+// поэтому несколько изменений одной метрики записываются в бд один раз
+type writeBehindQueue struct {
+	mutex    sync.Mutex
+	capacity int
+	gauges   map[string]struct{}
+	counters map[string]struct{}
+	// since время самого старого изменения в очереди
+	since time.Time
+	// space закрывается, когда очередь освобождается
+	space chan struct{}
+	// kick просит начать запись, не дожидаясь периода
+	kick chan struct{}
+	// flushMutex не даёт записи в бд пересекаться между собой и с удалением метрик
+	flushMutex sync.Mutex
+	stats      WriteBehindStats
+}
+
+// newWriteBehindQueue создание очереди на capacity метрик
+func newWriteBehindQueue(capacity int) *writeBehindQueue {
+	if capacity <= 0 {
+		capacity = DefaultWriteBehindQueueSize
+	}
+	return &writeBehindQueue{
+		capacity: capacity,
+		gauges:   make(map[string]struct{}),
+		counters: make(map[string]struct{}),
+		space:    make(chan struct{}),
+		kick:     make(chan struct{}, 1),
+	}
+}
+
+// add применяет изменение в памяти и ставит изменённые метрики в очередь. Если для них нет места,
+// то просит начать запись и ждёт, пока очередь освободится или отменится контекст.
+// Изменение применяется под блокировкой очереди, чтобы запись не взяла имя раньше, чем изменится значение
+func (q *writeBehindQueue) add(ctx context.Context, gauges, counters []string, apply func() error) error {
+	for {
+		q.mutex.Lock()
+		added := countMissing(q.gauges, gauges) + countMissing(q.counters, counters)
+		depth := len(q.gauges) + len(q.counters)
+		// Изменение больше всей очереди принимается в пустую очередь, иначе оно никогда не поместится
+		if added == 0 || depth+added <= q.capacity || depth == 0 {
+			err := q.apply(gauges, counters, apply)
+			full := len(q.gauges)+len(q.counters) >= q.capacity
+			q.mutex.Unlock()
+			if full {
+				q.requestFlush()
+			}
+			return err
+		}
+		space := q.space
+		q.mutex.Unlock()
+		q.requestFlush()
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// apply применяет изменение и отмечает метрики изменёнными
+func (q *writeBehindQueue) apply(gauges, counters []string, apply func() error) error {
+	if err := apply(); err != nil {
+		return err
+	}
+	if len(q.gauges)+len(q.counters) == 0 && (len(gauges) > 0 || len(counters) > 0) {
+		q.since = time.Now()
+	}
+	for _, name := range gauges {
+		q.gauges[name] = struct{}{}
+	}
+	for _, name := range counters {
+		q.counters[name] = struct{}{}
+	}
+	return nil
+}
+
+// requestFlush просит начать запись, не дожидаясь периода
+func (q *writeBehindQueue) requestFlush() {
+	select {
+	case q.kick <- struct{}{}:
+	default:
+	}
+}
+
+// take забирает изменённые метрики из очереди и будит ждущих места
+func (q *writeBehindQueue) take() (gauges, counters []string, since time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	gauges, counters, since = sortedNames(q.gauges), sortedNames(q.counters), q.since
+	q.gauges = make(map[string]struct{})
+	q.counters = make(map[string]struct{})
+	close(q.space)
+	q.space = make(chan struct{})
+	return gauges, counters, since
+}
+
+// putBack возвращает в очередь метрики, которые не удалось записать
+func (q *writeBehindQueue) putBack(gauges, counters []string, since time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.gauges)+len(q.counters) == 0 || since.Before(q.since) {
+		q.since = since
+	}
+	for _, name := range gauges {
+		q.gauges[name] = struct{}{}
+	}
+	for _, name := range counters {
+		q.counters[name] = struct{}{}
+	}
+}
+
+// record учёт результата записи в бд
+func (q *writeBehindQueue) record(series int, start, since time.Time, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	now := time.Now()
+	q.stats.Flushes++
+	q.stats.LastFlushAt = now
+	q.stats.LastFlushDuration = float64(now.Sub(start)) / float64(time.Millisecond)
+	if err != nil {
+		q.stats.FailedFlushes++
+		q.stats.LastError = err.Error()
+		return
+	}
+	q.stats.FlushedSeries += uint64(series)
+	q.stats.LastFlushSeries = series
+	q.stats.LastFlushLag = float64(now.Sub(since)) / float64(time.Millisecond)
+	q.stats.LastError = ""
+}
+
+// snapshot состояние очереди
+func (q *writeBehindQueue) snapshot() WriteBehindStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	stats := q.stats
+	stats.Enabled = true
+	stats.QueueDepth = len(q.gauges) + len(q.counters)
+	stats.QueueCapacity = q.capacity
+	return stats
+}
+
+// countMissing сколько имён без повторов ещё нет в множестве
+func countMissing(set map[string]struct{}, names []string) int {
+	count := 0
+	var seen map[string]struct{}
+	for _, name := range names {
+		if _, ok := set[name]; ok {
+			continue
+		}
+		if seen == nil {
+			seen = make(map[string]struct{}, len(names))
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		count++
+	}
+	return count
+}
+
+// sortedNames отсортированные имена метрик из набора
+func sortedNames[V any](values map[string]V) []string {
+	names := mapNames(values)
+	slices.Sort(names)
+	return names
+}
+
+// EnableWriteBehind включает запись изменений в бд в фоне через очередь на capacity метрик.
+// Запросы меняют только память, а в бд изменённые метрики записывает RunWriteBehind. Вызывается до начала работы хранилища
+func (storage *DBStorage) EnableWriteBehind(capacity int) {
+	storage.queue = newWriteBehindQueue(capacity)
+	// Последние значения есть только в памяти, поэтому читать их из бд нельзя
+	storage.syncMode = false
+}
+
+// RunWriteBehind запись изменённых метрик в бд с периодом interval, пока не закрыт контекст.
+// Ошибки записи не останавливают работу: метрики возвращаются в очередь и записываются следующей попыткой
+func (storage *DBStorage) RunWriteBehind(ctx context.Context, interval time.Duration) error {
+	if storage.queue == nil {
+		return nil
+	}
+	logger.Log.Infof("Write-behind process starts. Period is %s, queue size is %d", interval, storage.queue.capacity)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-storage.queue.kick:
+		case <-ctx.Done():
+			// Последняя запись не должна прерываться закрытием контекста
+			return storage.flushQueue(context.WithoutCancel(ctx))
+		}
+		if err := storage.flushQueue(ctx); err != nil {
+			logger.Log.Error(err)
+		}
+	}
+}
+
+// WriteBehindStats состояние очереди изменённых метрик
+func (storage *DBStorage) WriteBehindStats() WriteBehindStats {
+	if storage.queue == nil {
+		return WriteBehindStats{}
+	}
+	return storage.queue.snapshot()
+}
+
+// enqueue применяет изменение в памяти и ставит изменённые метрики в очередь записи
+func (storage *DBStorage) enqueue(ctx context.Context, gauges, counters []string, apply func() error) error {
+	if storage.close {
+		return ErrorStorageDatabaseClosed
+	}
+	return storage.queue.add(ctx, gauges, counters, apply)
+}
+
+// lockQueue не даёт записи из очереди пересечься с изменением бд в обход очереди.
+// Иначе запись могла бы вернуть в бд только что удалённую метрику
+func (storage *DBStorage) lockQueue() func() {
+	if storage.queue == nil {
+		return func() {}
+	}
+	storage.queue.flushMutex.Lock()
+	return storage.queue.flushMutex.Unlock
+}
+
+// flushQueue записывает в бд текущие значения метрик из очереди. Метрики, которых уже нет в памяти, не записываются
+func (storage *DBStorage) flushQueue(ctx context.Context) error {
+	unlock := storage.lockQueue()
+	defer unlock()
+	names, counterNames, since := storage.queue.take()
+	if len(names)+len(counterNames) == 0 {
+		return nil
+	}
+	start := time.Now()
+	gauges := make(map[string]Gauge, len(names))
+	for _, name := range names {
+		if value, ok := storage.IStorage.GetGauge(name); ok {
+			gauges[name] = value
+		}
+	}
+	counters := make(map[string]Counter, len(counterNames))
+	for _, name := range counterNames {
+		if value, ok := storage.IStorage.GetCounter(name); ok {
+			counters[name] = value
+		}
+	}
+	err := storage.syncDirty(ctx, gauges, counters)
+	storage.queue.record(len(gauges)+len(counters), start, since, err)
+	if err != nil {
+		storage.queue.putBack(names, counterNames, since)
+		return err
+	}
+	logger.Log.Debugw("Write-behind flushed", "gauges", len(gauges), "counters", len(counters), "duration", time.Since(start))
+	return nil
+}
+
+// syncDirty запись значений метрик в бд одной транзакцией с повторными попытками
+func (storage *DBStorage) syncDirty(ctx context.Context, gauges map[string]Gauge, counters map[string]Counter) (err error) {
+	if storage.close {
+		return ErrorStorageDatabaseClosed
+	}
+	pause := time.Second
+	var pgErr *pgconn.PgError
+	for i := 0; i < 3; i++ {
+		err = storage.upsertDirty(ctx, gauges, counters)
+		if err == nil {
+			break
+		}
+		logger.Log.Error(err)
+		if !(errors.As(err, &pgErr) && pgerrcode.IsConnectionException(pgErr.Code)) {
+			break
+		}
+
+		if wErr := waitRetry(ctx, pause); wErr != nil {
+			err = errors.Join(err, wErr)
+			break
+		}
+		pause += 2 * time.Second
+	}
+	return err
+}
+
+// upsertDirty запись значений метрик в бд запросами INSERT с несколькими строками VALUES
+func (storage *DBStorage) upsertDirty(ctx context.Context, gauges map[string]Gauge, counters map[string]Counter) error {
+	nowTime := storage.timeArg(time.Now())
+	tx, err := storage.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if tErr := tx.Rollback(); tErr != nil && tErr.Error() != "sql: transaction has already been committed or rolled back" {
+			logger.Log.Error(tErr)
+		}
+	}()
+	if err = upsertValues(ctx, tx, storage.tenant, "t_gauge", gauges, nowTime); err != nil {
+		return err
+	}
+	if err = upsertValues(ctx, tx, storage.tenant, "t_counter", counters, nowTime); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// upsertValues запись значений тенанта tenant в таблицу порциями по upsertChunkSize строк. Тенант - первый параметр запроса.
+// Имена сортируются, чтобы параллельные транзакции блокировали строки в одном порядке
+func upsertValues[V Gauge | Counter](ctx context.Context, tx ITX, tenant, table string, values map[string]V, now any) error {
+	names := sortedNames(values)
+	for start := 0; start < len(names); start += upsertChunkSize {
+		chunk := names[start:min(start+upsertChunkSize, len(names))]
+		rows := make([]string, 0, len(chunk))
+		args := make([]any, 0, 3*len(chunk)+1)
+		args = append(args, tenant)
+		for _, name := range chunk {
+			n := len(args)
+			rows = append(rows, "($1, $"+strconv.Itoa(n+1)+", $"+strconv.Itoa(n+2)+", $"+strconv.Itoa(n+3)+")")
+			args = append(args, name, values[name], now)
+		}
+		query := "INSERT INTO " + table + " (tenant, name, value, updated_at) VALUES " + strings.Join(rows, ", ") +
+			" on conflict (tenant, name) do update set value = excluded.value, updated_at = excluded.updated_at"
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWriteBehindStorage хранилище SQLite с записью в фоне через очередь на capacity метрик
+func newWriteBehindStorage(t *testing.T, capacity int) (*SQLiteStorage, *sql.DB) {
+	db := newSQLiteDB(t)
+	store, err := NewSQLiteStorage(context.Background(), NewDBAdapter(db), false, true)
+	require.NoError(t, err)
+	store.EnableWriteBehind(capacity)
+	return store, db
+}
+
+// countRows количество строк в таблице
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	var count int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM "+table).Scan(&count))
+	return count
+}
+
+func TestDBStorage_WriteBehind(t *testing.T) {
+	store, db := newWriteBehindStorage(t, 10)
+	assert.False(t, store.IsSyncMode())
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.SetGauge("Alloc", Gauge(i)))
+		require.NoError(t, store.AddCounter("PollCount", 1))
+	}
+	require.NoError(t, store.SetGauges(map[string]Gauge{"Heap": 3}))
+	require.NoError(t, store.AddCounters(map[string]Counter{"PollCount": 5}))
+
+	// До записи изменения есть только в памяти
+	assert.Equal(t, 0, countRows(t, db, "t_gauge"))
+	stats := store.WriteBehindStats()
+	assert.True(t, stats.Enabled)
+	assert.Equal(t, 3, stats.QueueDepth)
+	assert.Equal(t, 10, stats.QueueCapacity)
+
+	require.NoError(t, store.Flush())
+	var alloc Gauge
+	require.NoError(t, db.QueryRow("SELECT value FROM t_gauge WHERE name = 'Alloc'").Scan(&alloc))
+	assert.Equal(t, Gauge(4), alloc)
+	var pollCount Counter
+	require.NoError(t, db.QueryRow("SELECT value FROM t_counter WHERE name = 'PollCount'").Scan(&pollCount))
+	assert.Equal(t, Counter(10), pollCount)
+
+	stats = store.WriteBehindStats()
+	assert.Equal(t, 0, stats.QueueDepth)
+	assert.Equal(t, uint64(1), stats.Flushes)
+	assert.Equal(t, uint64(3), stats.FlushedSeries)
+	assert.Empty(t, stats.LastError)
+
+	// Удалённая до записи метрика не возвращается в бд
+	require.NoError(t, store.SetGauge("Temp", 1))
+	require.NoError(t, store.Delete(TypeGauge, "Temp"))
+	require.NoError(t, store.Flush())
+	assert.Equal(t, 2, countRows(t, db, "t_gauge"))
+}
+
+func TestDBStorage_WriteBehindFullQueue(t *testing.T) {
+	store, db := newWriteBehindStorage(t, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- store.RunWriteBehind(ctx, time.Hour)
+	}()
+
+	// Третья метрика не помещается в очередь и запускает запись, не дожидаясь периода
+	require.NoError(t, store.SetGauges(map[string]Gauge{"a": 1, "b": 2}))
+	require.NoError(t, store.SetGaugeContext(context.Background(), "c", 3))
+	assert.GreaterOrEqual(t, countRows(t, db, "t_gauge"), 2)
+
+	// Закрытие контекста записывает оставшиеся изменения
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, 3, countRows(t, db, "t_gauge"))
+	assert.Equal(t, 0, store.WriteBehindStats().QueueDepth)
+}
+
+func TestDBStorage_WriteBehindCancelledWait(t *testing.T) {
+	store, _ := newWriteBehindStorage(t, 1)
+	require.NoError(t, store.SetGauge("a", 1))
+
+	// Записи нет, поэтому место в очереди не освободится до отмены контекста
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, store.SetGaugeContext(ctx, "b", 2), context.DeadlineExceeded)
+	_, ok := store.IStorage.GetGauge("b")
+	assert.False(t, ok)
+	// Изменение уже стоящей в очереди метрики места не требует
+	assert.NoError(t, store.SetGaugeContext(ctx, "a", 3))
+}
+
+func TestDBStorage_WriteBehindFailedFlush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	executor := NewMockSQLExecutor(ctrl)
+	executor.EXPECT().BeginTx(gomock.Any(), gomock.Any()).Return(nil, errors.New("no connection"))
+	store := &DBStorage{IStorage: NewMemStorage(), storeCtx: context.Background(), db: executor}
+	store.EnableWriteBehind(10)
+	require.NoError(t, store.SetGauge("Alloc", 1))
+
+	assert.Error(t, store.Flush())
+	// Незаписанные метрики остаются в очереди до следующей попытки
+	stats := store.WriteBehindStats()
+	assert.Equal(t, 1, stats.QueueDepth)
+	assert.Equal(t, uint64(1), stats.FailedFlushes)
+	assert.Equal(t, "no connection", stats.LastError)
+}
+
+func TestCountMissing(t *testing.T) {
+	set := map[string]struct{}{"a": {}}
+	assert.Equal(t, 2, countMissing(set, []string{"a", "b", "c", "b"}))
+	assert.Equal(t, 0, countMissing(set, nil))
+}
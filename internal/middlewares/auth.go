@@ -0,0 +1,97 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"gmetrics/internal/auth"
+	"gmetrics/internal/helpers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+)
+
+// AuthMiddleware проверка токенов доступа для http и rpc
+type AuthMiddleware struct {
+	authenticator *auth.Authenticator
+}
+
+// NewAuthMiddleware создаёт проверку токенов. Если authenticator не содержит токенов, то все запросы пропускаются
+func NewAuthMiddleware(authenticator *auth.Authenticator) *AuthMiddleware {
+	return &AuthMiddleware{authenticator: authenticator}
+}
+
+// Require проверка, что у запроса есть токен с нужной областью действия. Мидлваре
+// Возвращает 401, если токена нет или он неверный, и 403, если у токена нет нужной области действия
+func (am *AuthMiddleware) Require(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !am.authenticator.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			value := bearerToken(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+			token, err := am.authenticator.Authorize(value, scope)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				if errors.Is(err, auth.ErrorForbidden) {
+					helpers.SetHTTPResponse(w, http.StatusForbidden, helpers.GetErrorJSONBody(err.Error()))
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gmetrics"`)
+				helpers.SetHTTPResponse(w, http.StatusUnauthorized, helpers.GetErrorJSONBody(err.Error()))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withTokenIdentity(r.Context(), token)))
+		})
+	}
+}
+
+// Interceptor проверка токенов для rpc. В scopes указывается область действия для каждого метода,
+// методы, которых нет в scopes, требуют ScopeAdmin
+func (am *AuthMiddleware) Interceptor(scopes map[string]auth.Scope) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !am.authenticator.Enabled() {
+			return handler(ctx, req)
+		}
+		scope, ok := scopes[info.FullMethod]
+		if !ok {
+			scope = auth.ScopeAdmin
+		}
+		var authorization, apiKey string
+		if md, hasMD := metadata.FromIncomingContext(ctx); hasMD {
+			if h := md.Get("Authorization"); len(h) > 0 {
+				authorization = h[0]
+			}
+			if h := md.Get("X-API-Key"); len(h) > 0 {
+				apiKey = h[0]
+			}
+		}
+		token, err := am.authenticator.Authorize(bearerToken(authorization, apiKey), scope)
+		if err != nil {
+			if errors.Is(err, auth.ErrorForbidden) {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(withTokenIdentity(ctx, token), req)
+	}
+}
+
+// bearerToken получение значения токена из заголовка Authorization со схемой Bearer или из заголовка X-API-Key
+func bearerToken(authorization, apiKey string) string {
+	if scheme, value, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(value)
+	}
+	return apiKey
+}
+
+// withTokenIdentity сохраняет имя токена как идентификатор клиента, если клиент ещё не определён по сертификату
+func withTokenIdentity(ctx context.Context, token *auth.Token) context.Context {
+	if GetClientID(ctx) != "" || token.Name == "" {
+		return ctx
+	}
+	return WithClientID(ctx, token.Name)
+}
@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	"context"
+	"gmetrics/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestAuthenticator проверка с токенами на запись и на чтение
+func newTestAuthenticator(t *testing.T) *auth.Authenticator {
+	t.Helper()
+	authenticator, err := auth.NewAuthenticator([]auth.Token{
+		{Name: "agent", Value: "agent-secret", Scopes: []auth.Scope{auth.ScopeWrite}},
+		{Name: "grafana", Value: "grafana-secret", Scopes: []auth.Scope{auth.ScopeRead}},
+	})
+	require.NoError(t, err)
+	return authenticator
+}
+
+func TestAuthMiddleware_Require(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	disabled, err := auth.NewAuthenticator(nil)
+	require.NoError(t, err)
+	tests := []struct {
+		name          string
+		authenticator *auth.Authenticator
+		headers       map[string]string
+		wantStatus    int
+		wantClientID  string
+	}{
+		{
+			name:          "auth_disabled",
+			authenticator: disabled,
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "no_token",
+			authenticator: authenticator,
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid_token",
+			authenticator: authenticator,
+			headers:       map[string]string{"Authorization": "Bearer wrong"},
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "bearer_token",
+			authenticator: authenticator,
+			headers:       map[string]string{"Authorization": "Bearer agent-secret"},
+			wantStatus:    http.StatusOK,
+			wantClientID:  "agent",
+		},
+		{
+			name:          "api_key",
+			authenticator: authenticator,
+			headers:       map[string]string{"X-API-Key": "agent-secret"},
+			wantStatus:    http.StatusOK,
+			wantClientID:  "agent",
+		},
+		{
+			name:          "insufficient_scope",
+			authenticator: authenticator,
+			headers:       map[string]string{"Authorization": "Bearer grafana-secret"},
+			wantStatus:    http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clientID string
+			handler := NewAuthMiddleware(tt.authenticator).Require(auth.ScopeWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clientID = GetClientID(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/update", nil)
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			res := recorder.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantClientID, clientID)
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_RequireKeepsCertificateIdentity(t *testing.T) {
+	var clientID string
+	handler := NewAuthMiddleware(newTestAuthenticator(t)).Require(auth.ScopeWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID = GetClientID(r.Context())
+	}))
+	request := httptest.NewRequest(http.MethodPost, "/update", nil)
+	request.Header.Set("Authorization", "Bearer agent-secret")
+	request = request.WithContext(WithClientID(request.Context(), "agent-1.local"))
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, "agent-1.local", clientID)
+}
+
+func TestAuthMiddleware_Interceptor(t *testing.T) {
+	interceptor := NewAuthMiddleware(newTestAuthenticator(t)).Interceptor(map[string]auth.Scope{
+		"/metrics.MetricsService/HandleMetrics": auth.ScopeWrite,
+	})
+	tests := []struct {
+		name     string
+		method   string
+		md       metadata.MD
+		wantCode codes.Code
+	}{
+		{
+			name:     "no_metadata",
+			method:   "/metrics.MetricsService/HandleMetrics",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "valid_token",
+			method:   "/metrics.MetricsService/HandleMetrics",
+			md:       metadata.Pairs("authorization", "Bearer agent-secret"),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "insufficient_scope",
+			method:   "/metrics.MetricsService/HandleMetrics",
+			md:       metadata.Pairs("x-api-key", "grafana-secret"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "unknown_method_requires_admin",
+			method:   "/metrics.MetricsService/Unknown",
+			md:       metadata.Pairs("authorization", "Bearer agent-secret"),
+			wantCode: codes.PermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, req any) (any, error) {
+				return nil, nil
+			})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
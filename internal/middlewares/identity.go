@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/x509"
+	"gmetrics/internal/contextkeys"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"net/http"
+)
+
+// ClientIdentity определение идентификатора клиента по его сертификату. Мидлваре
+// Работает только при mTLS, когда клиент предъявил проверенный сертификат
+func ClientIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			if id := CertificateIdentity(r.TLS.PeerCertificates[0]); id != "" {
+				r = r.WithContext(WithClientID(r.Context(), id))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIdentityInterceptor определение идентификатора клиента по его сертификату для rpc
+func ClientIdentityInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return handler(ctx, req)
+	}
+	if id := CertificateIdentity(tlsInfo.State.PeerCertificates[0]); id != "" {
+		ctx = WithClientID(ctx, id)
+	}
+	return handler(ctx, req)
+}
+
+// CertificateIdentity возвращает идентификатор владельца сертификата.
+// Используется CN, а если он пустой, то первое из альтернативных имён (DNS, email, URI)
+func CertificateIdentity(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return ""
+}
+
+// WithClientID сохраняет идентификатор клиента в контексте
+func WithClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextkeys.ClientID, id)
+}
+
+// GetClientID возвращает идентификатор клиента из контекста или пустую строку, если клиент не определён
+func GetClientID(ctx context.Context) string {
+	id, _ := ctx.Value(contextkeys.ClientID).(string)
+	return id
+}
@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestCertificateIdentity(t *testing.T) {
+	agentURI, _ := url.Parse("spiffe://gmetrics/agent-4")
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{
+			name: "common_name",
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}, DNSNames: []string{"host"}},
+			want: "agent-1",
+		},
+		{
+			name: "dns_name",
+			cert: &x509.Certificate{DNSNames: []string{"agent-2.local"}},
+			want: "agent-2.local",
+		},
+		{
+			name: "email",
+			cert: &x509.Certificate{EmailAddresses: []string{"agent-3@example.com"}},
+			want: "agent-3@example.com",
+		},
+		{
+			name: "uri",
+			cert: &x509.Certificate{URIs: []*url.URL{agentURI}},
+			want: "spiffe://gmetrics/agent-4",
+		},
+		{
+			name: "empty",
+			cert: &x509.Certificate{},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CertificateIdentity(tt.cert))
+		})
+	}
+}
+
+func TestClientIdentity(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+		want  string
+	}{
+		{
+			name:  "plain_http",
+			state: nil,
+			want:  "",
+		},
+		{
+			name:  "tls_without_client_certificate",
+			state: &tls.ConnectionState{},
+			want:  "",
+		},
+		{
+			name: "mtls",
+			state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: "agent-1"}},
+			}},
+			want: "agent-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetClientID(r.Context())
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/", nil)
+			request.TLS = tt.state
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientIdentityInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "no_peer",
+			ctx:  context.Background(),
+			want: "",
+		},
+		{
+			name: "insecure_peer",
+			ctx:  peer.NewContext(context.Background(), &peer.Peer{}),
+			want: "",
+		},
+		{
+			name: "mtls_peer",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+					{DNSNames: []string{"agent-2.local"}},
+				}}},
+			}),
+			want: "agent-2.local",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			_, err := ClientIdentityInterceptor(tt.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				got = GetClientID(ctx)
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -34,7 +34,7 @@ func GZIPDecompressRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Если указано сжатие тела в gzip, то заменяем тело на разжатое
 		if r.Header.Get("Content-Encoding") == "gzip" {
-			reader, err := compress.NewGZIPReader(r.Body)
+			reader, err := compress.NewLimitedGZIPReader(r.Body, config.Params.MaxDecompressedSize)
 			logger.Log.Debugw("Content encoded", "type", "gzip")
 			if err != nil {
 				// Если ошибка создания читателя, то отправляем ошибку сервера
@@ -84,7 +84,7 @@ func CheckSign(next http.Handler) http.Handler {
 			// Читаем тело запроса
 			rawBody, err := io.ReadAll(r.Body)
 			if err != nil {
-				helpers.SetHTTPResponse(w, http.StatusBadRequest, []byte(err.Error()))
+				helpers.SetHTTPResponse(w, helpers.ReadBodyErrorStatus(err), []byte(err.Error()))
 				return
 			}
 			// Ставим тело снова, чтобы его можно было прочитать снова.
@@ -8,11 +8,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"gmetrics/cmd/server/config"
+	"gmetrics/internal/helpers"
 	pb "gmetrics/internal/payload/proto"
 	"google.golang.org/grpc/metadata"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -133,6 +135,7 @@ func TestGZIPDecompressRequest(t *testing.T) {
 		desc         string
 		body         string
 		encoding     string
+		maxSize      int64
 		expectedCode int
 	}{
 		{
@@ -146,10 +149,25 @@ func TestGZIPDecompressRequest(t *testing.T) {
 			body:         "regular body",
 			expectedCode: http.StatusOK,
 		},
+		{
+			desc:         "gzip_body_within_limit",
+			body:         strings.Repeat("a", 100),
+			encoding:     "gzip",
+			maxSize:      100,
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "gzip_body_over_limit",
+			body:         strings.Repeat("a", 10000),
+			encoding:     "gzip",
+			maxSize:      100,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.desc, func(t *testing.T) {
+			config.Params = &config.CliConfig{MaxDecompressedSize: tc.maxSize}
 			request := resty.New().R()
 
 			var body []byte
@@ -176,7 +194,8 @@ func TestGZIPDecompressRequest(t *testing.T) {
 			router.Post("/", func(writer http.ResponseWriter, request *http.Request) {
 				rawBody, err := io.ReadAll(request.Body)
 				if err != nil {
-					t.Fatal(err)
+					writer.WriteHeader(helpers.ReadBodyErrorStatus(err))
+					return
 				}
 				if _, err = writer.Write(rawBody); err != nil {
 					t.Fatal(err)
@@ -196,6 +215,9 @@ func TestGZIPDecompressRequest(t *testing.T) {
 			result := res.StatusCode()
 			resultBody := res.Body()
 			assert.Equal(t, tc.expectedCode, result, "handler returned wrong status code")
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
 			assert.Equal(t, tc.body, string(resultBody), "handler returned wrong body")
 		})
 	}
@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"gmetrics/internal/helpers"
+	"gmetrics/internal/payload"
+	pb "gmetrics/internal/payload/proto"
+	"gmetrics/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrorTooManyRequests ошибка, что клиент превысил допустимую частоту запросов
+var ErrorTooManyRequests = errors.New("too many requests")
+
+// RateLimitMiddleware ограничение частоты запросов от каждого клиента
+type RateLimitMiddleware struct {
+	limiter *ratelimit.Limiter
+}
+
+// NewRateLimitMiddleware создаёт ограничение частоты запросов. Если limiter nil, то все запросы пропускаются
+func NewRateLimitMiddleware(limiter *ratelimit.Limiter) *RateLimitMiddleware {
+	return &RateLimitMiddleware{limiter: limiter}
+}
+
+// Limit ограничение частоты запросов по клиенту. Мидлваре
+// Клиент определяется по сертификату или токену, а если их нет, то по ip адресу соединения.
+// При превышении возвращает 429 и заголовок Retry-After
+func (rm *RateLimitMiddleware) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := GetClientID(r.Context())
+		if key == "" {
+			key = hostFromAddr(r.RemoteAddr)
+		}
+		if ok, wait := rm.limiter.Allow(key); !ok {
+			backoff := retryAfterSeconds(wait)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.FormatInt(backoff, 10))
+			helpers.SetHTTPResponse(w, http.StatusTooManyRequests, helpers.GetBackoffJSONBody(ErrorTooManyRequests.Error(), backoff))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Interceptor ограничение частоты запросов для rpc.
+// При превышении возвращает ResourceExhausted с ответом, в котором указано время ожидания, и заголовок retry-after
+func (rm *RateLimitMiddleware) Interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	key := GetClientID(ctx)
+	if p, ok := peer.FromContext(ctx); key == "" && ok && p.Addr != nil {
+		key = hostFromAddr(p.Addr.String())
+	}
+	if ok, wait := rm.limiter.Allow(key); !ok {
+		backoff := retryAfterSeconds(wait)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(backoff, 10)))
+		st := status.New(codes.ResourceExhausted, ErrorTooManyRequests.Error())
+		if detailed, err := st.WithDetails(&pb.MetricsResponse{
+			Status:  payload.ResponseErrorStatus,
+			Message: ErrorTooManyRequests.Error(),
+			Backoff: backoff,
+		}); err == nil {
+			st = detailed
+		}
+		return nil, st.Err()
+	}
+	return handler(ctx, req)
+}
+
+// hostFromAddr получение ip адреса из адреса вида host:port
+func hostFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// retryAfterSeconds время ожидания в секундах, округлённое вверх
+func retryAfterSeconds(wait time.Duration) int64 {
+	return int64(math.Ceil(wait.Seconds()))
+}
+
+// LimitBody ограничение размера тела запроса. Мидлваре
+// Если maxBytes не больше нуля, то размер не ограничивается
+func LimitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					helpers.SetHTTPResponse(w, http.StatusRequestEntityTooLarge, helpers.GetErrorJSONBody("request body too large"))
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"gmetrics/internal/payload"
+	pb "gmetrics/internal/payload/proto"
+	"gmetrics/internal/ratelimit"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitMiddleware_Limit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limiter    *ratelimit.Limiter
+		requests   []string // идентификатор клиента для каждого запроса, пустой - определяется по ip
+		wantStatus []int
+	}{
+		{
+			name:       "disabled",
+			limiter:    nil,
+			requests:   []string{"", "", ""},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:       "limit_by_ip",
+			limiter:    ratelimit.New(1, 2),
+			requests:   []string{"", "", ""},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:       "limit_by_client_id",
+			limiter:    ratelimit.New(1, 1),
+			requests:   []string{"agent-1", "agent-2", "agent-1"},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewRateLimitMiddleware(tt.limiter).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			for i, clientID := range tt.requests {
+				request := httptest.NewRequest(http.MethodPost, "/updates", nil)
+				if clientID != "" {
+					request = request.WithContext(WithClientID(request.Context(), clientID))
+				}
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				res := recorder.Result()
+				assert.Equal(t, tt.wantStatus[i], res.StatusCode)
+				if res.StatusCode == http.StatusTooManyRequests {
+					assert.Equal(t, "1", res.Header.Get("Retry-After"))
+					var body payload.ResponseBody
+					assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+					assert.Equal(t, int64(1), body.Backoff)
+				}
+				_ = res.Body.Close()
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_Interceptor(t *testing.T) {
+	interceptor := NewRateLimitMiddleware(ratelimit.New(1, 1)).Interceptor
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	}
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.NoError(t, err)
+	// Другой порт того же адреса считается тем же клиентом
+	ctx = peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5001}})
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		assert.Equal(t, int64(1), st.Details()[0].(*pb.MetricsResponse).GetBackoff())
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBytes   int64
+		body       string
+		wantStatus int
+	}{
+		{name: "no_limit", maxBytes: 0, body: strings.Repeat("a", 100), wantStatus: http.StatusOK},
+		{name: "within_limit", maxBytes: 100, body: strings.Repeat("a", 100), wantStatus: http.StatusOK},
+		{name: "over_limit", maxBytes: 10, body: strings.Repeat("a", 100), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := LimitBody(tt.maxBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(tt.body)))
+			res := recorder.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"gmetrics/cmd/server/config"
+	"gmetrics/internal/logger"
+	"gmetrics/internal/payload"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+// signWriter копит тело ответа, чтобы подписать его перед отправкой.
+// Тело ответа без подписи не копится, а пишется сразу
+type signWriter struct {
+	http.ResponseWriter
+	body     bytes.Buffer
+	status   int
+	unsigned bool
+}
+
+// WriteHeader запоминает статус ответа до подписи тела
+func (sw *signWriter) WriteHeader(statusCode int) {
+	if sw.unsigned {
+		sw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if sw.status == 0 {
+		sw.status = statusCode
+	}
+}
+
+// Write копит тело ответа
+func (sw *signWriter) Write(p []byte) (int, error) {
+	if sw.unsigned {
+		return sw.ResponseWriter.Write(p)
+	}
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.body.Write(p)
+}
+
+// Flush отправляет записанную часть ответа без подписи. Подписанный ответ
+// отправляется целиком после подписи, поэтому для него ничего не делает
+func (sw *signWriter) Flush() {
+	if !sw.unsigned {
+		return
+	}
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// SignResponse подпись тела ответа в заголовке HashSHA256. Мидлвар
+// Подписывается несжатое тело, поэтому мидлвар ставится после сжатия ответа
+func SignResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if config.Params.HashKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writer := &signWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r)
+		if writer.unsigned {
+			return
+		}
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+		w.Header().Set("HashSHA256", signBody(writer.body.Bytes()))
+		w.WriteHeader(writer.status)
+		if _, err := w.Write(writer.body.Bytes()); err != nil {
+			logger.Log.Error(err)
+		}
+	})
+}
+
+// Unsigned отключает подпись ответа для страниц и потоковой выгрузки снимка, чтобы не копить
+// их тело в памяти. Агенты такие ответы не запрашивают. Мидлвар ставится на роут после SignResponse
+func Unsigned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if writer, ok := w.(*signWriter); ok {
+			writer.unsigned = true
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SignResponseInterceptor подпись ответа rpc в трейлере HashSHA256.
+// Подписывается ответ в формате JSON, как по http. Если запрос завершился ошибкой,
+// то подписывается ответ из деталей ошибки, например, с просьбой подождать
+func SignResponseInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if config.Params.HashKey == "" {
+		return resp, err
+	}
+	var signed payload.RPCResponse
+	if r, ok := resp.(payload.RPCResponse); ok && err == nil {
+		signed = r
+	} else if st, isStatus := status.FromError(err); isStatus && err != nil {
+		for _, detail := range st.Details() {
+			if r, isResponse := detail.(payload.RPCResponse); isResponse {
+				signed = r
+				break
+			}
+		}
+	}
+	if signed == nil {
+		return resp, err
+	}
+	body, mErr := payload.RPCResponseBody(signed)
+	if mErr != nil {
+		logger.Log.Error(mErr)
+		return resp, err
+	}
+	if tErr := grpc.SetTrailer(ctx, metadata.Pairs("HashSHA256", signBody(body))); tErr != nil {
+		logger.Log.Error(tErr)
+	}
+	return resp, err
+}
+
+// signBody подпись тела ключом сервера
+func signBody(body []byte) string {
+	harsher := hmac.New(sha256.New, []byte(config.Params.HashKey))
+	harsher.Write(body)
+	return hex.EncodeToString(harsher.Sum(nil))
+}
@@ -0,0 +1,189 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gmetrics/cmd/server/config"
+	"gmetrics/internal/payload"
+	pb "gmetrics/internal/payload/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testSign подпись тела ключом для сравнения в тестах
+func testSign(key string, body []byte) string {
+	harsher := hmac.New(sha256.New, []byte(key))
+	harsher.Write(body)
+	return hex.EncodeToString(harsher.Sum(nil))
+}
+
+func TestSignResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		hashKey    string
+		status     int
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "no_key",
+			status:     http.StatusOK,
+			body:       `{"status":"success"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "signed_success",
+			hashKey:    "key",
+			status:     http.StatusOK,
+			body:       `{"status":"success"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "signed_error",
+			hashKey:    "key",
+			status:     http.StatusTooManyRequests,
+			body:       `{"status":"error","backoff":3}`,
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "signed_without_write_header",
+			hashKey:    "key",
+			body:       `{"status":"success"}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Params = &config.CliConfig{HashKey: tt.hashKey}
+			handler := SignResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/updates", nil))
+			res := recorder.Result()
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.body, string(body))
+			if tt.hashKey == "" {
+				assert.Empty(t, res.Header.Get("HashSHA256"))
+				return
+			}
+			assert.Equal(t, testSign(tt.hashKey, body), res.Header.Get("HashSHA256"))
+		})
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	config.Params = &config.CliConfig{HashKey: "key"}
+	var flushed bool
+	handler := SignResponse(Unsigned(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, "part")
+		// Часть ответа уходит клиенту до конца обработки
+		w.(http.Flusher).Flush()
+		flushed = true
+		_, _ = io.WriteString(w, "s")
+	})))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	assert.True(t, flushed)
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, "parts", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("HashSHA256"))
+
+	// Без подписи ответа мидлвар ничего не меняет
+	config.Params = &config.CliConfig{}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	assert.Equal(t, "parts", recorder.Body.String())
+}
+
+// trailerStream поток rpc, который запоминает установленные трейлеры
+type trailerStream struct {
+	grpc.ServerTransportStream
+	trailer metadata.MD
+}
+
+func (s *trailerStream) Method() string                  { return "/proto.MetricsService/HandleMetrics" }
+func (s *trailerStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *trailerStream) SendHeader(md metadata.MD) error { return nil }
+func (s *trailerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func TestSignResponseInterceptor(t *testing.T) {
+	backoffStatus, err := status.New(codes.ResourceExhausted, "too many requests").
+		WithDetails(&pb.MetricsResponse{Status: payload.ResponseErrorStatus, Backoff: 3})
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		hashKey  string
+		resp     any
+		err      error
+		wantBody *payload.ResponseBody
+	}{
+		{
+			name:    "no_key",
+			resp:    &pb.MetricsResponse{Status: payload.ResponseSuccessStatus},
+			hashKey: "",
+		},
+		{
+			name:     "signed_response",
+			hashKey:  "key",
+			resp:     &pb.MetricsResponse{Status: payload.ResponseSuccessStatus},
+			wantBody: &payload.ResponseBody{Status: payload.ResponseSuccessStatus},
+		},
+		{
+			name:     "signed_error_details",
+			hashKey:  "key",
+			err:      backoffStatus.Err(),
+			wantBody: &payload.ResponseBody{Status: payload.ResponseErrorStatus, Backoff: 3},
+		},
+		{
+			name:    "error_without_details",
+			hashKey: "key",
+			err:     errors.New("internal"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Params = &config.CliConfig{HashKey: tt.hashKey}
+			stream := &trailerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			resp, hErr := SignResponseInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				return tt.resp, tt.err
+			})
+			assert.Equal(t, tt.resp, resp)
+			assert.Equal(t, tt.err, hErr)
+			if tt.wantBody == nil {
+				assert.Empty(t, stream.trailer.Get("HashSHA256"))
+				return
+			}
+			body, mErr := payload.RPCResponseBody(&pb.MetricsResponse{
+				Status:  tt.wantBody.Status,
+				Backoff: tt.wantBody.Backoff,
+			})
+			require.NoError(t, mErr)
+			assert.Equal(t, []string{testSign(tt.hashKey, body)}, stream.trailer.Get("HashSHA256"))
+		})
+	}
+}
@@ -1,11 +1,68 @@
 package payload
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Metrics описывает структуру данных для представления метрик.
 type Metrics struct {
-	Value *float64 `json:"value,omitempty"` // Значение метрики в случае передачи gauge
-	Delta *int64   `json:"delta,omitempty"` // Значение метрики в случае передачи counter
-	ID    string   `json:"id"`              // Имя метрики
-	MType string   `json:"type"`            // Параметр, принимающий значение gauge или counter
+	Value        *float64   `json:"value,omitempty"`        // Значение метрики в случае передачи gauge или одно наблюдение histogram и summary
+	Delta        *int64     `json:"delta,omitempty"`        // Значение метрики в случае передачи counter
+	ID           string     `json:"id"`                     // Имя метрики
+	MType        string     `json:"type"`                   // Параметр, принимающий значение gauge, counter, histogram, summary или set
+	Histogram    *Histogram `json:"histogram,omitempty"`    // Корзины гистограммы, которые прибавляются к сохранённой
+	Observations []float64  `json:"observations,omitempty"` // Наблюдения в случае передачи summary
+	Summary      *Summary   `json:"summary,omitempty"`      // Количество, сумма и квантили сводки в ответе на чтение summary
+	Members      []string   `json:"members,omitempty"`      // Элементы, которые добавляются в множество set
+	Sketch       []byte     `json:"sketch,omitempty"`       // Скетч HyperLogLog множества, собранный агентом, в base64
+	Cardinality  *uint64    `json:"cardinality,omitempty"`  // Оценка количества уникальных элементов в ответе на чтение set
+	Metadata     *Metadata  `json:"metadata,omitempty"`     // Описание метрики, которое регистрирует агент
+}
+
+// Metadata описание метрики: единица измерения, пояснение и команда-владелец
+type Metadata struct {
+	Unit  string `json:"unit,omitempty"`
+	Help  string `json:"help,omitempty"`
+	Owner string `json:"owner,omitempty"`
+}
+
+// Histogram гистограмма с фиксированными корзинами. Counts на один элемент длиннее Bounds,
+// последний элемент - количество наблюдений больше всех границ
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// Summary количество, сумма и квантили наблюдений сводки за окно
+type Summary struct {
+	Count     uint64             `json:"count"`
+	Sum       float64            `json:"sum"`
+	Quantiles map[string]float64 `json:"quantiles,omitempty"` // Значения по квантилю, например "0.99"
+}
+
+// MetricValue метрика в ответе на чтение многих метрик за раз
+type MetricValue struct {
+	Metrics
+	Found bool `json:"found"` // Найдена ли метрика, если нет, то значение не заполнено
+}
+
+// ListedMetric метрика в списке метрик
+type ListedMetric struct {
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"` // Метки из имени метрики в нотации name{key="value"}
+	Value     any               `json:"value"`            // Число для gauge и counter, строка для NaN и бесконечностей gauge, объект для гистограмм и сводок
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  *Metadata         `json:"metadata,omitempty"` // Описание метрики, если оно зарегистрировано
+}
+
+// MetricsList страница списка метрик
+type MetricsList struct {
+	Metrics    []ListedMetric `json:"metrics"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Курсор следующей страницы, пустой на последней странице
 }
 
 // ResponseSuccessStatus статус, что метрика установлена удачно
@@ -16,9 +73,35 @@ var ResponseErrorStatus = "error"
 
 // ResponseBody представляет структуру типичного тела ответа API.
 type ResponseBody struct {
-	Status  string  `json:"status"` // Успешный или не успешный результат
-	ID      string  `json:"id,omitempty"`
-	Message string  `json:"message,omitempty"`
-	Delta   int64   `json:"delta,omitempty"` // Новое значение метрики в случае передачи counter
-	Value   float64 `json:"value,omitempty"` // Новое значение метрики в случае передачи gauge
+	Status  string      `json:"status"` // Успешный или не успешный результат
+	ID      string      `json:"id,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Delta   int64       `json:"delta,omitempty"`   // Новое значение метрики в случае передачи counter
+	Value   float64     `json:"value,omitempty"`   // Новое значение метрики в случае передачи gauge
+	Backoff int64       `json:"backoff,omitempty"` // Через сколько секунд агенту можно отправлять метрики снова
+	Errors  []ItemError `json:"errors,omitempty"`  // Ошибки отдельных метрик, из-за которых отклонён пакет
+}
+
+// ItemError ошибка отдельной метрики пакета
+type ItemError struct {
+	Index   int    `json:"index"` // Номер метрики в пакете, начиная с нуля
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// RPCResponse ответ сервера по rpc, который можно сопоставить с ResponseBody
+type RPCResponse interface {
+	GetStatus() string
+	GetMessage() string
+	GetBackoff() int64
+}
+
+// RPCResponseBody тело ответа rpc в том же формате JSON, что и ответ по http.
+// По нему сервер подписывает ответ rpc, а агент проверяет подпись
+func RPCResponseBody(r RPCResponse) ([]byte, error) {
+	return json.Marshal(ResponseBody{
+		Status:  r.GetStatus(),
+		Message: r.GetMessage(),
+		Backoff: r.GetBackoff(),
+	})
 }
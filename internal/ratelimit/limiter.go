@@ -0,0 +1,86 @@
+// Package ratelimit Пакет содержит ограничение частоты запросов от клиентов по алгоритму token bucket
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// cleanupInterval как часто удаляются корзины клиентов, которые давно не присылали запросы
+const cleanupInterval = time.Minute
+
+// bucket корзина токенов клиента
+type bucket struct {
+	tokens float64   // Доступное количество запросов
+	last   time.Time // Время последнего пополнения корзины
+}
+
+// Limiter ограничение частоты запросов для каждого клиента отдельно.
+// Каждому клиенту выдаётся корзина на burst запросов, которая пополняется со скоростью rate запросов в секунду
+type Limiter struct {
+	rate        float64
+	burst       float64
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+// New создаёт ограничение на rate запросов в секунду с запасом в burst запросов.
+// Если rate не больше нуля, то ограничение отключено и возвращается nil.
+// Если burst не больше нуля, то запас равен округлённому вверх rate
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow проверяет, можно ли выполнить запрос клиента key.
+// Если нельзя, то возвращает время, через которое у клиента появится доступный запрос
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup удаляет корзины, которые уже успели полностью пополниться, они ничем не отличаются от новых
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < cleanupInterval {
+		return
+	}
+	l.lastCleanup = now
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
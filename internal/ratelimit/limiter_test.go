@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestLimiter ограничение с управляемым временем
+func newTestLimiter(rate float64, burst int, now *time.Time) *Limiter {
+	l := New(rate, burst)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		burst     int
+		wantNil   bool
+		wantBurst float64
+	}{
+		{name: "disabled", rate: 0, wantNil: true},
+		{name: "negative_rate", rate: -1, wantNil: true},
+		{name: "explicit_burst", rate: 2, burst: 5, wantBurst: 5},
+		{name: "burst_from_rate", rate: 2.5, wantBurst: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.rate, tt.burst)
+			if tt.wantNil {
+				assert.Nil(t, got)
+				ok, wait := got.Allow("client")
+				assert.True(t, ok)
+				assert.Zero(t, wait)
+				return
+			}
+			assert.Equal(t, tt.wantBurst, got.burst)
+		})
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(2, 2, &now)
+
+	// Запас расходуется сразу
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("agent-1")
+		assert.True(t, ok)
+	}
+	ok, wait := l.Allow("agent-1")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Другой клиент не зависит от первого
+	ok, _ = l.Allow("agent-2")
+	assert.True(t, ok)
+
+	// Через полсекунды появляется один запрос
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("agent-1")
+	assert.True(t, ok)
+	ok, _ = l.Allow("agent-1")
+	assert.False(t, ok)
+
+	// Корзина не пополняется больше запаса
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		ok, _ = l.Allow("agent-1")
+		assert.True(t, ok)
+	}
+	ok, _ = l.Allow("agent-1")
+	assert.False(t, ok)
+}
+
+func TestLimiter_Cleanup(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(1, 1, &now)
+	l.Allow("agent-1")
+	l.Allow("agent-2")
+	assert.Len(t, l.buckets, 2)
+
+	now = now.Add(2 * cleanupInterval)
+	l.Allow("agent-3")
+	assert.Len(t, l.buckets, 1)
+	assert.Contains(t, l.buckets, "agent-3")
+}
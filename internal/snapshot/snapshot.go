@@ -0,0 +1,363 @@
+// Package snapshot Пакет описывает переносимый снимок хранилища метрик.
+//
+// Снимок бывает двух форматов:
+//   - NDJSON: первая строка - заголовок с версией и временем создания, далее по метрике на строку,
+//     последняя строка - количество метрик;
+//   - сжатый gzip JSON: один объект с заголовком, массивом метрик в поле metrics и их количеством после массива.
+//
+// Количество записывается после метрик, поэтому снимок пишется потоком по страницам хранилища,
+// а обрезанный снимок обнаруживается при чтении.
+//
+// Значение gauge и counter записывается числом, гистограммы - объектом с границами и количествами корзин,
+// сводки - объектом с наблюдениями за окно, чтобы после загрузки квантили считались так же,
+// множества - строкой base64 двоичного представления скетча.
+// Описания метрик (единица измерения, пояснение и владелец) записываются в заголовок отдельным разделом metadata.
+// Снимок не зависит от хранилища, поэтому его можно выгрузить из одного хранилища и загрузить в другое
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gmetrics/internal/helpers/compress"
+	"gmetrics/internal/metrics"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// Version версия формата снимка. Во второй версии появились гистограммы, сводки и множества,
+	// в третьей количество метрик перенесено в конец снимка. Снимки прежних версий тоже читаются
+	Version = 3
+	// FormatNDJSON снимок построчно
+	FormatNDJSON = "ndjson"
+	// FormatJSON снимок одним объектом JSON, сжатым gzip
+	FormatJSON = "json"
+)
+
+var (
+	// ErrorWrongFormat ошибка, что формат снимка не поддерживается
+	ErrorWrongFormat = errors.New("format must be ndjson or json")
+	// ErrorWrongVersion ошибка, что версия снимка не поддерживается
+	ErrorWrongVersion = fmt.Errorf("snapshot version must be from 1 to %d", Version)
+	// ErrorWrongMetric ошибка, что метрика в снимке некорректна
+	ErrorWrongMetric = errors.New("wrong metric in snapshot")
+	// ErrorWrongCount ошибка, что количество метрик не совпадает с записанным в снимке, например, снимок обрезан
+	ErrorWrongCount = errors.New("metrics count does not match snapshot")
+)
+
+// gzipMagic первые байты данных, сжатых gzip
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Header заголовок снимка
+type Header struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Count     int       `json:"count,omitempty"` // Количество метрик в снимке. С третьей версии пишется после метрик
+	// Описания метрик. Описание может быть и у метрики, которой нет в снимке
+	Metadata []metrics.MetadataEntry `json:"metadata,omitempty"`
+}
+
+// Metric метрика в снимке
+type Metric struct {
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"` // Метки из имени метрики, только для чтения человеком
+	Value     any               `json:"value"`            // Число для gauge и counter, строка для NaN и бесконечностей gauge, объект для гистограмм и сводок, строка base64 для множеств
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// trailer последняя строка снимка NDJSON
+type trailer struct {
+	Count int `json:"count"`
+}
+
+// Snapshot прочитанный снимок
+type Snapshot struct {
+	Header
+	Metrics []metrics.ListedMetric
+}
+
+// Writer потоковая запись снимка: метрики добавляются страницами, количество записывается при закрытии.
+// Снимок без Close считается обрезанным
+type Writer struct {
+	format  string
+	encoder *json.Encoder
+	gzip    *gzip.Writer
+	w       io.Writer
+	count   int
+}
+
+// NewWriter начинает снимок формата format и записывает заголовок с описаниями метрик
+func NewWriter(w io.Writer, format string, metadata []metrics.MetadataEntry, createdAt time.Time) (*Writer, error) {
+	header := Header{Version: Version, CreatedAt: createdAt.UTC(), Metadata: metadata}
+	switch format {
+	case FormatNDJSON:
+		writer := &Writer{format: format, encoder: json.NewEncoder(w), w: w}
+		return writer, writer.encoder.Encode(header)
+	case FormatJSON:
+		zw := gzip.NewWriter(w)
+		writer := &Writer{format: format, encoder: json.NewEncoder(zw), gzip: zw, w: zw}
+		raw, err := json.Marshal(header)
+		if err != nil {
+			return nil, err
+		}
+		// Объект заголовка продолжается массивом метрик
+		raw = append(bytes.TrimSuffix(raw, []byte("}")), `,"metrics":[`...)
+		_, err = zw.Write(raw)
+		return writer, err
+	}
+	return nil, ErrorWrongFormat
+}
+
+// Add записывает страницу метрик
+func (sw *Writer) Add(list []metrics.ListedMetric) error {
+	for _, metric := range list {
+		if sw.format == FormatJSON && sw.count > 0 {
+			if _, err := sw.w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		if err := sw.encoder.Encode(newMetric(metric)); err != nil {
+			return err
+		}
+		sw.count++
+	}
+	return nil
+}
+
+// Close записывает количество метрик и завершает снимок
+func (sw *Writer) Close() error {
+	if sw.format == FormatNDJSON {
+		return sw.encoder.Encode(trailer{Count: sw.count})
+	}
+	if _, err := fmt.Fprintf(sw.w, `],"count":%d}`+"\n", sw.count); err != nil {
+		return err
+	}
+	return sw.gzip.Close()
+}
+
+// Write записывает метрики и их описания в снимок формата format
+func Write(w io.Writer, format string, list []metrics.ListedMetric, metadata []metrics.MetadataEntry, createdAt time.Time) error {
+	writer, err := NewWriter(w, format, metadata, createdAt)
+	if err != nil {
+		return err
+	}
+	if err = writer.Add(list); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// Read читает снимок любого формата. Сжатый снимок разжимается не больше, чем до limit байт; 0 - без ограничений
+func Read(r io.Reader, limit int64) (Snapshot, error) {
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		reader, gErr := compress.NewLimitedGZIPReader(io.NopCloser(buffered), limit)
+		if gErr != nil {
+			return Snapshot{}, gErr
+		}
+		r = reader
+	} else {
+		r = buffered
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	// Заголовок снимка NDJSON - тот же документ JSON без метрик
+	var doc struct {
+		Header
+		Metrics *[]Metric `json:"metrics"`
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return Snapshot{}, err
+	}
+	if doc.Version < 1 || doc.Version > Version {
+		return Snapshot{}, ErrorWrongVersion
+	}
+	for _, entry := range doc.Metadata {
+		if err := checkMetadata(entry); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	result := Snapshot{Header: doc.Header, Metrics: make([]metrics.ListedMetric, 0, max(doc.Count, 0))}
+	add := func(metric Metric) error {
+		listed, err := metric.toListed()
+		if err != nil {
+			return err
+		}
+		result.Metrics = append(result.Metrics, listed)
+		return nil
+	}
+	if doc.Metrics != nil {
+		for _, metric := range *doc.Metrics {
+			if err := add(metric); err != nil {
+				return result, err
+			}
+		}
+	} else {
+		counted := false
+		for {
+			// С третьей версии последняя строка - количество метрик
+			var line struct {
+				Metric
+				Count *int `json:"count"`
+			}
+			err := decoder.Decode(&line)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return result, err
+			}
+			if counted {
+				// После количества метрик строк быть не должно
+				return result, ErrorWrongCount
+			}
+			if doc.Version >= 3 && line.Count != nil && line.Type == "" {
+				doc.Count = *line.Count
+				counted = true
+				continue
+			}
+			if err = add(line.Metric); err != nil {
+				return result, err
+			}
+		}
+		if doc.Version >= 3 && !counted {
+			return result, ErrorWrongCount
+		}
+	}
+	result.Count = doc.Count
+	if len(result.Metrics) != doc.Count {
+		return result, ErrorWrongCount
+	}
+	return result, nil
+}
+
+// newMetric метрика снимка. NaN и бесконечности не представимы в JSON числом, поэтому записываются строкой.
+// Время обновления обрезается до микросекунд, как в бд, чтобы снимки разных хранилищ совпадали
+func newMetric(metric metrics.ListedMetric) Metric {
+	_, labels := metrics.SplitLabels(metric.Name)
+	result := Metric{
+		Type:      metric.Type,
+		Name:      metric.Name,
+		Labels:    labels,
+		UpdatedAt: metric.UpdatedAt.UTC().Truncate(time.Microsecond),
+	}
+	switch metric.Type {
+	case metrics.TypeCounter:
+		result.Value = metric.Counter.GetRaw()
+	case metrics.TypeHistogram:
+		result.Value = metric.Histogram
+	case metrics.TypeSummary:
+		result.Value = metric.Summary
+	case metrics.TypeSet:
+		result.Value = metric.Set
+	default:
+		value := metric.Gauge.GetRaw()
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			result.Value = strconv.FormatFloat(value, 'g', -1, 64)
+		} else {
+			result.Value = value
+		}
+	}
+	return result
+}
+
+// toListed метрика хранилища из метрики снимка
+func (m Metric) toListed() (metrics.ListedMetric, error) {
+	result := metrics.ListedMetric{Type: m.Type, Name: m.Name, UpdatedAt: m.UpdatedAt}
+	if m.Name == "" {
+		return result, fmt.Errorf("%w: empty name", ErrorWrongMetric)
+	}
+	switch m.Type {
+	case metrics.TypeGauge:
+		var raw string
+		switch value := m.Value.(type) {
+		case json.Number:
+			raw = value.String()
+		case string:
+			raw = value
+		default:
+			return result, fmt.Errorf("%w: gauge %s has no value", ErrorWrongMetric, m.Name)
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return result, fmt.Errorf("%w: gauge %s value %q", ErrorWrongMetric, m.Name, raw)
+		}
+		result.Gauge = metrics.Gauge(value)
+	case metrics.TypeCounter:
+		number, ok := m.Value.(json.Number)
+		if !ok {
+			return result, fmt.Errorf("%w: counter %s has no value", ErrorWrongMetric, m.Name)
+		}
+		value, err := number.Int64()
+		if err != nil {
+			return result, fmt.Errorf("%w: counter %s value %s", ErrorWrongMetric, m.Name, number)
+		}
+		result.Counter = metrics.Counter(value)
+	case metrics.TypeHistogram:
+		if err := decodeValue(m.Value, &result.Histogram); err != nil {
+			return result, fmt.Errorf("%w: histogram %s: %w", ErrorWrongMetric, m.Name, err)
+		}
+		if err := result.Histogram.Validate(); err != nil {
+			return result, fmt.Errorf("%w: histogram %s: %w", ErrorWrongMetric, m.Name, err)
+		}
+	case metrics.TypeSummary:
+		if err := decodeValue(m.Value, &result.Summary); err != nil {
+			return result, fmt.Errorf("%w: summary %s: %w", ErrorWrongMetric, m.Name, err)
+		}
+		if err := result.Summary.Validate(); err != nil {
+			return result, fmt.Errorf("%w: summary %s: %w", ErrorWrongMetric, m.Name, err)
+		}
+	case metrics.TypeSet:
+		raw, ok := m.Value.(string)
+		if !ok {
+			return result, fmt.Errorf("%w: set %s has no value", ErrorWrongMetric, m.Name)
+		}
+		binary, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return result, fmt.Errorf("%w: set %s: %w", ErrorWrongMetric, m.Name, err)
+		}
+		if err = result.Set.UnmarshalBinary(binary); err != nil {
+			return result, fmt.Errorf("%w: set %s: %w", ErrorWrongMetric, m.Name, err)
+		}
+	default:
+		return result, fmt.Errorf("%w: %s has unknown type %q", ErrorWrongMetric, m.Name, m.Type)
+	}
+	return result, nil
+}
+
+// checkMetadata проверка описания метрики в заголовке снимка
+func checkMetadata(entry metrics.MetadataEntry) error {
+	switch entry.Type {
+	case metrics.TypeGauge, metrics.TypeCounter, metrics.TypeHistogram, metrics.TypeSummary, metrics.TypeSet:
+	default:
+		return fmt.Errorf("%w: metadata of %s has unknown type %q", ErrorWrongMetric, entry.Name, entry.Type)
+	}
+	if entry.Name == "" {
+		return fmt.Errorf("%w: metadata with empty name", ErrorWrongMetric)
+	}
+	if err := entry.Validate(); err != nil {
+		return fmt.Errorf("%w: metadata of %s: %w", ErrorWrongMetric, entry.Name, err)
+	}
+	return nil
+}
+
+// decodeValue разбор значения-объекта метрики снимка. Значение уже прочитано из JSON как map, поэтому оно
+// записывается обратно в JSON и читается в target. Значение без объекта не принимается
+func decodeValue(value any, target any) error {
+	if _, ok := value.(map[string]any); !ok {
+		return errors.New("value must be an object")
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
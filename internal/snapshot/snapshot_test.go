@@ -0,0 +1,152 @@
+package snapshot
+
+import (
+	"bytes"
+	"gmetrics/internal/metrics"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updated := createdAt.Add(-time.Hour)
+	latency := metrics.NewHistogram([]float64{0.1, 1})
+	latency.Observe(0.5)
+	list := []metrics.ListedMetric{
+		{Type: metrics.TypeGauge, Name: "Alloc", Gauge: 1.5, UpdatedAt: updated},
+		{Type: metrics.TypeGauge, Name: "Broken", Gauge: metrics.Gauge(math.Inf(-1)), UpdatedAt: updated},
+		{Type: metrics.TypeHistogram, Name: "Latency", Histogram: latency, UpdatedAt: updated},
+		{Type: metrics.TypeCounter, Name: "Requests{method=GET}", Counter: 9007199254740993, UpdatedAt: updated},
+		{
+			Type:      metrics.TypeSummary,
+			Name:      "Size",
+			Summary:   metrics.Summary{Observations: []metrics.Observation{{Value: 2, Time: updated}, {Value: 5, Time: updated}}},
+			UpdatedAt: updated,
+		},
+		{Type: metrics.TypeSet, Name: "Users", Set: metrics.NewSketch("alice", "bob"), UpdatedAt: updated},
+	}
+	metadata := []metrics.MetadataEntry{
+		{Type: metrics.TypeGauge, Name: "Alloc", Metadata: metrics.Metadata{Unit: "bytes", Help: "Allocated heap", Owner: "runtime"}},
+		{Type: metrics.TypeCounter, Name: "Removed", Metadata: metrics.Metadata{Help: "Metric without value"}},
+	}
+	for _, format := range []string{FormatNDJSON, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, format, list, metadata, createdAt))
+			snap, err := Read(&buf, 0)
+			require.NoError(t, err)
+			assert.Equal(t, Header{Version: Version, CreatedAt: createdAt, Count: 6, Metadata: metadata}, snap.Header)
+			assert.Equal(t, list, snap.Metrics)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatNDJSON, []metrics.ListedMetric{
+		{Type: metrics.TypeGauge, Name: `Temp{host="a"}`, Gauge: metrics.Gauge(math.NaN()), UpdatedAt: createdAt},
+	}, nil, createdAt))
+	assert.Equal(t, `{"version":3,"created_at":"2024-05-01T12:00:00Z"}`+"\n"+
+		`{"type":"gauge","name":"Temp{host=\"a\"}","labels":{"host":"a"},"value":"NaN","updated_at":"2024-05-01T12:00:00Z"}`+"\n"+
+		`{"count":1}`+"\n", buf.String())
+
+	assert.ErrorIs(t, Write(&buf, "csv", nil, nil, createdAt), ErrorWrongFormat)
+}
+
+func TestWriter(t *testing.T) {
+	pages := [][]metrics.ListedMetric{
+		{{Type: metrics.TypeGauge, Name: "Alloc", Gauge: 1}, {Type: metrics.TypeGauge, Name: "Frees", Gauge: 2}},
+		{{Type: metrics.TypeCounter, Name: "PollCount", Counter: 3}},
+		{},
+	}
+	for _, format := range []string{FormatNDJSON, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(&buf, format, nil, time.Now())
+			require.NoError(t, err)
+			for _, page := range pages {
+				require.NoError(t, writer.Add(page))
+			}
+			require.NoError(t, writer.Close())
+			snap, err := Read(&buf, 0)
+			require.NoError(t, err)
+			assert.Equal(t, 3, snap.Count)
+			assert.Equal(t, append(pages[0], pages[1]...), snap.Metrics)
+		})
+	}
+
+	// Снимок, запись которого прервалась, не загружается
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, FormatNDJSON, nil, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, writer.Add(pages[0]))
+	_, err = Read(&buf, 0)
+	assert.ErrorIs(t, err, ErrorWrongCount)
+
+	_, err = NewWriter(&buf, "csv", nil, time.Now())
+	assert.ErrorIs(t, err, ErrorWrongFormat)
+}
+
+func TestRead(t *testing.T) {
+	// Снимки первой версии тоже читаются
+	header := `{"version":1,"created_at":"2024-05-01T12:00:00Z","count":1}` + "\n"
+	v3 := `{"version":3,"created_at":"2024-05-01T12:00:00Z"}` + "\n"
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{name: "ok", body: header + `{"type":"counter","name":"PollCount","value":5}`},
+		{name: "histogram", body: header + `{"type":"histogram","name":"h","value":{"bounds":[1],"counts":[0,1],"sum":2,"count":1}}`},
+		{name: "v3", body: v3 + `{"type":"counter","name":"PollCount","value":5}` + "\n" + `{"count":1}`},
+		{name: "v3_json", body: `{"version":3,"metrics":[{"type":"counter","name":"PollCount","value":5}],"count":1}`},
+		{name: "wrong_version", body: `{"version":4,"count":0}`, wantErr: ErrorWrongVersion},
+		{name: "truncated", body: header, wantErr: ErrorWrongCount},
+		{name: "v3_truncated", body: v3 + `{"type":"counter","name":"PollCount","value":5}`, wantErr: ErrorWrongCount},
+		{name: "v3_wrong_count", body: v3 + `{"count":2}`, wantErr: ErrorWrongCount},
+		{name: "v3_after_count", body: v3 + `{"count":0}` + "\n" + `{"type":"counter","name":"PollCount","value":5}`, wantErr: ErrorWrongCount},
+		{name: "v3_json_without_count", body: `{"version":3,"metrics":[{"type":"counter","name":"PollCount","value":5}]}`, wantErr: ErrorWrongCount},
+		{name: "fractional_counter", body: header + `{"type":"counter","name":"PollCount","value":1.5}`, wantErr: ErrorWrongMetric},
+		{name: "unknown_type", body: header + `{"type":"unknown","name":"u","value":1}`, wantErr: ErrorWrongMetric},
+		{name: "histogram_number", body: header + `{"type":"histogram","name":"h","value":1}`, wantErr: ErrorWrongMetric},
+		{name: "wrong_histogram", body: header + `{"type":"histogram","name":"h","value":{"bounds":[1],"counts":[1],"count":1}}`, wantErr: ErrorWrongMetric},
+		{name: "wrong_summary", body: header + `{"type":"summary","name":"s","value":{"observations":[{"v":"x"}]}}`, wantErr: ErrorWrongMetric},
+		{name: "set_object", body: header + `{"type":"set","name":"u","value":{}}`, wantErr: ErrorWrongMetric},
+		{name: "wrong_set", body: header + `{"type":"set","name":"u","value":"AQw="}`, wantErr: ErrorWrongMetric},
+		{name: "metadata", body: `{"version":2,"count":0,"metadata":[{"type":"gauge","name":"Alloc","unit":"bytes"}]}`},
+		{name: "metadata_unknown_type", body: `{"version":2,"count":0,"metadata":[{"type":"unknown","name":"Alloc"}]}`, wantErr: ErrorWrongMetric},
+		{name: "metadata_empty_name", body: `{"version":2,"count":0,"metadata":[{"type":"gauge","unit":"bytes"}]}`, wantErr: ErrorWrongMetric},
+		{name: "wrong_metadata", body: `{"version":2,"count":0,"metadata":[{"type":"gauge","name":"Alloc","unit":"a\nb"}]}`, wantErr: ErrorWrongMetric},
+		{name: "empty_name", body: header + `{"type":"gauge","value":1}`, wantErr: ErrorWrongMetric},
+		{name: "gauge_without_value", body: header + `{"type":"gauge","name":"Alloc"}`, wantErr: ErrorWrongMetric},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tc.body), 0)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRead_Limit(t *testing.T) {
+	list := make([]metrics.ListedMetric, 0, 100)
+	for i := 0; i < 100; i++ {
+		list = append(list, metrics.ListedMetric{Type: metrics.TypeGauge, Name: "Alloc", Gauge: metrics.Gauge(i)})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, list, nil, time.Now()))
+	_, err := Read(&buf, 256)
+	var maxErr *http.MaxBytesError
+	assert.ErrorAs(t, err, &maxErr)
+}
@@ -0,0 +1,166 @@
+// Package tenant Пакет разделяет метрики клиентов по тенантам. У каждого тенанта своё хранилище,
+// свои лимиты рядов и своя история значений, а клиенты без тенанта работают с общим пространством имён
+package tenant
+
+import (
+	"context"
+	"errors"
+	"gmetrics/internal/cardinality"
+	"gmetrics/internal/contextkeys"
+	"gmetrics/internal/history"
+	"gmetrics/internal/metrics"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrorWrongTenants ошибка, что тенанты клиентов указаны не в формате client:tenant
+	ErrorWrongTenants = errors.New("tenants must be in format client:tenant, tenant of letters, digits, _ and -")
+	// ErrorWrongTenantSeries ошибка, что лимиты рядов тенантов указаны не в формате tenant:limit
+	ErrorWrongTenantSeries = errors.New("tenant series limits must be in format tenant:limit")
+)
+
+// nameRegexp допустимое имя тенанта. Имя попадает в имя файла и в запросы к бд, поэтому набор символов ограничен
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Namespace пространство имён метрик тенанта
+type Namespace struct {
+	Name    string               // Имя тенанта, пустое у общего пространства имён
+	Storage metrics.IStorage     // Хранилище метрик тенанта
+	Series  *cardinality.Limiter // Лимиты рядов тенанта; nil - ряды не учитываются
+	History *history.Recorder    // Недавние значения метрик тенанта; nil - история не ведётся
+}
+
+// Registry тенанты клиентов и пространства имён тенантов. Заполняется при запуске сервера
+// и дальше только читается, поэтому не защищён блокировкой
+type Registry struct {
+	clients    map[string]string
+	namespaces map[string]*Namespace
+}
+
+// Tenants глобальный реестр тенантов, если nil, то все клиенты работают с общим пространством имён
+var Tenants *Registry
+
+// NewRegistry создаёт реестр с тенантами клиентов clients: идентификатор клиента - имя тенанта
+func NewRegistry(clients map[string]string) *Registry {
+	return &Registry{
+		clients:    clients,
+		namespaces: make(map[string]*Namespace),
+	}
+}
+
+// Names имена тенантов клиентов по алфавиту
+func (r *Registry) Names() []string {
+	seen := make(map[string]struct{}, len(r.clients))
+	names := make([]string, 0, len(r.clients))
+	for _, name := range r.clients {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Add добавляет пространство имён тенанта
+func (r *Registry) Add(namespace *Namespace) {
+	r.namespaces[namespace.Name] = namespace
+}
+
+// Namespaces пространства имён тенантов по алфавиту
+func (r *Registry) Namespaces() []*Namespace {
+	result := make([]*Namespace, 0, len(r.namespaces))
+	for _, namespace := range r.namespaces {
+		result = append(result, namespace)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// lookup пространство имён тенанта клиента clientID
+func (r *Registry) lookup(clientID string) (*Namespace, bool) {
+	name, ok := r.clients[clientID]
+	if !ok {
+		return nil, false
+	}
+	namespace, ok := r.namespaces[name]
+	return namespace, ok
+}
+
+// Shared общее пространство имён для клиентов без тенанта
+func Shared() *Namespace {
+	return &Namespace{Storage: metrics.MeStore, Series: cardinality.Series, History: history.Recent}
+}
+
+// ForClient пространство имён клиента clientID. Клиенты без тенанта и анонимные клиенты
+// получают общее пространство имён
+func ForClient(clientID string) *Namespace {
+	if Tenants != nil {
+		if namespace, ok := Tenants.lookup(clientID); ok {
+			return namespace
+		}
+	}
+	return Shared()
+}
+
+// FromContext пространство имён клиента, определённого по токену или сертификату.
+// Идентификатор читается по ключу контекста напрямую: пакет middlewares зависит от конфигурации, а конфигурация от этого пакета
+func FromContext(ctx context.Context) *Namespace {
+	clientID, _ := ctx.Value(contextkeys.ClientID).(string)
+	return ForClient(clientID)
+}
+
+// ParseClients разбирает тенанты клиентов из строки конфигурации.
+// Формат: client:tenant, клиенты разделяются запятой. Например: agent-1:team-a,agent-2:team-a,agent-3:team-b
+func ParseClients(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	result := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		client, name, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || client == "" || !nameRegexp.MatchString(name) {
+			return nil, ErrorWrongTenants
+		}
+		if previous, ok := result[client]; ok && previous != name {
+			return nil, ErrorWrongTenants
+		}
+		result[client] = name
+	}
+	return result, nil
+}
+
+// ParseSeriesLimits разбирает лимиты рядов тенантов из строки конфигурации.
+// Формат: tenant:limit, тенанты разделяются запятой. Например: team-a:1000,team-b:500
+func ParseSeriesLimits(s string) (map[string]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	result := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || !nameRegexp.MatchString(name) {
+			return nil, ErrorWrongTenantSeries
+		}
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return nil, ErrorWrongTenantSeries
+		}
+		result[name] = limit
+	}
+	return result, nil
+}
+
+// StoragePath путь к файлу хранилища тенанта: имя тенанта вставляется перед расширением файла,
+// например, metrics.json -> metrics.team-a.json
+func StoragePath(path, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
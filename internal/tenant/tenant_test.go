@@ -0,0 +1,111 @@
+package tenant
+
+import (
+	"context"
+	"gmetrics/internal/cardinality"
+	"gmetrics/internal/contextkeys"
+	"gmetrics/internal/history"
+	"gmetrics/internal/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForClient(t *testing.T) {
+	oldStore, oldSeries, oldRecent, oldTenants := metrics.MeStore, cardinality.Series, history.Recent, Tenants
+	t.Cleanup(func() {
+		metrics.MeStore, cardinality.Series, history.Recent, Tenants = oldStore, oldSeries, oldRecent, oldTenants
+	})
+	metrics.MeStore = metrics.NewMemStorage()
+	cardinality.Series = cardinality.New(0, 0)
+	history.Recent = history.New(history.DefaultSize)
+
+	// Без реестра все клиенты работают с общим пространством имён
+	Tenants = nil
+	shared := ForClient("agent-1")
+	assert.Equal(t, "", shared.Name)
+	assert.Same(t, metrics.MeStore, shared.Storage)
+	assert.Same(t, cardinality.Series, shared.Series)
+	assert.Same(t, history.Recent, shared.History)
+
+	teamA := &Namespace{Name: "team-a", Storage: metrics.NewMemStorage(), Series: cardinality.New(10, 0)}
+	Tenants = NewRegistry(map[string]string{"agent-1": "team-a", "agent-2": "team-a", "agent-3": "team-b"})
+	Tenants.Add(teamA)
+	assert.Same(t, teamA, ForClient("agent-1"))
+	assert.Same(t, teamA, ForClient("agent-2"))
+	assert.Same(t, metrics.MeStore, ForClient("").Storage)
+	assert.Same(t, metrics.MeStore, ForClient("unknown").Storage)
+	// Тенант без пространства имён не получает чужое
+	assert.Equal(t, "", ForClient("agent-3").Name)
+
+	assert.Equal(t, []string{"team-a", "team-b"}, Tenants.Names())
+	teamB := &Namespace{Name: "team-b", Storage: metrics.NewMemStorage()}
+	Tenants.Add(teamB)
+	assert.Equal(t, []*Namespace{teamA, teamB}, Tenants.Namespaces())
+	assert.Same(t, teamB, ForClient("agent-3"))
+
+	assert.Same(t, teamA, FromContext(context.WithValue(context.Background(), contextkeys.ClientID, "agent-1")))
+	assert.Same(t, metrics.MeStore, FromContext(context.Background()).Storage)
+}
+
+func TestParseClients(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr error
+	}{
+		{name: "empty", input: ""},
+		{name: "clients", input: "agent-1:team-a, agent-2:team_b", want: map[string]string{"agent-1": "team-a", "agent-2": "team_b"}},
+		{name: "repeated", input: "agent-1:team-a,agent-1:team-a", want: map[string]string{"agent-1": "team-a"}},
+		{name: "two_tenants", input: "agent-1:team-a,agent-1:team-b", wantErr: ErrorWrongTenants},
+		{name: "no_tenant", input: "agent-1", wantErr: ErrorWrongTenants},
+		{name: "empty_client", input: ":team-a", wantErr: ErrorWrongTenants},
+		{name: "quote", input: "agent-1:team'a", wantErr: ErrorWrongTenants},
+		{name: "path", input: "agent-1:../team", wantErr: ErrorWrongTenants},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClients(tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSeriesLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]int
+		wantErr error
+	}{
+		{name: "empty", input: ""},
+		{name: "limits", input: "team-a:1000, team-b:0", want: map[string]int{"team-a": 1000, "team-b": 0}},
+		{name: "not_number", input: "team-a:many", wantErr: ErrorWrongTenantSeries},
+		{name: "negative", input: "team-a:-1", wantErr: ErrorWrongTenantSeries},
+		{name: "no_limit", input: "team-a", wantErr: ErrorWrongTenantSeries},
+		{name: "wrong_tenant", input: "team a:10", wantErr: ErrorWrongTenantSeries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeriesLimits(tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStoragePath(t *testing.T) {
+	assert.Equal(t, "/tmp/metrics.team-a.json", StoragePath("/tmp/metrics.json", "team-a"))
+	assert.Equal(t, "metrics.team-a", StoragePath("metrics", "team-a"))
+}
@@ -0,0 +1,140 @@
+// Package validation Пакет содержит политику проверки имён и значений метрик, которые принимает сервер
+package validation
+
+import (
+	"errors"
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrorNameTooLong ошибка, что имя метрики длиннее разрешённого
+	ErrorNameTooLong = errors.New("metric name is too long")
+	// ErrorWrongName ошибка, что имя метрики не подходит под разрешённое регулярное выражение
+	ErrorWrongName = errors.New("metric name does not match the allowed pattern")
+	// ErrorNonFinite ошибка, что значение метрики NaN или бесконечность
+	ErrorNonFinite = errors.New("metric value must be finite")
+	// ErrorNegativeDelta ошибка, что counter уменьшается
+	ErrorNegativeDelta = errors.New("counter delta must not be negative")
+	// ErrorNameNotAllowed ошибка, что клиенту не разрешено писать метрику с таким именем
+	ErrorNameNotAllowed = errors.New("metric name is not allowed for the client")
+	// ErrorWrongAllowedNames ошибка, что разрешённые имена в строке конфигурации записаны неверно
+	ErrorWrongAllowedNames = errors.New("allowed names must be in format client:name|prefix*")
+)
+
+// Policy политика проверки метрик. Нулевые поля не ограничивают метрики, nil политика пропускает всё
+type Policy struct {
+	NameRegexp          *regexp.Regexp      // Регулярное выражение для базового имени метрики без меток
+	MaxNameLength       int                 // Наибольшая длина имени вместе с метками в байтах
+	RejectNonFinite     bool                // Отклонять NaN и бесконечности gauge, наблюдений, суммы и границ гистограмм
+	RejectNegativeDelta bool                // Отклонять отрицательное приращение counter
+	AllowedNames        map[string][]string // Разрешённые имена по идентификатору клиента. Имя с * на конце - префикс
+}
+
+// NewPolicy создаёт политику. Регулярное выражение должно подходить под базовое имя целиком
+func NewPolicy(nameRegex string, maxNameLength int, rejectNonFinite, rejectNegativeDelta bool, allowedNames map[string][]string) (*Policy, error) {
+	policy := &Policy{
+		MaxNameLength:       maxNameLength,
+		RejectNonFinite:     rejectNonFinite,
+		RejectNegativeDelta: rejectNegativeDelta,
+		AllowedNames:        allowedNames,
+	}
+	if nameRegex != "" {
+		re, err := regexp.Compile("^(?:" + nameRegex + ")$")
+		if err != nil {
+			return nil, err
+		}
+		policy.NameRegexp = re
+	}
+	return policy, nil
+}
+
+// Enabled ограничивает ли политика хоть что-то
+func (p *Policy) Enabled() bool {
+	return p != nil && (p.NameRegexp != nil || p.MaxNameLength > 0 || p.RejectNonFinite || p.RejectNegativeDelta || len(p.AllowedNames) > 0)
+}
+
+// Check проверка метрики, которую пишет клиент clientID. Клиенту без списка разрешённых имён можно писать любые имена
+func (p *Policy) Check(clientID string, metric payload.Metrics) error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxNameLength > 0 && len(metric.ID) > p.MaxNameLength {
+		return ErrorNameTooLong
+	}
+	base, _ := metrics.SplitLabels(metric.ID)
+	if p.NameRegexp != nil && !p.NameRegexp.MatchString(base) {
+		return ErrorWrongName
+	}
+	if allowed, ok := p.AllowedNames[clientID]; ok && !nameAllowed(base, allowed) {
+		return ErrorNameNotAllowed
+	}
+	if p.RejectNonFinite {
+		if metric.Value != nil && !finite(*metric.Value) {
+			return ErrorNonFinite
+		}
+		for _, value := range metric.Observations {
+			if !finite(value) {
+				return ErrorNonFinite
+			}
+		}
+		if metric.Histogram != nil {
+			if !finite(metric.Histogram.Sum) {
+				return ErrorNonFinite
+			}
+			for _, bound := range metric.Histogram.Bounds {
+				if !finite(bound) {
+					return ErrorNonFinite
+				}
+			}
+		}
+	}
+	if p.RejectNegativeDelta && metric.MType == metrics.TypeCounter && metric.Delta != nil && *metric.Delta < 0 {
+		return ErrorNegativeDelta
+	}
+	return nil
+}
+
+// nameAllowed есть ли имя среди разрешённых имён и префиксов
+func nameAllowed(name string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// finite не NaN и не бесконечность
+func finite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// ParseAllowedNames разбирает разрешённые имена клиентов из строки конфигурации.
+// Формат: client:name|prefix*, клиенты разделяются запятой. Например: agent-1:Alloc|Heap*,agent-2:cpu_*
+func ParseAllowedNames(s string) (map[string][]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	result := make(map[string][]string)
+	for _, part := range strings.Split(s, ",") {
+		client, names, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || client == "" || names == "" {
+			return nil, ErrorWrongAllowedNames
+		}
+		for _, name := range strings.Split(names, "|") {
+			if name == "" {
+				return nil, ErrorWrongAllowedNames
+			}
+			result[client] = append(result[client], name)
+		}
+	}
+	return result, nil
+}
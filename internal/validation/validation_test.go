@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"gmetrics/internal/metrics"
+	"gmetrics/internal/payload"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := NewPolicy("", 0, false, false, nil)
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled())
+	var nilPolicy *Policy
+	assert.False(t, nilPolicy.Enabled())
+	assert.NoError(t, nilPolicy.Check("agent", payload.Metrics{ID: "any name"}))
+
+	policy, err = NewPolicy("[a-z]+", 0, false, false, nil)
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled())
+	// Выражение должно подходить под имя целиком
+	assert.False(t, policy.NameRegexp.MatchString("abc1"))
+
+	_, err = NewPolicy("[", 0, false, false, nil)
+	assert.Error(t, err)
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy, err := NewPolicy("[A-Za-z_][A-Za-z0-9_]*", 32, true, true, map[string][]string{
+		"agent-1": {"Alloc", "Heap*"},
+	})
+	require.NoError(t, err)
+	value := 1.5
+	nan := math.NaN()
+	inf := math.Inf(-1)
+	delta := int64(1)
+	negative := int64(-1)
+
+	tests := []struct {
+		name     string
+		clientID string
+		metric   payload.Metrics
+		wantErr  error
+	}{
+		{name: "valid", metric: payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &value}},
+		{name: "labels_are_not_matched", metric: payload.Metrics{ID: `requests{method="GET"}`, MType: metrics.TypeCounter, Delta: &delta}},
+		{name: "too_long", metric: payload.Metrics{ID: strings.Repeat("a", 33), MType: metrics.TypeGauge, Value: &value}, wantErr: ErrorNameTooLong},
+		{name: "space", metric: payload.Metrics{ID: "heap alloc", MType: metrics.TypeGauge, Value: &value}, wantErr: ErrorWrongName},
+		{name: "unicode", metric: payload.Metrics{ID: "память", MType: metrics.TypeGauge, Value: &value}, wantErr: ErrorWrongName},
+		{name: "nan", metric: payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &nan}, wantErr: ErrorNonFinite},
+		{name: "inf_observation", metric: payload.Metrics{ID: "latency", MType: metrics.TypeSummary, Observations: []float64{1, inf}}, wantErr: ErrorNonFinite},
+		{name: "inf_histogram_sum", metric: payload.Metrics{ID: "latency", MType: metrics.TypeHistogram, Histogram: &payload.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: inf, Count: 1}}, wantErr: ErrorNonFinite},
+		{name: "nan_histogram_bound", metric: payload.Metrics{ID: "latency", MType: metrics.TypeHistogram, Histogram: &payload.Histogram{Bounds: []float64{nan}, Counts: []uint64{0, 0}}}, wantErr: ErrorNonFinite},
+		{name: "finite_histogram", metric: payload.Metrics{ID: "latency", MType: metrics.TypeHistogram, Histogram: &payload.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1}}},
+		{name: "negative_delta", metric: payload.Metrics{ID: "PollCount", MType: metrics.TypeCounter, Delta: &negative}, wantErr: ErrorNegativeDelta},
+		{name: "allowed_name", clientID: "agent-1", metric: payload.Metrics{ID: "Alloc", MType: metrics.TypeGauge, Value: &value}},
+		{name: "allowed_prefix", clientID: "agent-1", metric: payload.Metrics{ID: `HeapIdle{host="a"}`, MType: metrics.TypeGauge, Value: &value}},
+		{name: "not_allowed", clientID: "agent-1", metric: payload.Metrics{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta}, wantErr: ErrorNameNotAllowed},
+		{name: "client_without_list", clientID: "agent-2", metric: payload.Metrics{ID: "PollCount", MType: metrics.TypeCounter, Delta: &delta}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, policy.Check(tt.clientID, tt.metric), tt.wantErr)
+		})
+	}
+}
+
+func TestParseAllowedNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string][]string
+		wantErr error
+	}{
+		{name: "empty"},
+		{
+			name: "valid",
+			s:    "agent-1:Alloc|Heap*, agent-2:cpu_*",
+			want: map[string][]string{"agent-1": {"Alloc", "Heap*"}, "agent-2": {"cpu_*"}},
+		},
+		{name: "no_names", s: "agent-1:", wantErr: ErrorWrongAllowedNames},
+		{name: "no_client", s: ":Alloc", wantErr: ErrorWrongAllowedNames},
+		{name: "empty_name", s: "agent-1:Alloc||Heap*", wantErr: ErrorWrongAllowedNames},
+		{name: "no_separator", s: "agent-1", wantErr: ErrorWrongAllowedNames},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAllowedNames(tt.s)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}